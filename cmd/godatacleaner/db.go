@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"godatacleaner/internal/config"
+	"godatacleaner/internal/storage"
+)
+
+func runDB(args []string) {
+	if len(args) < 2 || (args[0] != "backup" && args[0] != "restore") {
+		printDBHelp()
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Erreur de configuration: %v", err)
+	}
+
+	store, err := storage.NewStorage(cfg.SQLitePath, cfg.SQLiteBatchSize)
+	if err != nil {
+		log.Fatalf("Erreur connexion SQLite: %v", err)
+	}
+	defer store.Close()
+	store.WithUnicodeNormalization(cfg.NormalizeUnicode).WithPathRewrites(pathRewrites(cfg)).
+		WithRelativeMarkers(cfg.RelativePathMarkers())
+
+	ctx := context.Background()
+	if err := store.Initialize(ctx); err != nil {
+		log.Fatalf("Erreur initialisation DB: %v", err)
+	}
+
+	path := args[1]
+	switch args[0] {
+	case "backup":
+		log.Printf("💾 Sauvegarde de %s vers %s...", cfg.SQLitePath, path)
+		if err := store.Backup(ctx, path); err != nil {
+			log.Fatalf("Erreur sauvegarde: %v", err)
+		}
+		fmt.Printf("✅ Base sauvegardée dans %s\n", path)
+	case "restore":
+		log.Printf("♻️  Restauration de %s depuis %s...", cfg.SQLitePath, path)
+		if err := store.Restore(ctx, path); err != nil {
+			if errors.Is(err, storage.ErrLockHeld) {
+				log.Fatalf("Erreur: une synchronisation est en cours, réessayer une fois terminée")
+			}
+			log.Fatalf("Erreur restauration: %v", err)
+		}
+		// La sauvegarde peut dater d'une version antérieure du schéma
+		if err := store.Initialize(ctx); err != nil {
+			log.Fatalf("Erreur initialisation DB: %v", err)
+		}
+		fmt.Printf("✅ Base restaurée depuis %s\n", path)
+	}
+}
+
+func printDBHelp() {
+	fmt.Println("Usage: godatacleaner db <sous-commande>")
+	fmt.Println()
+	fmt.Println("Sous-commandes:")
+	fmt.Println("  backup <fichier>             Sauvegarder la base dans un nouveau fichier, sans arrêter le serveur web")
+	fmt.Println("  restore <fichier>            Remplacer le contenu de la base par une sauvegarde")
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sizeFlag is a size in bytes given with an optional unit, as 1GB, 500M or
+// 1.5 TiB. Units are powers of 1024, as in formatSize.
+type sizeFlag int64
+
+func (s *sizeFlag) String() string {
+	if *s == 0 {
+		return ""
+	}
+	return formatSize(int64(*s))
+}
+
+func (s *sizeFlag) Set(v string) error {
+	value := strings.ToUpper(strings.TrimSpace(v))
+	value = strings.TrimSuffix(strings.TrimSuffix(value, "B"), "I")
+	multiplier := int64(1)
+	if n := len(value); n > 0 {
+		if i := strings.IndexByte("KMGTP", value[n-1]); i >= 0 {
+			value = strings.TrimSpace(value[:n-1])
+			for ; i >= 0; i-- {
+				multiplier *= 1024
+			}
+		}
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 {
+		return fmt.Errorf("taille invalide %q (ex: 500MB, 1GB, 1.5TB)", v)
+	}
+	*s = sizeFlag(n * float64(multiplier))
+	return nil
+}
+
+// ageFlag is a duration given in days (30d, or 30), weeks (2w) or as a Go
+// duration (12h).
+type ageFlag time.Duration
+
+func (a *ageFlag) String() string {
+	if *a == 0 {
+		return ""
+	}
+	return time.Duration(*a).String()
+}
+
+func (a *ageFlag) Set(v string) error {
+	value := strings.ToLower(strings.TrimSpace(v))
+	day := 24 * time.Hour
+	unit := day
+	switch {
+	case strings.HasSuffix(value, "d"):
+		value = strings.TrimSuffix(value, "d")
+	case strings.HasSuffix(value, "w"):
+		value, unit = strings.TrimSuffix(value, "w"), 7*day
+	default:
+		if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+			*a = ageFlag(d)
+			return nil
+		}
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 {
+		return fmt.Errorf("durée invalide %q (ex: 30d, 2w, 12h)", v)
+	}
+	*a = ageFlag(n * float64(unit))
+	return nil
+}
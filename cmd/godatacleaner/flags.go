@@ -0,0 +1,154 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// configVar is a setting of config.Load that can be passed as a flag as well
+// as an environment variable.
+type configVar struct {
+	env   string
+	usage string
+	bool  bool // Given without value, as --scan-watch
+}
+
+// configVars lists the settings read from the environment by config.Load,
+// the per-instance QBITTORRENT_<NAME>_* variables excepted.
+var configVars = []configVar{
+	{env: "CONFIG_PATH", usage: "Fichier de configuration (défaut: ./config.json)"},
+	{env: "LOCAL_HOST", usage: "Hôte du serveur (défaut: localhost)"},
+	{env: "LOCAL_PORT", usage: "Port du serveur (défaut: 61913)"},
+	{env: "TORRENT_CLIENTS", usage: "Clients synchronisés: qbittorrent, transmission, deluge, rtorrent (défaut: qbittorrent)"},
+	{env: "QBITTORRENT_HOST", usage: "Hôte qBittorrent (défaut: qbt.home)"},
+	{env: "QBITTORRENT_PORT", usage: "Port qBittorrent (défaut: 80)"},
+	{env: "QBITTORRENT_USERNAME", usage: "Utilisateur (défaut: admin)"},
+	{env: "QBITTORRENT_PASSWORD", usage: "Mot de passe (défaut: adminadmin)"},
+	{env: "QBITTORRENT_MAX_WORKERS", usage: "Workers parallèles pour la sync (défaut: 10)"},
+	{env: "QBITTORRENT_RETRIES", usage: "Nouveaux essais d'une requête de sync en échec (défaut: 3)"},
+	{env: "QBITTORRENT_RETRY_DELAY_MS", usage: "Délai avant le premier nouvel essai, doublé à chaque essai (défaut: 500)"},
+	{env: "QBITTORRENT_TIMEOUT", usage: "Délai maximal de chaque requête de sync, en secondes (défaut: 30)"},
+	{env: "QBITTORRENT_CA_PATH", usage: "Certificats (PEM) d'une autorité interne"},
+	{env: "QBITTORRENT_TLS_SKIP_VERIFY", usage: "Ne pas vérifier le certificat HTTPS de qBittorrent", bool: true},
+	{env: "QBITTORRENT_INSTANCES", usage: "Instances qBittorrent multiples, séparées par des virgules (QBITTORRENT_<NOM>_* en variables d'environnement)"},
+	{env: "TRANSMISSION_HOST", usage: "Hôte Transmission (défaut: localhost)"},
+	{env: "TRANSMISSION_PORT", usage: "Port Transmission (défaut: 9091)"},
+	{env: "TRANSMISSION_USERNAME", usage: "Utilisateur Transmission (défaut: aucun)"},
+	{env: "TRANSMISSION_PASSWORD", usage: "Mot de passe Transmission"},
+	{env: "TRANSMISSION_RPC_PATH", usage: "Chemin de l'API RPC (défaut: /transmission/rpc)"},
+	{env: "DELUGE_HOST", usage: "Hôte de la WebUI Deluge (défaut: localhost)"},
+	{env: "DELUGE_PORT", usage: "Port de la WebUI Deluge (défaut: 8112)"},
+	{env: "DELUGE_PASSWORD", usage: "Mot de passe de la WebUI Deluge (défaut: deluge)"},
+	{env: "RTORRENT_URL", usage: "Endpoint XML-RPC rTorrent (défaut: http://localhost/RPC2)"},
+	{env: "RTORRENT_USERNAME", usage: "Utilisateur rTorrent (défaut: aucun)"},
+	{env: "RTORRENT_PASSWORD", usage: "Mot de passe rTorrent"},
+	{env: "SQLITE_PATH", usage: "Chemin de la DB (défaut: ./data/torrents.db)"},
+	{env: "SQLITE_BATCH_SIZE", usage: "Taille des lots d'insertion (défaut: 1000)"},
+	{env: "COUNT_CACHE_SECONDS", usage: "Secondes pendant lesquelles le serveur garde les totaux des listes (défaut: 30)"},
+	{env: "LOCAL_PATH", usage: "Chemin à scanner (défaut: ./data/torrents)"},
+	{env: "LOCAL_REMOTE", usage: "Remote rclone (ex: un bucket S3) listé à la place de LOCAL_PATH (défaut: aucun)"},
+	{env: "SCAN_WORKERS", usage: "Répertoires lus en parallèle par le scan (défaut: 8)"},
+	{env: "SCAN_FOLLOW_SYMLINKS", usage: "Suivre les liens symboliques pendant le scan", bool: true},
+	{env: "SCAN_INCREMENTAL", usage: "Ne relire que les répertoires modifiés depuis le scan précédent", bool: true},
+	{env: "SCAN_WATCH", usage: "Surveiller LOCAL_PATH depuis le serveur web, comme la commande watch", bool: true},
+	{env: "SYNC_SCHEDULE", usage: "Expression cron des synchronisations lancées par la commande daemon (ex: \"0 */6 * * *\", défaut: aucune)"},
+	{env: "SCAN_CANARY_FILE", usage: "Fichier (relatif à LOCAL_PATH) sans lequel les fichiers locaux ne sont pas remplacés"},
+	{env: "SCAN_REQUIRE_MOUNT", usage: "Exiger que LOCAL_PATH soit hors du système de fichiers racine", bool: true},
+	{env: "SCAN_MIN_SIZE", usage: "Taille minimale en octets des fichiers scannés (défaut: 0)"},
+	{env: "SCAN_EXTENSIONS", usage: "Extensions des fichiers scannés, séparées par des virgules (défaut: toutes)"},
+	{env: "SCAN_DEPTH", usage: "Profondeur maximale chemin=niveaux par racine de scan (ex: downloads=2)"},
+	{env: "SCAN_INCLUDE", usage: "Motifs chemin=motif des sous-chemins scannés par racine (ex: downloads=complete/*)"},
+	{env: "SCAN_EXTRAS", usage: "Classer samples, proofs, extras et fichiers parasites à part", bool: true},
+	{env: "NORMALIZE_UNICODE", usage: "Comparer les chemins locaux et des torrents en Unicode NFC", bool: true},
+	{env: "PATH_REWRITES", usage: "Réécritures source=cible des chemins locaux et des torrents, none pour aucune (défaut: /mnt=)"},
+	{env: "CATEGORY_RULES", usage: "Règles nom=expression des catégories (défaut: 4k=/4k/,movies=/movies/,shows=/shows/)"},
+	{env: "RELATIVE_MARKERS", usage: "Dossiers de comparaison des chemins locaux et des torrents (défaut: déduits de CATEGORY_RULES)"},
+	{env: "CHECKSUM", usage: "Empreintes des fichiers locaux: xxhash ou sha1 (défaut: désactivé)"},
+	{env: "CHECKSUM_MODE", usage: "partial (début et fin des fichiers) ou full (défaut: partial)"},
+	{env: "CHECKSUM_PARTIAL_MB", usage: "Mo lus au début et à la fin des fichiers en mode partial (défaut: 16)"},
+	{env: "CHECKSUM_WORKERS", usage: "Fichiers lus en parallèle pour les empreintes (défaut: 2)"},
+	{env: "CHECKSUM_RATE_MB", usage: "Débit de lecture maximal en Mo/s pour les empreintes (défaut: illimité)"},
+	{env: "QUARANTINE_PATH", usage: "Répertoire de quarantaine (défaut: désactivé)"},
+	{env: "QUARANTINE_RETENTION_DAYS", usage: "Jours avant purge de la quarantaine (défaut: 30)"},
+	{env: "ARCHIVE_PATH", usage: "Répertoire d'archive (défaut: désactivé)"},
+	{env: "AUTH_USERNAME", usage: "Utilisateur de l'interface web (défaut: désactivé)"},
+	{env: "AUTH_PASSWORD", usage: "Mot de passe de l'interface web"},
+	{env: "AUTH_TOKEN", usage: "Jeton d'API (Authorization: Bearer)"},
+	{env: "CORS_ALLOWED_ORIGINS", usage: "Origines autorisées à appeler l'API, séparées par des virgules"},
+	{env: "PROTECTED_PATHS", usage: "Motifs protégés, séparés par des virgules"},
+	{env: "COMPANION_EXTENSIONS", usage: "Extensions des fichiers compagnons (défaut: .srt,.nfo,.jpg...)"},
+	{env: "DEAD_TRACKER_MESSAGES", usage: "Messages des trackers signalant un torrent mort (défaut: unregistered,...)"},
+}
+
+// flagName returns the flag of a setting: --local-path for LOCAL_PATH, and
+// --config for CONFIG_PATH.
+func (v configVar) flagName() string {
+	if v.env == "CONFIG_PATH" {
+		return "config"
+	}
+	return strings.ToLower(strings.ReplaceAll(v.env, "_", "-"))
+}
+
+// envFlag is the flag of a setting. It sets the environment variable read by
+// config.Load, so that a flag takes precedence over the environment and the
+// config file.
+type envFlag configVar
+
+func (f envFlag) String() string     { return "" }
+func (f envFlag) Set(v string) error { return os.Setenv(f.env, v) }
+func (f envFlag) IsBoolFlag() bool   { return f.bool }
+
+// addConfigFlags adds a flag for every setting of configVars to fs.
+func addConfigFlags(fs *flag.FlagSet) {
+	for _, v := range configVars {
+		fs.Var(envFlag(v), v.flagName(), v.usage+" ["+v.env+"]")
+	}
+}
+
+// newFlagSet returns the flag set of a command, accepting the settings of
+// configVars after its own options. Its help (-h) lists the options of the
+// command, the settings being listed by godatacleaner help.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	addConfigFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: godatacleaner %s [options]\n\nOptions:\n", name)
+		fs.VisitAll(func(f *flag.Flag) {
+			if _, ok := f.Value.(envFlag); !ok {
+				printFlag(fs, f)
+			}
+		})
+		fmt.Fprintln(fs.Output(), "\nLes options de configuration (--config, --local-path...) sont listées par godatacleaner help.")
+	}
+	return fs
+}
+
+// printFlag prints the help of a flag, as flag.PrintDefaults does.
+func printFlag(fs *flag.FlagSet, f *flag.Flag) {
+	name, usage := flag.UnquoteUsage(f)
+	line := "  --" + f.Name
+	if len(f.Name) == 1 {
+		line = "  -" + f.Name
+	}
+	if name != "" {
+		line += " " + name
+	}
+	if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "0" {
+		usage += fmt.Sprintf(" (défaut: %s)", f.DefValue)
+	}
+	fmt.Fprintf(fs.Output(), "%s\n    \t%s\n", line, strings.ReplaceAll(usage, "\n", "\n    \t"))
+}
+
+// printConfigFlags prints the settings of configVars, for godatacleaner help.
+func printConfigFlags() {
+	for _, v := range configVars {
+		value := " <valeur>"
+		if v.bool {
+			value = ""
+		}
+		fmt.Printf("  --%-30s %s\n", v.flagName()+value, v.env)
+		fmt.Printf("        %s\n", v.usage)
+	}
+}
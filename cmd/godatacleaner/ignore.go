@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"godatacleaner/internal/config"
+	"godatacleaner/internal/storage"
+)
+
+func runIgnore(args []string) {
+	if len(args) < 1 {
+		printIgnoreHelp()
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Erreur de configuration: %v", err)
+	}
+
+	store, err := storage.NewStorage(cfg.SQLitePath, cfg.SQLiteBatchSize)
+	if err != nil {
+		log.Fatalf("Erreur connexion SQLite: %v", err)
+	}
+	defer store.Close()
+	store.WithUnicodeNormalization(cfg.NormalizeUnicode).WithPathRewrites(pathRewrites(cfg)).
+		WithRelativeMarkers(cfg.RelativePathMarkers())
+
+	ctx := context.Background()
+	if err := store.Initialize(ctx); err != nil {
+		log.Fatalf("Erreur initialisation DB: %v", err)
+	}
+
+	switch args[0] {
+	case "list":
+		paths, err := store.ListIgnoredPaths(ctx)
+		if err != nil {
+			log.Fatalf("Erreur lecture chemins ignorés: %v", err)
+		}
+		for _, p := range paths {
+			kind := "chemin"
+			if p.Pattern {
+				kind = "motif"
+			}
+			fmt.Printf("🙈 [%d] %s %s (%d fichiers, %s ; %d orphelins masqués, %s)\n", p.ID, kind, p.Path,
+				p.FileCount, formatSize(p.TotalSize), p.OrphanFiles, formatSize(p.OrphanSize))
+		}
+	case "add":
+		if len(args) < 2 {
+			log.Fatalf("Erreur: chemin manquant")
+		}
+		id, path, err := store.AddIgnoredPath(ctx, args[1])
+		if err != nil {
+			log.Fatalf("Erreur ajout chemin ignoré: %v", err)
+		}
+		fmt.Printf("✅ Chemin ignoré %d ajouté: %s\n", id, path)
+	case "add-pattern":
+		if len(args) < 2 {
+			log.Fatalf("Erreur: motif manquant")
+		}
+		id, pattern, err := store.AddIgnoredPattern(ctx, args[1])
+		if err != nil {
+			log.Fatalf("Erreur ajout motif ignoré: %v", err)
+		}
+		fmt.Printf("✅ Motif ignoré %d ajouté: %s\n", id, pattern)
+	case "remove":
+		if len(args) < 2 {
+			log.Fatalf("Erreur: identifiant manquant")
+		}
+		id, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			log.Fatalf("Erreur: identifiant invalide: %s", args[1])
+		}
+		if err := store.RemoveIgnoredPath(ctx, id); err != nil {
+			log.Fatalf("Erreur suppression chemin ignoré: %v", err)
+		}
+		fmt.Printf("✅ Chemin ignoré %d supprimé\n", id)
+	default:
+		fmt.Fprintf(os.Stderr, "Sous-commande inconnue: %s\n\n", args[0])
+		printIgnoreHelp()
+		os.Exit(1)
+	}
+}
+
+func printIgnoreHelp() {
+	fmt.Println("Usage: godatacleaner ignore <sous-commande>")
+	fmt.Println()
+	fmt.Println("Sous-commandes:")
+	fmt.Println("  list                 Lister les chemins et motifs ignorés")
+	fmt.Println("  add <chemin>         Ne plus compter un fichier ou un dossier comme orphelin (chemin absolu)")
+	fmt.Println("  add-pattern <motif>  Ne plus compter comme orphelins les chemins correspondant à un motif")
+	fmt.Println("                       (GLOB SQLite, absolu ou commençant par *, ex: '*/Extras', '*.nfo')")
+	fmt.Println("  remove <id>          Retirer un chemin ou un motif ignoré")
+}
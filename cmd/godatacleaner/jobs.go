@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"godatacleaner/internal/config"
+	"godatacleaner/internal/models"
+	"godatacleaner/internal/scheduler"
+	"godatacleaner/internal/storage"
+)
+
+func runJobs(args []string) {
+	if len(args) < 1 {
+		printJobsHelp()
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Erreur de configuration: %v", err)
+	}
+
+	store, err := storage.NewStorage(cfg.SQLitePath, cfg.SQLiteBatchSize)
+	if err != nil {
+		log.Fatalf("Erreur connexion SQLite: %v", err)
+	}
+	defer store.Close()
+	store.WithUnicodeNormalization(cfg.NormalizeUnicode).WithPathRewrites(pathRewrites(cfg)).
+		WithRelativeMarkers(cfg.RelativePathMarkers())
+
+	ctx := context.Background()
+	if err := store.Initialize(ctx); err != nil {
+		log.Fatalf("Erreur initialisation DB: %v", err)
+	}
+
+	switch args[0] {
+	case "list":
+		jobsList(ctx, store)
+	case "add":
+		jobsAdd(ctx, store, cfg, args[1:])
+	case "enable":
+		jobsSetEnabled(ctx, store, args[1:], true)
+	case "disable":
+		jobsSetEnabled(ctx, store, args[1:], false)
+	case "remove":
+		id := parseJobID(args[1:])
+		if err := store.DeleteCleanupJob(ctx, id); err != nil {
+			log.Fatalf("Erreur suppression tâche: %v", err)
+		}
+		fmt.Printf("✅ Tâche %d supprimée\n", id)
+	case "run":
+		jobsRun(ctx, store, cfg, args[1:])
+	case "history":
+		jobsHistory(ctx, store, args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Sous-commande inconnue: %s\n\n", args[0])
+		printJobsHelp()
+		os.Exit(1)
+	}
+}
+
+func jobsList(ctx context.Context, store *storage.Storage) {
+	jobs, err := store.ListCleanupJobs(ctx)
+	if err != nil {
+		log.Fatalf("Erreur lecture tâches: %v", err)
+	}
+	if len(jobs) == 0 {
+		fmt.Println("Aucune tâche de nettoyage")
+		return
+	}
+	for _, j := range jobs {
+		status := "✅"
+		if !j.Enabled {
+			status = "⏸️ "
+		}
+		lastRun := "jamais"
+		if j.LastRunAt != nil {
+			lastRun = j.LastRunAt.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Printf("%s [%d] %-20s %-16s catégorie=%q quarantaine=%t archive=%t compagnons=%t dry-run=%t dernière exécution: %s\n",
+			status, j.ID, j.Name, j.Schedule, j.Category, j.Quarantine, j.Archive, j.Companions, j.DryRun, lastRun)
+	}
+}
+
+func jobsAdd(ctx context.Context, store *storage.Storage, cfg *config.Config, args []string) {
+	fs := newFlagSet("jobs add")
+	name := fs.String("name", "", "Nom unique de la tâche")
+	schedule := fs.String("schedule", scheduler.AfterSync, "Expression cron (ex: \"0 3 * * *\") ou @after-sync")
+	category := fs.String("category", "", "Limiter à une catégorie")
+	quarantine := fs.Bool("quarantine", cfg.QuarantinePath != "", "Mettre en quarantaine au lieu de supprimer")
+	archive := fs.Bool("archive", false, "Déplacer vers l'archive (ARCHIVE_PATH) au lieu de supprimer")
+	companions := fs.Bool("companions", false, "Traiter aussi les fichiers compagnons (sous-titres, nfo, images)")
+	dryRun := fs.Bool("dry-run", false, "Simuler uniquement")
+	disabled := fs.Bool("disabled", false, "Créer la tâche désactivée")
+	fs.Parse(args)
+
+	if *name == "" {
+		log.Fatalf("Erreur: --name est obligatoire")
+	}
+	if err := scheduler.ValidateSchedule(*schedule); err != nil {
+		log.Fatalf("Erreur: %v", err)
+	}
+	if *archive {
+		if cfg.ArchivePath == "" {
+			log.Fatalf("Erreur: --archive nécessite ARCHIVE_PATH")
+		}
+		// L'archive remplace la quarantaine activée par défaut
+		fs.Visit(func(f *flag.Flag) {
+			if f.Name == "quarantine" && *quarantine {
+				log.Fatalf("Erreur: --quarantine et --archive sont incompatibles")
+			}
+		})
+		*quarantine = false
+	}
+	if *quarantine && cfg.QuarantinePath == "" {
+		log.Fatalf("Erreur: --quarantine nécessite QUARANTINE_PATH")
+	}
+
+	id, err := store.CreateCleanupJob(ctx, models.CleanupJob{
+		Name:       *name,
+		Schedule:   *schedule,
+		Category:   *category,
+		Quarantine: *quarantine,
+		Archive:    *archive,
+		Companions: *companions,
+		DryRun:     *dryRun,
+		Enabled:    !*disabled,
+	})
+	if err != nil {
+		log.Fatalf("Erreur création tâche: %v", err)
+	}
+	fmt.Printf("✅ Tâche %d créée\n", id)
+}
+
+func jobsSetEnabled(ctx context.Context, store *storage.Storage, args []string, enabled bool) {
+	id := parseJobID(args)
+	if err := store.SetCleanupJobEnabled(ctx, id, enabled); err != nil {
+		log.Fatalf("Erreur mise à jour tâche: %v", err)
+	}
+	if enabled {
+		fmt.Printf("✅ Tâche %d activée\n", id)
+	} else {
+		fmt.Printf("⏸️  Tâche %d désactivée\n", id)
+	}
+}
+
+func jobsRun(ctx context.Context, store *storage.Storage, cfg *config.Config, args []string) {
+	job, err := store.GetCleanupJob(ctx, parseJobID(args))
+	if err != nil {
+		log.Fatalf("Erreur lecture tâche: %v", err)
+	}
+
+	sched := scheduler.NewScheduler(store, newCleaner(store, cfg))
+	run, err := sched.RunJob(ctx, *job)
+	if err != nil {
+		log.Fatalf("Erreur exécution tâche: %v", err)
+	}
+	fmt.Printf("✅ Exécution %d: %d fichiers traités (%s), %d en erreur\n",
+		run.ID, run.FilesRemoved, formatSize(run.BytesReclaimed), run.Failed)
+}
+
+func jobsHistory(ctx context.Context, store *storage.Storage, args []string) {
+	fs := newFlagSet("jobs history")
+	jobID := fs.Int64("job", 0, "Filtrer par tâche")
+	limit := fs.Int("limit", 20, "Nombre d'exécutions à afficher")
+	files := fs.Int64("run", 0, "Afficher les fichiers traités par une exécution")
+	fs.Parse(args)
+
+	if *files != 0 {
+		runFiles, err := store.GetCleanupRunFiles(ctx, *files)
+		if err != nil {
+			log.Fatalf("Erreur lecture exécution: %v", err)
+		}
+		for _, f := range runFiles {
+			switch {
+			case f.Error != "":
+				fmt.Printf("   ❌ %s: %s\n", f.FilePath, f.Error)
+			case f.MovedTo != "":
+				fmt.Printf("   📦 %s → %s (%s)\n", f.FilePath, f.MovedTo, formatSize(f.Size))
+			default:
+				fmt.Printf("   🗑️  %s (%s)\n", f.FilePath, formatSize(f.Size))
+			}
+		}
+		return
+	}
+
+	runs, err := store.ListCleanupRuns(ctx, *jobID, *limit)
+	if err != nil {
+		log.Fatalf("Erreur lecture historique: %v", err)
+	}
+	for _, r := range runs {
+		mode := "suppression"
+		if r.Quarantine {
+			mode = "quarantaine"
+		}
+		if r.DryRun {
+			mode += " (dry-run)"
+		}
+		origin := fmt.Sprintf("tâche %d", r.JobID)
+		switch r.Source {
+		case models.CleanupSourceWeb:
+			origin = "WebUI " + r.User
+		case models.CleanupSourceCLI:
+			origin = "clean"
+		}
+		fmt.Printf("[%d] %s %s %s: %d fichiers (%s), %d erreurs %s\n",
+			r.ID, origin, r.StartedAt.Local().Format("2006-01-02 15:04"), mode,
+			r.FilesRemoved, formatSize(r.BytesReclaimed), r.Failed, r.Error)
+	}
+}
+
+func parseJobID(args []string) int64 {
+	if len(args) < 1 {
+		log.Fatalf("Erreur: identifiant de tâche manquant")
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		log.Fatalf("Erreur: identifiant de tâche invalide: %s", args[0])
+	}
+	return id
+}
+
+func printJobsHelp() {
+	fmt.Println("Usage: godatacleaner jobs <sous-commande>")
+	fmt.Println()
+	fmt.Println("Sous-commandes:")
+	fmt.Println("  list                 Lister les tâches de nettoyage")
+	fmt.Println("  add --name N [...]   Créer une tâche (--schedule, --category, --quarantine, --archive, --companions, --dry-run, --disabled)")
+	fmt.Println("  enable <id>          Activer une tâche")
+	fmt.Println("  disable <id>         Désactiver une tâche")
+	fmt.Println("  remove <id>          Supprimer une tâche")
+	fmt.Println("  run <id>             Exécuter une tâche immédiatement")
+	fmt.Println("  history [--job ID]   Historique des exécutions (--run ID pour le détail des fichiers)")
+}
@@ -2,34 +2,79 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
+	"godatacleaner/internal/cleaner"
 	"godatacleaner/internal/config"
 	"godatacleaner/internal/models"
-	"godatacleaner/internal/qbittorrent"
-	"godatacleaner/internal/scanner"
+	"godatacleaner/internal/scheduler"
 	"godatacleaner/internal/storage"
+	"godatacleaner/internal/syncer"
 	"godatacleaner/internal/web"
 )
 
 func main() {
-	if len(os.Args) < 2 {
+	// Les options de configuration sont acceptées avant la commande
+	// (godatacleaner --config /etc/gdc.json sync) comme après
+	fs := flag.NewFlagSet("godatacleaner", flag.ExitOnError)
+	addConfigFlags(fs)
+	fs.Usage = printHelp
+	fs.Parse(os.Args[1:])
+	if fs.NArg() == 0 {
 		printHelp()
 		os.Exit(0)
 	}
 
-	command := os.Args[1]
+	command, args := fs.Arg(0), fs.Args()[1:]
 	switch command {
 	case "sync":
-		runSync()
+		runSync(args)
 	case "web":
-		runWeb()
+		runWeb(args)
+	case "daemon":
+		runDaemon(args)
+	case "watch":
+		runWatch(args)
 	case "stats":
-		runStats()
+		runStats(args)
+	case "verify":
+		runVerify(args)
+	case "top":
+		runTop(args)
+	case "orphans":
+		runOrphans(args)
+	case "clean":
+		runClean(args)
+	case "purge":
+		runPurge(args)
+	case "simulate":
+		runSimulate(args)
+	case "jobs":
+		runJobs(args)
+	case "protect":
+		runProtect(args)
+	case "ignore":
+		runIgnore(args)
+	case "restore":
+		runRestore(args)
+	case "reannounce":
+		runReannounce(args)
+	case "users":
+		runUsers(args)
+	case "db":
+		runDB(args)
 	case "help":
 		printHelp()
 	default:
@@ -39,7 +84,25 @@ func main() {
 	}
 }
 
-func runSync() {
+func runSync(args []string) {
+	if len(args) > 0 && args[0] == "history" {
+		runSyncHistory(args[1:])
+		return
+	}
+	if len(args) > 0 && args[0] == "diff" {
+		runSyncDiff(args[1:])
+		return
+	}
+	if len(args) > 0 && args[0] == "errors" {
+		runSyncErrors(args[1:])
+		return
+	}
+
+	fs := newFlagSet("sync")
+	full := fs.Bool("full", false, "Récupérer les fichiers de tous les torrents, pas seulement des torrents ajoutés ou modifiés, et relire tous les répertoires locaux")
+	asJSON := fs.Bool("json", false, "Afficher un résumé JSON (compteurs, durées, erreurs) au lieu de la progression")
+	fs.Parse(args)
+
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("Erreur de configuration: %v", err)
@@ -56,85 +119,189 @@ func runSync() {
 		log.Fatalf("Erreur connexion SQLite: %v", err)
 	}
 	defer store.Close()
+	store.WithUnicodeNormalization(cfg.NormalizeUnicode).WithPathRewrites(pathRewrites(cfg)).
+		WithRelativeMarkers(cfg.RelativePathMarkers())
 
 	ctx := context.Background()
 	if err := store.Initialize(ctx); err != nil {
 		log.Fatalf("Erreur initialisation DB: %v", err)
 	}
 
-	// Sync qBittorrent
-	log.Println("🔄 Synchronisation qBittorrent...")
-	qbtClient, err := qbittorrent.NewClient(cfg.QBittorrentURL(), cfg.QBittorrentUsername, cfg.QBittorrentPassword, cfg.QBittorrentMaxWorkers)
+	// Ctrl-C interrompt la synchronisation sans laisser la base à moitié vidée
+	syncCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("🔄 Synchronisation des clients torrents (%s)...", strings.Join(cfg.TorrentClients, ", "))
+	sched := scheduler.NewScheduler(store, newCleaner(store, cfg))
+	progress := printSyncProgress()
+	timer := &stageTimer{}
+	if *asJSON {
+		// Seul le résumé est écrit sur la sortie standard
+		progress = timer.progress
+	}
+	started := time.Now()
+	result, err := syncer.NewSyncer(store, cfg).WithScheduler(sched).WithFullSync(*full).Run(syncCtx, progress)
+	if *asJSON {
+		printSyncSummary(started, *full, timer, result, err)
+		return
+	}
+	if errors.Is(err, context.Canceled) {
+		fmt.Println()
+		log.Fatalf("⏹️  Synchronisation annulée, la base n'a pas été modifiée")
+	}
+	if errors.Is(err, syncer.ErrSyncRunning) {
+		log.Fatalf("⛔ Une synchronisation est déjà en cours (CLI ou serveur web)")
+	}
 	if err != nil {
-		log.Fatalf("Erreur création client qBittorrent: %v", err)
+		log.Fatalf("Erreur synchronisation: %v", err)
 	}
-
-	if err := qbtClient.Login(ctx); err != nil {
-		log.Printf("⚠️  Impossible de se connecter à qBittorrent: %v", err)
+	if result.TorrentsSynced {
+		fmt.Printf("✅ %d fichiers torrents synchronisés (%d torrents rafraîchis)\n", result.TorrentFiles, result.TorrentsRefreshed)
+		printChanges(result.TorrentChanges)
+		if result.TorrentsFailed > 0 {
+			fmt.Printf("⚠️  %d torrents n'ont pas pu être lus, leurs fichiers de la dernière synchronisation sont conservés (sync errors)\n", result.TorrentsFailed)
+		}
+	}
+	if result.LocalSynced {
+		fmt.Printf("✅ %d fichiers locaux synchronisés\n", result.LocalFiles)
+		printChanges(result.LocalChanges)
 	} else {
-		// Clear et sync torrents
-		if err := store.ClearTorrentFiles(ctx); err != nil {
-			log.Fatalf("Erreur clear torrent_files: %v", err)
+		fmt.Println("⚠️  Fichiers locaux non synchronisés, ceux de la dernière synchronisation sont conservés")
+	}
+	if result.Checksummed > 0 || result.ChecksumFailed > 0 {
+		fmt.Printf("🔐 %d empreintes calculées\n", result.Checksummed)
+		if result.ChecksumFailed > 0 {
+			fmt.Printf("⚠️  %d fichiers n'ont pas pu être lus, leur empreinte sera calculée à la prochaine synchronisation\n", result.ChecksumFailed)
 		}
+	}
 
-		torrents, err := qbtClient.GetTorrents(ctx)
-		if err != nil {
-			log.Printf("⚠️  Erreur récupération torrents: %v", err)
-		} else {
-			total := len(torrents)
-			fmt.Printf("📦 %d torrents trouvés\n", total)
-			var allFiles []models.TorrentFile
-			for i, t := range torrents {
-				files, err := qbtClient.GetTorrentFiles(ctx, t.Hash)
-				if err != nil {
-					continue
+	fmt.Println("🎉 Synchronisation terminée!")
+}
+
+// printChanges prints the rows written by a sync of a table.
+func printChanges(c storage.Changes) {
+	fmt.Printf("   %d ajoutés, %d modifiés, %d supprimés\n", c.Inserted, c.Updated, c.Deleted)
+}
+
+// printSyncProgress returns a progress callback printing the sync steps,
+// with the counters refreshed on a single line.
+func printSyncProgress() func(syncer.Progress) {
+	var last syncer.Stage
+	return func(p syncer.Progress) {
+		changed := p.Stage != last
+		if changed && (last == syncer.StageTorrents || last == syncer.StageScan || last == syncer.StageChecksum) {
+			fmt.Println() // New line after progress
+		}
+		last = p.Stage
+
+		switch p.Stage {
+		case syncer.StageTorrents:
+			if changed {
+				fmt.Printf("📦 %d torrents trouvés\n", p.TorrentsTotal)
+			}
+			if p.TorrentsTotal > 0 {
+				percent := float64(p.TorrentsDone) / float64(p.TorrentsTotal) * 100
+				fmt.Printf("\r⏳ Progression: %d/%d (%.1f%%) - %d fichiers", p.TorrentsDone, p.TorrentsTotal, percent, p.TorrentFiles)
+			}
+		case syncer.StageScan:
+			if changed {
+				fmt.Println("🔄 Scan des fichiers locaux...")
+			}
+			if sp := p.Scan; sp != nil {
+				line := fmt.Sprintf("⏳ Scan: %d fichiers trouvés (%s), %.0f fichiers/s", sp.Files, formatSize(sp.Bytes), sp.Rate)
+				if sp.ETASeconds > 0 {
+					line += fmt.Sprintf(", reste ~%s", (time.Duration(sp.ETASeconds) * time.Second).String())
 				}
-				allFiles = append(allFiles, files...)
-				// Progress on single line
-				percent := float64(i+1) / float64(total) * 100
-				fmt.Printf("\r⏳ Progression: %d/%d (%.1f%%) - %d fichiers", i+1, total, percent, len(allFiles))
+				if sp.CurrentDir != "" && !sp.Done {
+					line += " - " + shortenPath(sp.CurrentDir, 40)
+				}
+				// Efface la fin de la ligne précédente, plus longue
+				fmt.Printf("\r%s\033[K", line)
 			}
-			fmt.Println() // New line after progress
-			if err := store.InsertTorrentFiles(ctx, allFiles); err != nil {
-				log.Fatalf("Erreur insertion fichiers torrents: %v", err)
+		case syncer.StageInsert:
+			if changed {
+				fmt.Printf("💾 Insertion de %d fichiers en base...\n", p.InsertTotal)
+			}
+		case syncer.StageChecksum:
+			if changed {
+				fmt.Printf("🔐 Calcul des empreintes de %d fichiers...\n", p.ChecksumTotal)
+			}
+			if p.ChecksumTotal > 0 {
+				percent := float64(p.Checksummed) / float64(p.ChecksumTotal) * 100
+				fmt.Printf("\r⏳ Empreintes: %d/%d (%.1f%%)", p.Checksummed, p.ChecksumTotal, percent)
 			}
-			fmt.Printf("✅ %d fichiers torrents synchronisés\n", len(allFiles))
 		}
 	}
+}
 
-	// Sync local
-	fmt.Println("🔄 Scan des fichiers locaux...")
-	if err := store.ClearLocalFiles(ctx); err != nil {
-		log.Fatalf("Erreur clear local_files: %v", err)
-	}
+func runWeb(args []string) {
+	newFlagSet("web").Parse(args)
+	serve(false)
+}
 
-	scan := scanner.NewScanner(cfg.LocalPath)
-	filesChan, errsChan := scan.Scan(ctx)
+// runDaemon runs the web server, the scheduled cleanup jobs and the syncs
+// scheduled by SYNC_SCHEDULE in a single process.
+func runDaemon(args []string) {
+	newFlagSet("daemon").Parse(args)
+	serve(true)
+}
 
-	var localFiles []models.LocalFile
-	count := 0
-	for f := range filesChan {
-		localFiles = append(localFiles, f)
-		count++
-		if count%100 == 0 {
-			fmt.Printf("\r⏳ Scan: %d fichiers trouvés", count)
-		}
+// serve runs the web server and the scheduled cleanup jobs, and the syncs
+// scheduled by SYNC_SCHEDULE when daemon is set.
+func serve(daemon bool) {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Erreur de configuration: %v", err)
 	}
-	fmt.Println() // New line after progress
-	if err := <-errsChan; err != nil {
-		log.Printf("⚠️  Erreur scan: %v", err)
+
+	store, err := storage.NewStorage(cfg.SQLitePath, cfg.SQLiteBatchSize)
+	if err != nil {
+		log.Fatalf("Erreur connexion SQLite: %v", err)
+	}
+	defer store.Close()
+	store.WithUnicodeNormalization(cfg.NormalizeUnicode).WithPathRewrites(pathRewrites(cfg)).
+		WithRelativeMarkers(cfg.RelativePathMarkers()).
+		WithCountCache(time.Duration(cfg.CountCacheSeconds) * time.Second)
+
+	ctx := context.Background()
+	if err := store.Initialize(ctx); err != nil {
+		log.Fatalf("Erreur initialisation DB: %v", err)
 	}
 
-	fmt.Printf("💾 Insertion de %d fichiers en base...\n", len(localFiles))
-	if err := store.InsertLocalFiles(ctx, localFiles); err != nil {
-		log.Fatalf("Erreur insertion fichiers locaux: %v", err)
+	// Exécuter les tâches de nettoyage planifiées en arrière-plan
+	sched := scheduler.NewScheduler(store, newCleaner(store, cfg))
+	go sched.Start(ctx)
+
+	syn := syncer.NewSyncer(store, cfg).WithScheduler(sched)
+	if cfg.ScanWatch {
+		go watchLocalFiles(ctx, syn)
 	}
-	fmt.Printf("✅ %d fichiers locaux synchronisés\n", len(localFiles))
 
-	fmt.Println("🎉 Synchronisation terminée!")
+	server := web.NewServer(store, cfg.LocalHost, cfg.LocalPort).
+		WithCleaner(newCleaner(store, cfg)).
+		WithSyncer(syn).
+		WithAuth(cfg.AuthUsername, cfg.AuthPassword, cfg.AuthToken).
+		WithCORS(cfg.CORSAllowedOrigins).
+		WithSettings(cfg, config.Path(), func(next *config.Config) {
+			// Les chemins relatifs déjà en base sont recalculés au prochain sync
+			store.WithPathRewrites(pathRewrites(next)).WithRelativeMarkers(next.RelativePathMarkers())
+			syn.SetConfig(next)
+		})
+	if daemon {
+		go scheduleSyncs(ctx, server, cfg.SyncSchedule)
+	}
+	if users, err := store.CountUsers(ctx); err == nil && users == 0 && !cfg.AuthEnabled() {
+		log.Printf("⚠️  Authentification désactivée: créer un utilisateur (users add) ou définir AUTH_USERNAME/AUTH_PASSWORD ou AUTH_TOKEN")
+	}
+	log.Printf("🌐 Démarrage du serveur sur http://%s:%d", cfg.LocalHost, cfg.LocalPort)
+	if err := server.Start(); err != nil {
+		log.Fatalf("Erreur serveur: %v", err)
+	}
 }
 
-func runWeb() {
+func runWatch(args []string) {
+	newFlagSet("watch").Parse(args)
+
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("Erreur de configuration: %v", err)
@@ -145,20 +312,101 @@ func runWeb() {
 		log.Fatalf("Erreur connexion SQLite: %v", err)
 	}
 	defer store.Close()
+	store.WithUnicodeNormalization(cfg.NormalizeUnicode).WithPathRewrites(pathRewrites(cfg)).
+		WithRelativeMarkers(cfg.RelativePathMarkers())
 
 	ctx := context.Background()
 	if err := store.Initialize(ctx); err != nil {
 		log.Fatalf("Erreur initialisation DB: %v", err)
 	}
 
-	server := web.NewServer(store, cfg.LocalHost, cfg.LocalPort)
-	log.Printf("🌐 Démarrage du serveur sur http://%s:%d", cfg.LocalHost, cfg.LocalPort)
-	if err := server.Start(); err != nil {
-		log.Fatalf("Erreur serveur: %v", err)
+	watchCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	watchLocalFiles(watchCtx, syncer.NewSyncer(store, cfg))
+}
+
+// scheduleSyncs starts a sync through server on every activation of schedule,
+// so that the WebUI follows it as a sync started from the API.
+func scheduleSyncs(ctx context.Context, server *web.Server, schedule string) {
+	if schedule == "" {
+		log.Printf("⚠️  Aucune synchronisation planifiée: définir SYNC_SCHEDULE (ex: \"0 */6 * * *\")")
+		return
 	}
+	log.Printf("⏰ Synchronisations planifiées: %s", schedule)
+	err := scheduler.Every(ctx, schedule, func(context.Context) {
+		// Une synchronisation lancée depuis la WebUI ou la CLI n'est pas doublée
+		if !server.StartSync() {
+			log.Printf("⚠️  Synchronisation planifiée ignorée: une synchronisation est déjà en cours")
+			return
+		}
+		log.Printf("🔄 Synchronisation planifiée démarrée")
+	})
+	if err != nil {
+		log.Printf("⚠️  Synchronisations planifiées désactivées: %v", err)
+	}
+}
+
+// watchLocalFiles applies the changes under LOCAL_PATH to local_files until
+// ctx is done or the directory cannot be watched.
+func watchLocalFiles(ctx context.Context, syn *syncer.Syncer) {
+	log.Printf("👁️  Surveillance des fichiers locaux...")
+	if err := syn.Watch(ctx); err != nil && ctx.Err() == nil {
+		log.Printf("⚠️  Surveillance des fichiers locaux arrêtée: %v", err)
+	}
+}
+
+// statsJSON is the output of stats --json.
+type statsJSON struct {
+	Torrents     models.TorrentStatsResponse `json:"torrents"`
+	Local        []models.CategoryStats      `json:"local"`
+	Orphans      statsTotals                 `json:"orphans"`
+	InProgress   statsTotals                 `json:"in_progress"`
+	Extras       statsExtras                 `json:"extras"`
+	Extensions   []models.ExtensionStats     `json:"extensions"`
+	DeadTorrents *cleaner.DeadTorrentsReport `json:"dead_torrents"`
+	Missing      *cleaner.MissingReport      `json:"missing"`
+	Duplicates   *statsDuplicates            `json:"duplicates,omitempty"` // With CHECKSUM only
+}
+
+type statsTotals struct {
+	Categories    []models.CategoryStats `json:"categories"`
+	FileCount     int64                  `json:"file_count"`
+	TotalSize     int64                  `json:"total_size"`
+	AllocatedSize int64                  `json:"allocated_size"`
+}
+
+// newStatsTotals sums the stats of categories.
+func newStatsTotals(categories []models.CategoryStats) statsTotals {
+	t := statsTotals{Categories: categories}
+	if t.Categories == nil {
+		t.Categories = []models.CategoryStats{}
+	}
+	for _, s := range categories {
+		t.FileCount += s.FileCount
+		t.TotalSize += s.TotalSize
+		t.AllocatedSize += s.AllocatedSize
+	}
+	return t
+}
+
+type statsExtras struct {
+	Categories  []models.CategoryStats `json:"categories"`
+	TotalFiles  int64                  `json:"total_files"`
+	TotalSize   int64                  `json:"total_size"`
+	OrphanFiles int64                  `json:"orphan_files"`
+	OrphanBytes int64                  `json:"orphan_bytes"`
 }
 
-func runStats() {
+type statsDuplicates struct {
+	Groups      []models.DuplicateGroup `json:"groups"`
+	WastedBytes int64                   `json:"wasted_bytes"`
+}
+
+func runStats(args []string) {
+	fs := newFlagSet("stats")
+	asJSON := fs.Bool("json", false, "Afficher les statistiques en JSON")
+	fs.Parse(args)
+
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("Erreur de configuration: %v", err)
@@ -169,6 +417,8 @@ func runStats() {
 		log.Fatalf("Erreur connexion SQLite: %v", err)
 	}
 	defer store.Close()
+	store.WithUnicodeNormalization(cfg.NormalizeUnicode).WithPathRewrites(pathRewrites(cfg)).
+		WithRelativeMarkers(cfg.RelativePathMarkers())
 
 	ctx := context.Background()
 
@@ -190,6 +440,87 @@ func runStats() {
 		log.Fatalf("Erreur stats orphelins: %v", err)
 	}
 
+	// Fichiers en cours de téléchargement, ni orphelins ni sains
+	inProgress, err := store.GetInProgressStats(ctx)
+	if err != nil {
+		log.Fatalf("Erreur stats téléchargements: %v", err)
+	}
+
+	// Samples, extras et fichiers parasites, orphelins ou non
+	c := newCleaner(store, cfg)
+	extras, err := c.Extras(ctx)
+	if err != nil {
+		log.Fatalf("Erreur stats extras: %v", err)
+	}
+
+	// Extensions qui occupent le plus d'espace, toutes catégories confondues
+	extensions, err := store.GetExtensionStats(ctx, "")
+	if err != nil {
+		log.Fatalf("Erreur stats extensions: %v", err)
+	}
+
+	// Torrents désenregistrés de leur tracker
+	dead, err := c.DeadTorrents(ctx)
+	if err != nil {
+		log.Fatalf("Erreur stats torrents morts: %v", err)
+	}
+
+	// Torrents dont les fichiers ne sont plus sur le disque, l'inverse des orphelins
+	missing, err := c.Missing(ctx)
+	if err != nil {
+		log.Fatalf("Erreur stats fichiers manquants: %v", err)
+	}
+
+	// Fichiers de même contenu, d'après les empreintes des synchronisations
+	var duplicates *statsDuplicates
+	if cfg.Checksum != "" {
+		groups, err := syncer.NewSyncer(store, cfg).Duplicates(ctx)
+		if err != nil {
+			log.Fatalf("Erreur stats doublons: %v", err)
+		}
+		duplicates = &statsDuplicates{Groups: groups}
+		if groups == nil {
+			duplicates.Groups = []models.DuplicateGroup{}
+		}
+		for _, g := range groups {
+			duplicates.WastedBytes += g.WastedBytes
+		}
+	}
+
+	orphans := newStatsTotals(orphanStats)
+	downloading := newStatsTotals(inProgress)
+
+	if *asJSON {
+		out := statsJSON{
+			Torrents: models.TorrentStatsResponse{
+				TotalFiles: torrentStats.TotalFiles, TotalTorrents: torrentStats.TotalTorrents, TotalSize: torrentStats.TotalSize,
+			},
+			Local:      localStats,
+			Orphans:    orphans,
+			InProgress: downloading,
+			Extras: statsExtras{
+				Categories: extras.Categories, TotalFiles: extras.TotalFiles, TotalSize: extras.TotalSize,
+				OrphanFiles: extras.OrphanFiles, OrphanBytes: extras.OrphanBytes,
+			},
+			Extensions:   extensions,
+			DeadTorrents: dead,
+			Missing:      missing,
+			Duplicates:   duplicates,
+		}
+		if out.Local == nil {
+			out.Local = []models.CategoryStats{}
+		}
+		if out.Extensions == nil {
+			out.Extensions = []models.ExtensionStats{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(out); err != nil {
+			log.Fatalf("Erreur écriture: %v", err)
+		}
+		return
+	}
+
 	fmt.Println("📊 Statistiques GoDataCleaner")
 	fmt.Println("═══════════════════════════════")
 	fmt.Println()
@@ -200,18 +531,406 @@ func runStats() {
 	fmt.Println()
 	fmt.Println("💾 Fichiers locaux:")
 	for _, s := range localStats {
-		fmt.Printf("   %s: %d fichiers (%s)\n", s.Category, s.FileCount, formatSize(s.TotalSize))
+		fmt.Printf("   %s: %d fichiers (%s, %s sur disque)\n", s.Category, s.FileCount, formatSize(s.TotalSize), formatSize(s.AllocatedSize))
 	}
 	fmt.Println()
 	fmt.Println("🗑️  Orphelins:")
-	var totalOrphans int64
-	var totalOrphanSize int64
 	for _, s := range orphanStats {
+		fmt.Printf("   %s: %d fichiers (%s, %s sur disque)\n", s.Category, s.FileCount, formatSize(s.TotalSize), formatSize(s.AllocatedSize))
+	}
+	fmt.Printf("   Total: %d fichiers (%s), %s récupérables\n", orphans.FileCount, formatSize(orphans.TotalSize), formatSize(orphans.AllocatedSize))
+
+	fmt.Println()
+	fmt.Printf("⏳ En téléchargement: %d fichiers (%s)\n", downloading.FileCount, formatSize(downloading.TotalSize))
+
+	fmt.Println()
+	fmt.Println("🎞️  Samples et extras:")
+	for _, s := range extras.Categories {
 		fmt.Printf("   %s: %d fichiers (%s)\n", s.Category, s.FileCount, formatSize(s.TotalSize))
-		totalOrphans += s.FileCount
-		totalOrphanSize += s.TotalSize
 	}
-	fmt.Printf("   Total: %d fichiers (%s)\n", totalOrphans, formatSize(totalOrphanSize))
+	fmt.Printf("   Total: %d fichiers (%s), dont %d orphelins (%s)\n",
+		extras.TotalFiles, formatSize(extras.TotalSize), extras.OrphanFiles, formatSize(extras.OrphanBytes))
+
+	fmt.Println()
+	fmt.Println("🧩 Par extension:")
+	for i, e := range extensions {
+		if i == 10 {
+			fmt.Printf("   ... et %d autres\n", len(extensions)-i)
+			break
+		}
+		name := "." + e.Extension
+		if e.Extension == "no_extension" {
+			name = "sans extension"
+		}
+		fmt.Printf("   %s: %d fichiers (%s), dont %d orphelins (%s)\n",
+			name, e.FileCount, formatSize(e.TotalSize), e.OrphanFiles, formatSize(e.OrphanSize))
+	}
+
+	fmt.Println()
+	fmt.Println("💀 Torrents morts:")
+	for _, t := range dead.Torrents {
+		fmt.Printf("   %s (%s, %s): %s\n", t.Name, t.Instance, formatSize(t.Size), t.TrackerMessage)
+	}
+	fmt.Printf("   Total: %d torrents (%s), %d fichiers récupérables (%s)\n",
+		len(dead.Torrents), formatSize(dead.TotalSize), dead.ReclaimableFiles, formatSize(dead.ReclaimableBytes))
+
+	fmt.Println()
+	fmt.Println("🔍 Torrents incomplets sur le disque:")
+	for i, t := range missing.Torrents {
+		if i == 20 {
+			fmt.Printf("   ... et %d autres\n", len(missing.Torrents)-i)
+			break
+		}
+		fmt.Printf("   %s (%s): %.1f%% présent, %d/%d fichiers manquants (%s)",
+			t.Name, t.Instance, t.Completeness, t.MissingFiles, t.FileCount, formatSize(t.MissingBytes))
+		if t.MismatchedFiles > 0 {
+			fmt.Printf(", %d de taille différente (%s)", t.MismatchedFiles, formatSize(t.MismatchedBytes))
+		}
+		fmt.Println()
+	}
+	fmt.Printf("   Total: %d torrents, %d fichiers manquants (%s), %d de taille différente (%s)\n",
+		len(missing.Torrents), missing.MissingFiles, formatSize(missing.MissingBytes),
+		missing.MismatchedFiles, formatSize(missing.MismatchedBytes))
+
+	if duplicates == nil {
+		return
+	}
+	fmt.Println()
+	fmt.Println("👯 Doublons:")
+	for i, g := range duplicates.Groups {
+		if i == 20 {
+			fmt.Printf("   ... et %d autres\n", len(duplicates.Groups)-i)
+			break
+		}
+		fmt.Printf("   %d copies de %s (%s perdus):\n", g.Copies, formatSize(g.Size), formatSize(g.WastedBytes))
+		for _, f := range g.Files {
+			fmt.Printf("      %s\n", f.FilePath)
+		}
+	}
+	fmt.Printf("   Total: %d groupes de doublons, %s récupérables\n", len(duplicates.Groups), formatSize(duplicates.WastedBytes))
+}
+
+func runClean(args []string) {
+	fs := newFlagSet("clean")
+	dryRun := fs.Bool("dry-run", false, "Afficher les fichiers qui seraient supprimés sans rien supprimer")
+	category := fs.String("category", "", "Limiter le nettoyage à une catégorie (4k, movies, shows, unknown ou selon CATEGORY_RULES)")
+	quarantine := fs.Bool("quarantine", false, "Déplacer les fichiers en quarantaine au lieu de les supprimer (défaut si QUARANTINE_PATH est défini)")
+	archive := fs.Bool("archive", false, "Déplacer les fichiers vers l'archive (ARCHIVE_PATH) au lieu de les supprimer")
+	companions := fs.Bool("companions", false, "Traiter aussi les fichiers compagnons des vidéos (sous-titres, nfo, images)")
+	recheck := fs.Bool("recheck", false, "Forcer la revérification des torrents attendant les fichiers traités")
+	dead := fs.Bool("dead", false, "Traiter les fichiers des torrents morts (désenregistrés du tracker) au lieu des orphelins")
+	minAge := fs.Int("min-age", 0, "Ne traiter que les fichiers modifiés il y a au moins N jours")
+	orphanedFor := fs.Int("orphaned-for", 0, "Ne traiter que les fichiers orphelins depuis au moins N jours")
+	excludeHardlinked := fs.Bool("exclude-hardlinked", false, "Ignorer les fichiers ayant d'autres liens physiques, dont la suppression ne libère rien")
+	var minSize sizeFlag
+	fs.Var(&minSize, "min-size", "Ne traiter que les fichiers d'au moins cette taille (ex: 500MB, 1GB)")
+	var olderThan ageFlag
+	fs.Var(&olderThan, "older-than", "Ne traiter que les fichiers modifiés il y a au moins cette durée (ex: 30d, 2w), comme --min-age")
+	yes := fs.Bool("yes", false, "Ne pas demander de confirmation avant de traiter les fichiers")
+	fs.Parse(args)
+
+	if *minAge > 0 && olderThan > 0 {
+		log.Fatalf("Erreur: --min-age et --older-than sont incompatibles")
+	}
+	if *minAge > 0 {
+		olderThan = ageFlag(time.Duration(*minAge) * 24 * time.Hour)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Erreur de configuration: %v", err)
+	}
+
+	// La quarantaine est le mode par défaut dès qu'un répertoire est configuré,
+	// sauf si l'archive est demandée
+	useQuarantine := cfg.QuarantinePath != "" && !*archive
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "quarantine" {
+			useQuarantine = *quarantine
+		}
+	})
+
+	store, err := storage.NewStorage(cfg.SQLitePath, cfg.SQLiteBatchSize)
+	if err != nil {
+		log.Fatalf("Erreur connexion SQLite: %v", err)
+	}
+	defer store.Close()
+	store.WithUnicodeNormalization(cfg.NormalizeUnicode).WithPathRewrites(pathRewrites(cfg)).
+		WithRelativeMarkers(cfg.RelativePathMarkers())
+
+	ctx := context.Background()
+	if err := store.Initialize(ctx); err != nil {
+		log.Fatalf("Erreur initialisation DB: %v", err)
+	}
+
+	files := "fichiers orphelins"
+	if *dead {
+		files = "fichiers des torrents morts"
+	}
+	opts := cleaner.Options{
+		DryRun:            *dryRun,
+		Category:          *category,
+		Quarantine:        useQuarantine,
+		Archive:           *archive,
+		Companions:        *companions,
+		DeadTorrents:      *dead,
+		MinAge:            time.Duration(olderThan),
+		MinOrphanAge:      time.Duration(*orphanedFor) * 24 * time.Hour,
+		MinSize:           int64(minSize),
+		ExcludeHardlinked: *excludeHardlinked,
+	}
+	c := newCleaner(store, cfg)
+
+	// Sans --yes, les fichiers concernés sont listés par une simulation avant
+	// de demander confirmation, puis seuls ces fichiers sont traités : une
+	// synchronisation entre-temps n'en ajoute pas. Les compagnons sont
+	// retrouvés à partir de leur vidéo
+	var confirmed []string
+	if !*dryRun {
+		if err := c.CheckLastSync(ctx); err != nil {
+			log.Fatalf("Erreur nettoyage: %v", err)
+		}
+	}
+	if !*dryRun && !*yes {
+		preview := opts
+		preview.DryRun = true
+		report, err := c.Run(ctx, preview)
+		if err != nil {
+			log.Fatalf("Erreur nettoyage: %v", err)
+		}
+		printCleanActions(report)
+		printCleanSummary(report, cfg)
+		for _, a := range report.Actions {
+			if a.CompanionOf == "" && !a.Protected && a.Error == "" {
+				confirmed = append(confirmed, a.File.FilePath)
+			}
+		}
+		if len(confirmed) == 0 {
+			return
+		}
+		if !confirm("Continuer ?") {
+			fmt.Println("❌ Nettoyage annulé")
+			return
+		}
+		fmt.Println()
+	}
+
+	if *dryRun {
+		fmt.Println("🔍 Simulation du nettoyage (dry-run), aucun fichier ne sera supprimé")
+	} else if useQuarantine {
+		fmt.Printf("📦 Mise en quarantaine des %s dans %s...\n", files, cfg.QuarantinePath)
+	} else if *archive {
+		fmt.Printf("🗄️  Archivage des %s dans %s...\n", files, cfg.ArchivePath)
+	} else {
+		fmt.Printf("🧹 Nettoyage des %s...\n", files)
+	}
+
+	started := time.Now()
+	var report *cleaner.Report
+	if confirmed != nil {
+		report, err = c.RunFiles(ctx, confirmed, opts)
+	} else {
+		report, err = c.Run(ctx, opts)
+	}
+	if err != nil {
+		log.Fatalf("Erreur nettoyage: %v", err)
+	}
+
+	// Historique des suppressions, sans les simulations
+	if !report.DryRun {
+		run := models.CleanupRun{
+			Source:         models.CleanupSourceCLI,
+			StartedAt:      started,
+			FinishedAt:     time.Now(),
+			Quarantine:     report.Quarantine,
+			FilesRemoved:   report.FilesRemoved,
+			BytesReclaimed: report.BytesReclaimed,
+			Failed:         report.Failed,
+		}
+		if _, err := store.RecordCleanupRun(ctx, run, report.Files()); err != nil {
+			log.Printf("⚠️  Erreur enregistrement historique: %v", err)
+		}
+	}
+	if confirmed != nil {
+		// Les fichiers ont déjà été listés avant la confirmation, seuls ceux
+		// en erreur le sont à nouveau
+		for _, a := range report.Actions {
+			if a.Error != "" {
+				fmt.Printf("   ❌ %s: %s\n", a.File.FilePath, a.Error)
+			}
+		}
+	} else {
+		printCleanActions(report)
+	}
+	printCleanSummary(report, cfg)
+
+	if *recheck && !report.DryRun {
+		var paths []string
+		for _, a := range report.Actions {
+			if a.Error == "" && !a.Protected {
+				paths = append(paths, a.File.FilePath)
+			}
+		}
+		recheckTorrents(ctx, store, cfg, paths)
+	}
+}
+
+// printCleanActions prints the files handled by a cleanup run.
+func printCleanActions(report *cleaner.Report) {
+	for _, a := range report.Actions {
+		if a.Error != "" {
+			fmt.Printf("   ❌ %s: %s\n", a.File.FilePath, a.Error)
+			continue
+		}
+		if a.Protected {
+			fmt.Printf("   🛡️  %s (protégé)\n", a.DiskPath)
+			continue
+		}
+		prefix := "   "
+		if a.CompanionOf != "" {
+			prefix = "      ↳ "
+		}
+		if a.MovedTo != "" {
+			fmt.Printf("%s📦 %s → %s (%s)\n", prefix, a.DiskPath, a.MovedTo, formatSize(a.File.Size))
+			continue
+		}
+		fmt.Printf("%s🗑️  %s (%s)\n", prefix, a.DiskPath, formatSize(a.File.Size))
+	}
+}
+
+// printCleanSummary prints the number of files handled by a cleanup run, and
+// the space reclaimed.
+func printCleanSummary(report *cleaner.Report, cfg *config.Config) {
+	fmt.Println()
+	switch {
+	case report.DryRun && report.Quarantine:
+		fmt.Printf("📋 %d fichiers seraient mis en quarantaine (%s récupérables)\n", report.FilesRemoved, formatSize(report.BytesReclaimed))
+	case report.DryRun && report.Archive:
+		fmt.Printf("📋 %d fichiers seraient archivés (%s récupérables)\n", report.FilesRemoved, formatSize(report.BytesReclaimed))
+	case report.DryRun:
+		fmt.Printf("📋 %d fichiers seraient supprimés (%s récupérables)\n", report.FilesRemoved, formatSize(report.BytesReclaimed))
+	case report.Quarantine:
+		fmt.Printf("✅ %d fichiers mis en quarantaine (%s, purge dans %d jours)\n", report.FilesRemoved, formatSize(report.BytesReclaimed), cfg.QuarantineRetention)
+	case report.Archive:
+		fmt.Printf("✅ %d fichiers archivés dans %s (%s récupérés)\n", report.FilesRemoved, cfg.ArchivePath, formatSize(report.BytesReclaimed))
+	default:
+		fmt.Printf("✅ %d fichiers supprimés (%s récupérés)\n", report.FilesRemoved, formatSize(report.BytesReclaimed))
+	}
+	if report.Protected > 0 {
+		fmt.Printf("🛡️  %d fichiers protégés ignorés\n", report.Protected)
+	}
+	if report.Failed > 0 {
+		fmt.Printf("⚠️  %d fichiers en erreur\n", report.Failed)
+	}
+}
+
+// confirm asks question on the standard error and reports whether the answer
+// read from the standard input is yes.
+func confirm(question string) bool {
+	fmt.Fprintf(os.Stderr, "%s [o/N] ", question)
+	line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "o", "oui", "y", "yes":
+		return true
+	}
+	return false
+}
+
+// recheckTorrents forces a recheck of the torrents expecting any of the given
+// local files, so that the torrent clients notice they were moved.
+func recheckTorrents(ctx context.Context, store *storage.Storage, cfg *config.Config, paths []string) {
+	report, err := syncer.NewSyncer(store, cfg).RecheckPaths(ctx, paths)
+	if err != nil {
+		log.Fatalf("Erreur revérification des torrents: %v", err)
+	}
+	for _, a := range report.Actions {
+		if a.Error != "" {
+			fmt.Printf("   ❌ %s (%s): %s\n", a.Torrent.Name, a.Torrent.Instance, a.Error)
+		} else {
+			fmt.Printf("   🔁 %s (%s)\n", a.Torrent.Name, a.Torrent.Instance)
+		}
+	}
+	fmt.Printf("🔁 %d torrents revérifiés\n", report.Rechecked)
+	if report.Failed > 0 {
+		fmt.Printf("⚠️  %d torrents en erreur\n", report.Failed)
+	}
+}
+
+func runPurge(args []string) {
+	fs := newFlagSet("purge")
+	dryRun := fs.Bool("dry-run", false, "Afficher les lots qui seraient purgés sans rien supprimer")
+	fs.Parse(args)
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Erreur de configuration: %v", err)
+	}
+
+	store, err := storage.NewStorage(cfg.SQLitePath, cfg.SQLiteBatchSize)
+	if err != nil {
+		log.Fatalf("Erreur connexion SQLite: %v", err)
+	}
+	defer store.Close()
+	store.WithUnicodeNormalization(cfg.NormalizeUnicode).WithPathRewrites(pathRewrites(cfg)).
+		WithRelativeMarkers(cfg.RelativePathMarkers())
+
+	ctx := context.Background()
+	if err := store.Initialize(ctx); err != nil {
+		log.Fatalf("Erreur initialisation DB: %v", err)
+	}
+
+	c := newCleaner(store, cfg)
+	report, err := c.Purge(ctx, *dryRun)
+	if err != nil {
+		log.Fatalf("Erreur purge: %v", err)
+	}
+
+	for _, b := range report.Batches {
+		fmt.Printf("   🗑️  %s\n", filepath.Join(cfg.QuarantinePath, b))
+	}
+	if report.DryRun {
+		fmt.Printf("📋 %d lots seraient purgés: %d fichiers (%s)\n", len(report.Batches), report.FilesRemoved, formatSize(report.BytesReclaimed))
+	} else {
+		fmt.Printf("✅ %d lots purgés: %d fichiers (%s récupérés)\n", len(report.Batches), report.FilesRemoved, formatSize(report.BytesReclaimed))
+	}
+}
+
+// pathRewrites returns the path normalization rules of the configuration.
+func pathRewrites(cfg *config.Config) []storage.PathRewrite {
+	rules := make([]storage.PathRewrite, len(cfg.PathRewrites))
+	for i, m := range cfg.PathRewrites {
+		rules[i] = storage.PathRewrite{From: m.From, To: m.To}
+	}
+	return rules
+}
+
+// newCleaner builds a cleaner from the configuration, with its protected paths
+// and the quarantine and archive when configured.
+func newCleaner(store *storage.Storage, cfg *config.Config) *cleaner.Cleaner {
+	c := cleaner.NewCleaner(store, cfg.LocalPath).
+		WithProtectedPaths(cfg.ProtectedPaths).
+		WithCompanionExtensions(cfg.CompanionExtensions).
+		WithDeadTrackerMessages(cfg.DeadTrackerMessages).
+		WithScanFilter(models.FileFilter{MinSize: cfg.ScanMinSize, Extensions: cfg.ScanExtensions})
+	if cfg.QuarantinePath != "" {
+		c.WithQuarantine(cfg.QuarantinePath, time.Duration(cfg.QuarantineRetention)*24*time.Hour)
+	}
+	if cfg.ArchivePath != "" {
+		c.WithArchive(cfg.ArchivePath)
+	}
+	if cfg.LocalRemote != "" {
+		c.WithReadOnly()
+	}
+	return c
+}
+
+// shortenPath keeps the last n characters of path.
+func shortenPath(path string, n int) string {
+	runes := []rune(path)
+	if len(runes) <= n {
+		return path
+	}
+	return "…" + string(runes[len(runes)-n:])
 }
 
 func formatSize(bytes int64) string {
@@ -230,21 +949,36 @@ func formatSize(bytes int64) string {
 func printHelp() {
 	fmt.Println("GoDataCleaner - Gestionnaire de fichiers torrents")
 	fmt.Println()
-	fmt.Println("Usage: godatacleaner <commande>")
+	fmt.Println("Usage: godatacleaner [options] <commande> [options de la commande]")
 	fmt.Println()
 	fmt.Println("Commandes:")
-	fmt.Println("  sync   Synchroniser qBittorrent et fichiers locaux vers SQLite")
-	fmt.Println("  web    Démarrer le serveur WebUI")
-	fmt.Println("  stats  Afficher les statistiques de la base")
-	fmt.Println("  help   Afficher cette aide")
+	fmt.Println("  sync       Synchroniser les clients torrents et fichiers locaux vers SQLite (--full, --json)")
+	fmt.Println("             sync history [--limit N] : historique des synchronisations")
+	fmt.Println("             sync diff [--run N] [--limit N] : fichiers locaux ajoutés, supprimés ou modifiés par une synchronisation")
+	fmt.Println("             sync errors [--run N] : torrents et clients qu'une synchronisation n'a pas pu lire")
+	fmt.Println("  web        Démarrer le serveur WebUI")
+	fmt.Println("  daemon     Démarrer le serveur WebUI avec les synchronisations planifiées (SYNC_SCHEDULE) et les tâches de nettoyage")
+	fmt.Println("  watch      Mettre à jour les fichiers locaux en base à chaque changement sous LOCAL_PATH")
+	fmt.Println("  stats      Afficher les statistiques de la base (--json)")
+	fmt.Println("  verify     Recalculer les empreintes des fichiers locaux et signaler ceux dont le contenu a changé")
+	fmt.Println("  top        Afficher les plus gros fichiers locaux (--folders, --by size|allocated|files, -n, --orphans)")
+	fmt.Println("  orphans    Lister les fichiers orphelins (--category, --min-size, --older-than, --sort, --order, --limit, --format table|csv|json)")
+	fmt.Println("  clean      Supprimer les fichiers orphelins après confirmation (--dry-run, --yes, --category, --min-size, --older-than, --orphaned-for, --quarantine, --archive, --companions, --recheck, --dead)")
+	fmt.Println("  purge      Purger les lots de quarantaine expirés (--dry-run)")
+	fmt.Println("  simulate   Rapport de l'espace récupérable par règle (--format json|html, --output, --top)")
+	fmt.Println("  restore    Restaurer des fichiers de la quarantaine (<id>..., --batch, --list, --recheck, --reannounce)")
+	fmt.Println("  reannounce Réannoncer des torrents à leurs trackers (<hash>...)")
+	fmt.Println("  jobs       Gérer les tâches de nettoyage planifiées")
+	fmt.Println("  protect    Gérer les chemins protégés (list, add <motif>, remove <id>)")
+	fmt.Println("  ignore     Gérer les fichiers et dossiers jamais comptés comme orphelins (list, add <chemin>, add-pattern <motif>, remove <id>)")
+	fmt.Println("  users      Gérer les utilisateurs de l'interface web (list, add, passwd, role, remove)")
+	fmt.Println("  db         Sauvegarder ou restaurer la base (backup <fichier>, restore <fichier>)")
+	fmt.Println("  help       Afficher cette aide")
+	fmt.Println()
+	fmt.Println("Options de configuration, avant ou après la commande (ex: godatacleaner --config /etc/gdc.json --local-path /mnt/media sync).")
+	fmt.Println("Chaque option peut aussi être définie par la variable d'environnement indiquée. Priorité: option > variable > fichier de configuration > défaut.")
+	fmt.Println("Les instances qBittorrent multiples se configurent par les variables QBITTORRENT_<NOM>_* (HOST, PORT, USERNAME, PASSWORD, PATH_MAP).")
+	fmt.Println("Les options propres à une commande sont listées par godatacleaner <commande> -h.")
 	fmt.Println()
-	fmt.Println("Variables d'environnement:")
-	fmt.Println("  LOCAL_HOST              Hôte du serveur (défaut: localhost)")
-	fmt.Println("  LOCAL_PORT              Port du serveur (défaut: 61913)")
-	fmt.Println("  QBITTORRENT_HOST        Hôte qBittorrent (défaut: qbt.home)")
-	fmt.Println("  QBITTORRENT_PORT        Port qBittorrent (défaut: 80)")
-	fmt.Println("  QBITTORRENT_USERNAME    Utilisateur (défaut: admin)")
-	fmt.Println("  QBITTORRENT_PASSWORD    Mot de passe (défaut: adminadmin)")
-	fmt.Println("  SQLITE_PATH             Chemin de la DB (défaut: ./data/torrents.db)")
-	fmt.Println("  LOCAL_PATH              Chemin à scanner (défaut: ./data/torrents)")
+	printConfigFlags()
 }
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"godatacleaner/internal/config"
+	"godatacleaner/internal/models"
+	"godatacleaner/internal/storage"
+)
+
+// runOrphans lists the orphans matching the filters, as the orphans tab of
+// the WebUI does.
+func runOrphans(args []string) {
+	fs := newFlagSet("orphans")
+	category := fs.String("category", "", "Limiter la liste à une catégorie (4k, movies, shows, unknown ou selon CATEGORY_RULES)")
+	search := fs.String("search", "", "Ne lister que les chemins contenant ce texte")
+	var minSize, maxSize sizeFlag
+	fs.Var(&minSize, "min-size", "Ne lister que les fichiers d'au moins cette taille (ex: 500MB, 1GB)")
+	fs.Var(&maxSize, "max-size", "Ne lister que les fichiers d'au plus cette taille (ex: 500MB, 1GB)")
+	var olderThan, orphanedFor ageFlag
+	fs.Var(&olderThan, "older-than", "Ne lister que les fichiers modifiés il y a au moins cette durée (ex: 30d, 2w)")
+	fs.Var(&orphanedFor, "orphaned-for", "Ne lister que les fichiers orphelins depuis au moins cette durée (ex: 30d, 2w)")
+	excludeHardlinked := fs.Bool("exclude-hardlinked", false, "Ignorer les fichiers ayant d'autres liens physiques, dont la suppression ne libère rien")
+	sort := fs.String("sort", "size", "Tri: size, file_path, file_name, category, mod_time, orphaned_at ou links")
+	order := fs.String("order", "desc", "Ordre du tri: asc ou desc")
+	limit := fs.Int("limit", 100, "Nombre maximal de fichiers (0 = tous)")
+	format := fs.String("format", "table", "Format: table, csv ou json")
+	fs.Parse(args)
+
+	if *sort == "" || !storage.ValidViewSort(models.ViewListOrphans, *sort) {
+		log.Fatalf("Erreur: tri invalide %q", *sort)
+	}
+	if *order != "asc" && *order != "desc" {
+		log.Fatalf("Erreur: --order doit valoir asc ou desc")
+	}
+	if *format != "table" && *format != "csv" && *format != "json" {
+		log.Fatalf("Erreur: --format doit valoir table, csv ou json")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Erreur de configuration: %v", err)
+	}
+
+	store, err := storage.NewStorage(cfg.SQLitePath, cfg.SQLiteBatchSize)
+	if err != nil {
+		log.Fatalf("Erreur connexion SQLite: %v", err)
+	}
+	defer store.Close()
+	store.WithUnicodeNormalization(cfg.NormalizeUnicode).WithPathRewrites(pathRewrites(cfg)).
+		WithRelativeMarkers(cfg.RelativePathMarkers())
+
+	ctx := context.Background()
+	if err := store.Initialize(ctx); err != nil {
+		log.Fatalf("Erreur initialisation DB: %v", err)
+	}
+
+	opts := models.QueryOptions{
+		Sort:              *sort,
+		Order:             *order,
+		Search:            *search,
+		Category:          *category,
+		MinAge:            time.Duration(olderThan),
+		MinOrphanAge:      time.Duration(orphanedFor),
+		MinSize:           int64(minSize),
+		MaxSize:           int64(maxSize),
+		ExcludeHardlinked: *excludeHardlinked,
+	}
+	files, total := listOrphans(ctx, store, opts, *limit)
+
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if files == nil {
+			files = []models.OrphanFile{}
+		}
+		if err := enc.Encode(files); err != nil {
+			log.Fatalf("Erreur écriture: %v", err)
+		}
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"file_path", "size", "allocated", "category", "links", "mod_time", "orphaned_at"})
+		for _, f := range files {
+			w.Write([]string{f.FilePath, strconv.FormatInt(f.Size, 10), strconv.FormatInt(f.Allocated, 10), f.Category,
+				strconv.FormatInt(f.Links, 10), csvTime(f.ModTime), csvTime(f.OrphanedAt)})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			log.Fatalf("Erreur écriture: %v", err)
+		}
+	default:
+		printOrphanTable(files, total)
+	}
+}
+
+// listOrphans returns the first limit orphans matching opts, all of them when
+// limit is 0, read page after page from the cursor of the last one, and the
+// number of orphans matching opts.
+func listOrphans(ctx context.Context, store *storage.Storage, opts models.QueryOptions, limit int) ([]models.OrphanFile, int64) {
+	const pageSize = 1000
+
+	var files []models.OrphanFile
+	var total int64
+	opts.Page = 1
+	for {
+		opts.PerPage = pageSize
+		if limit > 0 && limit-len(files) < pageSize {
+			opts.PerPage = limit - len(files)
+		}
+		page, n, err := store.GetOrphanFiles(ctx, opts)
+		if err != nil {
+			log.Fatalf("Erreur lecture orphelins: %v", err)
+		}
+		if opts.After == nil {
+			total = n
+		}
+		files = append(files, page...)
+		if len(page) < opts.PerPage || (limit > 0 && len(files) >= limit) {
+			return files, total
+		}
+		opts.After = storage.OrphanFileCursor(opts.Sort, page[len(page)-1])
+	}
+}
+
+func printOrphanTable(files []models.OrphanFile, total int64) {
+	if len(files) == 0 {
+		fmt.Println("✅ Aucun fichier orphelin")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TAILLE\tCATÉGORIE\tMODIFIÉ\tORPHELIN DEPUIS\tCHEMIN")
+	var size int64
+	for _, f := range files {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", formatSize(f.Size), f.Category, tableDate(f.ModTime), tableDate(f.OrphanedAt), f.FilePath)
+		size += f.Size
+	}
+	w.Flush()
+
+	fmt.Println()
+	fmt.Printf("📋 %d fichiers orphelins affichés sur %d (%s)\n", len(files), total, formatSize(size))
+}
+
+// tableDate formats t for printOrphanTable, - when unknown.
+func tableDate(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02")
+}
+
+// csvTime formats t for the csv format, empty when unknown.
+func csvTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"godatacleaner/internal/cleaner"
+	"godatacleaner/internal/config"
+	"godatacleaner/internal/storage"
+)
+
+func runProtect(args []string) {
+	if len(args) < 1 {
+		printProtectHelp()
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Erreur de configuration: %v", err)
+	}
+
+	store, err := storage.NewStorage(cfg.SQLitePath, cfg.SQLiteBatchSize)
+	if err != nil {
+		log.Fatalf("Erreur connexion SQLite: %v", err)
+	}
+	defer store.Close()
+	store.WithUnicodeNormalization(cfg.NormalizeUnicode).WithPathRewrites(pathRewrites(cfg)).
+		WithRelativeMarkers(cfg.RelativePathMarkers())
+
+	ctx := context.Background()
+	if err := store.Initialize(ctx); err != nil {
+		log.Fatalf("Erreur initialisation DB: %v", err)
+	}
+
+	switch args[0] {
+	case "list":
+		for _, p := range cfg.ProtectedPaths {
+			fmt.Printf("🛡️  [config] %s\n", p)
+		}
+		paths, err := store.ListProtectedPaths(ctx)
+		if err != nil {
+			log.Fatalf("Erreur lecture chemins protégés: %v", err)
+		}
+		for _, p := range paths {
+			fmt.Printf("🛡️  [%d] %s\n", p.ID, p.Pattern)
+		}
+	case "add":
+		if len(args) < 2 {
+			log.Fatalf("Erreur: motif manquant")
+		}
+		if err := cleaner.ValidatePattern(args[1]); err != nil {
+			log.Fatalf("Erreur: %v", err)
+		}
+		id, err := store.AddProtectedPath(ctx, args[1])
+		if err != nil {
+			log.Fatalf("Erreur ajout chemin protégé: %v", err)
+		}
+		fmt.Printf("✅ Chemin protégé %d ajouté: %s\n", id, args[1])
+	case "remove":
+		if len(args) < 2 {
+			log.Fatalf("Erreur: identifiant manquant")
+		}
+		id, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			log.Fatalf("Erreur: identifiant invalide: %s", args[1])
+		}
+		if err := store.RemoveProtectedPath(ctx, id); err != nil {
+			log.Fatalf("Erreur suppression chemin protégé: %v", err)
+		}
+		fmt.Printf("✅ Chemin protégé %d supprimé\n", id)
+	default:
+		fmt.Fprintf(os.Stderr, "Sous-commande inconnue: %s\n\n", args[0])
+		printProtectHelp()
+		os.Exit(1)
+	}
+}
+
+func printProtectHelp() {
+	fmt.Println("Usage: godatacleaner protect <sous-commande>")
+	fmt.Println()
+	fmt.Println("Sous-commandes:")
+	fmt.Println("  list            Lister les chemins protégés (config et base)")
+	fmt.Println("  add <motif>     Protéger un chemin (préfixe ou glob, ex: shows/kids ou */extras)")
+	fmt.Println("  remove <id>     Retirer un chemin protégé")
+}
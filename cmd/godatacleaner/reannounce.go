@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"godatacleaner/internal/config"
+	"godatacleaner/internal/models"
+	"godatacleaner/internal/storage"
+	"godatacleaner/internal/syncer"
+)
+
+func runReannounce(args []string) {
+	fs := newFlagSet("reannounce")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		log.Fatalf("Erreur: indiquer les hash des torrents à réannoncer")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Erreur de configuration: %v", err)
+	}
+
+	store, err := storage.NewStorage(cfg.SQLitePath, cfg.SQLiteBatchSize)
+	if err != nil {
+		log.Fatalf("Erreur connexion SQLite: %v", err)
+	}
+	defer store.Close()
+	store.WithUnicodeNormalization(cfg.NormalizeUnicode).WithPathRewrites(pathRewrites(cfg)).
+		WithRelativeMarkers(cfg.RelativePathMarkers())
+
+	ctx := context.Background()
+	if err := store.Initialize(ctx); err != nil {
+		log.Fatalf("Erreur initialisation DB: %v", err)
+	}
+
+	var torrents []models.Torrent
+	for _, hash := range fs.Args() {
+		found, err := store.GetTorrentsByHash(ctx, hash)
+		if err != nil {
+			log.Fatalf("Erreur lecture torrents: %v", err)
+		}
+		if len(found) == 0 {
+			fmt.Printf("⚠️  Torrent inconnu: %s\n", hash)
+		}
+		torrents = append(torrents, found...)
+	}
+
+	printReannounceReport(syncer.NewSyncer(store, cfg).Reannounce(ctx, torrents))
+}
+
+// reannounceTorrents reannounces the torrents expecting any of the given
+// local files, so that their trackers see them seeding again.
+func reannounceTorrents(ctx context.Context, store *storage.Storage, cfg *config.Config, paths []string) {
+	report, err := syncer.NewSyncer(store, cfg).ReannouncePaths(ctx, paths)
+	if err != nil {
+		log.Fatalf("Erreur réannonce des torrents: %v", err)
+	}
+	printReannounceReport(report)
+}
+
+func printReannounceReport(report *syncer.ReannounceReport) {
+	for _, a := range report.Actions {
+		if a.Error != "" {
+			fmt.Printf("   ❌ %s (%d torrents): %s\n", a.Instance, len(a.Hashes), a.Error)
+		} else {
+			fmt.Printf("   📣 %s (%d torrents)\n", a.Instance, len(a.Hashes))
+		}
+	}
+	fmt.Printf("📣 %d torrents réannoncés\n", report.Reannounced)
+	if report.Failed > 0 {
+		fmt.Printf("⚠️  %d torrents en erreur\n", report.Failed)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"godatacleaner/internal/cleaner"
+	"godatacleaner/internal/config"
+	"godatacleaner/internal/models"
+	"godatacleaner/internal/storage"
+)
+
+func runRestore(args []string) {
+	fs := newFlagSet("restore")
+	batch := fs.String("batch", "", "Restaurer tout un lot de quarantaine")
+	list := fs.Bool("list", false, "Lister les fichiers en quarantaine")
+	recheck := fs.Bool("recheck", false, "Forcer la revérification des torrents attendant les fichiers restaurés")
+	reannounce := fs.Bool("reannounce", false, "Réannoncer aux trackers les torrents attendant les fichiers restaurés")
+	fs.Parse(args)
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Erreur de configuration: %v", err)
+	}
+
+	store, err := storage.NewStorage(cfg.SQLitePath, cfg.SQLiteBatchSize)
+	if err != nil {
+		log.Fatalf("Erreur connexion SQLite: %v", err)
+	}
+	defer store.Close()
+	store.WithUnicodeNormalization(cfg.NormalizeUnicode).WithPathRewrites(pathRewrites(cfg)).
+		WithRelativeMarkers(cfg.RelativePathMarkers())
+
+	ctx := context.Background()
+	if err := store.Initialize(ctx); err != nil {
+		log.Fatalf("Erreur initialisation DB: %v", err)
+	}
+
+	if *list {
+		restoreList(ctx, store)
+		return
+	}
+
+	c := newCleaner(store, cfg)
+	var report *cleaner.RestoreReport
+	switch {
+	case *batch != "":
+		report, err = c.RestoreBatch(ctx, *batch)
+	case fs.NArg() > 0:
+		ids := make([]int64, 0, fs.NArg())
+		for _, arg := range fs.Args() {
+			id, err := strconv.ParseInt(arg, 10, 64)
+			if err != nil {
+				log.Fatalf("Erreur: identifiant invalide: %s", arg)
+			}
+			ids = append(ids, id)
+		}
+		report, err = c.Restore(ctx, ids)
+	default:
+		log.Fatalf("Erreur: indiquer des identifiants, --batch ou --list")
+	}
+	if err != nil {
+		log.Fatalf("Erreur restauration: %v", err)
+	}
+
+	for _, a := range report.Actions {
+		if a.Error != "" {
+			fmt.Printf("   ❌ [%d] %s: %s\n", a.Entry.ID, a.Entry.DiskPath, a.Error)
+		} else {
+			fmt.Printf("   ♻️  [%d] %s\n", a.Entry.ID, a.Entry.DiskPath)
+		}
+	}
+	fmt.Printf("✅ %d fichiers restaurés\n", report.Restored)
+	if report.Failed > 0 {
+		fmt.Printf("⚠️  %d fichiers en erreur\n", report.Failed)
+	}
+
+	var paths []string
+	for _, a := range report.Actions {
+		if a.Error == "" {
+			paths = append(paths, a.Entry.FilePath)
+		}
+	}
+	if *recheck {
+		recheckTorrents(ctx, store, cfg, paths)
+	}
+	if *reannounce {
+		reannounceTorrents(ctx, store, cfg, paths)
+	}
+}
+
+func restoreList(ctx context.Context, store *storage.Storage) {
+	opts := models.QueryOptions{Page: 1, PerPage: 1000}
+	for {
+		entries, total, err := store.ListQuarantine(ctx, opts)
+		if err != nil {
+			log.Fatalf("Erreur lecture quarantaine: %v", err)
+		}
+		if total == 0 {
+			fmt.Println("Aucun fichier en quarantaine")
+			return
+		}
+		for _, e := range entries {
+			fmt.Printf("📦 [%d] %s %s (%s)\n", e.ID, e.Batch, e.DiskPath, formatSize(e.Size))
+		}
+		if int64(opts.Page*opts.PerPage) >= total {
+			return
+		}
+		opts.Page++
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"godatacleaner/internal/cleaner"
+	"godatacleaner/internal/config"
+	"godatacleaner/internal/storage"
+)
+
+func runSimulate(args []string) {
+	fs := newFlagSet("simulate")
+	format := fs.String("format", "json", "Format du rapport (json, html)")
+	output := fs.String("output", "", "Fichier de sortie (défaut: sortie standard)")
+	top := fs.Int("top", cleaner.DefaultSimulationTop, "Nombre de plus gros fichiers listés par catégorie")
+	fs.Parse(args)
+
+	if *format != "json" && *format != "html" {
+		log.Fatalf("Erreur: format inconnu: %s", *format)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Erreur de configuration: %v", err)
+	}
+
+	store, err := storage.NewStorage(cfg.SQLitePath, cfg.SQLiteBatchSize)
+	if err != nil {
+		log.Fatalf("Erreur connexion SQLite: %v", err)
+	}
+	defer store.Close()
+	store.WithUnicodeNormalization(cfg.NormalizeUnicode).WithPathRewrites(pathRewrites(cfg)).
+		WithRelativeMarkers(cfg.RelativePathMarkers())
+
+	ctx := context.Background()
+	if err := store.Initialize(ctx); err != nil {
+		log.Fatalf("Erreur initialisation DB: %v", err)
+	}
+
+	report, err := newCleaner(store, cfg).Simulate(ctx, *top)
+	if err != nil {
+		log.Fatalf("Erreur simulation: %v", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Fatalf("Erreur création fichier: %v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if *format == "html" {
+		err = report.WriteHTML(w)
+	} else {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	}
+	if err != nil {
+		log.Fatalf("Erreur écriture rapport: %v", err)
+	}
+
+	if *output != "" {
+		fmt.Printf("📋 Rapport écrit dans %s: %d fichiers (%s récupérables)\n", *output, report.TotalFiles, formatSize(report.TotalBytes))
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"godatacleaner/internal/config"
+	"godatacleaner/internal/models"
+	"godatacleaner/internal/storage"
+)
+
+// runSyncHistory prints the latest syncs, run by the CLI or the web server.
+func runSyncHistory(args []string) {
+	fs := newFlagSet("sync history")
+	limit := fs.Int("limit", 20, "Nombre de synchronisations affichées")
+	fs.Parse(args)
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Erreur de configuration: %v", err)
+	}
+
+	store, err := storage.NewStorage(cfg.SQLitePath, cfg.SQLiteBatchSize)
+	if err != nil {
+		log.Fatalf("Erreur connexion SQLite: %v", err)
+	}
+	defer store.Close()
+	store.WithUnicodeNormalization(cfg.NormalizeUnicode).WithPathRewrites(pathRewrites(cfg)).
+		WithRelativeMarkers(cfg.RelativePathMarkers())
+
+	ctx := context.Background()
+	if err := store.Initialize(ctx); err != nil {
+		log.Fatalf("Erreur initialisation DB: %v", err)
+	}
+
+	runs, err := store.ListSyncRuns(ctx, *limit)
+	if err != nil {
+		log.Fatalf("Erreur lecture historique: %v", err)
+	}
+	if len(runs) == 0 {
+		fmt.Println("Aucune synchronisation enregistrée")
+		return
+	}
+	for _, r := range runs {
+		icon := map[string]string{
+			models.SyncRunDone:      "✅",
+			models.SyncRunPartial:   "⚠️ ",
+			models.SyncRunFailed:    "❌",
+			models.SyncRunCancelled: "⏹️ ",
+		}[r.Status]
+		mode := ""
+		if r.Full {
+			mode = " (complète)"
+		}
+		fmt.Printf("%s [%d] %s%s en %s: %d torrents, %d fichiers torrents, %d fichiers locaux\n",
+			icon, r.ID, r.StartedAt.Local().Format("2006-01-02 15:04"), mode,
+			time.Duration(r.DurationSeconds*float64(time.Second)).Round(time.Second), r.Torrents, r.TorrentFiles, r.LocalFiles)
+		switch {
+		case r.Error != "":
+			fmt.Printf("   %s\n", r.Error)
+		case !r.TorrentsSynced:
+			fmt.Println("   Clients torrents injoignables, fichiers torrents conservés")
+		case r.TorrentsFailed > 0:
+			fmt.Printf("   %d torrents illisibles, leurs fichiers ont été conservés\n", r.TorrentsFailed)
+		}
+		if r.Error == "" && r.Status != models.SyncRunCancelled && !r.LocalSynced {
+			fmt.Println("   Fichiers locaux non synchronisés, ceux de la synchronisation précédente sont conservés")
+		}
+	}
+}
+
+// runSyncDiff prints the local files added, removed or resized by a sync
+// since the previous one.
+func runSyncDiff(args []string) {
+	fs := newFlagSet("sync diff")
+	runID := fs.Int64("run", 0, "Synchronisation affichée (défaut: la dernière ayant relevé les modifications)")
+	limit := fs.Int("limit", 0, "Nombre maximal de fichiers affichés (défaut: tous)")
+	fs.Parse(args)
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Erreur de configuration: %v", err)
+	}
+
+	store, err := storage.NewStorage(cfg.SQLitePath, cfg.SQLiteBatchSize)
+	if err != nil {
+		log.Fatalf("Erreur connexion SQLite: %v", err)
+	}
+	defer store.Close()
+	store.WithUnicodeNormalization(cfg.NormalizeUnicode).WithPathRewrites(pathRewrites(cfg)).
+		WithRelativeMarkers(cfg.RelativePathMarkers())
+
+	ctx := context.Background()
+	if err := store.Initialize(ctx); err != nil {
+		log.Fatalf("Erreur initialisation DB: %v", err)
+	}
+
+	diff, err := store.GetSyncDiff(ctx, *runID, *limit)
+	if errors.Is(err, storage.ErrNotFound) && *runID != 0 {
+		log.Fatalf("Erreur: synchronisation %d introuvable", *runID)
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		fmt.Println("Aucune synchronisation n'a relevé de modifications")
+		return
+	}
+	if err != nil {
+		log.Fatalf("Erreur lecture modifications: %v", err)
+	}
+
+	fmt.Printf("🔀 Synchronisation [%d] du %s\n", diff.Run.ID, diff.Run.StartedAt.Local().Format("2006-01-02 15:04"))
+	if !diff.Run.ChangesRecorded {
+		fmt.Println("   Modifications non relevées (première synchronisation, fichiers locaux non synchronisés ou trop ancienne)")
+		return
+	}
+	fmt.Printf("   %d ajoutés (%s), %d supprimés (%s), %d de taille modifiée\n",
+		diff.Added, formatSize(diff.AddedBytes), diff.Removed, formatSize(diff.RemovedBytes), diff.Changed)
+	for _, f := range diff.Files {
+		switch f.Change {
+		case models.FileAdded:
+			fmt.Printf("+ %s (%s)\n", f.FilePath, formatSize(f.Size))
+		case models.FileRemoved:
+			fmt.Printf("- %s (%s)\n", f.FilePath, formatSize(f.Size))
+		case models.FileChanged:
+			fmt.Printf("~ %s (%s → %s)\n", f.FilePath, formatSize(f.OldSize), formatSize(f.Size))
+		}
+	}
+	if total := diff.Added + diff.Removed + diff.Changed; int64(len(diff.Files)) < total {
+		fmt.Printf("   ... et %d autres fichiers\n", total-int64(len(diff.Files)))
+	}
+}
+
+// runSyncErrors prints the torrents and torrent clients a sync could not read.
+func runSyncErrors(args []string) {
+	fs := newFlagSet("sync errors")
+	runID := fs.Int64("run", 0, "Synchronisation affichée (défaut: la dernière)")
+	fs.Parse(args)
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Erreur de configuration: %v", err)
+	}
+
+	store, err := storage.NewStorage(cfg.SQLitePath, cfg.SQLiteBatchSize)
+	if err != nil {
+		log.Fatalf("Erreur connexion SQLite: %v", err)
+	}
+	defer store.Close()
+	store.WithUnicodeNormalization(cfg.NormalizeUnicode).WithPathRewrites(pathRewrites(cfg)).
+		WithRelativeMarkers(cfg.RelativePathMarkers())
+
+	ctx := context.Background()
+	if err := store.Initialize(ctx); err != nil {
+		log.Fatalf("Erreur initialisation DB: %v", err)
+	}
+
+	report, err := store.GetSyncErrors(ctx, *runID)
+	if errors.Is(err, storage.ErrNotFound) && *runID != 0 {
+		log.Fatalf("Erreur: synchronisation %d introuvable", *runID)
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		fmt.Println("Aucune synchronisation enregistrée")
+		return
+	}
+	if err != nil {
+		log.Fatalf("Erreur lecture erreurs: %v", err)
+	}
+
+	fmt.Printf("🔀 Synchronisation [%d] du %s\n", report.Run.ID, report.Run.StartedAt.Local().Format("2006-01-02 15:04"))
+	if len(report.Errors) == 0 {
+		fmt.Println("   Aucune erreur relevée")
+		return
+	}
+	for _, e := range report.Errors {
+		if e.Hash == "" {
+			fmt.Printf("❌ Client %s injoignable, fichiers torrents conservés: %s\n", e.Instance, e.Error)
+			continue
+		}
+		kept := fmt.Sprintf("%d fichiers conservés", e.KeptFiles)
+		if e.KeptFiles == 0 {
+			kept = "nouveau torrent, ses fichiers passent pour des orphelins"
+		}
+		fmt.Printf("❌ %s (%s, %s): %s\n   %s\n", e.Name, e.Instance, e.Hash, kept, e.Error)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"time"
+
+	"godatacleaner/internal/models"
+	"godatacleaner/internal/syncer"
+)
+
+// syncSummary is the output of sync --json.
+type syncSummary struct {
+	Status          string    `json:"status"` // See models.SyncRunDone
+	Error           string    `json:"error,omitempty"`
+	Full            bool      `json:"full"`
+	StartedAt       time.Time `json:"started_at"`
+	FinishedAt      time.Time `json:"finished_at"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	// Seconds spent in every stage the sync went through
+	Stages map[syncer.Stage]float64 `json:"stages"`
+	*syncer.Result
+	Errors []models.SyncError `json:"errors"`
+}
+
+// stageTimer measures the time a sync spends in every stage, from its
+// progress.
+type stageTimer struct {
+	stages  []syncer.Stage
+	started []time.Time
+}
+
+func (t *stageTimer) progress(p syncer.Progress) {
+	if n := len(t.stages); n == 0 || t.stages[n-1] != p.Stage {
+		t.stages = append(t.stages, p.Stage)
+		t.started = append(t.started, time.Now())
+	}
+}
+
+// durations returns the seconds spent in every stage, the last one ending at
+// finished.
+func (t *stageTimer) durations(finished time.Time) map[syncer.Stage]float64 {
+	seconds := make(map[syncer.Stage]float64)
+	for i, stage := range t.stages {
+		if stage == syncer.StageDone {
+			continue
+		}
+		end := finished
+		if i+1 < len(t.started) {
+			end = t.started[i+1]
+		}
+		seconds[stage] += end.Sub(t.started[i]).Seconds()
+	}
+	return seconds
+}
+
+// printSyncSummary prints the summary of a sync as JSON, and exits with
+// status 1 when it failed.
+func printSyncSummary(started time.Time, full bool, timer *stageTimer, result *syncer.Result, err error) {
+	finished := time.Now()
+	summary := syncSummary{
+		Status:          syncer.Status(result, err),
+		Full:            full,
+		StartedAt:       started,
+		FinishedAt:      finished,
+		DurationSeconds: finished.Sub(started).Seconds(),
+		Stages:          timer.durations(finished),
+		Result:          result,
+		Errors:          []models.SyncError{},
+	}
+	if err != nil {
+		summary.Error = err.Error()
+	}
+	if result != nil && result.Errors != nil {
+		summary.Errors = result.Errors
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(summary); err != nil {
+		log.Fatalf("Erreur écriture: %v", err)
+	}
+	if err != nil {
+		os.Exit(1)
+	}
+}
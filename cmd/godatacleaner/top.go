@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"godatacleaner/internal/config"
+	"godatacleaner/internal/storage"
+)
+
+// runTop prints the largest local files or folders.
+func runTop(args []string) {
+	fs := newFlagSet("top")
+	folders := fs.Bool("folders", false, "Afficher les dossiers les plus gros au lieu des fichiers")
+	by := fs.String("by", "size", "Classement: size, allocated (taille sur disque) ou files (dossiers seulement)")
+	n := fs.Int("n", 20, "Nombre de résultats")
+	orphans := fs.Bool("orphans", false, "Ne compter que les fichiers orphelins")
+	fs.Parse(args)
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Erreur de configuration: %v", err)
+	}
+
+	store, err := storage.NewStorage(cfg.SQLitePath, cfg.SQLiteBatchSize)
+	if err != nil {
+		log.Fatalf("Erreur connexion SQLite: %v", err)
+	}
+	defer store.Close()
+	store.WithUnicodeNormalization(cfg.NormalizeUnicode).WithPathRewrites(pathRewrites(cfg)).
+		WithRelativeMarkers(cfg.RelativePathMarkers())
+
+	ctx := context.Background()
+	if err := store.Initialize(ctx); err != nil {
+		log.Fatalf("Erreur initialisation DB: %v", err)
+	}
+
+	if *folders {
+		top, err := store.GetTopFolders(ctx, *by, *n, *orphans)
+		if err != nil {
+			log.Fatalf("Erreur lecture dossiers: %v", err)
+		}
+		for i, f := range top {
+			fmt.Printf("%3d. %10s  %6d fichiers  %s\n", i+1, formatSize(f.TotalSize), f.FileCount, f.Path)
+		}
+		return
+	}
+
+	top, err := store.GetTopFiles(ctx, *by, *n, *orphans)
+	if err != nil {
+		log.Fatalf("Erreur lecture fichiers: %v", err)
+	}
+	for i, f := range top {
+		orphan := ""
+		if f.Orphan {
+			orphan = "  🗑️"
+		}
+		fmt.Printf("%3d. %10s  %s%s\n", i+1, formatSize(f.Size), f.FilePath, orphan)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"godatacleaner/internal/auth"
+	"godatacleaner/internal/config"
+	"godatacleaner/internal/models"
+	"godatacleaner/internal/storage"
+)
+
+func runUsers(args []string) {
+	if len(args) < 1 {
+		printUsersHelp()
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Erreur de configuration: %v", err)
+	}
+
+	store, err := storage.NewStorage(cfg.SQLitePath, cfg.SQLiteBatchSize)
+	if err != nil {
+		log.Fatalf("Erreur connexion SQLite: %v", err)
+	}
+	defer store.Close()
+	store.WithUnicodeNormalization(cfg.NormalizeUnicode).WithPathRewrites(pathRewrites(cfg)).
+		WithRelativeMarkers(cfg.RelativePathMarkers())
+
+	ctx := context.Background()
+	if err := store.Initialize(ctx); err != nil {
+		log.Fatalf("Erreur initialisation DB: %v", err)
+	}
+
+	switch args[0] {
+	case "list":
+		users, err := store.ListUsers(ctx)
+		if err != nil {
+			log.Fatalf("Erreur lecture utilisateurs: %v", err)
+		}
+		if len(users) == 0 {
+			fmt.Println("Aucun utilisateur")
+			return
+		}
+		for _, u := range users {
+			fmt.Printf("👤 [%d] %-20s %-7s créé le %s\n", u.ID, u.Username, u.Role, u.CreatedAt.Local().Format("2006-01-02 15:04"))
+		}
+	case "add":
+		usersAdd(ctx, store, args[1:])
+	case "passwd":
+		username := parseUsername(args[1:])
+		fs := newFlagSet("users passwd")
+		password := fs.String("password", "", "Nouveau mot de passe (lu sur l'entrée standard si absent)")
+		fs.Parse(args[2:])
+
+		hash, err := auth.HashPassword(readPassword(*password))
+		if err != nil {
+			log.Fatalf("Erreur: %v", err)
+		}
+		if err := store.SetUserPassword(ctx, username, hash); err != nil {
+			log.Fatalf("Erreur mise à jour utilisateur: %v", err)
+		}
+		// Déconnecter les sessions ouvertes avec l'ancien mot de passe
+		if err := store.DeleteUserSessions(ctx, username); err != nil {
+			log.Fatalf("Erreur suppression sessions: %v", err)
+		}
+		fmt.Printf("✅ Mot de passe de %s modifié\n", username)
+	case "role":
+		username := parseUsername(args[1:])
+		if len(args) < 3 || !models.ValidRole(args[2]) {
+			log.Fatalf("Erreur: rôle invalide (viewer ou admin)")
+		}
+		if err := store.SetUserRole(ctx, username, args[2]); err != nil {
+			log.Fatalf("Erreur mise à jour utilisateur: %v", err)
+		}
+		fmt.Printf("✅ %s est maintenant %s\n", username, args[2])
+	case "remove":
+		username := parseUsername(args[1:])
+		if err := store.DeleteUser(ctx, username); err != nil {
+			log.Fatalf("Erreur suppression utilisateur: %v", err)
+		}
+		if err := store.DeleteUserSessions(ctx, username); err != nil {
+			log.Fatalf("Erreur suppression sessions: %v", err)
+		}
+		fmt.Printf("✅ Utilisateur %s supprimé\n", username)
+	default:
+		fmt.Fprintf(os.Stderr, "Sous-commande inconnue: %s\n\n", args[0])
+		printUsersHelp()
+		os.Exit(1)
+	}
+}
+
+func usersAdd(ctx context.Context, store *storage.Storage, args []string) {
+	username := parseUsername(args)
+	fs := newFlagSet("users add")
+	role := fs.String("role", models.RoleViewer, "Rôle: viewer (lecture seule) ou admin (sync et suppression)")
+	password := fs.String("password", "", "Mot de passe (lu sur l'entrée standard si absent)")
+	fs.Parse(args[1:])
+
+	if !models.ValidRole(*role) {
+		log.Fatalf("Erreur: rôle invalide: %s (viewer ou admin)", *role)
+	}
+	hash, err := auth.HashPassword(readPassword(*password))
+	if err != nil {
+		log.Fatalf("Erreur: %v", err)
+	}
+
+	id, err := store.CreateUser(ctx, username, hash, *role)
+	if err != nil {
+		log.Fatalf("Erreur création utilisateur: %v", err)
+	}
+	fmt.Printf("✅ Utilisateur %d créé: %s (%s)\n", id, username, *role)
+}
+
+func parseUsername(args []string) string {
+	if len(args) < 1 || args[0] == "" || strings.HasPrefix(args[0], "-") {
+		log.Fatalf("Erreur: nom d'utilisateur manquant")
+	}
+	return args[0]
+}
+
+// readPassword returns password, or reads it from the standard input when empty.
+func readPassword(password string) string {
+	if password != "" {
+		return password
+	}
+	fmt.Fprint(os.Stderr, "Mot de passe: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		log.Fatalf("Erreur lecture mot de passe: %v", err)
+	}
+	return strings.TrimRight(line, "\r\n")
+}
+
+func printUsersHelp() {
+	fmt.Println("Usage: godatacleaner users <sous-commande>")
+	fmt.Println()
+	fmt.Println("Sous-commandes:")
+	fmt.Println("  list                         Lister les utilisateurs")
+	fmt.Println("  add <nom> [--role R]         Créer un utilisateur (viewer par défaut, --password)")
+	fmt.Println("  passwd <nom>                 Changer le mot de passe (--password)")
+	fmt.Println("  role <nom> <viewer|admin>    Changer le rôle")
+	fmt.Println("  remove <nom>                 Supprimer un utilisateur")
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"godatacleaner/internal/config"
+	"godatacleaner/internal/storage"
+	"godatacleaner/internal/syncer"
+)
+
+// runVerify hashes again the local files hashed by the syncs and reports those
+// whose content changed, exiting with status 1 when there are any.
+func runVerify(args []string) {
+	newFlagSet("verify").Parse(args)
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Erreur de configuration: %v", err)
+	}
+
+	store, err := storage.NewStorage(cfg.SQLitePath, cfg.SQLiteBatchSize)
+	if err != nil {
+		log.Fatalf("Erreur connexion SQLite: %v", err)
+	}
+	defer store.Close()
+	store.WithUnicodeNormalization(cfg.NormalizeUnicode).WithPathRewrites(pathRewrites(cfg)).
+		WithRelativeMarkers(cfg.RelativePathMarkers())
+
+	ctx := context.Background()
+	if err := store.Initialize(ctx); err != nil {
+		log.Fatalf("Erreur initialisation DB: %v", err)
+	}
+
+	verifyCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Println("🔐 Vérification des empreintes des fichiers locaux...")
+	report, err := syncer.NewSyncer(store, cfg).Verify(verifyCtx, func(done, total int) {
+		fmt.Printf("\r⏳ Progression: %d/%d (%.1f%%)", done, total, float64(done)/float64(total)*100)
+	})
+	fmt.Println()
+	if errors.Is(err, syncer.ErrChecksumDisabled) {
+		log.Fatalf("⛔ Empreintes désactivées, définissez CHECKSUM puis lancez une synchronisation")
+	}
+	if errors.Is(err, context.Canceled) {
+		log.Fatalf("⏹️  Vérification annulée")
+	}
+	if err != nil {
+		log.Fatalf("Erreur vérification: %v", err)
+	}
+
+	for _, issue := range report.Changed {
+		fmt.Printf("   ❗ %s: contenu modifié (taille et date inchangées)\n", issue.DiskPath)
+	}
+	for _, issue := range report.Failed {
+		fmt.Printf("   ❌ %s: %s\n", issue.DiskPath, issue.Error)
+	}
+	fmt.Printf("✅ %d fichiers vérifiés\n", report.Verified)
+	if report.Skipped > 0 {
+		fmt.Printf("⏭️  %d fichiers modifiés depuis la dernière synchronisation ignorés\n", report.Skipped)
+	}
+	if len(report.Failed) > 0 {
+		fmt.Printf("⚠️  %d fichiers introuvables ou illisibles\n", len(report.Failed))
+	}
+	if len(report.Changed) > 0 {
+		fmt.Printf("❗ %d fichiers dont le contenu a changé\n", len(report.Changed))
+		os.Exit(1)
+	}
+}
@@ -2,14 +2,21 @@ module godatacleaner
 
 go 1.24.0
 
-require github.com/mattn/go-sqlite3 v1.14.33
+require (
+	github.com/autobrr/go-qbittorrent v1.14.0
+	github.com/cespare/xxhash/v2 v2.3.0
+	github.com/fsnotify/fsnotify v1.10.1
+	github.com/mattn/go-sqlite3 v1.14.33
+	github.com/robfig/cron/v3 v3.0.1
+	golang.org/x/sync v0.19.0
+	golang.org/x/text v0.24.0
+)
 
 require (
 	github.com/Masterminds/semver v1.5.0 // indirect
-	github.com/autobrr/go-qbittorrent v1.14.0 // indirect
 	github.com/avast/retry-go v3.0.0+incompatible // indirect
 	github.com/pkg/errors v0.9.1 // indirect
 	golang.org/x/exp v0.0.0-20241108190413-2d47ceb2692f // indirect
 	golang.org/x/net v0.39.0 // indirect
-	golang.org/x/sync v0.19.0 // indirect
+	golang.org/x/sys v0.32.0 // indirect
 )
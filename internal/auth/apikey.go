@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// API keys are "gdc_" followed by 32 random bytes in hex. Being random, they
+// are stored as a plain SHA-256 hash, which allows looking them up directly.
+const (
+	apiKeyPrefix      = "gdc_"
+	apiKeyBytes       = 32
+	apiKeyShownPrefix = 12
+)
+
+// GenerateAPIKey returns a new API key, the prefix displayed to identify it,
+// and the hash to store.
+func GenerateAPIKey() (key, prefix, hash string, err error) {
+	b := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", fmt.Errorf("auth: failed to generate API key: %w", err)
+	}
+	key = apiKeyPrefix + hex.EncodeToString(b)
+	return key, key[:apiKeyShownPrefix], HashToken(key), nil
+}
+
+// HashToken returns the stored hash of a random token such as an API key
+// or a session token.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,20 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+)
+
+// sessionTokenBytes is the number of random bytes of a session token.
+const sessionTokenBytes = 32
+
+// GenerateSessionToken returns a new session token and the hash to store.
+func GenerateSessionToken() (token, hash string, err error) {
+	b := make([]byte, sessionTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("auth: failed to generate session token: %w", err)
+	}
+	token = hex.EncodeToString(b)
+	return token, HashToken(token), nil
+}
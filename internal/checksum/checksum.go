@@ -0,0 +1,193 @@
+// Package checksum computes checksums of local files, to verify their content
+// over time and to find duplicates.
+package checksum
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// Algorithm names a hash function.
+type Algorithm string
+
+// Supported hash functions.
+const (
+	XXHash Algorithm = "xxhash" // Fast, non-cryptographic
+	SHA1   Algorithm = "sha1"
+)
+
+// ErrUnknownAlgorithm is returned for an algorithm other than XXHash and SHA1.
+var ErrUnknownAlgorithm = errors.New("checksum: unknown algorithm")
+
+// chunkSize is the size of the reads, each waiting for the rate limit.
+const chunkSize = 1 << 20
+
+// Hasher computes the checksums of files with a fixed algorithm and mode.
+type Hasher struct {
+	algorithm Algorithm
+	partial   int64 // Bytes hashed at each end of a file, 0 to hash whole files
+	workers   int
+	limiter   *limiter // Nil when reads are not limited
+}
+
+// NewHasher creates a hasher using algorithm. With partial above 0, only the
+// first and last partial bytes of a file are hashed, along with its size:
+// enough to tell apart two video files, without reading them entirely.
+func NewHasher(algorithm Algorithm, partial int64) (*Hasher, error) {
+	if algorithm != XXHash && algorithm != SHA1 {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAlgorithm, algorithm)
+	}
+	return &Hasher{
+		algorithm: algorithm,
+		partial:   max(partial, 0),
+		workers:   1,
+	}, nil
+}
+
+// WithWorkers sets the number of files hashed in parallel.
+func (h *Hasher) WithWorkers(n int) *Hasher {
+	if n > 0 {
+		h.workers = n
+	}
+	return h
+}
+
+// WithRateLimit limits the bytes read per second by all the workers together,
+// so that hashing a library does not starve the disks. 0 reads at full speed.
+func (h *Hasher) WithRateLimit(bytesPerSecond int64) *Hasher {
+	h.limiter = nil
+	if bytesPerSecond > 0 {
+		h.limiter = &limiter{rate: bytesPerSecond}
+	}
+	return h
+}
+
+// Spec identifies the algorithm and the mode of the checksums, such as
+// "xxhash/16777216" or "sha1/full". Checksums computed with another spec
+// cannot be compared.
+func (h *Hasher) Spec() string {
+	if h.partial == 0 {
+		return string(h.algorithm) + "/full"
+	}
+	return fmt.Sprintf("%s/%d", h.algorithm, h.partial)
+}
+
+// Sum returns the checksum of the file at path, prefixed with the spec of the
+// hasher and a colon.
+func (h *Hasher) Sum(ctx context.Context, path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	sum := h.newHash()
+	size := info.Size()
+	if h.partial == 0 || size <= 2*h.partial {
+		err = h.copy(ctx, sum, f, size)
+	} else {
+		// Head and tail, with the size telling apart files sharing both
+		if err = h.copy(ctx, sum, f, h.partial); err == nil {
+			if _, err = f.Seek(size-h.partial, io.SeekStart); err == nil {
+				err = h.copy(ctx, sum, f, h.partial)
+			}
+		}
+		binary.Write(sum, binary.BigEndian, size)
+	}
+	if err != nil {
+		return "", err
+	}
+	return h.Spec() + ":" + hex.EncodeToString(sum.Sum(nil)), nil
+}
+
+// Result is the checksum of one file of Run, or the error computing it.
+type Result struct {
+	Path     string
+	Checksum string
+	Err      error
+}
+
+// Run hashes the files at paths with the workers of the hasher, calling fn
+// with the result of each file, from a single goroutine at a time and in no
+// particular order. It returns once every file is hashed, or ctx is
+// cancelled, returning then its error.
+func (h *Hasher) Run(ctx context.Context, paths []string, fn func(Result)) error {
+	jobs := make(chan string)
+	results := make(chan Result)
+
+	var wg sync.WaitGroup
+	for range h.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				sum, err := h.Sum(ctx, path)
+				results <- Result{Path: path, Checksum: sum, Err: err}
+			}
+		}()
+	}
+	go func() {
+		defer close(results)
+		defer wg.Wait()
+		defer close(jobs)
+		for _, path := range paths {
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- path:
+			}
+		}
+	}()
+
+	for r := range results {
+		if ctx.Err() == nil {
+			fn(r)
+		}
+	}
+	return ctx.Err()
+}
+
+// newHash returns a hash of the algorithm of the hasher.
+func (h *Hasher) newHash() hash.Hash {
+	if h.algorithm == SHA1 {
+		return sha1.New()
+	}
+	return xxhash.New()
+}
+
+// copy writes n bytes of r to w by chunks, waiting for the rate limit before
+// each of them.
+func (h *Hasher) copy(ctx context.Context, w io.Writer, r io.Reader, n int64) error {
+	buf := make([]byte, chunkSize)
+	for n > 0 {
+		chunk := min(n, chunkSize)
+		if err := h.limiter.wait(ctx, chunk); err != nil {
+			return err
+		}
+		read, err := io.ReadFull(r, buf[:chunk])
+		w.Write(buf[:read])
+		n -= int64(read)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			// The file shrank since it was opened
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package checksum
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// limiter spreads the reads of the workers of a hasher so that they do not
+// exceed rate bytes per second together.
+type limiter struct {
+	rate int64
+
+	mu   sync.Mutex
+	next time.Time // When the reads reserved so far are allowed to end
+}
+
+// wait blocks until n bytes may be read, or ctx is done. A nil limiter never
+// blocks.
+func (l *limiter) wait(ctx context.Context, n int64) error {
+	if l == nil {
+		return ctx.Err()
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	start := l.next
+	l.next = l.next.Add(time.Duration(n) * time.Second / time.Duration(l.rate))
+	l.mu.Unlock()
+
+	delay := start.Sub(now)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
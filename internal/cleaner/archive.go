@@ -0,0 +1,21 @@
+package cleaner
+
+import "path/filepath"
+
+// WithArchive enables the archive action: orphans are moved under dir, for
+// example a cold storage mount, and kept there with no retention.
+func (c *Cleaner) WithArchive(dir string) *Cleaner {
+	c.archiveDir = dir
+	return c
+}
+
+// archivePath returns the destination of a file in the archive.
+// The path relative to the local root is preserved, so that the archive mirrors
+// the library layout ("movies/Title (2020)/Title.mkv").
+func (c *Cleaner) archivePath(diskPath string) (string, error) {
+	rel, err := filepath.Rel(filepath.Clean(c.root), diskPath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(c.archiveDir, rel), nil
+}
@@ -0,0 +1,432 @@
+// Package cleaner provides removal of orphan files detected by the storage layer.
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"godatacleaner/internal/models"
+	"godatacleaner/internal/storage"
+)
+
+// Error definitions for cleanup operations
+var (
+	ErrOutsideRoot  = errors.New("path is outside the local root")
+	ErrNoQuarantine = errors.New("cleaner: quarantine directory is not configured")
+	ErrNoArchive    = errors.New("cleaner: archive directory is not configured")
+	ErrBothMoves    = errors.New("cleaner: quarantine and archive are mutually exclusive")
+	ErrNotOrphan    = errors.New("not an orphan file")
+	ErrNotDead      = errors.New("not a file of a dead torrent only")
+	ErrReadOnly     = errors.New("cleaner: local files are listed from a remote, only dry runs are possible")
+	ErrPartialSync  = errors.New("cleaner: the last sync is partial, the files of the torrents it could not read would be taken for orphans")
+)
+
+// Options controls the behaviour of a cleanup run.
+type Options struct {
+	DryRun     bool   // Only report what would be removed
+	Category   string // Restrict the run to a single category (empty = all)
+	Search     string // Restrict the run to paths containing this text (empty = all)
+	Quarantine bool   // Move files to the quarantine instead of deleting them
+	Archive    bool   // Move files to the archive instead of deleting them
+	Companions bool   // Also handle sibling files sharing the basename of an orphan video
+	// Restrict the run to files modified at least MinAge ago, and at most MaxAge ago (0 = no limit)
+	MinAge time.Duration
+	MaxAge time.Duration
+	// Restrict the run to orphans orphaned for at least MinOrphanAge (0 = no limit)
+	MinOrphanAge time.Duration
+	// Restrict the run to files of at least MinSize bytes, and at most MaxSize bytes (0 = no limit)
+	MinSize int64
+	MaxSize int64
+	// Leave out the files with other hard links, whose deletion frees no space
+	ExcludeHardlinked bool
+	// Handle the local files of dead torrents instead of the orphans, see WithDeadTrackerMessages
+	DeadTorrents bool
+}
+
+// Action records what happened (or would happen) to a single orphan file.
+type Action struct {
+	File        models.OrphanFile `json:"file"`
+	DiskPath    string            `json:"disk_path"`
+	MovedTo     string            `json:"moved_to,omitempty"`
+	Protected   bool              `json:"protected,omitempty"`
+	CompanionOf string            `json:"companion_of,omitempty"`
+	Error       string            `json:"error,omitempty"`
+}
+
+// Report summarizes a cleanup run.
+type Report struct {
+	DryRun         bool     `json:"dry_run"`
+	Quarantine     bool     `json:"quarantine"`
+	Archive        bool     `json:"archive"`
+	Actions        []Action `json:"actions"`
+	FilesRemoved   int64    `json:"files_removed"`
+	BytesReclaimed int64    `json:"bytes_reclaimed"` // Allocated on disk, below the size of sparse files
+	Protected      int64    `json:"protected"`
+	Failed         int64    `json:"failed"`
+}
+
+// Files returns the files handled by the run, as recorded in its history.
+// Protected files, left in place, are not part of it.
+func (r *Report) Files() []models.CleanupRunFile {
+	var files []models.CleanupRunFile
+	for _, a := range r.Actions {
+		if a.Protected {
+			continue
+		}
+		files = append(files, models.CleanupRunFile{
+			FilePath: a.File.FilePath,
+			Size:     a.File.Size,
+			MovedTo:  a.MovedTo,
+			Error:    a.Error,
+		})
+	}
+	return files
+}
+
+// Cleaner deletes orphan files from the local filesystem.
+type Cleaner struct {
+	storage       storage.Store
+	root          string
+	quarantineDir string
+	retention     time.Duration
+	archiveDir    string
+	protected     []string
+	companionExts []string
+	deadMessages  []string
+	scanFilter    models.FileFilter
+	readOnly      bool
+}
+
+// NewCleaner creates a new cleaner for orphans found under the given local root.
+func NewCleaner(store storage.Store, root string) *Cleaner {
+	return &Cleaner{
+		storage: store,
+		root:    root,
+	}
+}
+
+// Run removes every orphan file matching the options, or moves it to the
+// quarantine when opts.Quarantine is set, or to the archive when opts.Archive is set.
+// In dry-run mode the filesystem and the database are left untouched,
+// the report only lists what would be removed and the space reclaimed.
+// Removed files are also deleted from local_files so they stop showing up as orphans.
+// Files matching a protected pattern are always skipped, and nothing is
+// removed after a partial sync, see CheckLastSync.
+func (c *Cleaner) Run(ctx context.Context, opts Options) (*Report, error) {
+	if err := c.checkOptions(opts); err != nil {
+		return nil, err
+	}
+	if err := c.checkSync(ctx, opts); err != nil {
+		return nil, err
+	}
+
+	orphans, err := c.collect(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.apply(ctx, orphans, opts)
+}
+
+// RunFiles handles the given files like Run, ignoring the category and search
+// filters. Paths that are not orphans, or not files of dead torrents with
+// opts.DeadTorrents, are reported as failures and left untouched.
+func (c *Cleaner) RunFiles(ctx context.Context, paths []string, opts Options) (*Report, error) {
+	if err := c.checkOptions(opts); err != nil {
+		return nil, err
+	}
+	if err := c.checkSync(ctx, opts); err != nil {
+		return nil, err
+	}
+
+	orphans, err := c.filesByPath(ctx, paths, opts)
+	if err != nil {
+		return nil, err
+	}
+	notFound := ErrNotOrphan
+	if opts.DeadTorrents {
+		notFound = ErrNotDead
+	}
+
+	found := make(map[string]bool, len(orphans))
+	for _, f := range orphans {
+		found[f.FilePath] = true
+	}
+
+	report, err := c.apply(ctx, orphans, opts)
+	if report == nil {
+		return nil, err
+	}
+	for _, p := range paths {
+		if !found[p] {
+			found[p] = true
+			report.Actions = append(report.Actions, Action{
+				File:  models.OrphanFile{FilePath: p, FileName: filepath.Base(p)},
+				Error: notFound.Error(),
+			})
+			report.Failed++
+		}
+	}
+	return report, err
+}
+
+// WithReadOnly makes every run that is not a dry run fail with ErrReadOnly,
+// for local files listed from a remote rather than found under the root.
+func (c *Cleaner) WithReadOnly() *Cleaner {
+	c.readOnly = true
+	return c
+}
+
+// HasQuarantine reports whether a quarantine directory is configured.
+func (c *Cleaner) HasQuarantine() bool {
+	return c.quarantineDir != ""
+}
+
+// checkOptions verifies that the options can be honoured by this cleaner.
+func (c *Cleaner) checkOptions(opts Options) error {
+	if c.readOnly && !opts.DryRun {
+		return ErrReadOnly
+	}
+	if opts.Quarantine && c.quarantineDir == "" {
+		return ErrNoQuarantine
+	}
+	if opts.Archive && c.archiveDir == "" {
+		return ErrNoArchive
+	}
+	if opts.Quarantine && opts.Archive {
+		return ErrBothMoves
+	}
+	return nil
+}
+
+// CheckLastSync returns ErrPartialSync when the last sync that wrote the
+// database is partial: the torrents it could not read, or all those of an
+// unreachable client, may have files it does not know, which look like
+// orphans until a complete sync. A store without sync history, such as
+// storage.Memory, never blocks.
+func (c *Cleaner) CheckLastSync(ctx context.Context) error {
+	runs, err := c.storage.ListSyncRuns(ctx, 0)
+	if err != nil {
+		return fmt.Errorf("cleaner: %w", err)
+	}
+	// Failed and cancelled syncs leave the database as the previous one wrote it
+	for _, run := range runs {
+		switch run.Status {
+		case models.SyncRunDone:
+			return nil
+		case models.SyncRunPartial:
+			return fmt.Errorf("%w (sync #%d)", ErrPartialSync, run.ID)
+		}
+	}
+	return nil
+}
+
+// checkSync checks the last sync before a run that is not a dry run.
+func (c *Cleaner) checkSync(ctx context.Context, opts Options) error {
+	if opts.DryRun {
+		return nil
+	}
+	return c.CheckLastSync(ctx)
+}
+
+// target is a file to handle, either an orphan or the companion of one.
+type target struct {
+	file        models.OrphanFile
+	companionOf string
+}
+
+// apply removes or quarantines the given files according to the options.
+func (c *Cleaner) apply(ctx context.Context, files []models.OrphanFile, opts Options) (*Report, error) {
+	protect, err := c.loadProtection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]target, 0, len(files))
+	for _, f := range files {
+		targets = append(targets, target{file: f})
+	}
+	if opts.Companions {
+		if targets, err = c.addCompanions(ctx, targets); err != nil {
+			return nil, err
+		}
+	}
+
+	report := &Report{DryRun: opts.DryRun, Quarantine: opts.Quarantine, Archive: opts.Archive}
+	now := time.Now()
+	batch := now.Format(batchLayout)
+	var removed []string
+	var quarantined []models.QuarantineEntry
+
+	for _, t := range targets {
+		// Stop on cancellation, but still record what was already done
+		if ctx.Err() != nil {
+			break
+		}
+
+		f := t.file
+		action := Action{File: f, CompanionOf: t.companionOf}
+		diskPath, err := c.resolve(f.FilePath)
+		if err == nil && protect.match(c.root, diskPath) {
+			action.DiskPath = diskPath
+			action.Protected = true
+			report.Protected++
+			report.Actions = append(report.Actions, action)
+			continue
+		}
+		if err == nil {
+			action.DiskPath = diskPath
+			switch {
+			case opts.Quarantine:
+				action.MovedTo, err = c.quarantinePath(batch, diskPath)
+			case opts.Archive:
+				action.MovedTo, err = c.archivePath(diskPath)
+			}
+		}
+		if err == nil && !opts.DryRun {
+			if action.MovedTo != "" {
+				err = moveFile(diskPath, action.MovedTo)
+			} else {
+				err = removeFile(diskPath)
+			}
+		}
+
+		if err != nil {
+			action.Error = err.Error()
+			report.Failed++
+		} else {
+			report.FilesRemoved++
+			report.BytesReclaimed += f.Allocated
+			removed = append(removed, f.FilePath)
+			if opts.Quarantine {
+				quarantined = append(quarantined, models.QuarantineEntry{
+					FilePath:       f.FilePath,
+					DiskPath:       diskPath,
+					QuarantinePath: action.MovedTo,
+					Batch:          batch,
+					Size:           f.Size,
+					Category:       f.Category,
+					QuarantinedAt:  now,
+				})
+			}
+		}
+		report.Actions = append(report.Actions, action)
+	}
+
+	if !opts.DryRun {
+		// Use a context that survives cancellation: the files are already gone
+		dbCtx := context.WithoutCancel(ctx)
+		if err := c.storage.AddQuarantineEntries(dbCtx, quarantined); err != nil {
+			return report, fmt.Errorf("cleaner: %w", err)
+		}
+		if err := c.storage.DeleteLocalFiles(dbCtx, removed); err != nil {
+			return report, fmt.Errorf("cleaner: %w", err)
+		}
+	}
+
+	return report, ctx.Err()
+}
+
+// filesByPath returns the files among paths handled by a run with opts.
+func (c *Cleaner) filesByPath(ctx context.Context, paths []string, opts Options) ([]models.OrphanFile, error) {
+	if !opts.DeadTorrents {
+		orphans, err := c.storage.GetOrphanFilesByPath(ctx, paths)
+		if err != nil {
+			return nil, fmt.Errorf("cleaner: %w", err)
+		}
+		return orphans, nil
+	}
+
+	dead, err := c.storage.GetDeadTorrentFiles(ctx, c.deadMessages, models.QueryOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("cleaner: %w", err)
+	}
+	wanted := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		wanted[p] = true
+	}
+	var files []models.OrphanFile
+	for _, f := range dead {
+		if wanted[f.FilePath] {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// collect loads all orphan files matching the options, page by page, or the
+// files of dead torrents with opts.DeadTorrents.
+func (c *Cleaner) collect(ctx context.Context, opts Options) ([]models.OrphanFile, error) {
+	const perPage = 1000
+
+	if opts.DeadTorrents {
+		files, err := c.storage.GetDeadTorrentFiles(ctx, c.deadMessages, models.QueryOptions{
+			Category:          opts.Category,
+			Search:            opts.Search,
+			MinAge:            opts.MinAge,
+			MaxAge:            opts.MaxAge,
+			MinSize:           opts.MinSize,
+			MaxSize:           opts.MaxSize,
+			ExcludeHardlinked: opts.ExcludeHardlinked,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("cleaner: %w", err)
+		}
+		return files, nil
+	}
+
+	// Pages follow the cursor of the last orphan, so that none is skipped
+	query := models.QueryOptions{
+		PerPage:           perPage,
+		Sort:              "file_path",
+		Order:             "asc",
+		Category:          opts.Category,
+		Search:            opts.Search,
+		MinAge:            opts.MinAge,
+		MaxAge:            opts.MaxAge,
+		MinOrphanAge:      opts.MinOrphanAge,
+		MinSize:           opts.MinSize,
+		MaxSize:           opts.MaxSize,
+		ExcludeHardlinked: opts.ExcludeHardlinked,
+	}
+	var orphans []models.OrphanFile
+	for {
+		files, _, err := c.storage.GetOrphanFiles(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("cleaner: %w", err)
+		}
+		orphans = append(orphans, files...)
+		if len(files) < perPage {
+			return orphans, nil
+		}
+		query.After = storage.OrphanFileCursor(query.Sort, files[len(files)-1])
+	}
+}
+
+// resolve maps a stored file path back to its location on disk.
+// Local paths are stored normalized (see storage.Storage.WithPathRewrites),
+// so the rewrite rules are reversed to find the path under the root.
+// Paths that do not live under the root are rejected.
+func (c *Cleaner) resolve(path string) (string, error) {
+	diskPath, ok := c.storage.DiskPath(c.root, path)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrOutsideRoot, path)
+	}
+	return diskPath, nil
+}
+
+// removeFile deletes a regular file, refusing to touch directories.
+func removeFile(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("refusing to remove directory: %s", path)
+	}
+	return os.Remove(path)
+}
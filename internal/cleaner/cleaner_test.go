@@ -0,0 +1,294 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"godatacleaner/internal/models"
+	"godatacleaner/internal/storage"
+)
+
+// library creates the given files under a temporary root, all of them local
+// files of an in-memory store, and the torrent expecting the files of
+// torrentPaths among them.
+func library(t *testing.T, paths []string, torrentPaths ...string) (*storage.Memory, string) {
+	t.Helper()
+	root := t.TempDir()
+
+	var local []models.LocalFile
+	for _, p := range paths {
+		diskPath := filepath.Join(root, p)
+		if err := os.MkdirAll(filepath.Dir(diskPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(diskPath, []byte(p), 0644); err != nil {
+			t.Fatal(err)
+		}
+		local = append(local, models.LocalFile{
+			FilePath: diskPath,
+			FileName: filepath.Base(p),
+			Size:     int64(len(p)),
+			Category: "movies",
+			Links:    1,
+		})
+	}
+
+	var files []models.TorrentFile
+	for _, p := range torrentPaths {
+		files = append(files, models.TorrentFile{
+			TorrentHash: "t",
+			FileName:    filepath.Base(p),
+			FilePath:    filepath.Join(root, p),
+			Instance:    "qbittorrent",
+		})
+	}
+
+	store := storage.NewMemory()
+	store.SetTorrents([]models.Torrent{{Hash: "t", State: models.TorrentStateSeeding, Instance: "qbittorrent"}}, files)
+	if err := store.InsertLocalFiles(context.Background(), local); err != nil {
+		t.Fatal(err)
+	}
+	store.UpdateOrphans(time.Now())
+	return store, root
+}
+
+func exists(t *testing.T, path string) bool {
+	t.Helper()
+	_, err := os.Lstat(path)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	return err == nil
+}
+
+func TestRunDryRun(t *testing.T) {
+	ctx := context.Background()
+	store, root := library(t, []string{"movies/A/a.mkv", "movies/B/b.mkv"}, "movies/A/a.mkv")
+
+	report, err := NewCleaner(store, root).Run(ctx, Options{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.FilesRemoved != 1 || len(report.Actions) != 1 || report.Actions[0].File.FilePath != filepath.Join(root, "movies/B/b.mkv") {
+		t.Errorf("dry run reports %+v, want movies/B/b.mkv only", report)
+	}
+	if !exists(t, filepath.Join(root, "movies/B/b.mkv")) {
+		t.Error("dry run removed the orphan")
+	}
+	if _, total, _ := store.GetOrphanFiles(ctx, models.QueryOptions{}); total != 1 {
+		t.Errorf("dry run left %d orphans, want 1", total)
+	}
+}
+
+func TestRunProtected(t *testing.T) {
+	ctx := context.Background()
+	store, root := library(t, []string{"movies/Keep/k.mkv", "movies/B/b.mkv"})
+	if _, err := store.AddProtectedPath(ctx, "movies/Keep"); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := NewCleaner(store, root).Run(ctx, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Protected != 1 || report.FilesRemoved != 1 {
+		t.Errorf("run reports %d protected, %d removed, want 1 and 1", report.Protected, report.FilesRemoved)
+	}
+	if !exists(t, filepath.Join(root, "movies/Keep/k.mkv")) {
+		t.Error("protected file removed")
+	}
+	if exists(t, filepath.Join(root, "movies/B/b.mkv")) {
+		t.Error("orphan not removed")
+	}
+	if files := report.Files(); len(files) != 1 || files[0].FilePath != filepath.Join(root, "movies/B/b.mkv") {
+		t.Errorf("Files() = %+v, want movies/B/b.mkv only", files)
+	}
+
+	// Patterns of the configuration protect absolute paths as well
+	report, err = NewCleaner(store, root).WithProtectedPaths([]string{filepath.Join(root, "movies")}).Run(ctx, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Protected != 1 || report.FilesRemoved != 0 {
+		t.Errorf("run reports %d protected, %d removed, want 1 and 0", report.Protected, report.FilesRemoved)
+	}
+}
+
+func TestQuarantineRestore(t *testing.T) {
+	ctx := context.Background()
+	store, root := library(t, []string{"movies/B/b.mkv"})
+	diskPath := filepath.Join(root, "movies/B/b.mkv")
+	c := NewCleaner(store, root).WithQuarantine(t.TempDir(), time.Hour)
+
+	report, err := c.Run(ctx, Options{Quarantine: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.FilesRemoved != 1 {
+		t.Fatalf("run reports %+v, want 1 file quarantined", report)
+	}
+	movedTo := report.Actions[0].MovedTo
+	if exists(t, diskPath) || !exists(t, movedTo) {
+		t.Fatalf("file not moved from %s to %s", diskPath, movedTo)
+	}
+	if _, total, _ := store.GetOrphanFiles(ctx, models.QueryOptions{}); total != 0 {
+		t.Errorf("%d orphans left after the quarantine, want 0", total)
+	}
+
+	entries, _, err := store.ListQuarantine(ctx, models.QueryOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("%d quarantine entries, want 1", len(entries))
+	}
+	restore, err := c.RestoreBatch(ctx, entries[0].Batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restore.Restored != 1 || restore.Failed != 0 {
+		t.Fatalf("restore reports %+v, want 1 file restored", restore)
+	}
+	if !exists(t, diskPath) || exists(t, movedTo) {
+		t.Errorf("file not moved back from %s to %s", movedTo, diskPath)
+	}
+	if _, total, _ := store.GetOrphanFiles(ctx, models.QueryOptions{}); total != 1 {
+		t.Errorf("%d orphans after the restore, want 1", total)
+	}
+
+	// A restored file cannot be restored twice
+	restore, err = c.Restore(ctx, []int64{entries[0].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restore.Failed != 1 {
+		t.Errorf("second restore reports %+v, want a failure", restore)
+	}
+}
+
+func TestRunCompanions(t *testing.T) {
+	ctx := context.Background()
+	// Siblings on disk only, as left by a media manager
+	setup := func(t *testing.T) (*Cleaner, string) {
+		store, root := library(t, []string{"movies/B/b.mkv", "movies/A/a.mkv"}, "movies/A/a.mkv")
+		for _, p := range []string{"movies/B/b.en.srt", "movies/B/b.nfo", "movies/B/other.srt", "movies/A/a.srt"} {
+			if err := os.WriteFile(filepath.Join(root, p), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return NewCleaner(store, root).WithCompanionExtensions([]string{"srt", ".NFO"}), root
+	}
+
+	c, root := setup(t)
+	report, err := c.Run(ctx, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.FilesRemoved != 1 || exists(t, filepath.Join(root, "movies/B/b.mkv")) {
+		t.Fatalf("run reports %+v, want the orphan only removed", report)
+	}
+	if !exists(t, filepath.Join(root, "movies/B/b.en.srt")) {
+		t.Error("companion removed without Options.Companions")
+	}
+
+	c, root = setup(t)
+	report, err = c.Run(ctx, Options{Companions: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	orphan := filepath.Join(root, "movies/B/b.mkv")
+	companions := map[string]bool{}
+	for _, a := range report.Actions {
+		if a.CompanionOf != "" {
+			if a.CompanionOf != orphan {
+				t.Errorf("%s is a companion of %s, want %s", a.File.FilePath, a.CompanionOf, orphan)
+			}
+			companions[a.File.FileName] = true
+		}
+	}
+	if len(companions) != 2 || !companions["b.en.srt"] || !companions["b.nfo"] {
+		t.Errorf("companions = %v, want b.en.srt and b.nfo", companions)
+	}
+	for p, want := range map[string]bool{
+		"movies/B/b.en.srt":  false,
+		"movies/B/b.nfo":     false,
+		"movies/B/other.srt": true, // Another basename
+		"movies/A/a.srt":     true, // Beside a file expected by the torrent
+		"movies/A/a.mkv":     true,
+	} {
+		if exists(t, filepath.Join(root, p)) != want {
+			t.Errorf("%s exists = %v, want %v", p, !want, want)
+		}
+	}
+}
+
+func TestRunAfterPartialSync(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	diskPath := filepath.Join(root, "movies/B/b.mkv")
+	if err := os.MkdirAll(filepath.Dir(diskPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(diskPath, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := storage.NewStorage(filepath.Join(t.TempDir(), "test.db"), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if err := store.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := store.BeginSync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	local := []models.LocalFile{{FilePath: diskPath, FileName: "b.mkv", Size: 4, Allocated: 4, Category: "movies", Links: 1}}
+	if _, err := tx.ReplaceLocalFiles(ctx, local, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(ctx, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	record := func(status string) {
+		t.Helper()
+		now := time.Now()
+		if _, err := store.RecordSyncRun(ctx, models.SyncRun{StartedAt: now, FinishedAt: now, Status: status}, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := NewCleaner(store, root)
+
+	// The files of a torrent whose files could not be read look like orphans
+	record(models.SyncRunPartial)
+	record(models.SyncRunFailed) // Leaves the database as the partial sync wrote it
+	if _, err := c.Run(ctx, Options{}); !errors.Is(err, ErrPartialSync) {
+		t.Fatalf("run after a partial sync = %v, want ErrPartialSync", err)
+	}
+	if _, err := c.RunFiles(ctx, []string{diskPath}, Options{}); !errors.Is(err, ErrPartialSync) {
+		t.Fatalf("run of files after a partial sync = %v, want ErrPartialSync", err)
+	}
+	if !exists(t, diskPath) {
+		t.Fatal("file removed after a partial sync")
+	}
+	if report, err := c.Run(ctx, Options{DryRun: true}); err != nil || report.FilesRemoved != 1 {
+		t.Fatalf("dry run after a partial sync = %+v, %v, want 1 file", report, err)
+	}
+
+	record(models.SyncRunDone)
+	report, err := c.Run(ctx, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.FilesRemoved != 1 || exists(t, diskPath) {
+		t.Errorf("run after a complete sync reports %+v, want the orphan removed", report)
+	}
+}
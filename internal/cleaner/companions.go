@@ -0,0 +1,127 @@
+package cleaner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"godatacleaner/internal/models"
+	"godatacleaner/internal/scanner"
+)
+
+// videoExtensions lists the extensions of files that can have companions.
+var videoExtensions = map[string]bool{
+	".mkv": true, ".mp4": true, ".avi": true, ".m4v": true, ".mov": true,
+	".wmv": true, ".ts": true, ".m2ts": true, ".mpg": true, ".mpeg": true,
+}
+
+// WithCompanionExtensions sets the extensions of the sibling files (subtitles,
+// nfo, artwork...) handled together with an orphan video.
+func (c *Cleaner) WithCompanionExtensions(exts []string) *Cleaner {
+	c.companionExts = nil
+	for _, ext := range exts {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext != "" && !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		if ext != "" {
+			c.companionExts = append(c.companionExts, ext)
+		}
+	}
+	return c
+}
+
+// addCompanions appends the companions of every orphan video to the targets.
+// Orphans are grouped by directory and basename: a companion is a sibling whose
+// name starts with the video basename ("Movie.mkv" → "Movie.srt", "Movie.en.srt",
+// "Movie.nfo") and has a companion extension. Siblings that are already targets
+// or that belong to a torrent are left out.
+func (c *Cleaner) addCompanions(ctx context.Context, targets []target) ([]target, error) {
+	if len(c.companionExts) == 0 {
+		return targets, nil
+	}
+
+	seen := make(map[string]bool, len(targets))
+	videos := make(map[string][]target) // directory → orphan videos
+	var dirs []string
+	for _, t := range targets {
+		seen[t.file.FilePath] = true
+		if videoExtensions[strings.ToLower(filepath.Ext(t.file.FileName))] {
+			dir := filepath.Dir(t.file.FilePath)
+			if _, ok := videos[dir]; !ok {
+				dirs = append(dirs, dir)
+			}
+			videos[dir] = append(videos[dir], t)
+		}
+	}
+	sort.Strings(dirs)
+
+	for _, dir := range dirs {
+		diskDir, err := c.resolve(dir)
+		if err != nil {
+			continue
+		}
+		entries, err := os.ReadDir(diskDir)
+		if err != nil {
+			continue
+		}
+
+		for _, e := range entries {
+			if !e.Type().IsRegular() || !c.isCompanionExt(e.Name()) {
+				continue
+			}
+			path := filepath.Join(dir, e.Name())
+			if seen[path] {
+				continue
+			}
+
+			for _, v := range videos[dir] {
+				stem := strings.TrimSuffix(v.file.FileName, filepath.Ext(v.file.FileName))
+				if !strings.HasPrefix(e.Name(), stem+".") {
+					continue
+				}
+
+				// Never touch a file that is still expected by a torrent
+				inTorrent, err := c.storage.HasTorrentFile(ctx, path)
+				if err != nil {
+					return nil, fmt.Errorf("cleaner: %w", err)
+				}
+				if inTorrent {
+					break
+				}
+
+				info, err := e.Info()
+				if err != nil {
+					break
+				}
+				seen[path] = true
+				targets = append(targets, target{
+					file: models.OrphanFile{
+						FilePath:  path,
+						FileName:  e.Name(),
+						Size:      info.Size(),
+						Allocated: scanner.AllocatedSize(info),
+						Category:  v.file.Category,
+					},
+					companionOf: v.file.FilePath,
+				})
+				break
+			}
+		}
+	}
+
+	return targets, nil
+}
+
+func (c *Cleaner) isCompanionExt(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range c.companionExts {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
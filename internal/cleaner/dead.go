@@ -0,0 +1,48 @@
+package cleaner
+
+import (
+	"context"
+	"fmt"
+
+	"godatacleaner/internal/models"
+)
+
+// WithDeadTrackerMessages sets the tracker error messages, matched without
+// case, telling that a torrent is no longer registered on its tracker ("dead").
+// The local files of dead torrents are wasted space, handled with Options.DeadTorrents.
+func (c *Cleaner) WithDeadTrackerMessages(patterns []string) *Cleaner {
+	c.deadMessages = patterns
+	return c
+}
+
+// DeadTorrentsReport lists the dead torrents and the local files only they expect.
+type DeadTorrentsReport struct {
+	Torrents         []models.Torrent `json:"torrents"`
+	TotalSize        int64            `json:"total_size"`
+	ReclaimableFiles int64            `json:"reclaimable_files"`
+	ReclaimableBytes int64            `json:"reclaimable_bytes"`
+}
+
+// DeadTorrents reports the dead torrents found by the last sync. The
+// reclaimable space is that of their local files not expected by a live
+// torrent, as handled by a run with Options.DeadTorrents.
+func (c *Cleaner) DeadTorrents(ctx context.Context) (*DeadTorrentsReport, error) {
+	torrents, err := c.storage.GetDeadTorrents(ctx, c.deadMessages)
+	if err != nil {
+		return nil, fmt.Errorf("cleaner: %w", err)
+	}
+	files, err := c.storage.GetDeadTorrentFiles(ctx, c.deadMessages, models.QueryOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("cleaner: %w", err)
+	}
+
+	report := &DeadTorrentsReport{Torrents: torrents}
+	for _, t := range torrents {
+		report.TotalSize += t.Size
+	}
+	for _, f := range files {
+		report.ReclaimableFiles++
+		report.ReclaimableBytes += f.Allocated
+	}
+	return report, nil
+}
@@ -0,0 +1,54 @@
+package cleaner
+
+import (
+	"context"
+	"fmt"
+
+	"godatacleaner/internal/models"
+)
+
+// ExtrasReport lists the samples, proofs, extras and junk files, orphans or
+// not, and their size by pseudo-category.
+type ExtrasReport struct {
+	Categories  []models.CategoryStats `json:"categories"`
+	Files       []models.ExtraFile     `json:"files"`
+	TotalFiles  int64                  `json:"total_files"`
+	TotalSize   int64                  `json:"total_size"`
+	OrphanFiles int64                  `json:"orphan_files"`
+	OrphanBytes int64                  `json:"orphan_bytes"`
+}
+
+// Extras reports the local files of the extra pseudo-categories found by the
+// last scan. Those still expected by a torrent are reported too: they are only
+// removed with their torrent, while the orphans are handled by a run with
+// Options.Category.
+func (c *Cleaner) Extras(ctx context.Context) (*ExtrasReport, error) {
+	files, err := c.storage.GetExtraFiles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cleaner: %w", err)
+	}
+
+	report := &ExtrasReport{
+		Categories: make([]models.CategoryStats, len(models.ExtraCategories)),
+		Files:      files,
+	}
+	byCategory := make(map[string]*models.CategoryStats)
+	for i, name := range models.ExtraCategories {
+		report.Categories[i].Category = name
+		byCategory[name] = &report.Categories[i]
+	}
+	for _, f := range files {
+		if cs := byCategory[f.Category]; cs != nil {
+			cs.FileCount++
+			cs.TotalSize += f.Size
+			cs.AllocatedSize += f.Allocated
+		}
+		report.TotalFiles++
+		report.TotalSize += f.Size
+		if f.Orphan {
+			report.OrphanFiles++
+			report.OrphanBytes += f.Allocated
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,68 @@
+package cleaner
+
+import (
+	"context"
+	"fmt"
+
+	"godatacleaner/internal/models"
+)
+
+// WithScanFilter sets the filter of the scans (SCAN_MIN_SIZE and
+// SCAN_EXTENSIONS): the torrent files it leaves out are never found locally,
+// so they are not reported missing.
+func (c *Cleaner) WithScanFilter(filter models.FileFilter) *Cleaner {
+	c.scanFilter = filter
+	return c
+}
+
+// MissingReport lists the torrents whose files were not all found locally,
+// or found with another size, the reverse of the orphans.
+type MissingReport struct {
+	Torrents        []models.MissingTorrent `json:"torrents"`
+	MissingFiles    int64                   `json:"missing_files"`
+	MissingBytes    int64                   `json:"missing_bytes"`
+	MismatchedFiles int64                   `json:"mismatched_files"`
+	MismatchedBytes int64                   `json:"mismatched_bytes"`
+}
+
+// Missing reports the torrents expecting files that the last scan did not
+// find, or found with another size: their data was deleted, moved or
+// truncated outside of the torrent client. Torrents still downloading are
+// left out.
+func (c *Cleaner) Missing(ctx context.Context) (*MissingReport, error) {
+	torrents, err := c.storage.GetMissingTorrents(ctx, c.scanFilter)
+	if err != nil {
+		return nil, fmt.Errorf("cleaner: %w", err)
+	}
+
+	report := &MissingReport{Torrents: torrents}
+	for _, t := range torrents {
+		report.MissingFiles += t.MissingFiles
+		report.MissingBytes += t.MissingBytes
+		report.MismatchedFiles += t.MismatchedFiles
+		report.MismatchedBytes += t.MismatchedBytes
+	}
+	return report, nil
+}
+
+// MissingFiles returns the files of a torrent that the last scan did not find,
+// or found with another size.
+func (c *Cleaner) MissingFiles(ctx context.Context, instance, hash string) ([]models.MissingFile, error) {
+	files, err := c.storage.GetMissingFiles(ctx, instance, hash, c.scanFilter)
+	if err != nil {
+		return nil, fmt.Errorf("cleaner: %w", err)
+	}
+	return files, nil
+}
+
+// Completeness returns the share of the files of every torrent found by the
+// last scan, at most maxCompleteness percent, paginated as opts describes.
+// Partially deleted torrents come first, to be rechecked so that the client
+// downloads their files again, or removed.
+func (c *Cleaner) Completeness(ctx context.Context, opts models.QueryOptions, maxCompleteness float64) ([]models.TorrentCompleteness, int64, error) {
+	torrents, total, err := c.storage.GetTorrentCompleteness(ctx, opts, c.scanFilter, maxCompleteness)
+	if err != nil {
+		return nil, 0, fmt.Errorf("cleaner: %w", err)
+	}
+	return torrents, total, nil
+}
@@ -0,0 +1,122 @@
+package cleaner
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// protection holds the compiled list of protected path patterns.
+//
+// A pattern is a glob (see path.Match) or a plain prefix. Relative patterns
+// such as "shows/kids" are matched against the path relative to the local root,
+// absolute patterns against the full path on disk. A pattern protects the
+// matching path and everything below it.
+type protection struct {
+	patterns []string
+}
+
+// WithProtectedPaths sets the protected patterns coming from the configuration.
+// They are merged with the patterns stored in the database on every run.
+func (c *Cleaner) WithProtectedPaths(patterns []string) *Cleaner {
+	c.protected = patterns
+	return c
+}
+
+// ValidatePattern checks that a protected path pattern is well-formed.
+func ValidatePattern(pattern string) error {
+	if strings.TrimSpace(pattern) == "" {
+		return fmt.Errorf("cleaner: empty protected pattern")
+	}
+	if _, err := path.Match(cleanPattern(pattern), ""); err != nil {
+		return fmt.Errorf("cleaner: invalid protected pattern %q: %w", pattern, err)
+	}
+	return nil
+}
+
+// loadProtection merges configured and stored patterns.
+func (c *Cleaner) loadProtection(ctx context.Context) (*protection, error) {
+	patterns := append([]string{}, c.protected...)
+
+	stored, err := c.storage.ListProtectedPaths(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cleaner: %w", err)
+	}
+	for _, p := range stored {
+		patterns = append(patterns, p.Pattern)
+	}
+
+	p := &protection{}
+	for _, pattern := range patterns {
+		if err := ValidatePattern(pattern); err != nil {
+			return nil, err
+		}
+		p.patterns = append(p.patterns, cleanPattern(pattern))
+	}
+	return p, nil
+}
+
+// IsProtected reports whether the file at diskPath must never be touched.
+func (c *Cleaner) IsProtected(ctx context.Context, diskPath string) (bool, error) {
+	p, err := c.loadProtection(ctx)
+	if err != nil {
+		return false, err
+	}
+	return p.match(c.root, diskPath), nil
+}
+
+// ProtectedPaths returns the stored local paths among paths whose file on
+// disk is protected, for the deletions that do not go through Run, such as
+// the data of a torrent deleted by its client. A path that does not live
+// under the root is checked as it is, against the absolute patterns.
+func (c *Cleaner) ProtectedPaths(ctx context.Context, paths []string) ([]string, error) {
+	p, err := c.loadProtection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var protected []string
+	for _, path := range paths {
+		diskPath, err := c.resolve(path)
+		if err != nil {
+			diskPath = path
+		}
+		if p.match(c.root, diskPath) {
+			protected = append(protected, path)
+		}
+	}
+	return protected, nil
+}
+
+// match reports whether diskPath, or one of its parent directories, matches a pattern.
+func (p *protection) match(root, diskPath string) bool {
+	abs := filepath.ToSlash(filepath.Clean(diskPath))
+	var rel string
+	if r, err := filepath.Rel(filepath.Clean(root), filepath.Clean(diskPath)); err == nil {
+		rel = filepath.ToSlash(r)
+	}
+
+	for _, pattern := range p.patterns {
+		candidate := rel
+		if strings.HasPrefix(pattern, "/") {
+			candidate = abs
+		}
+		for candidate != "" && candidate != "." && candidate != "/" {
+			if ok, _ := path.Match(pattern, candidate); ok {
+				return true
+			}
+			candidate = path.Dir(candidate)
+		}
+	}
+	return false
+}
+
+// cleanPattern normalizes separators and removes trailing slashes.
+func cleanPattern(pattern string) string {
+	pattern = filepath.ToSlash(strings.TrimSpace(pattern))
+	if len(pattern) > 1 {
+		pattern = strings.TrimRight(pattern, "/")
+	}
+	return pattern
+}
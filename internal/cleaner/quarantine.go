@@ -0,0 +1,257 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"godatacleaner/internal/models"
+	"godatacleaner/internal/scanner"
+	"godatacleaner/internal/storage"
+)
+
+// batchLayout is the name format of the per-run directories created in the quarantine.
+const batchLayout = "2006-01-02_15-04-05"
+
+// PurgeReport summarizes a purge of expired quarantine batches.
+type PurgeReport struct {
+	DryRun         bool     `json:"dry_run"`
+	Batches        []string `json:"batches"`
+	FilesRemoved   int64    `json:"files_removed"`
+	BytesReclaimed int64    `json:"bytes_reclaimed"`
+}
+
+// RestoreAction records the outcome of restoring a single quarantined file.
+type RestoreAction struct {
+	Entry models.QuarantineEntry `json:"entry"`
+	Error string                 `json:"error,omitempty"`
+}
+
+// RestoreReport summarizes a restore of quarantined files.
+type RestoreReport struct {
+	Actions  []RestoreAction `json:"actions"`
+	Restored int64           `json:"restored"`
+	Failed   int64           `json:"failed"`
+}
+
+// WithQuarantine enables quarantine mode: orphans are moved under dir instead of
+// being deleted, and kept for the retention period before Purge removes them.
+func (c *Cleaner) WithQuarantine(dir string, retention time.Duration) *Cleaner {
+	c.quarantineDir = dir
+	c.retention = retention
+	return c
+}
+
+// quarantinePath returns the destination of a file in the quarantine batch.
+// The path relative to the local root is preserved so files can be put back.
+func (c *Cleaner) quarantinePath(batch, diskPath string) (string, error) {
+	rel, err := filepath.Rel(filepath.Clean(c.root), diskPath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(c.quarantineDir, batch, rel), nil
+}
+
+// Purge permanently removes quarantine batches older than the retention period.
+func (c *Cleaner) Purge(ctx context.Context, dryRun bool) (*PurgeReport, error) {
+	if c.quarantineDir == "" {
+		return nil, ErrNoQuarantine
+	}
+
+	entries, err := os.ReadDir(c.quarantineDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &PurgeReport{DryRun: dryRun}, nil
+		}
+		return nil, fmt.Errorf("cleaner: failed to read quarantine: %w", err)
+	}
+
+	report := &PurgeReport{DryRun: dryRun}
+	cutoff := time.Now().Add(-c.retention)
+
+	for _, e := range entries {
+		select {
+		case <-ctx.Done():
+			return report, ctx.Err()
+		default:
+		}
+
+		// Ignore anything that is not a batch directory
+		created, err := time.ParseInLocation(batchLayout, e.Name(), time.Local)
+		if !e.IsDir() || err != nil || created.After(cutoff) {
+			continue
+		}
+
+		dir := filepath.Join(c.quarantineDir, e.Name())
+		files, bytes, err := dirUsage(dir)
+		if err != nil {
+			return report, fmt.Errorf("cleaner: failed to read batch %s: %w", e.Name(), err)
+		}
+		if !dryRun {
+			if err := os.RemoveAll(dir); err != nil {
+				return report, fmt.Errorf("cleaner: failed to purge batch %s: %w", e.Name(), err)
+			}
+			if err := c.storage.MarkQuarantineBatchPurged(ctx, e.Name()); err != nil {
+				return report, fmt.Errorf("cleaner: %w", err)
+			}
+		}
+
+		report.Batches = append(report.Batches, e.Name())
+		report.FilesRemoved += files
+		report.BytesReclaimed += bytes
+	}
+
+	return report, nil
+}
+
+// Restore moves quarantined files back to their original location and
+// indexes them again in local_files.
+func (c *Cleaner) Restore(ctx context.Context, ids []int64) (*RestoreReport, error) {
+	report := &RestoreReport{}
+
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+
+		entry, err := c.storage.GetQuarantineEntry(ctx, id)
+		if err != nil {
+			report.Actions = append(report.Actions, RestoreAction{
+				Entry: models.QuarantineEntry{ID: id},
+				Error: err.Error(),
+			})
+			report.Failed++
+			continue
+		}
+
+		action := RestoreAction{Entry: *entry}
+		if err := c.restoreEntry(ctx, entry); err != nil {
+			action.Error = err.Error()
+			report.Failed++
+		} else {
+			report.Restored++
+		}
+		report.Actions = append(report.Actions, action)
+	}
+
+	return report, nil
+}
+
+// RestoreBatch restores every file of a quarantine batch still in quarantine.
+func (c *Cleaner) RestoreBatch(ctx context.Context, batch string) (*RestoreReport, error) {
+	ids, err := c.storage.ListQuarantineBatch(ctx, batch)
+	if err != nil {
+		return nil, fmt.Errorf("cleaner: %w", err)
+	}
+	return c.Restore(ctx, ids)
+}
+
+func (c *Cleaner) restoreEntry(ctx context.Context, entry *models.QuarantineEntry) error {
+	if entry.RestoredAt != nil {
+		return fmt.Errorf("already restored")
+	}
+	if entry.PurgedAt != nil {
+		return fmt.Errorf("already purged")
+	}
+
+	if err := moveFile(entry.QuarantinePath, entry.DiskPath); err != nil {
+		return err
+	}
+
+	restored := models.LocalFile{
+		FilePath: entry.DiskPath,
+		FileName: filepath.Base(entry.DiskPath),
+		Size:     entry.Size,
+		Category: entry.Category,
+	}
+	if info, err := os.Stat(entry.DiskPath); err == nil {
+		restored.ModTime = info.ModTime()
+	}
+
+	dbCtx := context.WithoutCancel(ctx)
+	err := c.storage.InsertLocalFiles(dbCtx, []models.LocalFile{restored})
+	if err != nil {
+		return fmt.Errorf("cleaner: %w", err)
+	}
+	if err := c.storage.MarkQuarantineRestored(dbCtx, entry.ID); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("cleaner: %w", err)
+	}
+	return nil
+}
+
+// dirUsage returns the number of regular files and their total size under dir.
+func dirUsage(dir string) (int64, int64, error) {
+	var files, bytes int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			files++
+			bytes += scanner.AllocatedSize(info)
+		}
+		return nil
+	})
+	return files, bytes, err
+}
+
+// moveFile moves a regular file to dst, creating parent directories as needed.
+// When src and dst are on different filesystems the file is copied then removed.
+func moveFile(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("refusing to move non-regular file: %s", src)
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		return fmt.Errorf("destination already exists: %s", dst)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	err = os.Rename(src, dst)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	if err := copyFile(src, dst, info); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	return os.Remove(src)
+}
+
+// copyFile copies the content, permissions and modification time of src to dst.
+func copyFile(src, dst string, info fs.FileInfo) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
@@ -0,0 +1,191 @@
+package cleaner
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"time"
+
+	"godatacleaner/internal/models"
+)
+
+// DefaultSimulationTop is the number of largest files listed per category.
+const DefaultSimulationTop = 10
+
+// Rule is a set of cleanup options evaluated by a simulation.
+type Rule struct {
+	Name       string `json:"name"`
+	Category   string `json:"category,omitempty"`
+	Companions bool   `json:"companions"`
+	// Files of dead torrents instead of orphans, see Options.DeadTorrents
+	DeadTorrents bool `json:"dead_torrents,omitempty"`
+}
+
+// CategorySimulation holds what a rule would reclaim in a single category.
+type CategorySimulation struct {
+	Category string              `json:"category"`
+	Files    int64               `json:"files"`
+	Bytes    int64               `json:"bytes"`
+	Largest  []models.OrphanFile `json:"largest"`
+}
+
+// RuleSimulation holds what a rule would reclaim.
+type RuleSimulation struct {
+	Rule       Rule                 `json:"rule"`
+	Files      int64                `json:"files"`
+	Bytes      int64                `json:"bytes"`
+	Protected  int64                `json:"protected"`
+	Categories []CategorySimulation `json:"categories"`
+}
+
+// SimulationReport summarizes the space reclaimable by every cleanup rule.
+// Totals count each file once, even when several rules would remove it.
+type SimulationReport struct {
+	GeneratedAt time.Time        `json:"generated_at"`
+	Rules       []RuleSimulation `json:"rules"`
+	TotalFiles  int64            `json:"total_files"`
+	TotalBytes  int64            `json:"total_bytes"`
+}
+
+// Simulate evaluates every enabled cleanup job as a rule, or a single rule
+// covering all orphans when no job is defined, without touching the filesystem.
+// A last rule covers the files of dead torrents when dead tracker messages
+// are configured. top is the number of largest files kept per category.
+func (c *Cleaner) Simulate(ctx context.Context, top int) (*SimulationReport, error) {
+	jobs, err := c.storage.ListCleanupJobs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cleaner: %w", err)
+	}
+
+	var rules []Rule
+	for _, j := range jobs {
+		if j.Enabled {
+			rules = append(rules, Rule{Name: j.Name, Category: j.Category, Companions: j.Companions})
+		}
+	}
+	if len(rules) == 0 {
+		rules = []Rule{{Name: "orphans", Companions: len(c.companionExts) > 0}}
+	}
+	if len(c.deadMessages) > 0 {
+		rules = append(rules, Rule{Name: "dead-torrents", DeadTorrents: true})
+	}
+
+	return c.SimulateRules(ctx, rules, top)
+}
+
+// SimulateRules evaluates the given rules in dry-run mode.
+func (c *Cleaner) SimulateRules(ctx context.Context, rules []Rule, top int) (*SimulationReport, error) {
+	if top <= 0 {
+		top = DefaultSimulationTop
+	}
+
+	report := &SimulationReport{GeneratedAt: time.Now()}
+	seen := make(map[string]bool)
+
+	for _, rule := range rules {
+		run, err := c.Run(ctx, Options{DryRun: true, Category: rule.Category, Companions: rule.Companions, DeadTorrents: rule.DeadTorrents})
+		if err != nil {
+			return nil, err
+		}
+
+		sim := RuleSimulation{Rule: rule, Protected: run.Protected}
+		byCategory := make(map[string]*CategorySimulation)
+		var categories []string
+		for _, a := range run.Actions {
+			// Protected and unresolvable files would not be reclaimed
+			if a.Protected || a.Error != "" {
+				continue
+			}
+
+			cat, ok := byCategory[a.File.Category]
+			if !ok {
+				cat = &CategorySimulation{Category: a.File.Category}
+				byCategory[a.File.Category] = cat
+				categories = append(categories, a.File.Category)
+			}
+			cat.Files++
+			cat.Bytes += a.File.Allocated
+			cat.Largest = append(cat.Largest, a.File)
+			sim.Files++
+			sim.Bytes += a.File.Allocated
+
+			if !seen[a.File.FilePath] {
+				seen[a.File.FilePath] = true
+				report.TotalFiles++
+				report.TotalBytes += a.File.Allocated
+			}
+		}
+
+		sort.Strings(categories)
+		for _, name := range categories {
+			cat := byCategory[name]
+			sort.SliceStable(cat.Largest, func(i, j int) bool {
+				return cat.Largest[i].Size > cat.Largest[j].Size
+			})
+			if len(cat.Largest) > top {
+				cat.Largest = cat.Largest[:top]
+			}
+			sim.Categories = append(sim.Categories, *cat)
+		}
+		report.Rules = append(report.Rules, sim)
+	}
+
+	return report, nil
+}
+
+// WriteHTML renders the report as a standalone HTML page.
+func (r *SimulationReport) WriteHTML(w io.Writer) error {
+	return simulationTemplate.Execute(w, r)
+}
+
+var simulationTemplate = template.Must(template.New("simulation").Funcs(template.FuncMap{
+	"size": formatSize,
+}).Parse(`<!DOCTYPE html>
+<html lang="fr">
+<head>
+    <meta charset="UTF-8">
+    <title>GoDataCleaner - Simulation</title>
+    <style>
+        body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; background: #1a1a2e; color: #eee; padding: 20px; }
+        h1 { color: #00d9ff; }
+        h2 { color: #00d9ff; margin-top: 30px; }
+        table { border-collapse: collapse; width: 100%; margin: 10px 0; }
+        th, td { padding: 6px 10px; border-bottom: 1px solid #2a2a4e; text-align: left; }
+        th { color: #888; }
+        .num { text-align: right; }
+    </style>
+</head>
+<body>
+    <h1>Simulation de nettoyage</h1>
+    <p>Générée le {{.GeneratedAt.Format "2006-01-02 15:04:05"}} : {{.TotalFiles}} fichiers, {{size .TotalBytes}} récupérables.</p>
+    {{range .Rules}}
+    <h2>{{.Rule.Name}}{{if .Rule.Category}} ({{.Rule.Category}}){{end}}</h2>
+    <p>{{.Files}} fichiers, {{size .Bytes}}{{if .Protected}}, {{.Protected}} fichiers protégés ignorés{{end}}{{if .Rule.Companions}}, compagnons inclus{{end}}</p>
+    {{range .Categories}}
+    <h3>{{.Category}} : {{.Files}} fichiers, {{size .Bytes}}</h3>
+    <table>
+        <tr><th>Fichier</th><th class="num">Taille</th></tr>
+        {{range .Largest}}<tr><td>{{.FilePath}}</td><td class="num">{{size .Size}}</td></tr>
+        {{end}}
+    </table>
+    {{end}}
+    {{end}}
+</body>
+</html>
+`))
+
+// formatSize returns a human readable size.
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.2f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
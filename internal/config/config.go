@@ -8,7 +8,15 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"regexp/syntax"
+	"sort"
 	"strconv"
+	"strings"
+
+	"github.com/robfig/cron/v3"
 )
 
 // Default configuration values
@@ -21,29 +29,190 @@ const (
 	DefaultQBittorrentUsername   = "admin"
 	DefaultQBittorrentPassword   = "adminadmin"
 	DefaultQBittorrentMaxWorkers = 10
+	DefaultQBittorrentRetries    = 3
+	DefaultQBittorrentRetryDelay = 500 // milliseconds
+	DefaultQBittorrentTimeout    = 30  // seconds
+	DefaultTransmissionHost      = "localhost"
+	DefaultTransmissionPort      = 9091
+	DefaultTransmissionRPCPath   = "/transmission/rpc"
+	DefaultDelugeHost            = "localhost"
+	DefaultDelugePort            = 8112
+	DefaultDelugePassword        = "deluge"
+	DefaultRTorrentURL           = "http://localhost/RPC2"
 	DefaultSQLitePath            = "./data/torrents.db"
 	DefaultSQLiteBatchSize       = 1000
+	DefaultCountCacheSeconds     = 30
 	DefaultLocalPath             = "./data/torrents"
+	DefaultScanWorkers           = 8
+	DefaultQuarantineRetention   = 30 // days
+	DefaultChecksumMode          = ChecksumModePartial
+	DefaultChecksumPartialMB     = 16
+	DefaultChecksumWorkers       = 2
 )
 
+// Checksum algorithms (CHECKSUM) and modes (CHECKSUM_MODE)
+const (
+	ChecksumXXHash      = "xxhash"
+	ChecksumSHA1        = "sha1"
+	ChecksumModePartial = "partial" // First and last CHECKSUM_PARTIAL_MB of each file
+	ChecksumModeFull    = "full"
+)
+
+// Supported torrent clients
+const (
+	TorrentClientQBittorrent  = "qbittorrent"
+	TorrentClientTransmission = "transmission"
+	TorrentClientDeluge       = "deluge"
+	TorrentClientRTorrent     = "rtorrent"
+)
+
+// torrentClients holds the names accepted in TORRENT_CLIENTS, registered by
+// the torrent package along with the client implementations.
+var torrentClients = make(map[string]bool)
+
+// RegisterTorrentClient makes name a valid TORRENT_CLIENTS entry.
+func RegisterTorrentClient(name string) {
+	torrentClients[name] = true
+}
+
+// DefaultTorrentClients lists the torrent clients synced by default.
+var DefaultTorrentClients = []string{TorrentClientQBittorrent}
+
+// DefaultPathRewrites strips the /mnt prefix of the local paths, the torrent
+// clients usually seeing the library mounted at the root of their container.
+var DefaultPathRewrites = []PathMapping{{From: "/mnt", To: ""}}
+
+// DefaultCompanionExtensions lists the sibling files handled with an orphan video.
+var DefaultCompanionExtensions = []string{".srt", ".sub", ".idx", ".ass", ".ssa", ".nfo", ".jpg", ".jpeg", ".png", ".txt"}
+
+// DefaultCategoryRules categorizes the local files by their library directory.
+var DefaultCategoryRules = []CategoryRule{
+	{Name: "4k", Pattern: "/4k/"},
+	{Name: "movies", Pattern: "/movies/"},
+	{Name: "shows", Pattern: "/shows/"},
+}
+
+// DefaultDeadTrackerMessages lists the tracker error messages, matched without
+// case, telling that a torrent is no longer registered on its tracker.
+var DefaultDeadTrackerMessages = []string{
+	"unregistered", "not registered", "torrent not found", "torrent does not exist",
+	"infohash not found", "torrent has been deleted", "trumped", "nuked",
+}
+
 // Error definitions for configuration validation
 var (
 	ErrInvalidPort = errors.New("invalid port: must be between 1 and 65535")
 	ErrInvalidPath = errors.New("invalid path: path cannot be empty")
 )
 
+// QBittorrentInstance is one of several qBittorrent servers synced together.
+// Empty Port, Username and Password fall back to the QBITTORRENT_* values.
+type QBittorrentInstance struct {
+	Name         string        `json:"name"`
+	Host         string        `json:"host"`
+	Port         int           `json:"port"`
+	Username     string        `json:"username"`
+	Password     string        `json:"password"`
+	PathMappings []PathMapping `json:"path_mappings"`
+}
+
+// PathMapping rewrites the file paths reported by a torrent client starting
+// with From so that they start with To, e.g. when an instance sees the
+// library under another mount point.
+type PathMapping struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// CategoryRule gives the category Name to the local files whose path matches
+// Pattern, a regular expression. Rules are tried in order, and files matching
+// none are in the "unknown" category.
+type CategoryRule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+}
+
+// ScanRoot limits the scan of the directory Path, relative to LOCAL_PATH
+// ("." for LOCAL_PATH itself), and of its subdirectories. A root nested in
+// another overrides it for its subtree.
+type ScanRoot struct {
+	Path string `json:"path"`
+	// MaxDepth is the number of directory levels read under Path, 1 reading
+	// only Path. 0 reads every level.
+	MaxDepth int `json:"max_depth"`
+	// Include lists the patterns of the paths relative to Path, with forward
+	// slashes, that are scanned, such as "complete/*". A directory matched
+	// includes its whole subtree. Empty includes everything.
+	Include []string `json:"include"`
+}
+
+// URL returns the full qBittorrent server URL of the instance.
+func (i QBittorrentInstance) URL() string {
+	return qbittorrentURL(i.Host, i.Port)
+}
+
 // Config holds the application configuration.
 type Config struct {
-	LocalHost             string `json:"local_host"`
-	LocalPort             int    `json:"local_port"`
-	QBittorrentHost       string `json:"qbittorrent_host"`
-	QBittorrentPort       int    `json:"qbittorrent_port"`
-	QBittorrentUsername   string `json:"qbittorrent_username"`
-	QBittorrentPassword   string `json:"qbittorrent_password"`
-	QBittorrentMaxWorkers int    `json:"qbittorrent_max_workers"`
-	SQLitePath            string `json:"sqlite_path"`
-	SQLiteBatchSize       int    `json:"sqlite_batch_size"`
-	LocalPath             string `json:"local_path"`
+	LocalHost             string                `json:"local_host"`
+	LocalPort             int                   `json:"local_port"`
+	QBittorrentHost       string                `json:"qbittorrent_host"`
+	QBittorrentPort       int                   `json:"qbittorrent_port"`
+	QBittorrentUsername   string                `json:"qbittorrent_username"`
+	QBittorrentPassword   string                `json:"qbittorrent_password"`
+	QBittorrentMaxWorkers int                   `json:"qbittorrent_max_workers"`
+	QBittorrentRetries    int                   `json:"qbittorrent_retries"`
+	QBittorrentRetryDelay int                   `json:"qbittorrent_retry_delay_ms"`
+	QBittorrentTimeout    int                   `json:"qbittorrent_timeout"`
+	QBittorrentCAPath     string                `json:"qbittorrent_ca_path"`
+	QBittorrentSkipVerify bool                  `json:"qbittorrent_tls_skip_verify"`
+	QBittorrentInstances  []QBittorrentInstance `json:"qbittorrent_instances"`
+	TorrentClients        []string              `json:"torrent_clients"`
+	TransmissionHost      string                `json:"transmission_host"`
+	TransmissionPort      int                   `json:"transmission_port"`
+	TransmissionUsername  string                `json:"transmission_username"`
+	TransmissionPassword  string                `json:"transmission_password"`
+	TransmissionRPCPath   string                `json:"transmission_rpc_path"`
+	DelugeHost            string                `json:"deluge_host"`
+	DelugePort            int                   `json:"deluge_port"`
+	DelugePassword        string                `json:"deluge_password"`
+	RTorrentURL           string                `json:"rtorrent_url"`
+	RTorrentUsername      string                `json:"rtorrent_username"`
+	RTorrentPassword      string                `json:"rtorrent_password"`
+	SQLitePath            string                `json:"sqlite_path"`
+	SQLiteBatchSize       int                   `json:"sqlite_batch_size"`
+	CountCacheSeconds     int                   `json:"count_cache_seconds"` // 0 to count each page
+	LocalPath             string                `json:"local_path"`
+	LocalRemote           string                `json:"local_remote"`
+	ScanWorkers           int                   `json:"scan_workers"`
+	ScanFollowSymlinks    bool                  `json:"scan_follow_symlinks"`
+	ScanIncremental       bool                  `json:"scan_incremental"`
+	ScanWatch             bool                  `json:"scan_watch"`
+	SyncSchedule          string                `json:"sync_schedule"` // Cron expression of the syncs run by the daemon, empty for none
+	ScanCanaryFile        string                `json:"scan_canary_file"`
+	ScanMinSize           int64                 `json:"scan_min_size"`
+	ScanExtensions        []string              `json:"scan_extensions"`
+	ScanRequireMount      bool                  `json:"scan_require_mount"`
+	ScanExtras            bool                  `json:"scan_extras"`
+	NormalizeUnicode      bool                  `json:"normalize_unicode"`
+	PathRewrites          []PathMapping         `json:"path_rewrites"`
+	CategoryRules         []CategoryRule        `json:"category_rules"`
+	RelativeMarkers       []string              `json:"relative_markers"` // Empty to derive them from CategoryRules
+	ScanRoots             []ScanRoot            `json:"scan_roots"`
+	Checksum              string                `json:"checksum"` // Empty to disable hashing
+	ChecksumMode          string                `json:"checksum_mode"`
+	ChecksumPartialMB     int                   `json:"checksum_partial_mb"`
+	ChecksumWorkers       int                   `json:"checksum_workers"`
+	ChecksumRateMB        int                   `json:"checksum_rate_mb"` // MB read per second, 0 for no limit
+	QuarantinePath        string                `json:"quarantine_path"`
+	QuarantineRetention   int                   `json:"quarantine_retention_days"`
+	ArchivePath           string                `json:"archive_path"`
+	ProtectedPaths        []string              `json:"protected_paths"`
+	CompanionExtensions   []string              `json:"companion_extensions"`
+	DeadTrackerMessages   []string              `json:"dead_tracker_messages"`
+	AuthUsername          string                `json:"auth_username"`
+	AuthPassword          string                `json:"auth_password"`
+	AuthToken             string                `json:"auth_token"`
+	CORSAllowedOrigins    []string              `json:"cors_allowed_origins"`
 }
 
 // Load loads the configuration with the following priority:
@@ -60,13 +229,34 @@ func Load() (*Config, error) {
 		QBittorrentUsername:   DefaultQBittorrentUsername,
 		QBittorrentPassword:   DefaultQBittorrentPassword,
 		QBittorrentMaxWorkers: DefaultQBittorrentMaxWorkers,
+		QBittorrentRetries:    DefaultQBittorrentRetries,
+		QBittorrentRetryDelay: DefaultQBittorrentRetryDelay,
+		QBittorrentTimeout:    DefaultQBittorrentTimeout,
+		TorrentClients:        DefaultTorrentClients,
+		TransmissionHost:      DefaultTransmissionHost,
+		TransmissionPort:      DefaultTransmissionPort,
+		TransmissionRPCPath:   DefaultTransmissionRPCPath,
+		DelugeHost:            DefaultDelugeHost,
+		DelugePort:            DefaultDelugePort,
+		DelugePassword:        DefaultDelugePassword,
+		RTorrentURL:           DefaultRTorrentURL,
 		SQLitePath:            DefaultSQLitePath,
 		SQLiteBatchSize:       DefaultSQLiteBatchSize,
+		CountCacheSeconds:     DefaultCountCacheSeconds,
 		LocalPath:             DefaultLocalPath,
+		ScanWorkers:           DefaultScanWorkers,
+		CategoryRules:         DefaultCategoryRules,
+		PathRewrites:          DefaultPathRewrites,
+		QuarantineRetention:   DefaultQuarantineRetention,
+		ChecksumMode:          DefaultChecksumMode,
+		ChecksumPartialMB:     DefaultChecksumPartialMB,
+		ChecksumWorkers:       DefaultChecksumWorkers,
+		CompanionExtensions:   DefaultCompanionExtensions,
+		DeadTrackerMessages:   DefaultDeadTrackerMessages,
 	}
 
 	// Load from config file if it exists
-	configPath := getEnvString("CONFIG_PATH", DefaultConfigPath)
+	configPath := Path()
 	if err := cfg.loadFromFile(configPath); err != nil {
 		// Ignore file not found errors
 		if !os.IsNotExist(err) {
@@ -119,15 +309,166 @@ func (c *Config) loadFromFile(path string) error {
 	if fileCfg.QBittorrentMaxWorkers != 0 {
 		c.QBittorrentMaxWorkers = fileCfg.QBittorrentMaxWorkers
 	}
+	if fileCfg.QBittorrentRetries != 0 {
+		c.QBittorrentRetries = fileCfg.QBittorrentRetries
+	}
+	if fileCfg.QBittorrentRetryDelay != 0 {
+		c.QBittorrentRetryDelay = fileCfg.QBittorrentRetryDelay
+	}
+	if fileCfg.QBittorrentTimeout != 0 {
+		c.QBittorrentTimeout = fileCfg.QBittorrentTimeout
+	}
+	if fileCfg.QBittorrentCAPath != "" {
+		c.QBittorrentCAPath = fileCfg.QBittorrentCAPath
+	}
+	if fileCfg.QBittorrentSkipVerify {
+		c.QBittorrentSkipVerify = true
+	}
+	if len(fileCfg.QBittorrentInstances) > 0 {
+		c.QBittorrentInstances = fileCfg.QBittorrentInstances
+	}
+	if len(fileCfg.TorrentClients) > 0 {
+		c.TorrentClients = fileCfg.TorrentClients
+	}
+	if fileCfg.TransmissionHost != "" {
+		c.TransmissionHost = fileCfg.TransmissionHost
+	}
+	if fileCfg.TransmissionPort != 0 {
+		c.TransmissionPort = fileCfg.TransmissionPort
+	}
+	if fileCfg.TransmissionUsername != "" {
+		c.TransmissionUsername = fileCfg.TransmissionUsername
+	}
+	if fileCfg.TransmissionPassword != "" {
+		c.TransmissionPassword = fileCfg.TransmissionPassword
+	}
+	if fileCfg.TransmissionRPCPath != "" {
+		c.TransmissionRPCPath = fileCfg.TransmissionRPCPath
+	}
+	if fileCfg.DelugeHost != "" {
+		c.DelugeHost = fileCfg.DelugeHost
+	}
+	if fileCfg.DelugePort != 0 {
+		c.DelugePort = fileCfg.DelugePort
+	}
+	if fileCfg.DelugePassword != "" {
+		c.DelugePassword = fileCfg.DelugePassword
+	}
+	if fileCfg.RTorrentURL != "" {
+		c.RTorrentURL = fileCfg.RTorrentURL
+	}
+	if fileCfg.RTorrentUsername != "" {
+		c.RTorrentUsername = fileCfg.RTorrentUsername
+	}
+	if fileCfg.RTorrentPassword != "" {
+		c.RTorrentPassword = fileCfg.RTorrentPassword
+	}
 	if fileCfg.SQLitePath != "" {
 		c.SQLitePath = fileCfg.SQLitePath
 	}
 	if fileCfg.SQLiteBatchSize != 0 {
 		c.SQLiteBatchSize = fileCfg.SQLiteBatchSize
 	}
+	if fileCfg.CountCacheSeconds != 0 {
+		c.CountCacheSeconds = fileCfg.CountCacheSeconds
+	}
 	if fileCfg.LocalPath != "" {
 		c.LocalPath = fileCfg.LocalPath
 	}
+	if fileCfg.ScanWorkers != 0 {
+		c.ScanWorkers = fileCfg.ScanWorkers
+	}
+	if fileCfg.LocalRemote != "" {
+		c.LocalRemote = fileCfg.LocalRemote
+	}
+	if fileCfg.ScanFollowSymlinks {
+		c.ScanFollowSymlinks = true
+	}
+	if fileCfg.ScanIncremental {
+		c.ScanIncremental = true
+	}
+	if fileCfg.ScanWatch {
+		c.ScanWatch = true
+	}
+	if fileCfg.SyncSchedule != "" {
+		c.SyncSchedule = fileCfg.SyncSchedule
+	}
+	if fileCfg.ScanCanaryFile != "" {
+		c.ScanCanaryFile = fileCfg.ScanCanaryFile
+	}
+	if fileCfg.ScanMinSize != 0 {
+		c.ScanMinSize = fileCfg.ScanMinSize
+	}
+	if len(fileCfg.ScanExtensions) > 0 {
+		c.ScanExtensions = fileCfg.ScanExtensions
+	}
+	if fileCfg.ScanRequireMount {
+		c.ScanRequireMount = true
+	}
+	if fileCfg.ScanExtras {
+		c.ScanExtras = true
+	}
+	if fileCfg.NormalizeUnicode {
+		c.NormalizeUnicode = true
+	}
+	// An empty list disables the default rewrite
+	if fileCfg.PathRewrites != nil {
+		c.PathRewrites = fileCfg.PathRewrites
+	}
+	if len(fileCfg.CategoryRules) > 0 {
+		c.CategoryRules = fileCfg.CategoryRules
+	}
+	if len(fileCfg.RelativeMarkers) > 0 {
+		c.RelativeMarkers = fileCfg.RelativeMarkers
+	}
+	if len(fileCfg.ScanRoots) > 0 {
+		c.ScanRoots = fileCfg.ScanRoots
+	}
+	if fileCfg.Checksum != "" {
+		c.Checksum = fileCfg.Checksum
+	}
+	if fileCfg.ChecksumMode != "" {
+		c.ChecksumMode = fileCfg.ChecksumMode
+	}
+	if fileCfg.ChecksumPartialMB != 0 {
+		c.ChecksumPartialMB = fileCfg.ChecksumPartialMB
+	}
+	if fileCfg.ChecksumWorkers != 0 {
+		c.ChecksumWorkers = fileCfg.ChecksumWorkers
+	}
+	if fileCfg.ChecksumRateMB != 0 {
+		c.ChecksumRateMB = fileCfg.ChecksumRateMB
+	}
+	if fileCfg.QuarantinePath != "" {
+		c.QuarantinePath = fileCfg.QuarantinePath
+	}
+	if fileCfg.QuarantineRetention != 0 {
+		c.QuarantineRetention = fileCfg.QuarantineRetention
+	}
+	if fileCfg.ArchivePath != "" {
+		c.ArchivePath = fileCfg.ArchivePath
+	}
+	if len(fileCfg.ProtectedPaths) > 0 {
+		c.ProtectedPaths = fileCfg.ProtectedPaths
+	}
+	if len(fileCfg.CompanionExtensions) > 0 {
+		c.CompanionExtensions = fileCfg.CompanionExtensions
+	}
+	if len(fileCfg.DeadTrackerMessages) > 0 {
+		c.DeadTrackerMessages = fileCfg.DeadTrackerMessages
+	}
+	if fileCfg.AuthUsername != "" {
+		c.AuthUsername = fileCfg.AuthUsername
+	}
+	if fileCfg.AuthPassword != "" {
+		c.AuthPassword = fileCfg.AuthPassword
+	}
+	if fileCfg.AuthToken != "" {
+		c.AuthToken = fileCfg.AuthToken
+	}
+	if len(fileCfg.CORSAllowedOrigins) > 0 {
+		c.CORSAllowedOrigins = fileCfg.CORSAllowedOrigins
+	}
 
 	return nil
 }
@@ -161,6 +502,72 @@ func (c *Config) loadFromEnv() {
 			c.QBittorrentMaxWorkers = i
 		}
 	}
+	if v := os.Getenv("QBITTORRENT_RETRIES"); v != "" {
+		if i, err := strconv.Atoi(v); err == nil {
+			c.QBittorrentRetries = i
+		}
+	}
+	if v := os.Getenv("QBITTORRENT_RETRY_DELAY_MS"); v != "" {
+		if i, err := strconv.Atoi(v); err == nil {
+			c.QBittorrentRetryDelay = i
+		}
+	}
+	if v := os.Getenv("QBITTORRENT_TIMEOUT"); v != "" {
+		if i, err := strconv.Atoi(v); err == nil {
+			c.QBittorrentTimeout = i
+		}
+	}
+	if v := os.Getenv("QBITTORRENT_CA_PATH"); v != "" {
+		c.QBittorrentCAPath = v
+	}
+	if v := os.Getenv("QBITTORRENT_TLS_SKIP_VERIFY"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			c.QBittorrentSkipVerify = b
+		}
+	}
+	if v := os.Getenv("QBITTORRENT_INSTANCES"); v != "" {
+		c.loadInstancesFromEnv(splitList(v))
+	}
+	if v := os.Getenv("TORRENT_CLIENTS"); v != "" {
+		c.TorrentClients = splitList(v)
+	}
+	if v := os.Getenv("TRANSMISSION_HOST"); v != "" {
+		c.TransmissionHost = v
+	}
+	if v := os.Getenv("TRANSMISSION_PORT"); v != "" {
+		if i, err := strconv.Atoi(v); err == nil {
+			c.TransmissionPort = i
+		}
+	}
+	if v := os.Getenv("TRANSMISSION_USERNAME"); v != "" {
+		c.TransmissionUsername = v
+	}
+	if v := os.Getenv("TRANSMISSION_PASSWORD"); v != "" {
+		c.TransmissionPassword = v
+	}
+	if v := os.Getenv("TRANSMISSION_RPC_PATH"); v != "" {
+		c.TransmissionRPCPath = v
+	}
+	if v := os.Getenv("DELUGE_HOST"); v != "" {
+		c.DelugeHost = v
+	}
+	if v := os.Getenv("DELUGE_PORT"); v != "" {
+		if i, err := strconv.Atoi(v); err == nil {
+			c.DelugePort = i
+		}
+	}
+	if v := os.Getenv("DELUGE_PASSWORD"); v != "" {
+		c.DelugePassword = v
+	}
+	if v := os.Getenv("RTORRENT_URL"); v != "" {
+		c.RTorrentURL = v
+	}
+	if v := os.Getenv("RTORRENT_USERNAME"); v != "" {
+		c.RTorrentUsername = v
+	}
+	if v := os.Getenv("RTORRENT_PASSWORD"); v != "" {
+		c.RTorrentPassword = v
+	}
 	if v := os.Getenv("SQLITE_PATH"); v != "" {
 		c.SQLitePath = v
 	}
@@ -169,9 +576,189 @@ func (c *Config) loadFromEnv() {
 			c.SQLiteBatchSize = i
 		}
 	}
+	if v := os.Getenv("COUNT_CACHE_SECONDS"); v != "" {
+		if i, err := strconv.Atoi(v); err == nil {
+			c.CountCacheSeconds = i
+		}
+	}
 	if v := os.Getenv("LOCAL_PATH"); v != "" {
 		c.LocalPath = v
 	}
+	if v := os.Getenv("SCAN_WORKERS"); v != "" {
+		if i, err := strconv.Atoi(v); err == nil {
+			c.ScanWorkers = i
+		}
+	}
+	if v := os.Getenv("LOCAL_REMOTE"); v != "" {
+		c.LocalRemote = v
+	}
+	if v := os.Getenv("SCAN_FOLLOW_SYMLINKS"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			c.ScanFollowSymlinks = b
+		}
+	}
+	if v := os.Getenv("SCAN_INCREMENTAL"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			c.ScanIncremental = b
+		}
+	}
+	if v := os.Getenv("SCAN_WATCH"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			c.ScanWatch = b
+		}
+	}
+	if v := os.Getenv("SCAN_CANARY_FILE"); v != "" {
+		c.ScanCanaryFile = v
+	}
+	if v := os.Getenv("SYNC_SCHEDULE"); v != "" {
+		c.SyncSchedule = v
+	}
+	if v := os.Getenv("SCAN_MIN_SIZE"); v != "" {
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			c.ScanMinSize = i
+		}
+	}
+	if v := os.Getenv("SCAN_EXTENSIONS"); v != "" {
+		c.ScanExtensions = splitList(v)
+	}
+	if v := os.Getenv("SCAN_REQUIRE_MOUNT"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			c.ScanRequireMount = b
+		}
+	}
+	if v := os.Getenv("SCAN_EXTRAS"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			c.ScanExtras = b
+		}
+	}
+	if v := os.Getenv("NORMALIZE_UNICODE"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			c.NormalizeUnicode = b
+		}
+	}
+	if v := os.Getenv("PATH_REWRITES"); v != "" {
+		c.PathRewrites = nil
+		if v != "none" {
+			c.PathRewrites = parsePathMappings(v)
+		}
+	}
+	if v := os.Getenv("CATEGORY_RULES"); v != "" {
+		c.CategoryRules = parseCategoryRules(v)
+	}
+	if v := os.Getenv("RELATIVE_MARKERS"); v != "" {
+		c.RelativeMarkers = splitList(v)
+	}
+	// Both variables complete the roots of the config file, by path
+	if v := os.Getenv("SCAN_DEPTH"); v != "" {
+		for _, item := range splitList(v) {
+			p, depth, _ := strings.Cut(item, "=")
+			if i, err := strconv.Atoi(strings.TrimSpace(depth)); err == nil {
+				c.scanRoot(strings.TrimSpace(p)).MaxDepth = i
+			}
+		}
+	}
+	if v := os.Getenv("SCAN_INCLUDE"); v != "" {
+		included := make(map[string]bool)
+		for _, item := range splitList(v) {
+			p, pattern, _ := strings.Cut(item, "=")
+			root := c.scanRoot(strings.TrimSpace(p))
+			// The patterns of the variable replace those of the file
+			if !included[root.Path] {
+				included[root.Path] = true
+				root.Include = nil
+			}
+			root.Include = append(root.Include, strings.TrimSpace(pattern))
+		}
+	}
+	if v := os.Getenv("CHECKSUM"); v != "" {
+		c.Checksum = strings.ToLower(v)
+	}
+	if v := os.Getenv("CHECKSUM_MODE"); v != "" {
+		c.ChecksumMode = strings.ToLower(v)
+	}
+	if v := os.Getenv("CHECKSUM_PARTIAL_MB"); v != "" {
+		if i, err := strconv.Atoi(v); err == nil {
+			c.ChecksumPartialMB = i
+		}
+	}
+	if v := os.Getenv("CHECKSUM_WORKERS"); v != "" {
+		if i, err := strconv.Atoi(v); err == nil {
+			c.ChecksumWorkers = i
+		}
+	}
+	if v := os.Getenv("CHECKSUM_RATE_MB"); v != "" {
+		if i, err := strconv.Atoi(v); err == nil {
+			c.ChecksumRateMB = i
+		}
+	}
+	if v := os.Getenv("QUARANTINE_PATH"); v != "" {
+		c.QuarantinePath = v
+	}
+	if v := os.Getenv("QUARANTINE_RETENTION_DAYS"); v != "" {
+		if i, err := strconv.Atoi(v); err == nil {
+			c.QuarantineRetention = i
+		}
+	}
+	if v := os.Getenv("ARCHIVE_PATH"); v != "" {
+		c.ArchivePath = v
+	}
+	if v := os.Getenv("PROTECTED_PATHS"); v != "" {
+		c.ProtectedPaths = splitList(v)
+	}
+	if v := os.Getenv("COMPANION_EXTENSIONS"); v != "" {
+		c.CompanionExtensions = splitList(v)
+	}
+	if v := os.Getenv("DEAD_TRACKER_MESSAGES"); v != "" {
+		c.DeadTrackerMessages = splitList(v)
+	}
+	if v := os.Getenv("AUTH_USERNAME"); v != "" {
+		c.AuthUsername = v
+	}
+	if v := os.Getenv("AUTH_PASSWORD"); v != "" {
+		c.AuthPassword = v
+	}
+	if v := os.Getenv("AUTH_TOKEN"); v != "" {
+		c.AuthToken = v
+	}
+	if v := os.Getenv("CORS_ALLOWED_ORIGINS"); v != "" {
+		c.CORSAllowedOrigins = splitList(v)
+	}
+}
+
+// loadInstancesFromEnv replaces the qBittorrent instances with the named ones,
+// configured by QBITTORRENT_<NAME>_HOST, _PORT, _USERNAME, _PASSWORD and
+// _PATH_MAP. An instance of the config file with the same name is used as base.
+func (c *Config) loadInstancesFromEnv(names []string) {
+	instances := make([]QBittorrentInstance, 0, len(names))
+	for _, name := range names {
+		inst := QBittorrentInstance{Name: name}
+		for _, fileInst := range c.QBittorrentInstances {
+			if fileInst.Name == name {
+				inst = fileInst
+			}
+		}
+
+		prefix := "QBITTORRENT_" + envName(name) + "_"
+		if v := os.Getenv(prefix + "HOST"); v != "" {
+			inst.Host = v
+		}
+		if v := os.Getenv(prefix + "PORT"); v != "" {
+			if i, err := strconv.Atoi(v); err == nil {
+				inst.Port = i
+			}
+		}
+		if v := os.Getenv(prefix + "USERNAME"); v != "" {
+			inst.Username = v
+		}
+		if v := os.Getenv(prefix + "PASSWORD"); v != "" {
+			inst.Password = v
+		}
+		if v := os.Getenv(prefix + "PATH_MAP"); v != "" {
+			inst.PathMappings = parsePathMappings(v)
+		}
+		instances = append(instances, inst)
+	}
+	c.QBittorrentInstances = instances
 }
 
 // Validate validates the configuration.
@@ -182,6 +769,46 @@ func (c *Config) Validate() error {
 	if !isValidPort(c.QBittorrentPort) {
 		return fmt.Errorf("QBITTORRENT_PORT %w: got %d", ErrInvalidPort, c.QBittorrentPort)
 	}
+	if !isValidPort(c.TransmissionPort) {
+		return fmt.Errorf("TRANSMISSION_PORT %w: got %d", ErrInvalidPort, c.TransmissionPort)
+	}
+	if !isValidPort(c.DelugePort) {
+		return fmt.Errorf("DELUGE_PORT %w: got %d", ErrInvalidPort, c.DelugePort)
+	}
+	names := make(map[string]bool)
+	for _, inst := range c.QBittorrentInstances {
+		if !isValidInstanceName(inst.Name) {
+			return fmt.Errorf("QBITTORRENT_INSTANCES: invalid name %q (letters, digits, - and _)", inst.Name)
+		}
+		if names[inst.Name] {
+			return fmt.Errorf("QBITTORRENT_INSTANCES: duplicate name %q", inst.Name)
+		}
+		names[inst.Name] = true
+		if inst.Host == "" {
+			return fmt.Errorf("qBittorrent instance %s: host cannot be empty", inst.Name)
+		}
+		if inst.Port != 0 && !isValidPort(inst.Port) {
+			return fmt.Errorf("qBittorrent instance %s: port %w: got %d", inst.Name, ErrInvalidPort, inst.Port)
+		}
+		for _, m := range inst.PathMappings {
+			if !filepath.IsAbs(m.From) || !filepath.IsAbs(m.To) {
+				return fmt.Errorf("qBittorrent instance %s: path mapping %s=%s must use absolute paths", inst.Name, m.From, m.To)
+			}
+		}
+	}
+	if len(c.TorrentClients) == 0 {
+		return fmt.Errorf("TORRENT_CLIENTS must list at least one client")
+	}
+	for _, name := range c.TorrentClients {
+		if !torrentClients[name] {
+			known := make([]string, 0, len(torrentClients))
+			for n := range torrentClients {
+				known = append(known, n)
+			}
+			sort.Strings(known)
+			return fmt.Errorf("TORRENT_CLIENTS: unknown client %q (%s)", name, strings.Join(known, ", "))
+		}
+	}
 	if c.SQLitePath == "" {
 		return fmt.Errorf("SQLITE_PATH %w", ErrInvalidPath)
 	}
@@ -191,22 +818,194 @@ func (c *Config) Validate() error {
 	if c.QBittorrentMaxWorkers < 1 {
 		return fmt.Errorf("QBITTORRENT_MAX_WORKERS must be at least 1: got %d", c.QBittorrentMaxWorkers)
 	}
+	if c.QBittorrentRetries < 0 {
+		return fmt.Errorf("QBITTORRENT_RETRIES must not be negative: got %d", c.QBittorrentRetries)
+	}
+	if c.QBittorrentRetryDelay < 0 {
+		return fmt.Errorf("QBITTORRENT_RETRY_DELAY_MS must not be negative: got %d", c.QBittorrentRetryDelay)
+	}
+	if c.QBittorrentTimeout < 1 {
+		return fmt.Errorf("QBITTORRENT_TIMEOUT must be at least 1: got %d", c.QBittorrentTimeout)
+	}
+	if c.ScanWorkers < 1 {
+		return fmt.Errorf("SCAN_WORKERS must be at least 1: got %d", c.ScanWorkers)
+	}
+	if c.ScanMinSize < 0 {
+		return fmt.Errorf("SCAN_MIN_SIZE must be at least 0: got %d", c.ScanMinSize)
+	}
+	if filepath.IsAbs(c.ScanCanaryFile) {
+		return fmt.Errorf("SCAN_CANARY_FILE must be relative to LOCAL_PATH: got %s", c.ScanCanaryFile)
+	}
+	if c.SyncSchedule != "" {
+		if _, err := cron.ParseStandard(c.SyncSchedule); err != nil {
+			return fmt.Errorf("SYNC_SCHEDULE must be a cron expression: %w", err)
+		}
+	}
+	// A remote is only listed by syncs, it cannot be watched
+	if c.LocalRemote != "" && c.ScanWatch {
+		return fmt.Errorf("SCAN_WATCH cannot be used with LOCAL_REMOTE")
+	}
+	for _, m := range c.PathRewrites {
+		if m.From == "" || (m.To != "" && !strings.HasPrefix(m.To, "/")) {
+			return fmt.Errorf("PATH_REWRITES: invalid rule %s=%s (from must not be empty, to must be empty or absolute)", m.From, m.To)
+		}
+	}
+	for _, r := range c.CategoryRules {
+		if !isValidInstanceName(r.Name) || r.Name == "unknown" {
+			return fmt.Errorf("CATEGORY_RULES: invalid category name %q (letters, digits, - and _, not unknown)", r.Name)
+		}
+		if _, err := regexp.Compile(r.Pattern); r.Pattern == "" || err != nil {
+			return fmt.Errorf("CATEGORY_RULES: invalid pattern %q for category %s", r.Pattern, r.Name)
+		}
+	}
+	for _, m := range c.RelativeMarkers {
+		if strings.Trim(m, "/") == "" {
+			return fmt.Errorf("RELATIVE_MARKERS: marker must be a directory name: got %q", m)
+		}
+	}
+	for _, r := range c.ScanRoots {
+		if !filepath.IsLocal(r.Path) {
+			return fmt.Errorf("SCAN_DEPTH/SCAN_INCLUDE: root must be relative to LOCAL_PATH: got %q", r.Path)
+		}
+		if r.MaxDepth < 0 {
+			return fmt.Errorf("SCAN_DEPTH: depth of %s must be at least 0: got %d", r.Path, r.MaxDepth)
+		}
+		for _, pattern := range r.Include {
+			if _, err := path.Match(pattern, ""); pattern == "" || err != nil {
+				return fmt.Errorf("SCAN_INCLUDE: invalid pattern %q for %s", pattern, r.Path)
+			}
+		}
+	}
+	if c.Checksum != "" && c.Checksum != ChecksumXXHash && c.Checksum != ChecksumSHA1 {
+		return fmt.Errorf("CHECKSUM must be %s or %s: got %s", ChecksumXXHash, ChecksumSHA1, c.Checksum)
+	}
+	if c.ChecksumMode != ChecksumModePartial && c.ChecksumMode != ChecksumModeFull {
+		return fmt.Errorf("CHECKSUM_MODE must be %s or %s: got %s", ChecksumModePartial, ChecksumModeFull, c.ChecksumMode)
+	}
+	if c.ChecksumPartialMB < 1 {
+		return fmt.Errorf("CHECKSUM_PARTIAL_MB must be at least 1: got %d", c.ChecksumPartialMB)
+	}
+	if c.ChecksumWorkers < 1 {
+		return fmt.Errorf("CHECKSUM_WORKERS must be at least 1: got %d", c.ChecksumWorkers)
+	}
+	if c.ChecksumRateMB < 0 {
+		return fmt.Errorf("CHECKSUM_RATE_MB must be at least 0: got %d", c.ChecksumRateMB)
+	}
+	// A remote is only listed, its files cannot be read
+	if c.LocalRemote != "" && c.Checksum != "" {
+		return fmt.Errorf("CHECKSUM cannot be used with LOCAL_REMOTE")
+	}
 	if c.SQLiteBatchSize < 1 {
 		return fmt.Errorf("SQLITE_BATCH_SIZE must be at least 1: got %d", c.SQLiteBatchSize)
 	}
+	if c.CountCacheSeconds < 0 {
+		return fmt.Errorf("COUNT_CACHE_SECONDS must be at least 0: got %d", c.CountCacheSeconds)
+	}
+	if c.QuarantineRetention < 1 {
+		return fmt.Errorf("QUARANTINE_RETENTION_DAYS must be at least 1: got %d", c.QuarantineRetention)
+	}
+	// A quarantine inside the scanned tree would be rescanned and reported as orphans,
+	// unless it is hidden (the scanner skips hidden directories).
+	if c.QuarantinePath != "" && isSubPath(c.LocalPath, c.QuarantinePath) && !isHiddenPath(c.LocalPath, c.QuarantinePath) {
+		return fmt.Errorf("QUARANTINE_PATH must be outside LOCAL_PATH or hidden: got %s", c.QuarantinePath)
+	}
+	if c.ArchivePath != "" && isSubPath(c.LocalPath, c.ArchivePath) && !isHiddenPath(c.LocalPath, c.ArchivePath) {
+		return fmt.Errorf("ARCHIVE_PATH must be outside LOCAL_PATH or hidden: got %s", c.ArchivePath)
+	}
+	if (c.AuthUsername == "") != (c.AuthPassword == "") {
+		return fmt.Errorf("AUTH_USERNAME and AUTH_PASSWORD must be set together")
+	}
 	return nil
 }
 
+// RelativePathMarkers returns the directories, such as /movies/, from which
+// the paths of the local and torrent files are compared: RELATIVE_MARKERS, or
+// the directories matched by the category rules when it is not set.
+func (c *Config) RelativePathMarkers() []string {
+	if len(c.RelativeMarkers) == 0 {
+		return categoryMarkers(c.CategoryRules)
+	}
+	markers := make([]string, len(c.RelativeMarkers))
+	for i, m := range c.RelativeMarkers {
+		markers[i] = "/" + strings.Trim(m, "/") + "/"
+	}
+	return markers
+}
+
+// AuthEnabled reports whether the web server requires authentication.
+func (c *Config) AuthEnabled() bool {
+	return c.AuthUsername != "" || c.AuthToken != ""
+}
+
 // QBittorrentURL returns the full qBittorrent server URL.
 func (c *Config) QBittorrentURL() string {
+	return qbittorrentURL(c.QBittorrentHost, c.QBittorrentPort)
+}
+
+// QBittorrentServers returns the qBittorrent instances to sync, with their
+// defaults filled in. Without QBITTORRENT_INSTANCES, it is the single server
+// of QBITTORRENT_HOST, named "qbittorrent".
+func (c *Config) QBittorrentServers() []QBittorrentInstance {
+	if len(c.QBittorrentInstances) == 0 {
+		return []QBittorrentInstance{{
+			Name:     TorrentClientQBittorrent,
+			Host:     c.QBittorrentHost,
+			Port:     c.QBittorrentPort,
+			Username: c.QBittorrentUsername,
+			Password: c.QBittorrentPassword,
+		}}
+	}
+
+	servers := make([]QBittorrentInstance, 0, len(c.QBittorrentInstances))
+	for _, inst := range c.QBittorrentInstances {
+		if inst.Port == 0 {
+			inst.Port = c.QBittorrentPort
+		}
+		if inst.Username == "" {
+			inst.Username = c.QBittorrentUsername
+		}
+		if inst.Password == "" {
+			inst.Password = c.QBittorrentPassword
+		}
+		servers = append(servers, inst)
+	}
+	return servers
+}
+
+func qbittorrentURL(host string, port int) string {
+	// A host given with its scheme (https://qbt.lan) keeps it, the port being
+	// omitted when it is the default one of the scheme
+	if scheme, name, ok := strings.Cut(host, "://"); ok {
+		if (scheme == "http" && port == 80) || (scheme == "https" && port == 443) {
+			return host
+		}
+		return fmt.Sprintf("%s://%s:%d", scheme, name, port)
+	}
 	// Don't include port 80 explicitly as it can cause auth issues with some servers
-	if c.QBittorrentPort == 80 {
-		return fmt.Sprintf("http://%s", c.QBittorrentHost)
+	if port == 80 {
+		return fmt.Sprintf("http://%s", host)
 	}
-	if c.QBittorrentPort == 443 {
-		return fmt.Sprintf("https://%s", c.QBittorrentHost)
+	if port == 443 {
+		return fmt.Sprintf("https://%s", host)
 	}
-	return fmt.Sprintf("http://%s:%d", c.QBittorrentHost, c.QBittorrentPort)
+	return fmt.Sprintf("http://%s:%d", host, port)
+}
+
+// TransmissionURL returns the full Transmission RPC URL.
+func (c *Config) TransmissionURL() string {
+	path := "/" + strings.TrimPrefix(c.TransmissionRPCPath, "/")
+	if c.TransmissionPort == 443 {
+		return fmt.Sprintf("https://%s%s", c.TransmissionHost, path)
+	}
+	return fmt.Sprintf("http://%s:%d%s", c.TransmissionHost, c.TransmissionPort, path)
+}
+
+// DelugeURL returns the full Deluge Web UI JSON-RPC URL.
+func (c *Config) DelugeURL() string {
+	if c.DelugePort == 443 {
+		return fmt.Sprintf("https://%s/json", c.DelugeHost)
+	}
+	return fmt.Sprintf("http://%s:%d/json", c.DelugeHost, c.DelugePort)
 }
 
 func getEnvString(key, defaultValue string) string {
@@ -216,6 +1015,177 @@ func getEnvString(key, defaultValue string) string {
 	return defaultValue
 }
 
+// splitList splits a comma-separated environment value, ignoring empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parsePathMappings parses a comma-separated list of from=to path mappings.
+func parsePathMappings(value string) []PathMapping {
+	var mappings []PathMapping
+	for _, item := range splitList(value) {
+		from, to, _ := strings.Cut(item, "=")
+		mappings = append(mappings, PathMapping{From: strings.TrimSpace(from), To: strings.TrimSpace(to)})
+	}
+	return mappings
+}
+
+// parseCategoryRules parses a comma-separated list of name=pattern category rules.
+func parseCategoryRules(value string) []CategoryRule {
+	var rules []CategoryRule
+	for _, item := range splitList(value) {
+		name, pattern, _ := strings.Cut(item, "=")
+		rules = append(rules, CategoryRule{Name: strings.TrimSpace(name), Pattern: strings.TrimSpace(pattern)})
+	}
+	return rules
+}
+
+// scanRoot returns the scan root of the given path, added if missing.
+func (c *Config) scanRoot(p string) *ScanRoot {
+	for i := range c.ScanRoots {
+		if filepath.Clean(c.ScanRoots[i].Path) == filepath.Clean(p) {
+			return &c.ScanRoots[i]
+		}
+	}
+	c.ScanRoots = append(c.ScanRoots, ScanRoot{Path: p})
+	return &c.ScanRoots[len(c.ScanRoots)-1]
+}
+
+// envName returns the environment variable form of an instance name.
+func envName(name string) string {
+	return strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+func isValidInstanceName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
 func isValidPort(port int) bool {
 	return port >= 1 && port <= 65535
 }
+
+// isSubPath reports whether path is equal to or nested under base.
+func isSubPath(base, path string) bool {
+	rel, err := filepath.Rel(filepath.Clean(base), filepath.Clean(path))
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// isHiddenPath reports whether a component of path below base starts with a dot.
+func isHiddenPath(base, path string) bool {
+	rel, err := filepath.Rel(filepath.Clean(base), filepath.Clean(path))
+	if err != nil {
+		return false
+	}
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		if strings.HasPrefix(part, ".") && part != "." {
+			return true
+		}
+	}
+	return false
+}
+
+// maxPatternStrings bounds the strings a category pattern is expanded to.
+const maxPatternStrings = 32
+
+// categoryMarkers returns the directories matched by the category rules, in
+// order: the strings between slashes that a pattern matches, when it matches
+// a few fixed ones, like /movies/ or /(movies|films)/. Other patterns, such
+// as case-insensitive ones, give no marker.
+func categoryMarkers(rules []CategoryRule) []string {
+	var markers []string
+	seen := make(map[string]bool)
+	for _, r := range rules {
+		re, err := syntax.Parse(r.Pattern, syntax.Perl)
+		if err != nil {
+			continue
+		}
+		strs, ok := patternStrings(re)
+		if !ok {
+			continue
+		}
+		for _, m := range strs {
+			if len(m) > 2 && strings.HasPrefix(m, "/") && strings.HasSuffix(m, "/") && !seen[m] {
+				seen[m] = true
+				markers = append(markers, m)
+			}
+		}
+	}
+	return markers
+}
+
+// patternStrings returns the strings matched by re, or false when they are
+// not a few fixed strings.
+func patternStrings(re *syntax.Regexp) ([]string, bool) {
+	switch re.Op {
+	case syntax.OpEmptyMatch:
+		return []string{""}, true
+	case syntax.OpLiteral:
+		if re.Flags&syntax.FoldCase != 0 {
+			return nil, false
+		}
+		return []string{string(re.Rune)}, true
+	case syntax.OpCharClass:
+		var strs []string
+		for i := 0; i+1 < len(re.Rune); i += 2 {
+			for c := re.Rune[i]; c <= re.Rune[i+1]; c++ {
+				if len(strs) == maxPatternStrings {
+					return nil, false
+				}
+				strs = append(strs, string(c))
+			}
+		}
+		return strs, true
+	case syntax.OpCapture:
+		return patternStrings(re.Sub[0])
+	case syntax.OpQuest:
+		strs, ok := patternStrings(re.Sub[0])
+		if !ok || len(strs) == maxPatternStrings {
+			return nil, false
+		}
+		return append(strs, ""), true
+	case syntax.OpAlternate:
+		var strs []string
+		for _, sub := range re.Sub {
+			subStrs, ok := patternStrings(sub)
+			if !ok || len(strs)+len(subStrs) > maxPatternStrings {
+				return nil, false
+			}
+			strs = append(strs, subStrs...)
+		}
+		return strs, true
+	case syntax.OpConcat:
+		strs := []string{""}
+		for _, sub := range re.Sub {
+			subStrs, ok := patternStrings(sub)
+			if !ok || len(strs)*len(subStrs) > maxPatternStrings {
+				return nil, false
+			}
+			var next []string
+			for _, prefix := range strs {
+				for _, s := range subStrs {
+					next = append(next, prefix+s)
+				}
+			}
+			strs = next
+		}
+		return strs, true
+	}
+	return nil, false
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Settings are the options that can be changed while the program runs, from
+// the WebUI: the scan roots, the category rules and the path comparison
+// settings. They are stored in the config file under the keys of Config.
+type Settings struct {
+	ScanRoots       []ScanRoot     `json:"scan_roots"`
+	CategoryRules   []CategoryRule `json:"category_rules"`   // Empty for DefaultCategoryRules
+	RelativeMarkers []string       `json:"relative_markers"` // Empty to derive them from CategoryRules
+	PathRewrites    []PathMapping  `json:"path_rewrites"`
+}
+
+// settingsEnv lists, for the config file key of every setting, the
+// environment variables that override it.
+var settingsEnv = []struct {
+	key  string
+	vars []string
+}{
+	{"scan_roots", []string{"SCAN_DEPTH", "SCAN_INCLUDE"}},
+	{"category_rules", []string{"CATEGORY_RULES"}},
+	{"relative_markers", []string{"RELATIVE_MARKERS"}},
+	{"path_rewrites", []string{"PATH_REWRITES"}},
+}
+
+// Path returns the path of the config file: CONFIG_PATH, or DefaultConfigPath.
+func Path() string {
+	return getEnvString("CONFIG_PATH", DefaultConfigPath)
+}
+
+// Settings returns the settings of c, with empty lists rather than nil.
+func (c *Config) Settings() Settings {
+	s := Settings{
+		ScanRoots:       c.ScanRoots,
+		CategoryRules:   c.CategoryRules,
+		RelativeMarkers: c.RelativeMarkers,
+		PathRewrites:    c.PathRewrites,
+	}
+	if s.ScanRoots == nil {
+		s.ScanRoots = []ScanRoot{}
+	}
+	if s.CategoryRules == nil {
+		s.CategoryRules = []CategoryRule{}
+	}
+	if s.RelativeMarkers == nil {
+		s.RelativeMarkers = []string{}
+	}
+	if s.PathRewrites == nil {
+		s.PathRewrites = []PathMapping{}
+	}
+	return s
+}
+
+// WithSettings returns a copy of c with settings applied, or an error when
+// the resulting configuration is not valid. No category rules stands for
+// DefaultCategoryRules, as in the config file.
+func (c *Config) WithSettings(settings Settings) (*Config, error) {
+	next := *c
+	next.ScanRoots = settings.ScanRoots
+	next.CategoryRules = settings.CategoryRules
+	if len(next.CategoryRules) == 0 {
+		next.CategoryRules = DefaultCategoryRules
+	}
+	next.RelativeMarkers = settings.RelativeMarkers
+	next.PathRewrites = settings.PathRewrites
+	if err := next.Validate(); err != nil {
+		return nil, err
+	}
+	return &next, nil
+}
+
+// OverriddenSettings returns the config file keys of the settings set by
+// environment variables. Those take precedence over the config file on the
+// next start, whatever was saved with SaveSettings.
+func OverriddenSettings() []string {
+	keys := []string{}
+	for _, s := range settingsEnv {
+		for _, v := range s.vars {
+			if os.Getenv(v) != "" {
+				keys = append(keys, s.key)
+				break
+			}
+		}
+	}
+	return keys
+}
+
+// SaveSettings writes settings to the config file at path, keeping its other
+// keys, and creates it when it does not exist. The file is replaced at once,
+// so that a failed write leaves the previous one intact.
+func SaveSettings(path string, settings Settings) error {
+	doc := make(map[string]json.RawMessage)
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("invalid JSON: %w", err)
+		}
+	}
+
+	values, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(values, &doc); err != nil {
+		return err
+	}
+	data, err = json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// The file may hold passwords: 0600, unless it already exists
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if info, err := os.Stat(path); err == nil {
+		if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
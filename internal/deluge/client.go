@@ -0,0 +1,286 @@
+// Package deluge provides a client for the Deluge Web UI JSON-RPC API.
+package deluge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"godatacleaner/internal/models"
+)
+
+// Client is a Deluge Web UI client. The Web UI forwards the calls to a
+// Deluge daemon, to which it is connected on login when needed.
+type Client struct {
+	url        string
+	password   string
+	httpClient *http.Client
+	nextID     atomic.Int64
+}
+
+// NewClient creates a new Deluge client for the JSON-RPC endpoint url
+// (e.g. http://localhost:8112/json).
+func NewClient(url, password string) (*Client, error) {
+	if url == "" {
+		return nil, fmt.Errorf("deluge: url cannot be empty")
+	}
+	// The session is kept in a cookie set by auth.login
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, fmt.Errorf("deluge: %w", err)
+	}
+	return &Client{
+		url:      url,
+		password: password,
+		httpClient: &http.Client{
+			Jar:     jar,
+			Timeout: 30 * time.Second,
+		},
+	}, nil
+}
+
+// Name returns the name of the torrent client.
+func (c *Client) Name() string {
+	return "Deluge"
+}
+
+// Login authenticates with the Web UI and connects it to the first known
+// daemon if it is not connected yet.
+func (c *Client) Login(ctx context.Context) error {
+	var ok bool
+	if err := c.call(ctx, "auth.login", []interface{}{c.password}, &ok); err != nil {
+		return fmt.Errorf("deluge: authentication failed: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("deluge: authentication failed: invalid password")
+	}
+
+	var connected bool
+	if err := c.call(ctx, "web.connected", []interface{}{}, &connected); err != nil {
+		return fmt.Errorf("deluge: %w", err)
+	}
+	if connected {
+		return nil
+	}
+
+	// Each host is [id, address, port, status]
+	var hosts [][]interface{}
+	if err := c.call(ctx, "web.get_hosts", []interface{}{}, &hosts); err != nil {
+		return fmt.Errorf("deluge: failed to get daemons: %w", err)
+	}
+	if len(hosts) == 0 || len(hosts[0]) == 0 {
+		return fmt.Errorf("deluge: no daemon configured in the Web UI")
+	}
+	if err := c.call(ctx, "web.connect", []interface{}{hosts[0][0]}, nil); err != nil {
+		return fmt.Errorf("deluge: failed to connect to daemon: %w", err)
+	}
+	return nil
+}
+
+// status is the subset of the torrent status fields used by the client.
+type status struct {
+	Name          string  `json:"name"`
+	TotalWanted   int64   `json:"total_wanted"`
+	SavePath      string  `json:"save_path"`
+	State         string  `json:"state"`
+	Ratio         float64 `json:"ratio"`
+	SeedingTime   int64   `json:"seeding_time"`
+	TrackerStatus string  `json:"tracker_status"`
+	Trackers      []struct {
+		URL string `json:"url"`
+	} `json:"trackers"`
+	Files []struct {
+		Index int    `json:"index"`
+		Path  string `json:"path"`
+		Size  int64  `json:"size"`
+	} `json:"files"`
+	FilePriorities []int `json:"file_priorities"`
+}
+
+// GetTorrents retrieves the list of all torrents from Deluge.
+func (c *Client) GetTorrents(ctx context.Context) ([]models.Torrent, error) {
+	var res map[string]status
+	params := []interface{}{map[string]interface{}{}, []string{"name", "total_wanted", "save_path", "state", "ratio", "seeding_time", "trackers", "tracker_status"}}
+	if err := c.call(ctx, "core.get_torrents_status", params, &res); err != nil {
+		return nil, fmt.Errorf("deluge: failed to get torrents: %w", err)
+	}
+
+	torrents := make([]models.Torrent, 0, len(res))
+	for hash, t := range res {
+		announces := make([]string, 0, len(t.Trackers))
+		for _, tr := range t.Trackers {
+			announces = append(announces, tr.URL)
+		}
+		// tracker_status is e.g. "Announce OK" or "Error: unregistered torrent"
+		var message string
+		if msg, ok := strings.CutPrefix(t.TrackerStatus, "Error: "); ok {
+			message = msg
+		}
+		torrents = append(torrents, models.Torrent{
+			Hash:           hash,
+			Name:           t.Name,
+			Size:           t.TotalWanted, // Wanted files only, as in qBittorrent
+			SavePath:       t.SavePath,
+			State:          torrentState(t.State),
+			Ratio:          max(t.Ratio, 0), // -1 when not available
+			SeedingTime:    t.SeedingTime,
+			Trackers:       models.TrackerHosts(announces),
+			TrackerMessage: message,
+		})
+	}
+	return torrents, nil
+}
+
+// torrentState converts a Deluge torrent state.
+func torrentState(state string) string {
+	switch state {
+	case "Seeding":
+		return models.TorrentStateSeeding
+	case "Downloading", "Allocating":
+		return models.TorrentStateDownloading
+	case "Paused":
+		return models.TorrentStatePaused
+	case "Queued":
+		return models.TorrentStateQueued
+	case "Checking", "Moving":
+		return models.TorrentStateChecking
+	case "Error":
+		return models.TorrentStateErrored
+	default:
+		return models.TorrentStateUnknown
+	}
+}
+
+// GetTorrentFiles retrieves the files of a specific torrent by its hash.
+func (c *Client) GetTorrentFiles(ctx context.Context, hash string) ([]models.TorrentFile, error) {
+	if hash == "" {
+		return nil, fmt.Errorf("deluge: torrent hash cannot be empty")
+	}
+
+	var t status
+	params := []interface{}{hash, []string{"name", "save_path", "files", "file_priorities"}}
+	if err := c.call(ctx, "core.get_torrent_status", params, &t); err != nil {
+		return nil, fmt.Errorf("deluge: failed to get files for torrent %s: %w", hash, err)
+	}
+
+	files := make([]models.TorrentFile, 0, len(t.Files))
+	for _, f := range t.Files {
+		// Files with the "skip" priority are not expected on disk
+		if f.Index < len(t.FilePriorities) && t.FilePriorities[f.Index] == 0 {
+			continue
+		}
+
+		// File paths are relative to the save path and include the
+		// torrent folder for multi-file torrents, as in qBittorrent
+		files = append(files, models.TorrentFile{
+			TorrentHash: hash,
+			TorrentName: t.Name,
+			FileName:    filepath.Base(f.Path),
+			FilePath:    filepath.Join(t.SavePath, f.Path),
+			Size:        f.Size,
+		})
+	}
+	return files, nil
+}
+
+// DeleteTorrent deletes a torrent, and its files on disk when deleteFiles is set.
+func (c *Client) DeleteTorrent(ctx context.Context, hash string, deleteFiles bool) error {
+	if err := c.call(ctx, "core.remove_torrent", []interface{}{hash, deleteFiles}, nil); err != nil {
+		return fmt.Errorf("deluge: failed to delete torrent %s: %w", hash, err)
+	}
+	return nil
+}
+
+// PauseTorrent pauses a torrent.
+func (c *Client) PauseTorrent(ctx context.Context, hash string) error {
+	if err := c.call(ctx, "core.pause_torrent", []interface{}{hash}, nil); err != nil {
+		return fmt.Errorf("deluge: failed to pause torrent %s: %w", hash, err)
+	}
+	return nil
+}
+
+// ResumeTorrent resumes a torrent.
+func (c *Client) ResumeTorrent(ctx context.Context, hash string) error {
+	if err := c.call(ctx, "core.resume_torrent", []interface{}{hash}, nil); err != nil {
+		return fmt.Errorf("deluge: failed to resume torrent %s: %w", hash, err)
+	}
+	return nil
+}
+
+// RecheckTorrent forces a recheck of a torrent.
+func (c *Client) RecheckTorrent(ctx context.Context, hash string) error {
+	if err := c.call(ctx, "core.force_recheck", []interface{}{[]string{hash}}, nil); err != nil {
+		return fmt.Errorf("deluge: failed to recheck torrent %s: %w", hash, err)
+	}
+	return nil
+}
+
+// MoveTorrent moves the data of a torrent to location.
+func (c *Client) MoveTorrent(ctx context.Context, hash, location string) error {
+	if err := c.call(ctx, "core.move_storage", []interface{}{[]string{hash}, location}, nil); err != nil {
+		return fmt.Errorf("deluge: failed to move torrent %s: %w", hash, err)
+	}
+	return nil
+}
+
+// ReannounceTorrents reannounces torrents to their trackers.
+func (c *Client) ReannounceTorrents(ctx context.Context, hashes []string) error {
+	if err := c.call(ctx, "core.force_reannounce", []interface{}{hashes}, nil); err != nil {
+		return fmt.Errorf("deluge: failed to reannounce torrents: %w", err)
+	}
+	return nil
+}
+
+// call performs a JSON-RPC call and decodes its result into result.
+func (c *Client) call(ctx context.Context, method string, params []interface{}, result interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"method": method,
+		"params": params,
+		"id":     c.nextID.Add(1),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var res struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+			Code    int    `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	if res.Error != nil {
+		return fmt.Errorf("rpc error %d: %s", res.Error.Code, res.Error.Message)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(res.Result, result)
+}
@@ -1,37 +1,323 @@
 // Package models defines the data structures used throughout GoDataCleaner.
 package models
 
+import (
+	"net/url"
+	"sort"
+	"time"
+)
+
 // Torrent represents a torrent from qBittorrent.
 type Torrent struct {
-	Hash     string
-	Name     string
-	Size     int64
-	SavePath string
+	Hash        string   `json:"hash"`
+	Name        string   `json:"name"`
+	Size        int64    `json:"size"`
+	SavePath    string   `json:"save_path"`
+	Instance    string   `json:"instance"` // Torrent client or qBittorrent instance, set by the syncer
+	State       string   `json:"state"`    // One of the TorrentState values
+	Ratio       float64  `json:"ratio"`
+	SeedingTime int64    `json:"seeding_time"` // Seconds
+	Trackers    []string `json:"trackers"`     // Tracker hosts, see TrackerHost
+	// Error reported by the trackers of the torrent when none of them works
+	TrackerMessage string `json:"tracker_message,omitempty"`
+	// SavePath after the path mappings of the instance, as the paths of the
+	// torrent files, set by the syncer
+	LocalSavePath string `json:"local_save_path"`
+	// Path of the single file or of the root folder of the torrent, as seen
+	// by its client, when it reports it (qBittorrent)
+	ContentPath string `json:"content_path,omitempty"`
+	// When the syncer last fetched the files of the torrent from its client
+	FilesSyncedAt time.Time `json:"-"`
+}
+
+// TrackerHost returns the host of a tracker announce URL. Only the host is
+// stored since private trackers put the passkey in the rest of the URL.
+func TrackerHost(announce string) string {
+	u, err := url.Parse(announce)
+	if err != nil || u.Hostname() == "" {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// TrackerHosts returns the sorted and deduplicated hosts of announce URLs.
+func TrackerHosts(announces []string) []string {
+	seen := make(map[string]bool, len(announces))
+	var hosts []string
+	for _, announce := range announces {
+		host := TrackerHost(announce)
+		if host != "" && !seen[host] {
+			seen[host] = true
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// Torrent states, common to every torrent client.
+const (
+	TorrentStateDownloading = "downloading"
+	TorrentStateSeeding     = "seeding"
+	TorrentStatePaused      = "paused"
+	TorrentStateQueued      = "queued"
+	TorrentStateChecking    = "checking"
+	TorrentStateErrored     = "errored"
+	TorrentStateUnknown     = "unknown"
+)
+
+// TorrentStateStats represents statistics for the torrents in a given state.
+type TorrentStateStats struct {
+	State        string `json:"state"`
+	TorrentCount int64  `json:"torrent_count"`
+	TotalSize    int64  `json:"total_size"`
+}
+
+// TorrentStatesResponse represents the API response for torrent state statistics.
+type TorrentStatesResponse struct {
+	States []TorrentStateStats `json:"states"`
+}
+
+// TrackerStats represents statistics for the torrents announced to a given tracker.
+// A torrent with several trackers is counted for each of them.
+type TrackerStats struct {
+	Tracker      string `json:"tracker"` // Empty for torrents without tracker
+	TorrentCount int64  `json:"torrent_count"`
+	TotalSize    int64  `json:"total_size"`
+}
+
+// TrackerStatsResponse represents the API response for tracker statistics.
+type TrackerStatsResponse struct {
+	Trackers []TrackerStats `json:"trackers"`
 }
 
 // TorrentFile represents a file within a torrent.
 type TorrentFile struct {
+	ID          int64  `json:"id,omitempty"` // Set by the paginated queries, see Cursor
 	TorrentHash string `json:"torrent_hash"`
 	TorrentName string `json:"torrent_name"`
 	FileName    string `json:"file_name"`
 	FilePath    string `json:"file_path"`
 	Size        int64  `json:"size"`
+	Instance    string `json:"instance"` // Torrent client or qBittorrent instance the file comes from
+}
+
+// FileTorrent represents a torrent expecting a local file, see
+// FileTorrentsResponse.
+type FileTorrent struct {
+	Instance string `json:"instance"`
+	Hash     string `json:"hash"`
+	Name     string `json:"name"`
+	State    string `json:"state"`              // Empty for a torrent not listed by the last sync
+	FilePath string `json:"file_path"`          // Path of the file in the torrent
+	Hardlink string `json:"hardlink,omitempty"` // Hard link of the local file the torrent expects, empty when it expects the file itself
+}
+
+// Status of a local file in FileTorrentsResponse.
+const (
+	FileStatusMatched     = "matched"     // Expected by a torrent, or hard linked to a file one expects
+	FileStatusDownloading = "downloading" // Temporary file of a download, or expected by a downloading torrent
+	FileStatusIgnored     = "ignored"     // In the ignore list
+	FileStatusOrphan      = "orphan"
+)
+
+// NearMiss represents a torrent file with the same name as a local file but
+// another relative path, which therefore does not match it.
+type NearMiss struct {
+	Instance     string `json:"instance"`
+	Hash         string `json:"hash"`
+	Name         string `json:"name"` // Torrent name
+	FilePath     string `json:"file_path"`
+	RelativePath string `json:"relative_path"`
+	Size         int64  `json:"size"`
+}
+
+// FileDetail represents everything known about a local file, to explain how it
+// matches the torrents.
+type FileDetail struct {
+	LocalFile
+	RelativePath string        `json:"relative_path"` // Path compared with the relative paths of the torrent files
+	Status       string        `json:"status"`        // One of the FileStatus constants
+	Torrents     []FileTorrent `json:"torrents"`
+	NearMisses   []NearMiss    `json:"near_misses"`
+}
+
+// MissingTorrent represents a torrent some of whose files were not found by
+// the last scan, or found with another size, e.g. deleted, moved or truncated
+// outside of the client.
+type MissingTorrent struct {
+	Instance        string  `json:"instance"`
+	Hash            string  `json:"hash"`
+	Name            string  `json:"name"`
+	State           string  `json:"state"` // Empty for a torrent not listed by the last sync
+	FileCount       int64   `json:"file_count"`
+	TotalSize       int64   `json:"total_size"`
+	MissingFiles    int64   `json:"missing_files"`
+	MissingBytes    int64   `json:"missing_bytes"`
+	MismatchedFiles int64   `json:"mismatched_files"` // Files found locally, but with another size
+	MismatchedBytes int64   `json:"mismatched_bytes"`
+	Completeness    float64 `json:"completeness"` // Percentage of the size found locally
+}
+
+// MissingFile represents a file of a torrent that no local file matches:
+// absent from the disk, or found there with another size.
+type MissingFile struct {
+	TorrentFile
+	Mismatched bool  `json:"mismatched"`
+	LocalSize  int64 `json:"local_size,omitempty"` // Size of the local file of a mismatched file
+}
+
+// TorrentCompleteness represents the share of the files of a torrent found
+// by the last scan.
+type TorrentCompleteness struct {
+	Instance     string  `json:"instance"`
+	Hash         string  `json:"hash"`
+	Name         string  `json:"name"`
+	State        string  `json:"state"` // Empty for a torrent not listed by the last sync
+	FileCount    int64   `json:"file_count"`
+	PresentFiles int64   `json:"present_files"`
+	TotalSize    int64   `json:"total_size"`
+	MissingBytes int64   `json:"missing_bytes"`
+	Completeness float64 `json:"completeness"` // Percentage of the size found locally
+}
+
+// FileFilter selects the files recorded by the scans (SCAN_MIN_SIZE and
+// SCAN_EXTENSIONS), so that the torrent files left out are not reported missing.
+type FileFilter struct {
+	MinSize    int64
+	Extensions []string // Without case, with or without the leading dot; empty for all
 }
 
 // LocalFile represents a file found on the local filesystem.
 type LocalFile struct {
-	FilePath string `json:"file_path"`
-	FileName string `json:"file_name"`
-	Size     int64  `json:"size"`
-	Category string `json:"category"`
+	ID        int64     `json:"id,omitempty"` // Set by the paginated queries, see Cursor
+	FilePath  string    `json:"file_path"`
+	FileName  string    `json:"file_name"`
+	Size      int64     `json:"size"`
+	Allocated int64     `json:"allocated"` // Bytes allocated on disk, below Size for sparse files
+	Category  string    `json:"category"`
+	Links     int64     `json:"links,omitempty"` // Hard links to the file, 0 when unknown
+	ModTime   time.Time `json:"mod_time"`        // Zero when unknown
+
+	// Path on which the file is matched to torrent files, set by the
+	// paginated queries
+	RelativePath string `json:"relative_path,omitempty"`
+
+	// Identity of the file on disk, shared by its hard links (0 when unknown)
+	Device int64 `json:"-"`
+	Inode  int64 `json:"-"`
+
+	// Content checksum, prefixed with how it was computed (see CHECKSUM); empty
+	// when the file was not hashed since it last changed
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// DuplicateGroup represents local files with the same content, that are not
+// hard links of each other: the same checksum and size, or the same name and
+// size when compared by name.
+type DuplicateGroup struct {
+	Checksum    string          `json:"checksum"`       // Empty when compared by name
+	Name        string          `json:"name,omitempty"` // Set when compared by name
+	Size        int64           `json:"size"`
+	Copies      int64           `json:"copies"`       // Distinct files on disk, hard links counting once
+	WastedBytes int64           `json:"wasted_bytes"` // Size of the copies beyond the first
+	Files       []DuplicateFile `json:"files"`
+}
+
+// DuplicateFile represents a copy of a DuplicateGroup.
+type DuplicateFile struct {
+	LocalFile
+	Status    string `json:"status"`    // One of the FileStatus constants
+	Deletable bool   `json:"deletable"` // An orphan whose content another copy keeps
+}
+
+// ScanDir is a directory read by the local scan, with its modification time
+// when it was read (zero when it may change again unnoticed).
+type ScanDir struct {
+	Path    string
+	ModTime time.Time
+}
+
+// Pseudo-categories of the samples, proofs, extras and junk files of a
+// release, given to local files whatever their path rules.
+const (
+	CategorySample = "sample"
+	CategoryProof  = "proof"
+	CategoryExtras = "extras"
+	CategoryJunk   = "junk"
+)
+
+// ExtraCategories lists the pseudo-categories of the samples, proofs, extras
+// and junk files.
+var ExtraCategories = []string{CategorySample, CategoryProof, CategoryExtras, CategoryJunk}
+
+// ExtraFile represents a local file of an extra pseudo-category, orphan or not.
+type ExtraFile struct {
+	FilePath  string `json:"file_path"`
+	FileName  string `json:"file_name"`
+	Size      int64  `json:"size"`
+	Allocated int64  `json:"allocated"`
+	Category  string `json:"category"`
+	Orphan    bool   `json:"orphan"`
+}
+
+// TopFile represents one of the largest local files.
+type TopFile struct {
+	FilePath  string    `json:"file_path"`
+	FileName  string    `json:"file_name"`
+	Size      int64     `json:"size"`
+	Allocated int64     `json:"allocated"`
+	Category  string    `json:"category"`
+	Orphan    bool      `json:"orphan"`
+	ModTime   time.Time `json:"mod_time"` // Zero when unknown
+}
+
+// TopFolder represents one of the largest local folders, with the totals of
+// the files directly in it.
+type TopFolder struct {
+	Path      string `json:"path"`
+	FileCount int64  `json:"file_count"`
+	TotalSize int64  `json:"total_size"`
+	Allocated int64  `json:"allocated"`
 }
 
 // OrphanFile represents a local file that is not present in the torrent database.
 type OrphanFile struct {
-	FilePath string `json:"file_path"`
-	FileName string `json:"file_name"`
-	Size     int64  `json:"size"`
-	Category string `json:"category"`
+	ID        int64     `json:"id,omitempty"` // Set by the paginated queries, see Cursor
+	FilePath  string    `json:"file_path"`
+	FileName  string    `json:"file_name"`
+	Size      int64     `json:"size"`
+	Allocated int64     `json:"allocated"` // Bytes freed by deleting it, see LocalFile.Allocated
+	Category  string    `json:"category"`
+	Links     int64     `json:"links,omitempty"` // Hard links to the file: deleting it frees no space if > 1
+	ModTime   time.Time `json:"mod_time"`        // Zero when unknown
+	// First sync that found the file orphan, since when it stayed orphan;
+	// zero when unknown
+	OrphanedAt time.Time `json:"orphaned_at"`
+	// Path on which the file is matched to torrent files, set by the
+	// paginated queries
+	RelativePath string `json:"relative_path,omitempty"`
+}
+
+// OrphanRelease groups the orphans of a release folder, usually the folder
+// of a whole movie or show.
+type OrphanRelease struct {
+	Folder     string    `json:"folder"` // Absolute path of the folder, or of the file when Single
+	Name       string    `json:"name"`
+	Category   string    `json:"category"`
+	Single     bool      `json:"single"`     // Lone file directly in its category folder
+	FileCount  int64     `json:"file_count"` // Orphans in the folder
+	TotalSize  int64     `json:"total_size"`
+	Allocated  int64     `json:"allocated"`
+	LocalFiles int64     `json:"local_files"` // Local files in the folder, orphan or not
+	ModTime    time.Time `json:"mod_time"`    // Latest modification of its orphans, zero when unknown
+}
+
+// OrphanReleaseFilesResponse represents the API response for the orphans of
+// a release folder.
+type OrphanReleaseFilesResponse struct {
+	Folder string       `json:"folder"`
+	Files  []OrphanFile `json:"files"`
 }
 
 // Stats represents global statistics for torrents.
@@ -48,11 +334,48 @@ type FolderStats struct {
 	TotalSize int64  `json:"total_size"`
 }
 
+// DiskUsage represents the space of a filesystem holding the library.
+type DiskUsage struct {
+	MountPoint string   `json:"mount_point"`
+	Roots      []string `json:"roots"` // LOCAL_PATH and the scan roots on this filesystem
+	Total      int64    `json:"total"`
+	Used       int64    `json:"used"`
+	Free       int64    `json:"free"` // Available to unprivileged users, below Total - Used when blocks are reserved
+}
+
+// DiskUsageResponse represents the API response for the filesystems of the library.
+type DiskUsageResponse struct {
+	Disks []DiskUsage `json:"disks"`
+}
+
+// FolderEntry represents a child of a folder of the folder tree: a subfolder,
+// with the totals of all the files below it, or a file.
+type FolderEntry struct {
+	Name       string `json:"name"`
+	Path       string `json:"path"`
+	Dir        bool   `json:"dir"`
+	FileCount  int64  `json:"file_count"`
+	TotalSize  int64  `json:"total_size"`
+	OrphanSize int64  `json:"orphan_size"` // Size of the orphans among the local files
+}
+
+// FolderTree represents a folder of the folder tree with its children, the
+// biggest first.
+type FolderTree struct {
+	Path       string        `json:"path"`
+	Parent     string        `json:"parent"` // Empty at the root
+	FileCount  int64         `json:"file_count"`
+	TotalSize  int64         `json:"total_size"`
+	OrphanSize int64         `json:"orphan_size"`
+	Entries    []FolderEntry `json:"entries"`
+}
+
 // CategoryStats represents statistics for a specific category.
 type CategoryStats struct {
-	Category  string `json:"category"`
-	FileCount int64  `json:"file_count"`
-	TotalSize int64  `json:"total_size"`
+	Category      string `json:"category"`
+	FileCount     int64  `json:"file_count"`
+	TotalSize     int64  `json:"total_size"`
+	AllocatedSize int64  `json:"allocated_size"` // Bytes allocated on disk
 }
 
 // QueryOptions defines parameters for paginated queries.
@@ -63,7 +386,36 @@ type QueryOptions struct {
 	Order    string // "asc" ou "desc"
 	Search   string
 	Category string
-	Unique   bool // Filter unique files only (by relative_path)
+	State    string // Torrent state filter
+	Unique   bool   // Filter unique files only (by relative_path)
+
+	// Local files modified at least MinAge ago, and at most MaxAge ago (0 = no limit)
+	MinAge time.Duration
+	MaxAge time.Duration
+
+	// Orphans orphaned for at least MinOrphanAge (0 = no limit)
+	MinOrphanAge time.Duration
+
+	// Local files and orphans of at least MinSize and at most MaxSize bytes (0 = no limit)
+	MinSize int64
+	MaxSize int64
+
+	// Orphans without other hard links only: deleting a hard-linked file
+	// frees no space
+	ExcludeHardlinked bool
+
+	// Rows following After in the sort order, instead of those of Page
+	// (keyset pagination)
+	After *Cursor
+}
+
+// Cursor is the position of a row in a sorted list, for keyset pagination:
+// the value of the sort column and the ID of the row. Unlike pages, it
+// stays fast deep into large lists and skips no row when rows are added or
+// removed before it.
+type Cursor struct {
+	ID    int64  `json:"after_id"`
+	Value string `json:"after_value"`
 }
 
 // PaginatedResponse represents a paginated API response.
@@ -73,6 +425,7 @@ type PaginatedResponse struct {
 	Page       int         `json:"page"`
 	PerPage    int         `json:"per_page"`
 	TotalPages int         `json:"total_pages"`
+	Next       *Cursor     `json:"next,omitempty"` // Cursor of the last row, when the page is full
 }
 
 // TorrentStatsResponse represents the API response for torrent statistics.
@@ -87,6 +440,16 @@ type FolderStatsResponse struct {
 	Folders []FolderStats `json:"folders"`
 }
 
+// TopFilesResponse represents the API response for the largest local files.
+type TopFilesResponse struct {
+	Files []TopFile `json:"files"`
+}
+
+// TopFoldersResponse represents the API response for the largest local folders.
+type TopFoldersResponse struct {
+	Folders []TopFolder `json:"folders"`
+}
+
 // CategoryStatsResponse represents the API response for category statistics.
 type CategoryStatsResponse struct {
 	Categories []CategoryStats `json:"categories"`
@@ -94,12 +457,386 @@ type CategoryStatsResponse struct {
 
 // ExtensionStats represents statistics for a specific file extension.
 type ExtensionStats struct {
-	Extension string `json:"extension"`
-	FileCount int64  `json:"file_count"`
-	TotalSize int64  `json:"total_size"`
+	Extension     string          `json:"extension"`
+	FileCount     int64           `json:"file_count"`
+	TotalSize     int64           `json:"total_size"`
+	AllocatedSize int64           `json:"allocated_size"`
+	OrphanFiles   int64           `json:"orphan_files"`
+	OrphanSize    int64           `json:"orphan_size"`
+	Categories    []CategoryStats `json:"categories"` // Largest first
 }
 
 // ExtensionStatsResponse represents the API response for extension statistics.
 type ExtensionStatsResponse struct {
 	Extensions []ExtensionStats `json:"extensions"`
 }
+
+// CleanupJob represents a scheduled cleanup of orphan files.
+type CleanupJob struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Schedule   string     `json:"schedule"` // Cron expression or "@after-sync"
+	Category   string     `json:"category"`
+	Quarantine bool       `json:"quarantine"`
+	Archive    bool       `json:"archive"`
+	Companions bool       `json:"companions"`
+	DryRun     bool       `json:"dry_run"`
+	Enabled    bool       `json:"enabled"`
+	LastRunAt  *time.Time `json:"last_run_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Cleanup run sources
+const (
+	CleanupSourceJob = "job" // A cleanup job, scheduled or after a sync
+	CleanupSourceWeb = "web" // A deletion confirmed in the WebUI or through the API
+	CleanupSourceCLI = "cli" // The clean command
+)
+
+// CleanupRun represents one execution of a cleanup job, or one deletion of
+// orphans outside of a job. Together they form the deletion audit log.
+type CleanupRun struct {
+	ID             int64     `json:"id"`
+	Source         string    `json:"source"`
+	JobID          int64     `json:"job_id"`             // Zero outside of a job
+	JobName        string    `json:"job_name,omitempty"` // Empty once the job is deleted
+	User           string    `json:"user,omitempty"`     // Who confirmed a WebUI deletion
+	StartedAt      time.Time `json:"started_at"`
+	FinishedAt     time.Time `json:"finished_at"`
+	DryRun         bool      `json:"dry_run"`
+	Quarantine     bool      `json:"quarantine"`
+	FilesRemoved   int64     `json:"files_removed"`
+	BytesReclaimed int64     `json:"bytes_reclaimed"`
+	Failed         int64     `json:"failed"`
+	Error          string    `json:"error,omitempty"`
+}
+
+// Sync run statuses
+const (
+	SyncRunDone      = "done"      // Torrent and local files all refreshed
+	SyncRunPartial   = "partial"   // Finished, but some files were kept from the previous sync
+	SyncRunFailed    = "failed"    // Stopped by an error
+	SyncRunCancelled = "cancelled" // Stopped by a cancellation
+)
+
+// SyncRun represents one execution of a sync.
+type SyncRun struct {
+	ID              int64     `json:"id"`
+	StartedAt       time.Time `json:"started_at"`
+	FinishedAt      time.Time `json:"finished_at"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	Status          string    `json:"status"`
+	Full            bool      `json:"full"`
+	TorrentsSynced  bool      `json:"torrents_synced"` // False when a torrent client could not be reached
+	Torrents        int64     `json:"torrents"`
+	TorrentsFailed  int64     `json:"torrents_failed"` // Torrents whose files were kept from the previous sync
+	TorrentFiles    int64     `json:"torrent_files"`
+	LocalSynced     bool      `json:"local_synced"` // False when the local files were kept from the previous sync
+	LocalFiles      int64     `json:"local_files"`
+	ChangesRecorded bool      `json:"changes_recorded"` // Whether the local files added, removed and changed were recorded, see SyncDiff
+	Error           string    `json:"error,omitempty"`
+}
+
+// Local file changes recorded by a sync
+const (
+	FileAdded   = "added"
+	FileRemoved = "removed"
+	FileChanged = "changed" // Size changed
+)
+
+// FileChange represents a local file added, removed or whose size changed
+// since the previous sync.
+type FileChange struct {
+	FilePath string `json:"file_path"`
+	Change   string `json:"change"`
+	Size     int64  `json:"size"`               // Size after the sync, before it for a removed file
+	OldSize  int64  `json:"old_size,omitempty"` // Size before the sync of a changed file
+}
+
+// SyncDiff represents the local files changed by a sync since the previous one.
+type SyncDiff struct {
+	Run          SyncRun      `json:"run"`
+	Added        int64        `json:"added"`
+	Removed      int64        `json:"removed"`
+	Changed      int64        `json:"changed"`
+	AddedBytes   int64        `json:"added_bytes"`
+	RemovedBytes int64        `json:"removed_bytes"`
+	Files        []FileChange `json:"files"`
+}
+
+// SyncError represents a torrent whose files a sync could not fetch, or a
+// torrent client it could not reach.
+type SyncError struct {
+	Instance  string `json:"instance"`
+	Hash      string `json:"hash,omitempty"` // Empty when the whole client failed
+	Name      string `json:"name,omitempty"`
+	Error     string `json:"error"`
+	KeptFiles int64  `json:"kept_files"` // Files kept from the previous sync, none for a new torrent
+}
+
+// SyncErrors represents the torrents and clients a sync could not read.
+type SyncErrors struct {
+	Run    SyncRun     `json:"run"`
+	Errors []SyncError `json:"errors"`
+}
+
+// CleanupRunFile represents a file handled by a cleanup run.
+type CleanupRunFile struct {
+	RunID    int64  `json:"run_id"`
+	FilePath string `json:"file_path"`
+	Size     int64  `json:"size"`
+	MovedTo  string `json:"moved_to,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// CleanupJobsResponse represents the API response for the cleanup job list.
+type CleanupJobsResponse struct {
+	Jobs []CleanupJob `json:"jobs"`
+}
+
+// CleanupRunsResponse represents the API response for the cleanup run history.
+type CleanupRunsResponse struct {
+	Runs []CleanupRun `json:"runs"`
+}
+
+// SyncRunsResponse represents the API response for the sync run history.
+type SyncRunsResponse struct {
+	Runs []SyncRun `json:"runs"`
+}
+
+// LibraryHistoryPoint represents the size of a category and of its orphans
+// on a day, as left by the last sync of that day.
+type LibraryHistoryPoint struct {
+	Day         string `json:"day"` // YYYY-MM-DD, local time
+	Category    string `json:"category"`
+	FileCount   int64  `json:"file_count"`
+	TotalSize   int64  `json:"total_size"`
+	OrphanFiles int64  `json:"orphan_files"`
+	OrphanSize  int64  `json:"orphan_size"`
+}
+
+// LibraryHistoryResponse represents the API response for the library size history.
+type LibraryHistoryResponse struct {
+	Days   int                   `json:"days"`
+	Points []LibraryHistoryPoint `json:"points"` // Oldest day first
+}
+
+// FileTorrentsResponse represents the API response for the torrents expecting
+// a local file.
+type FileTorrentsResponse struct {
+	Path     string        `json:"path"`
+	Status   string        `json:"status"` // One of the FileStatus constants
+	Torrents []FileTorrent `json:"torrents"`
+}
+
+// MissingFilesResponse represents the API response for the missing files of a torrent.
+type MissingFilesResponse struct {
+	Files []MissingFile `json:"files"`
+}
+
+// DuplicatesResponse represents the API response for duplicate local files.
+type DuplicatesResponse struct {
+	By          string           `json:"by"` // "checksum" or "name"
+	Groups      []DuplicateGroup `json:"groups"`
+	WastedBytes int64            `json:"wasted_bytes"`
+}
+
+// CleanupRunFilesResponse represents the API response for the files of a cleanup run.
+type CleanupRunFilesResponse struct {
+	Files []CleanupRunFile `json:"files"`
+}
+
+// ProtectedPath represents a path pattern that cleanup must never touch.
+type ProtectedPath struct {
+	ID        int64     `json:"id"`
+	Pattern   string    `json:"pattern"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ProtectedPathsResponse represents the API response for protected paths.
+type ProtectedPathsResponse struct {
+	Paths []ProtectedPath `json:"paths"`
+}
+
+// IgnoredPath represents a local file, or a folder and everything below it,
+// never counted as an orphan. With Pattern, Path is a glob matched against the
+// local paths.
+type IgnoredPath struct {
+	ID          int64     `json:"id"`
+	Path        string    `json:"path"`
+	Pattern     bool      `json:"pattern"`
+	FileCount   int64     `json:"file_count"` // Local files ignored through it
+	TotalSize   int64     `json:"total_size"`
+	OrphanFiles int64     `json:"orphan_files"` // Orphans it hides from the orphan lists and stats
+	OrphanSize  int64     `json:"orphan_size"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// IgnoredPathsResponse represents the API response for the ignore list.
+type IgnoredPathsResponse struct {
+	Paths []IgnoredPath `json:"paths"`
+}
+
+// File lists of the WebUI a saved view applies to
+const (
+	ViewListOrphans = "orphans"
+	ViewListLocal   = "local"
+)
+
+// ValidViewList reports whether list is a known saved view list.
+func ValidViewList(list string) bool {
+	return list == ViewListOrphans || list == ViewListLocal
+}
+
+// SavedView is a named combination of filters of a file list of the WebUI,
+// such as the orphans of more than 10 GB in 4k.
+type SavedView struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	List      string    `json:"list"` // One of the ViewList values
+	Search    string    `json:"search"`
+	Category  string    `json:"category"`
+	MinSize   int64     `json:"min_size"` // Bytes, 0 for no limit
+	MaxSize   int64     `json:"max_size"`
+	Sort      string    `json:"sort"`
+	Order     string    `json:"order"` // "asc" or "desc"
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SavedViewsResponse represents the API response for the saved views.
+type SavedViewsResponse struct {
+	Views []SavedView `json:"views"`
+}
+
+// File lists of the WebUI whose columns each user can choose
+const (
+	ColumnListTorrentFiles = "torrent_files"
+	ColumnListLocal        = "local"
+	ColumnListOrphans      = "orphans"
+)
+
+// LayoutColumns lists the columns each column list can show. The selection
+// and action columns of the lists are always shown and are not part of it.
+var LayoutColumns = map[string][]string{
+	ColumnListTorrentFiles: {"file_name", "file_path", "torrent_name", "torrent_hash", "instance", "size"},
+	ColumnListLocal:        {"file_name", "file_path", "relative_path", "category", "size", "mod_time", "links"},
+	ColumnListOrphans:      {"file_name", "file_path", "relative_path", "category", "size", "mod_time", "orphaned_at", "links"},
+}
+
+// ColumnLayout is the columns a user shows in a file list of the WebUI, in
+// display order.
+type ColumnLayout struct {
+	Columns []string `json:"columns"`
+}
+
+// ColumnLayoutsResponse represents the API response for the column layouts of
+// the current user, by column list. Lists missing from it use their default
+// columns.
+type ColumnLayoutsResponse struct {
+	Layouts map[string][]string `json:"layouts"`
+}
+
+// OrphanTotals represents the API response for the number and the size of
+// the orphans matching filters.
+type OrphanTotals struct {
+	FileCount int64 `json:"file_count"`
+	TotalSize int64 `json:"total_size"`
+}
+
+// Language is a language the WebUI is translated to.
+type Language struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// LanguagePackResponse represents the API response for the translations of
+// the WebUI in one language.
+type LanguagePackResponse struct {
+	Language  string            `json:"language"`
+	Languages []Language        `json:"languages"`
+	Messages  map[string]string `json:"messages"`
+}
+
+// QuarantineEntry represents a file moved to the quarantine by the cleaner.
+type QuarantineEntry struct {
+	ID             int64      `json:"id"`
+	FilePath       string     `json:"file_path"`
+	DiskPath       string     `json:"disk_path"`
+	QuarantinePath string     `json:"quarantine_path"`
+	Batch          string     `json:"batch"`
+	Size           int64      `json:"size"`
+	Category       string     `json:"category"`
+	QuarantinedAt  time.Time  `json:"quarantined_at"`
+	RestoredAt     *time.Time `json:"restored_at,omitempty"`
+	PurgedAt       *time.Time `json:"purged_at,omitempty"`
+}
+
+// User roles
+const (
+	RoleViewer = "viewer" // Read-only access
+	RoleAdmin  = "admin"  // Can also sync and delete files
+)
+
+// ValidRole reports whether role is a known user role.
+func ValidRole(role string) bool {
+	return role == RoleViewer || role == RoleAdmin
+}
+
+// User represents an account allowed to use the WebUI and the API.
+type User struct {
+	ID           int64     `json:"id"`
+	Username     string    `json:"username"`
+	Role         string    `json:"role"`
+	PasswordHash string    `json:"-"`
+	APIKeyID     int64     `json:"api_key_id,omitempty"` // Set when authenticated with an API key
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Session represents a WebUI login. Only the hash of its token is stored.
+type Session struct {
+	Username  string    `json:"username"`
+	Remember  bool      `json:"remember"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CurrentUserResponse represents the API response describing the authenticated user.
+type CurrentUserResponse struct {
+	Username    string `json:"username,omitempty"`
+	Role        string `json:"role"`
+	AuthEnabled bool   `json:"auth_enabled"`
+}
+
+// API key scopes
+const (
+	ScopeRead  = "read"  // Same rights as the viewer role
+	ScopeWrite = "write" // Same rights as the admin role
+)
+
+// ValidScope reports whether scope is a known API key scope.
+func ValidScope(scope string) bool {
+	return scope == ScopeRead || scope == ScopeWrite
+}
+
+// APIKey represents a key used by scripts to call the API. The key itself is
+// only returned once, at creation, and only its hash is stored.
+type APIKey struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // First characters of the key, to recognize it
+	Scope      string     `json:"scope"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// APIKeysResponse represents the API response for API keys.
+type APIKeysResponse struct {
+	Keys []APIKey `json:"keys"`
+}
+
+// APIKeyCreatedResponse represents the API response for a new API key.
+type APIKeyCreatedResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
@@ -3,9 +3,12 @@ package qbittorrent
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -18,7 +21,23 @@ import (
 // Client wraps the qBittorrent API client with additional functionality.
 type Client struct {
 	client     *qbt.Client
+	http       *http.Client
+	transport  *http.Transport
 	maxWorkers int
+
+	// Retries of the calls listing torrents and their files, see WithRetry
+	retries    int
+	retryDelay time.Duration
+	timeout    time.Duration
+
+	// State of the sync/maindata API: the last response id, and the torrents
+	// and trackers (announce URL to hashes) rebuilt from the deltas received
+	// since the last full update
+	mu       sync.Mutex
+	loggedIn bool
+	rid      int64
+	torrents map[string]models.Torrent
+	trackers map[string][]string
 }
 
 // NewClient creates a new qBittorrent client with connection pooling.
@@ -46,7 +65,7 @@ func NewClient(host, username, password string, maxWorkers int) (*Client, error)
 	// Create HTTP client with custom transport
 	httpClient := &http.Client{
 		Transport: transport,
-		Timeout:   30 * time.Second,
+		Timeout:   DefaultTimeout,
 	}
 
 	// Create qBittorrent client with configuration
@@ -62,52 +81,212 @@ func NewClient(host, username, password string, maxWorkers int) (*Client, error)
 
 	return &Client{
 		client:     qbtClient,
+		http:       httpClient,
+		transport:  transport,
 		maxWorkers: maxWorkers,
+		retries:    DefaultRetries,
+		retryDelay: DefaultRetryDelay,
+		timeout:    DefaultTimeout,
 	}, nil
 }
 
+// Name returns the name of the torrent client.
+func (c *Client) Name() string {
+	return "qBittorrent"
+}
+
 // Login authenticates the client with the qBittorrent API.
 // Returns an error if authentication fails with the HTTP status code.
+// The session is kept afterwards, as the sync/maindata deltas are tied to
+// it: the underlying client logs in again by itself when it expires.
 func (c *Client) Login(ctx context.Context) error {
 	if c.client == nil {
 		return fmt.Errorf("qbittorrent: client not initialized")
 	}
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.loggedIn {
+		return nil
+	}
+
 	err := c.client.LoginCtx(ctx)
 	if err != nil {
 		return fmt.Errorf("qbittorrent: authentication failed: %w", err)
 	}
+	c.loggedIn = true
 
 	return nil
 }
 
 // GetTorrents retrieves the list of all torrents from qBittorrent.
 // Returns a slice of Torrent models with hash, name, size, and save path.
+// The list is maintained with the sync/maindata API: after the first call,
+// only the torrents changed since the previous call are transferred.
 func (c *Client) GetTorrents(ctx context.Context) ([]models.Torrent, error) {
 	if c.client == nil {
 		return nil, fmt.Errorf("qbittorrent: client not initialized")
 	}
 
-	// Get all torrents without any filter
-	qbtTorrents, err := c.client.GetTorrentsCtx(ctx, qbt.TorrentFilterOptions{})
+	messages, err := c.trackerMessages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var data *qbt.MainData
+	err = c.retry(ctx, func(ctx context.Context) (err error) {
+		data, err = c.client.SyncMainDataCtx(ctx, c.rid)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("qbittorrent: failed to get torrents: %w", err)
 	}
 
-	// Convert qBittorrent torrents to our model
-	torrents := make([]models.Torrent, 0, len(qbtTorrents))
-	for _, t := range qbtTorrents {
-		torrents = append(torrents, models.Torrent{
-			Hash:     t.Hash,
-			Name:     t.Name,
-			Size:     t.Size,
-			SavePath: t.SavePath,
-		})
+	// A full update is sent for the first call, and whenever qBittorrent no
+	// longer knows rid (e.g. after a new login)
+	if data.FullUpdate || c.torrents == nil {
+		c.torrents = make(map[string]models.Torrent, len(data.Torrents))
+		c.trackers = make(map[string][]string, len(data.Trackers))
 	}
+	for hash, t := range data.Torrents {
+		// A delta only holds the changed fields, the others are zero
+		cur, ok := c.torrents[hash]
+		if !ok || data.FullUpdate {
+			cur = models.Torrent{Hash: hash}
+		}
+		if t.Name != "" || data.FullUpdate {
+			cur.Name = t.Name
+		}
+		if t.Size != 0 || data.FullUpdate {
+			cur.Size = t.Size
+		}
+		if t.SavePath != "" || data.FullUpdate {
+			cur.SavePath = t.SavePath
+		}
+		// Changes when the single file or the root folder of the torrent is
+		// renamed, which its name and size do not show
+		if t.ContentPath != "" || data.FullUpdate {
+			cur.ContentPath = t.ContentPath
+		}
+		if t.State != "" || data.FullUpdate {
+			cur.State = torrentState(t.State)
+		}
+		if t.Ratio != 0 || data.FullUpdate {
+			cur.Ratio = t.Ratio
+		}
+		if t.SeedingTime != 0 || data.FullUpdate {
+			cur.SeedingTime = t.SeedingTime
+		}
+		// Only used when qBittorrent does not send the trackers list
+		if t.Tracker != "" || data.FullUpdate {
+			cur.Trackers = nil
+			if host := models.TrackerHost(t.Tracker); host != "" {
+				cur.Trackers = []string{host}
+			}
+		}
+		c.torrents[hash] = cur
+	}
+	for _, hash := range data.TorrentsRemoved {
+		delete(c.torrents, hash)
+	}
+	// A delta holds the complete list of hashes of each changed tracker
+	for url, hashes := range data.Trackers {
+		c.trackers[url] = hashes
+	}
+	c.rid = data.Rid
+
+	trackers := c.torrentTrackers()
+	torrents := make([]models.Torrent, 0, len(c.torrents))
+	for hash, t := range c.torrents {
+		if hosts, ok := trackers[hash]; ok {
+			t.Trackers = hosts
+		}
+		t.TrackerMessage = messages[hash]
+		torrents = append(torrents, t)
+	}
+	sort.Slice(torrents, func(i, j int) bool { return torrents[i].Hash < torrents[j].Hash })
 
 	return torrents, nil
 }
 
+// trackerMessages returns the tracker error message of the torrents none of
+// whose trackers works. sync/maindata does not report tracker errors, so only
+// the stalled torrents without working tracker are asked for their trackers:
+// a torrent rejected by its tracker gets no peer.
+func (c *Client) trackerMessages(ctx context.Context) (map[string]string, error) {
+	var stalled []qbt.Torrent
+	err := c.retry(ctx, func(ctx context.Context) (err error) {
+		stalled, err = c.client.GetTorrentsCtx(ctx, qbt.TorrentFilterOptions{Filter: qbt.TorrentFilterStalled})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("qbittorrent: failed to get stalled torrents: %w", err)
+	}
+
+	messages := make(map[string]string)
+	for _, t := range stalled {
+		// The tracker field holds the first working tracker
+		if t.Tracker != "" {
+			continue
+		}
+		var trackers []qbt.TorrentTracker
+		err := c.retry(ctx, func(ctx context.Context) (err error) {
+			trackers, err = c.client.GetTorrentTrackersCtx(ctx, t.Hash)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("qbittorrent: failed to get trackers of torrent %s: %w", t.Hash, err)
+		}
+		for _, tr := range trackers {
+			if tr.Status == qbt.TrackerStatusNotWorking && tr.Message != "" {
+				messages[t.Hash] = tr.Message
+				break
+			}
+		}
+	}
+	return messages, nil
+}
+
+// torrentTrackers returns the tracker hosts of each torrent, from the
+// trackers of the sync/maindata API. Must be called with c.mu held.
+func (c *Client) torrentTrackers() map[string][]string {
+	urls := make(map[string][]string)
+	for url, hashes := range c.trackers {
+		for _, hash := range hashes {
+			urls[hash] = append(urls[hash], url)
+		}
+	}
+	trackers := make(map[string][]string, len(urls))
+	for hash, announces := range urls {
+		trackers[hash] = models.TrackerHosts(announces)
+	}
+	return trackers
+}
+
+// torrentState converts a qBittorrent torrent state.
+func torrentState(state qbt.TorrentState) string {
+	switch state {
+	case qbt.TorrentStateUploading, qbt.TorrentStateStalledUp, qbt.TorrentStateForcedUp:
+		return models.TorrentStateSeeding
+	case qbt.TorrentStateDownloading, qbt.TorrentStateStalledDl, qbt.TorrentStateForcedDl,
+		qbt.TorrentStateMetaDl, qbt.TorrentStateAllocating:
+		return models.TorrentStateDownloading
+	case qbt.TorrentStatePausedUp, qbt.TorrentStatePausedDl, qbt.TorrentStateStoppedUp, qbt.TorrentStateStoppedDl:
+		return models.TorrentStatePaused
+	case qbt.TorrentStateQueuedUp, qbt.TorrentStateQueuedDl:
+		return models.TorrentStateQueued
+	case qbt.TorrentStateCheckingUp, qbt.TorrentStateCheckingDl, qbt.TorrentStateCheckingResumeData, qbt.TorrentStateMoving:
+		return models.TorrentStateChecking
+	case qbt.TorrentStateError, qbt.TorrentStateMissingFiles:
+		return models.TorrentStateErrored
+	default:
+		return models.TorrentStateUnknown
+	}
+}
+
 // GetTorrentFiles retrieves the files of a specific torrent by its hash.
 // Returns a slice of TorrentFile models with file details.
 func (c *Client) GetTorrentFiles(ctx context.Context, hash string) ([]models.TorrentFile, error) {
@@ -120,23 +299,37 @@ func (c *Client) GetTorrentFiles(ctx context.Context, hash string) ([]models.Tor
 	}
 
 	// Get files for the specified torrent using GetFilesInformationCtx
-	qbtFiles, err := c.client.GetFilesInformationCtx(ctx, hash)
-	if err != nil {
-		return nil, fmt.Errorf("qbittorrent: failed to get files for torrent %s: %w", hash, err)
-	}
-
-	// We need to get the torrent info to get the name and save path
-	torrents, err := c.client.GetTorrentsCtx(ctx, qbt.TorrentFilterOptions{
-		Hashes: []string{hash},
+	var qbtFiles *qbt.TorrentFiles
+	err := c.retry(ctx, func(ctx context.Context) (err error) {
+		qbtFiles, err = c.client.GetFilesInformationCtx(ctx, hash)
+		return err
 	})
 	if err != nil {
-		return nil, fmt.Errorf("qbittorrent: failed to get torrent info for %s: %w", hash, err)
+		return nil, fmt.Errorf("qbittorrent: failed to get files for torrent %s: %w", hash, err)
 	}
 
-	var torrentName, savePath string
-	if len(torrents) > 0 {
-		torrentName = torrents[0].Name
-		savePath = torrents[0].SavePath
+	// We need the torrent name and save path, known from GetTorrents or
+	// fetched from the torrent info
+	c.mu.Lock()
+	known, ok := c.torrents[hash]
+	c.mu.Unlock()
+
+	torrentName, savePath := known.Name, known.SavePath
+	if !ok {
+		var torrents []qbt.Torrent
+		err := c.retry(ctx, func(ctx context.Context) (err error) {
+			torrents, err = c.client.GetTorrentsCtx(ctx, qbt.TorrentFilterOptions{
+				Hashes: []string{hash},
+			})
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("qbittorrent: failed to get torrent info for %s: %w", hash, err)
+		}
+		if len(torrents) > 0 {
+			torrentName = torrents[0].Name
+			savePath = torrents[0].SavePath
+		}
 	}
 
 	// Handle nil response
@@ -147,6 +340,11 @@ func (c *Client) GetTorrentFiles(ctx context.Context, hash string) ([]models.Tor
 	// Convert qBittorrent files to our model
 	files := make([]models.TorrentFile, 0, len(*qbtFiles))
 	for _, f := range *qbtFiles {
+		// Files marked "do not download" are not expected on disk
+		if f.Priority == 0 {
+			continue
+		}
+
 		// Build the full file path: savePath + file.Name
 		// qBittorrent's file.Name is relative to savePath (includes torrent folder for multi-file torrents)
 		fullPath := filepath.Join(savePath, f.Name)
@@ -163,11 +361,159 @@ func (c *Client) GetTorrentFiles(ctx context.Context, hash string) ([]models.Tor
 	return files, nil
 }
 
+// DeleteTorrent deletes a torrent, and its files on disk when deleteFiles is set.
+func (c *Client) DeleteTorrent(ctx context.Context, hash string, deleteFiles bool) error {
+	if c.client == nil {
+		return fmt.Errorf("qbittorrent: client not initialized")
+	}
+
+	if err := c.client.DeleteTorrentsCtx(ctx, []string{hash}, deleteFiles); err != nil {
+		return fmt.Errorf("qbittorrent: failed to delete torrent %s: %w", hash, err)
+	}
+	return nil
+}
+
+// PauseTorrent pauses (stops, since qBittorrent 5) a torrent.
+func (c *Client) PauseTorrent(ctx context.Context, hash string) error {
+	if c.client == nil {
+		return fmt.Errorf("qbittorrent: client not initialized")
+	}
+
+	if err := c.client.PauseCtx(ctx, []string{hash}); err != nil {
+		return fmt.Errorf("qbittorrent: failed to pause torrent %s: %w", hash, err)
+	}
+	return nil
+}
+
+// ResumeTorrent resumes (starts, since qBittorrent 5) a torrent.
+func (c *Client) ResumeTorrent(ctx context.Context, hash string) error {
+	if c.client == nil {
+		return fmt.Errorf("qbittorrent: client not initialized")
+	}
+
+	if err := c.client.ResumeCtx(ctx, []string{hash}); err != nil {
+		return fmt.Errorf("qbittorrent: failed to resume torrent %s: %w", hash, err)
+	}
+	return nil
+}
+
+// RecheckTorrent forces a recheck of a torrent.
+func (c *Client) RecheckTorrent(ctx context.Context, hash string) error {
+	if c.client == nil {
+		return fmt.Errorf("qbittorrent: client not initialized")
+	}
+
+	if err := c.client.RecheckCtx(ctx, []string{hash}); err != nil {
+		return fmt.Errorf("qbittorrent: failed to recheck torrent %s: %w", hash, err)
+	}
+	return nil
+}
+
+// MoveTorrent moves the data of a torrent to location. qBittorrent moves
+// the files in the background.
+func (c *Client) MoveTorrent(ctx context.Context, hash, location string) error {
+	if c.client == nil {
+		return fmt.Errorf("qbittorrent: client not initialized")
+	}
+
+	if err := c.client.SetLocationCtx(ctx, []string{hash}, location); err != nil {
+		return fmt.Errorf("qbittorrent: failed to move torrent %s: %w", hash, err)
+	}
+	return nil
+}
+
+// ReannounceTorrents reannounces torrents to all their trackers.
+func (c *Client) ReannounceTorrents(ctx context.Context, hashes []string) error {
+	if c.client == nil {
+		return fmt.Errorf("qbittorrent: client not initialized")
+	}
+
+	if err := c.client.ReAnnounceTorrentsCtx(ctx, hashes); err != nil {
+		return fmt.Errorf("qbittorrent: failed to reannounce torrents: %w", err)
+	}
+	return nil
+}
+
+// SetCategory sets the category of torrents, or removes it when category is
+// empty. The category is created first when it does not exist yet.
+func (c *Client) SetCategory(ctx context.Context, hashes []string, category string) error {
+	if c.client == nil {
+		return fmt.Errorf("qbittorrent: client not initialized")
+	}
+
+	err := c.client.SetCategoryCtx(ctx, hashes, category)
+	if errors.Is(err, qbt.ErrCategoryDoesNotExist) {
+		if err := c.client.CreateCategoryCtx(ctx, category, ""); err != nil {
+			return fmt.Errorf("qbittorrent: failed to create category %s: %w", category, err)
+		}
+		err = c.client.SetCategoryCtx(ctx, hashes, category)
+	}
+	if err != nil {
+		return fmt.Errorf("qbittorrent: failed to set category %s: %w", category, err)
+	}
+	return nil
+}
+
+// AddTags adds tags to torrents. qBittorrent creates the missing tags.
+func (c *Client) AddTags(ctx context.Context, hashes []string, tags []string) error {
+	if c.client == nil {
+		return fmt.Errorf("qbittorrent: client not initialized")
+	}
+
+	if err := c.client.AddTagsCtx(ctx, hashes, strings.Join(tags, ",")); err != nil {
+		return fmt.Errorf("qbittorrent: failed to add tags: %w", err)
+	}
+	return nil
+}
+
+// RemoveTags removes tags from torrents.
+func (c *Client) RemoveTags(ctx context.Context, hashes []string, tags []string) error {
+	if c.client == nil {
+		return fmt.Errorf("qbittorrent: client not initialized")
+	}
+
+	if err := c.client.RemoveTagsCtx(ctx, hashes, strings.Join(tags, ",")); err != nil {
+		return fmt.Errorf("qbittorrent: failed to remove tags: %w", err)
+	}
+	return nil
+}
+
 // GetMaxWorkers returns the configured maximum number of workers.
 func (c *Client) GetMaxWorkers() int {
 	return c.maxWorkers
 }
 
+// StreamTorrentFiles fetches the files of torrents in parallel, using up to
+// maxWorkers workers, and calls fn with the files of each torrent. Calls to fn
+// are serialized. It stops early only if ctx is cancelled.
+func (c *Client) StreamTorrentFiles(ctx context.Context, torrents []models.Torrent, fn func(hash string, files []models.TorrentFile, err error)) error {
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(c.maxWorkers)
+
+	// Mutex to serialize the calls to fn
+	var mu sync.Mutex
+
+	for _, t := range torrents {
+		g.Go(func() error {
+			if gCtx.Err() != nil {
+				return gCtx.Err()
+			}
+
+			files, err := c.GetTorrentFiles(gCtx, t.Hash)
+			if gCtx.Err() != nil {
+				return gCtx.Err()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			fn(t.Hash, files, err)
+			return nil
+		})
+	}
+
+	return g.Wait()
+}
+
 // SyncAll synchronizes all torrents and their files in parallel.
 // Uses errgroup with worker limit for parallel processing.
 // Returns two channels:
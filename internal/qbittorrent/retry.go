@@ -0,0 +1,67 @@
+package qbittorrent
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// Default retry policy of the calls listing torrents and their files.
+const (
+	DefaultRetries    = 3
+	DefaultRetryDelay = 500 * time.Millisecond
+	DefaultTimeout    = 30 * time.Second
+)
+
+// WithRetry sets how the calls listing torrents and their files are retried:
+// up to retries more attempts, the first one after delay, then twice longer
+// each time, and each attempt is limited to timeout.
+func (c *Client) WithRetry(retries int, delay, timeout time.Duration) *Client {
+	c.retries = retries
+	c.retryDelay = delay
+	c.timeout = timeout
+	if timeout > c.http.Timeout {
+		c.http.Timeout = timeout
+	}
+	return c
+}
+
+// retry calls fn until it succeeds or the retries are exhausted. Errors such
+// as the 502 of a reverse proxy restarting qBittorrent are often transient,
+// and a failed listing leaves torrents out of the sync. The delays are
+// jittered so that the workers fetching files do not retry together.
+func (c *Client) retry(ctx context.Context, fn func(ctx context.Context) error) error {
+	delay := c.retryDelay
+	for attempt := 1; ; attempt++ {
+		err := c.attempt(ctx, fn)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		if attempt > c.retries {
+			if attempt > 1 {
+				return fmt.Errorf("%w (after %d attempts)", err, attempt)
+			}
+			return err
+		}
+
+		// Random wait between half the delay and the delay
+		wait := delay/2 + rand.N(delay/2+1)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		delay *= 2
+	}
+}
+
+// attempt calls fn with a context limited to the per-call timeout.
+func (c *Client) attempt(ctx context.Context, fn func(ctx context.Context) error) error {
+	if c.timeout <= 0 {
+		return fn(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return fn(ctx)
+}
@@ -0,0 +1,41 @@
+package qbittorrent
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// TLSConfig returns the TLS configuration of a qBittorrent server behind
+// HTTPS: the certificates of caPath (PEM) are trusted in addition to the
+// system ones, and skipVerify disables the verification of the certificate.
+// It returns nil when neither is set.
+func TLSConfig(caPath string, skipVerify bool) (*tls.Config, error) {
+	if caPath == "" && !skipVerify {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{InsecureSkipVerify: skipVerify}
+	if caPath != "" {
+		pem, err := os.ReadFile(caPath)
+		if err != nil {
+			return nil, fmt.Errorf("qbittorrent: failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("qbittorrent: no certificate found in CA bundle %s", caPath)
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
+
+// WithTLSConfig sets the TLS configuration used to connect to the server.
+func (c *Client) WithTLSConfig(cfg *tls.Config) *Client {
+	c.transport.TLSClientConfig = cfg
+	return c
+}
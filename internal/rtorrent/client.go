@@ -0,0 +1,387 @@
+// Package rtorrent provides a client for the rTorrent XML-RPC API.
+package rtorrent
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"godatacleaner/internal/models"
+)
+
+// Client is an rTorrent XML-RPC client. rTorrent only speaks SCGI, so the
+// endpoint is the one exposed by the web server in front of it (usually /RPC2,
+// as set up by ruTorrent installations).
+type Client struct {
+	url        string
+	username   string
+	password   string
+	httpClient *http.Client
+}
+
+// NewClient creates a new rTorrent client for the XML-RPC endpoint url
+// (e.g. http://seedbox/RPC2).
+func NewClient(url, username, password string) (*Client, error) {
+	if url == "" {
+		return nil, fmt.Errorf("rtorrent: url cannot be empty")
+	}
+	return &Client{
+		url:      url,
+		username: username,
+		password: password,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}, nil
+}
+
+// Name returns the name of the torrent client.
+func (c *Client) Name() string {
+	return "rTorrent"
+}
+
+// Login checks that the endpoint is reachable and the credentials are valid.
+// XML-RPC has no login call, system.client_version is used instead.
+func (c *Client) Login(ctx context.Context) error {
+	if _, err := c.call(ctx, "system.client_version"); err != nil {
+		return fmt.Errorf("rtorrent: authentication failed: %w", err)
+	}
+	return nil
+}
+
+// GetTorrents retrieves the list of all torrents from rTorrent.
+func (c *Client) GetTorrents(ctx context.Context) ([]models.Torrent, error) {
+	res, err := c.call(ctx, "d.multicall2", "", "main", "d.hash=", "d.name=", "d.selected_size_bytes=", "d.directory=",
+		"d.state=", "d.is_active=", "d.complete=", "d.hashing=", "d.ratio=", "d.timestamp.finished=", "d.message=")
+	if err != nil {
+		return nil, fmt.Errorf("rtorrent: failed to get torrents: %w", err)
+	}
+
+	rows, _ := res.([]interface{})
+	torrents := make([]models.Torrent, 0, len(rows))
+	for _, row := range rows {
+		fields, ok := row.([]interface{})
+		if !ok || len(fields) < 11 {
+			return nil, fmt.Errorf("rtorrent: unexpected torrent row %v", row)
+		}
+
+		t := models.Torrent{
+			Hash:     toString(fields[0]),
+			Name:     toString(fields[1]),
+			Size:     toInt(fields[2]), // Wanted files only, as in qBittorrent
+			SavePath: toString(fields[3]),
+			State:    torrentState(toInt(fields[4]), toInt(fields[5]), toInt(fields[6]), toInt(fields[7])),
+			Ratio:    float64(toInt(fields[8])) / 1000, // Per mille
+		}
+		// rTorrent does not track the seeding time: the time elapsed since
+		// the download finished is used instead
+		if finished := toInt(fields[9]); t.State == models.TorrentStateSeeding && finished > 0 {
+			t.SeedingTime = max(time.Now().Unix()-finished, 0)
+		}
+		// d.message also holds storage errors, e.g. "Storage error: ..."
+		if msg := toString(fields[10]); strings.HasPrefix(msg, "Tracker: ") {
+			t.TrackerMessage = msg
+		}
+		torrents = append(torrents, t)
+	}
+
+	if err := c.fillTrackers(ctx, torrents); err != nil {
+		return nil, err
+	}
+	return torrents, nil
+}
+
+// fillTrackers sets the trackers of torrents, with a single system.multicall
+// of t.multicall for all of them.
+func (c *Client) fillTrackers(ctx context.Context, torrents []models.Torrent) error {
+	if len(torrents) == 0 {
+		return nil
+	}
+
+	calls := make(multicall, 0, len(torrents))
+	for _, t := range torrents {
+		calls = append(calls, []string{"t.multicall", t.Hash, "", "t.url="})
+	}
+	res, err := c.call(ctx, "system.multicall", calls)
+	if err != nil {
+		return fmt.Errorf("rtorrent: failed to get trackers: %w", err)
+	}
+
+	// Each result is wrapped in an array and holds one row per tracker.
+	// A torrent removed in the meantime gets a fault struct and no tracker.
+	results, _ := res.([]interface{})
+	for i := range torrents {
+		if i >= len(results) {
+			break
+		}
+		wrapped, _ := results[i].([]interface{})
+		if len(wrapped) == 0 {
+			continue
+		}
+		rows, _ := wrapped[0].([]interface{})
+		announces := make([]string, 0, len(rows))
+		for _, row := range rows {
+			announces = append(announces, firstString(row))
+		}
+		torrents[i].Trackers = models.TrackerHosts(announces)
+	}
+	return nil
+}
+
+// torrentState converts the rTorrent state flags of a torrent.
+func torrentState(started, active, complete, hashing int64) string {
+	switch {
+	case hashing != 0:
+		return models.TorrentStateChecking
+	case started == 0 || active == 0:
+		return models.TorrentStatePaused
+	case complete != 0:
+		return models.TorrentStateSeeding
+	default:
+		return models.TorrentStateDownloading
+	}
+}
+
+// GetTorrentFiles retrieves the files of a specific torrent by its hash.
+func (c *Client) GetTorrentFiles(ctx context.Context, hash string) ([]models.TorrentFile, error) {
+	if hash == "" {
+		return nil, fmt.Errorf("rtorrent: torrent hash cannot be empty")
+	}
+
+	// d.directory is the torrent folder of a multi-file torrent, and the
+	// folder containing the file of a single-file one: file paths are
+	// relative to it in both cases
+	info, err := c.call(ctx, "system.multicall", multicall{
+		{"d.name", hash},
+		{"d.directory", hash},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("rtorrent: failed to get torrent %s: %w", hash, err)
+	}
+	results, _ := info.([]interface{})
+	if len(results) < 2 {
+		return nil, fmt.Errorf("rtorrent: unexpected response for torrent %s", hash)
+	}
+	name, dir := firstString(results[0]), firstString(results[1])
+
+	res, err := c.call(ctx, "f.multicall", hash, "", "f.path=", "f.size_bytes=", "f.priority=")
+	if err != nil {
+		return nil, fmt.Errorf("rtorrent: failed to get files for torrent %s: %w", hash, err)
+	}
+
+	rows, _ := res.([]interface{})
+	files := make([]models.TorrentFile, 0, len(rows))
+	for _, row := range rows {
+		fields, ok := row.([]interface{})
+		if !ok || len(fields) < 3 {
+			return nil, fmt.Errorf("rtorrent: unexpected file row %v", row)
+		}
+		// Files with the "off" priority are not expected on disk
+		if toInt(fields[2]) == 0 {
+			continue
+		}
+		path := toString(fields[0])
+		files = append(files, models.TorrentFile{
+			TorrentHash: hash,
+			TorrentName: name,
+			FileName:    filepath.Base(path),
+			FilePath:    filepath.Join(dir, path),
+			Size:        toInt(fields[1]),
+		})
+	}
+	return files, nil
+}
+
+// PauseTorrent stops a torrent.
+func (c *Client) PauseTorrent(ctx context.Context, hash string) error {
+	if _, err := c.call(ctx, "d.stop", hash); err != nil {
+		return fmt.Errorf("rtorrent: failed to pause torrent %s: %w", hash, err)
+	}
+	return nil
+}
+
+// ResumeTorrent starts a torrent. d.resume is also called, for the torrents
+// paused (rather than stopped) from ruTorrent.
+func (c *Client) ResumeTorrent(ctx context.Context, hash string) error {
+	if _, err := c.call(ctx, "system.multicall", multicall{
+		{"d.start", hash},
+		{"d.resume", hash},
+	}); err != nil {
+		return fmt.Errorf("rtorrent: failed to resume torrent %s: %w", hash, err)
+	}
+	return nil
+}
+
+// RecheckTorrent checks the hash of the local data of a torrent.
+func (c *Client) RecheckTorrent(ctx context.Context, hash string) error {
+	if _, err := c.call(ctx, "d.check_hash", hash); err != nil {
+		return fmt.Errorf("rtorrent: failed to recheck torrent %s: %w", hash, err)
+	}
+	return nil
+}
+
+// ReannounceTorrents announces torrents to their trackers, with a single
+// system.multicall of d.tracker_announce.
+func (c *Client) ReannounceTorrents(ctx context.Context, hashes []string) error {
+	calls := make(multicall, 0, len(hashes))
+	for _, hash := range hashes {
+		calls = append(calls, []string{"d.tracker_announce", hash})
+	}
+	if _, err := c.call(ctx, "system.multicall", calls); err != nil {
+		return fmt.Errorf("rtorrent: failed to reannounce torrents: %w", err)
+	}
+	return nil
+}
+
+// call performs an XML-RPC call and returns its decoded result.
+func (c *Client) call(ctx context.Context, method string, params ...interface{}) (interface{}, error) {
+	var body bytes.Buffer
+	body.WriteString(xml.Header)
+	body.WriteString("<methodCall><methodName>")
+	xml.EscapeText(&body, []byte(method))
+	body.WriteString("</methodName><params>")
+	for _, p := range params {
+		body.WriteString("<param>")
+		writeValue(&body, p)
+		body.WriteString("</param>")
+	}
+	body.WriteString("</params></methodCall>")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "text/xml")
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var res struct {
+		Params []value `xml:"params>param>value"`
+		Fault  *value  `xml:"fault>value"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	if res.Fault != nil {
+		fault, _ := res.Fault.decode().(map[string]interface{})
+		return nil, fmt.Errorf("rpc fault %v: %v", fault["faultCode"], fault["faultString"])
+	}
+	if len(res.Params) == 0 {
+		return nil, nil
+	}
+	return res.Params[0].decode(), nil
+}
+
+// multicall is the parameter of system.multicall: a list of method names
+// followed by their string parameters.
+type multicall [][]string
+
+// writeValue encodes a parameter. Only the types used by the client are supported.
+func writeValue(buf *bytes.Buffer, v interface{}) {
+	buf.WriteString("<value>")
+	switch v := v.(type) {
+	case string:
+		buf.WriteString("<string>")
+		xml.EscapeText(buf, []byte(v))
+		buf.WriteString("</string>")
+	case multicall:
+		buf.WriteString("<array><data>")
+		for _, call := range v {
+			buf.WriteString("<value><struct><member><name>methodName</name>")
+			writeValue(buf, call[0])
+			buf.WriteString("</member><member><name>params</name><value><array><data>")
+			for _, p := range call[1:] {
+				writeValue(buf, p)
+			}
+			buf.WriteString("</data></array></value></member></struct></value>")
+		}
+		buf.WriteString("</data></array>")
+	}
+	buf.WriteString("</value>")
+}
+
+// value is an XML-RPC value. A value without type element is a string.
+type value struct {
+	String *string `xml:"string"`
+	Int    *string `xml:"int"`
+	I4     *string `xml:"i4"`
+	I8     *string `xml:"i8"`
+	Array  *struct {
+		Values []value `xml:"data>value"`
+	} `xml:"array"`
+	Struct *struct {
+		Members []struct {
+			Name  string `xml:"name"`
+			Value value  `xml:"value"`
+		} `xml:"member"`
+	} `xml:"struct"`
+	Text string `xml:",chardata"`
+}
+
+// decode converts a value to a string, an int64, a []interface{} or a map[string]interface{}.
+func (v value) decode() interface{} {
+	switch {
+	case v.String != nil:
+		return *v.String
+	case v.Int != nil, v.I4 != nil, v.I8 != nil:
+		s := v.Int
+		if s == nil {
+			s = v.I4
+		}
+		if s == nil {
+			s = v.I8
+		}
+		n, _ := strconv.ParseInt(*s, 10, 64)
+		return n
+	case v.Array != nil:
+		items := make([]interface{}, 0, len(v.Array.Values))
+		for _, item := range v.Array.Values {
+			items = append(items, item.decode())
+		}
+		return items
+	case v.Struct != nil:
+		members := make(map[string]interface{}, len(v.Struct.Members))
+		for _, m := range v.Struct.Members {
+			members[m.Name] = m.Value.decode()
+		}
+		return members
+	default:
+		return v.Text
+	}
+}
+
+func toString(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+func toInt(v interface{}) int64 {
+	n, _ := v.(int64)
+	return n
+}
+
+// firstString returns the single result of a system.multicall entry,
+// which is wrapped in an array.
+func firstString(v interface{}) string {
+	if items, ok := v.([]interface{}); ok && len(items) > 0 {
+		return toString(items[0])
+	}
+	return ""
+}
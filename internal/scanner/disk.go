@@ -0,0 +1,51 @@
+package scanner
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"godatacleaner/internal/models"
+)
+
+// ErrDiskUsageUnsupported is returned by DiskUsage on the platforms where the
+// space of a filesystem cannot be read.
+var ErrDiskUsageUnsupported = errors.New("scanner: disk usage not supported on this platform")
+
+// DiskUsage returns the size, used and free space of the filesystem holding
+// path, with its mount point.
+func DiskUsage(path string) (models.DiskUsage, error) {
+	total, used, free, err := diskSpace(path)
+	if err != nil {
+		return models.DiskUsage{}, err
+	}
+	return models.DiskUsage{MountPoint: mountPoint(path), Total: total, Used: used, Free: free}, nil
+}
+
+// mountPoint returns the topmost directory above path on the same device, or
+// path itself when the device is unknown.
+func mountPoint(path string) string {
+	path = filepath.Clean(path)
+	info, err := os.Stat(path)
+	if err != nil {
+		return path
+	}
+	device, _, _ := fileIdentity(info)
+	if device == 0 {
+		return path
+	}
+	for {
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		info, err := os.Stat(parent)
+		if err != nil {
+			return path
+		}
+		if d, _, _ := fileIdentity(info); d != device {
+			return path
+		}
+		path = parent
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !(linux || darwin || freebsd)
+
+package scanner
+
+// diskSpace is not available on this platform.
+func diskSpace(path string) (total, used, free int64, err error) {
+	return 0, 0, 0, ErrDiskUsageUnsupported
+}
@@ -0,0 +1,16 @@
+//go:build linux || darwin || freebsd
+
+package scanner
+
+import "syscall"
+
+// diskSpace returns the size of the filesystem holding path, its used bytes,
+// and the bytes available to unprivileged users.
+func diskSpace(path string) (total, used, free int64, err error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, 0, 0, err
+	}
+	size := int64(st.Bsize)
+	return int64(st.Blocks) * size, int64(st.Blocks-st.Bfree) * size, int64(st.Bavail) * size, nil
+}
@@ -0,0 +1,67 @@
+package scanner
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"godatacleaner/internal/models"
+)
+
+// extraDirs gives the pseudo-category of the files under a directory of one
+// of these names (lower case), as used by releases and media servers.
+var extraDirs = map[string]string{
+	"sample":            models.CategorySample,
+	"samples":           models.CategorySample,
+	"proof":             models.CategoryProof,
+	"proofs":            models.CategoryProof,
+	"extras":            models.CategoryExtras,
+	"featurettes":       models.CategoryExtras,
+	"behind the scenes": models.CategoryExtras,
+	"deleted scenes":    models.CategoryExtras,
+	"interviews":        models.CategoryExtras,
+	"trailers":          models.CategoryExtras,
+	"bonus":             models.CategoryExtras,
+	"screens":           models.CategoryJunk,
+	"screenshots":       models.CategoryJunk,
+}
+
+// extraNames gives the pseudo-category of the files whose name matches a
+// pattern, tried in order.
+var extraNames = []Category{
+	{Name: models.CategorySample, Pattern: regexp.MustCompile(`(?i)(^sample[._ -]|[._ -]sample\.[^.]+$)`)},
+	{Name: models.CategoryProof, Pattern: regexp.MustCompile(`(?i)(^proof[._ -]|[._ -]proof\.[^.]+$)`)},
+	{Name: models.CategoryJunk, Pattern: regexp.MustCompile(`(?i)^(rarbg(\.com)?\.txt|rarbg_do_not_mirror\.exe|torrent downloaded from .*\.txt|www\.yts.*\.(jpg|txt)|.*\.url)$`)},
+}
+
+// WithExtras makes the scan give the samples, proofs, extras and junk files
+// (models.ExtraCategories) their own pseudo-category instead of the one of
+// the category rules. They are found by the name of the file or of a
+// directory under the base path.
+func (s *Scanner) WithExtras() *Scanner {
+	s.extras = s.basePath
+	return s
+}
+
+// extraCategory returns the pseudo-category of the file at path, or "" when
+// it is not an extra. Only the directories under root are considered, so
+// that a library stored under a directory named "extras" is not one.
+func extraCategory(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	elems := strings.Split(filepath.ToSlash(rel), "/")
+	for _, dir := range elems[:len(elems)-1] {
+		if category, ok := extraDirs[strings.ToLower(dir)]; ok {
+			return category
+		}
+	}
+	name := elems[len(elems)-1]
+	for _, c := range extraNames {
+		if c.Pattern.MatchString(name) {
+			return c.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,43 @@
+package scanner
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// filter selects the files recorded by a scan.
+type filter struct {
+	minSize    int64
+	extensions map[string]bool // Lower case, with the leading dot; nil to keep all
+}
+
+// WithFilter makes the scan skip the files smaller than minSize bytes and,
+// when extensions is not empty, the files without one of these extensions
+// (case-insensitive, e.g. ".mkv" or "mkv"). Skipped files are neither listed
+// nor orphans, which keeps the thousands of small metadata files of a library
+// out of the database.
+func (s *Scanner) WithFilter(minSize int64, extensions []string) *Scanner {
+	s.filter = filter{minSize: minSize}
+	for _, ext := range extensions {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		if s.filter.extensions == nil {
+			s.filter.extensions = make(map[string]bool)
+		}
+		s.filter.extensions[ext] = true
+	}
+	return s
+}
+
+// keep reports whether a file of the given name and size is recorded.
+func (f filter) keep(name string, size int64) bool {
+	if size < f.minSize {
+		return false
+	}
+	return f.extensions == nil || f.extensions[strings.ToLower(filepath.Ext(name))]
+}
@@ -0,0 +1,96 @@
+package scanner
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+
+	"godatacleaner/internal/models"
+)
+
+// mtimeGrace is the age below which the modification time of a directory is
+// not trusted: on filesystems with a coarse timestamp resolution, a change
+// made within the same tick would leave it unchanged.
+const mtimeGrace = 2 * time.Second
+
+// WithPrevious makes the scan incremental: a directory whose modification
+// time is the one recorded by the previous scan is not read again, its files
+// and subdirectories are taken from dirs and files instead. files holds the
+// files of each directory of dirs, by directory path. The directories read
+// are then returned by Dirs, for the next scan.
+//
+// The modification time of a directory only changes when entries are added,
+// removed or renamed in it, so a file rewritten in place keeps its previous
+// size and modification time until a scan without WithPrevious. Scans
+// following symlinks always read every directory.
+func (s *Scanner) WithPrevious(dirs []models.ScanDir, files map[string][]models.LocalFile) *Scanner {
+	prev := &previous{
+		mtimes:  make(map[string]time.Time, len(dirs)),
+		subdirs: make(map[string][]string),
+		files:   files,
+	}
+	for _, d := range dirs {
+		prev.mtimes[d.Path] = d.ModTime
+		if d.Path != s.basePath {
+			parent := filepath.Dir(d.Path)
+			prev.subdirs[parent] = append(prev.subdirs[parent], d.Path)
+		}
+	}
+	s.prev = prev
+	return s
+}
+
+// Dirs returns the directories read by the last incremental scan, once its
+// file channel is closed. It returns nil after a scan that failed, which
+// left directories unread.
+func (s *Scanner) Dirs() []models.ScanDir {
+	if s.record == nil {
+		return nil
+	}
+	return s.record.dirs
+}
+
+// Unchanged returns the number of directories the last incremental scan took
+// from the previous scan.
+func (s *Scanner) Unchanged() int {
+	if s.record == nil {
+		return 0
+	}
+	return s.record.unchanged
+}
+
+// previous is the state of the previous scan.
+type previous struct {
+	mtimes  map[string]time.Time
+	subdirs map[string][]string
+	files   map[string][]models.LocalFile
+}
+
+// unchanged reports whether the directory path, modified at mtime, has not
+// changed since the previous scan.
+func (p *previous) unchanged(path string, mtime time.Time) bool {
+	prev, ok := p.mtimes[path]
+	return ok && !prev.IsZero() && prev.Equal(mtime)
+}
+
+// dirRecord collects the directories read by a scan.
+type dirRecord struct {
+	mu        sync.Mutex
+	dirs      []models.ScanDir
+	unchanged int
+}
+
+// add records a directory, modified at mtime. The modification time of a
+// recently modified directory is dropped, so the next scan reads it again.
+func (r *dirRecord) add(path string, mtime time.Time, unchanged bool) {
+	if time.Since(mtime) < mtimeGrace {
+		mtime = time.Time{}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.dirs = append(r.dirs, models.ScanDir{Path: path, ModTime: mtime})
+	if unchanged {
+		r.unchanged++
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package scanner
+
+import "io/fs"
+
+// fileIdentity is not available on this platform: hard links are not detected.
+func fileIdentity(info fs.FileInfo) (device, inode, links int64) {
+	return 0, 0, 0
+}
+
+// AllocatedSize is not available on this platform: it returns the size of the file.
+func AllocatedSize(info fs.FileInfo) int64 {
+	return info.Size()
+}
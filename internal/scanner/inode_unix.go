@@ -0,0 +1,29 @@
+//go:build unix
+
+package scanner
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// fileIdentity returns the device and inode of a file, shared by its hard
+// links, and its number of links.
+func fileIdentity(info fs.FileInfo) (device, inode, links int64) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, 0
+	}
+	return int64(st.Dev), int64(st.Ino), int64(st.Nlink)
+}
+
+// AllocatedSize returns the bytes allocated on disk to a file, below its size
+// when it is sparse, and above when its last block is partly used.
+func AllocatedSize(info fs.FileInfo) int64 {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return info.Size()
+	}
+	// st_blocks counts 512-byte units whatever the block size of the filesystem
+	return int64(st.Blocks) * 512
+}
@@ -0,0 +1,116 @@
+package scanner
+
+import (
+	"context"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"godatacleaner/internal/models"
+)
+
+// Progress is a snapshot of a running scan.
+type Progress struct {
+	Files          int64   `json:"files"` // Files recorded so far
+	Bytes          int64   `json:"bytes"`
+	Dirs           int64   `json:"dirs"`        // Directories read or taken from the previous scan
+	CurrentDir     string  `json:"current_dir"` // Directory read last
+	Rate           float64 `json:"rate"`        // Files recorded per second
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	ETASeconds     float64 `json:"eta_seconds"` // Estimated time left, 0 when unknown
+	Done           bool    `json:"done"`
+}
+
+// WithProgress makes the scan send its progress on ch every interval, then a
+// last time with Done set once it is finished, and close ch: the scanner is
+// then used for a single scan. A periodic update is dropped while ch is not
+// ready, so that a slow reader never slows down the scan. expected is the
+// number of files the scan should find, such as those of the previous scan,
+// used to estimate the time left; 0 when unknown.
+func (s *Scanner) WithProgress(ch chan<- Progress, interval time.Duration, expected int) *Scanner {
+	s.progress = &progress{ch: ch, interval: interval, expected: int64(expected)}
+	return s
+}
+
+// progress counts the files of a scan for WithProgress.
+type progress struct {
+	ch       chan<- Progress
+	interval time.Duration
+	expected int64
+
+	start time.Time
+	files atomic.Int64
+	bytes atomic.Int64
+	dirs  atomic.Int64
+	dir   atomic.Pointer[string]
+}
+
+// enterDir records that the directory path is being read.
+func (p *progress) enterDir(path string) {
+	if p == nil {
+		return
+	}
+	p.dirs.Add(1)
+	p.dir.Store(&path)
+}
+
+// snapshot returns the progress so far.
+func (p *progress) snapshot() Progress {
+	elapsed := time.Since(p.start).Seconds()
+	snap := Progress{
+		Files:          p.files.Load(),
+		Bytes:          p.bytes.Load(),
+		Dirs:           p.dirs.Load(),
+		ElapsedSeconds: elapsed,
+	}
+	if dir := p.dir.Load(); dir != nil {
+		snap.CurrentDir = *dir
+	}
+	if elapsed > 0 {
+		snap.Rate = float64(snap.Files) / elapsed
+	}
+	if snap.Rate > 0 && p.expected > snap.Files {
+		snap.ETASeconds = float64(p.expected-snap.Files) / snap.Rate
+	}
+	return snap
+}
+
+// run sends the progress every interval until stop is closed, then the final
+// progress, and closes the channel.
+func (p *progress) run(stop <-chan struct{}) {
+	defer close(p.ch)
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			final := p.snapshot()
+			final.Done = true
+			p.ch <- final
+			return
+		case <-ticker.C:
+			select {
+			case p.ch <- p.snapshot():
+			default:
+			}
+		}
+	}
+}
+
+// send sends a file of the scan, counting it for WithProgress.
+func (s *Scanner) send(ctx context.Context, files chan<- models.LocalFile, f models.LocalFile) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case files <- f:
+	}
+	if p := s.progress; p != nil {
+		p.files.Add(1)
+		p.bytes.Add(f.Size)
+		if s.lister != nil {
+			dir := filepath.Dir(f.FilePath)
+			p.dir.Store(&dir)
+		}
+	}
+	return nil
+}
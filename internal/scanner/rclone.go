@@ -0,0 +1,107 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Rclone lists the files of an rclone remote, such as an S3 bucket, with the
+// rclone command. The remote is either configured in rclone ("cold:media") or
+// given by a connection string (":s3,provider=Minio,endpoint=...:bucket").
+type Rclone struct {
+	binary string
+	remote string
+}
+
+// NewRclone creates a lister for the given remote, using the rclone binary
+// found in PATH. rclone reads its configuration file and credentials as usual,
+// including from the RCLONE_* environment variables.
+func NewRclone(remote string) *Rclone {
+	return &Rclone{binary: "rclone", remote: remote}
+}
+
+// rcloneEntry is an entry of the rclone lsjson output.
+type rcloneEntry struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+}
+
+// List implements Lister. The listing is decoded as rclone writes it, as a
+// bucket may hold millions of objects.
+func (r *Rclone) List(ctx context.Context, fn func(RemoteFile) error) error {
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, r.binary, "lsjson", "--recursive", "--files-only", "--no-mimetype", r.remote)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("scanner: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("scanner: rclone: %w", err)
+	}
+
+	var fnErr error
+	decodeErr := decodeRcloneListing(stdout, func(f RemoteFile) error {
+		fnErr = fn(f)
+		return fnErr
+	})
+	if decodeErr != nil {
+		// Stops rclone, which would otherwise block writing the rest
+		cancel()
+	}
+	waitErr := cmd.Wait()
+
+	switch {
+	case fnErr != nil:
+		return fnErr
+	case parent.Err() != nil:
+		return parent.Err()
+	case waitErr != nil:
+		msg := strings.TrimSpace(stderr.String())
+		if i := strings.LastIndexByte(msg, '\n'); i >= 0 {
+			msg = msg[i+1:]
+		}
+		return fmt.Errorf("scanner: rclone lsjson %s: %w: %s", r.remote, waitErr, msg)
+	case decodeErr != nil:
+		return fmt.Errorf("scanner: rclone lsjson %s: invalid output: %w", r.remote, decodeErr)
+	}
+	return nil
+}
+
+// decodeRcloneListing calls fn for each file of an rclone lsjson output, a
+// JSON array of entries.
+func decodeRcloneListing(r io.Reader, fn func(RemoteFile) error) error {
+	dec := json.NewDecoder(r)
+	if tok, err := dec.Token(); err != nil {
+		return err
+	} else if tok != json.Delim('[') {
+		return fmt.Errorf("expected an array, got %v", tok)
+	}
+	for dec.More() {
+		var e rcloneEntry
+		if err := dec.Decode(&e); err != nil {
+			return err
+		}
+		if e.IsDir {
+			continue
+		}
+		// Objects of unknown size are listed with -1
+		if err := fn(RemoteFile{Path: e.Path, Size: max(e.Size, 0), ModTime: e.ModTime}); err != nil {
+			return err
+		}
+	}
+	_, err := dec.Token()
+	return err
+}
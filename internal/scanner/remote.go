@@ -0,0 +1,76 @@
+package scanner
+
+import (
+	"context"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"godatacleaner/internal/models"
+)
+
+// RemoteFile is a file listed by a Lister.
+type RemoteFile struct {
+	Path    string // Relative to the listed root, with forward slashes
+	Size    int64
+	ModTime time.Time
+}
+
+// Lister lists the files of a source other than the local filesystem, such
+// as a cloud storage bucket.
+type Lister interface {
+	// List calls fn for each file of the source, and stops at the first
+	// error returned by fn.
+	List(ctx context.Context, fn func(RemoteFile) error) error
+}
+
+// WithLister makes the scan list its files with l instead of reading the
+// directories under the base path. The files are recorded under the base path,
+// as if the source was mounted there, so that they are matched against the
+// torrent files like local ones. Symlinks and incremental scans do not apply
+// to a lister, and files have no inode.
+func (s *Scanner) WithLister(l Lister) *Scanner {
+	s.lister = l
+	return s
+}
+
+// scanLister sends the files listed by the lister.
+func (s *Scanner) scanLister(ctx context.Context, files chan<- models.LocalFile) error {
+	return s.lister.List(ctx, func(rf RemoteFile) error {
+		rel := path.Clean(strings.TrimPrefix(rf.Path, "/"))
+		if rel == "." || strings.HasPrefix(rel, "../") || hasHiddenElem(rel) {
+			return nil
+		}
+		name := path.Base(rel)
+		if !s.filter.keep(name, rf.Size) {
+			return nil
+		}
+
+		p := filepath.Join(s.basePath, filepath.FromSlash(rel))
+		if !s.rules.keepFile(p) {
+			return nil
+		}
+		// The space used by a remote is not known, only its logical size
+		localFile := models.LocalFile{
+			FilePath:  p,
+			FileName:  name,
+			Size:      rf.Size,
+			Allocated: rf.Size,
+			Category:  s.categorize(p),
+			ModTime:   rf.ModTime,
+		}
+		return s.send(ctx, files, localFile)
+	})
+}
+
+// hasHiddenElem reports whether an element of the slash separated path is
+// hidden, as the scan of a directory skips hidden files and directories.
+func hasHiddenElem(p string) bool {
+	for elem := range strings.SplitSeq(p, "/") {
+		if isHidden(elem) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrRootUnavailable is returned by CheckRoot when the scanned directory does
+// not look like the library, typically a network share that is not mounted.
+var ErrRootUnavailable = errors.New("scanner: scanned directory unavailable")
+
+// CheckRoot verifies the scanned directory before its scan replaces the files
+// recorded by the previous one. It must be a directory, contain canary when
+// set (a path relative to it), be on another filesystem than / when
+// requireMount is set, and contain an entry unless allowEmpty is set.
+func (s *Scanner) CheckRoot(canary string, requireMount, allowEmpty bool) error {
+	info, err := os.Stat(s.basePath)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrRootUnavailable, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%w: %s is not a directory", ErrRootUnavailable, s.basePath)
+	}
+
+	if canary != "" {
+		if _, err := os.Stat(filepath.Join(s.basePath, canary)); err != nil {
+			return fmt.Errorf("%w: canary file: %w", ErrRootUnavailable, err)
+		}
+	}
+
+	if requireMount {
+		// The mount point of an unmounted share belongs to the root
+		// filesystem. The device is unknown (0) on Windows
+		rootInfo, err := os.Stat("/")
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrRootUnavailable, err)
+		}
+		device, _, _ := fileIdentity(info)
+		rootDevice, _, _ := fileIdentity(rootInfo)
+		if device != 0 && device == rootDevice {
+			return fmt.Errorf("%w: %s is on the root filesystem", ErrRootUnavailable, s.basePath)
+		}
+	}
+
+	if !allowEmpty {
+		f, err := os.Open(s.basePath)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrRootUnavailable, err)
+		}
+		defer f.Close()
+		if _, err := f.Readdirnames(1); errors.Is(err, io.EOF) {
+			return fmt.Errorf("%w: %s is empty", ErrRootUnavailable, s.basePath)
+		} else if err != nil {
+			return fmt.Errorf("%w: %w", ErrRootUnavailable, err)
+		}
+	}
+	return nil
+}
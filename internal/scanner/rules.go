@@ -0,0 +1,107 @@
+package scanner
+
+import (
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// TreeRule limits the scan of the directory Path and its subdirectories, such
+// as a downloads directory holding large trees that are not worth recording.
+type TreeRule struct {
+	Path string
+	// Levels of directories read under Path: 1 reads Path only, 2 also its
+	// subdirectories. 0 reads every level.
+	MaxDepth int
+	// Patterns (path.Match syntax) of the paths relative to Path, with forward
+	// slashes, that are scanned. A pattern matching a directory includes its
+	// whole subtree. Empty includes everything.
+	Include []string
+}
+
+// treeRules holds the tree rules, the longest path first.
+type treeRules []TreeRule
+
+// WithTreeRules limits the scan of some subtrees. The rule of the deepest
+// Path applies to a path, overriding the rules of the directories above it.
+func (s *Scanner) WithTreeRules(rules []TreeRule) *Scanner {
+	s.rules = make(treeRules, len(rules))
+	for i, r := range rules {
+		r.Path = filepath.Clean(r.Path)
+		s.rules[i] = r
+	}
+	sort.SliceStable(s.rules, func(i, j int) bool {
+		return len(s.rules[i].Path) > len(s.rules[j].Path)
+	})
+	return s
+}
+
+// match returns the rule applying to p, and the elements of p relative to the
+// path of the rule, or nil when no rule applies.
+func (rs treeRules) match(p string) (*TreeRule, []string) {
+	for i := range rs {
+		rel, err := filepath.Rel(rs[i].Path, p)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == ".." || strings.HasPrefix(rel, "../") {
+			continue
+		}
+		if rel == "." {
+			return &rs[i], nil
+		}
+		return &rs[i], strings.Split(rel, "/")
+	}
+	return nil, nil
+}
+
+// readDir reports whether the directory dir is read.
+func (rs treeRules) readDir(dir string) bool {
+	r, elems := rs.match(dir)
+	if r == nil || len(elems) == 0 {
+		return true
+	}
+	if r.MaxDepth > 0 && len(elems) >= r.MaxDepth {
+		return false
+	}
+	return len(r.Include) == 0 || includes(r.Include, elems, true)
+}
+
+// keepFile reports whether the file at p is recorded. The depth of its
+// directory is checked too, for the files of a Lister.
+func (rs treeRules) keepFile(p string) bool {
+	r, elems := rs.match(p)
+	if r == nil || len(elems) == 0 {
+		return true
+	}
+	if r.MaxDepth > 0 && len(elems)-1 >= r.MaxDepth {
+		return false
+	}
+	return len(r.Include) == 0 || includes(r.Include, elems, false)
+}
+
+// includes reports whether a pattern matches the path of the given elements
+// or a directory above it. With dir set, the path is a directory also
+// included when a pattern may match a path under it.
+func includes(patterns []string, elems []string, dir bool) bool {
+	for _, pattern := range patterns {
+		parts := strings.Split(strings.Trim(pattern, "/"), "/")
+		if len(parts) > len(elems) && !dir {
+			continue
+		}
+		n := min(len(parts), len(elems))
+		matched := true
+		for i := range n {
+			if ok, _ := path.Match(parts[i], elems[i]); !ok {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
@@ -3,31 +3,77 @@ package scanner
 
 import (
 	"context"
-	"io/fs"
+	"os"
 	"path/filepath"
-	"strings"
+	"regexp"
+	"sync"
+	"time"
 
 	"godatacleaner/internal/models"
 )
 
+// Category gives the category Name to the files whose path, with forward
+// slashes, matches Pattern.
+type Category struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// DefaultCategories categorizes files by their "4k", "movies" or "shows"
+// library directory.
+var DefaultCategories = []Category{
+	{Name: "4k", Pattern: regexp.MustCompile("/4k/")},
+	{Name: "movies", Pattern: regexp.MustCompile("/movies/")},
+	{Name: "shows", Pattern: regexp.MustCompile("/shows/")},
+}
+
 // Scanner scans local directories for files.
 type Scanner struct {
 	basePath   string
-	categories []string // ["4k", "movies", "shows"]
+	categories []Category
+	workers    int
+	follow     bool      // Follow symlinks, see WithFollowSymlinks
+	prev       *previous // Previous scan, see WithPrevious
+	filter     filter    // Files recorded, see WithFilter
+	lister     Lister    // Source listed instead of the base path, see WithLister
+	extras     string    // Root under which extras are classified, empty when disabled, see WithExtras
+	rules      treeRules // Subtrees scanned partially, see WithTreeRules
+	progress   *progress // Progress reporting, see WithProgress
+
+	record *dirRecord // Directories read by the last incremental scan
 }
 
 // NewScanner creates a new scanner for the given base path.
 func NewScanner(basePath string) *Scanner {
 	return &Scanner{
 		basePath:   basePath,
-		categories: []string{"4k", "movies", "shows"},
+		categories: DefaultCategories,
+		workers:    1,
 	}
 }
 
+// WithCategories sets the rules categorizing the files, tried in order.
+func (s *Scanner) WithCategories(categories []Category) *Scanner {
+	s.categories = categories
+	return s
+}
+
+// WithWorkers sets the number of directories read in parallel. Network and
+// union filesystems (NFS, mergerfs) answer each read slowly, so reading
+// several directories at once shortens the scan.
+func (s *Scanner) WithWorkers(n int) *Scanner {
+	if n > 0 {
+		s.workers = n
+	}
+	return s
+}
+
 // Scan recursively scans the directory and returns files via channel.
-// It uses filepath.WalkDir for efficient recursive traversal.
+// Directories are read by a bounded pool of workers, so files are sent in no
+// particular order.
 // Hidden files (starting with ".") are ignored.
-// Context cancellation is supported for graceful shutdown.
+// Context cancellation is supported for graceful shutdown. The first error
+// stops the scan and is sent on the error channel.
 func (s *Scanner) Scan(ctx context.Context) (<-chan models.LocalFile, <-chan error) {
 	files := make(chan models.LocalFile)
 	errs := make(chan error, 1)
@@ -36,84 +82,280 @@ func (s *Scanner) Scan(ctx context.Context) (<-chan models.LocalFile, <-chan err
 		defer close(files)
 		defer close(errs)
 
-		err := filepath.WalkDir(s.basePath, func(path string, d fs.DirEntry, err error) error {
-			// Check for context cancellation
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			default:
-			}
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		if s.progress != nil {
+			s.progress.start = time.Now()
+			stop := make(chan struct{})
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				s.progress.run(stop)
+			}()
+			// The final progress is sent before the file channel is closed
+			defer func() {
+				close(stop)
+				<-done
+			}()
+		}
 
-			// Handle walk errors
-			if err != nil {
-				return err
+		if s.lister != nil {
+			s.record = nil
+			if err := s.scanLister(ctx, files); err != nil {
+				errs <- err
 			}
+			return
+		}
 
-			// Get the file/directory name
-			name := d.Name()
+		links, err := s.newLinkSet()
+		if err != nil {
+			errs <- err
+			return
+		}
+		s.record = nil
+		if s.prev != nil && !s.follow {
+			s.record = &dirRecord{}
+		}
 
-			// Skip hidden files and directories
-			if isHidden(name) {
-				if d.IsDir() {
-					return filepath.SkipDir
+		queue := newDirQueue(dir{path: s.basePath})
+		var (
+			wg       sync.WaitGroup
+			errOnce  sync.Once
+			firstErr error
+		)
+		for range s.workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					d, ok := queue.pop()
+					if !ok {
+						return
+					}
+					subdirs, err := s.scanDir(ctx, d, links, files)
+					if err != nil {
+						errOnce.Do(func() {
+							firstErr = err
+							cancel()
+							queue.stop()
+						})
+					}
+					queue.done(subdirs)
 				}
-				return nil
-			}
+			}()
+		}
+		wg.Wait()
 
-			// Skip directories, we only want files
-			if d.IsDir() {
-				return nil
+		if firstErr == nil && ctx.Err() != nil {
+			firstErr = ctx.Err()
+		}
+		if firstErr != nil {
+			if s.record != nil {
+				s.record.dirs = nil
 			}
+			// Send error to error channel (non-blocking since buffer size is 1)
+			select {
+			case errs <- firstErr:
+			default:
+			}
+		}
+	}()
+
+	return files, errs
+}
+
+// dir is a directory to scan. real is its resolved path when it was reached
+// through a symlink, and empty otherwise.
+type dir struct {
+	path string
+	real string
+}
+
+// scanDir sends the files of d and returns its subdirectories.
+func (s *Scanner) scanDir(ctx context.Context, d dir, links *linkSet, files chan<- models.LocalFile) ([]dir, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// The modification time is read before the entries, so that a change
+	// made while reading them is seen by the next scan
+	var mtime time.Time
+	if s.record != nil {
+		info, err := os.Stat(d.path)
+		if err != nil {
+			return nil, err
+		}
+		mtime = info.ModTime()
+		if s.prev.unchanged(d.path, mtime) {
+			return s.reuseDir(ctx, d, mtime, files)
+		}
+	}
+
+	s.progress.enterDir(d.path)
+	entries, err := os.ReadDir(d.path)
+	if err != nil {
+		return nil, err
+	}
+	if s.record != nil {
+		s.record.add(d.path, mtime, false)
+	}
 
+	var subdirs []dir
+	for _, e := range entries {
+		// Get the file/directory name
+		name := e.Name()
+
+		// Skip hidden files and directories
+		if isHidden(name) {
+			continue
+		}
+
+		path := filepath.Join(d.path, name)
+		var real string
+		if d.real != "" {
+			real = filepath.Join(d.real, name)
+		}
+
+		var info os.FileInfo
+		switch {
+		case e.Type()&os.ModeSymlink != 0 && s.follow:
+			real, info = links.follow(path)
+			if info == nil {
+				continue
+			}
+		case d.real != "" && !links.visit(real):
+			// Under a symlinked directory, already reached through another symlink
+			continue
+		case e.IsDir():
+			// Queued below
+		default:
 			// Get file info for size
-			info, err := d.Info()
-			if err != nil {
-				return err
+			if info, err = e.Info(); err != nil {
+				return nil, err
 			}
+		}
 
-			// Create LocalFile and send to channel
-			localFile := models.LocalFile{
-				FilePath: path,
-				FileName: name,
-				Size:     info.Size(),
-				Category: s.categorize(path),
+		if info == nil || info.IsDir() {
+			if s.rules.readDir(path) {
+				subdirs = append(subdirs, dir{path: path, real: real})
 			}
+			continue
+		}
+		if !s.filter.keep(name, info.Size()) || !s.rules.keepFile(path) {
+			continue
+		}
 
-			// Send file to channel, respecting context cancellation
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case files <- localFile:
-			}
+		// Create LocalFile and send to channel
+		localFile := models.LocalFile{
+			FilePath:  path,
+			FileName:  name,
+			Size:      info.Size(),
+			Allocated: AllocatedSize(info),
+			Category:  s.categorize(path),
+			ModTime:   info.ModTime(),
+		}
+		localFile.Device, localFile.Inode, localFile.Links = fileIdentity(info)
 
-			return nil
-		})
+		// Send file to channel, respecting context cancellation
+		if err := s.send(ctx, files, localFile); err != nil {
+			return nil, err
+		}
+	}
+	return subdirs, nil
+}
 
-		if err != nil {
-			// Send error to error channel (non-blocking since buffer size is 1)
-			select {
-			case errs <- err:
-			default:
-			}
+// reuseDir sends the files of d recorded by the previous scan and returns its
+// previous subdirectories.
+func (s *Scanner) reuseDir(ctx context.Context, d dir, mtime time.Time, files chan<- models.LocalFile) ([]dir, error) {
+	s.progress.enterDir(d.path)
+	for _, f := range s.prev.files[d.path] {
+		// The category rules and filters may have changed since the previous scan
+		if !s.filter.keep(f.FileName, f.Size) || !s.rules.keepFile(f.FilePath) {
+			continue
 		}
-	}()
+		f.Category = s.categorize(f.FilePath)
+		if err := s.send(ctx, files, f); err != nil {
+			return nil, err
+		}
+	}
+	s.record.add(d.path, mtime, true)
 
-	return files, errs
+	var subdirs []dir
+	for _, path := range s.prev.subdirs[d.path] {
+		if s.rules.readDir(path) {
+			subdirs = append(subdirs, dir{path: path})
+		}
+	}
+	return subdirs, nil
+}
+
+// dirQueue holds the directories left to scan. It is unbounded, as each
+// directory read adds its subdirectories, and it is finished once no
+// directory is queued or being read.
+type dirQueue struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	dirs    []dir
+	pending int // Directories queued or being read
+	stopped bool
 }
 
-// categorize determines the category of a file based on its path.
-// It checks if the path contains "/4k/", "/movies/", or "/shows/".
-// If none of these patterns match, it returns "unknown".
+func newDirQueue(root dir) *dirQueue {
+	q := &dirQueue{dirs: []dir{root}, pending: 1}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// pop waits for a directory to read. ok is false once the scan is finished
+// or stopped.
+func (q *dirQueue) pop() (d dir, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.dirs) == 0 && q.pending > 0 && !q.stopped {
+		q.cond.Wait()
+	}
+	if len(q.dirs) == 0 || q.stopped {
+		return dir{}, false
+	}
+	d = q.dirs[len(q.dirs)-1]
+	q.dirs = q.dirs[:len(q.dirs)-1]
+	return d, true
+}
+
+// done queues the subdirectories of a directory that has been read.
+func (q *dirQueue) done(subdirs []dir) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.dirs = append(q.dirs, subdirs...)
+	q.pending += len(subdirs) - 1
+	q.cond.Broadcast()
+}
+
+// stop makes every pop return, leaving the queued directories unread.
+func (q *dirQueue) stop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.stopped = true
+	q.cond.Broadcast()
+}
+
+// categorize determines the category of a file based on its path: its extra
+// pseudo-category with WithExtras, else the first category whose pattern
+// matches the path. If none matches, it returns "unknown".
 func (s *Scanner) categorize(path string) string {
+	if s.extras != "" {
+		if category := extraCategory(s.extras, path); category != "" {
+			return category
+		}
+	}
+
 	// Normalize path separators for cross-platform compatibility
 	normalizedPath := filepath.ToSlash(path)
 
-	// Check for each category in the path
 	for _, category := range s.categories {
-		// Check for category as a directory component (e.g., "/4k/", "/movies/", "/shows/")
-		pattern := "/" + category + "/"
-		if strings.Contains(normalizedPath, pattern) {
-			return category
+		if category.Pattern.MatchString(normalizedPath) {
+			return category.Name
 		}
 	}
 
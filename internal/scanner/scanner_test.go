@@ -0,0 +1,176 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"godatacleaner/internal/models"
+)
+
+// writeFile creates the file at root/rel with size bytes, and its parent
+// directories.
+func writeFile(t *testing.T, root, rel string, size int) string {
+	t.Helper()
+	path := filepath.Join(root, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// age sets the modification time of the directories under root in the
+// past, so that an incremental scan trusts them (see mtimeGrace).
+func age(t *testing.T, root string) {
+	t.Helper()
+	past := time.Now().Add(-time.Hour)
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		return os.Chtimes(path, past, past)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// scanAll returns the files found by scan, sorted by path.
+func scanAll(t *testing.T, scan *Scanner) []models.LocalFile {
+	t.Helper()
+	filesChan, errsChan := scan.Scan(context.Background())
+	var files []models.LocalFile
+	for f := range filesChan {
+		files = append(files, f)
+	}
+	if err := <-errsChan; err != nil {
+		t.Fatal(err)
+	}
+	slices.SortFunc(files, func(a, b models.LocalFile) int { return strings.Compare(a.FilePath, b.FilePath) })
+	return files
+}
+
+// filePaths returns the paths of files.
+func filePaths(files []models.LocalFile) []string {
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.FilePath
+	}
+	return paths
+}
+
+// TestScanWorkers checks that a scan reading directories in parallel finds
+// the same files as a sequential one.
+func TestScanWorkers(t *testing.T) {
+	root := t.TempDir()
+	var want []string
+	for i := range 20 {
+		for j := range 5 {
+			want = append(want, writeFile(t, root, fmt.Sprintf("movies/%02d/sub/%d.mkv", i, j), 10))
+		}
+	}
+	writeFile(t, root, "movies/.hidden/a.mkv", 10)
+	slices.Sort(want)
+
+	for _, workers := range []int{1, 8} {
+		files := scanAll(t, NewScanner(root).WithWorkers(workers))
+		if got := filePaths(files); !slices.Equal(got, want) {
+			t.Errorf("%d workers: found %d files, want %d", workers, len(got), len(want))
+		}
+		for _, f := range files {
+			if f.Category != "movies" || f.Size != 10 {
+				t.Errorf("%d workers: %s = %+v, want category movies and size 10", workers, f.FilePath, f)
+				break
+			}
+		}
+	}
+}
+
+// TestIncrementalScan checks that a scan with the previous directories only
+// reads those modified since, and takes the files of the others from the
+// previous scan.
+func TestIncrementalScan(t *testing.T) {
+	root := t.TempDir()
+	a := writeFile(t, root, "movies/A/a.mkv", 10)
+	writeFile(t, root, "movies/B/b.mkv", 10)
+	age(t, root)
+
+	first := NewScanner(root).WithPrevious(nil, nil)
+	files := scanAll(t, first)
+	dirs := first.Dirs()
+	if len(dirs) != 4 || first.Unchanged() != 0 {
+		t.Fatalf("first scan read %d directories (%d unchanged), want 4 (0)", len(dirs), first.Unchanged())
+	}
+
+	// Un fichier réécrit sur place ne change pas la date de son répertoire,
+	// un fichier ajouté si
+	if err := os.WriteFile(a, make([]byte, 20), 0644); err != nil {
+		t.Fatal(err)
+	}
+	added := writeFile(t, root, "movies/B/c.mkv", 10)
+
+	byDir := make(map[string][]models.LocalFile)
+	for _, f := range files {
+		byDir[filepath.Dir(f.FilePath)] = append(byDir[filepath.Dir(f.FilePath)], f)
+	}
+	second := NewScanner(root).WithPrevious(dirs, byDir)
+	files = scanAll(t, second)
+
+	want := []string{a, filepath.Join(root, "movies/B/b.mkv"), added}
+	if got := filePaths(files); !slices.Equal(got, want) {
+		t.Errorf("second scan = %v, want %v", got, want)
+	}
+	if files[0].Size != 10 {
+		t.Errorf("file of an unchanged directory has size %d, want 10 from the previous scan", files[0].Size)
+	}
+	// Racine, movies et A inchangés, B relu
+	if second.Unchanged() != 3 {
+		t.Errorf("second scan reused %d directories, want 3", second.Unchanged())
+	}
+}
+
+// TestCheckRoot checks that a missing, empty or canary-less scanned
+// directory is refused.
+func TestCheckRoot(t *testing.T) {
+	root := t.TempDir()
+	missing := filepath.Join(root, "missing")
+	empty := filepath.Join(root, "empty")
+	library := filepath.Join(root, "library")
+	if err := os.Mkdir(empty, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, library, "movies/a.mkv", 10)
+	writeFile(t, library, ".mounted", 0)
+
+	for _, c := range []struct {
+		name       string
+		path       string
+		canary     string
+		allowEmpty bool
+		ok         bool
+	}{
+		{"missing", missing, "", true, false},
+		{"empty", empty, "", false, false},
+		{"empty allowed", empty, "", true, true},
+		{"library", library, "", false, true},
+		{"canary", library, ".mounted", false, true},
+		{"canary missing", library, ".other", false, false},
+	} {
+		err := NewScanner(c.path).CheckRoot(c.canary, false, c.allowEmpty)
+		if c.ok && err != nil {
+			t.Errorf("%s: CheckRoot = %v, want nil", c.name, err)
+		}
+		if !c.ok && !errors.Is(err, ErrRootUnavailable) {
+			t.Errorf("%s: CheckRoot = %v, want ErrRootUnavailable", c.name, err)
+		}
+	}
+}
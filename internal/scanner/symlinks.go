@@ -0,0 +1,73 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// WithFollowSymlinks makes the scan follow symlinks to files and directories,
+// e.g. the symlink farms of a media library. A target under the scanned
+// directory is left to the scan of that directory, so each file is recorded
+// once under its own path. Other targets are recorded under the path of the
+// first symlink reaching them, with the size of the target.
+func (s *Scanner) WithFollowSymlinks(follow bool) *Scanner {
+	s.follow = follow
+	return s
+}
+
+// linkSet tracks the targets reached through symlinks during a scan, so that
+// each is scanned once and symlink loops end.
+type linkSet struct {
+	root string // Resolved scanned directory
+
+	mu      sync.Mutex
+	visited map[string]bool
+}
+
+// newLinkSet returns the set of a scan, or nil when symlinks are not followed.
+func (s *Scanner) newLinkSet() (*linkSet, error) {
+	if !s.follow {
+		return nil, nil
+	}
+	root, err := filepath.EvalSymlinks(s.basePath)
+	if err != nil {
+		return nil, fmt.Errorf("scanner: %w", err)
+	}
+	return &linkSet{root: root, visited: make(map[string]bool)}, nil
+}
+
+// follow resolves a symlink. It returns the target and its info, or a nil
+// info when the symlink is broken, points under the scanned directory or to
+// a target already reached.
+func (l *linkSet) follow(path string) (string, os.FileInfo) {
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil || within(l.root, real) || !l.visit(real) {
+		return "", nil
+	}
+	info, err := os.Stat(real)
+	if err != nil {
+		return "", nil
+	}
+	return real, info
+}
+
+// visit records a target reached through a symlink, and reports whether it
+// was reached for the first time.
+func (l *linkSet) visit(real string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.visited[real] {
+		return false
+	}
+	l.visited[real] = true
+	return true
+}
+
+// within reports whether path is root or under it.
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
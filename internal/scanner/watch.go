@@ -0,0 +1,161 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+
+	"godatacleaner/internal/models"
+)
+
+// ErrEventsLost is sent by Watch when the kernel dropped events, so that
+// some changes are only seen by the next scan.
+var ErrEventsLost = errors.New("scanner: filesystem events lost")
+
+// Watch watches the scanned directory and its subdirectories, and sends the
+// paths created, written, removed or renamed under them. New directories are
+// watched as they appear; their path is sent too, as files may be created in
+// them before they are watched. Hidden files are ignored.
+//
+// An error adding a watch, typically the inotify watch limit being reached,
+// stops watching and is sent on the error channel. ErrEventsLost is sent
+// without stopping.
+func (s *Scanner) Watch(ctx context.Context) (<-chan string, <-chan error) {
+	paths := make(chan string)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(paths)
+		defer close(errs)
+
+		w, err := fsnotify.NewWatcher()
+		if err != nil {
+			errs <- fmt.Errorf("scanner: %w", err)
+			return
+		}
+		defer w.Close()
+
+		if err := s.watchTree(w, s.basePath); err != nil {
+			errs <- err
+			return
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case err := <-w.Errors:
+				if !errors.Is(err, fsnotify.ErrEventOverflow) {
+					errs <- fmt.Errorf("scanner: %w", err)
+					return
+				}
+				select {
+				case errs <- ErrEventsLost:
+				default:
+				}
+			case ev := <-w.Events:
+				if isHidden(filepath.Base(ev.Name)) {
+					continue
+				}
+				if ev.Has(fsnotify.Create) {
+					if info, err := os.Lstat(ev.Name); err == nil && info.IsDir() {
+						// A directory removed right after its creation is not an error
+						if err := s.watchTree(w, ev.Name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+							errs <- err
+							return
+						}
+					}
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case paths <- ev.Name:
+				}
+			}
+		}
+	}()
+
+	return paths, errs
+}
+
+// watchTree watches root and its subdirectories, except hidden ones and
+// those left out by the tree rules.
+func (s *Scanner) watchTree(w *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Directory removed meanwhile
+			if errors.Is(err, fs.ErrNotExist) && path != root {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != root && isHidden(d.Name()) || !s.rules.readDir(path) {
+			return filepath.SkipDir
+		}
+		if err := w.Add(path); err != nil {
+			return fmt.Errorf("scanner: failed to watch %s: %w", path, err)
+		}
+		return nil
+	})
+}
+
+// Stat returns the files at path as a scan would record them: the file at
+// path, or the files under it when it is a directory. ok is false when path
+// no longer exists. Symlinks are recorded as files, or skipped when the
+// scanner follows them, as their targets are only resolved by a scan.
+func (s *Scanner) Stat(ctx context.Context, path string) (files []models.LocalFile, ok bool, err error) {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	if info.IsDir() {
+		if !s.rules.readDir(path) {
+			return nil, true, nil
+		}
+		sub := NewScanner(path).WithWorkers(s.workers)
+		sub.categories = s.categories
+		sub.filter = s.filter
+		sub.extras = s.extras
+		sub.rules = s.rules
+		filesChan, errsChan := sub.Scan(ctx)
+		for f := range filesChan {
+			if s.follow && isSymlink(f.FilePath) {
+				continue
+			}
+			files = append(files, f)
+		}
+		return files, true, <-errsChan
+	}
+	if info.Mode()&os.ModeSymlink != 0 && s.follow || !s.filter.keep(info.Name(), info.Size()) || !s.rules.keepFile(path) {
+		return nil, true, nil
+	}
+
+	f := models.LocalFile{
+		FilePath:  path,
+		FileName:  info.Name(),
+		Size:      info.Size(),
+		Allocated: AllocatedSize(info),
+		Category:  s.categorize(path),
+		ModTime:   info.ModTime(),
+	}
+	f.Device, f.Inode, f.Links = fileIdentity(info)
+	return []models.LocalFile{f}, true, nil
+}
+
+// isSymlink reports whether path is a symlink.
+func isSymlink(path string) bool {
+	info, err := os.Lstat(path)
+	return err == nil && info.Mode()&os.ModeSymlink != 0
+}
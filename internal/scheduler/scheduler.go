@@ -0,0 +1,183 @@
+// Package scheduler runs cleanup jobs automatically, on a cron schedule or after each sync.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"godatacleaner/internal/cleaner"
+	"godatacleaner/internal/models"
+	"godatacleaner/internal/storage"
+)
+
+// AfterSync is the schedule of jobs that run at the end of every sync.
+const AfterSync = "@after-sync"
+
+// Scheduler executes the enabled cleanup jobs stored in the database.
+type Scheduler struct {
+	storage  storage.Store
+	cleaner  *cleaner.Cleaner
+	interval time.Duration
+}
+
+// NewScheduler creates a new scheduler that checks for due jobs every minute.
+func NewScheduler(store storage.Store, c *cleaner.Cleaner) *Scheduler {
+	return &Scheduler{
+		storage:  store,
+		cleaner:  c,
+		interval: time.Minute,
+	}
+}
+
+// ValidateSchedule checks that a schedule is either AfterSync or a standard
+// 5-field cron expression (descriptors such as @daily are accepted).
+func ValidateSchedule(schedule string) error {
+	if schedule == AfterSync {
+		return nil
+	}
+	if _, err := cron.ParseStandard(schedule); err != nil {
+		return fmt.Errorf("invalid schedule %q: %w", schedule, err)
+	}
+	return nil
+}
+
+// Start runs cron jobs as they become due until the context is cancelled.
+// Jobs are reloaded from the database on every tick, so changes made by
+// another process (e.g. the CLI) are picked up without a restart.
+func (s *Scheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.runDue(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Every calls run on every activation of the cron expression schedule,
+// evaluated in local time, until the context is cancelled.
+func Every(ctx context.Context, schedule string, run func(context.Context)) error {
+	sched, err := cron.ParseStandard(schedule)
+	if err != nil {
+		return fmt.Errorf("invalid schedule %q: %w", schedule, err)
+	}
+
+	for {
+		timer := time.NewTimer(time.Until(sched.Next(time.Now())))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+		run(ctx)
+	}
+}
+
+// RunAfterSync runs every enabled job scheduled after sync.
+func (s *Scheduler) RunAfterSync(ctx context.Context) error {
+	jobs, err := s.storage.ListCleanupJobs(ctx)
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		if !job.Enabled || job.Schedule != AfterSync {
+			continue
+		}
+		if _, err := s.RunJob(ctx, job); err != nil {
+			log.Printf("⚠️  Tâche de nettoyage %q en échec: %v", job.Name, err)
+		}
+	}
+	return nil
+}
+
+// RunJob executes a single job and records the run and the files it handled.
+func (s *Scheduler) RunJob(ctx context.Context, job models.CleanupJob) (*models.CleanupRun, error) {
+	run := models.CleanupRun{
+		Source:     models.CleanupSourceJob,
+		JobID:      job.ID,
+		StartedAt:  time.Now(),
+		DryRun:     job.DryRun,
+		Quarantine: job.Quarantine,
+	}
+
+	report, runErr := s.cleaner.Run(ctx, cleaner.Options{
+		DryRun:     job.DryRun,
+		Category:   job.Category,
+		Quarantine: job.Quarantine,
+		Archive:    job.Archive,
+		Companions: job.Companions,
+	})
+	run.FinishedAt = time.Now()
+	if runErr != nil {
+		run.Error = runErr.Error()
+	}
+
+	var files []models.CleanupRunFile
+	if report != nil {
+		run.FilesRemoved = report.FilesRemoved
+		run.BytesReclaimed = report.BytesReclaimed
+		run.Failed = report.Failed
+		files = report.Files()
+	}
+
+	// Record the run even when the cleaner failed, with a context that
+	// survives cancellation so partial work is never lost.
+	id, err := s.storage.RecordCleanupRun(context.WithoutCancel(ctx), run, files)
+	if err != nil {
+		return nil, err
+	}
+	run.ID = id
+
+	return &run, runErr
+}
+
+// runDue runs every enabled cron job whose next activation is not after now.
+func (s *Scheduler) runDue(ctx context.Context, now time.Time) {
+	jobs, err := s.storage.ListCleanupJobs(ctx)
+	if err != nil {
+		log.Printf("⚠️  Impossible de charger les tâches de nettoyage: %v", err)
+		return
+	}
+
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			return
+		}
+		if !job.Enabled || job.Schedule == AfterSync {
+			continue
+		}
+
+		sched, err := cron.ParseStandard(job.Schedule)
+		if err != nil {
+			log.Printf("⚠️  Planification invalide pour %q: %v", job.Name, err)
+			continue
+		}
+
+		// Evaluate the expression in local time, timestamps are stored in UTC
+		last := job.CreatedAt
+		if job.LastRunAt != nil {
+			last = *job.LastRunAt
+		}
+		if sched.Next(last.In(now.Location())).After(now) {
+			continue
+		}
+
+		log.Printf("🧹 Exécution de la tâche de nettoyage %q", job.Name)
+		run, err := s.RunJob(ctx, job)
+		if err != nil {
+			log.Printf("⚠️  Tâche de nettoyage %q en échec: %v", job.Name, err)
+			continue
+		}
+		log.Printf("✅ Tâche %q: %d fichiers traités (%d octets)", job.Name, run.FilesRemoved, run.BytesReclaimed)
+	}
+}
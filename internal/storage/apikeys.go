@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"godatacleaner/internal/models"
+)
+
+// CreateAPIKey stores a new API key from its hash and returns its ID.
+func (s *Storage) CreateAPIKey(ctx context.Context, name, prefix, keyHash, scope string) (int64, error) {
+	res, err := s.db.ExecContext(ctx,
+		"INSERT INTO api_keys (name, prefix, key_hash, scope) VALUES (?, ?, ?, ?)",
+		name, prefix, keyHash, scope)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert API key: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+// CountActiveAPIKeys returns the number of API keys that were not revoked.
+func (s *Storage) CountActiveAPIKeys(ctx context.Context) (int64, error) {
+	var n int64
+	if err := s.read.QueryRowContext(ctx, "SELECT COUNT(*) FROM api_keys WHERE revoked_at IS NULL").Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count API keys: %w", err)
+	}
+	return n, nil
+}
+
+// GetAPIKey returns an API key by ID.
+func (s *Storage) GetAPIKey(ctx context.Context, id int64) (*models.APIKey, error) {
+	return s.getAPIKey(ctx, "id = ?", id)
+}
+
+// GetActiveAPIKeyByHash returns the API key matching a hash, unless it was revoked.
+func (s *Storage) GetActiveAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	return s.getAPIKey(ctx, "key_hash = ? AND revoked_at IS NULL", keyHash)
+}
+
+func (s *Storage) getAPIKey(ctx context.Context, where string, args ...interface{}) (*models.APIKey, error) {
+	var k models.APIKey
+	err := s.read.QueryRowContext(ctx,
+		"SELECT id, name, prefix, scope, created_at, last_used_at, revoked_at FROM api_keys WHERE "+where, args...,
+	).Scan(&k.ID, &k.Name, &k.Prefix, &k.Scope, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+	return &k, nil
+}
+
+// ListAPIKeys returns all API keys, revoked ones included, newest first.
+func (s *Storage) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	rows, err := s.read.QueryContext(ctx,
+		"SELECT id, name, prefix, scope, created_at, last_used_at, revoked_at FROM api_keys ORDER BY id DESC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query API keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []models.APIKey
+	for rows.Next() {
+		var k models.APIKey
+		if err := rows.Scan(&k.ID, &k.Name, &k.Prefix, &k.Scope, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		keys = append(keys, k)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating API keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// TouchAPIKey records the use of an API key. The date is updated at most
+// once per minute so that scripts polling the API do not write on every call.
+func (s *Storage) TouchAPIKey(ctx context.Context, id int64) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE api_keys SET last_used_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND (last_used_at IS NULL OR last_used_at < datetime('now', '-1 minute'))`, id)
+	if err != nil {
+		return fmt.Errorf("failed to update API key: %w", err)
+	}
+	return nil
+}
+
+// RevokeAPIKey revokes an API key. Revoked keys are kept for the history.
+func (s *Storage) RevokeAPIKey(ctx context.Context, id int64) error {
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE api_keys SET revoked_at = CURRENT_TIMESTAMP WHERE id = ? AND revoked_at IS NULL", id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	return checkAffected(res)
+}
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// ErrLockHeld is returned by Restore while a sync or a watch holds its lock.
+var ErrLockHeld = errors.New("storage: a sync is running")
+
+// Backup copies the database to path, which must not exist, with the SQLite
+// online backup API: the copy is a consistent snapshot, taken without
+// stopping the syncs or the readers. It is written next to path first, so
+// that path never holds a partial copy.
+func (s *Storage) Backup(ctx context.Context, path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("backup file %s already exists", path)
+	}
+
+	tmp := path + ".tmp"
+	os.Remove(tmp)
+	dst, err := sql.Open("sqlite3", tmp)
+	if err != nil {
+		return fmt.Errorf("failed to open backup file: %w", err)
+	}
+	err = copyDatabase(ctx, dst, s.read)
+	if err == nil {
+		// La copie hérite du mode WAL de la base : la repasser en journal
+		// classique pour qu'elle tienne dans un seul fichier
+		_, err = dst.ExecContext(ctx, "PRAGMA journal_mode=DELETE")
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write backup file: %w", err)
+	}
+	return nil
+}
+
+// Restore replaces the content of the database with the backup at path,
+// taken by Backup. The database is replaced in place with the backup API,
+// so that the other processes using it see the restored content. It fails
+// with ErrLockHeld while a sync is running, and when path is not a
+// GoDataCleaner database. Initialize must be called after, to migrate the
+// schema of an older backup.
+func (s *Storage) Restore(ctx context.Context, path string) error {
+	defer s.counts.reset()
+
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("failed to open backup file: %w", err)
+	}
+	src, err := sql.Open("sqlite3", path+"?_query_only=true")
+	if err != nil {
+		return fmt.Errorf("failed to open backup file: %w", err)
+	}
+	defer src.Close()
+
+	// Vérifie la sauvegarde avant de remplacer la base
+	var tables int
+	if err := src.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM sqlite_master WHERE type IN ('table', 'view') AND name IN ('torrent_files', 'local_files')
+	`).Scan(&tables); err != nil {
+		return fmt.Errorf("failed to read backup file: %w", err)
+	}
+	if tables != 2 {
+		return fmt.Errorf("%s is not a GoDataCleaner database", path)
+	}
+	var check string
+	if err := src.QueryRowContext(ctx, "PRAGMA quick_check").Scan(&check); err != nil {
+		return fmt.Errorf("failed to check backup file: %w", err)
+	}
+	if check != "ok" {
+		return fmt.Errorf("backup file %s is corrupted: %s", path, check)
+	}
+
+	var locked bool
+	if err := s.read.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM locks WHERE expires_at >= ?)",
+		time.Now().UnixMilli()).Scan(&locked); err != nil {
+		return fmt.Errorf("failed to check locks: %w", err)
+	}
+	if locked {
+		return ErrLockHeld
+	}
+
+	if err := copyDatabase(ctx, s.db, src); err != nil {
+		return fmt.Errorf("failed to restore database: %w", err)
+	}
+	return nil
+}
+
+// copyDatabase copies the content of src into dst with the backup API, in a
+// single step: copying by chunks would start over each time another
+// connection writes to src.
+func copyDatabase(ctx context.Context, dst, src *sql.DB) error {
+	dstConn, err := dst.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer dstConn.Close()
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	return dstConn.Raw(func(dc any) error {
+		return srcConn.Raw(func(sc any) error {
+			b, err := dc.(*sqlite3.SQLiteConn).Backup("main", sc.(*sqlite3.SQLiteConn), "main")
+			if err != nil {
+				return err
+			}
+			if _, err := b.Step(-1); err != nil {
+				b.Finish()
+				return err
+			}
+			return b.Finish()
+		})
+	})
+}
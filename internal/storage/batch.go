@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// maxInsertVariables bounds the bound parameters of a multi-row INSERT, below
+// the SQLite limit of 32766, and maxInsertRows the rows of a statement.
+const (
+	maxInsertVariables = 32000
+	maxInsertRows      = 500
+)
+
+// rowInserter writes rows within a transaction with multi-row INSERT
+// statements: a single statement for hundreds of rows is much faster than
+// one ExecContext per row. Rows are buffered by add, and written when a
+// statement is full or by flush, which must be called once all are added.
+type rowInserter struct {
+	tx      *sql.Tx
+	query   func(rows int) string
+	columns int
+	rows    int       // Rows per full statement
+	full    *sql.Stmt // Statement of a full batch, prepared on first use
+	args    []any
+}
+
+// newRowInserter returns an inserter of rows of columns values into table.
+// conflict, such as an ON CONFLICT clause, follows the VALUES; verb is INSERT
+// or INSERT OR REPLACE.
+func newRowInserter(tx *sql.Tx, verb, table, columns, conflict string) *rowInserter {
+	n := strings.Count(columns, ",") + 1
+	row := "(" + strings.TrimSuffix(strings.Repeat("?, ", n), ", ") + ")"
+	return &rowInserter{
+		tx: tx,
+		query: func(rows int) string {
+			return verb + " INTO " + table + " (" + columns + ") VALUES " +
+				strings.TrimSuffix(strings.Repeat(row+", ", rows), ", ") + " " + conflict
+		},
+		columns: n,
+		rows:    min(maxInsertRows, maxInsertVariables/n),
+	}
+}
+
+// add buffers a row, whose values are in the order of the columns.
+func (r *rowInserter) add(ctx context.Context, values ...any) error {
+	r.args = append(r.args, values...)
+	if len(r.args) < r.rows*r.columns {
+		return nil
+	}
+	if r.full == nil {
+		stmt, err := r.tx.PrepareContext(ctx, r.query(r.rows))
+		if err != nil {
+			return fmt.Errorf("failed to prepare statement: %w", err)
+		}
+		r.full = stmt
+	}
+	_, err := r.full.ExecContext(ctx, r.args...)
+	r.args = r.args[:0]
+	return err
+}
+
+// flush writes the buffered rows.
+func (r *rowInserter) flush(ctx context.Context) error {
+	if len(r.args) == 0 {
+		return nil
+	}
+	_, err := r.tx.ExecContext(ctx, r.query(len(r.args)/r.columns), r.args...)
+	r.args = r.args[:0]
+	return err
+}
+
+// close releases the prepared statement.
+func (r *rowInserter) close() {
+	if r.full != nil {
+		r.full.Close()
+	}
+}
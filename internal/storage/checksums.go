@@ -0,0 +1,206 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"godatacleaner/internal/models"
+)
+
+// ListFilesToHash returns the local files without a checksum computed as
+// spec describes (see checksum.Hasher.Spec): never hashed, changed since, or
+// hashed with another algorithm or mode.
+func (s *Storage) ListFilesToHash(ctx context.Context, spec string) ([]models.LocalFile, error) {
+	return s.listChecksumFiles(ctx, "substr(checksum, 1, length(?1)) != ?1", spec)
+}
+
+// ListHashedFiles returns the local files with a checksum computed as spec
+// describes, along with the checksum.
+func (s *Storage) ListHashedFiles(ctx context.Context, spec string) ([]models.LocalFile, error) {
+	return s.listChecksumFiles(ctx, "substr(checksum, 1, length(?1)) = ?1", spec)
+}
+
+// listChecksumFiles returns the local files matching condition, whose single
+// argument is the spec prefix of the checksums.
+func (s *Storage) listChecksumFiles(ctx context.Context, condition, spec string) ([]models.LocalFile, error) {
+	rows, err := s.read.QueryContext(ctx, `
+		SELECT file_path, file_name, size, allocated, category, device, inode, links, mtime, checksum
+		FROM local_files WHERE `+condition+`
+		ORDER BY file_path`, spec+":")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query local files: %w", err)
+	}
+	defer rows.Close()
+
+	var files []models.LocalFile
+	for rows.Next() {
+		var f models.LocalFile
+		var mtime int64
+		if err := rows.Scan(&f.FilePath, &f.FileName, &f.Size, &f.Allocated, &f.Category, &f.Device, &f.Inode, &f.Links, &mtime, &f.Checksum); err != nil {
+			return nil, fmt.Errorf("failed to scan local file: %w", err)
+		}
+		f.ModTime = fromUnixTime(mtime)
+		files = append(files, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating local files: %w", err)
+	}
+	return files, nil
+}
+
+// SetChecksums stores the Checksum of the given local files, by FilePath.
+// A file whose size or modification time changed since it was listed keeps
+// no checksum, as the content hashed may not be the one recorded.
+func (s *Storage) SetChecksums(ctx context.Context, files []models.LocalFile) error {
+	if len(files) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, "UPDATE local_file_rows SET checksum = ? WHERE file_path = ? AND size = ? AND mtime = ?")
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, f := range files {
+		if _, err := stmt.ExecContext(ctx, f.Checksum, f.FilePath, f.Size, unixTime(f.ModTime)); err != nil {
+			return fmt.Errorf("failed to set checksum: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// identity is the SQL expression identifying the file on disk of the local
+// file l, shared by its hard links; its path when the inode is unknown.
+const identity = `CASE WHEN l.inode = 0 THEN l.file_path ELSE l.device || ':' || l.inode END`
+
+// fileStatus is the SQL expression of the models.FileStatus of the local file
+// l, with the priorities of the orphan detection.
+const fileStatus = `CASE
+	WHEN EXISTS (SELECT 1 FROM torrent_files t WHERE t.relative_path = l.relative_path) OR NOT ` + notHardlinked + `
+		THEN '` + models.FileStatusMatched + `'
+	WHEN ` + incomplete + ` THEN '` + models.FileStatusDownloading + `'
+	WHEN NOT ` + notIgnored + ` THEN '` + models.FileStatusIgnored + `'
+	ELSE '` + models.FileStatusOrphan + `' END`
+
+// GetDuplicateFiles returns the groups of local files with the same checksum
+// and size that are not all hard links of a single file, most wasted bytes
+// first. Only checksums computed as spec describes are compared.
+func (s *Storage) GetDuplicateFiles(ctx context.Context, spec string) ([]models.DuplicateGroup, error) {
+	groups, err := s.duplicateGroups(ctx, "l.checksum", "substr(l.checksum, 1, length(?1)) = ?1", spec+":")
+	if err != nil {
+		return nil, err
+	}
+	for i := range groups {
+		groups[i].Checksum = groups[i].Files[0].Checksum
+	}
+	return groups, nil
+}
+
+// GetDuplicateNames returns the groups of local files with the same name and
+// size that are not all hard links of a single file, most wasted bytes first:
+// likely the same content, without checksums to tell. Empty files are left
+// out.
+func (s *Storage) GetDuplicateNames(ctx context.Context) ([]models.DuplicateGroup, error) {
+	groups, err := s.duplicateGroups(ctx, "l.file_name", "l.size > 0")
+	if err != nil {
+		return nil, err
+	}
+	for i := range groups {
+		groups[i].Name = groups[i].Files[0].FileName
+	}
+	return groups, nil
+}
+
+// duplicateGroups returns the groups of local files with the same key column
+// and size, among those matching where, with the status of every copy.
+func (s *Storage) duplicateGroups(ctx context.Context, key, where string, args ...any) ([]models.DuplicateGroup, error) {
+	rows, err := s.read.QueryContext(ctx, `
+		WITH d AS (
+			SELECT `+key+` AS k, l.size, COUNT(DISTINCT `+identity+`) AS copies
+			FROM local_files l
+			WHERE `+where+`
+			GROUP BY `+key+`, l.size
+			HAVING copies > 1
+		)
+		SELECT d.k, d.size, d.copies, l.file_path, l.file_name, l.allocated, l.category,
+			l.device, l.inode, l.links, l.mtime, l.checksum, `+fileStatus+`
+		FROM d JOIN local_files l ON `+key+` = d.k AND l.size = d.size
+		ORDER BY (d.copies - 1) * d.size DESC, d.k, d.size, l.file_path`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query duplicate files: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []models.DuplicateGroup
+	var last string
+	for rows.Next() {
+		var g models.DuplicateGroup
+		var f models.DuplicateFile
+		var k string
+		var mtime int64
+		if err := rows.Scan(&k, &g.Size, &g.Copies, &f.FilePath, &f.FileName, &f.Allocated, &f.Category,
+			&f.Device, &f.Inode, &f.Links, &mtime, &f.Checksum, &f.Status); err != nil {
+			return nil, fmt.Errorf("failed to scan duplicate file: %w", err)
+		}
+		f.Size = g.Size
+		f.ModTime = fromUnixTime(mtime)
+
+		// Les fichiers d'un groupe se suivent
+		if n := len(groups); n > 0 && last == k && groups[n-1].Size == g.Size {
+			groups[n-1].Files = append(groups[n-1].Files, f)
+			continue
+		}
+		last = k
+		g.WastedBytes = (g.Copies - 1) * g.Size
+		g.Files = []models.DuplicateFile{f}
+		groups = append(groups, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating duplicate files: %w", err)
+	}
+
+	for i := range groups {
+		markDeletable(&groups[i])
+	}
+	return groups, nil
+}
+
+// markDeletable flags the copies of g that can be deleted: the orphans, as
+// long as a copy of the content is kept, the first one when no torrent
+// protects any. A hard link of a kept copy is not flagged, as deleting it
+// frees no space.
+func markDeletable(g *models.DuplicateGroup) {
+	kept := make(map[string]bool)
+	for _, f := range g.Files {
+		if f.Status != models.FileStatusOrphan {
+			kept[fileIdentity(f.LocalFile)] = true
+		}
+	}
+	if len(kept) == 0 {
+		kept[fileIdentity(g.Files[0].LocalFile)] = true
+	}
+	for i := range g.Files {
+		f := &g.Files[i]
+		f.Deletable = f.Status == models.FileStatusOrphan && !kept[fileIdentity(f.LocalFile)]
+	}
+}
+
+// fileIdentity identifies the file on disk of f as the identity SQL
+// expression does.
+func fileIdentity(f models.LocalFile) string {
+	if f.Inode == 0 {
+		return f.FilePath
+	}
+	return fmt.Sprintf("%d:%d", f.Device, f.Inode)
+}
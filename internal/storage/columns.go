@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// GetColumnLayouts returns the columns shown by a user in the file lists of
+// the WebUI, by column list. Lists the user never customized are missing.
+func (s *Storage) GetColumnLayouts(ctx context.Context, username string) (map[string][]string, error) {
+	rows, err := s.read.QueryContext(ctx, "SELECT list, columns FROM column_layouts WHERE username = ?", username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query column layouts: %w", err)
+	}
+	defer rows.Close()
+
+	layouts := map[string][]string{}
+	for rows.Next() {
+		var list, columns string
+		if err := rows.Scan(&list, &columns); err != nil {
+			return nil, fmt.Errorf("failed to scan column layout: %w", err)
+		}
+		var cols []string
+		if err := json.Unmarshal([]byte(columns), &cols); err != nil {
+			return nil, fmt.Errorf("failed to decode column layout %s: %w", list, err)
+		}
+		layouts[list] = cols
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating column layouts: %w", err)
+	}
+
+	return layouts, nil
+}
+
+// SetColumnLayout replaces the columns shown by a user in a file list.
+func (s *Storage) SetColumnLayout(ctx context.Context, username, list string, columns []string) error {
+	data, err := json.Marshal(columns)
+	if err != nil {
+		return fmt.Errorf("failed to encode column layout: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO column_layouts (username, list, columns, updated_at) VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(username, list) DO UPDATE SET columns = excluded.columns, updated_at = excluded.updated_at
+	`, username, list, string(data))
+	if err != nil {
+		return fmt.Errorf("failed to save column layout: %w", err)
+	}
+	return nil
+}
+
+// DeleteColumnLayout restores the default columns of a file list for a user.
+func (s *Storage) DeleteColumnLayout(ctx context.Context, username, list string) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM column_layouts WHERE username = ? AND list = ?", username, list); err != nil {
+		return fmt.Errorf("failed to delete column layout: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"godatacleaner/internal/models"
+)
+
+// countCache keeps the totals of the paginated file queries for a short
+// time: counting the orphans joins every local file to the torrent files,
+// again for each page of the same list.
+type countCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration // 0 when disabled
+	entries map[string]countEntry
+	resets  int64 // Incremented by reset
+}
+
+type countEntry struct {
+	total   int64
+	expires time.Time
+}
+
+// WithCountCache keeps the totals of the paginated lists of torrent files,
+// local files and orphans for ttl, so that browsing the pages of a large list
+// does not count it again on each page. The totals are dropped as soon as
+// this Storage writes files, torrents or ignored paths; the writes of other
+// processes, such as a sync run from the CLI, show within ttl. 0 disables
+// the cache.
+func (s *Storage) WithCountCache(ttl time.Duration) *Storage {
+	s.counts.mu.Lock()
+	defer s.counts.mu.Unlock()
+	s.counts.ttl = ttl
+	s.counts.entries = make(map[string]countEntry)
+	return s
+}
+
+// countKey identifies the rows of list selected by the filters of opts. The
+// age limits are kept as durations: the rows they select only drift by the
+// time spent in the cache.
+func countKey(list string, opts models.QueryOptions) string {
+	return fmt.Sprintf("%s\x00%s\x00%s\x00%s\x00%t\x00%d\x00%d\x00%d\x00%d\x00%d\x00%t",
+		list, opts.Search, opts.Category, opts.State, opts.Unique,
+		opts.MinAge, opts.MaxAge, opts.MinOrphanAge, opts.MinSize, opts.MaxSize, opts.ExcludeHardlinked)
+}
+
+// count runs the count query of a paginated list, or returns its cached
+// total under key.
+func (s *Storage) count(ctx context.Context, key, query string, args ...interface{}) (int64, error) {
+	c := &s.counts
+	c.mu.Lock()
+	ttl, resets := c.ttl, c.resets
+	e, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && time.Now().Before(e.expires) {
+		return e.total, nil
+	}
+
+	var total int64
+	if err := s.read.QueryRowContext(ctx, query, args...).Scan(&total); err != nil {
+		return 0, err
+	}
+	if ttl > 0 {
+		c.mu.Lock()
+		// Une écriture pendant le comptage a vidé le cache : ne pas y remettre
+		// un total d'avant l'écriture
+		if c.resets == resets && c.ttl == ttl {
+			c.entries[key] = countEntry{total: total, expires: time.Now().Add(ttl)}
+		}
+		c.mu.Unlock()
+	}
+	return total, nil
+}
+
+// reset drops the cached totals, after a write changing them.
+func (c *countCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.resets++
+	if len(c.entries) > 0 {
+		c.entries = make(map[string]countEntry)
+	}
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+
+	"godatacleaner/internal/models"
+)
+
+// keyset returns the ORDER BY clause sorting on col in order, then on idCol
+// in the same order so that the rows of equal value keep a stable order, and
+// with after set, the condition selecting the rows that follow it. An empty
+// col sorts on idCol alone.
+func keyset(col, idCol, order string, after *models.Cursor) (string, string, []interface{}) {
+	op := ">"
+	if order == "desc" {
+		op = "<"
+	}
+	if col == "" {
+		if after == nil {
+			return fmt.Sprintf("ORDER BY %s %s", idCol, order), "", nil
+		}
+		return fmt.Sprintf("ORDER BY %s %s", idCol, order), idCol + " " + op + " ?", []interface{}{after.ID}
+	}
+	orderClause := fmt.Sprintf("ORDER BY %s %s, %s %s", col, order, idCol, order)
+	if after == nil {
+		return orderClause, "", nil
+	}
+	return orderClause, fmt.Sprintf("(%s, %s) %s (?, ?)", col, idCol, op), []interface{}{cursorValue(after.Value), after.ID}
+}
+
+// cursorValue returns the value of a cursor as an integer when it is one, to
+// be compared with the numeric columns and expressions. A text such as 007
+// stays a text: SQLite would compare 7 to the text columns as '7'.
+func cursorValue(v string) interface{} {
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil && strconv.FormatInt(n, 10) == v {
+		return n
+	}
+	return v
+}
+
+// compareCursor compares the cursors of two rows on their value, as numbers
+// for a numeric column, then on their ID, as keyset does.
+func compareCursor(a, b models.Cursor, numeric bool) int {
+	if numeric {
+		av, _ := strconv.ParseInt(a.Value, 10, 64)
+		bv, _ := strconv.ParseInt(b.Value, 10, 64)
+		return cmp.Or(cmp.Compare(av, bv), cmp.Compare(a.ID, b.ID))
+	}
+	return cmp.Or(cmp.Compare(a.Value, b.Value), cmp.Compare(a.ID, b.ID))
+}
+
+// TorrentFileCursor returns the cursor of f in the torrent files sorted on
+// sort, to get the following ones with QueryOptions.After.
+func TorrentFileCursor(sort string, f models.TorrentFile) *models.Cursor {
+	c := &models.Cursor{ID: f.ID}
+	switch sort {
+	case "torrent_hash":
+		c.Value = f.TorrentHash
+	case "torrent_name":
+		c.Value = f.TorrentName
+	case "file_name":
+		c.Value = f.FileName
+	case "file_path":
+		c.Value = f.FilePath
+	case "size":
+		c.Value = strconv.FormatInt(f.Size, 10)
+	case "instance":
+		c.Value = f.Instance
+	}
+	return c
+}
+
+// LocalFileCursor returns the cursor of f in the local files sorted on sort.
+func LocalFileCursor(sort string, f models.LocalFile) *models.Cursor {
+	c := &models.Cursor{ID: f.ID}
+	switch sort {
+	case "file_path":
+		c.Value = f.FilePath
+	case "file_name":
+		c.Value = f.FileName
+	case "size":
+		c.Value = strconv.FormatInt(f.Size, 10)
+	case "category":
+		c.Value = f.Category
+	case "mod_time":
+		c.Value = strconv.FormatInt(unixTime(f.ModTime), 10)
+	}
+	return c
+}
+
+// OrphanFileCursor returns the cursor of f in the orphans sorted on sort.
+// Orphans are sorted by size by default.
+func OrphanFileCursor(sort string, f models.OrphanFile) *models.Cursor {
+	c := &models.Cursor{ID: f.ID}
+	switch sort {
+	case "file_path":
+		c.Value = f.FilePath
+	case "file_name":
+		c.Value = f.FileName
+	case "category":
+		c.Value = f.Category
+	case "mod_time":
+		c.Value = strconv.FormatInt(unixTime(f.ModTime), 10)
+	case "orphaned_at":
+		c.Value = strconv.FormatInt(unixTime(f.OrphanedAt), 10)
+	case "links":
+		c.Value = strconv.FormatInt(f.Links, 10)
+	default:
+		c.Value = strconv.FormatInt(f.Size, 10)
+	}
+	return c
+}
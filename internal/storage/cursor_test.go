@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"godatacleaner/internal/models"
+)
+
+// TestKeysetPagination pages through files whose sort values are all equal:
+// the ID must keep their order stable, so that every file is listed once.
+func TestKeysetPagination(t *testing.T) {
+	ctx := context.Background()
+	c := storeCase{name: "ties"}
+	for i := range 7 {
+		c.local = append(c.local, localFile(fmt.Sprintf("/data/movies/%c/file.mkv", 'G'-i)))
+	}
+
+	for _, b := range backends {
+		s := b.open(t, c)
+
+		for _, sort := range []string{"size", "category", "file_name", "file_path"} {
+			for _, order := range []string{"asc", "desc"} {
+				t.Run(fmt.Sprintf("%s/orphans/%s_%s", b.name, sort, order), func(t *testing.T) {
+					opts := models.QueryOptions{PerPage: 3, Sort: sort, Order: order}
+					var seen []string
+					for range len(c.local) {
+						files, total, err := s.GetOrphanFiles(ctx, opts)
+						if err != nil {
+							t.Fatal(err)
+						}
+						if total != int64(len(c.local)) {
+							t.Fatalf("total = %d, want %d", total, len(c.local))
+						}
+						for _, f := range files {
+							seen = append(seen, f.FilePath)
+						}
+						if len(files) < opts.PerPage {
+							break
+						}
+						opts.After = OrphanFileCursor(sort, files[len(files)-1])
+					}
+					checkPages(t, seen, c.local)
+				})
+
+				t.Run(fmt.Sprintf("%s/local/%s_%s", b.name, sort, order), func(t *testing.T) {
+					opts := models.QueryOptions{PerPage: 3, Sort: sort, Order: order}
+					var seen []string
+					for range len(c.local) {
+						files, _, err := s.GetLocalFiles(ctx, opts)
+						if err != nil {
+							t.Fatal(err)
+						}
+						for _, f := range files {
+							seen = append(seen, f.FilePath)
+						}
+						if len(files) < opts.PerPage {
+							break
+						}
+						opts.After = LocalFileCursor(sort, files[len(files)-1])
+					}
+					checkPages(t, seen, c.local)
+				})
+			}
+		}
+	}
+}
+
+// checkPages fails unless seen lists every file once.
+func checkPages(t *testing.T, seen []string, files []models.LocalFile) {
+	t.Helper()
+	var want []string
+	for _, f := range files {
+		want = append(want, f.FilePath)
+	}
+	slices.Sort(want)
+	got := slices.Clone(seen)
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Errorf("pages list %v, want each of %v once", seen, want)
+	}
+}
@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"godatacleaner/internal/models"
+)
+
+// Changes counts the rows written by a sync of a table: the rows of files that
+// are unchanged since the previous sync are not written again.
+type Changes struct {
+	Inserted int `json:"inserted"`
+	Updated  int `json:"updated"`
+	Deleted  int `json:"deleted"`
+
+	// Local files added, removed or whose size changed, to be recorded with
+	// the sync run. They are not listed by the first sync, which adds them all.
+	Files    []models.FileChange `json:"-"`
+	Recorded bool                `json:"-"` // Whether Files were listed
+}
+
+// localRow holds the columns of a local_files row compared by a sync.
+type localRow struct {
+	fileName     string
+	relativePath string
+	size         int64
+	allocated    int64
+	category     string
+	device       int64
+	inode        int64
+	links        int64
+	mtime        int64
+}
+
+// storedLocalFile is a current local_file_rows row.
+type storedLocalFile struct {
+	id        int64
+	gen       int64 // Generation that wrote it
+	row       localRow
+	checksum  string
+	createdAt sql.NullString
+}
+
+// loadLocalRows reads the current local files, those no generation replaced
+// yet, by path: the files at paths, or all of them when paths is nil.
+func (s *Storage) loadLocalRows(ctx context.Context, paths []string) (map[string]storedLocalFile, error) {
+	query := `
+		SELECT file_path, id, gen_from, file_name, relative_path, size, allocated, category, device, inode, links, mtime,
+			checksum, CAST(created_at AS TEXT)
+		FROM local_file_rows WHERE gen_to = 0`
+	if paths == nil {
+		rows, err := s.read.QueryContext(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query local files: %w", err)
+		}
+		existing := make(map[string]storedLocalFile)
+		if err := scanLocalRows(rows, existing); err != nil {
+			return nil, err
+		}
+		return existing, nil
+	}
+
+	stmt, err := s.read.PrepareContext(ctx, query+" AND file_path = ?")
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+	existing := make(map[string]storedLocalFile, len(paths))
+	for _, path := range paths {
+		rows, err := stmt.QueryContext(ctx, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query local files: %w", err)
+		}
+		if err := scanLocalRows(rows, existing); err != nil {
+			return nil, err
+		}
+	}
+	return existing, nil
+}
+
+// scanLocalRows adds the local files of rows to existing, by path.
+func scanLocalRows(rows *sql.Rows, existing map[string]storedLocalFile) error {
+	defer rows.Close()
+	for rows.Next() {
+		var path string
+		var f storedLocalFile
+		r := &f.row
+		if err := rows.Scan(&path, &f.id, &f.gen, &r.fileName, &r.relativePath, &r.size, &r.allocated, &r.category, &r.device, &r.inode, &r.links, &r.mtime,
+			&f.checksum, &f.createdAt); err != nil {
+			return fmt.Errorf("failed to scan local file: %w", err)
+		}
+		existing[path] = f
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating local files: %w", err)
+	}
+	return nil
+}
+
+// torrentFileKey identifies a torrent file across syncs.
+type torrentFileKey struct {
+	instance string
+	hash     string
+	filePath string
+}
+
+// torrentFileRow holds the columns of a torrent_files row compared by a sync.
+type torrentFileRow struct {
+	torrentName  string
+	fileName     string
+	relativePath string
+	size         int64
+}
+
+// storedTorrentFile is a current torrent_file_rows row.
+type storedTorrentFile struct {
+	id        int64
+	row       torrentFileRow
+	createdAt sql.NullString
+}
+
+// loadTorrentFileRows reads the current torrent files, those no generation
+// replaced yet, by key. The ids of the rows duplicating the key of another
+// one are returned apart, to be removed.
+func (s *Storage) loadTorrentFileRows(ctx context.Context) (map[torrentFileKey]storedTorrentFile, []int64, error) {
+	rows, err := s.read.QueryContext(ctx, `
+		SELECT id, instance, torrent_hash, file_path, torrent_name, file_name, relative_path, size, CAST(created_at AS TEXT)
+		FROM torrent_file_rows WHERE gen_to = 0`)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query torrent files: %w", err)
+	}
+	defer rows.Close()
+
+	existing := make(map[torrentFileKey]storedTorrentFile)
+	var duplicates []int64
+	for rows.Next() {
+		var k torrentFileKey
+		var f storedTorrentFile
+		if err := rows.Scan(&f.id, &k.instance, &k.hash, &k.filePath, &f.row.torrentName, &f.row.fileName, &f.row.relativePath, &f.row.size, &f.createdAt); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan torrent file: %w", err)
+		}
+		if _, ok := existing[k]; ok {
+			duplicates = append(duplicates, f.id)
+			continue
+		}
+		existing[k] = f
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error iterating torrent files: %w", err)
+	}
+	return existing, duplicates, nil
+}
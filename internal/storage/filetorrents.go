@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"godatacleaner/internal/models"
+)
+
+// GetFileTorrents returns the torrents expecting the local file at path (the
+// normalized file_path), with the same relative_path matching as orphan
+// detection: the torrents expecting the file itself, then those expecting one
+// of its hard links. The status tells why a file no torrent expects is kept
+// or not: still downloading, ignored, or an orphan. It returns ErrNotFound
+// when no local file has this path.
+func (s *Storage) GetFileTorrents(ctx context.Context, path string) (models.FileTorrentsResponse, error) {
+	resp := models.FileTorrentsResponse{Path: path, Torrents: []models.FileTorrent{}}
+
+	var downloading, ignored bool
+	err := s.read.QueryRowContext(ctx, `
+		SELECT `+incomplete+`, NOT `+notIgnored+`
+		FROM local_files l WHERE l.file_path = ?`, path).Scan(&downloading, &ignored)
+	if errors.Is(err, sql.ErrNoRows) {
+		return resp, ErrNotFound
+	}
+	if err != nil {
+		return resp, fmt.Errorf("failed to get local file: %w", err)
+	}
+
+	// Correspondances directes d'abord, puis via les liens physiques
+	rows, err := s.read.QueryContext(ctx, `
+		SELECT t.instance, t.torrent_hash, t.torrent_name, COALESCE(d.state, ''), t.file_path, m.hardlink
+		FROM (
+			SELECT t.id, '' AS hardlink, 0 AS rank
+			FROM local_files l
+			JOIN torrent_files t ON t.relative_path = l.relative_path
+			WHERE l.file_path = ?
+			UNION
+			SELECT t.id, h.file_path, 1
+			FROM local_files l
+			JOIN local_files h ON h.inode = l.inode AND h.device = l.device AND h.file_path != l.file_path
+			JOIN torrent_files t ON t.relative_path = h.relative_path
+			WHERE l.file_path = ? AND l.inode != 0
+		) m
+		JOIN torrent_files t ON t.id = m.id
+		LEFT JOIN torrents d ON d.instance = t.instance AND d.hash = t.torrent_hash
+		ORDER BY m.rank, t.torrent_name, t.instance, t.torrent_hash`, path, path)
+	if err != nil {
+		return resp, fmt.Errorf("failed to query file torrents: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t models.FileTorrent
+		if err := rows.Scan(&t.Instance, &t.Hash, &t.Name, &t.State, &t.FilePath, &t.Hardlink); err != nil {
+			return resp, fmt.Errorf("failed to scan file torrent: %w", err)
+		}
+		resp.Torrents = append(resp.Torrents, t)
+	}
+	if err := rows.Err(); err != nil {
+		return resp, fmt.Errorf("error iterating file torrents: %w", err)
+	}
+
+	// Même ordre de priorité que la détection des orphelins
+	switch {
+	case len(resp.Torrents) > 0:
+		resp.Status = models.FileStatusMatched
+	case downloading:
+		resp.Status = models.FileStatusDownloading
+	case ignored:
+		resp.Status = models.FileStatusIgnored
+	default:
+		resp.Status = models.FileStatusOrphan
+	}
+	return resp, nil
+}
+
+// maxNearMisses is the number of near misses GetFileDetail returns.
+const maxNearMisses = 20
+
+// GetFileDetail returns everything known about the local file at path, a
+// path on disk or already normalized: its stored form, relative path and
+// scan data, the torrents expecting it (see GetFileTorrents), and the near
+// misses, torrent files with the same name under another relative path, the
+// same size first. Near misses explain most false orphans: a torrent saved
+// under another folder, or a relative path marker missing from one side. It
+// returns ErrNotFound when no local file has this path.
+func (s *Storage) GetFileDetail(ctx context.Context, path string) (models.FileDetail, error) {
+	var d models.FileDetail
+	var mtime int64
+	err := s.read.QueryRowContext(ctx, `
+		SELECT file_path, file_name, relative_path, size, allocated, category, links, mtime, checksum
+		FROM local_files WHERE file_path = ?`, s.NormalizePath(path)).Scan(
+		&d.FilePath, &d.FileName, &d.RelativePath, &d.Size, &d.Allocated, &d.Category, &d.Links, &mtime, &d.Checksum)
+	if errors.Is(err, sql.ErrNoRows) {
+		return d, ErrNotFound
+	}
+	if err != nil {
+		return d, fmt.Errorf("failed to get local file: %w", err)
+	}
+	d.ModTime = fromUnixTime(mtime)
+
+	torrents, err := s.GetFileTorrents(ctx, d.FilePath)
+	if err != nil {
+		return d, err
+	}
+	d.Status, d.Torrents = torrents.Status, torrents.Torrents
+
+	rows, err := s.read.QueryContext(ctx, `
+		SELECT instance, torrent_hash, torrent_name, file_path, relative_path, size
+		FROM torrent_files
+		WHERE file_name = ? AND relative_path != ?
+		ORDER BY size != ?, torrent_name, file_path
+		LIMIT ?`, d.FileName, d.RelativePath, d.Size, maxNearMisses)
+	if err != nil {
+		return d, fmt.Errorf("failed to query near misses: %w", err)
+	}
+	defer rows.Close()
+
+	d.NearMisses = []models.NearMiss{}
+	for rows.Next() {
+		var m models.NearMiss
+		if err := rows.Scan(&m.Instance, &m.Hash, &m.Name, &m.FilePath, &m.RelativePath, &m.Size); err != nil {
+			return d, fmt.Errorf("failed to scan near miss: %w", err)
+		}
+		d.NearMisses = append(d.NearMisses, m)
+	}
+	if err := rows.Err(); err != nil {
+		return d, fmt.Errorf("error iterating near misses: %w", err)
+	}
+	return d, nil
+}
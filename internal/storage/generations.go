@@ -0,0 +1,316 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// The torrents, their trackers, the torrent files and the local files are
+// written by generation, one per sync (see SyncTx). Their rows are stored in
+// the *_rows tables, tagged with the generation that wrote them (gen_from)
+// and the one that replaced or removed them (gen_to, 0 while they are
+// current). The tables of the queries, torrents, torrent_trackers,
+// torrent_files and local_files, are views of the rows of the latest
+// completed generation: a sync writes its rows in batches, none of them
+// visible until the generation is completed, and the rows it replaces are
+// collected once it is.
+
+// currentGeneration is the latest completed generation, 0 before the first
+// sync.
+const currentGeneration = `(SELECT COALESCE(MAX(id), 0) FROM sync_generations WHERE completed_at != 0)`
+
+// visibleRow is the condition on a *_rows row that it belongs to the latest
+// completed generation.
+const visibleRow = `gen_from <= ` + currentGeneration + ` AND (gen_to = 0 OR gen_to > ` + currentGeneration + `)`
+
+// generationTable is a table written by generation.
+type generationTable struct {
+	view    string // Read by the queries
+	rows    string // Holding the rows of every generation
+	columns string // Of the view
+	// Columns added to the table of the view before it was split into
+	// generations, as in the databases created since
+	legacy []legacyColumn
+	// Columns added to the rows table since, also added to the table of the
+	// view before it is moved into it
+	added []legacyColumn
+}
+
+type legacyColumn struct {
+	column, definition string
+}
+
+var generationTables = []generationTable{
+	{
+		view: "torrents", rows: "torrent_rows",
+		columns: "instance, hash, name, save_path, local_save_path, size, state, ratio, seeding_time, tracker_message, content_path, files_synced_at",
+		legacy: []legacyColumn{
+			{"state", "TEXT NOT NULL DEFAULT ''"},
+			{"ratio", "REAL NOT NULL DEFAULT 0"},
+			{"seeding_time", "INTEGER NOT NULL DEFAULT 0"},
+			{"tracker_message", "TEXT NOT NULL DEFAULT ''"},
+			{"local_save_path", "TEXT NOT NULL DEFAULT ''"}, // Filled by the next sync
+		},
+		// Renseignés par la prochaine synchronisation, qui redemande les fichiers
+		// de tous les torrents
+		added: []legacyColumn{
+			{"content_path", "TEXT NOT NULL DEFAULT ''"},
+			{"files_synced_at", "INTEGER NOT NULL DEFAULT 0"}, // Unix seconds
+		},
+	},
+	{
+		view: "torrent_trackers", rows: "torrent_tracker_rows",
+		columns: "instance, hash, tracker",
+	},
+	{
+		view: "torrent_files", rows: "torrent_file_rows",
+		columns: "id, torrent_hash, torrent_name, file_name, file_path, relative_path, size, instance, created_at",
+		legacy:  []legacyColumn{{"instance", "TEXT NOT NULL DEFAULT ''"}},
+	},
+	{
+		view: "local_files", rows: "local_file_rows",
+		columns: "id, file_path, file_name, relative_path, size, allocated, category, device, inode, links, mtime, checksum, created_at",
+		legacy: []legacyColumn{
+			{"device", "INTEGER NOT NULL DEFAULT 0"},
+			{"inode", "INTEGER NOT NULL DEFAULT 0"},
+			{"links", "INTEGER NOT NULL DEFAULT 0"},
+			{"mtime", "INTEGER NOT NULL DEFAULT 0"}, // Unix seconds
+			{"checksum", "TEXT NOT NULL DEFAULT ''"},
+			{"allocated", "INTEGER NOT NULL DEFAULT 0"},
+		},
+	},
+}
+
+// generationSchema creates the generations and the *_rows tables.
+var generationSchema = []string{
+	// Générations des tables synchronisées, une par synchronisation
+	`CREATE TABLE IF NOT EXISTS sync_generations (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		started_at INTEGER NOT NULL, -- Unix seconds
+		completed_at INTEGER NOT NULL DEFAULT 0 -- Unix seconds, 0 until the sync writing it commits
+	)`,
+
+	// Torrents de la dernière synchronisation, pour ne rafraîchir que les fichiers
+	// des torrents modifiés
+	`CREATE TABLE IF NOT EXISTS torrent_rows (
+		instance TEXT NOT NULL,
+		hash TEXT NOT NULL,
+		name TEXT NOT NULL,
+		save_path TEXT NOT NULL,
+		local_save_path TEXT NOT NULL DEFAULT '',
+		size INTEGER NOT NULL,
+		state TEXT NOT NULL DEFAULT '',
+		ratio REAL NOT NULL DEFAULT 0,
+		seeding_time INTEGER NOT NULL DEFAULT 0,
+		tracker_message TEXT NOT NULL DEFAULT '',
+		content_path TEXT NOT NULL DEFAULT '',
+		files_synced_at INTEGER NOT NULL DEFAULT 0, -- Unix seconds
+		gen_from INTEGER NOT NULL DEFAULT 0,
+		gen_to INTEGER NOT NULL DEFAULT 0,
+		UNIQUE (instance, hash, gen_from)
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_torrent_rows_state ON torrent_rows(state)`,
+
+	// Trackers (hôtes) des torrents
+	`CREATE TABLE IF NOT EXISTS torrent_tracker_rows (
+		instance TEXT NOT NULL,
+		hash TEXT NOT NULL,
+		tracker TEXT NOT NULL,
+		gen_from INTEGER NOT NULL DEFAULT 0,
+		gen_to INTEGER NOT NULL DEFAULT 0,
+		UNIQUE (instance, hash, tracker, gen_from)
+	)`,
+
+	// Fichiers des torrents
+	`CREATE TABLE IF NOT EXISTS torrent_file_rows (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		torrent_hash TEXT NOT NULL,
+		torrent_name TEXT NOT NULL,
+		file_name TEXT NOT NULL,
+		file_path TEXT NOT NULL,
+		relative_path TEXT NOT NULL,
+		size INTEGER NOT NULL,
+		instance TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		gen_from INTEGER NOT NULL DEFAULT 0,
+		gen_to INTEGER NOT NULL DEFAULT 0
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_torrent_file_rows_hash ON torrent_file_rows(torrent_hash)`,
+	`CREATE INDEX IF NOT EXISTS idx_torrent_file_rows_path ON torrent_file_rows(file_path)`,
+	`CREATE INDEX IF NOT EXISTS idx_torrent_file_rows_name ON torrent_file_rows(file_name)`,
+	// Index sur relative_path pour les JOINs orphelins
+	`CREATE INDEX IF NOT EXISTS idx_torrent_file_rows_relative_path ON torrent_file_rows(relative_path)`,
+
+	// Fichiers locaux, un seul par chemin et par génération
+	`CREATE TABLE IF NOT EXISTS local_file_rows (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		file_path TEXT NOT NULL,
+		file_name TEXT NOT NULL,
+		relative_path TEXT NOT NULL,
+		size INTEGER NOT NULL,
+		allocated INTEGER NOT NULL DEFAULT 0,
+		category TEXT NOT NULL,
+		device INTEGER NOT NULL DEFAULT 0,
+		inode INTEGER NOT NULL DEFAULT 0,
+		links INTEGER NOT NULL DEFAULT 0,
+		mtime INTEGER NOT NULL DEFAULT 0, -- Unix seconds
+		checksum TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		gen_from INTEGER NOT NULL DEFAULT 0,
+		gen_to INTEGER NOT NULL DEFAULT 0,
+		UNIQUE (file_path, gen_from)
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_local_file_rows_category ON local_file_rows(category)`,
+	`CREATE INDEX IF NOT EXISTS idx_local_file_rows_name ON local_file_rows(file_name)`,
+	// Index sur relative_path pour les JOINs orphelins
+	`CREATE INDEX IF NOT EXISTS idx_local_file_rows_relative_path ON local_file_rows(relative_path)`,
+	// Liens physiques d'un même fichier, et fichiers de même contenu
+	`CREATE INDEX IF NOT EXISTS idx_local_file_rows_inode ON local_file_rows(inode, device)`,
+	`CREATE INDEX IF NOT EXISTS idx_local_file_rows_checksum ON local_file_rows(checksum)`,
+}
+
+// initGenerations creates the *_rows tables, moves into them the rows of the
+// tables of a database created before the generations, and creates the views
+// read by the queries.
+func (s *Storage) initGenerations(ctx context.Context) error {
+	stmts := generationSchema
+	for _, t := range generationTables {
+		// Lignes remplacées, à collecter une fois leur génération terminée
+		stmts = append(stmts, fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_gen_to ON %s(gen_to) WHERE gen_to != 0", t.rows, t.rows))
+	}
+	for _, stmt := range stmts {
+		if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to execute statement: %w", err)
+		}
+	}
+
+	for _, t := range generationTables {
+		for _, c := range t.added {
+			if _, err := s.addColumn(ctx, t.rows, c.column, c.definition); err != nil {
+				return err
+			}
+		}
+		if err := s.migrateLegacyTable(ctx, t); err != nil {
+			return err
+		}
+		// Les vues sont recréées pour suivre les colonnes des tables
+		if _, err := s.db.ExecContext(ctx, "DROP VIEW IF EXISTS "+t.view); err != nil {
+			return fmt.Errorf("failed to drop view %s: %w", t.view, err)
+		}
+		if _, err := s.db.ExecContext(ctx, fmt.Sprintf("CREATE VIEW %s AS SELECT %s FROM %s WHERE %s",
+			t.view, t.columns, t.rows, visibleRow)); err != nil {
+			return fmt.Errorf("failed to create view %s: %w", t.view, err)
+		}
+	}
+	return nil
+}
+
+// migrateLegacyTable moves the rows of the table t.view of a database
+// created before the generations into t.rows, as rows of generation 0, and
+// drops it for the view.
+func (s *Storage) migrateLegacyTable(ctx context.Context, t generationTable) error {
+	var kind string
+	err := s.db.QueryRowContext(ctx, "SELECT type FROM sqlite_master WHERE name = ?", t.view).Scan(&kind)
+	if errors.Is(err, sql.ErrNoRows) || kind == "view" {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read schema: %w", err)
+	}
+
+	for _, c := range slices.Concat(t.legacy, t.added) {
+		added, err := s.addColumn(ctx, t.view, c.column, c.definition)
+		if err != nil {
+			return err
+		}
+		// Taille allouée sur le disque : les fichiers scannés avant son ajout prennent
+		// leur taille logique, conservée par les scans incrémentaux jusqu'à un scan complet
+		if added && c.column == "allocated" {
+			if _, err := s.db.ExecContext(ctx, "UPDATE local_files SET allocated = size"); err != nil {
+				return fmt.Errorf("failed to fill column local_files.allocated: %w", err)
+			}
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s",
+		t.rows, t.columns, t.columns, t.view)); err != nil {
+		return fmt.Errorf("failed to move %s: %w", t.view, err)
+	}
+	if _, err := tx.ExecContext(ctx, "DROP TABLE "+t.view); err != nil {
+		return fmt.Errorf("failed to drop table %s: %w", t.view, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// discardGenerations removes the rows written by the generation gen, or by
+// every generation never completed when gen is 0, and restores the rows they
+// replaced.
+func (s *Storage) discardGenerations(ctx context.Context, gen int64) error {
+	cond, args := "IN (SELECT id FROM sync_generations WHERE completed_at = 0)", []any{}
+	if gen != 0 {
+		cond, args = "= ?", []any{gen}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, t := range generationTables {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+t.rows+" WHERE gen_from "+cond, args...); err != nil {
+			return fmt.Errorf("failed to discard rows of %s: %w", t.view, err)
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE "+t.rows+" SET gen_to = 0 WHERE gen_to "+cond, args...); err != nil {
+			return fmt.Errorf("failed to restore rows of %s: %w", t.view, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM sync_generations WHERE completed_at = 0 AND id "+cond, args...); err != nil {
+		return fmt.Errorf("failed to discard generations: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// collectGenerations removes the rows replaced or removed by the latest
+// completed generation or an earlier one, which no query reads any more, and
+// the generations before it.
+func (s *Storage) collectGenerations(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var current int64
+	if err := tx.QueryRowContext(ctx, "SELECT "+currentGeneration).Scan(&current); err != nil {
+		return fmt.Errorf("failed to read current generation: %w", err)
+	}
+	for _, t := range generationTables {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+t.rows+" WHERE gen_to != 0 AND gen_to <= ?", current); err != nil {
+			return fmt.Errorf("failed to collect rows of %s: %w", t.view, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM sync_generations WHERE id < ?", current); err != nil {
+		return fmt.Errorf("failed to collect generations: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"godatacleaner/internal/models"
+)
+
+// RecordLibraryHistory stores the file count and size of every category, and
+// of its orphans, for the day of now. A later call on the same day replaces
+// them, so each day keeps the state left by its last sync.
+func (s *Storage) RecordLibraryHistory(ctx context.Context, now time.Time) error {
+	day := now.Format(time.DateOnly)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Les catégories disparues depuis la dernière synchronisation du jour ne
+	// doivent pas rester dans l'historique
+	if _, err := tx.ExecContext(ctx, "DELETE FROM library_history WHERE day = ?", day); err != nil {
+		return fmt.Errorf("failed to delete library history: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO library_history (day, category, file_count, total_size, orphan_files, orphan_size)
+		SELECT ?, l.category, COUNT(*), COALESCE(SUM(l.size), 0),
+			COUNT(CASE WHEN `+isOrphan+` THEN 1 END),
+			COALESCE(SUM(CASE WHEN `+isOrphan+` THEN l.size END), 0)
+		FROM local_files l
+		GROUP BY l.category
+	`, day)
+	if err != nil {
+		return fmt.Errorf("failed to insert library history: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetLibraryHistory returns the daily sizes of every category recorded by
+// RecordLibraryHistory since the day of since, oldest day first.
+func (s *Storage) GetLibraryHistory(ctx context.Context, since time.Time) ([]models.LibraryHistoryPoint, error) {
+	rows, err := s.read.QueryContext(ctx, `
+		SELECT day, category, file_count, total_size, orphan_files, orphan_size
+		FROM library_history
+		WHERE day >= ?
+		ORDER BY day ASC, category ASC
+	`, since.Format(time.DateOnly))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query library history: %w", err)
+	}
+	defer rows.Close()
+
+	var points []models.LibraryHistoryPoint
+	for rows.Next() {
+		var p models.LibraryHistoryPoint
+		if err := rows.Scan(&p.Day, &p.Category, &p.FileCount, &p.TotalSize, &p.OrphanFiles, &p.OrphanSize); err != nil {
+			return nil, fmt.Errorf("failed to scan library history: %w", err)
+		}
+		points = append(points, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating library history: %w", err)
+	}
+
+	return points, nil
+}
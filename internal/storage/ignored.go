@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"godatacleaner/internal/models"
+)
+
+// AddIgnoredPath adds a local file or folder, as listed among the orphans, to
+// the ignore list and returns its ID and stored form. It and everything below
+// it are no longer orphans: left out of the orphan lists and stats, and never
+// cleaned. p is normalized like the scanned paths (see WithPathRewrites).
+func (s *Storage) AddIgnoredPath(ctx context.Context, p string) (int64, string, error) {
+	defer s.counts.reset()
+
+	if !strings.HasPrefix(p, "/") {
+		return 0, "", fmt.Errorf("ignored path must be absolute: %s", p)
+	}
+	p = path.Clean(s.NormalizePath(p))
+	res, err := s.db.ExecContext(ctx, "INSERT INTO ignored_paths (path) VALUES (?)", p)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to insert ignored path: %w", err)
+	}
+	id, err := res.LastInsertId()
+	return id, p, err
+}
+
+// AddIgnoredPattern adds a glob to the ignore list and returns its ID and
+// stored form. The local files matching it, and everything below a folder
+// matching it, are no longer orphans. Patterns follow SQLite's GLOB, where *
+// and ? also match "/", and must be absolute or start with "*", such as
+// "*/Extras" or "*.nfo"; absolute ones are normalized like AddIgnoredPath.
+func (s *Storage) AddIgnoredPattern(ctx context.Context, pattern string) (int64, string, error) {
+	defer s.counts.reset()
+
+	pattern, err := normalizeIgnoredPattern(pattern, s.NormalizePath)
+	if err != nil {
+		return 0, "", err
+	}
+	res, err := s.db.ExecContext(ctx, "INSERT INTO ignored_paths (path, pattern) VALUES (?, 1)", pattern)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to insert ignored pattern: %w", err)
+	}
+	id, err := res.LastInsertId()
+	return id, pattern, err
+}
+
+// normalizeIgnoredPattern checks an ignore pattern and normalizes it when
+// absolute, see AddIgnoredPattern.
+func normalizeIgnoredPattern(pattern string, normalize func(string) string) (string, error) {
+	switch {
+	case strings.HasPrefix(pattern, "/"):
+		pattern = path.Clean(normalize(pattern))
+	case !strings.HasPrefix(pattern, "*"):
+		return "", fmt.Errorf("ignored pattern must be absolute or start with *: %s", pattern)
+	}
+	if _, err := globRegexp(pattern); err != nil {
+		return "", fmt.Errorf("invalid ignored pattern %s: %w", pattern, err)
+	}
+	return pattern, nil
+}
+
+// ValidIgnoredPattern reports whether AddIgnoredPattern accepts pattern.
+func ValidIgnoredPattern(pattern string) bool {
+	_, err := normalizeIgnoredPattern(pattern, func(p string) string { return p })
+	return err == nil
+}
+
+// globRegexp compiles a pattern of SQLite's GLOB into the regular expression
+// matching the same strings: * any run of characters, ? one character, and
+// [...] one character of a class, negated by a leading ^.
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("(?s)^")
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '[':
+			j := i + 1
+			if j < len(runes) && runes[j] == '^' {
+				j++
+			}
+			if j < len(runes) && runes[j] == ']' {
+				j++
+			}
+			for j < len(runes) && runes[j] != ']' {
+				j++
+			}
+			if j == len(runes) {
+				return nil, fmt.Errorf("unclosed [ in %s", pattern)
+			}
+			b.WriteByte('[')
+			for k, c := range runes[i+1 : j] {
+				if c == '^' && k == 0 || c == '-' {
+					b.WriteRune(c)
+				} else {
+					b.WriteString(regexp.QuoteMeta(string(c)))
+				}
+			}
+			b.WriteByte(']')
+			i = j
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// ListIgnoredPaths returns the ignore list, with the local files each entry
+// ignores and the orphans among them it hides.
+func (s *Storage) ListIgnoredPaths(ctx context.Context) ([]models.IgnoredPath, error) {
+	rows, err := s.read.QueryContext(ctx, `
+		SELECT i.id, i.path, i.pattern, COUNT(l.file_path), COALESCE(SUM(l.size), 0),
+			COALESCE(SUM(CASE WHEN `+orphanUnlessIgnored+` THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN `+orphanUnlessIgnored+` THEN l.size ELSE 0 END), 0),
+			i.created_at
+		FROM ignored_paths i
+		LEFT JOIN local_files l ON `+ignoredBy+`
+		GROUP BY i.id
+		ORDER BY i.path ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ignored paths: %w", err)
+	}
+	defer rows.Close()
+
+	var paths []models.IgnoredPath
+	for rows.Next() {
+		var p models.IgnoredPath
+		if err := rows.Scan(&p.ID, &p.Path, &p.Pattern, &p.FileCount, &p.TotalSize, &p.OrphanFiles, &p.OrphanSize, &p.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan ignored path: %w", err)
+		}
+		paths = append(paths, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating ignored paths: %w", err)
+	}
+
+	return paths, nil
+}
+
+// RemoveIgnoredPath deletes an entry of the ignore list: the files it
+// ignored are orphans again if no torrent expects them.
+func (s *Storage) RemoveIgnoredPath(ctx context.Context, id int64) error {
+	defer s.counts.reset()
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM ignored_paths WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete ignored path: %w", err)
+	}
+	return checkAffected(res)
+}
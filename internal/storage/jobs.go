@@ -0,0 +1,244 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"godatacleaner/internal/models"
+)
+
+// ErrNotFound is returned when a requested record does not exist.
+var ErrNotFound = errors.New("record not found")
+
+// CreateCleanupJob inserts a new cleanup job and returns its ID.
+func (s *Storage) CreateCleanupJob(ctx context.Context, job models.CleanupJob) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO cleanup_jobs (name, schedule, category, quarantine, archive, companions, dry_run, enabled)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, job.Name, job.Schedule, job.Category, job.Quarantine, job.Archive, job.Companions, job.DryRun, job.Enabled)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert cleanup job: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+// GetCleanupJob retrieves a cleanup job by ID.
+func (s *Storage) GetCleanupJob(ctx context.Context, id int64) (*models.CleanupJob, error) {
+	row := s.read.QueryRowContext(ctx, `
+		SELECT id, name, schedule, category, quarantine, archive, companions, dry_run, enabled, last_run_at, created_at
+		FROM cleanup_jobs WHERE id = ?
+	`, id)
+
+	job, err := scanCleanupJob(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cleanup job: %w", err)
+	}
+	return job, nil
+}
+
+// ListCleanupJobs returns all cleanup jobs ordered by ID.
+func (s *Storage) ListCleanupJobs(ctx context.Context) ([]models.CleanupJob, error) {
+	rows, err := s.read.QueryContext(ctx, `
+		SELECT id, name, schedule, category, quarantine, archive, companions, dry_run, enabled, last_run_at, created_at
+		FROM cleanup_jobs ORDER BY id ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cleanup jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []models.CleanupJob
+	for rows.Next() {
+		job, err := scanCleanupJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan cleanup job: %w", err)
+		}
+		jobs = append(jobs, *job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating cleanup jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// SetCleanupJobEnabled enables or disables a cleanup job.
+func (s *Storage) SetCleanupJobEnabled(ctx context.Context, id int64, enabled bool) error {
+	res, err := s.db.ExecContext(ctx, "UPDATE cleanup_jobs SET enabled = ? WHERE id = ?", enabled, id)
+	if err != nil {
+		return fmt.Errorf("failed to update cleanup job: %w", err)
+	}
+	return checkAffected(res)
+}
+
+// SetCleanupJobSchedule changes the schedule of a cleanup job. The schedule
+// must have been checked with scheduler.ValidateSchedule.
+func (s *Storage) SetCleanupJobSchedule(ctx context.Context, id int64, schedule string) error {
+	res, err := s.db.ExecContext(ctx, "UPDATE cleanup_jobs SET schedule = ? WHERE id = ?", schedule, id)
+	if err != nil {
+		return fmt.Errorf("failed to update cleanup job: %w", err)
+	}
+	return checkAffected(res)
+}
+
+// DeleteCleanupJob removes a cleanup job. Its run history is kept.
+func (s *Storage) DeleteCleanupJob(ctx context.Context, id int64) error {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM cleanup_jobs WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete cleanup job: %w", err)
+	}
+	return checkAffected(res)
+}
+
+// RecordCleanupRun stores a cleanup run with the files it handled,
+// and updates the last run time of its job, if any.
+func (s *Storage) RecordCleanupRun(ctx context.Context, run models.CleanupRun, files []models.CleanupRunFile) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO cleanup_runs (source, job_id, username, started_at, finished_at, dry_run, quarantine, files_removed, bytes_reclaimed, failed, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, run.Source, run.JobID, run.User, run.StartedAt, run.FinishedAt, run.DryRun, run.Quarantine, run.FilesRemoved, run.BytesReclaimed, run.Failed, run.Error)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert cleanup run: %w", err)
+	}
+	runID, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get cleanup run id: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO cleanup_run_files (run_id, file_path, size, moved_to, error)
+		VALUES (?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, f := range files {
+		if _, err := stmt.ExecContext(ctx, runID, f.FilePath, f.Size, f.MovedTo, f.Error); err != nil {
+			return 0, fmt.Errorf("failed to insert cleanup run file: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE cleanup_jobs SET last_run_at = ? WHERE id = ?", run.StartedAt, run.JobID); err != nil {
+		return 0, fmt.Errorf("failed to update cleanup job: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return runID, nil
+}
+
+// ListCleanupRuns returns the most recent cleanup runs, newest first, with
+// the name of their job. If jobID is not zero, only runs of that job are
+// returned.
+func (s *Storage) ListCleanupRuns(ctx context.Context, jobID int64, limit int) ([]models.CleanupRun, error) {
+	if limit < 1 {
+		limit = 50
+	}
+
+	query := `
+		SELECT r.id, r.source, r.job_id, COALESCE(j.name, ''), r.username, r.started_at, r.finished_at,
+			r.dry_run, r.quarantine, r.files_removed, r.bytes_reclaimed, r.failed, r.error
+		FROM cleanup_runs r
+		LEFT JOIN cleanup_jobs j ON j.id = r.job_id`
+	var args []interface{}
+	if jobID != 0 {
+		query += " WHERE r.job_id = ?"
+		args = append(args, jobID)
+	}
+	query += " ORDER BY r.id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := s.read.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cleanup runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []models.CleanupRun
+	for rows.Next() {
+		var r models.CleanupRun
+		if err := rows.Scan(&r.ID, &r.Source, &r.JobID, &r.JobName, &r.User, &r.StartedAt, &r.FinishedAt, &r.DryRun, &r.Quarantine, &r.FilesRemoved, &r.BytesReclaimed, &r.Failed, &r.Error); err != nil {
+			return nil, fmt.Errorf("failed to scan cleanup run: %w", err)
+		}
+		runs = append(runs, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating cleanup runs: %w", err)
+	}
+
+	return runs, nil
+}
+
+// GetCleanupRunFiles returns the files handled by a cleanup run.
+func (s *Storage) GetCleanupRunFiles(ctx context.Context, runID int64) ([]models.CleanupRunFile, error) {
+	rows, err := s.read.QueryContext(ctx, `
+		SELECT run_id, file_path, size, moved_to, error
+		FROM cleanup_run_files WHERE run_id = ? ORDER BY id ASC
+	`, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cleanup run files: %w", err)
+	}
+	defer rows.Close()
+
+	var files []models.CleanupRunFile
+	for rows.Next() {
+		var f models.CleanupRunFile
+		if err := rows.Scan(&f.RunID, &f.FilePath, &f.Size, &f.MovedTo, &f.Error); err != nil {
+			return nil, fmt.Errorf("failed to scan cleanup run file: %w", err)
+		}
+		files = append(files, f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating cleanup run files: %w", err)
+	}
+
+	return files, nil
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanCleanupJob(row rowScanner) (*models.CleanupJob, error) {
+	var job models.CleanupJob
+	var lastRun sql.NullTime
+	if err := row.Scan(&job.ID, &job.Name, &job.Schedule, &job.Category, &job.Quarantine, &job.Archive, &job.Companions, &job.DryRun, &job.Enabled, &lastRun, &job.CreatedAt); err != nil {
+		return nil, err
+	}
+	if lastRun.Valid {
+		t := lastRun.Time
+		job.LastRunAt = &t
+	}
+	return &job, nil
+}
+
+// checkAffected returns ErrNotFound when a statement did not touch any row.
+func checkAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
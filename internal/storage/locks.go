@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// AcquireLock takes the named lock for owner until ttl expires.
+// It returns false when another owner holds a lock that has not expired yet.
+// A lock left behind by a crashed process is taken over once expired.
+func (s *Storage) AcquireLock(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO locks (name, owner, acquired_at, expires_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET owner = excluded.owner, acquired_at = excluded.acquired_at, expires_at = excluded.expires_at
+		WHERE locks.expires_at < ? OR locks.owner = excluded.owner
+	`, name, owner, now.UnixMilli(), now.Add(ttl).UnixMilli(), now.UnixMilli())
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	return n > 0, nil
+}
+
+// RefreshLock extends a lock held by owner. It returns false if the lock was lost.
+func (s *Storage) RefreshLock(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE locks SET expires_at = ? WHERE name = ? AND owner = ?",
+		time.Now().Add(ttl).UnixMilli(), name, owner)
+	if err != nil {
+		return false, fmt.Errorf("failed to refresh lock: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to refresh lock: %w", err)
+	}
+	return n > 0, nil
+}
+
+// ReleaseLock releases a lock held by owner.
+func (s *Storage) ReleaseLock(ctx context.Context, name, owner string) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM locks WHERE name = ? AND owner = ?", name, owner); err != nil {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,822 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"godatacleaner/internal/models"
+)
+
+// Memory is an in-memory Store, for the programs embedding the cleaner
+// without SQLite and for unit tests. It holds the torrents and files it is
+// given, and finds the orphans, the dead and the missing torrents with the
+// same rules as the SQLite backend. Nothing is persisted, and no sync history
+// is recorded.
+type Memory struct {
+	pathConfig
+
+	mu           sync.RWMutex
+	torrents     map[torrentKey]models.Torrent
+	torrentFiles []memoryTorrentFile
+	localFiles   map[string]memoryLocalFile // By normalized file path
+	orphanedAt   map[string]time.Time       // First time orphan, see UpdateOrphans
+	protected    []models.ProtectedPath
+	ignored      []models.IgnoredPath
+	jobs         []models.CleanupJob
+	runs         []models.CleanupRun
+	runFiles     map[int64][]models.CleanupRunFile // By run ID
+	quarantine   []models.QuarantineEntry
+	users        []models.User
+	sessions     map[string]models.Session // By token hash
+	apiKeys      []memoryAPIKey
+	views        []models.SavedView
+	layouts      map[string]map[string][]string // By username, then list
+	lastID       int64
+}
+
+type torrentKey struct {
+	instance, hash string
+}
+
+type memoryTorrentFile struct {
+	models.TorrentFile
+	relativePath string
+}
+
+type memoryLocalFile struct {
+	models.LocalFile
+	relativePath string
+}
+
+type memoryAPIKey struct {
+	models.APIKey
+	hash string
+}
+
+// NewMemory creates an empty in-memory store.
+func NewMemory() *Memory {
+	return &Memory{
+		torrents:   make(map[torrentKey]models.Torrent),
+		localFiles: make(map[string]memoryLocalFile),
+		orphanedAt: make(map[string]time.Time),
+		runFiles:   make(map[int64][]models.CleanupRunFile),
+		sessions:   make(map[string]models.Session),
+		layouts:    make(map[string]map[string][]string),
+	}
+}
+
+// WithUnicodeNormalization is Storage.WithUnicodeNormalization.
+func (m *Memory) WithUnicodeNormalization(enabled bool) *Memory {
+	m.pathConfig.mu.Lock()
+	defer m.pathConfig.mu.Unlock()
+	m.nfc = enabled
+	return m
+}
+
+// WithPathRewrites is Storage.WithPathRewrites.
+func (m *Memory) WithPathRewrites(rules []PathRewrite) *Memory {
+	m.pathConfig.mu.Lock()
+	defer m.pathConfig.mu.Unlock()
+	m.rewrites = rules
+	return m
+}
+
+// WithRelativeMarkers is Storage.WithRelativeMarkers.
+func (m *Memory) WithRelativeMarkers(markers []string) *Memory {
+	m.pathConfig.mu.Lock()
+	defer m.pathConfig.mu.Unlock()
+	m.markers = markers
+	return m
+}
+
+func (m *Memory) nextID() int64 {
+	m.lastID++
+	return m.lastID
+}
+
+// SetTorrents replaces the torrents and their files, as a sync does.
+func (m *Memory) SetTorrents(torrents []models.Torrent, files []models.TorrentFile) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.torrents = make(map[torrentKey]models.Torrent, len(torrents))
+	for _, t := range torrents {
+		m.torrents[torrentKey{t.Instance, t.Hash}] = t
+	}
+	m.torrentFiles = make([]memoryTorrentFile, len(files))
+	for i, f := range files {
+		f.ID = int64(i + 1)
+		m.torrentFiles[i] = memoryTorrentFile{f, m.relativePath(m.NormalizePath(f.FilePath))}
+	}
+}
+
+// InsertLocalFiles adds local files, or updates those already known.
+func (m *Memory) InsertLocalFiles(ctx context.Context, files []models.LocalFile) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, f := range files {
+		f.FilePath = m.NormalizePath(f.FilePath)
+		if old, ok := m.localFiles[f.FilePath]; ok {
+			f.ID = old.ID
+		} else {
+			f.ID = m.nextID()
+		}
+		m.localFiles[f.FilePath] = memoryLocalFile{f, m.relativePath(f.FilePath)}
+	}
+	return nil
+}
+
+// DeleteLocalFiles removes the given local files.
+func (m *Memory) DeleteLocalFiles(ctx context.Context, paths []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, p := range paths {
+		delete(m.localFiles, p)
+	}
+	return nil
+}
+
+// UpdateOrphans records now as the time the new orphans became orphans, and
+// forgets the files no longer orphans, as a sync does (see
+// SyncTx.Commit).
+func (m *Memory) UpdateOrphans(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expected := m.expectedPaths()
+	for p := range m.orphanedAt {
+		if f, ok := m.localFiles[p]; !ok || !m.isOrphan(f, expected) {
+			delete(m.orphanedAt, p)
+		}
+	}
+	for p, f := range m.localFiles {
+		if _, ok := m.orphanedAt[p]; !ok && m.isOrphan(f, expected) {
+			m.orphanedAt[p] = now
+		}
+	}
+}
+
+// expectedPaths returns the relative paths expected by a torrent.
+func (m *Memory) expectedPaths() map[string]bool {
+	expected := make(map[string]bool, len(m.torrentFiles))
+	for _, f := range m.torrentFiles {
+		expected[f.relativePath] = true
+	}
+	return expected
+}
+
+// hardlinked reports whether a hard link of f is expected by a torrent.
+func (m *Memory) hardlinked(f memoryLocalFile, expected map[string]bool) bool {
+	if f.Inode == 0 {
+		return false
+	}
+	for _, h := range m.localFiles {
+		if h.Inode == f.Inode && h.Device == f.Device && h.FilePath != f.FilePath && expected[h.relativePath] {
+			return true
+		}
+	}
+	return false
+}
+
+// incomplete reports whether f, expected by no torrent, is still being
+// downloaded, see incomplete.
+func (m *Memory) incomplete(f memoryLocalFile) bool {
+	name := strings.ToLower(f.FileName)
+	if strings.HasSuffix(name, ".!qb") || strings.HasSuffix(name, ".parts") {
+		return true
+	}
+	for _, tf := range m.torrentFiles {
+		t, ok := m.torrents[torrentKey{tf.Instance, tf.TorrentHash}]
+		if !ok || t.State != models.TorrentStateDownloading {
+			continue
+		}
+		folder := tf.relativePath[:strings.LastIndex(tf.relativePath, "/")+1]
+		if tf.FilePath != strings.TrimRight(t.LocalSavePath, "/")+"/"+tf.FileName && strings.HasPrefix(f.relativePath, folder) {
+			return true
+		}
+	}
+	return false
+}
+
+// isIgnored reports whether f is in the ignore list, see notIgnored.
+func (m *Memory) isIgnored(f memoryLocalFile) bool {
+	return slices.ContainsFunc(m.ignored, func(i models.IgnoredPath) bool { return ignores(i, f) })
+}
+
+// ignores reports whether the entry i of the ignore list covers f, see
+// ignoredBy.
+func ignores(i models.IgnoredPath, f memoryLocalFile) bool {
+	if !i.Pattern {
+		return f.FilePath == i.Path || strings.HasPrefix(f.FilePath, i.Path+"/")
+	}
+	for _, p := range []string{i.Path, i.Path + "/*"} {
+		if re, err := globRegexp(p); err == nil && re.MatchString(f.FilePath) {
+			return true
+		}
+	}
+	return false
+}
+
+// isOrphan reports whether f is an orphan, see isOrphan.
+func (m *Memory) isOrphan(f memoryLocalFile, expected map[string]bool) bool {
+	return !expected[f.relativePath] && !m.hardlinked(f, expected) && !m.incomplete(f) && !m.isIgnored(f)
+}
+
+// status returns the models.FileStatus of f, see fileStatus.
+func (m *Memory) status(f memoryLocalFile, expected map[string]bool) string {
+	switch {
+	case expected[f.relativePath] || m.hardlinked(f, expected):
+		return models.FileStatusMatched
+	case m.incomplete(f):
+		return models.FileStatusDownloading
+	case m.isIgnored(f):
+		return models.FileStatusIgnored
+	}
+	return models.FileStatusOrphan
+}
+
+// HasTorrentFile reports whether a local path (normalized) is expected by a
+// torrent, directly or through a hard link.
+func (m *Memory) HasTorrentFile(ctx context.Context, localPath string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	expected := m.expectedPaths()
+	if expected[m.relativePath(localPath)] {
+		return true, nil
+	}
+	f, ok := m.localFiles[localPath]
+	return ok && m.hardlinked(f, expected), nil
+}
+
+// containsFold is the LIKE '%substr%' of SQLite, which ignores the case of
+// ASCII letters.
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// matchesOptions reports whether f passes the search, category, age and size
+// filters of opts.
+func matchesOptions(f models.LocalFile, opts models.QueryOptions, now time.Time) bool {
+	if opts.Search != "" && !containsFold(f.FileName, opts.Search) && !containsFold(f.FilePath, opts.Search) {
+		return false
+	}
+	if opts.Category != "" && f.Category != opts.Category {
+		return false
+	}
+	mtime := unixTime(f.ModTime)
+	if opts.MinAge > 0 && (mtime == 0 || mtime > now.Add(-opts.MinAge).Unix()) {
+		return false
+	}
+	if opts.MaxAge > 0 && mtime < now.Add(-opts.MaxAge).Unix() {
+		return false
+	}
+	if (opts.MinSize > 0 && f.Size < opts.MinSize) || (opts.MaxSize > 0 && f.Size > opts.MaxSize) {
+		return false
+	}
+	if opts.ExcludeHardlinked && f.Links > 1 {
+		return false
+	}
+	return true
+}
+
+func orphanFile(f memoryLocalFile) models.OrphanFile {
+	return models.OrphanFile{
+		ID:        f.ID,
+		FilePath:  f.FilePath,
+		FileName:  f.FileName,
+		Size:      f.Size,
+		Allocated: f.Allocated,
+		Category:  f.Category,
+		Links:     f.Links,
+		ModTime:   f.ModTime,
+
+		RelativePath: f.relativePath,
+	}
+}
+
+// orphans returns the orphans matching the filters of opts, unsorted, see
+// orphanFilter.
+func (m *Memory) orphans(opts models.QueryOptions, now time.Time) []models.OrphanFile {
+	expected := m.expectedPaths()
+	var files []models.OrphanFile
+	for _, f := range m.localFiles {
+		if !m.isOrphan(f, expected) || !matchesOptions(f.LocalFile, opts, now) {
+			continue
+		}
+		orphan := orphanFile(f)
+		orphan.OrphanedAt = m.orphanedAt[f.FilePath]
+		if opts.MinOrphanAge > 0 && (orphan.OrphanedAt.IsZero() || orphan.OrphanedAt.After(now.Add(-opts.MinOrphanAge))) {
+			continue
+		}
+		files = append(files, orphan)
+	}
+	return files
+}
+
+// GetOrphanFiles retrieves orphan files with pagination, as Storage.GetOrphanFiles.
+func (m *Memory) GetOrphanFiles(ctx context.Context, opts models.QueryOptions) ([]models.OrphanFile, int64, error) {
+	opts = normalizeQueryOptions(opts)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	files := m.orphans(opts, time.Now())
+
+	// Par défaut les plus gros d'abord, comme en SQL
+	if _, ok := allowedOrphanColumns[opts.Sort]; !ok {
+		opts.Sort, opts.Order = "size", "desc"
+	}
+	numeric := !slices.Contains([]string{"file_path", "file_name", "category"}, opts.Sort)
+	page := keysetPage(files, opts, numeric, func(f models.OrphanFile) *models.Cursor { return OrphanFileCursor(opts.Sort, f) })
+	return page, int64(len(files)), nil
+}
+
+// keysetPage sorts items on their cursor, the value of the sort column then
+// the ID, in the order of opts, and returns the page selected by opts: the
+// items following opts.After when set, as keyset does.
+func keysetPage[T any](items []T, opts models.QueryOptions, numeric bool, cursor func(T) *models.Cursor) []T {
+	compare := func(a, b models.Cursor) int {
+		if opts.Order == "desc" {
+			return compareCursor(b, a, numeric)
+		}
+		return compareCursor(a, b, numeric)
+	}
+	slices.SortFunc(items, func(a, b T) int { return compare(*cursor(a), *cursor(b)) })
+
+	if opts.After != nil {
+		start, _ := slices.BinarySearchFunc(items, *opts.After, func(item T, after models.Cursor) int {
+			if c := compare(*cursor(item), after); c != 0 {
+				return c
+			}
+			return -1
+		})
+		items = items[start:]
+		opts.Page = 1
+	}
+	return paginate(items, opts)
+}
+
+// paginate returns the page of items selected by opts.
+func paginate[T any](items []T, opts models.QueryOptions) []T {
+	start := min((opts.Page-1)*opts.PerPage, len(items))
+	end := min(start+opts.PerPage, len(items))
+	return items[start:end]
+}
+
+// GetOrphanFilesByPath returns the files among paths that are orphans.
+func (m *Memory) GetOrphanFilesByPath(ctx context.Context, paths []string) ([]models.OrphanFile, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	expected := m.expectedPaths()
+	var files []models.OrphanFile
+	for _, p := range paths {
+		if f, ok := m.localFiles[p]; ok && m.isOrphan(f, expected) {
+			files = append(files, orphanFile(f))
+		}
+	}
+	slices.SortFunc(files, func(a, b models.OrphanFile) int { return cmp.Compare(a.FilePath, b.FilePath) })
+	return slices.CompactFunc(files, func(a, b models.OrphanFile) bool { return a.FilePath == b.FilePath }), nil
+}
+
+// GetExtraFiles returns the local files of the extra pseudo-categories,
+// largest first, telling which ones are orphans.
+func (m *Memory) GetExtraFiles(ctx context.Context) ([]models.ExtraFile, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	expected := m.expectedPaths()
+	var files []models.ExtraFile
+	for _, f := range m.localFiles {
+		if !slices.Contains(models.ExtraCategories, f.Category) {
+			continue
+		}
+		files = append(files, models.ExtraFile{
+			FilePath:  f.FilePath,
+			FileName:  f.FileName,
+			Size:      f.Size,
+			Allocated: f.Allocated,
+			Category:  f.Category,
+			Orphan:    m.isOrphan(f, expected),
+		})
+	}
+	slices.SortFunc(files, func(a, b models.ExtraFile) int {
+		return cmp.Or(cmp.Compare(b.Size, a.Size), cmp.Compare(a.FilePath, b.FilePath))
+	})
+	return files, nil
+}
+
+// isDead reports whether t is a dead torrent, see GetDeadTorrents.
+func isDead(t models.Torrent, patterns []string) bool {
+	if t.TrackerMessage == "" {
+		return false
+	}
+	for _, p := range patterns {
+		if containsFold(t.TrackerMessage, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetDeadTorrents returns the torrents whose tracker message matches one of
+// patterns, largest first.
+func (m *Memory) GetDeadTorrents(ctx context.Context, patterns []string) ([]models.Torrent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var torrents []models.Torrent
+	for _, t := range m.torrents {
+		if isDead(t, patterns) {
+			torrents = append(torrents, t)
+		}
+	}
+	slices.SortFunc(torrents, func(a, b models.Torrent) int {
+		return cmp.Or(cmp.Compare(b.Size, a.Size), cmp.Compare(a.Hash, b.Hash))
+	})
+	return torrents, nil
+}
+
+// GetDeadTorrentFiles returns the local files expected by dead torrents only,
+// as Storage.GetDeadTorrentFiles.
+func (m *Memory) GetDeadTorrentFiles(ctx context.Context, patterns []string, opts models.QueryOptions) ([]models.OrphanFile, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// Un chemin attendu par un torrent inconnu compte comme attendu par un
+	// torrent vivant
+	dead := make(map[string]bool)
+	alive := make(map[string]bool)
+	for _, f := range m.torrentFiles {
+		t, ok := m.torrents[torrentKey{f.Instance, f.TorrentHash}]
+		if ok && isDead(t, patterns) {
+			dead[f.relativePath] = true
+		} else {
+			alive[f.relativePath] = true
+		}
+	}
+
+	now := time.Now()
+	var files []models.OrphanFile
+	for _, f := range m.localFiles {
+		if dead[f.relativePath] && !alive[f.relativePath] && matchesOptions(f.LocalFile, opts, now) {
+			files = append(files, orphanFile(f))
+		}
+	}
+	slices.SortFunc(files, func(a, b models.OrphanFile) int { return cmp.Compare(a.FilePath, b.FilePath) })
+	return files, nil
+}
+
+// keepFile reports whether filter keeps the torrent file f, see
+// fileFilterConditions.
+func keepFile(f models.TorrentFile, filter models.FileFilter) bool {
+	if filter.MinSize > 0 && f.Size < filter.MinSize {
+		return false
+	}
+	filtered := false
+	for _, ext := range filter.Extensions {
+		ext = strings.TrimPrefix(strings.TrimSpace(ext), ".")
+		if ext == "" {
+			continue
+		}
+		if strings.HasSuffix(strings.ToLower(f.FileName), "."+strings.ToLower(ext)) {
+			return true
+		}
+		filtered = true
+	}
+	return !filtered
+}
+
+// completeness returns the files of every torrent found locally, in the
+// order of the torrent files. Files filter leaves out are not counted.
+func (m *Memory) completeness(filter models.FileFilter) []models.TorrentCompleteness {
+	local := make(map[string]bool, len(m.localFiles))
+	for _, f := range m.localFiles {
+		local[f.relativePath] = true
+	}
+
+	var torrents []models.TorrentCompleteness
+	index := make(map[torrentKey]int)
+	for _, f := range m.torrentFiles {
+		if !keepFile(f.TorrentFile, filter) {
+			continue
+		}
+		key := torrentKey{f.Instance, f.TorrentHash}
+		i, ok := index[key]
+		if !ok {
+			i = len(torrents)
+			index[key] = i
+			torrents = append(torrents, models.TorrentCompleteness{
+				Instance: f.Instance,
+				Hash:     f.TorrentHash,
+				Name:     f.TorrentName,
+				State:    m.torrents[key].State,
+			})
+		}
+		t := &torrents[i]
+		t.FileCount++
+		t.TotalSize += f.Size
+		if local[f.relativePath] {
+			t.PresentFiles++
+		} else {
+			t.MissingBytes += f.Size
+		}
+	}
+	for i := range torrents {
+		t := &torrents[i]
+		if t.TotalSize > 0 {
+			t.Completeness = float64(t.TotalSize-t.MissingBytes) / float64(t.TotalSize) * 100
+		} else {
+			t.Completeness = float64(t.PresentFiles) / float64(t.FileCount) * 100
+		}
+	}
+	return torrents
+}
+
+// localSizes returns the sizes of the local files at every relative path.
+func (m *Memory) localSizes() map[string][]int64 {
+	sizes := make(map[string][]int64, len(m.localFiles))
+	for _, f := range m.localFiles {
+		sizes[f.relativePath] = append(sizes[f.relativePath], f.Size)
+	}
+	return sizes
+}
+
+// GetMissingTorrents returns the torrents expecting files that match no local
+// file, or only local files of another size, most missing and mismatched
+// bytes first. Torrents still downloading are left out.
+func (m *Memory) GetMissingTorrents(ctx context.Context, filter models.FileFilter) ([]models.MissingTorrent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	local := m.localSizes()
+	var all []models.MissingTorrent
+	index := make(map[torrentKey]int)
+	for _, f := range m.torrentFiles {
+		if !keepFile(f.TorrentFile, filter) {
+			continue
+		}
+		key := torrentKey{f.Instance, f.TorrentHash}
+		i, ok := index[key]
+		if !ok {
+			i = len(all)
+			index[key] = i
+			all = append(all, models.MissingTorrent{
+				Instance: f.Instance,
+				Hash:     f.TorrentHash,
+				Name:     f.TorrentName,
+				State:    m.torrents[key].State,
+			})
+		}
+		t := &all[i]
+		t.FileCount++
+		t.TotalSize += f.Size
+		sizes, found := local[f.relativePath]
+		switch {
+		case !found:
+			t.MissingFiles++
+			t.MissingBytes += f.Size
+		case !slices.Contains(sizes, f.Size):
+			t.MismatchedFiles++
+			t.MismatchedBytes += f.Size
+		}
+	}
+
+	var torrents []models.MissingTorrent
+	for _, t := range all {
+		if t.MissingFiles+t.MismatchedFiles == 0 || t.State == models.TorrentStateDownloading {
+			continue
+		}
+		if t.TotalSize > 0 {
+			t.Completeness = float64(t.TotalSize-t.MissingBytes) / float64(t.TotalSize) * 100
+		} else {
+			t.Completeness = float64(t.FileCount-t.MissingFiles) / float64(t.FileCount) * 100
+		}
+		torrents = append(torrents, t)
+	}
+	slices.SortFunc(torrents, func(a, b models.MissingTorrent) int {
+		return cmp.Or(cmp.Compare(b.MissingBytes+b.MismatchedBytes, a.MissingBytes+a.MismatchedBytes), cmp.Compare(a.Name, b.Name))
+	})
+	return torrents, nil
+}
+
+// GetMissingFiles returns the files of a torrent that match no local file, or
+// only local files of another size, except those filter leaves out of the
+// scans.
+func (m *Memory) GetMissingFiles(ctx context.Context, instance, hash string, filter models.FileFilter) ([]models.MissingFile, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	local := m.localSizes()
+	var files []models.MissingFile
+	for _, f := range m.torrentFiles {
+		if f.Instance != instance || f.TorrentHash != hash || !keepFile(f.TorrentFile, filter) {
+			continue
+		}
+		sizes, found := local[f.relativePath]
+		switch {
+		case !found:
+			files = append(files, models.MissingFile{TorrentFile: f.TorrentFile})
+		case !slices.Contains(sizes, f.Size):
+			files = append(files, models.MissingFile{TorrentFile: f.TorrentFile, Mismatched: true, LocalSize: slices.Max(sizes)})
+		}
+	}
+	slices.SortFunc(files, func(a, b models.MissingFile) int { return cmp.Compare(a.FilePath, b.FilePath) })
+	return files, nil
+}
+
+// memoryCompletenessOrder compares the torrents on the columns of
+// allowedCompletenessColumns.
+var memoryCompletenessOrder = map[string]func(a, b models.TorrentCompleteness) int{
+	"name":          func(a, b models.TorrentCompleteness) int { return cmp.Compare(a.Name, b.Name) },
+	"instance":      func(a, b models.TorrentCompleteness) int { return cmp.Compare(a.Instance, b.Instance) },
+	"state":         func(a, b models.TorrentCompleteness) int { return cmp.Compare(a.State, b.State) },
+	"file_count":    func(a, b models.TorrentCompleteness) int { return cmp.Compare(a.FileCount, b.FileCount) },
+	"present_files": func(a, b models.TorrentCompleteness) int { return cmp.Compare(a.PresentFiles, b.PresentFiles) },
+	"total_size":    func(a, b models.TorrentCompleteness) int { return cmp.Compare(a.TotalSize, b.TotalSize) },
+	"missing_bytes": func(a, b models.TorrentCompleteness) int { return cmp.Compare(a.MissingBytes, b.MissingBytes) },
+	"completeness":  func(a, b models.TorrentCompleteness) int { return cmp.Compare(a.Completeness, b.Completeness) },
+}
+
+// GetTorrentCompleteness returns the completeness of every torrent, as
+// Storage.GetTorrentCompleteness.
+func (m *Memory) GetTorrentCompleteness(ctx context.Context, opts models.QueryOptions, filter models.FileFilter, maxCompleteness float64) ([]models.TorrentCompleteness, int64, error) {
+	opts = normalizeQueryOptions(opts)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var torrents []models.TorrentCompleteness
+	for _, t := range m.completeness(filter) {
+		if t.Completeness > maxCompleteness ||
+			(opts.Search != "" && !containsFold(t.Name, opts.Search)) ||
+			(opts.State != "" && t.State != opts.State) {
+			continue
+		}
+		torrents = append(torrents, t)
+	}
+
+	order := func(a, b models.TorrentCompleteness) int {
+		return cmp.Or(cmp.Compare(a.Completeness, b.Completeness), cmp.Compare(b.MissingBytes, a.MissingBytes))
+	}
+	if col, ok := memoryCompletenessOrder[opts.Sort]; ok {
+		order = col
+		if opts.Order == "desc" {
+			order = func(a, b models.TorrentCompleteness) int { return col(b, a) }
+		}
+	}
+	slices.SortStableFunc(torrents, func(a, b models.TorrentCompleteness) int {
+		return cmp.Or(order(a, b), cmp.Compare(a.Name, b.Name))
+	})
+
+	return paginate(torrents, opts), int64(len(torrents)), nil
+}
+
+// AddProtectedPath stores a protected path pattern and returns its ID.
+func (m *Memory) AddProtectedPath(ctx context.Context, pattern string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := models.ProtectedPath{ID: m.nextID(), Pattern: pattern, CreatedAt: time.Now()}
+	m.protected = append(m.protected, p)
+	return p.ID, nil
+}
+
+// ListProtectedPaths returns all stored protected path patterns.
+func (m *Memory) ListProtectedPaths(ctx context.Context) ([]models.ProtectedPath, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	paths := slices.Clone(m.protected)
+	slices.SortFunc(paths, func(a, b models.ProtectedPath) int { return cmp.Compare(a.Pattern, b.Pattern) })
+	return paths, nil
+}
+
+// AddIgnoredPath adds a local file or folder to the ignore list, as
+// Storage.AddIgnoredPath.
+func (m *Memory) AddIgnoredPath(ctx context.Context, p string) (int64, string, error) {
+	if !strings.HasPrefix(p, "/") {
+		return 0, "", fmt.Errorf("ignored path must be absolute: %s", p)
+	}
+	p = path.Clean(m.NormalizePath(p))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := models.IgnoredPath{ID: m.nextID(), Path: p, CreatedAt: time.Now()}
+	m.ignored = append(m.ignored, i)
+	return i.ID, p, nil
+}
+
+// AddIgnoredPattern adds a glob to the ignore list, as
+// Storage.AddIgnoredPattern.
+func (m *Memory) AddIgnoredPattern(ctx context.Context, pattern string) (int64, string, error) {
+	pattern, err := normalizeIgnoredPattern(pattern, m.NormalizePath)
+	if err != nil {
+		return 0, "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := models.IgnoredPath{ID: m.nextID(), Path: pattern, Pattern: true, CreatedAt: time.Now()}
+	m.ignored = append(m.ignored, i)
+	return i.ID, pattern, nil
+}
+
+// CreateCleanupJob stores a cleanup job and returns its ID.
+func (m *Memory) CreateCleanupJob(ctx context.Context, job models.CleanupJob) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job.ID = m.nextID()
+	job.CreatedAt = time.Now()
+	m.jobs = append(m.jobs, job)
+	return job.ID, nil
+}
+
+// ListCleanupJobs returns all cleanup jobs ordered by ID.
+func (m *Memory) ListCleanupJobs(ctx context.Context) ([]models.CleanupJob, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return slices.Clone(m.jobs), nil
+}
+
+// AddQuarantineEntries records files moved to the quarantine.
+func (m *Memory) AddQuarantineEntries(ctx context.Context, entries []models.QuarantineEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, e := range entries {
+		e.ID = m.nextID()
+		m.quarantine = append(m.quarantine, e)
+	}
+	return nil
+}
+
+// GetQuarantineEntry retrieves a quarantine entry by ID.
+func (m *Memory) GetQuarantineEntry(ctx context.Context, id int64) (*models.QuarantineEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, e := range m.quarantine {
+		if e.ID == id {
+			return &e, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// ListQuarantineBatch returns the IDs of the files of a batch still in quarantine.
+func (m *Memory) ListQuarantineBatch(ctx context.Context, batch string) ([]int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var ids []int64
+	for _, e := range m.quarantine {
+		if e.Batch == batch && e.RestoredAt == nil && e.PurgedAt == nil {
+			ids = append(ids, e.ID)
+		}
+	}
+	return ids, nil
+}
+
+// MarkQuarantineRestored flags a quarantine entry as restored.
+func (m *Memory) MarkQuarantineRestored(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.quarantine {
+		if m.quarantine[i].ID == id {
+			now := time.Now()
+			m.quarantine[i].RestoredAt = &now
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// MarkQuarantineBatchPurged flags every remaining entry of a batch as purged.
+func (m *Memory) MarkQuarantineBatchPurged(ctx context.Context, batch string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for i := range m.quarantine {
+		e := &m.quarantine[i]
+		if e.Batch == batch && e.RestoredAt == nil && e.PurgedAt == nil {
+			e.PurgedAt = &now
+		}
+	}
+	return nil
+}
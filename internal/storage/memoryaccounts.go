@@ -0,0 +1,427 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"godatacleaner/internal/models"
+)
+
+// CreateUser adds a user and returns its ID.
+func (m *Memory) CreateUser(ctx context.Context, username, passwordHash, role string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, u := range m.users {
+		if u.Username == username {
+			return 0, fmt.Errorf("failed to insert user: %s already exists", username)
+		}
+	}
+	u := models.User{ID: m.nextID(), Username: username, Role: role, PasswordHash: passwordHash, CreatedAt: time.Now()}
+	m.users = append(m.users, u)
+	return u.ID, nil
+}
+
+// GetUserByUsername returns a user, including its password hash.
+func (m *Memory) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, u := range m.users {
+		if u.Username == username {
+			return &u, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// CountUsers returns the number of users.
+func (m *Memory) CountUsers(ctx context.Context) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return int64(len(m.users)), nil
+}
+
+// CreateSession stores a new session for username, valid for ttl.
+func (m *Memory) CreateSession(ctx context.Context, tokenHash, username string, remember bool, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.sessions[tokenHash] = models.Session{Username: username, Remember: remember, CreatedAt: now, ExpiresAt: now.Add(ttl)}
+	return nil
+}
+
+// GetSession returns a session that has not expired yet.
+func (m *Memory) GetSession(ctx context.Context, tokenHash string) (*models.Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sess, ok := m.sessions[tokenHash]
+	if !ok || !sess.ExpiresAt.After(time.Now()) {
+		return nil, ErrNotFound
+	}
+	return &sess, nil
+}
+
+// ExtendSession pushes back the expiration of a session to ttl from now,
+// when it would gain more than minGain, as Storage.ExtendSession.
+func (m *Memory) ExtendSession(ctx context.Context, tokenHash string, ttl, minGain time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expiresAt := time.Now().Add(ttl)
+	if sess, ok := m.sessions[tokenHash]; ok && sess.ExpiresAt.Before(expiresAt.Add(-minGain)) {
+		sess.ExpiresAt = expiresAt
+		m.sessions[tokenHash] = sess
+	}
+	return nil
+}
+
+// DeleteSession deletes a session.
+func (m *Memory) DeleteSession(ctx context.Context, tokenHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.sessions, tokenHash)
+	return nil
+}
+
+// DeleteExpiredSessions deletes the sessions that have expired.
+func (m *Memory) DeleteExpiredSessions(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	maps.DeleteFunc(m.sessions, func(_ string, sess models.Session) bool { return !sess.ExpiresAt.After(now) })
+	return nil
+}
+
+// CreateAPIKey stores a new API key from its hash and returns its ID.
+func (m *Memory) CreateAPIKey(ctx context.Context, name, prefix, keyHash, scope string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := models.APIKey{ID: m.nextID(), Name: name, Prefix: prefix, Scope: scope, CreatedAt: time.Now()}
+	m.apiKeys = append(m.apiKeys, memoryAPIKey{k, keyHash})
+	return k.ID, nil
+}
+
+// CountActiveAPIKeys returns the number of API keys that were not revoked.
+func (m *Memory) CountActiveAPIKeys(ctx context.Context) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var n int64
+	for _, k := range m.apiKeys {
+		if k.RevokedAt == nil {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// GetAPIKey returns an API key by ID.
+func (m *Memory) GetAPIKey(ctx context.Context, id int64) (*models.APIKey, error) {
+	return m.getAPIKey(func(k memoryAPIKey) bool { return k.ID == id })
+}
+
+// GetActiveAPIKeyByHash returns the API key matching a hash, unless it was revoked.
+func (m *Memory) GetActiveAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	return m.getAPIKey(func(k memoryAPIKey) bool { return k.hash == keyHash && k.RevokedAt == nil })
+}
+
+func (m *Memory) getAPIKey(match func(memoryAPIKey) bool) (*models.APIKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, k := range m.apiKeys {
+		if match(k) {
+			return &k.APIKey, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// ListAPIKeys returns all API keys, revoked ones included, newest first.
+func (m *Memory) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var keys []models.APIKey
+	for _, k := range slices.Backward(m.apiKeys) {
+		keys = append(keys, k.APIKey)
+	}
+	return keys, nil
+}
+
+// TouchAPIKey records the use of an API key, at most once per minute.
+func (m *Memory) TouchAPIKey(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for i := range m.apiKeys {
+		k := &m.apiKeys[i]
+		if k.ID == id && (k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) > time.Minute) {
+			k.LastUsedAt = &now
+		}
+	}
+	return nil
+}
+
+// RevokeAPIKey revokes an API key. Revoked keys are kept for the history.
+func (m *Memory) RevokeAPIKey(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.apiKeys {
+		if k := &m.apiKeys[i]; k.ID == id && k.RevokedAt == nil {
+			now := time.Now()
+			k.RevokedAt = &now
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// CreateSavedView stores a saved view and returns its ID.
+func (m *Memory) CreateSavedView(ctx context.Context, v models.SavedView) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	v.ID = m.nextID()
+	v.CreatedAt = time.Now()
+	m.views = append(m.views, v)
+	return v.ID, nil
+}
+
+// ListSavedViews returns the saved views of a file list, or of all lists
+// when list is empty, ordered by name.
+func (m *Memory) ListSavedViews(ctx context.Context, list string) ([]models.SavedView, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var views []models.SavedView
+	for _, v := range m.views {
+		if list == "" || v.List == list {
+			views = append(views, v)
+		}
+	}
+	slices.SortFunc(views, func(a, b models.SavedView) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.ID, b.ID))
+	})
+	return views, nil
+}
+
+// UpdateSavedView replaces the name and the filters of the saved view v.ID.
+func (m *Memory) UpdateSavedView(ctx context.Context, v models.SavedView) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.views {
+		if m.views[i].ID == v.ID {
+			v.CreatedAt = m.views[i].CreatedAt
+			m.views[i] = v
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// DeleteSavedView removes a saved view.
+func (m *Memory) DeleteSavedView(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := len(m.views)
+	m.views = slices.DeleteFunc(m.views, func(v models.SavedView) bool { return v.ID == id })
+	if len(m.views) == n {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetColumnLayouts returns the columns shown by a user in the file lists of
+// the WebUI, by column list.
+func (m *Memory) GetColumnLayouts(ctx context.Context, username string) (map[string][]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	layouts := map[string][]string{}
+	for list, columns := range m.layouts[username] {
+		layouts[list] = slices.Clone(columns)
+	}
+	return layouts, nil
+}
+
+// SetColumnLayout replaces the columns shown by a user in a file list.
+func (m *Memory) SetColumnLayout(ctx context.Context, username, list string, columns []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.layouts[username] == nil {
+		m.layouts[username] = make(map[string][]string)
+	}
+	m.layouts[username][list] = slices.Clone(columns)
+	return nil
+}
+
+// DeleteColumnLayout restores the default columns of a file list for a user.
+func (m *Memory) DeleteColumnLayout(ctx context.Context, username, list string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.layouts[username], list)
+	return nil
+}
+
+// RemoveProtectedPath deletes a stored protected path pattern.
+func (m *Memory) RemoveProtectedPath(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := len(m.protected)
+	m.protected = slices.DeleteFunc(m.protected, func(p models.ProtectedPath) bool { return p.ID == id })
+	if len(m.protected) == n {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RemoveIgnoredPath deletes an entry of the ignore list.
+func (m *Memory) RemoveIgnoredPath(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := len(m.ignored)
+	m.ignored = slices.DeleteFunc(m.ignored, func(i models.IgnoredPath) bool { return i.ID == id })
+	if len(m.ignored) == n {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetCleanupJob retrieves a cleanup job by ID.
+func (m *Memory) GetCleanupJob(ctx context.Context, id int64) (*models.CleanupJob, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, job := range m.jobs {
+		if job.ID == id {
+			return &job, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// updateJob applies update to the cleanup job id.
+func (m *Memory) updateJob(id int64, update func(*models.CleanupJob)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.jobs {
+		if m.jobs[i].ID == id {
+			update(&m.jobs[i])
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// SetCleanupJobEnabled enables or disables a cleanup job.
+func (m *Memory) SetCleanupJobEnabled(ctx context.Context, id int64, enabled bool) error {
+	return m.updateJob(id, func(job *models.CleanupJob) { job.Enabled = enabled })
+}
+
+// SetCleanupJobSchedule changes the schedule of a cleanup job.
+func (m *Memory) SetCleanupJobSchedule(ctx context.Context, id int64, schedule string) error {
+	return m.updateJob(id, func(job *models.CleanupJob) { job.Schedule = schedule })
+}
+
+// RecordCleanupRun stores a cleanup run with the files it handled,
+// and updates the last run time of its job, if any.
+func (m *Memory) RecordCleanupRun(ctx context.Context, run models.CleanupRun, files []models.CleanupRunFile) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	run.ID = m.nextID()
+	m.runs = append(m.runs, run)
+	for _, f := range files {
+		f.RunID = run.ID
+		m.runFiles[run.ID] = append(m.runFiles[run.ID], f)
+	}
+	for i := range m.jobs {
+		if m.jobs[i].ID == run.JobID {
+			started := run.StartedAt
+			m.jobs[i].LastRunAt = &started
+		}
+	}
+	return run.ID, nil
+}
+
+// ListCleanupRuns returns the most recent cleanup runs, newest first, with
+// the name of their job, as Storage.ListCleanupRuns.
+func (m *Memory) ListCleanupRuns(ctx context.Context, jobID int64, limit int) ([]models.CleanupRun, error) {
+	if limit < 1 {
+		limit = 50
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var runs []models.CleanupRun
+	for _, r := range slices.Backward(m.runs) {
+		if len(runs) == limit {
+			break
+		}
+		if jobID != 0 && r.JobID != jobID {
+			continue
+		}
+		for _, job := range m.jobs {
+			if job.ID == r.JobID {
+				r.JobName = job.Name
+			}
+		}
+		runs = append(runs, r)
+	}
+	return runs, nil
+}
+
+// GetCleanupRunFiles returns the files handled by a cleanup run.
+func (m *Memory) GetCleanupRunFiles(ctx context.Context, runID int64) ([]models.CleanupRunFile, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return slices.Clone(m.runFiles[runID]), nil
+}
+
+// ListSyncRuns returns no sync run: Memory records none.
+func (m *Memory) ListSyncRuns(ctx context.Context, limit int) ([]models.SyncRun, error) {
+	return nil, nil
+}
+
+// GetSyncDiff returns ErrNotFound: Memory records no sync run.
+func (m *Memory) GetSyncDiff(ctx context.Context, id int64, limit int) (*models.SyncDiff, error) {
+	return nil, ErrNotFound
+}
+
+// GetSyncErrors returns ErrNotFound: Memory records no sync run.
+func (m *Memory) GetSyncErrors(ctx context.Context, id int64) (*models.SyncErrors, error) {
+	return nil, ErrNotFound
+}
+
+// GetLibraryHistory returns no point: Memory records no sync run.
+func (m *Memory) GetLibraryHistory(ctx context.Context, since time.Time) ([]models.LibraryHistoryPoint, error) {
+	return nil, nil
+}
+
+// Backup returns ErrNotSupported: Memory has no database to back up.
+func (m *Memory) Backup(ctx context.Context, path string) error {
+	return ErrNotSupported
+}
@@ -0,0 +1,796 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"godatacleaner/internal/models"
+)
+
+// GetTorrentFiles retrieves torrent files with pagination, as
+// Storage.GetTorrentFiles.
+func (m *Memory) GetTorrentFiles(ctx context.Context, opts models.QueryOptions) ([]models.TorrentFile, int64, error) {
+	opts = normalizeQueryOptions(opts)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// En mode unique, le premier fichier de chaque chemin relatif, avant la recherche
+	seen := make(map[string]bool)
+	var files []models.TorrentFile
+	for _, f := range m.torrentFiles {
+		if opts.Unique {
+			if seen[f.relativePath] {
+				continue
+			}
+			seen[f.relativePath] = true
+		}
+		if opts.Search != "" && !containsFold(f.FileName, opts.Search) && !containsFold(f.FilePath, opts.Search) {
+			continue
+		}
+		files = append(files, f.TorrentFile)
+	}
+
+	if _, ok := allowedTorrentColumns[opts.Sort]; !ok {
+		opts.Order = "asc"
+	}
+	page := keysetPage(files, opts, opts.Sort == "size", func(f models.TorrentFile) *models.Cursor { return TorrentFileCursor(opts.Sort, f) })
+	return page, int64(len(files)), nil
+}
+
+// GetLocalFiles retrieves local files with pagination, as Storage.GetLocalFiles.
+func (m *Memory) GetLocalFiles(ctx context.Context, opts models.QueryOptions) ([]models.LocalFile, int64, error) {
+	opts = normalizeQueryOptions(opts)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	filter := opts
+	filter.ExcludeHardlinked = false
+	now := time.Now()
+	var files []models.LocalFile
+	for _, f := range m.localFiles {
+		if matchesOptions(f.LocalFile, filter, now) {
+			file := f.LocalFile
+			file.RelativePath = f.relativePath
+			files = append(files, file)
+		}
+	}
+
+	if _, ok := allowedLocalColumns[opts.Sort]; !ok {
+		opts.Order = "asc"
+	}
+	numeric := opts.Sort == "size" || opts.Sort == "mod_time"
+	page := keysetPage(files, opts, numeric, func(f models.LocalFile) *models.Cursor { return LocalFileCursor(opts.Sort, f) })
+	return page, int64(len(files)), nil
+}
+
+// memoryTorrentOrder compares the torrents on the columns of
+// allowedTorrentListColumns.
+var memoryTorrentOrder = map[string]func(a, b models.Torrent) int{
+	"name":         func(a, b models.Torrent) int { return cmp.Compare(a.Name, b.Name) },
+	"instance":     func(a, b models.Torrent) int { return cmp.Compare(a.Instance, b.Instance) },
+	"size":         func(a, b models.Torrent) int { return cmp.Compare(a.Size, b.Size) },
+	"state":        func(a, b models.Torrent) int { return cmp.Compare(a.State, b.State) },
+	"ratio":        func(a, b models.Torrent) int { return cmp.Compare(a.Ratio, b.Ratio) },
+	"seeding_time": func(a, b models.Torrent) int { return cmp.Compare(a.SeedingTime, b.SeedingTime) },
+}
+
+// GetTorrents retrieves the torrents with pagination, as Storage.GetTorrents.
+func (m *Memory) GetTorrents(ctx context.Context, opts models.QueryOptions) ([]models.Torrent, int64, error) {
+	opts = normalizeQueryOptions(opts)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var torrents []models.Torrent
+	for _, t := range m.torrents {
+		if (opts.Search == "" || containsFold(t.Name, opts.Search)) && (opts.State == "" || t.State == opts.State) {
+			torrents = append(torrents, t)
+		}
+	}
+
+	order, ok := memoryTorrentOrder[opts.Sort]
+	if !ok {
+		order, opts.Order = memoryTorrentOrder["name"], "asc"
+	}
+	slices.SortFunc(torrents, func(a, b models.Torrent) int {
+		c := order(a, b)
+		if opts.Order == "desc" {
+			c = -c
+		}
+		return cmp.Or(c, cmp.Compare(a.Instance, b.Instance), cmp.Compare(a.Hash, b.Hash))
+	})
+	return paginate(torrents, opts), int64(len(torrents)), nil
+}
+
+// GetTorrentStats returns global torrent statistics, counting the files of
+// a relative path once if unique is set.
+func (m *Memory) GetTorrentStats(ctx context.Context, unique bool) (*models.Stats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var stats models.Stats
+	seen := make(map[string]bool)
+	hashes := make(map[string]bool)
+	for _, f := range m.torrentFiles {
+		if unique {
+			if seen[f.relativePath] {
+				continue
+			}
+			seen[f.relativePath] = true
+		}
+		stats.TotalFiles++
+		stats.TotalSize += f.Size
+		hashes[f.TorrentHash] = true
+	}
+	stats.TotalTorrents = int64(len(hashes))
+	return &stats, nil
+}
+
+// GetTorrentStateStats returns the number and total size of the torrents per state.
+func (m *Memory) GetTorrentStateStats(ctx context.Context) ([]models.TorrentStateStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	index := make(map[string]int)
+	var stats []models.TorrentStateStats
+	for _, t := range m.torrents {
+		i, ok := index[t.State]
+		if !ok {
+			i = len(stats)
+			index[t.State] = i
+			stats = append(stats, models.TorrentStateStats{State: t.State})
+		}
+		stats[i].TorrentCount++
+		stats[i].TotalSize += t.Size
+	}
+	slices.SortFunc(stats, func(a, b models.TorrentStateStats) int { return cmp.Compare(a.State, b.State) })
+	return stats, nil
+}
+
+// GetTrackerStats returns the number and total size of the torrents per tracker.
+// Torrents without tracker are grouped under an empty tracker.
+func (m *Memory) GetTrackerStats(ctx context.Context) ([]models.TrackerStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	index := make(map[string]int)
+	var stats []models.TrackerStats
+	for _, t := range m.torrents {
+		trackers := slices.Compact(slices.Sorted(slices.Values(t.Trackers)))
+		if len(trackers) == 0 {
+			trackers = []string{""}
+		}
+		for _, tracker := range trackers {
+			i, ok := index[tracker]
+			if !ok {
+				i = len(stats)
+				index[tracker] = i
+				stats = append(stats, models.TrackerStats{Tracker: tracker})
+			}
+			stats[i].TorrentCount++
+			stats[i].TotalSize += t.Size
+		}
+	}
+	slices.SortFunc(stats, func(a, b models.TrackerStats) int {
+		return cmp.Or(cmp.Compare(b.TotalSize, a.TotalSize), cmp.Compare(a.Tracker, b.Tracker))
+	})
+	return stats, nil
+}
+
+// categoryStats returns the statistics by category of the local files keep
+// keeps, ordered by category.
+func (m *Memory) categoryStats(keep func(memoryLocalFile) bool) []models.CategoryStats {
+	index := make(map[string]int)
+	var stats []models.CategoryStats
+	for _, f := range m.localFiles {
+		if !keep(f) {
+			continue
+		}
+		i, ok := index[f.Category]
+		if !ok {
+			i = len(stats)
+			index[f.Category] = i
+			stats = append(stats, models.CategoryStats{Category: f.Category})
+		}
+		stats[i].FileCount++
+		stats[i].TotalSize += f.Size
+		stats[i].AllocatedSize += f.Allocated
+	}
+	slices.SortFunc(stats, func(a, b models.CategoryStats) int { return cmp.Compare(a.Category, b.Category) })
+	return stats
+}
+
+// GetLocalStats returns local file statistics by category.
+func (m *Memory) GetLocalStats(ctx context.Context) ([]models.CategoryStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.categoryStats(func(memoryLocalFile) bool { return true }), nil
+}
+
+// GetOrphanStats returns orphan file statistics by category.
+func (m *Memory) GetOrphanStats(ctx context.Context) ([]models.CategoryStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	expected := m.expectedPaths()
+	return m.categoryStats(func(f memoryLocalFile) bool { return m.isOrphan(f, expected) }), nil
+}
+
+// GetInProgressStats returns statistics by category of the local files still
+// being downloaded, see inProgress.
+func (m *Memory) GetInProgressStats(ctx context.Context) ([]models.CategoryStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	downloading := make(map[string]bool)
+	for _, tf := range m.torrentFiles {
+		if t, ok := m.torrents[torrentKey{tf.Instance, tf.TorrentHash}]; ok && t.State == models.TorrentStateDownloading {
+			downloading[tf.relativePath] = true
+		}
+	}
+	return m.categoryStats(func(f memoryLocalFile) bool { return downloading[f.relativePath] || m.incomplete(f) }), nil
+}
+
+// GetOrphanTotals returns the number and the size of the orphans matching the
+// filters of opts.
+func (m *Memory) GetOrphanTotals(ctx context.Context, opts models.QueryOptions) (models.OrphanTotals, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var totals models.OrphanTotals
+	for _, f := range m.orphans(opts, time.Now()) {
+		totals.FileCount++
+		totals.TotalSize += f.Size
+	}
+	return totals, nil
+}
+
+// GetTorrentsForPaths returns the torrents expecting any of the given local
+// files, matched on their relative path.
+func (m *Memory) GetTorrentsForPaths(ctx context.Context, paths []string) ([]models.Torrent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	seen := make(map[torrentKey]bool)
+	var torrents []models.Torrent
+	for _, p := range paths {
+		relativePath := m.relativePath(p)
+		for _, f := range m.torrentFiles {
+			key := torrentKey{f.Instance, f.TorrentHash}
+			t, ok := m.torrents[key]
+			if ok && f.relativePath == relativePath && !seen[key] {
+				seen[key] = true
+				torrents = append(torrents, t)
+			}
+		}
+	}
+	return torrents, nil
+}
+
+// GetFileTorrents returns the torrents expecting the local file at path, as
+// Storage.GetFileTorrents.
+func (m *Memory) GetFileTorrents(ctx context.Context, path string) (models.FileTorrentsResponse, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.fileTorrents(path)
+}
+
+func (m *Memory) fileTorrents(path string) (models.FileTorrentsResponse, error) {
+	resp := models.FileTorrentsResponse{Path: path, Torrents: []models.FileTorrent{}}
+	f, ok := m.localFiles[path]
+	if !ok {
+		return resp, ErrNotFound
+	}
+
+	// Correspondances directes d'abord, puis via les liens physiques
+	matches := func(relativePath, hardlink string) []models.FileTorrent {
+		var torrents []models.FileTorrent
+		for _, tf := range m.torrentFiles {
+			if tf.relativePath == relativePath {
+				torrents = append(torrents, models.FileTorrent{
+					Instance: tf.Instance,
+					Hash:     tf.TorrentHash,
+					Name:     tf.TorrentName,
+					State:    m.torrents[torrentKey{tf.Instance, tf.TorrentHash}].State,
+					FilePath: tf.FilePath,
+					Hardlink: hardlink,
+				})
+			}
+		}
+		return torrents
+	}
+	direct := matches(f.relativePath, "")
+	var linked []models.FileTorrent
+	if f.Inode != 0 {
+		for _, h := range m.localFiles {
+			if h.Inode == f.Inode && h.Device == f.Device && h.FilePath != f.FilePath {
+				linked = append(linked, matches(h.relativePath, h.FilePath)...)
+			}
+		}
+	}
+	for _, torrents := range [][]models.FileTorrent{direct, linked} {
+		slices.SortFunc(torrents, func(a, b models.FileTorrent) int {
+			return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.Instance, b.Instance), cmp.Compare(a.Hash, b.Hash))
+		})
+		resp.Torrents = append(resp.Torrents, torrents...)
+	}
+
+	resp.Status = m.status(f, m.expectedPaths())
+	return resp, nil
+}
+
+// GetFileDetail returns everything known about the local file at path, as
+// Storage.GetFileDetail.
+func (m *Memory) GetFileDetail(ctx context.Context, path string) (models.FileDetail, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var d models.FileDetail
+	f, ok := m.localFiles[m.NormalizePath(path)]
+	if !ok {
+		return d, ErrNotFound
+	}
+	d.LocalFile = f.LocalFile
+	d.ID, d.Device, d.Inode = 0, 0, 0
+	d.RelativePath = f.relativePath
+
+	torrents, err := m.fileTorrents(f.FilePath)
+	if err != nil {
+		return d, err
+	}
+	d.Status, d.Torrents = torrents.Status, torrents.Torrents
+
+	d.NearMisses = []models.NearMiss{}
+	for _, tf := range m.torrentFiles {
+		if tf.FileName == f.FileName && tf.relativePath != f.relativePath {
+			d.NearMisses = append(d.NearMisses, models.NearMiss{
+				Instance:     tf.Instance,
+				Hash:         tf.TorrentHash,
+				Name:         tf.TorrentName,
+				FilePath:     tf.FilePath,
+				RelativePath: tf.relativePath,
+				Size:         tf.Size,
+			})
+		}
+	}
+	slices.SortFunc(d.NearMisses, func(a, b models.NearMiss) int {
+		return cmp.Or(compareBool(a.Size != f.Size, b.Size != f.Size), cmp.Compare(a.Name, b.Name), cmp.Compare(a.FilePath, b.FilePath))
+	})
+	d.NearMisses = d.NearMisses[:min(len(d.NearMisses), maxNearMisses)]
+	return d, nil
+}
+
+// compareBool compares two booleans, false first as in SQL.
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	}
+	return -1
+}
+
+// GetTopFiles returns the n largest local files, as Storage.GetTopFiles.
+func (m *Memory) GetTopFiles(ctx context.Context, by string, n int, orphansOnly bool) ([]models.TopFile, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	expected := m.expectedPaths()
+	var files []models.TopFile
+	for _, f := range m.localFiles {
+		orphan := m.isOrphan(f, expected)
+		if orphansOnly && !orphan {
+			continue
+		}
+		files = append(files, models.TopFile{
+			FilePath:  f.FilePath,
+			FileName:  f.FileName,
+			Size:      f.Size,
+			Allocated: f.Allocated,
+			Category:  f.Category,
+			Orphan:    orphan,
+			ModTime:   f.ModTime,
+		})
+	}
+
+	value := func(f models.TopFile) int64 { return f.Size }
+	if by == "allocated" {
+		value = func(f models.TopFile) int64 { return f.Allocated }
+	}
+	slices.SortFunc(files, func(a, b models.TopFile) int {
+		return cmp.Or(cmp.Compare(value(b), value(a)), cmp.Compare(a.FilePath, b.FilePath))
+	})
+	return files[:min(len(files), max(n, 0))], nil
+}
+
+// GetTopFolders returns the n largest local folders, as Storage.GetTopFolders.
+func (m *Memory) GetTopFolders(ctx context.Context, by string, n int, orphansOnly bool) ([]models.TopFolder, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	expected := m.expectedPaths()
+	index := make(map[string]int)
+	var folders []models.TopFolder
+	for _, f := range m.localFiles {
+		if orphansOnly && !m.isOrphan(f, expected) {
+			continue
+		}
+		folder := f.FilePath[:max(len(f.FilePath)-len(f.FileName)-1, 0)]
+		if folder == "" {
+			folder = "/"
+		}
+		i, ok := index[folder]
+		if !ok {
+			i = len(folders)
+			index[folder] = i
+			folders = append(folders, models.TopFolder{Path: folder})
+		}
+		folders[i].FileCount++
+		folders[i].TotalSize += f.Size
+		folders[i].Allocated += f.Allocated
+	}
+
+	value := func(f models.TopFolder) int64 { return f.TotalSize }
+	switch by {
+	case "allocated":
+		value = func(f models.TopFolder) int64 { return f.Allocated }
+	case "files":
+		value = func(f models.TopFolder) int64 { return f.FileCount }
+	}
+	slices.SortFunc(folders, func(a, b models.TopFolder) int {
+		return cmp.Or(cmp.Compare(value(b), value(a)), cmp.Compare(a.Path, b.Path))
+	})
+	return folders[:min(len(folders), max(n, 0))], nil
+}
+
+// inReleaseFolder reports whether the local file at filePath is below a
+// release folder, or is the lone file of a single release, see inFolder.
+func inReleaseFolder(filePath, folder string) bool {
+	return filePath == folder || strings.HasPrefix(filePath, folder+"/")
+}
+
+// GetOrphanReleases returns a page of the orphans matching the filters of
+// opts grouped by release folder, as Storage.GetOrphanReleases.
+func (m *Memory) GetOrphanReleases(ctx context.Context, opts models.QueryOptions) ([]models.OrphanRelease, int64, error) {
+	opts = normalizeQueryOptions(opts)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	groups := map[string]*models.OrphanRelease{}
+	for _, f := range m.orphans(opts, time.Now()) {
+		folder, single := releaseFolder(f.FilePath, f.RelativePath)
+		g, ok := groups[folder]
+		if !ok {
+			g = &models.OrphanRelease{Folder: folder, Name: path.Base(folder), Category: f.Category, Single: single}
+			groups[folder] = g
+		}
+		g.FileCount++
+		g.TotalSize += f.Size
+		g.Allocated += f.Allocated
+		if f.ModTime.After(g.ModTime) {
+			g.ModTime = f.ModTime
+		}
+	}
+
+	releases := make([]models.OrphanRelease, 0, len(groups))
+	for _, g := range groups {
+		releases = append(releases, *g)
+	}
+	order, ok := releaseOrder[opts.Sort]
+	desc := opts.Order == "desc"
+	if !ok {
+		order, desc = releaseOrder["size"], true
+	}
+	slices.SortFunc(releases, func(a, b models.OrphanRelease) int {
+		c := cmp.Or(order(a, b), cmp.Compare(a.Folder, b.Folder))
+		if desc {
+			return -c
+		}
+		return c
+	})
+
+	page := paginate(releases, opts)
+	for i := range page {
+		for _, f := range m.localFiles {
+			if inReleaseFolder(f.FilePath, page[i].Folder) {
+				page[i].LocalFiles++
+			}
+		}
+	}
+	return page, int64(len(releases)), nil
+}
+
+// GetOrphanReleaseFiles returns the orphans below a release folder, or the
+// lone file of a single release, that match the filters of opts.
+func (m *Memory) GetOrphanReleaseFiles(ctx context.Context, folder string, opts models.QueryOptions) ([]models.OrphanFile, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var files []models.OrphanFile
+	for _, f := range m.orphans(opts, time.Now()) {
+		if inReleaseFolder(f.FilePath, folder) {
+			files = append(files, f)
+		}
+	}
+	slices.SortFunc(files, func(a, b models.OrphanFile) int { return cmp.Compare(a.FilePath, b.FilePath) })
+	return files, nil
+}
+
+// tableFiles returns the path and the size of the files of a table of
+// allowedTables, and for the local files whether they are orphans.
+func (m *Memory) tableFiles(table string) ([]models.TopFile, error) {
+	if !allowedTables[table] {
+		return nil, fmt.Errorf("invalid table name: %s", table)
+	}
+
+	var files []models.TopFile
+	if table == "torrent_files" {
+		for _, f := range m.torrentFiles {
+			files = append(files, models.TopFile{FilePath: f.FilePath, Size: f.Size})
+		}
+		return files, nil
+	}
+	expected := m.expectedPaths()
+	for _, f := range m.localFiles {
+		files = append(files, models.TopFile{FilePath: f.FilePath, Size: f.Size, Orphan: m.isOrphan(f, expected)})
+	}
+	return files, nil
+}
+
+// GetFolderStats returns statistics by first component of the file paths,
+// as Storage.GetFolderStats.
+func (m *Memory) GetFolderStats(ctx context.Context, table string) ([]models.FolderStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	files, err := m.tableFiles(table)
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string]int)
+	var stats []models.FolderStats
+	for _, f := range files {
+		folder, _, _ := strings.Cut(f.FilePath, "/")
+		i, ok := index[folder]
+		if !ok {
+			i = len(stats)
+			index[folder] = i
+			stats = append(stats, models.FolderStats{Folder: folder})
+		}
+		stats[i].FileCount++
+		stats[i].TotalSize += f.Size
+	}
+	slices.SortFunc(stats, func(a, b models.FolderStats) int {
+		return cmp.Or(cmp.Compare(b.TotalSize, a.TotalSize), cmp.Compare(a.Folder, b.Folder))
+	})
+	return stats, nil
+}
+
+// GetFolderTree returns the subfolders and files directly in dir, as
+// Storage.GetFolderTree.
+func (m *Memory) GetFolderTree(ctx context.Context, table, dir string) (models.FolderTree, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	files, err := m.tableFiles(table)
+	if err != nil {
+		return models.FolderTree{}, err
+	}
+
+	dir = path.Clean("/" + dir)
+	tree := models.FolderTree{Path: dir, Entries: []models.FolderEntry{}}
+	if dir != "/" {
+		tree.Parent = path.Dir(dir)
+	}
+
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	index := make(map[string]int)
+	for _, f := range files {
+		rest, ok := strings.CutPrefix(f.FilePath, prefix)
+		if !ok {
+			continue
+		}
+		name, _, sub := strings.Cut(rest, "/")
+		i, ok := index[name]
+		if !ok {
+			i = len(tree.Entries)
+			index[name] = i
+			tree.Entries = append(tree.Entries, models.FolderEntry{Name: name, Path: prefix + name})
+		}
+		e := &tree.Entries[i]
+		e.Dir = e.Dir || sub
+		e.FileCount++
+		e.TotalSize += f.Size
+		if f.Orphan {
+			e.OrphanSize += f.Size
+		}
+		tree.FileCount++
+		tree.TotalSize += f.Size
+		if f.Orphan {
+			tree.OrphanSize += f.Size
+		}
+	}
+	slices.SortFunc(tree.Entries, func(a, b models.FolderEntry) int {
+		return cmp.Or(cmp.Compare(b.TotalSize, a.TotalSize), cmp.Compare(a.Name, b.Name))
+	})
+	return tree, nil
+}
+
+// extension returns the lowercase extension of a file name, see
+// fileExtension.
+func extension(name string) string {
+	i := strings.LastIndex(name, ".")
+	if i < 0 || i == len(name)-1 {
+		return "no_extension"
+	}
+	return strings.ToLower(name[i+1:])
+}
+
+// GetExtensionStats returns statistics by extension of the local files of
+// category, or all of them if empty, as Storage.GetExtensionStats.
+func (m *Memory) GetExtensionStats(ctx context.Context, category string) ([]models.ExtensionStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	expected := m.expectedPaths()
+	index := make(map[string]int)
+	var stats []models.ExtensionStats
+	for _, f := range m.localFiles {
+		if category != "" && f.Category != category {
+			continue
+		}
+		ext := extension(f.FileName)
+		i, ok := index[ext]
+		if !ok {
+			i = len(stats)
+			index[ext] = i
+			stats = append(stats, models.ExtensionStats{Extension: ext})
+		}
+		es := &stats[i]
+		es.FileCount++
+		es.TotalSize += f.Size
+		es.AllocatedSize += f.Allocated
+		if m.isOrphan(f, expected) {
+			es.OrphanFiles++
+			es.OrphanSize += f.Size
+		}
+		c := slices.IndexFunc(es.Categories, func(cs models.CategoryStats) bool { return cs.Category == f.Category })
+		if c < 0 {
+			c = len(es.Categories)
+			es.Categories = append(es.Categories, models.CategoryStats{Category: f.Category})
+		}
+		es.Categories[c].FileCount++
+		es.Categories[c].TotalSize += f.Size
+		es.Categories[c].AllocatedSize += f.Allocated
+	}
+	for _, es := range stats {
+		slices.SortFunc(es.Categories, func(a, b models.CategoryStats) int {
+			return cmp.Or(cmp.Compare(b.TotalSize, a.TotalSize), cmp.Compare(a.Category, b.Category))
+		})
+	}
+	slices.SortFunc(stats, func(a, b models.ExtensionStats) int {
+		return cmp.Or(cmp.Compare(b.TotalSize, a.TotalSize), cmp.Compare(a.Extension, b.Extension))
+	})
+	return stats, nil
+}
+
+// GetDuplicateNames returns the groups of local files with the same name and
+// size that are not all hard links of a single file, as
+// Storage.GetDuplicateNames.
+func (m *Memory) GetDuplicateNames(ctx context.Context) ([]models.DuplicateGroup, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	type key struct {
+		name string
+		size int64
+	}
+	expected := m.expectedPaths()
+	copies := make(map[key][]models.DuplicateFile)
+	for _, f := range m.localFiles {
+		if f.Size == 0 {
+			continue
+		}
+		file := f.LocalFile
+		file.ID = 0
+		k := key{f.FileName, f.Size}
+		copies[k] = append(copies[k], models.DuplicateFile{LocalFile: file, Status: m.status(f, expected)})
+	}
+
+	var groups []models.DuplicateGroup
+	for k, files := range copies {
+		identities := make(map[string]bool)
+		for _, f := range files {
+			identities[fileIdentity(f.LocalFile)] = true
+		}
+		if len(identities) < 2 {
+			continue
+		}
+		slices.SortFunc(files, func(a, b models.DuplicateFile) int { return cmp.Compare(a.FilePath, b.FilePath) })
+		g := models.DuplicateGroup{Name: k.name, Size: k.size, Copies: int64(len(identities)), Files: files}
+		g.WastedBytes = (g.Copies - 1) * g.Size
+		markDeletable(&g)
+		groups = append(groups, g)
+	}
+	slices.SortFunc(groups, func(a, b models.DuplicateGroup) int {
+		return cmp.Or(cmp.Compare(b.WastedBytes, a.WastedBytes), cmp.Compare(a.Name, b.Name), cmp.Compare(a.Size, b.Size))
+	})
+	return groups, nil
+}
+
+// ListIgnoredPaths returns the ignore list, with the local files each entry
+// ignores and the orphans among them it hides.
+func (m *Memory) ListIgnoredPaths(ctx context.Context) ([]models.IgnoredPath, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	expected := m.expectedPaths()
+	paths := slices.Clone(m.ignored)
+	for i := range paths {
+		p := &paths[i]
+		for _, f := range m.localFiles {
+			if !ignores(*p, f) {
+				continue
+			}
+			p.FileCount++
+			p.TotalSize += f.Size
+			if !expected[f.relativePath] && !m.hardlinked(f, expected) && !m.incomplete(f) {
+				p.OrphanFiles++
+				p.OrphanSize += f.Size
+			}
+		}
+	}
+	slices.SortFunc(paths, func(a, b models.IgnoredPath) int { return cmp.Or(cmp.Compare(a.Path, b.Path), cmp.Compare(a.ID, b.ID)) })
+	return paths, nil
+}
+
+// memoryQuarantineOrder compares the quarantine entries on the columns of
+// allowedQuarantineColumns.
+var memoryQuarantineOrder = map[string]func(a, b models.QuarantineEntry) int{
+	"file_path":      func(a, b models.QuarantineEntry) int { return cmp.Compare(a.FilePath, b.FilePath) },
+	"size":           func(a, b models.QuarantineEntry) int { return cmp.Compare(a.Size, b.Size) },
+	"category":       func(a, b models.QuarantineEntry) int { return cmp.Compare(a.Category, b.Category) },
+	"batch":          func(a, b models.QuarantineEntry) int { return cmp.Compare(a.Batch, b.Batch) },
+	"quarantined_at": func(a, b models.QuarantineEntry) int { return a.QuarantinedAt.Compare(b.QuarantinedAt) },
+}
+
+// ListQuarantine retrieves the files currently in quarantine with pagination,
+// as Storage.ListQuarantine.
+func (m *Memory) ListQuarantine(ctx context.Context, opts models.QueryOptions) ([]models.QuarantineEntry, int64, error) {
+	opts = normalizeQueryOptions(opts)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var entries []models.QuarantineEntry
+	for _, e := range m.quarantine {
+		if e.RestoredAt != nil || e.PurgedAt != nil ||
+			(opts.Search != "" && !containsFold(e.FilePath, opts.Search)) ||
+			(opts.Category != "" && e.Category != opts.Category) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+
+	// Par défaut les plus récents d'abord, comme en SQL
+	order, ok := memoryQuarantineOrder[opts.Sort]
+	if !ok {
+		order = func(a, b models.QuarantineEntry) int { return cmp.Compare(b.ID, a.ID) }
+	} else if opts.Order == "desc" {
+		asc := order
+		order = func(a, b models.QuarantineEntry) int { return asc(b, a) }
+	}
+	slices.SortStableFunc(entries, order)
+	return paginate(entries, opts), int64(len(entries)), nil
+}
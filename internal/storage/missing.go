@@ -0,0 +1,215 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"godatacleaner/internal/models"
+)
+
+// missingCondition is the condition on the torrent file f that no local file
+// matches it, the reverse of an orphan.
+const missingCondition = `NOT EXISTS (SELECT 1 FROM local_files l WHERE l.relative_path = f.relative_path)`
+
+// mismatchCondition is the condition on the torrent file f that local files
+// are at its path, but none of them has its size.
+const mismatchCondition = `EXISTS (SELECT 1 FROM local_files l WHERE l.relative_path = f.relative_path)
+	AND NOT EXISTS (SELECT 1 FROM local_files l WHERE l.relative_path = f.relative_path AND l.size = f.size)`
+
+// fileFilterConditions returns the SQL conditions on the torrent file f
+// selecting the files that filter keeps, and their arguments.
+func fileFilterConditions(filter models.FileFilter) ([]string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if filter.MinSize > 0 {
+		conditions = append(conditions, "f.size >= ?")
+		args = append(args, filter.MinSize)
+	}
+	var exts []string
+	for _, ext := range filter.Extensions {
+		ext = strings.TrimPrefix(strings.TrimSpace(ext), ".")
+		if ext == "" {
+			continue
+		}
+		// LIKE ignores the case of ASCII letters
+		exts = append(exts, "f.file_name LIKE ?")
+		args = append(args, "%."+ext)
+	}
+	if len(exts) > 0 {
+		conditions = append(conditions, "("+strings.Join(exts, " OR ")+")")
+	}
+	return conditions, args
+}
+
+// GetMissingTorrents returns the torrents expecting files that match no local
+// file, or only local files of another size, with the number and size of
+// those files, most missing and mismatched bytes first. Torrents still
+// downloading are left out, as are the files filter leaves out of the scans.
+func (s *Storage) GetMissingTorrents(ctx context.Context, filter models.FileFilter) ([]models.MissingTorrent, error) {
+	conditions, args := fileFilterConditions(filter)
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	// Un fichier n'est cherché qu'une fois, le regroupement se fait ensuite
+	rows, err := s.read.QueryContext(ctx, `
+		SELECT m.instance, m.torrent_hash, MAX(m.torrent_name), COALESCE(MAX(t.state), ''),
+			COUNT(*), COALESCE(SUM(m.size), 0), SUM(m.missing), COALESCE(SUM(m.missing * m.size), 0),
+			SUM(m.mismatched), COALESCE(SUM(m.mismatched * m.size), 0)
+		FROM (
+			SELECT f.instance, f.torrent_hash, f.torrent_name, f.size, `+missingCondition+` AS missing,
+				`+mismatchCondition+` AS mismatched
+			FROM torrent_files f `+where+`
+		) m
+		LEFT JOIN torrents t ON t.instance = m.instance AND t.hash = m.torrent_hash
+		WHERE t.state IS NULL OR t.state != ?
+		GROUP BY m.instance, m.torrent_hash
+		HAVING SUM(m.missing) + SUM(m.mismatched) > 0
+		ORDER BY SUM((m.missing + m.mismatched) * m.size) DESC, MAX(m.torrent_name) ASC`,
+		append(args, models.TorrentStateDownloading)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query missing torrents: %w", err)
+	}
+	defer rows.Close()
+
+	var torrents []models.MissingTorrent
+	for rows.Next() {
+		var t models.MissingTorrent
+		if err := rows.Scan(&t.Instance, &t.Hash, &t.Name, &t.State, &t.FileCount, &t.TotalSize, &t.MissingFiles, &t.MissingBytes,
+			&t.MismatchedFiles, &t.MismatchedBytes); err != nil {
+			return nil, fmt.Errorf("failed to scan missing torrent: %w", err)
+		}
+		if t.TotalSize > 0 {
+			t.Completeness = float64(t.TotalSize-t.MissingBytes) / float64(t.TotalSize) * 100
+		} else {
+			t.Completeness = float64(t.FileCount-t.MissingFiles) / float64(t.FileCount) * 100
+		}
+		torrents = append(torrents, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating missing torrents: %w", err)
+	}
+	return torrents, nil
+}
+
+// GetMissingFiles returns the files of a torrent that match no local file, or
+// only local files of another size, except those filter leaves out of the
+// scans.
+func (s *Storage) GetMissingFiles(ctx context.Context, instance, hash string, filter models.FileFilter) ([]models.MissingFile, error) {
+	conditions, args := fileFilterConditions(filter)
+	conditions = append([]string{"f.instance = ?", "f.torrent_hash = ?", "(" + missingCondition + " OR " + mismatchCondition + ")"}, conditions...)
+	args = append([]interface{}{instance, hash}, args...)
+
+	rows, err := s.read.QueryContext(ctx, `
+		SELECT f.torrent_hash, f.torrent_name, f.file_name, f.file_path, f.size, f.instance,
+			NOT `+missingCondition+`,
+			COALESCE((SELECT MAX(l.size) FROM local_files l WHERE l.relative_path = f.relative_path), 0)
+		FROM torrent_files f
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY f.file_path ASC`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query missing files: %w", err)
+	}
+	defer rows.Close()
+
+	var files []models.MissingFile
+	for rows.Next() {
+		var f models.MissingFile
+		if err := rows.Scan(&f.TorrentHash, &f.TorrentName, &f.FileName, &f.FilePath, &f.Size, &f.Instance, &f.Mismatched, &f.LocalSize); err != nil {
+			return nil, fmt.Errorf("failed to scan missing file: %w", err)
+		}
+		files = append(files, f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating missing files: %w", err)
+	}
+	return files, nil
+}
+
+// allowedCompletenessColumns defines the whitelist of columns allowed for
+// sorting the torrent completeness.
+var allowedCompletenessColumns = map[string]string{
+	"name":          "name",
+	"instance":      "instance",
+	"state":         "state",
+	"file_count":    "file_count",
+	"present_files": "present_files",
+	"total_size":    "total_size",
+	"missing_bytes": "missing_bytes",
+	"completeness":  "completeness",
+}
+
+// GetTorrentCompleteness returns the number and size of the files of every
+// torrent found locally, with pagination, sorting, search on the name and
+// filtering on the state. Only the torrents at most maxCompleteness percent
+// on disk are returned. As for GetMissingTorrents, the files filter leaves
+// out of the scans are not counted.
+func (s *Storage) GetTorrentCompleteness(ctx context.Context, opts models.QueryOptions, filter models.FileFilter, maxCompleteness float64) ([]models.TorrentCompleteness, int64, error) {
+	opts = normalizeQueryOptions(opts)
+
+	fileConditions, args := fileFilterConditions(filter)
+	where := ""
+	if len(fileConditions) > 0 {
+		where = "WHERE " + strings.Join(fileConditions, " AND ")
+	}
+
+	conditions := []string{"completeness <= ?"}
+	args = append(args, maxCompleteness)
+	if opts.Search != "" {
+		conditions = append(conditions, "name LIKE ?")
+		args = append(args, "%"+opts.Search+"%")
+	}
+	if opts.State != "" {
+		conditions = append(conditions, "state = ?")
+		args = append(args, opts.State)
+	}
+
+	orderClause := "ORDER BY completeness ASC, missing_bytes DESC"
+	if col, ok := allowedCompletenessColumns[opts.Sort]; ok {
+		orderClause = fmt.Sprintf("ORDER BY %s %s", col, opts.Order)
+	}
+
+	// Un torrent par ligne : la pagination se fait après la lecture
+	rows, err := s.read.QueryContext(ctx, `
+		SELECT instance, torrent_hash, name, state, file_count, present_files, total_size, missing_bytes, completeness
+		FROM (
+			SELECT m.instance, m.torrent_hash, MAX(m.torrent_name) AS name, COALESCE(MAX(t.state), '') AS state,
+				COUNT(*) AS file_count, COUNT(*) - SUM(m.missing) AS present_files,
+				COALESCE(SUM(m.size), 0) AS total_size, COALESCE(SUM(m.missing * m.size), 0) AS missing_bytes,
+				CASE WHEN SUM(m.size) > 0 THEN 100.0 * (SUM(m.size) - SUM(m.missing * m.size)) / SUM(m.size)
+					ELSE 100.0 * (COUNT(*) - SUM(m.missing)) / COUNT(*) END AS completeness
+			FROM (
+				SELECT f.instance, f.torrent_hash, f.torrent_name, f.size, `+missingCondition+` AS missing
+				FROM torrent_files f `+where+`
+			) m
+			LEFT JOIN torrents t ON t.instance = m.instance AND t.hash = m.torrent_hash
+			GROUP BY m.instance, m.torrent_hash
+		)
+		WHERE `+strings.Join(conditions, " AND ")+`
+		`+orderClause+`, name ASC`, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query torrent completeness: %w", err)
+	}
+	defer rows.Close()
+
+	var torrents []models.TorrentCompleteness
+	for rows.Next() {
+		var t models.TorrentCompleteness
+		if err := rows.Scan(&t.Instance, &t.Hash, &t.Name, &t.State, &t.FileCount, &t.PresentFiles, &t.TotalSize, &t.MissingBytes, &t.Completeness); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan torrent completeness: %w", err)
+		}
+		torrents = append(torrents, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating torrent completeness: %w", err)
+	}
+
+	total := int64(len(torrents))
+	start := min((opts.Page-1)*opts.PerPage, len(torrents))
+	end := min(start+opts.PerPage, len(torrents))
+	return torrents[start:end], total, nil
+}
@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// updateOrphans records when each orphan became one, as of the torrent files
+// and local files of the generation completed by tx: the new orphans are
+// orphaned at now, the others keep their date, and the files no longer
+// orphan, or gone, are forgotten. A file orphaned again later starts over from that sync.
+func updateOrphans(ctx context.Context, tx *sql.Tx, now time.Time) error {
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM orphaned_files
+		WHERE file_path NOT IN (SELECT l.file_path FROM local_files l WHERE `+isOrphan+`)
+	`); err != nil {
+		return fmt.Errorf("failed to forget former orphans: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT OR IGNORE INTO orphaned_files (file_path, first_orphaned_at)
+		SELECT l.file_path, ? FROM local_files l WHERE `+isOrphan, now.Unix()); err != nil {
+		return fmt.Errorf("failed to record new orphans: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// pathConfig holds the path normalization settings shared by the backends,
+// set with WithUnicodeNormalization, WithPathRewrites and WithRelativeMarkers.
+// They may be changed while the backend is in use, from the WebUI settings.
+type pathConfig struct {
+	mu       sync.RWMutex
+	nfc      bool          // Relative paths in Unicode NFC, see WithUnicodeNormalization
+	rewrites []PathRewrite // Path normalization, see WithPathRewrites
+	markers  []string      // Relative path markers, see WithRelativeMarkers
+}
+
+// rules returns the path rewrites. The slices are replaced, never modified,
+// so they can be read once the lock is released.
+func (p *pathConfig) rules() []PathRewrite {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.rewrites
+}
+
+// PathRewrite replaces the leading From of a path with To, see WithPathRewrites.
+type PathRewrite struct {
+	From string
+	To   string
+}
+
+// WithPathRewrites sets the rules normalizing the paths before they are
+// compared: local file paths are stored rewritten, and the relative paths of
+// both the local and the torrent files are computed on the rewritten paths.
+// The first rule whose From is a leading part of a path applies. A From with
+// backslashes, such as D:\media, matches the paths of a Windows client, whose
+// backslashes are turned into slashes.
+func (s *Storage) WithPathRewrites(rules []PathRewrite) *Storage {
+	s.pathConfig.mu.Lock()
+	defer s.pathConfig.mu.Unlock()
+	s.rewrites = rules
+	return s
+}
+
+// NormalizePath returns path rewritten by the first rule of WithPathRewrites
+// that matches it, or path itself.
+func (p *pathConfig) NormalizePath(path string) string {
+	for _, r := range p.rules() {
+		from := strings.TrimRight(r.From, `/\`)
+		if path != from && !strings.HasPrefix(path, from+"/") && !strings.HasPrefix(path, from+`\`) {
+			continue
+		}
+		rest := path[len(from):]
+		if strings.Contains(r.From, `\`) {
+			rest = strings.ReplaceAll(rest, `\`, "/")
+		}
+		if rewritten := strings.TrimRight(r.To, "/") + rest; rewritten != "" {
+			return rewritten
+		}
+		return "/"
+	}
+	return path
+}
+
+// DiskPath maps a stored local path back to its location on disk, under root.
+// The path may have been rewritten by any rule, or none: the location found
+// is the one under root that NormalizePath turns into path. ok is false when
+// there is none, or when it is root itself.
+func (p *pathConfig) DiskPath(root, path string) (string, bool) {
+	root, path = filepath.Clean(root), filepath.Clean(path)
+
+	candidates := []string{path}
+	for _, r := range p.rules() {
+		to := strings.TrimRight(r.To, "/")
+		if path == to || strings.HasPrefix(path, to+"/") {
+			candidates = append(candidates, strings.TrimRight(r.From, "/")+path[len(to):])
+		}
+	}
+	for _, c := range candidates {
+		rel, err := filepath.Rel(root, c)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if filepath.Clean(p.NormalizePath(c)) == path {
+			return c, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"godatacleaner/internal/models"
+)
+
+// AddProtectedPath stores a protected path pattern and returns its ID.
+func (s *Storage) AddProtectedPath(ctx context.Context, pattern string) (int64, error) {
+	res, err := s.db.ExecContext(ctx, "INSERT INTO protected_paths (pattern) VALUES (?)", pattern)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert protected path: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+// ListProtectedPaths returns all stored protected path patterns.
+func (s *Storage) ListProtectedPaths(ctx context.Context) ([]models.ProtectedPath, error) {
+	rows, err := s.read.QueryContext(ctx, "SELECT id, pattern, created_at FROM protected_paths ORDER BY pattern ASC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query protected paths: %w", err)
+	}
+	defer rows.Close()
+
+	var paths []models.ProtectedPath
+	for rows.Next() {
+		var p models.ProtectedPath
+		if err := rows.Scan(&p.ID, &p.Pattern, &p.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan protected path: %w", err)
+		}
+		paths = append(paths, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating protected paths: %w", err)
+	}
+
+	return paths, nil
+}
+
+// RemoveProtectedPath deletes a stored protected path pattern.
+func (s *Storage) RemoveProtectedPath(ctx context.Context, id int64) error {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM protected_paths WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete protected path: %w", err)
+	}
+	return checkAffected(res)
+}
@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"godatacleaner/internal/models"
+)
+
+// AddQuarantineEntries records files moved to the quarantine.
+func (s *Storage) AddQuarantineEntries(ctx context.Context, entries []models.QuarantineEntry) error {
+	// Handle empty slice gracefully
+	if len(entries) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO quarantine (file_path, disk_path, quarantine_path, batch, size, category, quarantined_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, e := range entries {
+		if _, err := stmt.ExecContext(ctx, e.FilePath, e.DiskPath, e.QuarantinePath, e.Batch, e.Size, e.Category, e.QuarantinedAt); err != nil {
+			return fmt.Errorf("failed to insert quarantine entry: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// allowedQuarantineColumns defines the whitelist of columns allowed for sorting in quarantine queries.
+var allowedQuarantineColumns = map[string]string{
+	"file_path":      "file_path",
+	"size":           "size",
+	"category":       "category",
+	"batch":          "batch",
+	"quarantined_at": "quarantined_at",
+}
+
+// ListQuarantine retrieves the files currently in quarantine (neither restored nor purged)
+// with pagination, sorting, search and category filtering.
+func (s *Storage) ListQuarantine(ctx context.Context, opts models.QueryOptions) ([]models.QuarantineEntry, int64, error) {
+	opts = normalizeQueryOptions(opts)
+
+	conditions := []string{"restored_at IS NULL", "purged_at IS NULL"}
+	var args []interface{}
+
+	if opts.Search != "" {
+		conditions = append(conditions, "file_path LIKE ?")
+		args = append(args, "%"+opts.Search+"%")
+	}
+
+	if opts.Category != "" {
+		conditions = append(conditions, "category = ?")
+		args = append(args, opts.Category)
+	}
+
+	whereClause := "WHERE " + conditions[0]
+	for i := 1; i < len(conditions); i++ {
+		whereClause += " AND " + conditions[i]
+	}
+
+	var total int64
+	if err := s.read.QueryRowContext(ctx, "SELECT COUNT(*) FROM quarantine "+whereClause, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count quarantine entries: %w", err)
+	}
+
+	orderClause := "ORDER BY id DESC"
+	if opts.Sort != "" {
+		if col, ok := allowedQuarantineColumns[opts.Sort]; ok {
+			orderClause = fmt.Sprintf("ORDER BY %s %s", col, opts.Order)
+		}
+	}
+
+	offset := (opts.Page - 1) * opts.PerPage
+	query := fmt.Sprintf(`
+		SELECT id, file_path, disk_path, quarantine_path, batch, size, category, quarantined_at, restored_at, purged_at
+		FROM quarantine %s %s LIMIT ? OFFSET ?`, whereClause, orderClause)
+	args = append(args, opts.PerPage, offset)
+
+	rows, err := s.read.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query quarantine entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []models.QuarantineEntry
+	for rows.Next() {
+		e, err := scanQuarantineEntry(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan quarantine entry: %w", err)
+		}
+		entries = append(entries, *e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating quarantine entries: %w", err)
+	}
+
+	return entries, total, nil
+}
+
+// GetQuarantineEntry retrieves a quarantine entry by ID.
+func (s *Storage) GetQuarantineEntry(ctx context.Context, id int64) (*models.QuarantineEntry, error) {
+	row := s.read.QueryRowContext(ctx, `
+		SELECT id, file_path, disk_path, quarantine_path, batch, size, category, quarantined_at, restored_at, purged_at
+		FROM quarantine WHERE id = ?
+	`, id)
+
+	e, err := scanQuarantineEntry(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quarantine entry: %w", err)
+	}
+	return e, nil
+}
+
+// ListQuarantineBatch returns the IDs of the files of a batch still in quarantine.
+func (s *Storage) ListQuarantineBatch(ctx context.Context, batch string) ([]int64, error) {
+	rows, err := s.read.QueryContext(ctx, `
+		SELECT id FROM quarantine
+		WHERE batch = ? AND restored_at IS NULL AND purged_at IS NULL
+		ORDER BY id ASC
+	`, batch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quarantine batch: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan quarantine entry: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating quarantine batch: %w", err)
+	}
+
+	return ids, nil
+}
+
+// MarkQuarantineRestored flags a quarantine entry as restored.
+func (s *Storage) MarkQuarantineRestored(ctx context.Context, id int64) error {
+	res, err := s.db.ExecContext(ctx, "UPDATE quarantine SET restored_at = ? WHERE id = ?", time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update quarantine entry: %w", err)
+	}
+	return checkAffected(res)
+}
+
+// MarkQuarantineBatchPurged flags every remaining entry of a batch as purged.
+func (s *Storage) MarkQuarantineBatchPurged(ctx context.Context, batch string) error {
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE quarantine SET purged_at = ? WHERE batch = ? AND restored_at IS NULL AND purged_at IS NULL",
+		time.Now(), batch)
+	if err != nil {
+		return fmt.Errorf("failed to update quarantine batch: %w", err)
+	}
+	return nil
+}
+
+func scanQuarantineEntry(row rowScanner) (*models.QuarantineEntry, error) {
+	var e models.QuarantineEntry
+	var restored, purged sql.NullTime
+	if err := row.Scan(&e.ID, &e.FilePath, &e.DiskPath, &e.QuarantinePath, &e.Batch, &e.Size, &e.Category, &e.QuarantinedAt, &restored, &purged); err != nil {
+		return nil, err
+	}
+	if restored.Valid {
+		t := restored.Time
+		e.RestoredAt = &t
+	}
+	if purged.Valid {
+		t := purged.Time
+		e.PurgedAt = &t
+	}
+	return &e, nil
+}
@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"godatacleaner/internal/models"
+)
+
+// releaseFolder returns the release folder of a local file: the directory
+// right below its relative path marker, such as /data/movies/Movie (2020) for
+// /data/movies/Movie (2020)/Subs/en.srt. A file directly below the marker is
+// its own release, single, and a file outside of any marker belongs to its
+// parent directory.
+func releaseFolder(filePath, relativePath string) (folder string, single bool) {
+	prefix, found := strings.CutSuffix(filePath, relativePath)
+	rest, ok := strings.CutPrefix(relativePath, "/")
+	if relativePath == filePath || !found || !ok {
+		return path.Dir(filePath), false
+	}
+	parts := strings.SplitN(rest, "/", 3)
+	if len(parts) < 3 {
+		return filePath, true
+	}
+	return prefix + "/" + parts[0] + "/" + parts[1], false
+}
+
+// releaseOrder compares the release folders on the sort columns of
+// GetOrphanReleases.
+var releaseOrder = map[string]func(a, b models.OrphanRelease) int{
+	"size":       func(a, b models.OrphanRelease) int { return cmp.Compare(a.TotalSize, b.TotalSize) },
+	"file_count": func(a, b models.OrphanRelease) int { return cmp.Compare(a.FileCount, b.FileCount) },
+	"name":       func(a, b models.OrphanRelease) int { return cmp.Compare(a.Name, b.Name) },
+	"mod_time":   func(a, b models.OrphanRelease) int { return a.ModTime.Compare(b.ModTime) },
+}
+
+// GetOrphanReleases returns a page of the orphans matching the filters of
+// opts grouped by release folder (see releaseFolder), the largest first by
+// default, and the number of folders.
+func (s *Storage) GetOrphanReleases(ctx context.Context, opts models.QueryOptions) ([]models.OrphanRelease, int64, error) {
+	opts = normalizeQueryOptions(opts)
+	whereClause, args := orphanFilter(opts)
+
+	rows, err := s.read.QueryContext(ctx, `
+		SELECT l.file_path, l.relative_path, l.size, l.allocated, l.category, l.mtime
+		FROM local_files l
+		LEFT JOIN torrent_files t ON l.relative_path = t.relative_path
+		LEFT JOIN orphaned_files o ON o.file_path = l.file_path
+		`+whereClause, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query orphan releases: %w", err)
+	}
+	defer rows.Close()
+
+	// Regroupement en Go : le dossier dépend du marqueur du chemin relatif
+	groups := map[string]*models.OrphanRelease{}
+	for rows.Next() {
+		var filePath, relativePath, category string
+		var size, allocated, mtime int64
+		if err := rows.Scan(&filePath, &relativePath, &size, &allocated, &category, &mtime); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan orphan release: %w", err)
+		}
+		folder, single := releaseFolder(filePath, relativePath)
+		g, ok := groups[folder]
+		if !ok {
+			g = &models.OrphanRelease{Folder: folder, Name: path.Base(folder), Category: category, Single: single}
+			groups[folder] = g
+		}
+		g.FileCount++
+		g.TotalSize += size
+		g.Allocated += allocated
+		if t := fromUnixTime(mtime); t.After(g.ModTime) {
+			g.ModTime = t
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating orphan releases: %w", err)
+	}
+
+	releases := make([]models.OrphanRelease, 0, len(groups))
+	for _, g := range groups {
+		releases = append(releases, *g)
+	}
+	order, ok := releaseOrder[opts.Sort]
+	desc := opts.Order == "desc"
+	if !ok {
+		order, desc = releaseOrder["size"], true
+	}
+	slices.SortFunc(releases, func(a, b models.OrphanRelease) int {
+		c := cmp.Or(order(a, b), cmp.Compare(a.Folder, b.Folder))
+		if desc {
+			return -c
+		}
+		return c
+	})
+
+	page := paginate(releases, opts)
+	for i := range page {
+		if page[i].LocalFiles, err = s.countFolderFiles(ctx, page[i].Folder); err != nil {
+			return nil, 0, err
+		}
+	}
+	return page, int64(len(releases)), nil
+}
+
+// inFolder selects the local files l below a release folder, or the lone
+// file of a single release, on the index of file_path.
+const inFolder = `(l.file_path = ? OR (l.file_path >= ? AND l.file_path < ?))`
+
+// inFolderArgs returns the arguments of inFolder. '0' follows '/' in byte
+// order.
+func inFolderArgs(folder string) []interface{} {
+	return []interface{}{folder, folder + "/", folder + "0"}
+}
+
+// countFolderFiles returns the number of local files below folder.
+func (s *Storage) countFolderFiles(ctx context.Context, folder string) (int64, error) {
+	var n int64
+	if err := s.read.QueryRowContext(ctx, "SELECT COUNT(*) FROM local_files l WHERE "+inFolder, inFolderArgs(folder)...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count folder files: %w", err)
+	}
+	return n, nil
+}
+
+// GetOrphanReleaseFiles returns the orphans below a release folder, or the
+// lone file of a single release, that match the filters of opts.
+func (s *Storage) GetOrphanReleaseFiles(ctx context.Context, folder string, opts models.QueryOptions) ([]models.OrphanFile, error) {
+	whereClause, args := orphanFilter(opts)
+	args = append(args, inFolderArgs(folder)...)
+
+	rows, err := s.read.QueryContext(ctx, `
+		SELECT l.id, l.file_path, l.file_name, l.relative_path, l.size, l.allocated, l.category, l.links, l.mtime, COALESCE(o.first_orphaned_at, 0)
+		FROM local_files l
+		LEFT JOIN torrent_files t ON l.relative_path = t.relative_path
+		LEFT JOIN orphaned_files o ON o.file_path = l.file_path
+		`+whereClause+` AND `+inFolder+`
+		ORDER BY l.file_path`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query orphan release files: %w", err)
+	}
+	defer rows.Close()
+
+	var files []models.OrphanFile
+	for rows.Next() {
+		var f models.OrphanFile
+		var mtime, orphaned int64
+		if err := rows.Scan(&f.ID, &f.FilePath, &f.FileName, &f.RelativePath, &f.Size, &f.Allocated, &f.Category, &f.Links, &mtime, &orphaned); err != nil {
+			return nil, fmt.Errorf("failed to scan orphan file: %w", err)
+		}
+		f.ModTime = fromUnixTime(mtime)
+		f.OrphanedAt = fromUnixTime(orphaned)
+		files = append(files, f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating orphan release files: %w", err)
+	}
+
+	return files, nil
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"godatacleaner/internal/models"
+)
+
+// CreateSession stores a new session for username, valid for ttl.
+func (s *Storage) CreateSession(ctx context.Context, tokenHash, username string, remember bool, ttl time.Duration) error {
+	now := time.Now()
+	_, err := s.db.ExecContext(ctx,
+		"INSERT INTO sessions (token_hash, username, remember, created_at, expires_at) VALUES (?, ?, ?, ?, ?)",
+		tokenHash, username, remember, now.UnixMilli(), now.Add(ttl).UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to insert session: %w", err)
+	}
+	return nil
+}
+
+// GetSession returns a session that has not expired yet.
+func (s *Storage) GetSession(ctx context.Context, tokenHash string) (*models.Session, error) {
+	var (
+		sess               models.Session
+		created, expiresAt int64
+	)
+	err := s.read.QueryRowContext(ctx,
+		"SELECT username, remember, created_at, expires_at FROM sessions WHERE token_hash = ? AND expires_at > ?",
+		tokenHash, time.Now().UnixMilli(),
+	).Scan(&sess.Username, &sess.Remember, &created, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	sess.CreatedAt = time.UnixMilli(created)
+	sess.ExpiresAt = time.UnixMilli(expiresAt)
+	return &sess, nil
+}
+
+// ExtendSession pushes back the expiration of a session to ttl from now.
+// The row is only written when it would gain more than minGain, so an active
+// session is not updated on every request.
+func (s *Storage) ExtendSession(ctx context.Context, tokenHash string, ttl, minGain time.Duration) error {
+	expiresAt := time.Now().Add(ttl)
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE sessions SET expires_at = ? WHERE token_hash = ? AND expires_at < ?",
+		expiresAt.UnixMilli(), tokenHash, expiresAt.Add(-minGain).UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to extend session: %w", err)
+	}
+	return nil
+}
+
+// DeleteSession deletes a session.
+func (s *Storage) DeleteSession(ctx context.Context, tokenHash string) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM sessions WHERE token_hash = ?", tokenHash); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// DeleteUserSessions deletes every session of a user.
+func (s *Storage) DeleteUserSessions(ctx context.Context, username string) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM sessions WHERE username = ?", username); err != nil {
+		return fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpiredSessions deletes the sessions that have expired.
+func (s *Storage) DeleteExpiredSessions(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM sessions WHERE expires_at <= ?", time.Now().UnixMilli()); err != nil {
+		return fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+	return nil
+}
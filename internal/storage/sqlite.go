@@ -5,87 +5,268 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"path"
+	"runtime"
+	"sort"
 	"strings"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+	"golang.org/x/text/unicode/norm"
+
 	"godatacleaner/internal/models"
 )
 
 // Storage manages SQLite database operations.
 type Storage struct {
-	db        *sql.DB
+	db        *sql.DB // Single connection, for the writes and their transactions
+	read      *sql.DB // Pool of read-only connections, for the other queries
 	batchSize int
+	pathConfig
+	counts countCache // Totals of the paginated lists, see WithCountCache
 }
 
 // NewStorage creates a new SQLite storage with WAL mode optimizations.
-// DSN includes: WAL journal mode, 10000 page cache, 5000ms busy timeout, shared cache.
+// DSN includes: WAL journal mode, 10000 page cache, 5000ms busy timeout.
+// Writes go through a single connection, so that they never fail with
+// "database is locked", while reads use a pool of read-only connections: in
+// WAL mode they see the last commit and are not blocked by a write in
+// progress, such as the transaction of a sync.
 func NewStorage(path string, batchSize int) (*Storage, error) {
 	// Build DSN with optimizations as per requirements 3.1, 3.6
-	dsn := fmt.Sprintf("%s?_journal_mode=WAL&_cache_size=10000&_busy_timeout=5000&cache=shared", path)
+	dsn := fmt.Sprintf("%s?_journal_mode=WAL&_cache_size=10000&_busy_timeout=5000", path)
 
 	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	// Set max open connections to 1 to avoid "database is locked" errors
+	// Une seule connexion d'écriture : les écritures sont sérialisées
 	db.SetMaxOpenConns(1)
 
-	// Verify connection
+	// Verify connection, which also switches the database to WAL mode before
+	// the readers connect
 	if err := db.Ping(); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	read, err := sql.Open("sqlite3", fmt.Sprintf("%s?_cache_size=10000&_busy_timeout=5000&_query_only=true", path))
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	read.SetMaxOpenConns(max(4, runtime.NumCPU()))
+	if err := read.Ping(); err != nil {
+		db.Close()
+		read.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
 	return &Storage{
 		db:        db,
+		read:      read,
 		batchSize: batchSize,
 	}, nil
 }
 
 // Initialize creates the database tables and indexes.
-// Creates torrent_files and local_files tables with appropriate indexes.
+// The synced tables are written by generation, see initGenerations.
 func (s *Storage) Initialize(ctx context.Context) error {
 	// SQL statements for table and index creation as per requirements 3.2, 3.3
 	statements := []string{
-		// Table des fichiers torrents
-		`CREATE TABLE IF NOT EXISTS torrent_files (
+		// Répertoires lus par le dernier scan, pour les scans incrémentaux
+		`CREATE TABLE IF NOT EXISTS scan_dirs (
+			path TEXT PRIMARY KEY,
+			mtime INTEGER NOT NULL -- Unix nanoseconds, 0 to read the directory again
+		)`,
+
+		// Table des tâches de nettoyage planifiées
+		`CREATE TABLE IF NOT EXISTS cleanup_jobs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE,
+			schedule TEXT NOT NULL,
+			category TEXT NOT NULL DEFAULT '',
+			quarantine INTEGER NOT NULL DEFAULT 0,
+			dry_run INTEGER NOT NULL DEFAULT 0,
+			enabled INTEGER NOT NULL DEFAULT 1,
+			last_run_at DATETIME,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// Historique des exécutions de nettoyage
+		`CREATE TABLE IF NOT EXISTS cleanup_runs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			job_id INTEGER NOT NULL,
+			started_at DATETIME NOT NULL,
+			finished_at DATETIME NOT NULL,
+			dry_run INTEGER NOT NULL,
+			quarantine INTEGER NOT NULL,
+			files_removed INTEGER NOT NULL,
+			bytes_reclaimed INTEGER NOT NULL,
+			failed INTEGER NOT NULL,
+			error TEXT NOT NULL DEFAULT ''
+		)`,
+		// Index sur job_id
+		`CREATE INDEX IF NOT EXISTS idx_cleanup_run_job ON cleanup_runs(job_id)`,
+
+		// Fichiers traités par chaque exécution
+		`CREATE TABLE IF NOT EXISTS cleanup_run_files (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			torrent_hash TEXT NOT NULL,
-			torrent_name TEXT NOT NULL,
-			file_name TEXT NOT NULL,
+			run_id INTEGER NOT NULL,
 			file_path TEXT NOT NULL,
-			relative_path TEXT NOT NULL,
 			size INTEGER NOT NULL,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			moved_to TEXT NOT NULL DEFAULT '',
+			error TEXT NOT NULL DEFAULT ''
 		)`,
-		// Index sur torrent_hash
-		`CREATE INDEX IF NOT EXISTS idx_torrent_hash ON torrent_files(torrent_hash)`,
-		// Index sur file_path
-		`CREATE INDEX IF NOT EXISTS idx_torrent_file_path ON torrent_files(file_path)`,
-		// Index sur file_name
-		`CREATE INDEX IF NOT EXISTS idx_torrent_file_name ON torrent_files(file_name)`,
-		// Index sur relative_path pour les JOINs orphelins
-		`CREATE INDEX IF NOT EXISTS idx_torrent_relative_path ON torrent_files(relative_path)`,
-
-		// Table des fichiers locaux
-		`CREATE TABLE IF NOT EXISTS local_files (
+		// Index sur run_id
+		`CREATE INDEX IF NOT EXISTS idx_cleanup_run_file_run ON cleanup_run_files(run_id)`,
+
+		// Historique des synchronisations
+		`CREATE TABLE IF NOT EXISTS sync_runs (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			file_path TEXT NOT NULL UNIQUE,
-			file_name TEXT NOT NULL,
-			relative_path TEXT NOT NULL,
+			started_at DATETIME NOT NULL,
+			finished_at DATETIME NOT NULL,
+			status TEXT NOT NULL,
+			full INTEGER NOT NULL,
+			torrents_synced INTEGER NOT NULL,
+			torrents INTEGER NOT NULL,
+			torrents_failed INTEGER NOT NULL,
+			torrent_files INTEGER NOT NULL,
+			local_synced INTEGER NOT NULL,
+			local_files INTEGER NOT NULL,
+			error TEXT NOT NULL DEFAULT ''
+		)`,
+		// Torrents et clients qu'une synchronisation n'a pas pu lire
+		`CREATE TABLE IF NOT EXISTS sync_errors (
+			run_id INTEGER NOT NULL,
+			instance TEXT NOT NULL,
+			hash TEXT NOT NULL,
+			name TEXT NOT NULL,
+			error TEXT NOT NULL,
+			kept_files INTEGER NOT NULL
+		)`,
+		// Index sur run_id
+		`CREATE INDEX IF NOT EXISTS idx_sync_error_run ON sync_errors(run_id)`,
+
+		// Fichiers locaux ajoutés, supprimés ou redimensionnés par une synchronisation
+		`CREATE TABLE IF NOT EXISTS sync_changes (
+			run_id INTEGER NOT NULL,
+			file_path TEXT NOT NULL,
+			change TEXT NOT NULL,
+			size INTEGER NOT NULL,
+			old_size INTEGER NOT NULL DEFAULT 0
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_sync_changes_run ON sync_changes(run_id)`,
+
+		// Taille de la bibliothèque et des orphelins par jour et par catégorie
+		`CREATE TABLE IF NOT EXISTS library_history (
+			day TEXT NOT NULL, -- YYYY-MM-DD, heure locale
+			category TEXT NOT NULL,
+			file_count INTEGER NOT NULL,
+			total_size INTEGER NOT NULL,
+			orphan_files INTEGER NOT NULL,
+			orphan_size INTEGER NOT NULL,
+			PRIMARY KEY (day, category)
+		)`,
+
+		// Date à laquelle chaque orphelin actuel l'est devenu
+		`CREATE TABLE IF NOT EXISTS orphaned_files (
+			file_path TEXT PRIMARY KEY,
+			first_orphaned_at INTEGER NOT NULL -- Unix seconds
+		)`,
+
+		// Fichiers mis en quarantaine, pour pouvoir les restaurer
+		`CREATE TABLE IF NOT EXISTS quarantine (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			file_path TEXT NOT NULL,
+			disk_path TEXT NOT NULL,
+			quarantine_path TEXT NOT NULL,
+			batch TEXT NOT NULL,
 			size INTEGER NOT NULL,
 			category TEXT NOT NULL,
+			quarantined_at DATETIME NOT NULL,
+			restored_at DATETIME,
+			purged_at DATETIME
+		)`,
+		// Index sur batch
+		`CREATE INDEX IF NOT EXISTS idx_quarantine_batch ON quarantine(batch)`,
+
+		// Verrous partagés entre processus (ex: une seule synchronisation à la fois)
+		`CREATE TABLE IF NOT EXISTS locks (
+			name TEXT PRIMARY KEY,
+			owner TEXT NOT NULL,
+			acquired_at INTEGER NOT NULL, -- Unix milliseconds
+			expires_at INTEGER NOT NULL   -- Unix milliseconds
+		)`,
+
+		// Chemins protégés, jamais supprimés par le nettoyage
+		`CREATE TABLE IF NOT EXISTS protected_paths (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			pattern TEXT NOT NULL UNIQUE,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// Fichiers et dossiers connus, jamais comptés comme orphelins
+		`CREATE TABLE IF NOT EXISTS ignored_paths (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			path TEXT NOT NULL UNIQUE,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// Filtres enregistrés des listes de fichiers de la WebUI
+		`CREATE TABLE IF NOT EXISTS saved_views (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			list TEXT NOT NULL,
+			search TEXT NOT NULL DEFAULT '',
+			category TEXT NOT NULL DEFAULT '',
+			min_size INTEGER NOT NULL DEFAULT 0,
+			max_size INTEGER NOT NULL DEFAULT 0,
+			sort TEXT NOT NULL DEFAULT '',
+			sort_order TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// Colonnes affichées par chaque utilisateur dans les listes de la WebUI
+		`CREATE TABLE IF NOT EXISTS column_layouts (
+			username TEXT NOT NULL,
+			list TEXT NOT NULL,
+			columns TEXT NOT NULL, -- JSON array of column names
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (username, list)
+		)`,
+
+		// Comptes de la WebUI et de l'API
+		`CREATE TABLE IF NOT EXISTS users (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			username TEXT NOT NULL UNIQUE,
+			password_hash TEXT NOT NULL,
+			role TEXT NOT NULL,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 		)`,
-		// Index sur file_path
-		`CREATE INDEX IF NOT EXISTS idx_local_file_path ON local_files(file_path)`,
-		// Index sur category
-		`CREATE INDEX IF NOT EXISTS idx_local_category ON local_files(category)`,
-		// Index sur file_name
-		`CREATE INDEX IF NOT EXISTS idx_local_file_name ON local_files(file_name)`,
-		// Index sur relative_path pour les JOINs orphelins
-		`CREATE INDEX IF NOT EXISTS idx_local_relative_path ON local_files(relative_path)`,
+
+		// Clés d'API des scripts, seule leur empreinte est conservée
+		`CREATE TABLE IF NOT EXISTS api_keys (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			prefix TEXT NOT NULL,
+			key_hash TEXT NOT NULL UNIQUE,
+			scope TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			last_used_at DATETIME,
+			revoked_at DATETIME
+		)`,
+
+		// Sessions de la WebUI, identifiées par l'empreinte de leur jeton
+		`CREATE TABLE IF NOT EXISTS sessions (
+			token_hash TEXT PRIMARY KEY,
+			username TEXT NOT NULL,
+			remember INTEGER NOT NULL DEFAULT 0,
+			created_at INTEGER NOT NULL, -- Unix milliseconds
+			expires_at INTEGER NOT NULL  -- Unix milliseconds
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_sessions_username ON sessions(username)`,
 	}
 
 	for _, stmt := range statements {
@@ -94,14 +275,107 @@ func (s *Storage) Initialize(ctx context.Context) error {
 		}
 	}
 
-	return nil
+	// Colonnes ajoutées après la création initiale des tables
+	columns := []struct{ table, column, definition string }{
+		{"cleanup_jobs", "companions", "INTEGER NOT NULL DEFAULT 0"},
+		{"cleanup_jobs", "archive", "INTEGER NOT NULL DEFAULT 0"},
+		{"sync_runs", "changes_recorded", "INTEGER NOT NULL DEFAULT 0"},
+		{"ignored_paths", "pattern", "INTEGER NOT NULL DEFAULT 0"},
+		{"cleanup_runs", "source", "TEXT NOT NULL DEFAULT '" + models.CleanupSourceJob + "'"},
+		{"cleanup_runs", "username", "TEXT NOT NULL DEFAULT ''"},
+	}
+
+	for _, c := range columns {
+		if _, err := s.addColumn(ctx, c.table, c.column, c.definition); err != nil {
+			return err
+		}
+	}
+
+	// Torrents, trackers, fichiers torrents et fichiers locaux, écrits par génération
+	return s.initGenerations(ctx)
+}
+
+// addColumn adds a column to an existing table if it is not already present,
+// and reports whether it was added.
+func (s *Storage) addColumn(ctx context.Context, table, column, definition string) (bool, error) {
+	rows, err := s.read.QueryContext(ctx, fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, fmt.Errorf("failed to read table info: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid        int
+			name, typ  string
+			notNull    bool
+			defaultVal sql.NullString
+			pk         int
+		)
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &defaultVal, &pk); err != nil {
+			return false, fmt.Errorf("failed to scan table info: %w", err)
+		}
+		if name == column {
+			return false, nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("error iterating table info: %w", err)
+	}
+	rows.Close()
+
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return false, fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
+	}
+	return true, nil
+}
+
+// WithUnicodeNormalization makes the relative paths, on which local files are
+// matched to torrent files, computed on the Unicode NFC form of the paths.
+// Files copied from macOS have decomposed (NFD) names, which otherwise never
+// match the names of the torrent. The file paths themselves are stored as is,
+// to still reach the files on disk. Paths already stored keep their relative
+// path until a full sync.
+func (s *Storage) WithUnicodeNormalization(enabled bool) *Storage {
+	s.pathConfig.mu.Lock()
+	defer s.pathConfig.mu.Unlock()
+	s.nfc = enabled
+	return s
+}
+
+// DefaultRelativeMarkers are the relative path markers used when
+// WithRelativeMarkers sets none.
+var DefaultRelativeMarkers = []string{"/movies/", "/shows/", "/4k/"}
+
+// WithRelativeMarkers sets the directories, such as /movies/, from which the
+// relative paths are taken (see extractRelativePath), tried in order. Paths
+// already stored get their new relative path on the next sync.
+func (s *Storage) WithRelativeMarkers(markers []string) *Storage {
+	s.pathConfig.mu.Lock()
+	defer s.pathConfig.mu.Unlock()
+	s.markers = markers
+	return s
+}
+
+// relativePath returns the relative path of a full path, on which local
+// files are matched to torrent files (see extractRelativePath).
+func (p *pathConfig) relativePath(fullPath string) string {
+	p.mu.RLock()
+	nfc, markers := p.nfc, p.markers
+	p.mu.RUnlock()
+	if nfc {
+		fullPath = norm.NFC.String(fullPath)
+	}
+	if len(markers) == 0 {
+		markers = DefaultRelativeMarkers
+	}
+	return extractRelativePath(fullPath, markers)
 }
 
 // extractRelativePath extracts the relative path from a full path.
-// It looks for /movies/, /shows/, or /4k/ and returns the path from that point.
-// If none found, returns the original path.
-func extractRelativePath(fullPath string) string {
-	markers := []string{"/movies/", "/shows/", "/4k/"}
+// It looks for the first of markers found in the path and returns the path
+// from that point. If none found, returns the original path.
+func extractRelativePath(fullPath string, markers []string) string {
 	for _, marker := range markers {
 		if idx := strings.Index(fullPath, marker); idx != -1 {
 			return fullPath[idx:]
@@ -110,16 +384,14 @@ func extractRelativePath(fullPath string) string {
 	return fullPath
 }
 
-// normalizeLocalPath removes the /mnt prefix from local paths to match torrent paths.
-func normalizeLocalPath(path string) string {
-	if strings.HasPrefix(path, "/mnt") {
-		return path[4:] // Remove "/mnt"
-	}
-	return path
-}
+// InsertProgress is called after each batch with the number of files processed so far.
+type InsertProgress func(inserted int)
 
-// InsertTorrentFiles inserts torrent files in batches using prepared statements.
+// InsertTorrentFiles adds torrent files to the current generation, in
+// batches using multi-row INSERT statements.
 func (s *Storage) InsertTorrentFiles(ctx context.Context, files []models.TorrentFile) error {
+	defer s.counts.reset()
+
 	// Handle empty slice gracefully
 	if len(files) == 0 {
 		return nil
@@ -132,43 +404,37 @@ func (s *Storage) InsertTorrentFiles(ctx context.Context, files []models.Torrent
 	}
 	defer tx.Rollback()
 
-	// Prepare the insert statement
-	stmt, err := tx.PrepareContext(ctx, `
-		INSERT INTO torrent_files (torrent_hash, torrent_name, file_name, file_path, relative_path, size)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`)
-	if err != nil {
-		return fmt.Errorf("failed to prepare statement: %w", err)
+	var gen int64
+	if err := tx.QueryRowContext(ctx, "SELECT "+currentGeneration).Scan(&gen); err != nil {
+		return fmt.Errorf("failed to read current generation: %w", err)
 	}
-	defer stmt.Close()
-
-	// Insert files in batches
-	for i := 0; i < len(files); i += s.batchSize {
-		end := i + s.batchSize
-		if end > len(files) {
-			end = len(files)
-		}
-
-		// Insert each file in the current batch
-		for _, file := range files[i:end] {
-			relativePath := extractRelativePath(file.FilePath)
-			_, err := stmt.ExecContext(ctx, file.TorrentHash, file.TorrentName, file.FileName, file.FilePath, relativePath, file.Size)
-			if err != nil {
-				return fmt.Errorf("failed to insert torrent file: %w", err)
-			}
+	insert := newRowInserter(tx, "INSERT", "torrent_file_rows",
+		"torrent_hash, torrent_name, file_name, file_path, relative_path, size, instance, gen_from", "")
+	defer insert.close()
+	for _, file := range files {
+		err := insert.add(ctx, file.TorrentHash, file.TorrentName, file.FileName, file.FilePath,
+			s.relativePath(s.NormalizePath(file.FilePath)), file.Size, file.Instance, gen)
+		if err != nil {
+			return fmt.Errorf("failed to insert torrent file: %w", err)
 		}
 	}
+	if err := insert.flush(ctx); err != nil {
+		return fmt.Errorf("failed to insert torrent file: %w", err)
+	}
 
 	// Commit the transaction
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
-
 	return nil
 }
 
-// InsertLocalFiles inserts local files in batches using prepared statements.
+// InsertLocalFiles adds local files to the current generation. A file
+// already recorded is updated in every generation, keeping its created_at,
+// and its checksum unless the size or the modification time changed.
 func (s *Storage) InsertLocalFiles(ctx context.Context, files []models.LocalFile) error {
+	defer s.counts.reset()
+
 	// Handle empty slice gracefully
 	if len(files) == 0 {
 		return nil
@@ -181,32 +447,39 @@ func (s *Storage) InsertLocalFiles(ctx context.Context, files []models.LocalFile
 	}
 	defer tx.Rollback()
 
-	// Prepare the insert statement with INSERT OR REPLACE for UNIQUE constraint on file_path
-	stmt, err := tx.PrepareContext(ctx, `
-		INSERT OR REPLACE INTO local_files (file_path, file_name, relative_path, size, category)
-		VALUES (?, ?, ?, ?, ?)
+	update, err := tx.PrepareContext(ctx, `
+		UPDATE local_file_rows SET
+			file_name = ?, relative_path = ?, size = ?, allocated = ?, category = ?, device = ?, inode = ?, links = ?,
+			mtime = ?, checksum = CASE WHEN size = ?3 AND mtime = ?9 THEN checksum ELSE '' END
+		WHERE file_path = ?
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %w", err)
 	}
-	defer stmt.Close()
-
-	// Insert files in batches
-	for i := 0; i < len(files); i += s.batchSize {
-		end := i + s.batchSize
-		if end > len(files) {
-			end = len(files)
-		}
-
-		// Insert each file in the current batch
-		for _, file := range files[i:end] {
-			// Normalize path by removing /mnt prefix
-			normalizedPath := normalizeLocalPath(file.FilePath)
-			relativePath := extractRelativePath(normalizedPath)
-			_, err := stmt.ExecContext(ctx, normalizedPath, file.FileName, relativePath, file.Size, file.Category)
-			if err != nil {
-				return fmt.Errorf("failed to insert local file: %w", err)
-			}
+	defer update.Close()
+	insert, err := tx.PrepareContext(ctx, `
+		INSERT INTO local_file_rows (file_name, relative_path, size, allocated, category, device, inode, links, mtime, file_path, gen_from)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, `+currentGeneration+`)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer insert.Close()
+
+	for _, file := range files {
+		// Normalize path with the rewrite rules
+		normalizedPath := s.NormalizePath(file.FilePath)
+		args := []any{file.FileName, s.relativePath(normalizedPath), file.Size, file.Allocated, file.Category,
+			file.Device, file.Inode, file.Links, unixTime(file.ModTime), normalizedPath}
+		res, err := update.ExecContext(ctx, args...)
+		if err != nil {
+			return fmt.Errorf("failed to update local file: %w", err)
+		}
+		if n, err := res.RowsAffected(); err != nil || n > 0 {
+			continue
+		}
+		if _, err := insert.ExecContext(ctx, args...); err != nil {
+			return fmt.Errorf("failed to insert local file: %w", err)
 		}
 	}
 
@@ -214,25 +487,201 @@ func (s *Storage) InsertLocalFiles(ctx context.Context, files []models.LocalFile
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
-
 	return nil
 }
 
 // ClearTorrentFiles removes all torrent files from the database.
 func (s *Storage) ClearTorrentFiles(ctx context.Context) error {
-	_, err := s.db.ExecContext(ctx, "DELETE FROM torrent_files")
+	defer s.counts.reset()
+
+	_, err := s.db.ExecContext(ctx, "DELETE FROM torrent_file_rows")
 	if err != nil {
 		return fmt.Errorf("failed to clear torrent_files: %w", err)
 	}
 	return nil
 }
 
-// ClearLocalFiles removes all local files from the database.
+// ClearLocalFiles removes all local files from the database, and the
+// directories of the previous scan so that the next scan reads them all.
 func (s *Storage) ClearLocalFiles(ctx context.Context) error {
-	_, err := s.db.ExecContext(ctx, "DELETE FROM local_files")
+	defer s.counts.reset()
+
+	_, err := s.db.ExecContext(ctx, "DELETE FROM local_file_rows")
 	if err != nil {
 		return fmt.Errorf("failed to clear local_files: %w", err)
 	}
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM scan_dirs"); err != nil {
+		return fmt.Errorf("failed to clear scan_dirs: %w", err)
+	}
+	return nil
+}
+
+// replaceScanDirs replaces the directories of the previous scan within tx,
+// writing only the new, changed and missing ones.
+func replaceScanDirs(ctx context.Context, tx *sql.Tx, dirs []models.ScanDir) error {
+	rows, err := tx.QueryContext(ctx, "SELECT path, mtime FROM scan_dirs")
+	if err != nil {
+		return fmt.Errorf("failed to query scan directories: %w", err)
+	}
+	existing := make(map[string]int64)
+	for rows.Next() {
+		var path string
+		var mtime int64
+		if err := rows.Scan(&path, &mtime); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan scan directory: %w", err)
+		}
+		existing[path] = mtime
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating scan directories: %w", err)
+	}
+
+	insert := newRowInserter(tx, "INSERT OR REPLACE", "scan_dirs", "path, mtime", "")
+	defer insert.close()
+
+	for _, d := range dirs {
+		var mtime int64
+		if !d.ModTime.IsZero() {
+			mtime = d.ModTime.UnixNano()
+		}
+		old, ok := existing[d.Path]
+		delete(existing, d.Path)
+		if ok && old == mtime {
+			continue
+		}
+		if err := insert.add(ctx, d.Path, mtime); err != nil {
+			return fmt.Errorf("failed to insert scan directory: %w", err)
+		}
+	}
+	if err := insert.flush(ctx); err != nil {
+		return fmt.Errorf("failed to insert scan directory: %w", err)
+	}
+
+	del, err := tx.PrepareContext(ctx, "DELETE FROM scan_dirs WHERE path = ?")
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer del.Close()
+	for path := range existing {
+		if _, err := del.ExecContext(ctx, path); err != nil {
+			return fmt.Errorf("failed to delete scan directory: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListScanDirs returns the directories read by the previous scan.
+func (s *Storage) ListScanDirs(ctx context.Context) ([]models.ScanDir, error) {
+	rows, err := s.read.QueryContext(ctx, "SELECT path, mtime FROM scan_dirs")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scan directories: %w", err)
+	}
+	defer rows.Close()
+
+	var dirs []models.ScanDir
+	for rows.Next() {
+		var d models.ScanDir
+		var mtime int64
+		if err := rows.Scan(&d.Path, &mtime); err != nil {
+			return nil, fmt.Errorf("failed to scan scan directory: %w", err)
+		}
+		if mtime != 0 {
+			d.ModTime = time.Unix(0, mtime)
+		}
+		dirs = append(dirs, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating scan directories: %w", err)
+	}
+	return dirs, nil
+}
+
+// CountLocalFiles returns the number of local files.
+func (s *Storage) CountLocalFiles(ctx context.Context) (int64, error) {
+	var n int64
+	if err := s.read.QueryRowContext(ctx, "SELECT COUNT(*) FROM local_files").Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count local files: %w", err)
+	}
+	return n, nil
+}
+
+// ListAllLocalFiles returns every local file, with its identity on disk.
+func (s *Storage) ListAllLocalFiles(ctx context.Context) ([]models.LocalFile, error) {
+	rows, err := s.read.QueryContext(ctx, "SELECT file_path, file_name, size, allocated, category, device, inode, links, mtime FROM local_files")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query local files: %w", err)
+	}
+	defer rows.Close()
+
+	var files []models.LocalFile
+	for rows.Next() {
+		var f models.LocalFile
+		var mtime int64
+		if err := rows.Scan(&f.FilePath, &f.FileName, &f.Size, &f.Allocated, &f.Category, &f.Device, &f.Inode, &f.Links, &mtime); err != nil {
+			return nil, fmt.Errorf("failed to scan local file: %w", err)
+		}
+		f.ModTime = fromUnixTime(mtime)
+		files = append(files, f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating local files: %w", err)
+	}
+	return files, nil
+}
+
+// HasTorrentFile reports whether a local path (normalized) is expected by a torrent.
+// Matching uses the same relative_path comparison as orphan detection, and a
+// file hard linked to a file expected by a torrent is expected too.
+func (s *Storage) HasTorrentFile(ctx context.Context, localPath string) (bool, error) {
+	var exists bool
+	err := s.read.QueryRowContext(ctx, `
+		SELECT EXISTS(SELECT 1 FROM torrent_files WHERE relative_path = ?)
+		OR EXISTS(SELECT 1 FROM local_files l WHERE l.file_path = ? AND NOT `+notHardlinked+`)`,
+		s.relativePath(localPath), localPath,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check torrent file: %w", err)
+	}
+	return exists, nil
+}
+
+// DeleteLocalFiles removes the given local files from the database, from
+// every generation. Paths must be the normalized file_path values as
+// returned by the queries.
+func (s *Storage) DeleteLocalFiles(ctx context.Context, paths []string) error {
+	defer s.counts.reset()
+
+	// Handle empty slice gracefully
+	if len(paths) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, "DELETE FROM local_file_rows WHERE file_path = ?")
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, path := range paths {
+		if _, err := stmt.ExecContext(ctx, path); err != nil {
+			return fmt.Errorf("failed to delete local file: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
@@ -244,6 +693,7 @@ var allowedTorrentColumns = map[string]string{
 	"file_name":    "file_name",
 	"file_path":    "file_path",
 	"size":         "size",
+	"instance":     "instance",
 }
 
 // allowedLocalColumns defines the whitelist of columns allowed for sorting in local_files queries.
@@ -252,14 +702,18 @@ var allowedLocalColumns = map[string]string{
 	"file_name": "file_name",
 	"size":      "size",
 	"category":  "category",
+	"mod_time":  "mtime",
 }
 
 // allowedOrphanColumns defines the whitelist of columns allowed for sorting in orphan queries.
 var allowedOrphanColumns = map[string]string{
-	"file_path": "l.file_path",
-	"file_name": "l.file_name",
-	"size":      "l.size",
-	"category":  "l.category",
+	"file_path":   "l.file_path",
+	"file_name":   "l.file_name",
+	"size":        "l.size",
+	"category":    "l.category",
+	"mod_time":    "l.mtime",
+	"orphaned_at": "COALESCE(o.first_orphaned_at, 0)",
+	"links":       "l.links",
 }
 
 // normalizeQueryOptions sets default values for pagination options.
@@ -315,42 +769,53 @@ func (s *Storage) GetTorrentFiles(ctx context.Context, opts models.QueryOptions)
 	}
 
 	// Count total matching records
-	var total int64
-	err := s.db.QueryRowContext(ctx, countQuery, args...).Scan(&total)
+	total, err := s.count(ctx, countKey("torrent_files", opts), countQuery, args...)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to count torrent files: %w", err)
 	}
 
-	// Build ORDER BY clause with whitelist validation
-	orderClause := "ORDER BY id ASC"
-	if opts.Sort != "" {
-		if col, ok := allowedTorrentColumns[opts.Sort]; ok {
-			if opts.Unique {
-				orderClause = fmt.Sprintf("ORDER BY t.%s %s", col, opts.Order)
-			} else {
-				orderClause = fmt.Sprintf("ORDER BY %s %s", col, opts.Order)
-			}
-		}
+	// Build ORDER BY clause with whitelist validation, the ID breaking ties
+	idCol := "id"
+	if opts.Unique {
+		idCol = "t.id"
+	}
+	sortCol, ok := allowedTorrentColumns[opts.Sort]
+	if ok && opts.Unique {
+		sortCol = "t." + sortCol
+	}
+	order := opts.Order
+	if !ok {
+		order = "asc"
 	}
+	orderClause, afterCond, afterArgs := keyset(sortCol, idCol, order, opts.After)
 
-	// Calculate offset for pagination
+	// Calculate offset for pagination, or start after the cursor
 	offset := (opts.Page - 1) * opts.PerPage
+	if afterCond != "" {
+		offset = 0
+		if whereClause == "" {
+			whereClause = "WHERE " + afterCond
+		} else {
+			whereClause = "WHERE (" + strings.TrimPrefix(whereClause, "WHERE ") + ") AND " + afterCond
+		}
+		args = append(args, afterArgs...)
+	}
 
 	// Build and execute the main query
 	if opts.Unique {
 		query = fmt.Sprintf(
-			"SELECT t.torrent_hash, t.torrent_name, t.file_name, t.file_path, t.size FROM %s %s %s LIMIT ? OFFSET ?",
+			"SELECT t.id, t.torrent_hash, t.torrent_name, t.file_name, t.file_path, t.size, t.instance FROM %s %s %s LIMIT ? OFFSET ?",
 			fromClause, whereClause, orderClause,
 		)
 	} else {
 		query = fmt.Sprintf(
-			"SELECT torrent_hash, torrent_name, file_name, file_path, size FROM %s %s %s LIMIT ? OFFSET ?",
+			"SELECT id, torrent_hash, torrent_name, file_name, file_path, size, instance FROM %s %s %s LIMIT ? OFFSET ?",
 			fromClause, whereClause, orderClause,
 		)
 	}
 	args = append(args, opts.PerPage, offset)
 
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	rows, err := s.read.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to query torrent files: %w", err)
 	}
@@ -359,7 +824,7 @@ func (s *Storage) GetTorrentFiles(ctx context.Context, opts models.QueryOptions)
 	var files []models.TorrentFile
 	for rows.Next() {
 		var f models.TorrentFile
-		if err := rows.Scan(&f.TorrentHash, &f.TorrentName, &f.FileName, &f.FilePath, &f.Size); err != nil {
+		if err := rows.Scan(&f.ID, &f.TorrentHash, &f.TorrentName, &f.FileName, &f.FilePath, &f.Size, &f.Instance); err != nil {
 			return nil, 0, fmt.Errorf("failed to scan torrent file: %w", err)
 		}
 		files = append(files, f)
@@ -391,6 +856,14 @@ func (s *Storage) GetLocalFiles(ctx context.Context, opts models.QueryOptions) (
 		args = append(args, opts.Category)
 	}
 
+	ageConds, ageArgs := ageConditions("mtime", opts)
+	conditions = append(conditions, ageConds...)
+	args = append(args, ageArgs...)
+
+	sizeConds, sizeArgs := sizeConditions("size", opts)
+	conditions = append(conditions, sizeConds...)
+	args = append(args, sizeArgs...)
+
 	var whereClause string
 	if len(conditions) > 0 {
 		whereClause = "WHERE " + conditions[0]
@@ -401,31 +874,39 @@ func (s *Storage) GetLocalFiles(ctx context.Context, opts models.QueryOptions) (
 
 	// Count total matching records
 	countQuery := "SELECT COUNT(*) FROM local_files " + whereClause
-	var total int64
-	err := s.db.QueryRowContext(ctx, countQuery, args...).Scan(&total)
+	total, err := s.count(ctx, countKey("local_files", opts), countQuery, args...)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to count local files: %w", err)
 	}
 
-	// Build ORDER BY clause with whitelist validation
-	orderClause := "ORDER BY id ASC"
-	if opts.Sort != "" {
-		if col, ok := allowedLocalColumns[opts.Sort]; ok {
-			orderClause = fmt.Sprintf("ORDER BY %s %s", col, opts.Order)
-		}
+	// Build ORDER BY clause with whitelist validation, the ID breaking ties
+	sortCol, ok := allowedLocalColumns[opts.Sort]
+	order := opts.Order
+	if !ok {
+		order = "asc"
 	}
+	orderClause, afterCond, afterArgs := keyset(sortCol, "id", order, opts.After)
 
-	// Calculate offset for pagination
+	// Calculate offset for pagination, or start after the cursor
 	offset := (opts.Page - 1) * opts.PerPage
+	if afterCond != "" {
+		offset = 0
+		if whereClause == "" {
+			whereClause = "WHERE " + afterCond
+		} else {
+			whereClause += " AND " + afterCond
+		}
+		args = append(args, afterArgs...)
+	}
 
 	// Build and execute the main query
 	query := fmt.Sprintf(
-		"SELECT file_path, file_name, size, category FROM local_files %s %s LIMIT ? OFFSET ?",
+		"SELECT id, file_path, file_name, relative_path, size, allocated, category, links, mtime FROM local_files %s %s LIMIT ? OFFSET ?",
 		whereClause, orderClause,
 	)
 	args = append(args, opts.PerPage, offset)
 
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	rows, err := s.read.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to query local files: %w", err)
 	}
@@ -434,9 +915,11 @@ func (s *Storage) GetLocalFiles(ctx context.Context, opts models.QueryOptions) (
 	var files []models.LocalFile
 	for rows.Next() {
 		var f models.LocalFile
-		if err := rows.Scan(&f.FilePath, &f.FileName, &f.Size, &f.Category); err != nil {
+		var mtime int64
+		if err := rows.Scan(&f.ID, &f.FilePath, &f.FileName, &f.RelativePath, &f.Size, &f.Allocated, &f.Category, &f.Links, &mtime); err != nil {
 			return nil, 0, fmt.Errorf("failed to scan local file: %w", err)
 		}
+		f.ModTime = fromUnixTime(mtime)
 		files = append(files, f)
 	}
 
@@ -447,69 +930,166 @@ func (s *Storage) GetLocalFiles(ctx context.Context, opts models.QueryOptions) (
 	return files, total, nil
 }
 
-// GetOrphanFiles retrieves orphan files (local files not present in torrent_files) with pagination.
-// Comparison is done on relative_path column which is pre-computed and indexed.
-func (s *Storage) GetOrphanFiles(ctx context.Context, opts models.QueryOptions) ([]models.OrphanFile, int64, error) {
-	opts = normalizeQueryOptions(opts)
-
-	// Build WHERE clause for search and category filtering
-	// Base condition: no matching torrent file (orphan detection via LEFT JOIN on relative_path)
-	conditions := []string{"t.relative_path IS NULL"}
+// ageConditions returns the conditions of the MinAge and MaxAge filters on a
+// modification time column. A file of unknown age is never old enough.
+func ageConditions(column string, opts models.QueryOptions) ([]string, []interface{}) {
+	var conditions []string
 	var args []interface{}
+	now := time.Now()
+	if opts.MinAge > 0 {
+		conditions = append(conditions, fmt.Sprintf("(%s > 0 AND %s <= ?)", column, column))
+		args = append(args, now.Add(-opts.MinAge).Unix())
+	}
+	if opts.MaxAge > 0 {
+		conditions = append(conditions, column+" >= ?")
+		args = append(args, now.Add(-opts.MaxAge).Unix())
+	}
+	return conditions, args
+}
 
-	if opts.Search != "" {
-		conditions = append(conditions, "(l.file_name LIKE ? OR l.file_path LIKE ?)")
-		searchPattern := "%" + opts.Search + "%"
-		args = append(args, searchPattern, searchPattern)
+// sizeConditions returns the conditions of the MinSize and MaxSize filters on
+// a size column.
+func sizeConditions(column string, opts models.QueryOptions) ([]string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if opts.MinSize > 0 {
+		conditions = append(conditions, column+" >= ?")
+		args = append(args, opts.MinSize)
 	}
+	if opts.MaxSize > 0 {
+		conditions = append(conditions, column+" <= ?")
+		args = append(args, opts.MaxSize)
+	}
+	return conditions, args
+}
 
-	if opts.Category != "" {
-		conditions = append(conditions, "l.category = ?")
-		args = append(args, opts.Category)
+// unixTime converts a time to the Unix seconds stored in the database, 0 for
+// the zero time.
+func unixTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
 	}
+	return t.Unix()
+}
 
-	whereClause := "WHERE " + conditions[0]
-	for i := 1; i < len(conditions); i++ {
-		whereClause += " AND " + conditions[i]
+// fromUnixTime converts stored Unix seconds back to a time.
+func fromUnixTime(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
 	}
+	return time.Unix(sec, 0)
+}
+
+// notHardlinked is the orphan condition on the local file l that no hard
+// link of it is expected by a torrent: *arr imports hard link the downloads
+// of torrents to library paths that no torrent expects.
+const notHardlinked = `NOT EXISTS (
+	SELECT 1 FROM local_files h
+	JOIN torrent_files ht ON ht.relative_path = h.relative_path
+	WHERE l.inode != 0 AND h.inode = l.inode AND h.device = l.device AND h.file_path != l.file_path
+)`
+
+// inDownloadingFolder is the condition on the local file l that it lies in a
+// folder of a downloading multi-file torrent, below its save path: qBittorrent
+// also writes there the files it was told to skip, which no torrent file
+// expects. The save path is compared mapped, as the paths of the files. Trimming from a path all its characters but the slashes leaves its
+// folder, with the trailing slash.
+const inDownloadingFolder = `EXISTS (
+	SELECT 1 FROM torrents d
+	JOIN torrent_files dt ON dt.instance = d.instance AND dt.torrent_hash = d.hash
+	WHERE d.state = '` + models.TorrentStateDownloading + `'
+	AND dt.file_path != rtrim(d.local_save_path, '/') || '/' || dt.file_name
+	AND substr(l.relative_path, 1, length(rtrim(dt.relative_path, replace(dt.relative_path, '/', ''))))
+		= rtrim(dt.relative_path, replace(dt.relative_path, '/', ''))
+)`
+
+// incomplete is the condition on the local file l, expected by no torrent,
+// that it is still being downloaded: a temporary file of qBittorrent (an
+// incomplete file with the .!qB extension, or a .parts file holding the pieces
+// of skipped files), or a file in the folder of a downloading torrent. Such
+// files are not orphans.
+const incomplete = `(l.file_name LIKE '%.!qB' OR l.file_name LIKE '%.parts' OR ` + inDownloadingFolder + `)`
+
+// inProgress is the condition on the local file l that it is still being
+// downloaded: an incomplete file, or a file expected by a downloading
+// torrent. Such files are neither orphans nor healthy.
+const inProgress = `(` + incomplete + ` OR EXISTS (
+	SELECT 1 FROM torrent_files dt
+	JOIN torrents d ON d.instance = dt.instance AND d.hash = dt.torrent_hash
+	WHERE dt.relative_path = l.relative_path AND d.state = '` + models.TorrentStateDownloading + `'
+))`
+
+// ignoredBy is the condition on the local file l that the entry i of the
+// ignore list covers it: for a path, the file itself or a file below the
+// folder (see AddIgnoredPath), for a pattern, a file matching it or below a
+// folder matching it (see AddIgnoredPattern). '0' follows '/' in byte order.
+const ignoredBy = `(CASE WHEN i.pattern
+	THEN l.file_path GLOB i.path OR l.file_path GLOB i.path || '/*'
+	ELSE l.file_path = i.path OR (l.file_path > i.path || '/' AND l.file_path < i.path || '0')
+END)`
+
+// notIgnored is the orphan condition on the local file l that no entry of the
+// ignore list covers it.
+const notIgnored = `NOT EXISTS (SELECT 1 FROM ignored_paths i WHERE ` + ignoredBy + `)`
+
+// orphanUnlessIgnored is the condition on the local file l that it is an
+// orphan, or would be without the ignore list.
+const orphanUnlessIgnored = `NOT EXISTS (SELECT 1 FROM torrent_files t WHERE t.relative_path = l.relative_path)
+	AND ` + notHardlinked + ` AND NOT ` + incomplete
+
+// isOrphan is the condition on the local file l that it is an orphan, as
+// GetOrphanFiles finds them.
+const isOrphan = orphanUnlessIgnored + ` AND ` + notIgnored
+
+// GetOrphanFiles retrieves orphan files (local files not present in torrent_files) with pagination.
+// Comparison is done on relative_path column which is pre-computed and indexed.
+func (s *Storage) GetOrphanFiles(ctx context.Context, opts models.QueryOptions) ([]models.OrphanFile, int64, error) {
+	opts = normalizeQueryOptions(opts)
+	whereClause, args := orphanFilter(opts)
 
 	// Count total matching orphan records
 	countQuery := fmt.Sprintf(`
 		SELECT COUNT(*) 
 		FROM local_files l
 		LEFT JOIN torrent_files t ON l.relative_path = t.relative_path
+		LEFT JOIN orphaned_files o ON o.file_path = l.file_path
 		%s`, whereClause)
 
-	var total int64
-	err := s.db.QueryRowContext(ctx, countQuery, args...).Scan(&total)
+	total, err := s.count(ctx, countKey("orphans", opts), countQuery, args...)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to count orphan files: %w", err)
 	}
 
-	// Build ORDER BY clause with whitelist validation
+	// Build ORDER BY clause with whitelist validation, the ID breaking ties
 	// Default to size DESC as per design.md orphan query
-	orderClause := "ORDER BY l.size DESC"
-	if opts.Sort != "" {
-		if col, ok := allowedOrphanColumns[opts.Sort]; ok {
-			orderClause = fmt.Sprintf("ORDER BY %s %s", col, opts.Order)
-		}
+	sortCol, ok := allowedOrphanColumns[opts.Sort]
+	order := opts.Order
+	if !ok {
+		sortCol, order = "l.size", "desc"
 	}
+	orderClause, afterCond, afterArgs := keyset(sortCol, "l.id", order, opts.After)
 
-	// Calculate offset for pagination
+	// Calculate offset for pagination, or start after the cursor
 	offset := (opts.Page - 1) * opts.PerPage
+	if afterCond != "" {
+		offset = 0
+		whereClause += " AND " + afterCond
+		args = append(args, afterArgs...)
+	}
 
 	// Build and execute the main query using LEFT JOIN on relative_path
 	query := fmt.Sprintf(`
-		SELECT l.file_path, l.file_name, l.size, l.category
+		SELECT l.id, l.file_path, l.file_name, l.relative_path, l.size, l.allocated, l.category, l.links, l.mtime, COALESCE(o.first_orphaned_at, 0)
 		FROM local_files l
 		LEFT JOIN torrent_files t ON l.relative_path = t.relative_path
+		LEFT JOIN orphaned_files o ON o.file_path = l.file_path
 		%s
 		%s
 		LIMIT ? OFFSET ?`, whereClause, orderClause)
 
 	args = append(args, opts.PerPage, offset)
 
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	rows, err := s.read.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to query orphan files: %w", err)
 	}
@@ -518,9 +1098,12 @@ func (s *Storage) GetOrphanFiles(ctx context.Context, opts models.QueryOptions)
 	var files []models.OrphanFile
 	for rows.Next() {
 		var f models.OrphanFile
-		if err := rows.Scan(&f.FilePath, &f.FileName, &f.Size, &f.Category); err != nil {
+		var mtime, orphaned int64
+		if err := rows.Scan(&f.ID, &f.FilePath, &f.FileName, &f.RelativePath, &f.Size, &f.Allocated, &f.Category, &f.Links, &mtime, &orphaned); err != nil {
 			return nil, 0, fmt.Errorf("failed to scan orphan file: %w", err)
 		}
+		f.ModTime = fromUnixTime(mtime)
+		f.OrphanedAt = fromUnixTime(orphaned)
 		files = append(files, f)
 	}
 
@@ -531,6 +1114,116 @@ func (s *Storage) GetOrphanFiles(ctx context.Context, opts models.QueryOptions)
 	return files, total, nil
 }
 
+// orphanFilter returns the WHERE clause selecting the orphans matching the
+// filters of opts, from local_files l joined to torrent_files t and to
+// orphaned_files o.
+func orphanFilter(opts models.QueryOptions) (string, []interface{}) {
+	// Base condition: no matching torrent file (orphan detection via LEFT JOIN on relative_path)
+	conditions := []string{"t.relative_path IS NULL", notHardlinked, "NOT " + incomplete, notIgnored}
+	var args []interface{}
+
+	if opts.Search != "" {
+		conditions = append(conditions, "(l.file_name LIKE ? OR l.file_path LIKE ?)")
+		searchPattern := "%" + opts.Search + "%"
+		args = append(args, searchPattern, searchPattern)
+	}
+
+	if opts.Category != "" {
+		conditions = append(conditions, "l.category = ?")
+		args = append(args, opts.Category)
+	}
+
+	ageConds, ageArgs := ageConditions("l.mtime", opts)
+	conditions = append(conditions, ageConds...)
+	args = append(args, ageArgs...)
+
+	sizeConds, sizeArgs := sizeConditions("l.size", opts)
+	conditions = append(conditions, sizeConds...)
+	args = append(args, sizeArgs...)
+
+	// Un nombre de liens inconnu (0) ne fait pas écarter le fichier
+	if opts.ExcludeHardlinked {
+		conditions = append(conditions, "l.links <= 1")
+	}
+
+	// Un orphelin dont la date est inconnue n'est pas assez ancien
+	if opts.MinOrphanAge > 0 {
+		conditions = append(conditions, "o.first_orphaned_at <= ?")
+		args = append(args, time.Now().Add(-opts.MinOrphanAge).Unix())
+	}
+
+	whereClause := "WHERE " + conditions[0]
+	for i := 1; i < len(conditions); i++ {
+		whereClause += " AND " + conditions[i]
+	}
+	return whereClause, args
+}
+
+// GetOrphanTotals returns the number and the size of the orphans matching the
+// filters of opts, to act on all of them rather than on a page.
+func (s *Storage) GetOrphanTotals(ctx context.Context, opts models.QueryOptions) (models.OrphanTotals, error) {
+	whereClause, args := orphanFilter(opts)
+
+	var totals models.OrphanTotals
+	err := s.read.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT COUNT(*), COALESCE(SUM(l.size), 0)
+		FROM local_files l
+		LEFT JOIN torrent_files t ON l.relative_path = t.relative_path
+		LEFT JOIN orphaned_files o ON o.file_path = l.file_path
+		%s`, whereClause), args...).Scan(&totals.FileCount, &totals.TotalSize)
+	if err != nil {
+		return totals, fmt.Errorf("failed to sum orphan files: %w", err)
+	}
+	return totals, nil
+}
+
+// GetOrphanFilesByPath returns the files among paths that are orphans.
+// Paths that are unknown or still referenced by a torrent are left out.
+func (s *Storage) GetOrphanFilesByPath(ctx context.Context, paths []string) ([]models.OrphanFile, error) {
+	// Stay well below SQLite's limit on the number of bound parameters
+	const chunkSize = 500
+
+	var files []models.OrphanFile
+	for start := 0; start < len(paths); start += chunkSize {
+		chunk := paths[start:min(start+chunkSize, len(paths))]
+
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(chunk)), ",")
+		args := make([]interface{}, len(chunk))
+		for i, p := range chunk {
+			args[i] = p
+		}
+
+		query := fmt.Sprintf(`
+			SELECT l.file_path, l.file_name, l.size, l.allocated, l.category, l.links, l.mtime
+			FROM local_files l
+			LEFT JOIN torrent_files t ON l.relative_path = t.relative_path
+			WHERE t.relative_path IS NULL AND %s AND NOT %s AND %s AND l.file_path IN (%s)
+			ORDER BY l.file_path ASC`, notHardlinked, incomplete, notIgnored, placeholders)
+
+		rows, err := s.read.QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query orphan files: %w", err)
+		}
+		for rows.Next() {
+			var f models.OrphanFile
+			var mtime int64
+			if err := rows.Scan(&f.FilePath, &f.FileName, &f.Size, &f.Allocated, &f.Category, &f.Links, &mtime); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan orphan file: %w", err)
+			}
+			f.ModTime = fromUnixTime(mtime)
+			files = append(files, f)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error iterating orphan files: %w", err)
+		}
+	}
+
+	return files, nil
+}
+
 // GetTorrentStats returns global torrent statistics.
 // Returns COUNT files, COUNT DISTINCT torrent_hash, SUM size.
 // If unique is true, counts only unique files by relative_path.
@@ -555,7 +1248,7 @@ func (s *Storage) GetTorrentStats(ctx context.Context, unique bool) (*models.Sta
 	}
 
 	var stats models.Stats
-	err := s.db.QueryRowContext(ctx, query).Scan(&stats.TotalFiles, &stats.TotalTorrents, &stats.TotalSize)
+	err := s.read.QueryRowContext(ctx, query).Scan(&stats.TotalFiles, &stats.TotalTorrents, &stats.TotalSize)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get torrent stats: %w", err)
 	}
@@ -570,13 +1263,14 @@ func (s *Storage) GetLocalStats(ctx context.Context) ([]models.CategoryStats, er
 		SELECT 
 			category,
 			COUNT(*) as file_count,
-			COALESCE(SUM(size), 0) as total_size
+			COALESCE(SUM(size), 0) as total_size,
+			COALESCE(SUM(allocated), 0) as allocated_size
 		FROM local_files
 		GROUP BY category
 		ORDER BY category ASC
 	`
 
-	rows, err := s.db.QueryContext(ctx, query)
+	rows, err := s.read.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query local stats: %w", err)
 	}
@@ -585,7 +1279,7 @@ func (s *Storage) GetLocalStats(ctx context.Context) ([]models.CategoryStats, er
 	var stats []models.CategoryStats
 	for rows.Next() {
 		var cs models.CategoryStats
-		if err := rows.Scan(&cs.Category, &cs.FileCount, &cs.TotalSize); err != nil {
+		if err := rows.Scan(&cs.Category, &cs.FileCount, &cs.TotalSize, &cs.AllocatedSize); err != nil {
 			return nil, fmt.Errorf("failed to scan local stats: %w", err)
 		}
 		stats = append(stats, cs)
@@ -605,15 +1299,16 @@ func (s *Storage) GetOrphanStats(ctx context.Context) ([]models.CategoryStats, e
 		SELECT 
 			l.category,
 			COUNT(*) as file_count,
-			COALESCE(SUM(l.size), 0) as total_size
+			COALESCE(SUM(l.size), 0) as total_size,
+			COALESCE(SUM(l.allocated), 0) as allocated_size
 		FROM local_files l
 		LEFT JOIN torrent_files t ON l.relative_path = t.relative_path
-		WHERE t.relative_path IS NULL
+		WHERE t.relative_path IS NULL AND ` + notHardlinked + ` AND NOT ` + incomplete + ` AND ` + notIgnored + `
 		GROUP BY l.category
 		ORDER BY l.category ASC
 	`
 
-	rows, err := s.db.QueryContext(ctx, query)
+	rows, err := s.read.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query orphan stats: %w", err)
 	}
@@ -622,7 +1317,7 @@ func (s *Storage) GetOrphanStats(ctx context.Context) ([]models.CategoryStats, e
 	var stats []models.CategoryStats
 	for rows.Next() {
 		var cs models.CategoryStats
-		if err := rows.Scan(&cs.Category, &cs.FileCount, &cs.TotalSize); err != nil {
+		if err := rows.Scan(&cs.Category, &cs.FileCount, &cs.TotalSize, &cs.AllocatedSize); err != nil {
 			return nil, fmt.Errorf("failed to scan orphan stats: %w", err)
 		}
 		stats = append(stats, cs)
@@ -635,6 +1330,80 @@ func (s *Storage) GetOrphanStats(ctx context.Context) ([]models.CategoryStats, e
 	return stats, nil
 }
 
+// GetExtraFiles returns the local files of the extra pseudo-categories
+// (models.ExtraCategories), largest first, telling which ones are orphans.
+func (s *Storage) GetExtraFiles(ctx context.Context) ([]models.ExtraFile, error) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(models.ExtraCategories)), ",")
+	args := make([]interface{}, len(models.ExtraCategories))
+	for i, c := range models.ExtraCategories {
+		args[i] = c
+	}
+
+	query := fmt.Sprintf(`
+		SELECT l.file_path, l.file_name, l.size, l.allocated, l.category, %s
+		FROM local_files l
+		WHERE l.category IN (%s)
+		ORDER BY l.size DESC`, isOrphan, placeholders)
+
+	rows, err := s.read.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query extra files: %w", err)
+	}
+	defer rows.Close()
+
+	var files []models.ExtraFile
+	for rows.Next() {
+		var f models.ExtraFile
+		if err := rows.Scan(&f.FilePath, &f.FileName, &f.Size, &f.Allocated, &f.Category, &f.Orphan); err != nil {
+			return nil, fmt.Errorf("failed to scan extra file: %w", err)
+		}
+		files = append(files, f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating extra files: %w", err)
+	}
+
+	return files, nil
+}
+
+// GetInProgressStats returns statistics by category of the local files still
+// being downloaded (see inProgress).
+func (s *Storage) GetInProgressStats(ctx context.Context) ([]models.CategoryStats, error) {
+	query := `
+		SELECT
+			l.category,
+			COUNT(*) as file_count,
+			COALESCE(SUM(l.size), 0) as total_size,
+			COALESCE(SUM(l.allocated), 0) as allocated_size
+		FROM local_files l
+		WHERE ` + inProgress + `
+		GROUP BY l.category
+		ORDER BY l.category ASC
+	`
+
+	rows, err := s.read.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query in-progress stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []models.CategoryStats
+	for rows.Next() {
+		var cs models.CategoryStats
+		if err := rows.Scan(&cs.Category, &cs.FileCount, &cs.TotalSize, &cs.AllocatedSize); err != nil {
+			return nil, fmt.Errorf("failed to scan in-progress stats: %w", err)
+		}
+		stats = append(stats, cs)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating in-progress stats: %w", err)
+	}
+
+	return stats, nil
+}
+
 // allowedTables defines the whitelist of tables allowed for folder stats queries.
 var allowedTables = map[string]bool{
 	"torrent_files": true,
@@ -666,7 +1435,7 @@ func (s *Storage) GetFolderStats(ctx context.Context, table string) ([]models.Fo
 		ORDER BY total_size DESC
 	`, table)
 
-	rows, err := s.db.QueryContext(ctx, query)
+	rows, err := s.read.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query folder stats: %w", err)
 	}
@@ -688,47 +1457,146 @@ func (s *Storage) GetFolderStats(ctx context.Context, table string) ([]models.Fo
 	return stats, nil
 }
 
-// GetUnknownExtensionStats returns statistics for unknown files grouped by extension.
-func (s *Storage) GetUnknownExtensionStats(ctx context.Context) ([]models.ExtensionStats, error) {
-	query := `
-		SELECT 
-			LOWER(CASE 
-				WHEN instr(file_name, '.') > 0 THEN substr(file_name, -instr(reverse(file_name), '.') + 1)
-				ELSE 'no_extension'
-			END) as extension,
-			COUNT(*) as file_count,
-			COALESCE(SUM(size), 0) as total_size
-		FROM local_files
-		WHERE category = 'unknown'
-		GROUP BY extension
-		ORDER BY total_size DESC
-		LIMIT 20
-	`
+// GetFolderTree returns the subfolders and files directly in dir, at any
+// depth, with the number and size of the files below each subfolder, and for
+// the local files the size of the orphans among them. dir is an absolute
+// path, "/" for the root; a missing folder has no entries.
+func (s *Storage) GetFolderTree(ctx context.Context, table, dir string) (models.FolderTree, error) {
+	// Validate table name to prevent SQL injection
+	if !allowedTables[table] {
+		return models.FolderTree{}, fmt.Errorf("invalid table name: %s", table)
+	}
+
+	dir = path.Clean("/" + dir)
+	tree := models.FolderTree{Path: dir, Entries: []models.FolderEntry{}}
+	if dir != "/" {
+		tree.Parent = path.Dir(dir)
+	}
+
+	// Les fichiers des torrents ne sont jamais orphelins
+	orphan := "0"
+	if table == "local_files" {
+		orphan = isOrphan
+	}
+
+	// Bornes de l'index sur file_path : '0' suit '/' dans l'ordre des octets
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	upper := strings.TrimSuffix(prefix, "/") + "0"
+	query := fmt.Sprintf(`
+		SELECT
+			CASE WHEN instr(rest, '/') > 0 THEN substr(rest, 1, instr(rest, '/') - 1) ELSE rest END AS name,
+			MAX(instr(rest, '/') > 0) AS dir,
+			COUNT(*) AS file_count,
+			COALESCE(SUM(size), 0) AS total_size,
+			COALESCE(SUM(orphan * size), 0) AS orphan_size
+		FROM (
+			SELECT substr(l.file_path, ?) AS rest, l.size, %s AS orphan
+			FROM %s l
+			WHERE l.file_path >= ? AND l.file_path < ?
+		)
+		GROUP BY name
+		ORDER BY total_size DESC, name
+	`, orphan, table)
 
-	rows, err := s.db.QueryContext(ctx, query)
+	rows, err := s.read.QueryContext(ctx, query, len(prefix)+1, prefix, upper)
+	if err != nil {
+		return models.FolderTree{}, fmt.Errorf("failed to query folder tree: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e models.FolderEntry
+		if err := rows.Scan(&e.Name, &e.Dir, &e.FileCount, &e.TotalSize, &e.OrphanSize); err != nil {
+			return models.FolderTree{}, fmt.Errorf("failed to scan folder tree: %w", err)
+		}
+		e.Path = prefix + e.Name
+		tree.FileCount += e.FileCount
+		tree.TotalSize += e.TotalSize
+		tree.OrphanSize += e.OrphanSize
+		tree.Entries = append(tree.Entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return models.FolderTree{}, fmt.Errorf("error iterating folder tree: %w", err)
+	}
+
+	return tree, nil
+}
+
+// fileExtension is the lowercase extension of the file name of the local file
+// l, without the dot, or 'no_extension'. The rtrim removes from the end of the
+// name every character but '.', up to the last dot.
+const fileExtension = `LOWER(CASE
+	WHEN instr(l.file_name, '.') > 0 AND substr(l.file_name, -1) != '.'
+	THEN substr(l.file_name, length(rtrim(l.file_name, replace(l.file_name, '.', ''))) + 1)
+	ELSE 'no_extension'
+END)`
+
+// GetExtensionStats returns statistics by extension of the local files of
+// category, or all of them if empty, the largest first. Each extension is
+// broken down by category, and tells how much of it is orphan.
+func (s *Storage) GetExtensionStats(ctx context.Context, category string) ([]models.ExtensionStats, error) {
+	where := ""
+	var args []interface{}
+	if category != "" {
+		where = "WHERE l.category = ?"
+		args = append(args, category)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			%s AS extension,
+			l.category,
+			COUNT(*) AS file_count,
+			COALESCE(SUM(l.size), 0) AS total_size,
+			COALESCE(SUM(l.allocated), 0) AS allocated_size,
+			COALESCE(SUM(orphan), 0) AS orphan_files,
+			COALESCE(SUM(orphan * l.size), 0) AS orphan_size
+		FROM (SELECT l.*, %s AS orphan FROM local_files l %s) l
+		GROUP BY extension, l.category
+		ORDER BY extension, total_size DESC
+	`, fileExtension, isOrphan, where)
+
+	rows, err := s.read.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query extension stats: %w", err)
 	}
 	defer rows.Close()
 
+	// Une ligne par extension et catégorie, regroupées par extension
 	var stats []models.ExtensionStats
 	for rows.Next() {
-		var es models.ExtensionStats
-		if err := rows.Scan(&es.Extension, &es.FileCount, &es.TotalSize); err != nil {
+		var ext string
+		var cs models.CategoryStats
+		var orphanFiles, orphanSize int64
+		if err := rows.Scan(&ext, &cs.Category, &cs.FileCount, &cs.TotalSize, &cs.AllocatedSize, &orphanFiles, &orphanSize); err != nil {
 			return nil, fmt.Errorf("failed to scan extension stats: %w", err)
 		}
-		stats = append(stats, es)
+		if len(stats) == 0 || stats[len(stats)-1].Extension != ext {
+			stats = append(stats, models.ExtensionStats{Extension: ext})
+		}
+		es := &stats[len(stats)-1]
+		es.FileCount += cs.FileCount
+		es.TotalSize += cs.TotalSize
+		es.AllocatedSize += cs.AllocatedSize
+		es.OrphanFiles += orphanFiles
+		es.OrphanSize += orphanSize
+		es.Categories = append(es.Categories, cs)
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating extension stats: %w", err)
 	}
 
+	sort.SliceStable(stats, func(i, j int) bool { return stats[i].TotalSize > stats[j].TotalSize })
 	return stats, nil
 }
 
-// Close closes the database connection.
+// Close closes the database connections.
 func (s *Storage) Close() error {
+	if s.read != nil {
+		s.read.Close()
+	}
 	if s.db != nil {
 		return s.db.Close()
 	}
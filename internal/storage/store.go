@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"godatacleaner/internal/models"
+)
+
+// Store is the storage the reconciliation, the cleanup and the WebUI rely
+// on: the torrents and the local files, the orphans, dead and missing
+// torrents, the quarantine, the ignore and protection lists, the cleanup jobs
+// and the accounts. It is implemented by the SQLite backend (Storage) and by
+// the in-memory backend (Memory), for the programs embedding the cleaner or
+// the web server and for unit tests.
+type Store interface {
+	// NormalizePath and DiskPath map paths between the disk and the stored
+	// form, see WithPathRewrites.
+	NormalizePath(path string) string
+	DiskPath(root, path string) (string, bool)
+
+	InsertLocalFiles(ctx context.Context, files []models.LocalFile) error
+	DeleteLocalFiles(ctx context.Context, paths []string) error
+	HasTorrentFile(ctx context.Context, localPath string) (bool, error)
+
+	GetTorrents(ctx context.Context, opts models.QueryOptions) ([]models.Torrent, int64, error)
+	GetTorrentFiles(ctx context.Context, opts models.QueryOptions) ([]models.TorrentFile, int64, error)
+	GetLocalFiles(ctx context.Context, opts models.QueryOptions) ([]models.LocalFile, int64, error)
+	GetTorrentsForPaths(ctx context.Context, paths []string) ([]models.Torrent, error)
+	GetFileTorrents(ctx context.Context, path string) (models.FileTorrentsResponse, error)
+	GetFileDetail(ctx context.Context, path string) (models.FileDetail, error)
+
+	GetOrphanFiles(ctx context.Context, opts models.QueryOptions) ([]models.OrphanFile, int64, error)
+	GetOrphanFilesByPath(ctx context.Context, paths []string) ([]models.OrphanFile, error)
+	GetOrphanTotals(ctx context.Context, opts models.QueryOptions) (models.OrphanTotals, error)
+	GetOrphanReleases(ctx context.Context, opts models.QueryOptions) ([]models.OrphanRelease, int64, error)
+	GetOrphanReleaseFiles(ctx context.Context, folder string, opts models.QueryOptions) ([]models.OrphanFile, error)
+	GetExtraFiles(ctx context.Context) ([]models.ExtraFile, error)
+	GetDuplicateNames(ctx context.Context) ([]models.DuplicateGroup, error)
+
+	GetDeadTorrents(ctx context.Context, patterns []string) ([]models.Torrent, error)
+	GetDeadTorrentFiles(ctx context.Context, patterns []string, opts models.QueryOptions) ([]models.OrphanFile, error)
+	GetMissingTorrents(ctx context.Context, filter models.FileFilter) ([]models.MissingTorrent, error)
+	GetMissingFiles(ctx context.Context, instance, hash string, filter models.FileFilter) ([]models.MissingFile, error)
+	GetTorrentCompleteness(ctx context.Context, opts models.QueryOptions, filter models.FileFilter, maxCompleteness float64) ([]models.TorrentCompleteness, int64, error)
+
+	GetTorrentStats(ctx context.Context, unique bool) (*models.Stats, error)
+	GetTorrentStateStats(ctx context.Context) ([]models.TorrentStateStats, error)
+	GetTrackerStats(ctx context.Context) ([]models.TrackerStats, error)
+	GetLocalStats(ctx context.Context) ([]models.CategoryStats, error)
+	GetOrphanStats(ctx context.Context) ([]models.CategoryStats, error)
+	GetInProgressStats(ctx context.Context) ([]models.CategoryStats, error)
+	GetExtensionStats(ctx context.Context, category string) ([]models.ExtensionStats, error)
+	GetFolderStats(ctx context.Context, table string) ([]models.FolderStats, error)
+	GetFolderTree(ctx context.Context, table, dir string) (models.FolderTree, error)
+	GetTopFiles(ctx context.Context, by string, n int, orphansOnly bool) ([]models.TopFile, error)
+	GetTopFolders(ctx context.Context, by string, n int, orphansOnly bool) ([]models.TopFolder, error)
+
+	AddProtectedPath(ctx context.Context, pattern string) (int64, error)
+	ListProtectedPaths(ctx context.Context) ([]models.ProtectedPath, error)
+	RemoveProtectedPath(ctx context.Context, id int64) error
+	AddIgnoredPath(ctx context.Context, p string) (int64, string, error)
+	AddIgnoredPattern(ctx context.Context, pattern string) (int64, string, error)
+	ListIgnoredPaths(ctx context.Context) ([]models.IgnoredPath, error)
+	RemoveIgnoredPath(ctx context.Context, id int64) error
+
+	GetCleanupJob(ctx context.Context, id int64) (*models.CleanupJob, error)
+	ListCleanupJobs(ctx context.Context) ([]models.CleanupJob, error)
+	SetCleanupJobEnabled(ctx context.Context, id int64, enabled bool) error
+	SetCleanupJobSchedule(ctx context.Context, id int64, schedule string) error
+	RecordCleanupRun(ctx context.Context, run models.CleanupRun, files []models.CleanupRunFile) (int64, error)
+	ListCleanupRuns(ctx context.Context, jobID int64, limit int) ([]models.CleanupRun, error)
+	GetCleanupRunFiles(ctx context.Context, runID int64) ([]models.CleanupRunFile, error)
+
+	AddQuarantineEntries(ctx context.Context, entries []models.QuarantineEntry) error
+	GetQuarantineEntry(ctx context.Context, id int64) (*models.QuarantineEntry, error)
+	ListQuarantine(ctx context.Context, opts models.QueryOptions) ([]models.QuarantineEntry, int64, error)
+	ListQuarantineBatch(ctx context.Context, batch string) ([]int64, error)
+	MarkQuarantineRestored(ctx context.Context, id int64) error
+	MarkQuarantineBatchPurged(ctx context.Context, batch string) error
+
+	ListSyncRuns(ctx context.Context, limit int) ([]models.SyncRun, error)
+	GetSyncDiff(ctx context.Context, id int64, limit int) (*models.SyncDiff, error)
+	GetSyncErrors(ctx context.Context, id int64) (*models.SyncErrors, error)
+	GetLibraryHistory(ctx context.Context, since time.Time) ([]models.LibraryHistoryPoint, error)
+	Backup(ctx context.Context, path string) error
+
+	CountUsers(ctx context.Context) (int64, error)
+	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
+	CreateSession(ctx context.Context, tokenHash, username string, remember bool, ttl time.Duration) error
+	GetSession(ctx context.Context, tokenHash string) (*models.Session, error)
+	ExtendSession(ctx context.Context, tokenHash string, ttl, minGain time.Duration) error
+	DeleteSession(ctx context.Context, tokenHash string) error
+	DeleteExpiredSessions(ctx context.Context) error
+	CreateAPIKey(ctx context.Context, name, prefix, keyHash, scope string) (int64, error)
+	CountActiveAPIKeys(ctx context.Context) (int64, error)
+	GetAPIKey(ctx context.Context, id int64) (*models.APIKey, error)
+	GetActiveAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]models.APIKey, error)
+	TouchAPIKey(ctx context.Context, id int64) error
+	RevokeAPIKey(ctx context.Context, id int64) error
+
+	CreateSavedView(ctx context.Context, v models.SavedView) (int64, error)
+	ListSavedViews(ctx context.Context, list string) ([]models.SavedView, error)
+	UpdateSavedView(ctx context.Context, v models.SavedView) error
+	DeleteSavedView(ctx context.Context, id int64) error
+	GetColumnLayouts(ctx context.Context, username string) (map[string][]string, error)
+	SetColumnLayout(ctx context.Context, username, list string, columns []string) error
+	DeleteColumnLayout(ctx context.Context, username, list string) error
+}
+
+// ErrNotSupported is returned by a Store that cannot perform an operation,
+// such as Memory for a backup.
+var ErrNotSupported = errors.New("operation not supported")
+
+var (
+	_ Store = (*Storage)(nil)
+	_ Store = (*Memory)(nil)
+)
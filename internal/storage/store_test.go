@@ -0,0 +1,285 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"godatacleaner/internal/models"
+)
+
+// storeCase is a library given to every Store implementation, with what they
+// must find in it.
+type storeCase struct {
+	name     string
+	rewrites []PathRewrite
+	torrents []models.Torrent
+	files    []models.TorrentFile
+	local    []models.LocalFile
+
+	orphans    []string          // Stored paths of the orphans
+	status     map[string]string // FileStatus by stored path
+	inProgress int64             // Local files still being downloaded
+}
+
+// backends opens every Store implementation holding the library of a case,
+// as a sync leaves it.
+var backends = []struct {
+	name string
+	open func(t *testing.T, c storeCase) Store
+}{
+	{"memory", openMemory},
+	{"sqlite", openSQLite},
+}
+
+func openMemory(t *testing.T, c storeCase) Store {
+	m := NewMemory().WithPathRewrites(c.rewrites)
+	m.SetTorrents(c.torrents, c.files)
+	if err := m.InsertLocalFiles(context.Background(), c.local); err != nil {
+		t.Fatal(err)
+	}
+	m.UpdateOrphans(time.Now())
+	return m
+}
+
+func openSQLite(t *testing.T, c storeCase) Store {
+	ctx := context.Background()
+	s, err := NewStorage(filepath.Join(t.TempDir(), "test.db"), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	s.WithPathRewrites(c.rewrites)
+	if err := s.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := s.BeginSync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ReplaceTorrentFiles(ctx, c.torrents, c.files, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.ReplaceLocalFiles(ctx, c.local, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(ctx, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func torrent(hash, savePath, state string) models.Torrent {
+	return models.Torrent{Hash: hash, Name: hash, SavePath: savePath, LocalSavePath: savePath, State: state, Instance: "qbittorrent"}
+}
+
+func torrentFile(hash, filePath string) models.TorrentFile {
+	return models.TorrentFile{
+		TorrentHash: hash,
+		TorrentName: hash,
+		FileName:    filepath.Base(filePath),
+		FilePath:    filePath,
+		Size:        100,
+		Instance:    "qbittorrent",
+	}
+}
+
+func localFile(filePath string) models.LocalFile {
+	return models.LocalFile{FilePath: filePath, FileName: filepath.Base(filePath), Size: 100, Allocated: 100, Category: "movies", Links: 1}
+}
+
+// hardlink returns the local file at filePath as a hard link of the file
+// inode.
+func hardlink(filePath string, inode int64) models.LocalFile {
+	f := localFile(filePath)
+	f.Device, f.Inode, f.Links = 1, inode, 2
+	return f
+}
+
+var storeCases = []storeCase{
+	{
+		name:     "orphans",
+		torrents: []models.Torrent{torrent("a", "/downloads/movies", models.TorrentStateSeeding)},
+		files:    []models.TorrentFile{torrentFile("a", "/downloads/movies/A/a.mkv")},
+		local: []models.LocalFile{
+			localFile("/data/movies/A/a.mkv"),
+			localFile("/data/movies/B/b.mkv"),
+			localFile("/data/shows/S/s01e01.mkv"),
+		},
+		orphans: []string{"/data/movies/B/b.mkv", "/data/shows/S/s01e01.mkv"},
+		status: map[string]string{
+			"/data/movies/A/a.mkv": models.FileStatusMatched,
+			"/data/movies/B/b.mkv": models.FileStatusOrphan,
+		},
+	},
+	{
+		name: "in progress",
+		torrents: []models.Torrent{
+			torrent("multi", "/downloads", models.TorrentStateDownloading),
+			torrent("single", "/downloads/movies", models.TorrentStateDownloading),
+		},
+		files: []models.TorrentFile{
+			torrentFile("multi", "/downloads/movies/C/c1.mkv"),
+			torrentFile("single", "/downloads/movies/f.mkv"),
+		},
+		local: []models.LocalFile{
+			localFile("/data/movies/C/c1.mkv"),
+			// Skipped file of a multi-file torrent, in its folder
+			localFile("/data/movies/C/c2.nfo"),
+			localFile("/data/movies/D/d.mkv.!qB"),
+			localFile("/data/movies/E/e.mkv"),
+			// Beside a single-file torrent, whose folder is not its own
+			localFile("/data/movies/g.mkv"),
+		},
+		orphans: []string{"/data/movies/E/e.mkv", "/data/movies/g.mkv"},
+		status: map[string]string{
+			"/data/movies/C/c1.mkv":    models.FileStatusMatched,
+			"/data/movies/C/c2.nfo":    models.FileStatusDownloading,
+			"/data/movies/D/d.mkv.!qB": models.FileStatusDownloading,
+			"/data/movies/g.mkv":       models.FileStatusOrphan,
+			"/data/movies/E/e.mkv":     models.FileStatusOrphan,
+		},
+		inProgress: 3,
+	},
+	{
+		// Instance whose client sees /downloads as /volume1/torrents, the
+		// paths of its files being mapped by the syncer
+		name: "in progress on a mapped instance",
+		torrents: []models.Torrent{
+			func() models.Torrent {
+				t := torrent("multi", "/volume1/torrents", models.TorrentStateDownloading)
+				t.LocalSavePath = "/downloads"
+				return t
+			}(),
+			func() models.Torrent {
+				t := torrent("single", "/volume1/torrents/movies", models.TorrentStateDownloading)
+				t.LocalSavePath = "/downloads/movies"
+				return t
+			}(),
+		},
+		files: []models.TorrentFile{
+			torrentFile("multi", "/downloads/movies/C/c1.mkv"),
+			torrentFile("single", "/downloads/movies/f.mkv"),
+		},
+		local: []models.LocalFile{
+			localFile("/data/movies/C/c1.mkv"),
+			localFile("/data/movies/C/c2.nfo"),
+			localFile("/data/movies/E/e.mkv"),
+			localFile("/data/movies/g.mkv"),
+		},
+		orphans: []string{"/data/movies/E/e.mkv", "/data/movies/g.mkv"},
+		status: map[string]string{
+			"/data/movies/C/c2.nfo": models.FileStatusDownloading,
+			"/data/movies/g.mkv":    models.FileStatusOrphan,
+		},
+		inProgress: 2,
+	},
+	{
+		name:     "hardlinks",
+		torrents: []models.Torrent{torrent("h", "/downloads/movies", models.TorrentStateSeeding)},
+		files:    []models.TorrentFile{torrentFile("h", "/downloads/movies/H/h.mkv")},
+		local: []models.LocalFile{
+			hardlink("/data/movies/H/h.mkv", 10),
+			hardlink("/data/library/Movie (2020)/h.mkv", 10),
+			// Same inode on another device
+			func() models.LocalFile { f := hardlink("/data/library/other.mkv", 10); f.Device = 2; return f }(),
+		},
+		orphans: []string{"/data/library/other.mkv"},
+		status: map[string]string{
+			"/data/library/Movie (2020)/h.mkv": models.FileStatusMatched,
+			"/data/library/other.mkv":          models.FileStatusOrphan,
+		},
+	},
+	{
+		name: "path rewrites",
+		rewrites: []PathRewrite{
+			{From: `D:\torrents`, To: "/data"},
+			{From: "/mnt/pool", To: "/data"},
+		},
+		torrents: []models.Torrent{torrent("w", `D:\torrents\misc`, models.TorrentStateSeeding)},
+		files:    []models.TorrentFile{torrentFile("w", `D:\torrents\misc\tool.iso`)},
+		local: []models.LocalFile{
+			localFile("/mnt/pool/misc/tool.iso"),
+			localFile("/mnt/pool/misc/readme.txt"),
+			localFile("/data/misc/other.iso"),
+		},
+		orphans: []string{"/data/misc/other.iso", "/data/misc/readme.txt"},
+		status: map[string]string{
+			"/data/misc/tool.iso":   models.FileStatusMatched,
+			"/data/misc/readme.txt": models.FileStatusOrphan,
+		},
+	},
+}
+
+func TestStoreOrphans(t *testing.T) {
+	ctx := context.Background()
+	for _, c := range storeCases {
+		for _, b := range backends {
+			t.Run(c.name+"/"+b.name, func(t *testing.T) {
+				s := b.open(t, c)
+
+				files, total, err := s.GetOrphanFiles(ctx, models.QueryOptions{Sort: "file_path", Order: "asc"})
+				if err != nil {
+					t.Fatal(err)
+				}
+				var paths []string
+				for _, f := range files {
+					paths = append(paths, f.FilePath)
+				}
+				if !slices.Equal(paths, c.orphans) || total != int64(len(c.orphans)) {
+					t.Errorf("GetOrphanFiles = %v (%d), want %v", paths, total, c.orphans)
+				}
+
+				totals, err := s.GetOrphanTotals(ctx, models.QueryOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if totals.FileCount != int64(len(c.orphans)) || totals.TotalSize != 100*int64(len(c.orphans)) {
+					t.Errorf("GetOrphanTotals = %+v, want %d files", totals, len(c.orphans))
+				}
+
+				var stored []string
+				for _, f := range c.local {
+					stored = append(stored, s.NormalizePath(f.FilePath))
+				}
+				byPath, err := s.GetOrphanFilesByPath(ctx, stored)
+				if err != nil {
+					t.Fatal(err)
+				}
+				paths = nil
+				for _, f := range byPath {
+					paths = append(paths, f.FilePath)
+				}
+				if !slices.Equal(paths, c.orphans) {
+					t.Errorf("GetOrphanFilesByPath = %v, want %v", paths, c.orphans)
+				}
+
+				for p, want := range c.status {
+					resp, err := s.GetFileTorrents(ctx, p)
+					if err != nil {
+						t.Fatalf("GetFileTorrents(%s): %v", p, err)
+					}
+					if resp.Status != want {
+						t.Errorf("status of %s = %s, want %s", p, resp.Status, want)
+					}
+				}
+
+				stats, err := s.GetInProgressStats(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var inProgress int64
+				for _, cs := range stats {
+					inProgress += cs.FileCount
+				}
+				if inProgress != c.inProgress {
+					t.Errorf("GetInProgressStats counts %d files, want %d", inProgress, c.inProgress)
+				}
+			})
+		}
+	}
+}
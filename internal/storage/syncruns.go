@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"godatacleaner/internal/models"
+)
+
+// syncChangeRuns is the number of latest sync runs whose file changes and
+// errors are kept.
+const syncChangeRuns = 30
+
+// RecordSyncRun stores a sync run, along with the local files it added,
+// removed or resized when run.ChangesRecorded is set, and the torrents and
+// clients it could not read. Only the changes and errors of the latest syncs
+// are kept.
+func (s *Storage) RecordSyncRun(ctx context.Context, run models.SyncRun, files []models.FileChange, errs []models.SyncError) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO sync_runs (started_at, finished_at, status, full, torrents_synced, torrents, torrents_failed,
+			torrent_files, local_synced, local_files, changes_recorded, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, run.StartedAt, run.FinishedAt, run.Status, run.Full, run.TorrentsSynced, run.Torrents, run.TorrentsFailed,
+		run.TorrentFiles, run.LocalSynced, run.LocalFiles, run.ChangesRecorded, run.Error)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert sync run: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get sync run id: %w", err)
+	}
+
+	insert := newRowInserter(tx, "INSERT", "sync_changes", "run_id, file_path, change, size, old_size", "")
+	defer insert.close()
+	for _, f := range files {
+		if err := insert.add(ctx, id, f.FilePath, f.Change, f.Size, f.OldSize); err != nil {
+			return 0, fmt.Errorf("failed to insert sync change: %w", err)
+		}
+	}
+	if err := insert.flush(ctx); err != nil {
+		return 0, fmt.Errorf("failed to insert sync change: %w", err)
+	}
+
+	for _, e := range errs {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO sync_errors (run_id, instance, hash, name, error, kept_files) VALUES (?, ?, ?, ?, ?, ?)
+		`, id, e.Instance, e.Hash, e.Name, e.Error, e.KeptFiles); err != nil {
+			return 0, fmt.Errorf("failed to insert sync error: %w", err)
+		}
+	}
+
+	// Seules les modifications et erreurs des dernières synchronisations sont conservées
+	oldest := id - syncChangeRuns
+	if _, err := tx.ExecContext(ctx, "DELETE FROM sync_changes WHERE run_id <= ?", oldest); err != nil {
+		return 0, fmt.Errorf("failed to delete sync changes: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM sync_errors WHERE run_id <= ?", oldest); err != nil {
+		return 0, fmt.Errorf("failed to delete sync errors: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE sync_runs SET changes_recorded = 0 WHERE id <= ? AND changes_recorded", oldest); err != nil {
+		return 0, fmt.Errorf("failed to update sync runs: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return id, nil
+}
+
+// syncRunColumns are the sync_runs columns read by scanSyncRun, in order.
+const syncRunColumns = `id, started_at, finished_at, status, full, torrents_synced, torrents, torrents_failed,
+	torrent_files, local_synced, local_files, changes_recorded, error`
+
+// scanSyncRun reads a sync run selected with syncRunColumns.
+func scanSyncRun(row interface{ Scan(...any) error }) (models.SyncRun, error) {
+	var r models.SyncRun
+	err := row.Scan(&r.ID, &r.StartedAt, &r.FinishedAt, &r.Status, &r.Full, &r.TorrentsSynced, &r.Torrents, &r.TorrentsFailed,
+		&r.TorrentFiles, &r.LocalSynced, &r.LocalFiles, &r.ChangesRecorded, &r.Error)
+	if err != nil {
+		return r, err
+	}
+	r.DurationSeconds = r.FinishedAt.Sub(r.StartedAt).Seconds()
+	return r, nil
+}
+
+// ListSyncRuns returns the most recent sync runs, newest first.
+func (s *Storage) ListSyncRuns(ctx context.Context, limit int) ([]models.SyncRun, error) {
+	if limit < 1 {
+		limit = 50
+	}
+
+	rows, err := s.read.QueryContext(ctx, "SELECT "+syncRunColumns+" FROM sync_runs ORDER BY id DESC LIMIT ?", limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sync runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []models.SyncRun
+	for rows.Next() {
+		r, err := scanSyncRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan sync run: %w", err)
+		}
+		runs = append(runs, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sync runs: %w", err)
+	}
+
+	return runs, nil
+}
+
+// GetSyncDiff returns the local files added, removed or resized by the sync
+// run id, or by the latest sync that recorded its changes when id is 0. At
+// most limit files are listed, all of them when limit is 0, while the counts
+// cover them all. It returns ErrNotFound when there is no such run.
+func (s *Storage) GetSyncDiff(ctx context.Context, id int64, limit int) (*models.SyncDiff, error) {
+	var row *sql.Row
+	if id == 0 {
+		row = s.read.QueryRowContext(ctx, "SELECT "+syncRunColumns+" FROM sync_runs WHERE changes_recorded ORDER BY id DESC LIMIT 1")
+	} else {
+		row = s.read.QueryRowContext(ctx, "SELECT "+syncRunColumns+" FROM sync_runs WHERE id = ?", id)
+	}
+	run, err := scanSyncRun(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sync run: %w", err)
+	}
+
+	diff := &models.SyncDiff{Run: run, Files: []models.FileChange{}}
+	err = s.read.QueryRowContext(ctx, `
+		SELECT
+			COUNT(CASE WHEN change = ?2 THEN 1 END), COALESCE(SUM(CASE WHEN change = ?2 THEN size END), 0),
+			COUNT(CASE WHEN change = ?3 THEN 1 END), COALESCE(SUM(CASE WHEN change = ?3 THEN size END), 0),
+			COUNT(CASE WHEN change = ?4 THEN 1 END)
+		FROM sync_changes WHERE run_id = ?1`, run.ID, models.FileAdded, models.FileRemoved, models.FileChanged,
+	).Scan(&diff.Added, &diff.AddedBytes, &diff.Removed, &diff.RemovedBytes, &diff.Changed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count sync changes: %w", err)
+	}
+
+	if limit < 1 {
+		limit = -1
+	}
+	rows, err := s.read.QueryContext(ctx, `
+		SELECT file_path, change, size, old_size FROM sync_changes
+		WHERE run_id = ? ORDER BY change, file_path LIMIT ?`, run.ID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sync changes: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var f models.FileChange
+		if err := rows.Scan(&f.FilePath, &f.Change, &f.Size, &f.OldSize); err != nil {
+			return nil, fmt.Errorf("failed to scan sync change: %w", err)
+		}
+		diff.Files = append(diff.Files, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sync changes: %w", err)
+	}
+	return diff, nil
+}
+
+// GetSyncErrors returns the torrents and clients the sync run id could not
+// read, or those of the latest sync when id is 0. Errors are only kept for
+// the latest syncs. It returns ErrNotFound when there is no such run.
+func (s *Storage) GetSyncErrors(ctx context.Context, id int64) (*models.SyncErrors, error) {
+	var row *sql.Row
+	if id == 0 {
+		row = s.read.QueryRowContext(ctx, "SELECT "+syncRunColumns+" FROM sync_runs ORDER BY id DESC LIMIT 1")
+	} else {
+		row = s.read.QueryRowContext(ctx, "SELECT "+syncRunColumns+" FROM sync_runs WHERE id = ?", id)
+	}
+	run, err := scanSyncRun(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sync run: %w", err)
+	}
+
+	rows, err := s.read.QueryContext(ctx, `
+		SELECT instance, hash, name, error, kept_files FROM sync_errors
+		WHERE run_id = ? ORDER BY instance, name, hash`, run.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sync errors: %w", err)
+	}
+	defer rows.Close()
+
+	report := &models.SyncErrors{Run: run, Errors: []models.SyncError{}}
+	for rows.Next() {
+		var e models.SyncError
+		if err := rows.Scan(&e.Instance, &e.Hash, &e.Name, &e.Error, &e.KeptFiles); err != nil {
+			return nil, fmt.Errorf("failed to scan sync error: %w", err)
+		}
+		report.Errors = append(report.Errors, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sync errors: %w", err)
+	}
+	return report, nil
+}
@@ -0,0 +1,377 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"godatacleaner/internal/models"
+)
+
+// SyncTx writes the result of a sync as a new generation of the torrents,
+// torrent files and local files (see initGenerations). Its rows are written
+// in batches, each in its own transaction, and stay invisible until Commit
+// completes the generation: readers keep seeing the previous sync, then see
+// all the new rows at once, never a table half written, nor new torrent
+// files against old local files, which would show orphans and missing files
+// that do not exist. Between two batches, the writer connection is free for
+// the other writes, those of the WebUI and of the cleaner.
+//
+// A single SyncTx may be open at a time: the caller must hold the sync lock.
+type SyncTx struct {
+	s    *Storage
+	gen  int64
+	dirs []models.ScanDir // Directories of the scan, written by Commit
+	scan bool             // Whether dirs replace those of the previous scan
+	done bool             // Set by Commit
+}
+
+// BeginSync starts a new generation. The generations left incomplete by an
+// interrupted sync are discarded first, and the rows replaced by the
+// completed ones collected. Rollback must be called once done, even after
+// Commit.
+func (s *Storage) BeginSync(ctx context.Context) (*SyncTx, error) {
+	if err := s.discardGenerations(ctx, 0); err != nil {
+		return nil, err
+	}
+	if err := s.collectGenerations(ctx); err != nil {
+		return nil, err
+	}
+	res, err := s.db.ExecContext(ctx, "INSERT INTO sync_generations (started_at) VALUES (?)", time.Now().Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to start generation: %w", err)
+	}
+	gen, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start generation: %w", err)
+	}
+	return &SyncTx{s: s, gen: gen}, nil
+}
+
+// begin starts the transaction of a batch. It fails once the generation was
+// discarded, by the sync of another process that took over the sync lock
+// after it expired: its first statement writes, so that the check holds
+// until the commit.
+func (t *SyncTx) begin(ctx context.Context) (*sql.Tx, error) {
+	tx, err := t.s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	res, err := tx.ExecContext(ctx, "UPDATE sync_generations SET started_at = started_at WHERE id = ? AND completed_at = 0", t.gen)
+	if err == nil {
+		var n int64
+		if n, err = res.RowsAffected(); err == nil && n == 0 {
+			err = fmt.Errorf("generation %d discarded", t.gen)
+		}
+	}
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to write generation: %w", err)
+	}
+	return tx, nil
+}
+
+// batches calls write for each batch of the n items, in its own transaction,
+// with the bounds of the batch, then progress with the items written so far.
+func (t *SyncTx) batches(ctx context.Context, n int, write func(tx *sql.Tx, start, end int) error, progress InsertProgress) error {
+	for start := 0; start < n; start += t.s.batchSize {
+		end := min(start+t.s.batchSize, n)
+		tx, err := t.begin(ctx)
+		if err != nil {
+			return err
+		}
+		if err := write(tx, start, end); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		if progress != nil {
+			progress(end)
+		}
+	}
+	return nil
+}
+
+// replaceRows marks the rows of table with the given ids as replaced by the
+// generation, in batches.
+func (t *SyncTx) replaceRows(ctx context.Context, table string, ids []int64) error {
+	return t.batches(ctx, len(ids), func(tx *sql.Tx, start, end int) error {
+		stmt, err := tx.PrepareContext(ctx, "UPDATE "+table+" SET gen_to = ? WHERE id = ? AND gen_to = 0")
+		if err != nil {
+			return fmt.Errorf("failed to prepare statement: %w", err)
+		}
+		defer stmt.Close()
+		for _, id := range ids[start:end] {
+			if _, err := stmt.ExecContext(ctx, t.gen, id); err != nil {
+				return fmt.Errorf("failed to replace row of %s: %w", table, err)
+			}
+		}
+		return nil
+	}, nil)
+}
+
+// ReplaceTorrentFiles replaces all torrents and torrent files, reporting
+// progress after each batch of files. The current files are compared to
+// files: only the new, changed and missing ones are written, the others stay
+// in the new generation with their row and created_at. It must be called at
+// most once per SyncTx.
+func (t *SyncTx) ReplaceTorrentFiles(ctx context.Context, torrents []models.Torrent, files []models.TorrentFile, progress InsertProgress) (Changes, error) {
+	var changes Changes
+
+	// Fichiers de la synchronisation précédente, comparés aux nouveaux
+	existing, stale, err := t.s.loadTorrentFileRows(ctx)
+	if err != nil {
+		return changes, err
+	}
+
+	tx, err := t.begin(ctx)
+	if err != nil {
+		return changes, err
+	}
+	defer tx.Rollback()
+	if err := replaceTorrents(ctx, tx, t.gen, torrents); err != nil {
+		return changes, err
+	}
+	if err := tx.Commit(); err != nil {
+		return changes, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// Un fichier modifié est remplacé par une nouvelle ligne de la génération
+	now := time.Now().UTC().Format(time.DateTime)
+	err = t.batches(ctx, len(files), func(tx *sql.Tx, start, end int) error {
+		insert := newRowInserter(tx, "INSERT", "torrent_file_rows",
+			"torrent_hash, torrent_name, file_name, file_path, relative_path, size, instance, created_at, gen_from", "")
+		defer insert.close()
+
+		for _, file := range files[start:end] {
+			row := torrentFileRow{
+				torrentName:  file.TorrentName,
+				fileName:     file.FileName,
+				relativePath: t.s.relativePath(t.s.NormalizePath(file.FilePath)),
+				size:         file.Size,
+			}
+			key := torrentFileKey{instance: file.Instance, hash: file.TorrentHash, filePath: file.FilePath}
+			old, ok := existing[key]
+			delete(existing, key)
+			createdAt := any(now)
+			switch {
+			case ok && old.row == row:
+				continue
+			case ok:
+				stale = append(stale, old.id)
+				createdAt = old.createdAt
+				changes.Updated++
+			default:
+				changes.Inserted++
+			}
+			err := insert.add(ctx, file.TorrentHash, row.torrentName, row.fileName, file.FilePath, row.relativePath, row.size,
+				file.Instance, createdAt, t.gen)
+			if err != nil {
+				return fmt.Errorf("failed to write torrent file: %w", err)
+			}
+		}
+		if err := insert.flush(ctx); err != nil {
+			return fmt.Errorf("failed to write torrent file: %w", err)
+		}
+		return nil
+	}, progress)
+	if err != nil {
+		return changes, err
+	}
+
+	// Fichiers qui ne sont plus dans aucun torrent
+	for _, f := range existing {
+		stale = append(stale, f.id)
+	}
+	changes.Deleted = len(stale) - changes.Updated
+	return changes, t.replaceRows(ctx, "torrent_file_rows", stale)
+}
+
+// ReplaceLocalFiles replaces all local files with files, and the directories
+// of the previous scan with dirs, reporting progress after each batch. Like
+// ReplaceTorrentFiles, only the new, changed and missing files are written.
+// The directories are written by Commit, along with the files.
+func (t *SyncTx) ReplaceLocalFiles(ctx context.Context, files []models.LocalFile, dirs []models.ScanDir, progress InsertProgress) (Changes, error) {
+	// Fichiers du scan précédent, comparés aux nouveaux
+	existing, err := t.s.loadLocalRows(ctx, nil)
+	if err != nil {
+		return Changes{}, err
+	}
+	t.dirs, t.scan = dirs, true
+	return t.writeLocalFiles(ctx, files, existing, true, progress)
+}
+
+// DeleteLocalFilesUnder removes the local file at path, or the local files
+// under it when it is a directory. path must be normalized as file_path.
+func (t *SyncTx) DeleteLocalFilesUnder(ctx context.Context, path string) error {
+	tx, err := t.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	const under = "(file_path = ?1 OR substr(file_path, 1, length(?2)) = ?2)"
+	args := []any{path, strings.TrimSuffix(path, "/") + "/", t.gen}
+	if _, err := tx.ExecContext(ctx, "UPDATE local_file_rows SET gen_to = ?3 WHERE gen_to = 0 AND gen_from < ?3 AND "+under, args...); err != nil {
+		return fmt.Errorf("failed to delete local files: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM local_file_rows WHERE gen_from = ?3 AND "+under, args...); err != nil {
+		return fmt.Errorf("failed to delete local files: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// InsertLocalFiles adds local files, or updates those already known, without
+// touching the others.
+func (t *SyncTx) InsertLocalFiles(ctx context.Context, files []models.LocalFile) error {
+	if len(files) == 0 {
+		return nil
+	}
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = t.s.NormalizePath(f.FilePath)
+	}
+	existing, err := t.s.loadLocalRows(ctx, paths)
+	if err != nil {
+		return err
+	}
+	_, err = t.writeLocalFiles(ctx, files, existing, false, nil)
+	return err
+}
+
+// writeLocalFiles writes files in the generation, compared to the existing
+// ones. With replace set, the existing files missing from files are removed.
+func (t *SyncTx) writeLocalFiles(ctx context.Context, files []models.LocalFile, existing map[string]storedLocalFile, replace bool, progress InsertProgress) (Changes, error) {
+	changes := Changes{Recorded: replace && len(existing) > 0}
+	var stale []int64
+
+	// Une nouvelle ligne remplace celle d'un fichier modifié, en gardant son
+	// created_at, et son empreinte si sa taille et sa date de modification
+	// n'ont pas changé. L'upsert ne sert que pour un fichier déjà écrit par
+	// la génération
+	now := time.Now().UTC().Format(time.DateTime)
+	err := t.batches(ctx, len(files), func(tx *sql.Tx, start, end int) error {
+		upsert := newRowInserter(tx, "INSERT", "local_file_rows",
+			"file_path, file_name, relative_path, size, allocated, category, device, inode, links, mtime, checksum, created_at, gen_from", `
+			ON CONFLICT(file_path, gen_from) DO UPDATE SET
+				file_name = excluded.file_name, relative_path = excluded.relative_path, size = excluded.size,
+				allocated = excluded.allocated, category = excluded.category, device = excluded.device,
+				inode = excluded.inode, links = excluded.links, mtime = excluded.mtime,
+				checksum = CASE WHEN size = excluded.size AND mtime = excluded.mtime THEN checksum ELSE '' END`)
+		defer upsert.close()
+
+		for _, file := range files[start:end] {
+			// Normalize path with the rewrite rules
+			normalizedPath := t.s.NormalizePath(file.FilePath)
+			row := localRow{
+				fileName:     file.FileName,
+				relativePath: t.s.relativePath(normalizedPath),
+				size:         file.Size,
+				allocated:    file.Allocated,
+				category:     file.Category,
+				device:       file.Device,
+				inode:        file.Inode,
+				links:        file.Links,
+				mtime:        unixTime(file.ModTime),
+			}
+			old, ok := existing[normalizedPath]
+			delete(existing, normalizedPath)
+			checksum, createdAt := "", any(now)
+			switch {
+			case ok && old.row == row:
+				continue
+			case ok:
+				changes.Updated++
+				if changes.Recorded && old.row.size != row.size {
+					changes.Files = append(changes.Files, models.FileChange{FilePath: normalizedPath, Change: models.FileChanged, Size: row.size, OldSize: old.row.size})
+				}
+				if old.gen != t.gen {
+					stale = append(stale, old.id)
+				}
+				if old.row.size == row.size && old.row.mtime == row.mtime {
+					checksum = old.checksum
+				}
+				createdAt = old.createdAt
+			default:
+				changes.Inserted++
+				if changes.Recorded {
+					changes.Files = append(changes.Files, models.FileChange{FilePath: normalizedPath, Change: models.FileAdded, Size: row.size})
+				}
+			}
+			err := upsert.add(ctx, normalizedPath, row.fileName, row.relativePath, row.size, row.allocated, row.category,
+				row.device, row.inode, row.links, row.mtime, checksum, createdAt, t.gen)
+			if err != nil {
+				return fmt.Errorf("failed to insert local file: %w", err)
+			}
+		}
+		if err := upsert.flush(ctx); err != nil {
+			return fmt.Errorf("failed to insert local file: %w", err)
+		}
+		return nil
+	}, progress)
+	if err != nil {
+		return changes, err
+	}
+
+	// Fichiers disparus depuis le scan précédent
+	if replace {
+		for path, old := range existing {
+			stale = append(stale, old.id)
+			if changes.Recorded {
+				changes.Files = append(changes.Files, models.FileChange{FilePath: path, Change: models.FileRemoved, Size: old.row.size})
+			}
+		}
+		changes.Deleted = len(existing)
+	}
+	return changes, t.replaceRows(ctx, "local_file_rows", stale)
+}
+
+// Commit completes the generation, with the directories of the scan and the
+// dates of the orphans as of now (see updateOrphans), making all its rows
+// visible to the readers at once. The rows it replaced are then collected.
+func (t *SyncTx) Commit(ctx context.Context, now time.Time) error {
+	defer t.s.counts.reset()
+
+	tx, err := t.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if t.scan {
+		if err := replaceScanDirs(ctx, tx, t.dirs); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE sync_generations SET completed_at = ? WHERE id = ?", now.Unix(), t.gen); err != nil {
+		return fmt.Errorf("failed to complete generation: %w", err)
+	}
+	// Les orphelins dépendent des tables de la génération, désormais courante
+	if err := updateOrphans(ctx, tx, now); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	t.done = true
+
+	// En cas d'échec, la prochaine synchronisation les collectera
+	t.s.collectGenerations(context.WithoutCancel(ctx))
+	return nil
+}
+
+// Rollback discards the generation if it was not completed. If ctx is
+// cancelled or a write fails, the previous content of every table is kept.
+func (t *SyncTx) Rollback() {
+	if !t.done {
+		t.s.discardGenerations(context.Background(), t.gen)
+	}
+}
@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"godatacleaner/internal/models"
+)
+
+var generationCase = storeCase{
+	name:     "generations",
+	torrents: []models.Torrent{torrent("a", "/downloads/movies", models.TorrentStateSeeding)},
+	files:    []models.TorrentFile{torrentFile("a", "/downloads/movies/A/a.mkv")},
+	local: []models.LocalFile{
+		localFile("/data/movies/A/a.mkv"),
+		localFile("/data/movies/B/b.mkv"),
+		localFile("/data/movies/C/c.mkv"),
+	},
+}
+
+// localPaths returns the paths of the local files the readers see.
+func localPaths(t *testing.T, s *Storage) []string {
+	t.Helper()
+	files, err := s.ListAllLocalFiles(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, f.FilePath)
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+// countRows returns the rows of table, a view or the *_rows table holding
+// every generation.
+func countRows(t *testing.T, s *Storage, table string) int {
+	t.Helper()
+	var n int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// writeGeneration writes the next sync of generationCase: b.mkv removed,
+// c.mkv resized, d.mkv added and a.mkv no longer in a torrent.
+func writeGeneration(t *testing.T, tx *SyncTx) {
+	t.Helper()
+	ctx := context.Background()
+	c := localFile("/data/movies/C/c.mkv")
+	c.Size = 200
+	local := []models.LocalFile{localFile("/data/movies/A/a.mkv"), c, localFile("/data/movies/D/d.mkv")}
+	if _, err := tx.ReplaceTorrentFiles(ctx, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	changes, err := tx.ReplaceLocalFiles(ctx, local, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes.Inserted != 1 || changes.Updated != 1 || changes.Deleted != 1 {
+		t.Errorf("ReplaceLocalFiles = %+v, want 1 inserted, 1 updated, 1 deleted", changes)
+	}
+}
+
+// TestSyncGenerations checks that the readers see the previous sync until
+// Commit, that the writer is free meanwhile, and that Commit collects the
+// replaced rows.
+func TestSyncGenerations(t *testing.T) {
+	ctx := context.Background()
+	s := openSQLite(t, generationCase).(*Storage)
+	before := []string{"/data/movies/A/a.mkv", "/data/movies/B/b.mkv", "/data/movies/C/c.mkv"}
+
+	tx, err := s.BeginSync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	writeGeneration(t, tx)
+
+	if got := localPaths(t, s); !slices.Equal(got, before) {
+		t.Errorf("local files before Commit = %v, want %v", got, before)
+	}
+	if n := countRows(t, s, "torrent_files"); n != 1 {
+		t.Errorf("torrent files before Commit = %d, want 1", n)
+	}
+	// Les autres écritures n'attendent pas la fin de la synchronisation
+	if _, err := s.AcquireLock(ctx, "other", "test", time.Minute); err != nil {
+		t.Errorf("AcquireLock during the sync: %v", err)
+	}
+
+	if err := tx.Commit(ctx, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	after := []string{"/data/movies/A/a.mkv", "/data/movies/C/c.mkv", "/data/movies/D/d.mkv"}
+	if got := localPaths(t, s); !slices.Equal(got, after) {
+		t.Errorf("local files after Commit = %v, want %v", got, after)
+	}
+	if n := countRows(t, s, "torrent_files"); n != 0 {
+		t.Errorf("torrent files after Commit = %d, want 0", n)
+	}
+	orphans, total, err := s.GetOrphanFiles(ctx, models.QueryOptions{})
+	if err != nil || total != 3 || len(orphans) != 3 {
+		t.Errorf("GetOrphanFiles after Commit = %d files, %v, want 3", total, err)
+	}
+
+	// Seules les lignes de la génération courante restent
+	if n := countRows(t, s, "local_file_rows"); n != len(after) {
+		t.Errorf("local_file_rows = %d rows, want %d", n, len(after))
+	}
+	if n := countRows(t, s, "torrent_file_rows"); n != 0 {
+		t.Errorf("torrent_file_rows = %d rows, want 0", n)
+	}
+}
+
+// TestSyncRollback checks that an interrupted sync leaves the previous one
+// in place, with no row left behind.
+func TestSyncRollback(t *testing.T) {
+	ctx := context.Background()
+	s := openSQLite(t, generationCase).(*Storage)
+	before := []string{"/data/movies/A/a.mkv", "/data/movies/B/b.mkv", "/data/movies/C/c.mkv"}
+
+	tx, err := s.BeginSync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeGeneration(t, tx)
+	tx.Rollback()
+
+	if got := localPaths(t, s); !slices.Equal(got, before) {
+		t.Errorf("local files after Rollback = %v, want %v", got, before)
+	}
+	if n := countRows(t, s, "local_file_rows"); n != len(before) {
+		t.Errorf("local_file_rows = %d rows, want %d", n, len(before))
+	}
+	if n := countRows(t, s, "torrent_file_rows"); n != 1 {
+		t.Errorf("torrent_file_rows = %d rows, want 1", n)
+	}
+	if err := tx.Commit(ctx, time.Now()); err == nil {
+		t.Error("Commit after Rollback succeeded")
+	}
+}
@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"godatacleaner/internal/models"
+)
+
+// allowedTopFileColumns and allowedTopFolderColumns define the whitelists of
+// the orderings of the largest files and folders.
+var (
+	allowedTopFileColumns = map[string]string{
+		"size":      "l.size",
+		"allocated": "l.allocated",
+	}
+	allowedTopFolderColumns = map[string]string{
+		"size":      "total_size",
+		"allocated": "allocated",
+		"files":     "file_count",
+	}
+)
+
+// GetTopFiles returns the n largest local files by size or allocated size
+// (by size for an unknown order), only the orphans if orphansOnly is set.
+func (s *Storage) GetTopFiles(ctx context.Context, by string, n int, orphansOnly bool) ([]models.TopFile, error) {
+	col, ok := allowedTopFileColumns[by]
+	if !ok {
+		col = allowedTopFileColumns["size"]
+	}
+	where := ""
+	if orphansOnly {
+		where = "WHERE " + isOrphan
+	}
+
+	query := fmt.Sprintf(`
+		SELECT l.file_path, l.file_name, l.size, l.allocated, l.category, l.mtime, %s
+		FROM local_files l
+		%s
+		ORDER BY %s DESC, l.file_path
+		LIMIT ?`, isOrphan, where, col)
+
+	rows, err := s.read.QueryContext(ctx, query, n)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top files: %w", err)
+	}
+	defer rows.Close()
+
+	var files []models.TopFile
+	for rows.Next() {
+		var f models.TopFile
+		var mtime int64
+		if err := rows.Scan(&f.FilePath, &f.FileName, &f.Size, &f.Allocated, &f.Category, &mtime, &f.Orphan); err != nil {
+			return nil, fmt.Errorf("failed to scan top file: %w", err)
+		}
+		f.ModTime = fromUnixTime(mtime)
+		files = append(files, f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating top files: %w", err)
+	}
+
+	return files, nil
+}
+
+// GetTopFolders returns the n largest local folders by size, allocated size
+// or number of files (by size for an unknown order). A folder counts the
+// files directly in it, not those of its subfolders, so that a library
+// folder does not hide the releases it holds; only the orphans are counted
+// if orphansOnly is set.
+func (s *Storage) GetTopFolders(ctx context.Context, by string, n int, orphansOnly bool) ([]models.TopFolder, error) {
+	col, ok := allowedTopFolderColumns[by]
+	if !ok {
+		col = allowedTopFolderColumns["size"]
+	}
+	where := ""
+	if orphansOnly {
+		where = "WHERE " + isOrphan
+	}
+
+	// Le dossier d'un fichier est son chemin privé de son nom
+	query := fmt.Sprintf(`
+		SELECT
+			substr(l.file_path, 1, length(l.file_path) - length(l.file_name) - 1) AS folder,
+			COUNT(*) AS file_count,
+			COALESCE(SUM(l.size), 0) AS total_size,
+			COALESCE(SUM(l.allocated), 0) AS allocated
+		FROM local_files l
+		%s
+		GROUP BY folder
+		ORDER BY %s DESC, folder
+		LIMIT ?`, where, col)
+
+	rows, err := s.read.QueryContext(ctx, query, n)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top folders: %w", err)
+	}
+	defer rows.Close()
+
+	var folders []models.TopFolder
+	for rows.Next() {
+		var f models.TopFolder
+		if err := rows.Scan(&f.Path, &f.FileCount, &f.TotalSize, &f.Allocated); err != nil {
+			return nil, fmt.Errorf("failed to scan top folder: %w", err)
+		}
+		if f.Path == "" {
+			f.Path = "/"
+		}
+		folders = append(folders, f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating top folders: %w", err)
+	}
+
+	return folders, nil
+}
@@ -0,0 +1,549 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"godatacleaner/internal/models"
+)
+
+// replaceTorrents replaces the current torrents and their trackers with
+// torrents in the generation gen, within tx.
+func replaceTorrents(ctx context.Context, tx *sql.Tx, gen int64, torrents []models.Torrent) error {
+	if _, err := tx.ExecContext(ctx, "UPDATE torrent_rows SET gen_to = ? WHERE gen_to = 0 AND gen_from < ?", gen, gen); err != nil {
+		return fmt.Errorf("failed to replace torrents: %w", err)
+	}
+
+	insert := newRowInserter(tx, "INSERT", "torrent_rows",
+		"instance, hash, name, save_path, local_save_path, size, state, ratio, seeding_time, tracker_message, content_path, files_synced_at, gen_from", "")
+	defer insert.close()
+	for _, t := range torrents {
+		if err := insert.add(ctx, t.Instance, t.Hash, t.Name, t.SavePath, t.LocalSavePath, t.Size, t.State, t.Ratio, t.SeedingTime, t.TrackerMessage,
+			t.ContentPath, unixTime(t.FilesSyncedAt), gen); err != nil {
+			return fmt.Errorf("failed to insert torrent: %w", err)
+		}
+	}
+	if err := insert.flush(ctx); err != nil {
+		return fmt.Errorf("failed to insert torrent: %w", err)
+	}
+
+	return replaceTorrentTrackers(ctx, tx, gen, torrents)
+}
+
+// replaceTorrentTrackers replaces the current trackers with those of
+// torrents in the generation gen, within tx.
+func replaceTorrentTrackers(ctx context.Context, tx *sql.Tx, gen int64, torrents []models.Torrent) error {
+	if _, err := tx.ExecContext(ctx, "UPDATE torrent_tracker_rows SET gen_to = ? WHERE gen_to = 0 AND gen_from < ?", gen, gen); err != nil {
+		return fmt.Errorf("failed to replace torrent trackers: %w", err)
+	}
+
+	insert := newRowInserter(tx, "INSERT OR IGNORE", "torrent_tracker_rows", "instance, hash, tracker, gen_from", "")
+	defer insert.close()
+	for _, t := range torrents {
+		for _, tracker := range t.Trackers {
+			if err := insert.add(ctx, t.Instance, t.Hash, tracker, gen); err != nil {
+				return fmt.Errorf("failed to insert torrent tracker: %w", err)
+			}
+		}
+	}
+	if err := insert.flush(ctx); err != nil {
+		return fmt.Errorf("failed to insert torrent tracker: %w", err)
+	}
+	return nil
+}
+
+// DeleteTorrent removes a torrent deleted from its client, with its trackers
+// and files. When deleteLocal is set, its files were deleted from the disk too:
+// the local files they matched are removed, unless another torrent expects them.
+func (s *Storage) DeleteTorrent(ctx context.Context, instance, hash string, deleteLocal bool) error {
+	defer s.counts.reset()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var paths []string
+	if deleteLocal {
+		rows, err := tx.QueryContext(ctx, "SELECT DISTINCT relative_path FROM torrent_files WHERE instance = ? AND torrent_hash = ?", instance, hash)
+		if err != nil {
+			return fmt.Errorf("failed to query torrent files: %w", err)
+		}
+		for rows.Next() {
+			var path string
+			if err := rows.Scan(&path); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan torrent file: %w", err)
+			}
+			paths = append(paths, path)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating torrent files: %w", err)
+		}
+	}
+
+	for _, table := range []string{"torrent_rows", "torrent_tracker_rows"} {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE instance = ? AND hash = ?", instance, hash); err != nil {
+			return fmt.Errorf("failed to delete from %s: %w", table, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM torrent_file_rows WHERE instance = ? AND torrent_hash = ?", instance, hash); err != nil {
+		return fmt.Errorf("failed to delete torrent files: %w", err)
+	}
+
+	// Les fichiers locaux encore attendus par un autre torrent sont gardés :
+	// le prochain scan dira s'ils existent toujours
+	for _, path := range paths {
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM local_file_rows
+			WHERE relative_path = ?
+			AND NOT EXISTS (SELECT 1 FROM torrent_files WHERE relative_path = ?)
+		`, path, path); err != nil {
+			return fmt.Errorf("failed to delete local files: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// UpdateTorrentState updates the state, ratio and seeding time of a stored torrent.
+func (s *Storage) UpdateTorrentState(ctx context.Context, t models.Torrent) error {
+	defer s.counts.reset()
+
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE torrent_rows SET state = ?, ratio = ?, seeding_time = ?
+		WHERE instance = ? AND hash = ?
+	`, t.State, t.Ratio, t.SeedingTime, t.Instance, t.Hash)
+	if err != nil {
+		return fmt.Errorf("failed to update torrent state: %w", err)
+	}
+	return nil
+}
+
+// GetTorrent returns a torrent of the last sync, or ErrNotFound.
+func (s *Storage) GetTorrent(ctx context.Context, instance, hash string) (*models.Torrent, error) {
+	rows, err := s.read.QueryContext(ctx, "SELECT "+torrentColumns+" FROM torrents WHERE instance = ? AND hash = ?", instance, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query torrent: %w", err)
+	}
+	torrents, err := scanTorrents(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(torrents) == 0 {
+		return nil, ErrNotFound
+	}
+	return &torrents[0], nil
+}
+
+// GetTorrentsByHash returns the torrents of the last sync with the given
+// hash, one per client or instance seeding it. Hashes are compared without
+// case, rTorrent reporting them in upper case.
+func (s *Storage) GetTorrentsByHash(ctx context.Context, hash string) ([]models.Torrent, error) {
+	rows, err := s.read.QueryContext(ctx, "SELECT "+torrentColumns+" FROM torrents WHERE hash = ? COLLATE NOCASE ORDER BY instance", hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query torrents: %w", err)
+	}
+	return scanTorrents(rows)
+}
+
+// ListTorrentFilesOf returns the stored files of a torrent.
+func (s *Storage) ListTorrentFilesOf(ctx context.Context, instance, hash string) ([]models.TorrentFile, error) {
+	rows, err := s.read.QueryContext(ctx, `
+		SELECT torrent_hash, torrent_name, file_name, file_path, size, instance
+		FROM torrent_files WHERE instance = ? AND torrent_hash = ? ORDER BY id ASC
+	`, instance, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query torrent files: %w", err)
+	}
+	return scanTorrentFiles(rows)
+}
+
+// ListTorrentLocalFiles returns the local files matching the files of a
+// torrent, those its client deletes with its data.
+func (s *Storage) ListTorrentLocalFiles(ctx context.Context, instance, hash string) ([]models.LocalFile, error) {
+	rows, err := s.read.QueryContext(ctx, `
+		SELECT DISTINCT l.file_path, l.file_name, l.size, l.allocated
+		FROM local_files l
+		JOIN torrent_files t ON t.relative_path = l.relative_path
+		WHERE t.instance = ? AND t.torrent_hash = ?
+		ORDER BY l.file_path ASC
+	`, instance, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query torrent local files: %w", err)
+	}
+	defer rows.Close()
+
+	var files []models.LocalFile
+	for rows.Next() {
+		var f models.LocalFile
+		if err := rows.Scan(&f.FilePath, &f.FileName, &f.Size, &f.Allocated); err != nil {
+			return nil, fmt.Errorf("failed to scan torrent local file: %w", err)
+		}
+		files = append(files, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating torrent local files: %w", err)
+	}
+	return files, nil
+}
+
+// MoveTorrent updates the save path of a torrent, as seen by its client and
+// mapped, and replaces its files with files, holding their new paths, in a
+// single transaction.
+func (s *Storage) MoveTorrent(ctx context.Context, instance, hash, savePath, localSavePath string, files []models.TorrentFile) error {
+	defer s.counts.reset()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Les fichiers déplacés remplacent ceux de toutes les générations, dans la
+	// génération courante
+	if _, err := tx.ExecContext(ctx, "UPDATE torrent_rows SET save_path = ?, local_save_path = ? WHERE instance = ? AND hash = ?", savePath, localSavePath, instance, hash); err != nil {
+		return fmt.Errorf("failed to update torrent: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM torrent_file_rows WHERE instance = ? AND torrent_hash = ?", instance, hash); err != nil {
+		return fmt.Errorf("failed to delete torrent files: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO torrent_file_rows (torrent_hash, torrent_name, file_name, file_path, relative_path, size, instance, gen_from)
+		VALUES (?, ?, ?, ?, ?, ?, ?, `+currentGeneration+`)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, f := range files {
+		if _, err := stmt.ExecContext(ctx, f.TorrentHash, f.TorrentName, f.FileName, f.FilePath, s.relativePath(s.NormalizePath(f.FilePath)), f.Size, f.Instance); err != nil {
+			return fmt.Errorf("failed to insert torrent file: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ListSyncedTorrents returns the torrents of the last sync.
+func (s *Storage) ListSyncedTorrents(ctx context.Context) ([]models.Torrent, error) {
+	rows, err := s.read.QueryContext(ctx, "SELECT "+torrentColumns+" FROM torrents")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query torrents: %w", err)
+	}
+	return scanTorrents(rows)
+}
+
+// torrentColumns are the torrents columns read by scanTorrents, in order.
+// Trackers are concatenated with newlines, which cannot appear in a host.
+const torrentColumns = `instance, hash, name, save_path, local_save_path, size, state, ratio, seeding_time, tracker_message,
+	content_path, files_synced_at,
+	(SELECT group_concat(tracker, char(10)) FROM torrent_trackers tt
+		WHERE tt.instance = torrents.instance AND tt.hash = torrents.hash)`
+
+func scanTorrents(rows *sql.Rows) ([]models.Torrent, error) {
+	defer rows.Close()
+
+	var torrents []models.Torrent
+	for rows.Next() {
+		var t models.Torrent
+		var trackers sql.NullString
+		var filesSyncedAt int64
+		if err := rows.Scan(&t.Instance, &t.Hash, &t.Name, &t.SavePath, &t.LocalSavePath, &t.Size, &t.State, &t.Ratio, &t.SeedingTime, &t.TrackerMessage,
+			&t.ContentPath, &filesSyncedAt, &trackers); err != nil {
+			return nil, fmt.Errorf("failed to scan torrent: %w", err)
+		}
+		t.FilesSyncedAt = fromUnixTime(filesSyncedAt)
+		if trackers.String != "" {
+			t.Trackers = strings.Split(trackers.String, "\n")
+		}
+		torrents = append(torrents, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating torrents: %w", err)
+	}
+
+	return torrents, nil
+}
+
+// allowedTorrentListColumns defines the whitelist of columns allowed for sorting in torrents queries.
+var allowedTorrentListColumns = map[string]string{
+	"name":         "name",
+	"instance":     "instance",
+	"size":         "size",
+	"state":        "state",
+	"ratio":        "ratio",
+	"seeding_time": "seeding_time",
+}
+
+// GetTorrents retrieves the torrents of the last sync with pagination, sorting,
+// search on the name and filtering on the state.
+func (s *Storage) GetTorrents(ctx context.Context, opts models.QueryOptions) ([]models.Torrent, int64, error) {
+	opts = normalizeQueryOptions(opts)
+
+	var conditions []string
+	var args []interface{}
+	if opts.Search != "" {
+		conditions = append(conditions, "name LIKE ?")
+		args = append(args, "%"+opts.Search+"%")
+	}
+	if opts.State != "" {
+		conditions = append(conditions, "state = ?")
+		args = append(args, opts.State)
+	}
+	var whereClause string
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int64
+	if err := s.read.QueryRowContext(ctx, "SELECT COUNT(*) FROM torrents "+whereClause, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count torrents: %w", err)
+	}
+
+	orderClause := "ORDER BY name ASC"
+	if col, ok := allowedTorrentListColumns[opts.Sort]; ok {
+		orderClause = fmt.Sprintf("ORDER BY %s %s", col, opts.Order)
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM torrents %s %s LIMIT ? OFFSET ?", torrentColumns, whereClause, orderClause)
+	args = append(args, opts.PerPage, (opts.Page-1)*opts.PerPage)
+	rows, err := s.read.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query torrents: %w", err)
+	}
+	torrents, err := scanTorrents(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return torrents, total, nil
+}
+
+// GetTorrentStateStats returns the number and total size of the torrents per state.
+func (s *Storage) GetTorrentStateStats(ctx context.Context) ([]models.TorrentStateStats, error) {
+	rows, err := s.read.QueryContext(ctx, `
+		SELECT state, COUNT(*), COALESCE(SUM(size), 0)
+		FROM torrents
+		GROUP BY state
+		ORDER BY state ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query torrent states: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []models.TorrentStateStats
+	for rows.Next() {
+		var st models.TorrentStateStats
+		if err := rows.Scan(&st.State, &st.TorrentCount, &st.TotalSize); err != nil {
+			return nil, fmt.Errorf("failed to scan torrent state stats: %w", err)
+		}
+		stats = append(stats, st)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating torrent states: %w", err)
+	}
+
+	return stats, nil
+}
+
+// GetTorrentsForPaths returns the torrents expecting any of the given local
+// files. Paths are normalized local paths, matched on relative_path as in
+// orphan detection.
+func (s *Storage) GetTorrentsForPaths(ctx context.Context, paths []string) ([]models.Torrent, error) {
+	seen := make(map[string]bool)
+	var torrents []models.Torrent
+	for _, path := range paths {
+		rows, err := s.read.QueryContext(ctx, `
+			SELECT `+torrentColumns+` FROM torrents
+			WHERE EXISTS (
+				SELECT 1 FROM torrent_files f
+				WHERE f.instance = torrents.instance AND f.torrent_hash = torrents.hash AND f.relative_path = ?
+			)
+		`, s.relativePath(path))
+		if err != nil {
+			return nil, fmt.Errorf("failed to query torrents: %w", err)
+		}
+		found, err := scanTorrents(rows)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range found {
+			if key := t.Instance + "/" + t.Hash; !seen[key] {
+				seen[key] = true
+				torrents = append(torrents, t)
+			}
+		}
+	}
+	return torrents, nil
+}
+
+// deadCondition returns the SQL condition matching the tracker messages of
+// column against patterns, without case, and its arguments. No pattern
+// matches no torrent.
+func deadCondition(column string, patterns []string) (string, []interface{}) {
+	if len(patterns) == 0 {
+		return "0", nil
+	}
+	conditions := make([]string, len(patterns))
+	args := make([]interface{}, len(patterns))
+	for i, p := range patterns {
+		conditions[i] = column + " LIKE ?"
+		args[i] = "%" + p + "%"
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", args
+}
+
+// GetDeadTorrents returns the torrents whose tracker message matches one of
+// patterns, i.e. torrents no longer registered on their tracker, largest first.
+func (s *Storage) GetDeadTorrents(ctx context.Context, patterns []string) ([]models.Torrent, error) {
+	cond, args := deadCondition("tracker_message", patterns)
+	rows, err := s.read.QueryContext(ctx, "SELECT "+torrentColumns+" FROM torrents WHERE tracker_message != '' AND "+cond+" ORDER BY size DESC", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dead torrents: %w", err)
+	}
+	return scanTorrents(rows)
+}
+
+// GetDeadTorrentFiles returns the local files expected by dead torrents only
+// (see GetDeadTorrents), filtered by category and search as orphans are.
+// A file also expected by a live torrent, e.g. cross-seeded on another
+// tracker, is left out.
+func (s *Storage) GetDeadTorrentFiles(ctx context.Context, patterns []string, opts models.QueryOptions) ([]models.OrphanFile, error) {
+	cond, condArgs := deadCondition("t.tracker_message", patterns)
+
+	// Un fichier attendu par un torrent absent de la table torrents compte
+	// comme attendu par un torrent vivant
+	query := `
+		SELECT l.file_path, l.file_name, l.size, l.allocated, l.category, l.links, l.mtime
+		FROM local_files l
+		WHERE EXISTS (
+			SELECT 1 FROM torrent_files f
+			JOIN torrents t ON t.instance = f.instance AND t.hash = f.torrent_hash
+			WHERE f.relative_path = l.relative_path AND ` + cond + `
+		)
+		AND NOT EXISTS (
+			SELECT 1 FROM torrent_files f
+			LEFT JOIN torrents t ON t.instance = f.instance AND t.hash = f.torrent_hash
+			WHERE f.relative_path = l.relative_path AND (t.hash IS NULL OR NOT ` + cond + `)
+		)`
+	args := append(append([]interface{}{}, condArgs...), condArgs...)
+
+	if opts.Category != "" {
+		query += " AND l.category = ?"
+		args = append(args, opts.Category)
+	}
+	if opts.Search != "" {
+		query += " AND (l.file_name LIKE ? OR l.file_path LIKE ?)"
+		searchPattern := "%" + opts.Search + "%"
+		args = append(args, searchPattern, searchPattern)
+	}
+	ageConds, ageArgs := ageConditions("l.mtime", opts)
+	for _, cond := range ageConds {
+		query += " AND " + cond
+	}
+	args = append(args, ageArgs...)
+	sizeConds, sizeArgs := sizeConditions("l.size", opts)
+	for _, cond := range sizeConds {
+		query += " AND " + cond
+	}
+	args = append(args, sizeArgs...)
+	if opts.ExcludeHardlinked {
+		query += " AND l.links <= 1"
+	}
+	query += " ORDER BY l.file_path ASC"
+
+	rows, err := s.read.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dead torrent files: %w", err)
+	}
+	defer rows.Close()
+
+	var files []models.OrphanFile
+	for rows.Next() {
+		var f models.OrphanFile
+		var mtime int64
+		if err := rows.Scan(&f.FilePath, &f.FileName, &f.Size, &f.Allocated, &f.Category, &f.Links, &mtime); err != nil {
+			return nil, fmt.Errorf("failed to scan dead torrent file: %w", err)
+		}
+		f.ModTime = fromUnixTime(mtime)
+		files = append(files, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating dead torrent files: %w", err)
+	}
+	return files, nil
+}
+
+// GetTrackerStats returns the number and total size of the torrents per tracker.
+// Torrents without tracker are grouped under an empty tracker.
+func (s *Storage) GetTrackerStats(ctx context.Context) ([]models.TrackerStats, error) {
+	rows, err := s.read.QueryContext(ctx, `
+		SELECT COALESCE(tt.tracker, ''), COUNT(*), COALESCE(SUM(t.size), 0)
+		FROM torrents t
+		LEFT JOIN torrent_trackers tt ON tt.instance = t.instance AND tt.hash = t.hash
+		GROUP BY COALESCE(tt.tracker, '')
+		ORDER BY SUM(t.size) DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tracker stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []models.TrackerStats
+	for rows.Next() {
+		var st models.TrackerStats
+		if err := rows.Scan(&st.Tracker, &st.TorrentCount, &st.TotalSize); err != nil {
+			return nil, fmt.Errorf("failed to scan tracker stats: %w", err)
+		}
+		stats = append(stats, st)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tracker stats: %w", err)
+	}
+
+	return stats, nil
+}
+
+// ListAllTorrentFiles returns every stored torrent file.
+func (s *Storage) ListAllTorrentFiles(ctx context.Context) ([]models.TorrentFile, error) {
+	rows, err := s.read.QueryContext(ctx, "SELECT torrent_hash, torrent_name, file_name, file_path, size, instance FROM torrent_files ORDER BY id ASC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query torrent files: %w", err)
+	}
+	return scanTorrentFiles(rows)
+}
+
+func scanTorrentFiles(rows *sql.Rows) ([]models.TorrentFile, error) {
+	defer rows.Close()
+
+	var files []models.TorrentFile
+	for rows.Next() {
+		var f models.TorrentFile
+		if err := rows.Scan(&f.TorrentHash, &f.TorrentName, &f.FileName, &f.FilePath, &f.Size, &f.Instance); err != nil {
+			return nil, fmt.Errorf("failed to scan torrent file: %w", err)
+		}
+		files = append(files, f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating torrent files: %w", err)
+	}
+
+	return files, nil
+}
@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"godatacleaner/internal/models"
+)
+
+// CreateUser inserts a new user and returns its ID.
+func (s *Storage) CreateUser(ctx context.Context, username, passwordHash, role string) (int64, error) {
+	res, err := s.db.ExecContext(ctx,
+		"INSERT INTO users (username, password_hash, role) VALUES (?, ?, ?)",
+		username, passwordHash, role)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert user: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+// GetUserByUsername returns a user, including its password hash.
+func (s *Storage) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	var u models.User
+	err := s.read.QueryRowContext(ctx,
+		"SELECT id, username, role, password_hash, created_at FROM users WHERE username = ?", username,
+	).Scan(&u.ID, &u.Username, &u.Role, &u.PasswordHash, &u.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return &u, nil
+}
+
+// ListUsers returns all users ordered by username.
+func (s *Storage) ListUsers(ctx context.Context) ([]models.User, error) {
+	rows, err := s.read.QueryContext(ctx, "SELECT id, username, role, created_at FROM users ORDER BY username ASC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var u models.User
+		if err := rows.Scan(&u.ID, &u.Username, &u.Role, &u.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating users: %w", err)
+	}
+
+	return users, nil
+}
+
+// CountUsers returns the number of users.
+func (s *Storage) CountUsers(ctx context.Context) (int64, error) {
+	var n int64
+	if err := s.read.QueryRowContext(ctx, "SELECT COUNT(*) FROM users").Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	return n, nil
+}
+
+// SetUserRole changes the role of a user.
+func (s *Storage) SetUserRole(ctx context.Context, username, role string) error {
+	res, err := s.db.ExecContext(ctx, "UPDATE users SET role = ? WHERE username = ?", role, username)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	return checkAffected(res)
+}
+
+// SetUserPassword replaces the password hash of a user.
+func (s *Storage) SetUserPassword(ctx context.Context, username, passwordHash string) error {
+	res, err := s.db.ExecContext(ctx, "UPDATE users SET password_hash = ? WHERE username = ?", passwordHash, username)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	return checkAffected(res)
+}
+
+// DeleteUser deletes a user and its column layouts.
+func (s *Storage) DeleteUser(ctx context.Context, username string) error {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM users WHERE username = ?", username)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	if err := checkAffected(res); err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM column_layouts WHERE username = ?", username); err != nil {
+		return fmt.Errorf("failed to delete column layouts: %w", err)
+	}
+	return nil
+}
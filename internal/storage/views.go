@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"godatacleaner/internal/models"
+)
+
+// ValidViewSort reports whether sort is a sort column of the file list of a
+// saved view. An empty sort keeps the default order of the list.
+func ValidViewSort(list, sort string) bool {
+	if sort == "" {
+		return true
+	}
+	switch list {
+	case models.ViewListOrphans:
+		_, ok := allowedOrphanColumns[sort]
+		return ok
+	case models.ViewListLocal:
+		_, ok := allowedLocalColumns[sort]
+		return ok
+	}
+	return false
+}
+
+// CreateSavedView stores a saved view and returns its ID.
+func (s *Storage) CreateSavedView(ctx context.Context, v models.SavedView) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO saved_views (name, list, search, category, min_size, max_size, sort, sort_order)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, v.Name, v.List, v.Search, v.Category, v.MinSize, v.MaxSize, v.Sort, v.Order)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert saved view: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+// ListSavedViews returns the saved views of a file list, or of all lists
+// when list is empty, ordered by name.
+func (s *Storage) ListSavedViews(ctx context.Context, list string) ([]models.SavedView, error) {
+	rows, err := s.read.QueryContext(ctx, `
+		SELECT id, name, list, search, category, min_size, max_size, sort, sort_order, created_at
+		FROM saved_views WHERE ? = '' OR list = ?
+		ORDER BY name ASC, id ASC
+	`, list, list)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query saved views: %w", err)
+	}
+	defer rows.Close()
+
+	var views []models.SavedView
+	for rows.Next() {
+		var v models.SavedView
+		if err := rows.Scan(&v.ID, &v.Name, &v.List, &v.Search, &v.Category, &v.MinSize, &v.MaxSize, &v.Sort, &v.Order, &v.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan saved view: %w", err)
+		}
+		views = append(views, v)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating saved views: %w", err)
+	}
+
+	return views, nil
+}
+
+// UpdateSavedView replaces the name and the filters of the saved view v.ID.
+func (s *Storage) UpdateSavedView(ctx context.Context, v models.SavedView) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE saved_views SET name = ?, list = ?, search = ?, category = ?, min_size = ?, max_size = ?, sort = ?, sort_order = ?
+		WHERE id = ?
+	`, v.Name, v.List, v.Search, v.Category, v.MinSize, v.MaxSize, v.Sort, v.Order, v.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update saved view: %w", err)
+	}
+	return checkAffected(res)
+}
+
+// DeleteSavedView removes a saved view.
+func (s *Storage) DeleteSavedView(ctx context.Context, id int64) error {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM saved_views WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete saved view: %w", err)
+	}
+	return checkAffected(res)
+}
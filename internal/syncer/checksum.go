@@ -0,0 +1,187 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"godatacleaner/internal/checksum"
+	"godatacleaner/internal/config"
+	"godatacleaner/internal/models"
+)
+
+// ErrChecksumDisabled is returned by the checksum operations when CHECKSUM is not set.
+var ErrChecksumDisabled = errors.New("syncer: checksums are disabled, CHECKSUM is not set")
+
+// newHasher returns the hasher of the checksum settings, nil when CHECKSUM is not set.
+func (s *Syncer) newHasher() (*checksum.Hasher, error) {
+	if s.config().Checksum == "" {
+		return nil, nil
+	}
+	var partial int64
+	if s.config().ChecksumMode == config.ChecksumModePartial {
+		partial = int64(s.config().ChecksumPartialMB) << 20
+	}
+	h, err := checksum.NewHasher(checksum.Algorithm(s.config().Checksum), partial)
+	if err != nil {
+		return nil, err
+	}
+	return h.WithWorkers(s.config().ChecksumWorkers).WithRateLimit(int64(s.config().ChecksumRateMB) << 20), nil
+}
+
+// hashFiles computes the checksums of the local files added or changed since
+// they were last hashed. The checksums are stored by batches, so that an
+// interrupted sync keeps those computed so far. A file that cannot be read is
+// only counted in result.ChecksumFailed.
+func (s *Syncer) hashFiles(ctx context.Context, h *checksum.Hasher, result *Result, p *Progress, progress func(Progress)) error {
+	files, err := s.storage.ListFilesToHash(ctx, h.Spec())
+	if err != nil {
+		return syncError(ctx, err)
+	}
+	byPath := make(map[string]models.LocalFile, len(files))
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = s.diskPath(f.FilePath)
+		byPath[paths[i]] = f
+	}
+
+	p.Stage = StageChecksum
+	p.ChecksumTotal, p.Checksummed = len(files), 0
+	progress(*p)
+
+	var batch []models.LocalFile
+	var storeErr error
+	err = h.Run(ctx, paths, func(r checksum.Result) {
+		p.Checksummed++
+		progress(*p)
+		if r.Err != nil {
+			log.Printf("⚠️  Empreinte de %s non calculée: %v", r.Path, r.Err)
+			result.ChecksumFailed++
+			return
+		}
+		f := byPath[r.Path]
+		f.Checksum = r.Checksum
+		batch = append(batch, f)
+		result.Checksummed++
+		if len(batch) >= s.config().SQLiteBatchSize && storeErr == nil {
+			storeErr = s.storage.SetChecksums(ctx, batch)
+			batch = batch[:0]
+		}
+	})
+	if storeErr != nil {
+		return syncError(ctx, storeErr)
+	}
+	// Les empreintes déjà calculées sont conservées même si la synchronisation est annulée
+	if err := s.storage.SetChecksums(context.WithoutCancel(ctx), batch); err != nil {
+		return syncError(ctx, err)
+	}
+	return err
+}
+
+// VerifyIssue describes a local file whose content could not be verified or
+// does not match its checksum.
+type VerifyIssue struct {
+	File     models.LocalFile `json:"file"`
+	DiskPath string           `json:"disk_path"`
+	Checksum string           `json:"checksum,omitempty"` // Checksum of the current content, when it changed
+	Error    string           `json:"error,omitempty"`
+}
+
+// VerifyReport summarizes a verification of the local files.
+type VerifyReport struct {
+	Verified int64         `json:"verified"` // Files whose content matches their checksum
+	Skipped  int64         `json:"skipped"`  // Files modified since the last sync, hashed again by the next one
+	Changed  []VerifyIssue `json:"changed"`  // Files whose content changed although their size and modification time did not
+	Failed   []VerifyIssue `json:"failed"`   // Files missing or unreadable
+}
+
+// Verify hashes again the local files hashed by the previous syncs and
+// reports those whose content no longer matches their checksum while their
+// size and modification time are unchanged, a sign of corruption. Stored
+// checksums are left as is, so that a corrupted file keeps being reported.
+// progress, when not nil, is called after each file with the number of files
+// done and to do.
+func (s *Syncer) Verify(ctx context.Context, progress func(done, total int)) (*VerifyReport, error) {
+	h, err := s.newHasher()
+	if err != nil {
+		return nil, fmt.Errorf("syncer: %w", err)
+	}
+	if h == nil {
+		return nil, ErrChecksumDisabled
+	}
+	if progress == nil {
+		progress = func(int, int) {}
+	}
+
+	files, err := s.storage.ListHashedFiles(ctx, h.Spec())
+	if err != nil {
+		return nil, fmt.Errorf("syncer: %w", err)
+	}
+
+	report := &VerifyReport{}
+	byPath := make(map[string]models.LocalFile, len(files))
+	var paths []string
+	for _, f := range files {
+		diskPath := s.diskPath(f.FilePath)
+		info, err := os.Stat(diskPath)
+		if err != nil {
+			report.Failed = append(report.Failed, VerifyIssue{File: f, DiskPath: diskPath, Error: err.Error()})
+			continue
+		}
+		// Un fichier modifié depuis la synchronisation n'est pas corrompu
+		if info.Size() != f.Size || info.ModTime().Unix() != f.ModTime.Unix() {
+			report.Skipped++
+			continue
+		}
+		byPath[diskPath] = f
+		paths = append(paths, diskPath)
+	}
+
+	done := 0
+	err = h.Run(ctx, paths, func(r checksum.Result) {
+		done++
+		progress(done, len(paths))
+		f := byPath[r.Path]
+		switch {
+		case r.Err != nil:
+			report.Failed = append(report.Failed, VerifyIssue{File: f, DiskPath: r.Path, Error: r.Err.Error()})
+		case r.Checksum != f.Checksum:
+			report.Changed = append(report.Changed, VerifyIssue{File: f, DiskPath: r.Path, Checksum: r.Checksum})
+		default:
+			report.Verified++
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// Duplicates returns the groups of local files with the same content, as
+// found by their checksums.
+func (s *Syncer) Duplicates(ctx context.Context) ([]models.DuplicateGroup, error) {
+	h, err := s.newHasher()
+	if err != nil {
+		return nil, fmt.Errorf("syncer: %w", err)
+	}
+	if h == nil {
+		return nil, ErrChecksumDisabled
+	}
+	groups, err := s.storage.GetDuplicateFiles(ctx, h.Spec())
+	if err != nil {
+		return nil, fmt.Errorf("syncer: %w", err)
+	}
+	return groups, nil
+}
+
+// diskPath maps a stored local file path back to its location on disk, under
+// the local path. Local paths are stored normalized (see
+// storage.Storage.WithPathRewrites).
+func (s *Syncer) diskPath(path string) string {
+	if diskPath, ok := s.storage.DiskPath(s.config().LocalPath, path); ok {
+		return diskPath
+	}
+	return path
+}
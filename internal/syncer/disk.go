@@ -0,0 +1,40 @@
+package syncer
+
+import (
+	"path/filepath"
+
+	"godatacleaner/internal/models"
+	"godatacleaner/internal/scanner"
+)
+
+// DiskUsage returns the space of the filesystems holding LOCAL_PATH and the
+// scan roots, once per filesystem. It is empty for a remote library
+// (LOCAL_REMOTE), and leaves out the roots that cannot be read.
+func (s *Syncer) DiskUsage() []models.DiskUsage {
+	cfg := s.config()
+	disks := []models.DiskUsage{}
+	if cfg.LocalRemote != "" {
+		return disks
+	}
+
+	roots := []string{cfg.LocalPath}
+	for _, r := range cfg.ScanRoots {
+		roots = append(roots, filepath.Join(cfg.LocalPath, r.Path))
+	}
+	byMount := make(map[string]int)
+	for _, root := range roots {
+		d, err := scanner.DiskUsage(root)
+		if err != nil {
+			continue
+		}
+		// Plusieurs racines sur le même disque ne le comptent qu'une fois
+		if i, ok := byMount[d.MountPoint]; ok {
+			disks[i].Roots = append(disks[i].Roots, root)
+			continue
+		}
+		d.Roots = []string{root}
+		byMount[d.MountPoint] = len(disks)
+		disks = append(disks, d)
+	}
+	return disks
+}
@@ -0,0 +1,1030 @@
+// Package syncer synchronizes the torrent client files and the local files into the database.
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"godatacleaner/internal/config"
+	"godatacleaner/internal/models"
+	"godatacleaner/internal/scanner"
+	"godatacleaner/internal/scheduler"
+	"godatacleaner/internal/storage"
+	"godatacleaner/internal/torrent"
+)
+
+// ErrSyncRunning is returned when another sync, possibly in another process, is running.
+var ErrSyncRunning = errors.New("syncer: another sync is already running")
+
+// ErrUnknownInstance is returned for a torrent client or instance not listed in TORRENT_CLIENTS.
+var ErrUnknownInstance = errors.New("syncer: unknown torrent client instance")
+
+// The sync lock is stored in the database so that the CLI and the web server
+// never sync concurrently. It is refreshed while the sync runs, between the
+// batches of its writes too (see storage.SyncTx), and expires if the process
+// dies, so a crash never blocks later syncs for long.
+const (
+	lockName    = "sync"
+	lockTTL     = 2 * time.Minute
+	lockRefresh = 30 * time.Second
+)
+
+// filesRefresh bounds the time the files of an unchanged torrent are reused
+// from the previous syncs, see filesMaxAge.
+const filesRefresh = 24 * time.Hour
+
+// scanProgressInterval is the delay between two progress updates of a scan.
+const scanProgressInterval = 500 * time.Millisecond
+
+// Stage identifies the step a sync is in.
+type Stage string
+
+// Sync stages, in execution order.
+const (
+	StageTorrents       Stage = "torrents"        // Fetching the files of every torrent
+	StageScan           Stage = "scan"            // Scanning the local directory
+	StageTorrentsInsert Stage = "torrents_insert" // Writing the torrent files to the database
+	StageInsert         Stage = "insert"          // Writing the local files to the database, in the same transaction
+	StageCleanup        Stage = "cleanup"         // Running the after-sync cleanup jobs
+	StageChecksum       Stage = "checksum"        // Hashing the local files added or changed
+	StageDone           Stage = "done"
+)
+
+// Progress is a snapshot of a running sync.
+type Progress struct {
+	Stage         Stage `json:"stage"`
+	TorrentsTotal int   `json:"torrents_total"`
+	TorrentsDone  int   `json:"torrents_done"`
+	TorrentFiles  int   `json:"torrent_files"`
+	LocalFiles    int   `json:"local_files"`
+	InsertTotal   int   `json:"insert_total"` // Rows to write in the current insert stage
+	Inserted      int   `json:"inserted"`
+	ChecksumTotal int   `json:"checksum_total"` // Local files to hash
+	Checksummed   int   `json:"checksummed"`
+
+	// Scan is the progress of the local scan, from the scan stage on
+	Scan *scanner.Progress `json:"scan,omitempty"`
+}
+
+// Result summarizes a finished sync.
+type Result struct {
+	TorrentsSynced    bool `json:"torrents_synced"`    // False when a torrent client could not be reached
+	Torrents          int  `json:"torrents"`           // Torrents recorded, refreshed or not
+	TorrentsRefreshed int  `json:"torrents_refreshed"` // Torrents whose files were fetched, the others were unchanged
+	TorrentsFailed    int  `json:"torrents_failed"`    // Torrents whose files could not be fetched
+	TorrentFiles      int  `json:"torrent_files"`
+	LocalSynced       bool `json:"local_synced"` // False when the local path was unavailable or its scan failed
+	LocalFiles        int  `json:"local_files"`
+	Checksummed       int  `json:"checksummed"`     // Local files hashed, see CHECKSUM
+	ChecksumFailed    int  `json:"checksum_failed"` // Local files that could not be read to be hashed
+
+	// Torrents whose files could not be fetched, or the client that could not be reached
+	Errors []models.SyncError `json:"errors,omitempty"`
+
+	// Rows written to the database, the files unchanged since the previous sync are not
+	TorrentChanges storage.Changes `json:"torrent_changes"`
+	LocalChanges   storage.Changes `json:"local_changes"`
+}
+
+// Syncer refreshes torrent_files and local_files.
+type Syncer struct {
+	storage   *storage.Storage
+	cfg       atomic.Pointer[config.Config] // See SetConfig
+	scheduler *scheduler.Scheduler
+	full      bool
+
+	// The torrent sources are kept between syncs, as some of them only
+	// transfer the changes since their previous call
+	mu      sync.Mutex
+	sources []torrent.TorrentSource
+}
+
+// NewSyncer creates a new syncer using the torrent clients and local path settings of cfg.
+func NewSyncer(store *storage.Storage, cfg *config.Config) *Syncer {
+	s := &Syncer{storage: store}
+	s.cfg.Store(cfg)
+	return s
+}
+
+// SetConfig replaces the configuration of the syncer, as changed from the
+// WebUI settings. A sync already running and the watcher (SCAN_WATCH) keep
+// the scanner they built; the next sync uses cfg. The torrent clients are not
+// reconnected.
+func (s *Syncer) SetConfig(cfg *config.Config) {
+	s.cfg.Store(cfg)
+}
+
+// config returns the current configuration, see SetConfig.
+func (s *Syncer) config() *config.Config {
+	return s.cfg.Load()
+}
+
+// WithScheduler sets the scheduler whose after-sync jobs run at the end of every sync.
+func (s *Syncer) WithScheduler(sched *scheduler.Scheduler) *Syncer {
+	s.scheduler = sched
+	return s
+}
+
+// WithFullSync makes every sync fetch the files of all torrents, instead of
+// only those of the torrents added or changed since the previous sync, and
+// read every local directory when scans are incremental.
+func (s *Syncer) WithFullSync(full bool) *Syncer {
+	s.full = full
+	return s
+}
+
+// Run performs a sync. progress, when not nil, is called on every step.
+// An unreachable torrent client is not an error: the torrent files of every
+// client are kept as is and only the local files are refreshed.
+// The torrents and the local files are written as a single generation once
+// both are fetched, so readers see either the previous or the new content of
+// every table, and cancelling ctx leaves the database untouched.
+// Every sync that started, finished or not, is recorded in the sync history.
+func (s *Syncer) Run(ctx context.Context, progress func(Progress)) (*Result, error) {
+	started := time.Now()
+	result, err := s.run(ctx, progress)
+	if !errors.Is(err, ErrSyncRunning) {
+		s.recordRun(ctx, started, result, err)
+	}
+	return result, err
+}
+
+// recordRun stores a sync in the sync history, with the local files it
+// added, removed or resized. A failure to store it is only logged, as the
+// sync itself is done.
+func (s *Syncer) recordRun(ctx context.Context, started time.Time, result *Result, err error) {
+	run := models.SyncRun{
+		StartedAt:  started,
+		FinishedAt: time.Now(),
+		Full:       s.full,
+	}
+	run.Status = Status(result, err)
+	if err != nil && run.Status == models.SyncRunFailed {
+		run.Error = err.Error()
+	}
+	var files []models.FileChange
+	var errs []models.SyncError
+	if result != nil {
+		run.TorrentsSynced = result.TorrentsSynced
+		run.Torrents = int64(result.Torrents)
+		run.TorrentsFailed = int64(result.TorrentsFailed)
+		run.TorrentFiles = int64(result.TorrentFiles)
+		run.LocalSynced = result.LocalSynced
+		run.LocalFiles = int64(result.LocalFiles)
+		run.ChangesRecorded = result.LocalChanges.Recorded
+		files = result.LocalChanges.Files
+		errs = result.Errors
+	}
+
+	// Une synchronisation annulée est aussi enregistrée
+	if _, err := s.storage.RecordSyncRun(context.WithoutCancel(ctx), run, files, errs); err != nil {
+		log.Printf("⚠️  Erreur enregistrement de la synchronisation: %v", err)
+	}
+}
+
+// Status returns the status of a sync in the sync history, from the result
+// and the error returned by Run.
+func Status(result *Result, err error) string {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return models.SyncRunCancelled
+	case err != nil:
+		return models.SyncRunFailed
+	case !result.TorrentsSynced || result.TorrentsFailed > 0 || !result.LocalSynced:
+		return models.SyncRunPartial
+	}
+	return models.SyncRunDone
+}
+
+func (s *Syncer) run(ctx context.Context, progress func(Progress)) (*Result, error) {
+	if progress == nil {
+		progress = func(Progress) {}
+	}
+
+	owner, err := s.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go s.keepLock(ctx, owner, cancel)
+	defer s.unlock(ctx, owner)
+
+	result := &Result{}
+	var p Progress
+
+	// Sync torrent clients
+	clients, err := s.torrentSources()
+	if err != nil {
+		return nil, fmt.Errorf("syncer: %w", err)
+	}
+
+	// Les fichiers torrents ne sont remplacés que si tous les clients ont répondu,
+	// sinon ceux d'un client injoignable apparaîtraient tous comme orphelins
+	sources, failure := listTorrents(ctx, clients)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	var fetched *torrentSync
+	if failure != nil {
+		result.Errors = append(result.Errors, *failure)
+	} else {
+		p.Stage = StageTorrents
+		for _, src := range sources {
+			p.TorrentsTotal += len(src.torrents)
+		}
+		progress(p)
+
+		// Les fichiers des torrents inchangés depuis la dernière synchronisation
+		// sont repris de la base, seuls les autres sont demandés aux clients
+		prev, err := s.loadSnapshot(ctx)
+		if err != nil {
+			return nil, syncError(ctx, err)
+		}
+
+		var allFiles []models.TorrentFile
+		var synced []models.Torrent
+		now := time.Now()
+		for _, src := range sources {
+			stale := make(map[string]models.Torrent)
+			var refresh []models.Torrent
+			for _, t := range src.torrents {
+				if files, ok := prev.unchanged(t, now); ok && !s.full {
+					t.FilesSyncedAt = prev.torrents[torrentKey{t.Instance, t.Hash}].FilesSyncedAt
+					allFiles = append(allFiles, files...)
+					synced = append(synced, t)
+					p.TorrentsDone++
+					continue
+				}
+				stale[t.Hash] = t
+				refresh = append(refresh, t)
+			}
+			p.TorrentFiles = len(allFiles)
+			progress(p)
+
+			err := torrent.StreamFiles(ctx, src.client, refresh, func(hash string, files []models.TorrentFile, err error) {
+				if err == nil {
+					t := stale[hash]
+					t.FilesSyncedAt = now
+					allFiles = append(allFiles, files...)
+					synced = append(synced, t)
+					result.TorrentsRefreshed++
+				} else {
+					// Les fichiers d'un torrent illisible sont repris de la
+					// dernière synchronisation, sinon ils deviendraient orphelins.
+					// L'ancien torrent est conservé, le prochain sync réessaiera
+					log.Printf("⚠️  Fichiers du torrent %s (%s) non récupérés: %v", stale[hash].Name, hash, err)
+					key := torrentKey{stale[hash].Instance, hash}
+					if old, ok := prev.torrents[key]; ok {
+						allFiles = append(allFiles, prev.files[key]...)
+						synced = append(synced, old)
+					}
+					result.TorrentsFailed++
+					result.Errors = append(result.Errors, models.SyncError{
+						Instance:  key.instance,
+						Hash:      hash,
+						Name:      stale[hash].Name,
+						Error:     err.Error(),
+						KeptFiles: int64(len(prev.files[key])),
+					})
+				}
+				p.TorrentsDone++
+				p.TorrentFiles = len(allFiles)
+				progress(p)
+			})
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if err != nil {
+				return nil, fmt.Errorf("syncer: %w", err)
+			}
+		}
+
+		fetched = &torrentSync{torrents: synced, files: allFiles}
+	}
+
+	// Sync local
+	scanned, err := s.scanLocal(ctx, &p, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	// Les deux tables sont écrites ensemble, une fois tout récupéré
+	if err := s.write(ctx, result, fetched, scanned, &p, progress); err != nil {
+		return nil, err
+	}
+
+	// Tâches de nettoyage planifiées après chaque sync, sauf si des torrents
+	// n'ont pas pu être lus : leurs fichiers seraient pris pour des orphelins.
+	// De même si les fichiers locaux n'ont pas pu être relus
+	switch {
+	case s.scheduler == nil:
+	case result.TorrentsFailed > 0:
+		log.Printf("⚠️  Tâches de nettoyage ignorées: %d torrents n'ont pas pu être lus", result.TorrentsFailed)
+	case !result.LocalSynced:
+		log.Printf("⚠️  Tâches de nettoyage ignorées: fichiers locaux non synchronisés")
+	default:
+		p.Stage = StageCleanup
+		progress(p)
+		if err := s.scheduler.RunAfterSync(ctx); err != nil {
+			log.Printf("⚠️  Erreur tâches de nettoyage: %v", err)
+		}
+	}
+
+	// Historique de la taille de la bibliothèque, après le nettoyage et dans
+	// les mêmes conditions : des orphelins en trop fausseraient la courbe
+	if result.TorrentsFailed == 0 && result.LocalSynced {
+		if err := s.storage.RecordLibraryHistory(context.WithoutCancel(ctx), time.Now()); err != nil {
+			log.Printf("⚠️  Erreur enregistrement de l'historique: %v", err)
+		}
+	}
+
+	// Empreintes des fichiers locaux ajoutés ou modifiés, après le nettoyage
+	// pour ne pas lire les fichiers qu'il supprime
+	h, err := s.newHasher()
+	if err != nil {
+		return nil, fmt.Errorf("syncer: %w", err)
+	}
+	if h != nil && result.LocalSynced {
+		if err := s.hashFiles(ctx, h, result, &p, progress); err != nil {
+			return nil, err
+		}
+	}
+
+	p.Stage = StageDone
+	progress(p)
+	return result, nil
+}
+
+// torrentSync holds the torrents and torrent files fetched by a sync.
+type torrentSync struct {
+	torrents []models.Torrent
+	files    []models.TorrentFile
+}
+
+// localScan holds the local files and directories found by a sync.
+type localScan struct {
+	files []models.LocalFile
+	dirs  []models.ScanDir
+}
+
+// scanLocal scans the local path, or lists the remote recorded under it. The
+// scan is nil when the local path or the remote looks unavailable or its scan
+// failed: the local files of the previous sync are then kept, as an unmounted
+// network share would otherwise leave none and hide every orphan.
+func (s *Syncer) scanLocal(ctx context.Context, p *Progress, progress func(Progress)) (*localScan, error) {
+	p.Stage = StageScan
+	p.InsertTotal, p.Inserted = 0, 0
+	progress(*p)
+
+	scan := s.newScanner()
+
+	// Un répertoire vide n'est accepté que si la base n'a aucun fichier local
+	recorded, err := s.storage.CountLocalFiles(ctx)
+	if err != nil {
+		return nil, syncError(ctx, err)
+	}
+	// Un remote n'est pas monté sous LOCAL_PATH : seul son listing est vérifié
+	remote := s.config().LocalRemote != ""
+	if !remote {
+		if err := scan.CheckRoot(s.config().ScanCanaryFile, s.config().ScanRequireMount, recorded == 0); err != nil {
+			log.Printf("⚠️  Fichiers locaux conservés, LOCAL_PATH indisponible: %v", err)
+			return nil, nil
+		}
+	}
+
+	if s.config().ScanIncremental {
+		// Une synchronisation complète relit tous les répertoires
+		var dirs []models.ScanDir
+		var files map[string][]models.LocalFile
+		if !s.full {
+			if dirs, files, err = s.loadScanState(ctx); err != nil {
+				return nil, syncError(ctx, err)
+			}
+		}
+		scan.WithPrevious(dirs, files)
+	}
+	// Le nombre de fichiers en base estime la durée restante du scan
+	scanProgress := make(chan scanner.Progress, 1)
+	scan.WithProgress(scanProgress, scanProgressInterval, int(recorded))
+	filesChan, errsChan := scan.Scan(ctx)
+
+	// La progression est relayée jusqu'à la fin du scan, qui ferme le canal
+	relayed := make(chan struct{})
+	go func() {
+		defer close(relayed)
+		for sp := range scanProgress {
+			p.LocalFiles = int(sp.Files)
+			p.Scan = &sp
+			progress(*p)
+		}
+	}()
+
+	var localFiles []models.LocalFile
+	for f := range filesChan {
+		localFiles = append(localFiles, f)
+	}
+	<-relayed
+	if err := <-errsChan; err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		log.Printf("⚠️  Erreur scan, fichiers locaux conservés: %v", err)
+		return nil, nil
+	}
+	if remote && len(localFiles) == 0 && recorded > 0 {
+		log.Printf("⚠️  Fichiers locaux conservés, LOCAL_REMOTE %s est vide", s.config().LocalRemote)
+		return nil, nil
+	}
+	dirs := scan.Dirs()
+	if n := scan.Unchanged(); n > 0 {
+		log.Printf("📂 %d répertoires inchangés sur %d repris du scan précédent", n, len(dirs))
+	}
+	return &localScan{files: localFiles, dirs: dirs}, nil
+}
+
+// write stores the torrents and the local files of a sync as a new
+// generation, visible once complete, and records them in result. A nil part
+// was not synced and keeps its previous content.
+func (s *Syncer) write(ctx context.Context, result *Result, fetched *torrentSync, scanned *localScan, p *Progress, progress func(Progress)) error {
+	if fetched == nil && scanned == nil {
+		return nil
+	}
+	tx, err := s.storage.BeginSync(ctx)
+	if err != nil {
+		return syncError(ctx, err)
+	}
+	defer tx.Rollback()
+
+	// Seuls les fichiers ajoutés, modifiés ou supprimés sont écrits
+	var torrentChanges, localChanges storage.Changes
+	if fetched != nil {
+		p.Stage = StageTorrentsInsert
+		p.InsertTotal, p.Inserted = len(fetched.files), 0
+		progress(*p)
+		torrentChanges, err = tx.ReplaceTorrentFiles(ctx, fetched.torrents, fetched.files, func(inserted int) {
+			p.Inserted = inserted
+			progress(*p)
+		})
+		if err != nil {
+			return syncError(ctx, err)
+		}
+	}
+	if scanned != nil {
+		p.Stage = StageInsert
+		p.LocalFiles = len(scanned.files)
+		p.InsertTotal, p.Inserted = len(scanned.files), 0
+		progress(*p)
+		localChanges, err = tx.ReplaceLocalFiles(ctx, scanned.files, scanned.dirs, func(inserted int) {
+			p.Inserted = inserted
+			progress(*p)
+		})
+		if err != nil {
+			return syncError(ctx, err)
+		}
+	}
+	// Les orphelins dépendent des deux tables, publiées ensemble
+	if err := tx.Commit(ctx, time.Now()); err != nil {
+		return syncError(ctx, err)
+	}
+
+	if fetched != nil {
+		result.TorrentsSynced = true
+		result.Torrents = len(fetched.torrents)
+		result.TorrentFiles = len(fetched.files)
+		result.TorrentChanges = torrentChanges
+	}
+	if scanned != nil {
+		result.LocalSynced = true
+		result.LocalFiles = len(scanned.files)
+		result.LocalChanges = localChanges
+	}
+	return nil
+}
+
+// DeleteTorrent deletes a torrent from the client or instance it was synced
+// from, and its files on disk when deleteFiles is set, then removes it from
+// the database. The sync lock is held meanwhile, so that a running sync does
+// not store the torrent again.
+func (s *Syncer) DeleteTorrent(ctx context.Context, instance, hash string, deleteFiles bool) error {
+	owner, err := s.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.unlock(ctx, owner)
+
+	src, err := s.findSource(instance)
+	if err != nil {
+		return err
+	}
+	if err := src.Login(ctx); err != nil {
+		return fmt.Errorf("syncer: %w", err)
+	}
+	if err := torrent.Delete(ctx, src, hash, deleteFiles); err != nil {
+		return fmt.Errorf("syncer: %w", err)
+	}
+
+	if err := s.storage.DeleteTorrent(ctx, instance, hash, deleteFiles); err != nil {
+		return fmt.Errorf("syncer: %w", err)
+	}
+	return nil
+}
+
+// TorrentLocalFiles returns the local files of a torrent, those DeleteTorrent
+// deletes with deleteFiles.
+func (s *Syncer) TorrentLocalFiles(ctx context.Context, instance, hash string) ([]models.LocalFile, error) {
+	files, err := s.storage.ListTorrentLocalFiles(ctx, instance, hash)
+	if err != nil {
+		return nil, fmt.Errorf("syncer: %w", err)
+	}
+	return files, nil
+}
+
+// MoveTorrent moves the data of a torrent to location, a directory as seen by
+// its torrent client, and updates the expected paths of its files in the same
+// operation. Like DeleteTorrent, it holds the sync lock.
+func (s *Syncer) MoveTorrent(ctx context.Context, instance, hash, location string) error {
+	owner, err := s.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.unlock(ctx, owner)
+
+	t, err := s.storage.GetTorrent(ctx, instance, hash)
+	if err != nil {
+		return fmt.Errorf("syncer: %w", err)
+	}
+	files, err := s.storage.ListTorrentFilesOf(ctx, instance, hash)
+	if err != nil {
+		return fmt.Errorf("syncer: %w", err)
+	}
+
+	src, err := s.findSource(instance)
+	if err != nil {
+		return err
+	}
+	if err := src.Login(ctx); err != nil {
+		return fmt.Errorf("syncer: %w", err)
+	}
+	if err := torrent.Move(ctx, src, hash, location); err != nil {
+		return fmt.Errorf("syncer: %w", err)
+	}
+
+	// Les chemins des fichiers sont relatifs au répertoire de sauvegarde,
+	// après application des correspondances de chemins de l'instance
+	from, to := torrent.MapPath(src, t.SavePath), torrent.MapPath(src, location)
+	for i, f := range files {
+		rel, err := filepath.Rel(from, f.FilePath)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			log.Printf("⚠️  %s n'est pas dans %s, chemin inchangé", f.FilePath, from)
+			continue
+		}
+		files[i].FilePath = filepath.Join(to, rel)
+	}
+
+	if err := s.storage.MoveTorrent(ctx, instance, hash, location, to, files); err != nil {
+		return fmt.Errorf("syncer: %w", err)
+	}
+	return nil
+}
+
+// PauseTorrent pauses a torrent of a client or instance and stores its new state.
+func (s *Syncer) PauseTorrent(ctx context.Context, instance, hash string) error {
+	return s.torrentAction(ctx, instance, hash, torrent.Pause)
+}
+
+// ResumeTorrent resumes a torrent of a client or instance and stores its new state.
+func (s *Syncer) ResumeTorrent(ctx context.Context, instance, hash string) error {
+	return s.torrentAction(ctx, instance, hash, torrent.Resume)
+}
+
+// RecheckTorrent forces a recheck of a torrent of a client or instance and stores its new state.
+func (s *Syncer) RecheckTorrent(ctx context.Context, instance, hash string) error {
+	return s.torrentAction(ctx, instance, hash, torrent.Recheck)
+}
+
+// RecheckAction describes the recheck of one torrent.
+type RecheckAction struct {
+	Torrent models.Torrent `json:"torrent"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// RecheckReport summarizes the recheck of the torrents affected by a cleanup or a restore.
+type RecheckReport struct {
+	Actions   []RecheckAction `json:"actions"`
+	Rechecked int64           `json:"rechecked"`
+	Failed    int64           `json:"failed"`
+}
+
+// RecheckPaths forces a recheck of the torrents expecting any of the given
+// local files, e.g. after they were quarantined or restored, so that the
+// torrent clients notice the change. A failed recheck does not stop the others.
+func (s *Syncer) RecheckPaths(ctx context.Context, paths []string) (*RecheckReport, error) {
+	torrents, err := s.storage.GetTorrentsForPaths(ctx, paths)
+	if err != nil {
+		return nil, fmt.Errorf("syncer: %w", err)
+	}
+
+	report := &RecheckReport{}
+	for _, t := range torrents {
+		action := RecheckAction{Torrent: t}
+		if err := s.RecheckTorrent(ctx, t.Instance, t.Hash); err != nil {
+			action.Error = err.Error()
+			report.Failed++
+		} else {
+			report.Rechecked++
+		}
+		report.Actions = append(report.Actions, action)
+	}
+	return report, nil
+}
+
+// InstanceAction describes an action on the torrents of one client or instance.
+type InstanceAction struct {
+	Instance string   `json:"instance"`
+	Hashes   []string `json:"hashes"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// LabelReport summarizes a change of the category or the tags of torrents.
+type LabelReport struct {
+	Actions []InstanceAction `json:"actions"`
+	Labeled int64            `json:"labeled"`
+	Failed  int64            `json:"failed"`
+}
+
+// SetCategory sets the category of torrents, or removes it when category is
+// empty. Only Instance and Hash of the torrents are used.
+func (s *Syncer) SetCategory(ctx context.Context, torrents []models.Torrent, category string) *LabelReport {
+	report := &LabelReport{}
+	report.Actions, report.Labeled, report.Failed = s.byInstance(ctx, torrents, func(ctx context.Context, src torrent.TorrentSource, hashes []string) error {
+		return torrent.SetCategory(ctx, src, hashes, category)
+	})
+	return report
+}
+
+// SetTags adds and removes tags of torrents. Only Instance and Hash of the
+// torrents are used.
+func (s *Syncer) SetTags(ctx context.Context, torrents []models.Torrent, add, remove []string) *LabelReport {
+	report := &LabelReport{}
+	report.Actions, report.Labeled, report.Failed = s.byInstance(ctx, torrents, func(ctx context.Context, src torrent.TorrentSource, hashes []string) error {
+		if len(add) > 0 {
+			if err := torrent.AddTags(ctx, src, hashes, add); err != nil {
+				return err
+			}
+		}
+		if len(remove) > 0 {
+			return torrent.RemoveTags(ctx, src, hashes, remove)
+		}
+		return nil
+	})
+	return report
+}
+
+// ReannounceReport summarizes the reannounce of torrents.
+type ReannounceReport struct {
+	Actions     []InstanceAction `json:"actions"`
+	Reannounced int64            `json:"reannounced"`
+	Failed      int64            `json:"failed"`
+}
+
+// Reannounce reannounces torrents to their trackers, e.g. after their data
+// was restored from the quarantine. Only Instance and Hash of the torrents
+// are used.
+func (s *Syncer) Reannounce(ctx context.Context, torrents []models.Torrent) *ReannounceReport {
+	report := &ReannounceReport{}
+	report.Actions, report.Reannounced, report.Failed = s.byInstance(ctx, torrents, torrent.Reannounce)
+	return report
+}
+
+// ReannouncePaths reannounces the torrents expecting any of the given local
+// files, as RecheckPaths does.
+func (s *Syncer) ReannouncePaths(ctx context.Context, paths []string) (*ReannounceReport, error) {
+	torrents, err := s.storage.GetTorrentsForPaths(ctx, paths)
+	if err != nil {
+		return nil, fmt.Errorf("syncer: %w", err)
+	}
+	return s.Reannounce(ctx, torrents), nil
+}
+
+// byInstance runs action once per client or instance, with the hashes of its
+// torrents, and returns the actions with the number of torrents they
+// succeeded and failed for. A failed instance does not stop the others.
+func (s *Syncer) byInstance(ctx context.Context, torrents []models.Torrent, action func(context.Context, torrent.TorrentSource, []string) error) (actions []InstanceAction, done, failed int64) {
+	var instances []string
+	hashes := make(map[string][]string)
+	seen := make(map[string]bool)
+	for _, t := range torrents {
+		key := t.Instance + "/" + t.Hash
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if _, ok := hashes[t.Instance]; !ok {
+			instances = append(instances, t.Instance)
+		}
+		hashes[t.Instance] = append(hashes[t.Instance], t.Hash)
+	}
+
+	for _, instance := range instances {
+		a := InstanceAction{Instance: instance, Hashes: hashes[instance]}
+		if err := s.runOnInstance(ctx, instance, a.Hashes, action); err != nil {
+			a.Error = err.Error()
+			failed += int64(len(a.Hashes))
+		} else {
+			done += int64(len(a.Hashes))
+		}
+		actions = append(actions, a)
+	}
+	return actions, done, failed
+}
+
+// runOnInstance logs in to the source of a client or instance and runs action.
+func (s *Syncer) runOnInstance(ctx context.Context, instance string, hashes []string, action func(context.Context, torrent.TorrentSource, []string) error) error {
+	src, err := s.findSource(instance)
+	if err != nil {
+		return err
+	}
+	if err := src.Login(ctx); err != nil {
+		return fmt.Errorf("syncer: %w", err)
+	}
+	if err := action(ctx, src, hashes); err != nil {
+		return fmt.Errorf("syncer: %w", err)
+	}
+	return nil
+}
+
+// torrentAction runs action on a torrent, then lists the torrents of its
+// client again to store the state the torrent is now in.
+func (s *Syncer) torrentAction(ctx context.Context, instance, hash string, action func(context.Context, torrent.TorrentSource, string) error) error {
+	src, err := s.findSource(instance)
+	if err != nil {
+		return err
+	}
+	if err := src.Login(ctx); err != nil {
+		return fmt.Errorf("syncer: %w", err)
+	}
+	if err := action(ctx, src, hash); err != nil {
+		return fmt.Errorf("syncer: %w", err)
+	}
+
+	torrents, err := src.GetTorrents(ctx)
+	if err != nil {
+		return fmt.Errorf("syncer: %w", err)
+	}
+	for _, t := range torrents {
+		if t.Hash == hash {
+			t.Instance = instance
+			if err := s.storage.UpdateTorrentState(ctx, t); err != nil {
+				return fmt.Errorf("syncer: %w", err)
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// findSource returns the torrent source of a client or instance.
+func (s *Syncer) findSource(instance string) (torrent.TorrentSource, error) {
+	sources, err := s.torrentSources()
+	if err != nil {
+		return nil, fmt.Errorf("syncer: %w", err)
+	}
+	for _, src := range sources {
+		if torrent.InstanceName(src) == instance {
+			return src, nil
+		}
+	}
+	return nil, ErrUnknownInstance
+}
+
+// torrentSources returns the torrent sources, created on the first sync.
+func (s *Syncer) torrentSources() ([]torrent.TorrentSource, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sources == nil {
+		sources, err := torrent.NewSources(s.config())
+		if err != nil {
+			return nil, err
+		}
+		s.sources = sources
+	}
+	return s.sources, nil
+}
+
+// torrentKey identifies a torrent across the torrent clients.
+type torrentKey struct {
+	instance, hash string
+}
+
+// snapshot holds the torrents and torrent files of the previous sync.
+type snapshot struct {
+	torrents map[torrentKey]models.Torrent
+	files    map[torrentKey][]models.TorrentFile
+}
+
+// loadSnapshot reads the torrents and torrent files of the previous sync.
+func (s *Syncer) loadSnapshot(ctx context.Context) (*snapshot, error) {
+	torrents, err := s.storage.ListSyncedTorrents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	files, err := s.storage.ListAllTorrentFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	snap := &snapshot{
+		torrents: make(map[torrentKey]models.Torrent, len(torrents)),
+		files:    make(map[torrentKey][]models.TorrentFile, len(torrents)),
+	}
+	for _, t := range torrents {
+		snap.torrents[torrentKey{t.Instance, t.Hash}] = t
+	}
+	for _, f := range files {
+		key := torrentKey{f.Instance, f.TorrentHash}
+		snap.files[key] = append(snap.files[key], f)
+	}
+	return snap, nil
+}
+
+// unchanged returns the files of t recorded by the previous sync, if t has
+// not been renamed, moved or resized since, nor its content path or path
+// mappings changed, and its files were fetched recently enough as of now
+// (see filesMaxAge).
+func (s *snapshot) unchanged(t models.Torrent, now time.Time) ([]models.TorrentFile, bool) {
+	prev, ok := s.torrents[torrentKey{t.Instance, t.Hash}]
+	if !ok || prev.Name != t.Name || prev.SavePath != t.SavePath || prev.LocalSavePath != t.LocalSavePath || prev.Size != t.Size ||
+		prev.ContentPath != t.ContentPath || now.Sub(prev.FilesSyncedAt) >= filesMaxAge(t.Hash) {
+		return nil, false
+	}
+	return s.files[torrentKey{t.Instance, t.Hash}], true
+}
+
+// filesMaxAge returns how long the files of the torrent hash are reused from
+// the previous syncs. No torrent client reports a file renamed inside a
+// torrent, its name and size unchanged, so the files of every torrent are
+// fetched again once that old: between half and all of filesRefresh
+// depending on the hash, so that the torrents fetched by the same sync are
+// not all fetched again together.
+func filesMaxAge(hash string) time.Duration {
+	h := fnv.New32a()
+	h.Write([]byte(hash))
+	return filesRefresh/2 + time.Duration(h.Sum32())%(filesRefresh/2)
+}
+
+// newScanner returns a scanner of the local path with the scan settings.
+func (s *Syncer) newScanner() *scanner.Scanner {
+	rules := make([]scanner.TreeRule, len(s.config().ScanRoots))
+	for i, r := range s.config().ScanRoots {
+		rules[i] = scanner.TreeRule{
+			Path:     filepath.Join(s.config().LocalPath, r.Path),
+			MaxDepth: r.MaxDepth,
+			Include:  r.Include,
+		}
+	}
+	categories := make([]scanner.Category, len(s.config().CategoryRules))
+	for i, r := range s.config().CategoryRules {
+		// Les motifs sont validés au chargement de la configuration
+		categories[i] = scanner.Category{Name: r.Name, Pattern: regexp.MustCompile(r.Pattern)}
+	}
+	scan := scanner.NewScanner(s.config().LocalPath).
+		WithWorkers(s.config().ScanWorkers).
+		WithFollowSymlinks(s.config().ScanFollowSymlinks).
+		WithCategories(categories).
+		WithFilter(s.config().ScanMinSize, s.config().ScanExtensions).
+		WithTreeRules(rules)
+	if s.config().LocalRemote != "" {
+		scan.WithLister(scanner.NewRclone(s.config().LocalRemote))
+	}
+	if s.config().ScanExtras {
+		scan.WithExtras()
+	}
+	return scan
+}
+
+// loadScanState reads the directories and local files of the previous scan,
+// the files grouped by scanned directory.
+func (s *Syncer) loadScanState(ctx context.Context) ([]models.ScanDir, map[string][]models.LocalFile, error) {
+	dirs, err := s.storage.ListScanDirs(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	files, err := s.storage.ListAllLocalFiles(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stored := make(map[string][]models.LocalFile)
+	for _, f := range files {
+		dir := filepath.Dir(f.FilePath)
+		stored[dir] = append(stored[dir], f)
+	}
+
+	// Les chemins enregistrés sont normalisés (PATH_REWRITES) : le répertoire
+	// enregistré d'un répertoire scanné est celui de ses fichiers normalisés
+	byDir := make(map[string][]models.LocalFile, len(dirs))
+	for _, d := range dirs {
+		key := filepath.Dir(s.storage.NormalizePath(filepath.Join(d.Path, "_")))
+		for _, f := range stored[key] {
+			f.FilePath = filepath.Join(d.Path, f.FileName)
+			byDir[d.Path] = append(byDir[d.Path], f)
+		}
+	}
+	return dirs, byDir, nil
+}
+
+// source is a torrent client and its torrents.
+type source struct {
+	client   torrent.TorrentSource
+	torrents []models.Torrent
+}
+
+// listTorrents logs in to every client and lists its torrents. It stops at
+// the first client that cannot be reached and returns its error.
+func listTorrents(ctx context.Context, clients []torrent.TorrentSource) ([]source, *models.SyncError) {
+	var sources []source
+	for _, c := range clients {
+		if err := c.Login(ctx); err != nil {
+			log.Printf("⚠️  Impossible de se connecter à %s: %v", c.Name(), err)
+			return nil, &models.SyncError{Instance: torrent.InstanceName(c), Error: err.Error()}
+		}
+		torrents, err := c.GetTorrents(ctx)
+		if err != nil {
+			log.Printf("⚠️  Erreur récupération torrents %s: %v", c.Name(), err)
+			return nil, &models.SyncError{Instance: torrent.InstanceName(c), Error: err.Error()}
+		}
+		instance := torrent.InstanceName(c)
+		for i := range torrents {
+			torrents[i].Instance = instance
+			torrents[i].LocalSavePath = torrent.MapPath(c, torrents[i].SavePath)
+		}
+		sources = append(sources, source{client: c, torrents: torrents})
+	}
+	return sources, nil
+}
+
+// lock takes the sync lock and returns its owner identifier.
+func (s *Syncer) lock(ctx context.Context) (string, error) {
+	host, _ := os.Hostname()
+	owner := fmt.Sprintf("%s:%d:%d", host, os.Getpid(), time.Now().UnixNano())
+
+	ok, err := s.storage.AcquireLock(ctx, lockName, owner, lockTTL)
+	if err != nil {
+		return "", fmt.Errorf("syncer: %w", err)
+	}
+	if !ok {
+		return "", ErrSyncRunning
+	}
+	return owner, nil
+}
+
+// unlock releases the sync lock, even when ctx is cancelled.
+func (s *Syncer) unlock(ctx context.Context, owner string) {
+	if err := s.storage.ReleaseLock(context.WithoutCancel(ctx), lockName, owner); err != nil {
+		log.Printf("⚠️  Erreur libération du verrou de synchronisation: %v", err)
+	}
+}
+
+// keepLock refreshes the sync lock until ctx is done. The sync is cancelled
+// if the lock is lost, as another process may then start syncing.
+func (s *Syncer) keepLock(ctx context.Context, owner string, cancel context.CancelFunc) {
+	ticker := time.NewTicker(lockRefresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		ok, err := s.storage.RefreshLock(ctx, lockName, owner, lockTTL)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil || !ok {
+			log.Printf("⚠️  Verrou de synchronisation perdu, synchronisation interrompue")
+			cancel()
+			return
+		}
+	}
+}
+
+// syncError returns ctx.Err() when the failure was caused by the cancellation.
+func syncError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return fmt.Errorf("syncer: %w", err)
+}
@@ -0,0 +1,258 @@
+package syncer
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"godatacleaner/internal/config"
+	"godatacleaner/internal/models"
+	"godatacleaner/internal/storage"
+	"godatacleaner/internal/torrent"
+)
+
+// fakeSource is a torrent client whose torrents and files are set by the
+// tests, counting the torrents whose files it is asked for.
+type fakeSource struct {
+	mu       sync.Mutex
+	torrents []models.Torrent
+	files    map[string][]models.TorrentFile // By hash
+	fetched  map[string]int                  // By hash
+}
+
+func newFakeSource() *fakeSource {
+	return &fakeSource{files: make(map[string][]models.TorrentFile), fetched: make(map[string]int)}
+}
+
+func (f *fakeSource) Name() string                    { return "fake" }
+func (f *fakeSource) Login(ctx context.Context) error { return nil }
+
+func (f *fakeSource) GetTorrents(ctx context.Context) ([]models.Torrent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.torrents), nil
+}
+
+func (f *fakeSource) GetTorrentFiles(ctx context.Context, hash string) ([]models.TorrentFile, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fetched[hash]++
+	return slices.Clone(f.files[hash]), nil
+}
+
+// set replaces the torrents of the client with a single torrent, hash, and
+// its files at paths, under savePath.
+func (f *fakeSource) set(hash, savePath, contentPath string, paths ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := models.Torrent{Hash: hash, Name: hash, SavePath: savePath, ContentPath: contentPath, State: models.TorrentStateSeeding}
+	f.files[hash] = nil
+	for _, p := range paths {
+		f.files[hash] = append(f.files[hash], models.TorrentFile{
+			TorrentHash: hash,
+			TorrentName: hash,
+			FileName:    filepath.Base(p),
+			FilePath:    filepath.Join(savePath, p),
+			Size:        100,
+		})
+		t.Size += 100
+	}
+	f.torrents = []models.Torrent{t}
+}
+
+// reset clears the fetch counts.
+func (f *fakeSource) reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	clear(f.fetched)
+}
+
+// newTestSyncer returns a syncer of the torrents of src and of the local
+// files under root, storing them in a new database.
+func newTestSyncer(t *testing.T, src torrent.TorrentSource, root string) (*Syncer, *storage.Storage) {
+	t.Helper()
+	store, err := storage.NewStorage(filepath.Join(t.TempDir(), "test.db"), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if err := store.Initialize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	s := NewSyncer(store, &config.Config{LocalPath: root})
+	s.sources = []torrent.TorrentSource{src}
+	return s, store
+}
+
+// torrentFilePaths returns the paths of the stored torrent files, sorted.
+func torrentFilePaths(t *testing.T, store *storage.Storage) []string {
+	t.Helper()
+	files, err := store.ListAllTorrentFiles(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, f.FilePath)
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+// TestIncrementalTorrentFiles checks that the files of an unchanged torrent
+// are reused, and fetched again once its content path changes.
+func TestIncrementalTorrentFiles(t *testing.T) {
+	ctx := context.Background()
+	src := newFakeSource()
+	s, store := newTestSyncer(t, src, t.TempDir())
+
+	src.set("a", "/downloads", "/downloads/a.mkv", "a.mkv")
+	if _, err := s.Run(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if src.fetched["a"] != 1 {
+		t.Errorf("first sync fetched the files %d times, want 1", src.fetched["a"])
+	}
+
+	src.reset()
+	result, err := s.Run(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src.fetched["a"] != 0 || result.TorrentsRefreshed != 0 {
+		t.Errorf("unchanged torrent fetched %d times, want 0", src.fetched["a"])
+	}
+
+	// Renommé dans le client, sans changer de nom ni de taille
+	src.reset()
+	src.set("a", "/downloads", "/downloads/b.mkv", "b.mkv")
+	if _, err := s.Run(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if src.fetched["a"] != 1 {
+		t.Errorf("renamed torrent fetched %d times, want 1", src.fetched["a"])
+	}
+	if got, want := torrentFilePaths(t, store), []string{"/downloads/b.mkv"}; !slices.Equal(got, want) {
+		t.Errorf("torrent files = %v, want %v", got, want)
+	}
+}
+
+// TestFilesMaxAge checks that the files of a torrent are fetched again once
+// too old, even when the torrent looks unchanged.
+func TestFilesMaxAge(t *testing.T) {
+	now := time.Now()
+	tor := models.Torrent{Hash: "a", Name: "a", SavePath: "/downloads", Size: 100, Instance: "fake"}
+	for _, c := range []struct {
+		age       time.Duration
+		unchanged bool
+	}{
+		{time.Hour, true},
+		{filesRefresh/2 - time.Minute, true},
+		{filesRefresh, false},
+	} {
+		prev := tor
+		prev.FilesSyncedAt = now.Add(-c.age)
+		snap := &snapshot{
+			torrents: map[torrentKey]models.Torrent{{"fake", "a"}: prev},
+			files:    map[torrentKey][]models.TorrentFile{{"fake", "a"}: {{TorrentHash: "a", FilePath: "/downloads/a.mkv"}}},
+		}
+		if _, ok := snap.unchanged(tor, now); ok != c.unchanged {
+			t.Errorf("unchanged after %v = %v, want %v", c.age, ok, c.unchanged)
+		}
+	}
+}
+
+// writeLocal creates the file at root/rel with size bytes.
+func writeLocal(t *testing.T, root, rel string, size int) string {
+	t.Helper()
+	path := filepath.Join(root, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// localSizes returns the sizes of the stored local files, by path.
+func localSizes(t *testing.T, store *storage.Storage) map[string]int64 {
+	t.Helper()
+	files, err := store.ListAllLocalFiles(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sizes := make(map[string]int64, len(files))
+	for _, f := range files {
+		sizes[f.FilePath] = f.Size
+	}
+	return sizes
+}
+
+// TestSyncLocalChanges checks that a sync only writes the local files added,
+// changed or removed since the previous one.
+func TestSyncLocalChanges(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	s, store := newTestSyncer(t, newFakeSource(), root)
+
+	a := writeLocal(t, root, "movies/A/a.mkv", 10)
+	b := writeLocal(t, root, "movies/B/b.mkv", 10)
+	c := writeLocal(t, root, "movies/C/c.mkv", 10)
+	result, err := s.Run(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ch := result.LocalChanges; ch.Inserted != 3 || ch.Updated != 0 || ch.Deleted != 0 {
+		t.Errorf("first sync changes = %+v, want 3 inserted", ch)
+	}
+
+	if err := os.Remove(b); err != nil {
+		t.Fatal(err)
+	}
+	writeLocal(t, root, "movies/C/c.mkv", 20)
+	d := writeLocal(t, root, "movies/D/d.mkv", 10)
+	result, err = s.Run(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ch := result.LocalChanges; ch.Inserted != 1 || ch.Updated != 1 || ch.Deleted != 1 {
+		t.Errorf("second sync changes = %+v, want 1 inserted, 1 updated, 1 deleted", ch)
+	}
+	want := map[string]int64{a: 10, c: 20, d: 10}
+	if got := localSizes(t, store); !maps.Equal(got, want) {
+		t.Errorf("local files = %v, want %v", got, want)
+	}
+}
+
+// TestSyncKeepsLocalFiles checks that the local files are kept when the
+// local path is unavailable, as an unmounted share would be.
+func TestSyncKeepsLocalFiles(t *testing.T) {
+	ctx := context.Background()
+	root := filepath.Join(t.TempDir(), "library")
+	s, store := newTestSyncer(t, newFakeSource(), root)
+
+	a := writeLocal(t, root, "movies/A/a.mkv", 10)
+	if _, err := s.Run(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.RemoveAll(root); err != nil {
+		t.Fatal(err)
+	}
+	result, err := s.Run(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.LocalSynced || Status(result, nil) != models.SyncRunPartial {
+		t.Errorf("sync of a missing local path: local synced %v, status %s, want partial", result.LocalSynced, Status(result, nil))
+	}
+	if got, want := localSizes(t, store), map[string]int64{a: 10}; !maps.Equal(got, want) {
+		t.Errorf("local files = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,147 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"godatacleaner/internal/models"
+	"godatacleaner/internal/scanner"
+)
+
+// ErrWatchRemote is returned by Watch when the local files are listed from a
+// remote, which reports no changes.
+var ErrWatchRemote = errors.New("syncer: LOCAL_REMOTE cannot be watched")
+
+// watchDelay is how long the changes reported by the filesystem are gathered
+// before being written, as copying a file reports many writes.
+const watchDelay = 2 * time.Second
+
+// Watch keeps local_files current between syncs, until ctx is done, by
+// applying the changes reported by the filesystem under the local path.
+// Changed paths are read again and written every watchDelay, while no sync is
+// running. It returns an error when the local path cannot be watched, or is
+// listed from a remote.
+func (s *Syncer) Watch(ctx context.Context) error {
+	if s.config().LocalRemote != "" {
+		return ErrWatchRemote
+	}
+	scan := s.newScanner()
+	paths, errs := scan.Watch(ctx)
+
+	ticker := time.NewTicker(watchDelay)
+	defer ticker.Stop()
+
+	pending := make(map[string]bool)
+	for {
+		select {
+		case path, ok := <-paths:
+			if !ok {
+				// errs est nil s'il a déjà été fermé
+				if errs != nil {
+					if err := <-errs; err != nil {
+						return fmt.Errorf("syncer: %w", err)
+					}
+				}
+				return ctx.Err()
+			}
+			pending[path] = true
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			if !errors.Is(err, scanner.ErrEventsLost) {
+				return fmt.Errorf("syncer: %w", err)
+			}
+			log.Printf("⚠️  Événements du système de fichiers perdus, certains changements attendront la prochaine synchronisation")
+		case <-ticker.C:
+			if len(pending) == 0 {
+				continue
+			}
+			if err := s.applyChanges(ctx, scan, pending); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				log.Printf("⚠️  Erreur mise à jour des fichiers locaux: %v", err)
+			}
+		}
+	}
+}
+
+// applyChanges writes the files at the pending paths, removes the pending
+// paths that no longer exist and updates the orphans, as a single
+// generation. While a sync is running, the changes are kept for later: its
+// scan may have read the paths before they changed.
+func (s *Syncer) applyChanges(ctx context.Context, scan *scanner.Scanner, pending map[string]bool) error {
+	owner, err := s.lock(ctx)
+	if errors.Is(err, ErrSyncRunning) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go s.keepLock(ctx, owner, cancel)
+	defer s.unlock(ctx, owner)
+
+	// Un partage réseau démonté ferait disparaître tous les chemins
+	recorded, err := s.storage.CountLocalFiles(ctx)
+	if err != nil {
+		return err
+	}
+	if err := scan.CheckRoot(s.config().ScanCanaryFile, s.config().ScanRequireMount, recorded == 0); err != nil {
+		clear(pending)
+		log.Printf("⚠️  Changements ignorés, LOCAL_PATH indisponible: %v", err)
+		return nil
+	}
+
+	// Les chemins sont lus avant d'ouvrir la transaction, pour ne pas garder
+	// la connexion d'écriture pendant les accès au disque
+	var paths []string
+	var updated []models.LocalFile
+	removed := 0
+	for path := range pending {
+		files, ok, err := scan.Stat(ctx, path)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		delete(pending, path)
+		if err != nil {
+			log.Printf("⚠️  Erreur lecture de %s: %v", path, err)
+			continue
+		}
+		paths = append(paths, path)
+		if !ok {
+			removed++
+		}
+		updated = append(updated, files...)
+	}
+
+	// Les suppressions, les ajouts et les dates des orphelins sont écrits
+	// ensemble, comme par une synchronisation
+	tx, err := s.storage.BeginSync(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Chaque chemin est remplacé par son contenu actuel, sans les fichiers
+	// supprimés ou désormais exclus par le filtre du scan
+	for _, path := range paths {
+		if err := tx.DeleteLocalFilesUnder(ctx, s.storage.NormalizePath(path)); err != nil {
+			return err
+		}
+	}
+	if err := tx.InsertLocalFiles(ctx, updated); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx, time.Now()); err != nil {
+		return err
+	}
+	log.Printf("👁️  Fichiers locaux: %d mis à jour, %d chemins supprimés", len(updated), removed)
+	return nil
+}
@@ -0,0 +1,41 @@
+package syncer
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestApplyChanges checks that the changes reported by the watcher replace
+// the local files at their paths, and only them.
+func TestApplyChanges(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	s, store := newTestSyncer(t, newFakeSource(), root)
+
+	a := writeLocal(t, root, "movies/A/a.mkv", 10)
+	b := writeLocal(t, root, "movies/B/b.mkv", 10)
+	if _, err := s.Run(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Un fichier ajouté, un répertoire supprimé et un fichier modifié
+	c := writeLocal(t, root, "movies/C/c.mkv", 10)
+	if err := os.RemoveAll(filepath.Dir(b)); err != nil {
+		t.Fatal(err)
+	}
+	writeLocal(t, root, "movies/A/a.mkv", 20)
+	pending := map[string]bool{filepath.Dir(c): true, filepath.Dir(b): true, a: true}
+	if err := s.applyChanges(ctx, s.newScanner(), pending); err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("pending paths left = %v, want none", pending)
+	}
+	want := map[string]int64{a: 20, c: 10}
+	if got := localSizes(t, store); !maps.Equal(got, want) {
+		t.Errorf("local files = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,63 @@
+package torrent
+
+import (
+	"time"
+
+	"godatacleaner/internal/config"
+	"godatacleaner/internal/deluge"
+	"godatacleaner/internal/qbittorrent"
+	"godatacleaner/internal/rtorrent"
+	"godatacleaner/internal/transmission"
+)
+
+// Built-in torrent clients
+func init() {
+	Register(config.TorrentClientQBittorrent, newQBittorrentSources)
+	Register(config.TorrentClientTransmission, func(cfg *config.Config) ([]TorrentSource, error) {
+		client, err := transmission.NewClient(cfg.TransmissionURL(), cfg.TransmissionUsername, cfg.TransmissionPassword)
+		if err != nil {
+			return nil, err
+		}
+		return []TorrentSource{client}, nil
+	})
+	Register(config.TorrentClientDeluge, func(cfg *config.Config) ([]TorrentSource, error) {
+		client, err := deluge.NewClient(cfg.DelugeURL(), cfg.DelugePassword)
+		if err != nil {
+			return nil, err
+		}
+		return []TorrentSource{client}, nil
+	})
+	Register(config.TorrentClientRTorrent, func(cfg *config.Config) ([]TorrentSource, error) {
+		client, err := rtorrent.NewClient(cfg.RTorrentURL, cfg.RTorrentUsername, cfg.RTorrentPassword)
+		if err != nil {
+			return nil, err
+		}
+		return []TorrentSource{client}, nil
+	})
+}
+
+// newQBittorrentSources creates a source per qBittorrent instance.
+func newQBittorrentSources(cfg *config.Config) ([]TorrentSource, error) {
+	servers := cfg.QBittorrentServers()
+	tlsConfig, err := qbittorrent.TLSConfig(cfg.QBittorrentCAPath, cfg.QBittorrentSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	sources := make([]TorrentSource, 0, len(servers))
+	for _, srv := range servers {
+		client, err := qbittorrent.NewClient(srv.URL(), srv.Username, srv.Password, cfg.QBittorrentMaxWorkers)
+		if err != nil {
+			return nil, err
+		}
+		client.WithRetry(cfg.QBittorrentRetries,
+			time.Duration(cfg.QBittorrentRetryDelay)*time.Millisecond,
+			time.Duration(cfg.QBittorrentTimeout)*time.Second).
+			WithTLSConfig(tlsConfig)
+		label := client.Name()
+		if len(cfg.QBittorrentInstances) > 0 {
+			label += " " + srv.Name
+		}
+		sources = append(sources, &instance{TorrentSource: client, name: srv.Name, label: label, mappings: srv.PathMappings})
+	}
+	return sources, nil
+}
@@ -0,0 +1,310 @@
+// Package torrent creates the torrent sources the expected files are synced from.
+package torrent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"godatacleaner/internal/config"
+	"godatacleaner/internal/models"
+)
+
+// TorrentSource lists the torrents of a torrent client and their files.
+type TorrentSource interface {
+	// Name returns the name of the torrent client, for logs.
+	Name() string
+	Login(ctx context.Context) error
+	GetTorrents(ctx context.Context) ([]models.Torrent, error)
+	GetTorrentFiles(ctx context.Context, hash string) ([]models.TorrentFile, error)
+}
+
+// FileStreamer is implemented by the sources able to fetch the files of
+// several torrents concurrently. fn is called once per torrent, never
+// concurrently, with the files of the torrent or the error fetching them.
+type FileStreamer interface {
+	StreamTorrentFiles(ctx context.Context, torrents []models.Torrent, fn func(hash string, files []models.TorrentFile, err error)) error
+}
+
+// Remover is implemented by the sources able to delete a torrent, and its
+// files on disk when deleteFiles is set.
+type Remover interface {
+	DeleteTorrent(ctx context.Context, hash string, deleteFiles bool) error
+}
+
+// Pauser is implemented by the sources able to pause and resume a torrent.
+type Pauser interface {
+	PauseTorrent(ctx context.Context, hash string) error
+	ResumeTorrent(ctx context.Context, hash string) error
+}
+
+// Rechecker is implemented by the sources able to force a recheck of the
+// data of a torrent.
+type Rechecker interface {
+	RecheckTorrent(ctx context.Context, hash string) error
+}
+
+// Mover is implemented by the sources able to move the data of a torrent to
+// another directory. location is a path as seen by the torrent client.
+type Mover interface {
+	MoveTorrent(ctx context.Context, hash, location string) error
+}
+
+// Labeler is implemented by the sources able to set the category and the
+// tags of torrents.
+type Labeler interface {
+	SetCategory(ctx context.Context, hashes []string, category string) error
+	AddTags(ctx context.Context, hashes []string, tags []string) error
+	RemoveTags(ctx context.Context, hashes []string, tags []string) error
+}
+
+// Reannouncer is implemented by the sources able to reannounce torrents to
+// their trackers.
+type Reannouncer interface {
+	ReannounceTorrents(ctx context.Context, hashes []string) error
+}
+
+// ErrUnsupported is returned for an action the torrent client does not support.
+var ErrUnsupported = errors.New("torrent: action not supported by the client")
+
+// Factory creates the sources of a torrent client from the configuration.
+// A client may have several sources, e.g. one per qBittorrent instance.
+type Factory func(cfg *config.Config) ([]TorrentSource, error)
+
+var (
+	mu        sync.RWMutex
+	factories = make(map[string]Factory)
+)
+
+// Register makes a torrent client available under name in TORRENT_CLIENTS.
+// It panics if name is already registered.
+func Register(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, ok := factories[name]; ok {
+		panic(fmt.Sprintf("torrent: client %q registered twice", name))
+	}
+	factories[name] = factory
+	config.RegisterTorrentClient(name)
+}
+
+// Registered returns the names of the registered torrent clients, sorted.
+func Registered() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewSources creates the sources of every torrent client listed in
+// cfg.TorrentClients. The files they return are tagged with the client or
+// instance name.
+func NewSources(cfg *config.Config) ([]TorrentSource, error) {
+	var sources []TorrentSource
+	for _, name := range cfg.TorrentClients {
+		mu.RLock()
+		factory, ok := factories[name]
+		mu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("torrent: unknown client %q", name)
+		}
+
+		created, err := factory(cfg)
+		if err != nil {
+			return nil, err
+		}
+		for _, src := range created {
+			if _, ok := src.(*instance); !ok {
+				src = &instance{TorrentSource: src, name: name, label: src.Name()}
+			}
+			sources = append(sources, src)
+		}
+	}
+	return sources, nil
+}
+
+// InstanceName returns the name the files of src are tagged with.
+func InstanceName(src TorrentSource) string {
+	if i, ok := src.(*instance); ok {
+		return i.name
+	}
+	return src.Name()
+}
+
+// StreamFiles fetches the files of torrents from src, concurrently when src
+// is a FileStreamer, and calls fn once per torrent.
+func StreamFiles(ctx context.Context, src TorrentSource, torrents []models.Torrent, fn func(hash string, files []models.TorrentFile, err error)) error {
+	if s, ok := src.(FileStreamer); ok {
+		return s.StreamTorrentFiles(ctx, torrents, fn)
+	}
+	for _, t := range torrents {
+		files, err := src.GetTorrentFiles(ctx, t.Hash)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		fn(t.Hash, files, err)
+	}
+	return nil
+}
+
+// Delete deletes a torrent from src, and its files on disk when deleteFiles
+// is set. It returns ErrUnsupported when src is not a Remover.
+func Delete(ctx context.Context, src TorrentSource, hash string, deleteFiles bool) error {
+	r, ok := unwrap(src).(Remover)
+	if !ok {
+		return ErrUnsupported
+	}
+	return r.DeleteTorrent(ctx, hash, deleteFiles)
+}
+
+// Pause pauses a torrent of src. It returns ErrUnsupported when src is not a Pauser.
+func Pause(ctx context.Context, src TorrentSource, hash string) error {
+	p, ok := unwrap(src).(Pauser)
+	if !ok {
+		return ErrUnsupported
+	}
+	return p.PauseTorrent(ctx, hash)
+}
+
+// Resume resumes a torrent of src. It returns ErrUnsupported when src is not a Pauser.
+func Resume(ctx context.Context, src TorrentSource, hash string) error {
+	p, ok := unwrap(src).(Pauser)
+	if !ok {
+		return ErrUnsupported
+	}
+	return p.ResumeTorrent(ctx, hash)
+}
+
+// Recheck forces a recheck of a torrent of src. It returns ErrUnsupported
+// when src is not a Rechecker.
+func Recheck(ctx context.Context, src TorrentSource, hash string) error {
+	r, ok := unwrap(src).(Rechecker)
+	if !ok {
+		return ErrUnsupported
+	}
+	return r.RecheckTorrent(ctx, hash)
+}
+
+// Move moves the data of a torrent of src to location. It returns
+// ErrUnsupported when src is not a Mover.
+func Move(ctx context.Context, src TorrentSource, hash, location string) error {
+	m, ok := unwrap(src).(Mover)
+	if !ok {
+		return ErrUnsupported
+	}
+	return m.MoveTorrent(ctx, hash, location)
+}
+
+// SetCategory sets the category of torrents of src, or removes it when
+// category is empty. It returns ErrUnsupported when src is not a Labeler.
+func SetCategory(ctx context.Context, src TorrentSource, hashes []string, category string) error {
+	l, ok := unwrap(src).(Labeler)
+	if !ok {
+		return ErrUnsupported
+	}
+	return l.SetCategory(ctx, hashes, category)
+}
+
+// AddTags adds tags to torrents of src. It returns ErrUnsupported when src
+// is not a Labeler.
+func AddTags(ctx context.Context, src TorrentSource, hashes []string, tags []string) error {
+	l, ok := unwrap(src).(Labeler)
+	if !ok {
+		return ErrUnsupported
+	}
+	return l.AddTags(ctx, hashes, tags)
+}
+
+// RemoveTags removes tags from torrents of src. It returns ErrUnsupported
+// when src is not a Labeler.
+func RemoveTags(ctx context.Context, src TorrentSource, hashes []string, tags []string) error {
+	l, ok := unwrap(src).(Labeler)
+	if !ok {
+		return ErrUnsupported
+	}
+	return l.RemoveTags(ctx, hashes, tags)
+}
+
+// Reannounce reannounces torrents of src to their trackers. It returns
+// ErrUnsupported when src is not a Reannouncer.
+func Reannounce(ctx context.Context, src TorrentSource, hashes []string) error {
+	r, ok := unwrap(src).(Reannouncer)
+	if !ok {
+		return ErrUnsupported
+	}
+	return r.ReannounceTorrents(ctx, hashes)
+}
+
+// MapPath applies the path mappings of src to a path as seen by its torrent
+// client, as done for the paths of its files.
+func MapPath(src TorrentSource, path string) string {
+	if i, ok := src.(*instance); ok {
+		return mapPath(path, i.mappings)
+	}
+	return path
+}
+
+// unwrap returns the source wrapped by an instance, to check the optional
+// interfaces it implements.
+func unwrap(src TorrentSource) TorrentSource {
+	if i, ok := src.(*instance); ok {
+		return i.TorrentSource
+	}
+	return src
+}
+
+// instance tags the files of a source with the name of the client or
+// instance they come from, and applies its path mappings.
+type instance struct {
+	TorrentSource
+	name     string
+	label    string
+	mappings []config.PathMapping
+}
+
+func (i *instance) Name() string {
+	return i.label
+}
+
+func (i *instance) GetTorrentFiles(ctx context.Context, hash string) ([]models.TorrentFile, error) {
+	files, err := i.TorrentSource.GetTorrentFiles(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	i.tag(files)
+	return files, nil
+}
+
+func (i *instance) StreamTorrentFiles(ctx context.Context, torrents []models.Torrent, fn func(hash string, files []models.TorrentFile, err error)) error {
+	return StreamFiles(ctx, i.TorrentSource, torrents, func(hash string, files []models.TorrentFile, err error) {
+		i.tag(files)
+		fn(hash, files, err)
+	})
+}
+
+func (i *instance) tag(files []models.TorrentFile) {
+	for j := range files {
+		files[j].Instance = i.name
+		files[j].FilePath = mapPath(files[j].FilePath, i.mappings)
+	}
+}
+
+// mapPath applies the first mapping whose From directory contains path.
+func mapPath(path string, mappings []config.PathMapping) string {
+	for _, m := range mappings {
+		from := strings.TrimSuffix(m.From, "/")
+		if path == from || strings.HasPrefix(path, from+"/") {
+			return strings.TrimSuffix(m.To, "/") + path[len(from):]
+		}
+	}
+	return path
+}
@@ -0,0 +1,302 @@
+// Package transmission provides a client for the Transmission RPC API.
+package transmission
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"godatacleaner/internal/models"
+)
+
+// sessionHeader carries the CSRF token Transmission requires on every call.
+// It is returned with a 409 status when missing or outdated.
+const sessionHeader = "X-Transmission-Session-Id"
+
+// Client is a Transmission RPC client.
+type Client struct {
+	url        string
+	username   string
+	password   string
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	sessionID string
+}
+
+// NewClient creates a new Transmission client for the RPC endpoint url
+// (e.g. http://localhost:9091/transmission/rpc).
+func NewClient(url, username, password string) (*Client, error) {
+	if url == "" {
+		return nil, fmt.Errorf("transmission: url cannot be empty")
+	}
+	return &Client{
+		url:      url,
+		username: username,
+		password: password,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}, nil
+}
+
+// Name returns the name of the torrent client.
+func (c *Client) Name() string {
+	return "Transmission"
+}
+
+// torrent is the subset of the torrent-get fields used by the client.
+type torrent struct {
+	HashString   string  `json:"hashString"`
+	Name         string  `json:"name"`
+	SizeWhenDone int64   `json:"sizeWhenDone"`
+	DownloadDir  string  `json:"downloadDir"`
+	Status       int     `json:"status"`
+	Error        int     `json:"error"`
+	ErrorString  string  `json:"errorString"`
+	UploadRatio  float64 `json:"uploadRatio"`
+	Seconds      int64   `json:"secondsSeeding"`
+	Trackers     []struct {
+		Announce string `json:"announce"`
+	} `json:"trackers"`
+	Files []struct {
+		Name   string `json:"name"`
+		Length int64  `json:"length"`
+	} `json:"files"`
+	FileStats []struct {
+		Wanted bool `json:"wanted"`
+	} `json:"fileStats"`
+}
+
+// Login checks that the RPC endpoint is reachable and the credentials are valid.
+// Transmission has no login call, a session-get request is used instead.
+func (c *Client) Login(ctx context.Context) error {
+	if err := c.call(ctx, "session-get", nil, nil); err != nil {
+		return fmt.Errorf("transmission: authentication failed: %w", err)
+	}
+	return nil
+}
+
+// GetTorrents retrieves the list of all torrents from Transmission.
+func (c *Client) GetTorrents(ctx context.Context) ([]models.Torrent, error) {
+	var res struct {
+		Torrents []torrent `json:"torrents"`
+	}
+	args := map[string]interface{}{
+		"fields": []string{"hashString", "name", "sizeWhenDone", "downloadDir", "status", "error", "errorString", "uploadRatio", "secondsSeeding", "trackers"},
+	}
+	if err := c.call(ctx, "torrent-get", args, &res); err != nil {
+		return nil, fmt.Errorf("transmission: failed to get torrents: %w", err)
+	}
+
+	torrents := make([]models.Torrent, 0, len(res.Torrents))
+	for _, t := range res.Torrents {
+		announces := make([]string, 0, len(t.Trackers))
+		for _, tr := range t.Trackers {
+			announces = append(announces, tr.Announce)
+		}
+		// Error 1 is a tracker warning, 2 a tracker error
+		var message string
+		if t.Error == 1 || t.Error == 2 {
+			message = t.ErrorString
+		}
+		torrents = append(torrents, models.Torrent{
+			Hash:           t.HashString,
+			Name:           t.Name,
+			Size:           t.SizeWhenDone, // Wanted files only, as in qBittorrent
+			SavePath:       t.DownloadDir,
+			State:          torrentState(t.Status, t.Error),
+			Ratio:          max(t.UploadRatio, 0), // -1 when not available
+			SeedingTime:    t.Seconds,
+			Trackers:       models.TrackerHosts(announces),
+			TrackerMessage: message,
+		})
+	}
+	return torrents, nil
+}
+
+// torrentState converts a Transmission torrent status. A tracker or local
+// error takes precedence over the status, a tracker warning (1) does not.
+func torrentState(status, errorCode int) string {
+	if errorCode >= 2 {
+		return models.TorrentStateErrored
+	}
+	switch status {
+	case 0:
+		return models.TorrentStatePaused
+	case 1, 2:
+		return models.TorrentStateChecking
+	case 3, 5:
+		return models.TorrentStateQueued
+	case 4:
+		return models.TorrentStateDownloading
+	case 6:
+		return models.TorrentStateSeeding
+	default:
+		return models.TorrentStateUnknown
+	}
+}
+
+// GetTorrentFiles retrieves the files of a specific torrent by its hash.
+func (c *Client) GetTorrentFiles(ctx context.Context, hash string) ([]models.TorrentFile, error) {
+	if hash == "" {
+		return nil, fmt.Errorf("transmission: torrent hash cannot be empty")
+	}
+
+	var res struct {
+		Torrents []torrent `json:"torrents"`
+	}
+	args := map[string]interface{}{
+		"ids":    []string{hash},
+		"fields": []string{"hashString", "name", "downloadDir", "files", "fileStats"},
+	}
+	if err := c.call(ctx, "torrent-get", args, &res); err != nil {
+		return nil, fmt.Errorf("transmission: failed to get files for torrent %s: %w", hash, err)
+	}
+	if len(res.Torrents) == 0 {
+		return []models.TorrentFile{}, nil
+	}
+
+	t := res.Torrents[0]
+	files := make([]models.TorrentFile, 0, len(t.Files))
+	for i, f := range t.Files {
+		// Unwanted files are not expected on disk
+		if i < len(t.FileStats) && !t.FileStats[i].Wanted {
+			continue
+		}
+
+		// File names are relative to the download directory and include
+		// the torrent folder for multi-file torrents, as in qBittorrent
+		files = append(files, models.TorrentFile{
+			TorrentHash: hash,
+			TorrentName: t.Name,
+			FileName:    filepath.Base(f.Name),
+			FilePath:    filepath.Join(t.DownloadDir, f.Name),
+			Size:        f.Length,
+		})
+	}
+	return files, nil
+}
+
+// DeleteTorrent deletes a torrent, and its files on disk when deleteFiles is set.
+func (c *Client) DeleteTorrent(ctx context.Context, hash string, deleteFiles bool) error {
+	args := map[string]interface{}{
+		"ids":               []string{hash},
+		"delete-local-data": deleteFiles,
+	}
+	if err := c.call(ctx, "torrent-remove", args, nil); err != nil {
+		return fmt.Errorf("transmission: failed to delete torrent %s: %w", hash, err)
+	}
+	return nil
+}
+
+// PauseTorrent stops a torrent.
+func (c *Client) PauseTorrent(ctx context.Context, hash string) error {
+	if err := c.call(ctx, "torrent-stop", map[string]interface{}{"ids": []string{hash}}, nil); err != nil {
+		return fmt.Errorf("transmission: failed to pause torrent %s: %w", hash, err)
+	}
+	return nil
+}
+
+// ResumeTorrent starts a torrent.
+func (c *Client) ResumeTorrent(ctx context.Context, hash string) error {
+	if err := c.call(ctx, "torrent-start", map[string]interface{}{"ids": []string{hash}}, nil); err != nil {
+		return fmt.Errorf("transmission: failed to resume torrent %s: %w", hash, err)
+	}
+	return nil
+}
+
+// RecheckTorrent verifies the local data of a torrent.
+func (c *Client) RecheckTorrent(ctx context.Context, hash string) error {
+	if err := c.call(ctx, "torrent-verify", map[string]interface{}{"ids": []string{hash}}, nil); err != nil {
+		return fmt.Errorf("transmission: failed to recheck torrent %s: %w", hash, err)
+	}
+	return nil
+}
+
+// MoveTorrent moves the data of a torrent to location.
+func (c *Client) MoveTorrent(ctx context.Context, hash, location string) error {
+	args := map[string]interface{}{
+		"ids":      []string{hash},
+		"location": location,
+		"move":     true,
+	}
+	if err := c.call(ctx, "torrent-set-location", args, nil); err != nil {
+		return fmt.Errorf("transmission: failed to move torrent %s: %w", hash, err)
+	}
+	return nil
+}
+
+// ReannounceTorrents asks the trackers of torrents for more peers.
+func (c *Client) ReannounceTorrents(ctx context.Context, hashes []string) error {
+	if err := c.call(ctx, "torrent-reannounce", map[string]interface{}{"ids": hashes}, nil); err != nil {
+		return fmt.Errorf("transmission: failed to reannounce torrents: %w", err)
+	}
+	return nil
+}
+
+// call performs an RPC call and decodes its arguments into result.
+// The request is sent again once when Transmission asks for a new session id.
+func (c *Client) call(ctx context.Context, method string, args interface{}, result interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{"method": method, "arguments": args})
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if c.username != "" {
+			req.SetBasicAuth(c.username, c.password)
+		}
+		c.mu.Lock()
+		req.Header.Set(sessionHeader, c.sessionID)
+		c.mu.Unlock()
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode == http.StatusConflict && attempt == 0 {
+			resp.Body.Close()
+			c.mu.Lock()
+			c.sessionID = resp.Header.Get(sessionHeader)
+			c.mu.Unlock()
+			continue
+		}
+
+		err = decodeResponse(resp, result)
+		resp.Body.Close()
+		return err
+	}
+}
+
+func decodeResponse(resp *http.Response, result interface{}) error {
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var res struct {
+		Result    string          `json:"result"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	if res.Result != "success" {
+		return fmt.Errorf("rpc error: %s", res.Result)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(res.Arguments, result)
+}
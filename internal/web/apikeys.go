@@ -0,0 +1,116 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"godatacleaner/internal/auth"
+	"godatacleaner/internal/models"
+	"godatacleaner/internal/storage"
+)
+
+// requireAccountAdmin rejects the requests not made by an admin account.
+// API keys cannot manage keys, even with the write scope, so a leaked key
+// cannot be used to create new ones.
+func requireAccountAdmin(w http.ResponseWriter, r *http.Request) bool {
+	u := currentUser(r)
+	if u.Role != models.RoleAdmin || u.APIKeyID != 0 {
+		writeError(w, 403, "Admin account required")
+		return false
+	}
+	return true
+}
+
+func (s *Server) handleAPIKeys(w http.ResponseWriter, r *http.Request) {
+	if !requireAccountAdmin(w, r) {
+		return
+	}
+	keys, err := s.storage.ListAPIKeys(r.Context())
+	if err != nil {
+		writeError(w, 500, "Failed to get API keys")
+		return
+	}
+	if keys == nil {
+		keys = []models.APIKey{}
+	}
+	writeJSON(w, 200, models.APIKeysResponse{Keys: keys})
+}
+
+func (s *Server) handleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	if !requireAccountAdmin(w, r) {
+		return
+	}
+
+	var req struct {
+		Name  string `json:"name"`
+		Scope string `json:"scope"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, 400, "Invalid JSON body")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		writeError(w, 400, "Name is required")
+		return
+	}
+	if req.Scope == "" {
+		req.Scope = models.ScopeRead
+	}
+	if !models.ValidScope(req.Scope) {
+		writeError(w, 400, "Invalid scope: must be read or write")
+		return
+	}
+
+	// Without another way to authenticate, the key would lock everyone else
+	// out of the WebUI
+	enabled, err := s.authEnabled(r.Context())
+	if err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	if !enabled {
+		writeError(w, 409, "Authentication is disabled: create a user or set AUTH_USERNAME or AUTH_TOKEN before creating API keys")
+		return
+	}
+
+	key, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		writeError(w, 500, "Failed to generate API key")
+		return
+	}
+	id, err := s.storage.CreateAPIKey(r.Context(), req.Name, prefix, hash, req.Scope)
+	if err != nil {
+		writeError(w, 500, "Failed to create API key")
+		return
+	}
+	created, err := s.storage.GetAPIKey(r.Context(), id)
+	if err != nil {
+		writeError(w, 500, "Failed to create API key")
+		return
+	}
+	writeJSON(w, 201, models.APIKeyCreatedResponse{APIKey: *created, Key: key})
+}
+
+func (s *Server) handleRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	if !requireAccountAdmin(w, r) {
+		return
+	}
+	id, ok := pathID(r)
+	if !ok {
+		writeError(w, 400, "Invalid API key id")
+		return
+	}
+	err := s.storage.RevokeAPIKey(r.Context(), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		writeError(w, 404, "API key not found")
+		return
+	}
+	if err != nil {
+		writeError(w, 500, "Failed to revoke API key")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
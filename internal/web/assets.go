@@ -0,0 +1,56 @@
+package web
+
+import (
+	"bufio"
+	"embed"
+	"net/http"
+	"strings"
+)
+
+// assets holds the frontend libraries of the WebUI, so that it works without
+// internet access. They are committed in assets/, with their SHA-256 sums
+// pinned in assets/SHA256SUMS (see make assets-pin). Each library is named
+// here, so that the build fails when one is missing: a version change in
+// assets/sources.txt must be made here too.
+//
+//go:embed assets/sources.txt assets/SHA256SUMS
+//go:embed assets/react-18.3.1.production.min.js assets/react-dom-18.3.1.production.min.js
+//go:embed assets/babel-standalone-7.26.4.min.js assets/chart-4.4.7.umd.js
+var assets embed.FS
+
+// assetSources maps the file names of assets/sources.txt to their URL.
+var assetSources = loadAssetSources()
+
+func loadAssetSources() map[string]string {
+	sources := make(map[string]string)
+	f, err := assets.Open("assets/sources.txt")
+	if err != nil {
+		return sources
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if name, url, ok := strings.Cut(line, " "); ok {
+			sources[name] = strings.TrimSpace(url)
+		}
+	}
+	return sources
+}
+
+// handleAsset serves a frontend library. The file names carry the version of
+// the library, so browsers may keep them. The WebUI never loads code from a
+// CDN.
+func (s *Server) handleAsset(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if _, ok := assetSources[name]; !ok {
+		writeError(w, 404, "Asset not found")
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	http.ServeFileFS(w, r, assets, "assets/"+name)
+}
@@ -0,0 +1,43 @@
+package web
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// TestAssetsPinned checks that every library of assets/sources.txt is
+// embedded with the SHA-256 sum pinned in assets/SHA256SUMS.
+func TestAssetsPinned(t *testing.T) {
+	sums, err := assets.ReadFile("assets/SHA256SUMS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinned := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(string(sums)))
+	for scanner.Scan() {
+		if sum, name, ok := strings.Cut(scanner.Text(), "  "); ok {
+			pinned[name] = sum
+		}
+	}
+
+	if len(assetSources) == 0 {
+		t.Fatal("no library in assets/sources.txt")
+	}
+	for name := range assetSources {
+		data, err := assets.ReadFile("assets/" + name)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		sum := sha256.Sum256(data)
+		switch want, ok := pinned[name]; {
+		case !ok:
+			t.Errorf("%s: no SHA-256 pinned in assets/SHA256SUMS", name)
+		case hex.EncodeToString(sum[:]) != want:
+			t.Errorf("%s: SHA-256 %x, pinned %s", name, sum, want)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package web
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"godatacleaner/internal/auth"
+	"godatacleaner/internal/models"
+	"godatacleaner/internal/storage"
+)
+
+// maxVerifiedPasswords bounds the cache of verified user passwords.
+const maxVerifiedPasswords = 1024
+
+// WithAuth protects every route with HTTP basic auth using username and password,
+// and/or with a bearer token. Empty values disable the corresponding method.
+// Both grant the admin role. Users and API keys stored in the database are
+// always accepted, and authentication is enabled as soon as a user exists.
+func (s *Server) WithAuth(username, password, token string) *Server {
+	s.authUsername = username
+	s.authPassword = password
+	s.authToken = token
+	return s
+}
+
+// userKey is the context key of the authenticated user.
+type userKey struct{}
+
+// currentUser returns the user authenticated for r.
+func currentUser(r *http.Request) *models.User {
+	u, _ := r.Context().Value(userKey{}).(*models.User)
+	return u
+}
+
+// passwordCache remembers the password hashes already verified, as checking a
+// hash is deliberately slow and the browser sends the credentials on every request.
+type passwordCache struct {
+	mu       sync.Mutex
+	verified map[[32]byte]struct{}
+}
+
+func newPasswordCache() *passwordCache {
+	return &passwordCache{verified: make(map[[32]byte]struct{})}
+}
+
+// check reports whether password matches hash. The key includes the hash, so a
+// password change invalidates its cached verification.
+func (c *passwordCache) check(hash, password string) bool {
+	key := sha256.Sum256([]byte(hash + "\x00" + password))
+
+	c.mu.Lock()
+	_, ok := c.verified[key]
+	c.mu.Unlock()
+	if ok {
+		return true
+	}
+
+	if !auth.CheckPassword(hash, password) {
+		return false
+	}
+	c.mu.Lock()
+	if len(c.verified) >= maxVerifiedPasswords {
+		clear(c.verified)
+	}
+	c.verified[key] = struct{}{}
+	c.mu.Unlock()
+	return true
+}
+
+// authEnabled reports whether requests must be authenticated: with
+// AUTH_USERNAME or AUTH_TOKEN, a user or an API key. An API key enables it
+// on its own, otherwise its scope would restrict nothing.
+func (s *Server) authEnabled(ctx context.Context) (bool, error) {
+	if s.authUsername != "" || s.authToken != "" {
+		return true, nil
+	}
+	n, err := s.storage.CountUsers(ctx)
+	if err != nil || n > 0 {
+		return n > 0, err
+	}
+	n, err = s.storage.CountActiveAPIKeys(ctx)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// requireAuth rejects the API requests that carry no valid session, credentials
+// or bearer token, the write requests of users without the admin role,
+// except those changing their own preferences, and the write requests that a
+// page of another site made with the credentials of the browser. When
+// authentication is disabled every request is handled as an admin.
+func (s *Server) requireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if publicPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		enabled, err := s.authEnabled(r.Context())
+		if err != nil {
+			writeError(w, 500, err.Error())
+			return
+		}
+
+		user := &models.User{Role: models.RoleAdmin}
+		if enabled {
+			if !readOnly(r) && ambientCredentials(r) && !sameOrigin(r) {
+				writeError(w, 403, "Cross-site request rejected")
+				return
+			}
+			user, err = s.authenticate(r)
+			if err != nil {
+				writeError(w, 500, err.Error())
+				return
+			}
+			if user == nil {
+				// No basic auth challenge: the browser would show its own login
+				// dialog instead of the login form of the WebUI
+				writeError(w, 401, "Unauthorized")
+				return
+			}
+		}
+
+		if !readOnly(r) && !personal(r) && user.Role != models.RoleAdmin {
+			writeError(w, 403, "Admin role required")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, user)))
+	})
+}
+
+// authenticate returns the user matching the request credentials, or nil when
+// they are missing or invalid.
+func (s *Server) authenticate(r *http.Request) (*models.User, error) {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		if s.authToken != "" && secureEqual(token, s.authToken) {
+			return &models.User{Role: models.RoleAdmin}, nil
+		}
+		return s.authenticateKey(r.Context(), token)
+	}
+
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		return s.authenticateSession(r.Context(), cookie.Value)
+	}
+
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, nil
+	}
+	user, err := s.checkCredentials(r.Context(), username, password)
+	if user != nil || err != nil {
+		return user, err
+	}
+
+	// The token is also accepted as the basic auth password, with any username,
+	// for the clients that cannot send a bearer token
+	if s.authToken != "" && secureEqual(password, s.authToken) {
+		return &models.User{Role: models.RoleAdmin}, nil
+	}
+	return nil, nil
+}
+
+// checkCredentials returns the user matching username and password, either
+// the one of AUTH_USERNAME or a user stored in the database, or nil.
+func (s *Server) checkCredentials(ctx context.Context, username, password string) (*models.User, error) {
+	if s.authUsername != "" && secureEqual(username, s.authUsername) && secureEqual(password, s.authPassword) {
+		return &models.User{Username: username, Role: models.RoleAdmin}, nil
+	}
+
+	user, err := s.storage.GetUserByUsername(ctx, username)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+	if user != nil && s.passwords.check(user.PasswordHash, password) {
+		return user, nil
+	}
+	return nil, nil
+}
+
+// authenticateKey returns the user acting with an API key: a read key has the
+// viewer role and a write key the admin role.
+func (s *Server) authenticateKey(ctx context.Context, key string) (*models.User, error) {
+	k, err := s.storage.GetActiveAPIKeyByHash(ctx, auth.HashToken(key))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if k.LastUsedAt == nil || time.Since(*k.LastUsedAt) > time.Minute {
+		ctx, cancel := context.WithTimeout(ctx, activityTimeout)
+		defer cancel()
+		if err := s.storage.TouchAPIKey(ctx, k.ID); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+	}
+
+	role := models.RoleViewer
+	if k.Scope == models.ScopeWrite {
+		role = models.RoleAdmin
+	}
+	return &models.User{Username: k.Name, Role: role, APIKeyID: k.ID}, nil
+}
+
+// publicPath reports whether path is reachable without authentication: the
+// WebUI page itself, which holds no data, the login and logout endpoints and
+// the translations of the login form.
+func publicPath(path string) bool {
+	switch path {
+	case "/api/v1/login", "/api/v1/logout", "/api/login", "/api/logout", "/api/v1/i18n", "/api/i18n":
+		return true
+	}
+	return !strings.HasPrefix(path, "/api/")
+}
+
+// readOnly reports whether r only reads data and is thus allowed to viewers.
+func readOnly(r *http.Request) bool {
+	return r.Method == http.MethodGet || r.Method == http.MethodHead
+}
+
+// personal reports whether r only changes the preferences of the current
+// user, such as its column layouts, and is thus allowed to viewers.
+func personal(r *http.Request) bool {
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1")
+	if path == r.URL.Path {
+		path = strings.TrimPrefix(path, "/api")
+	}
+	return strings.HasPrefix(path, "/me/")
+}
+
+// secureEqual compares two secrets in constant time.
+func secureEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+func (s *Server) handleCurrentUser(w http.ResponseWriter, r *http.Request) {
+	enabled, err := s.authEnabled(r.Context())
+	if err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+
+	user := currentUser(r)
+	writeJSON(w, 200, models.CurrentUserResponse{
+		Username:    user.Username,
+		Role:        user.Role,
+		AuthEnabled: enabled,
+	})
+}
@@ -0,0 +1,193 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"godatacleaner/internal/auth"
+	"godatacleaner/internal/models"
+	"godatacleaner/internal/storage"
+)
+
+// authRequest describes a request and the status the server must answer.
+type authRequest struct {
+	name   string
+	method string
+	path   string
+	header map[string]string
+	cookie string // Session token
+	body   string // Protected path added by default
+	want   int
+}
+
+func serve(t *testing.T, s *Server, req authRequest) {
+	t.Helper()
+	body := req.body
+	if body == "" && req.method != http.MethodGet {
+		body = `{"pattern": "movies/Keep"}`
+	}
+	r := httptest.NewRequest(req.method, req.path, strings.NewReader(body))
+	for k, v := range req.header {
+		r.Header.Set(k, v)
+	}
+	if req.cookie != "" {
+		r.AddCookie(&http.Cookie{Name: sessionCookie, Value: req.cookie})
+	}
+	w := httptest.NewRecorder()
+	s.handler().ServeHTTP(w, r)
+	if w.Code != req.want {
+		t.Errorf("%s: %s %s = %d, want %d (%s)", req.name, req.method, req.path, w.Code, req.want, w.Body.String())
+	}
+}
+
+func TestAuthDisabled(t *testing.T) {
+	s := NewServer(storage.NewMemory(), "localhost", 0)
+	for _, req := range []authRequest{
+		{name: "read", method: http.MethodGet, path: "/api/v1/protected", want: 200},
+		{name: "write", method: http.MethodPost, path: "/api/v1/protected", want: 201},
+	} {
+		serve(t, s, req)
+	}
+}
+
+func TestStaticCredentials(t *testing.T) {
+	s := NewServer(storage.NewMemory(), "localhost", 0).WithAuth("admin", "secret", "static-token")
+
+	basic := func(username, password string) map[string]string {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.SetBasicAuth(username, password)
+		return map[string]string{"Authorization": r.Header.Get("Authorization")}
+	}
+	for _, req := range []authRequest{
+		{name: "anonymous", method: http.MethodGet, path: "/api/v1/protected", want: 401},
+		{name: "WebUI page", method: http.MethodGet, path: "/", want: 200},
+		{name: "basic auth", method: http.MethodGet, path: "/api/v1/protected", header: basic("admin", "secret"), want: 200},
+		{name: "basic auth, write", method: http.MethodPost, path: "/api/v1/protected", header: basic("admin", "secret"), want: 201},
+		{name: "wrong password", method: http.MethodGet, path: "/api/v1/protected", header: basic("admin", "other"), want: 401},
+		{name: "token", method: http.MethodGet, path: "/api/v1/protected", header: map[string]string{"Authorization": "Bearer static-token"}, want: 200},
+		{name: "token, write", method: http.MethodPost, path: "/api/v1/protected", header: map[string]string{"Authorization": "Bearer static-token"}, want: 201},
+		{name: "wrong token", method: http.MethodGet, path: "/api/v1/protected", header: map[string]string{"Authorization": "Bearer other"}, want: 401},
+		// For the clients that cannot send a bearer token
+		{name: "token as password", method: http.MethodGet, path: "/api/v1/protected", header: basic("any", "static-token"), want: 200},
+		{name: "legacy path", method: http.MethodGet, path: "/api/protected", header: basic("admin", "secret"), want: 200},
+	} {
+		serve(t, s, req)
+	}
+}
+
+func TestUserRoles(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemory()
+	for username, role := range map[string]string{"admin": models.RoleAdmin, "viewer": models.RoleViewer} {
+		hash, err := auth.HashPassword(username + "-password")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.CreateUser(ctx, username, hash, role); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := NewServer(store, "localhost", 0)
+
+	basic := func(username string) map[string]string {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.SetBasicAuth(username, username+"-password")
+		return map[string]string{"Authorization": r.Header.Get("Authorization")}
+	}
+	for _, req := range []authRequest{
+		// A user enables authentication on its own
+		{name: "anonymous", method: http.MethodGet, path: "/api/v1/protected", want: 401},
+		{name: "admin", method: http.MethodGet, path: "/api/v1/protected", header: basic("admin"), want: 200},
+		{name: "admin, write", method: http.MethodPost, path: "/api/v1/protected", header: basic("admin"), want: 201},
+		{name: "viewer", method: http.MethodGet, path: "/api/v1/protected", header: basic("viewer"), want: 200},
+		{name: "viewer, write", method: http.MethodPost, path: "/api/v1/protected", header: basic("viewer"), want: 403},
+		{name: "viewer, own columns", method: http.MethodPost, path: "/api/v1/me/columns/orphans", header: basic("viewer"), body: `{"columns": ["file_path"]}`, want: 200},
+		{name: "unknown user", method: http.MethodGet, path: "/api/v1/protected", header: basic("other"), want: 401},
+	} {
+		serve(t, s, req)
+	}
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemory()
+	if _, err := store.CreateAPIKey(ctx, "reader", "gdc_read", auth.HashToken("read-key"), models.ScopeRead); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreateAPIKey(ctx, "writer", "gdc_writ", auth.HashToken("write-key"), models.ScopeWrite); err != nil {
+		t.Fatal(err)
+	}
+	revoked, err := store.CreateAPIKey(ctx, "old", "gdc_old_", auth.HashToken("revoked-key"), models.ScopeWrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RevokeAPIKey(ctx, revoked); err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(store, "localhost", 0)
+
+	bearer := func(key string) map[string]string { return map[string]string{"Authorization": "Bearer " + key} }
+	for _, req := range []authRequest{
+		// A key enables authentication on its own
+		{name: "anonymous", method: http.MethodGet, path: "/api/v1/protected", want: 401},
+		{name: "unknown key", method: http.MethodGet, path: "/api/v1/protected", header: bearer("other-key"), want: 401},
+		{name: "revoked key", method: http.MethodGet, path: "/api/v1/protected", header: bearer("revoked-key"), want: 401},
+		{name: "read key, read", method: http.MethodGet, path: "/api/v1/protected", header: bearer("read-key"), want: 200},
+		{name: "read key, legacy read", method: http.MethodGet, path: "/api/protected", header: bearer("read-key"), want: 200},
+		{name: "read key, write", method: http.MethodPost, path: "/api/v1/protected", header: bearer("read-key"), want: 403},
+		{name: "read key, legacy write", method: http.MethodPost, path: "/api/protected", header: bearer("read-key"), want: 403},
+		{name: "write key, read", method: http.MethodGet, path: "/api/v1/protected", header: bearer("write-key"), want: 200},
+		{name: "write key, write", method: http.MethodPost, path: "/api/v1/protected", header: bearer("write-key"), want: 201},
+		// Public paths
+		{name: "translations", method: http.MethodGet, path: "/api/v1/i18n", want: 200},
+	} {
+		serve(t, s, req)
+	}
+}
+
+func TestSessions(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemory()
+	if _, err := store.CreateUser(ctx, "admin", "unused", models.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreateUser(ctx, "viewer", "unused", models.RoleViewer); err != nil {
+		t.Fatal(err)
+	}
+	for token, sess := range map[string]struct {
+		username string
+		ttl      time.Duration
+	}{
+		"admin-token":   {"admin", time.Hour},
+		"viewer-token":  {"viewer", time.Hour},
+		"expired-token": {"admin", -time.Minute},
+		"deleted-token": {"deleted", time.Hour}, // User removed since
+	} {
+		if err := store.CreateSession(ctx, auth.HashToken(token), sess.username, false, sess.ttl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := NewServer(store, "localhost", 0)
+
+	crossSite := map[string]string{"Sec-Fetch-Site": "cross-site"}
+	for _, req := range []authRequest{
+		{name: "admin", method: http.MethodGet, path: "/api/v1/protected", cookie: "admin-token", want: 200},
+		{name: "admin, write", method: http.MethodPost, path: "/api/v1/protected", cookie: "admin-token", want: 201},
+		{name: "viewer", method: http.MethodGet, path: "/api/v1/protected", cookie: "viewer-token", want: 200},
+		{name: "viewer, write", method: http.MethodPost, path: "/api/v1/protected", cookie: "viewer-token", want: 403},
+		{name: "expired", method: http.MethodGet, path: "/api/v1/protected", cookie: "expired-token", want: 401},
+		{name: "deleted user", method: http.MethodGet, path: "/api/v1/protected", cookie: "deleted-token", want: 401},
+		{name: "unknown", method: http.MethodGet, path: "/api/v1/protected", cookie: "other-token", want: 401},
+		// The browser sends the cookie of the session with the requests of other sites
+		{name: "cross-site read", method: http.MethodGet, path: "/api/v1/protected", cookie: "admin-token", header: crossSite, want: 200},
+		{name: "cross-site write", method: http.MethodPost, path: "/api/v1/protected", cookie: "admin-token", header: crossSite, want: 403},
+		{name: "foreign origin", method: http.MethodPost, path: "/api/v1/protected", cookie: "admin-token", header: map[string]string{"Origin": "https://evil.example"}, want: 403},
+		{name: "same origin", method: http.MethodPost, path: "/api/v1/protected", cookie: "admin-token", header: map[string]string{"Origin": "http://example.com"}, want: 201},
+	} {
+		serve(t, s, req)
+	}
+}
@@ -0,0 +1,59 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"godatacleaner/internal/models"
+	"godatacleaner/internal/storage"
+)
+
+// handleBackup downloads a snapshot of the database, taken with the SQLite
+// online backup API. It holds the users, sessions and API key hashes, so
+// only admins may download it, even though it is a GET. A store without
+// database, such as storage.Memory, answers 501.
+func (s *Server) handleBackup(w http.ResponseWriter, r *http.Request) {
+	if currentUser(r).Role != models.RoleAdmin {
+		writeError(w, 403, "Admin role required")
+		return
+	}
+
+	dir, err := os.MkdirTemp("", "godatacleaner-backup-")
+	if err != nil {
+		writeError(w, 500, "Failed to back up database")
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "backup.db")
+	if err := s.storage.Backup(r.Context(), path); err != nil {
+		if errors.Is(err, storage.ErrNotSupported) {
+			writeError(w, 501, "Backups are not supported by this storage")
+			return
+		}
+		writeError(w, 500, "Failed to back up database")
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		writeError(w, 500, "Failed to back up database")
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		writeError(w, 500, "Failed to back up database")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=godatacleaner-%s.db", time.Now().Format("20060102-150405")))
+	w.Header().Set("Content-Length", fmt.Sprint(info.Size()))
+	w.WriteHeader(200)
+	io.Copy(w, f)
+}
@@ -0,0 +1,66 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	"godatacleaner/internal/models"
+)
+
+func (s *Server) handleColumnLayouts(w http.ResponseWriter, r *http.Request) {
+	layouts, err := s.storage.GetColumnLayouts(r.Context(), currentUser(r).Username)
+	if err != nil {
+		writeError(w, 500, "Failed to get column layouts")
+		return
+	}
+	writeJSON(w, 200, models.ColumnLayoutsResponse{Layouts: layouts})
+}
+
+func (s *Server) handleSetColumnLayout(w http.ResponseWriter, r *http.Request) {
+	list := r.PathValue("list")
+	allowed, ok := models.LayoutColumns[list]
+	if !ok {
+		writeError(w, 400, "Invalid list")
+		return
+	}
+
+	var layout models.ColumnLayout
+	if err := json.NewDecoder(r.Body).Decode(&layout); err != nil {
+		writeError(w, 400, "Invalid JSON body")
+		return
+	}
+	if len(layout.Columns) == 0 {
+		writeError(w, 400, "columns is required")
+		return
+	}
+	for i, col := range layout.Columns {
+		if !slices.Contains(allowed, col) {
+			writeError(w, 400, "Invalid column: "+col)
+			return
+		}
+		if slices.Contains(layout.Columns[:i], col) {
+			writeError(w, 400, "Duplicate column: "+col)
+			return
+		}
+	}
+
+	if err := s.storage.SetColumnLayout(r.Context(), currentUser(r).Username, list, layout.Columns); err != nil {
+		writeError(w, 500, "Failed to save column layout")
+		return
+	}
+	writeJSON(w, 200, layout)
+}
+
+func (s *Server) handleResetColumnLayout(w http.ResponseWriter, r *http.Request) {
+	list := r.PathValue("list")
+	if _, ok := models.LayoutColumns[list]; !ok {
+		writeError(w, 400, "Invalid list")
+		return
+	}
+	if err := s.storage.DeleteColumnLayout(r.Context(), currentUser(r).Username, list); err != nil {
+		writeError(w, 500, "Failed to reset column layout")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,68 @@
+package web
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"godatacleaner/internal/cleaner"
+)
+
+// confirmationTTL is how long a confirmation token returned by a preview stays valid.
+const confirmationTTL = 5 * time.Minute
+
+// pendingDeletion is a previewed deletion waiting for its confirmation.
+// The files are frozen at preview time so the execution never goes beyond the summary.
+// A deletion of a torrent with its data is identified by its instance and hash.
+type pendingDeletion struct {
+	paths    []string
+	opts     cleaner.Options
+	instance string
+	hash     string
+	expires  time.Time
+}
+
+// confirmations holds the pending deletions by token.
+type confirmations struct {
+	mu      sync.Mutex
+	pending map[string]pendingDeletion
+}
+
+func newConfirmations() *confirmations {
+	return &confirmations{pending: make(map[string]pendingDeletion)}
+}
+
+// add stores a pending deletion and returns its single-use token.
+func (c *confirmations) add(p pendingDeletion) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for t, pending := range c.pending {
+		if now.After(pending.expires) {
+			delete(c.pending, t)
+		}
+	}
+	c.pending[token] = p
+	return token, nil
+}
+
+// take removes and returns the pending deletion of a token, if still valid.
+func (c *confirmations) take(token string) (pendingDeletion, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, ok := c.pending[token]
+	delete(c.pending, token)
+	if !ok || time.Now().After(p.expires) {
+		return pendingDeletion{}, false
+	}
+	return p, true
+}
@@ -0,0 +1,63 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+)
+
+// corsMaxAge is how long, in seconds, browsers may cache a preflight response.
+const corsMaxAge = "600"
+
+// WithCORS allows browsers on the given origins (e.g. "https://homepage.home")
+// to call the API. "*" allows any origin. CORS is disabled when origins is empty.
+func (s *Server) WithCORS(origins []string) *Server {
+	s.corsOrigins = origins
+	return s
+}
+
+// corsOrigin returns the value of Access-Control-Allow-Origin for origin,
+// or "" when the origin is not allowed.
+func (s *Server) corsOrigin(origin string) string {
+	for _, o := range s.corsOrigins {
+		if o == "*" {
+			return "*"
+		}
+		if strings.EqualFold(strings.TrimSuffix(o, "/"), origin) {
+			return origin
+		}
+	}
+	return ""
+}
+
+// cors adds the CORS headers to the API responses for allowed origins and
+// answers preflight requests before authentication, as browsers send them
+// without credentials.
+func (s *Server) cors(next http.Handler) http.Handler {
+	if len(s.corsOrigins) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		allowed := s.corsOrigin(origin)
+		if allowed == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Origin", allowed)
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+			w.Header().Set("Access-Control-Max-Age", corsMaxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,52 @@
+package web
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ambientCredentials reports whether r is authenticated by credentials the
+// browser sends by itself, the session cookie or cached basic auth, which a
+// page of another site could thus use.
+func ambientCredentials(r *http.Request) bool {
+	if strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		return false
+	}
+	if _, err := r.Cookie(sessionCookie); err == nil {
+		return true
+	}
+	_, _, ok := r.BasicAuth()
+	return ok
+}
+
+// sameOrigin reports whether r comes from the WebUI itself rather than from a
+// page of another site. Browsers send Sec-Fetch-Site, or at least Origin, with
+// every write request; clients that send neither, such as curl, are not
+// browsers and cannot be driven by another site.
+func sameOrigin(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "same-origin", "none":
+		return true
+	case "":
+	default:
+		return false
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	// Behind a reverse proxy, Host may be the address of the server rather than
+	// the one of the browser
+	for _, host := range []string{r.Host, r.Header.Get("X-Forwarded-Host")} {
+		if host != "" && strings.EqualFold(u.Host, host) {
+			return true
+		}
+	}
+	return false
+}
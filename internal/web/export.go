@@ -0,0 +1,371 @@
+package web
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"godatacleaner/internal/models"
+	"godatacleaner/internal/storage"
+)
+
+// exportPageSize is the number of rows the export endpoints read at a time.
+const exportPageSize = 1000
+
+// exportColumn is a column of an export, named as the JSON field of the rows.
+type exportColumn[T any] struct {
+	name  string
+	value func(T) any // string, int64 or time.Time
+}
+
+var torrentFileExportColumns = []exportColumn[models.TorrentFile]{
+	{"file_path", func(f models.TorrentFile) any { return f.FilePath }},
+	{"file_name", func(f models.TorrentFile) any { return f.FileName }},
+	{"torrent_name", func(f models.TorrentFile) any { return f.TorrentName }},
+	{"torrent_hash", func(f models.TorrentFile) any { return f.TorrentHash }},
+	{"instance", func(f models.TorrentFile) any { return f.Instance }},
+	{"size", func(f models.TorrentFile) any { return f.Size }},
+}
+
+var localFileExportColumns = []exportColumn[models.LocalFile]{
+	{"file_path", func(f models.LocalFile) any { return f.FilePath }},
+	{"file_name", func(f models.LocalFile) any { return f.FileName }},
+	{"relative_path", func(f models.LocalFile) any { return f.RelativePath }},
+	{"category", func(f models.LocalFile) any { return f.Category }},
+	{"size", func(f models.LocalFile) any { return f.Size }},
+	{"allocated", func(f models.LocalFile) any { return f.Allocated }},
+	{"links", func(f models.LocalFile) any { return f.Links }},
+	{"mod_time", func(f models.LocalFile) any { return f.ModTime }},
+}
+
+var orphanFileExportColumns = []exportColumn[models.OrphanFile]{
+	{"file_path", func(f models.OrphanFile) any { return f.FilePath }},
+	{"file_name", func(f models.OrphanFile) any { return f.FileName }},
+	{"relative_path", func(f models.OrphanFile) any { return f.RelativePath }},
+	{"category", func(f models.OrphanFile) any { return f.Category }},
+	{"size", func(f models.OrphanFile) any { return f.Size }},
+	{"allocated", func(f models.OrphanFile) any { return f.Allocated }},
+	{"links", func(f models.OrphanFile) any { return f.Links }},
+	{"mod_time", func(f models.OrphanFile) any { return f.ModTime }},
+	{"orphaned_at", func(f models.OrphanFile) any { return f.OrphanedAt }},
+}
+
+func (s *Server) handleTorrentFileExport(w http.ResponseWriter, r *http.Request) {
+	writeExport(w, r, "torrent-files", "csv", torrentFileExportColumns, func(opts models.QueryOptions) ([]models.TorrentFile, error) {
+		files, _, err := s.storage.GetTorrentFiles(r.Context(), opts)
+		return files, err
+	}, storage.TorrentFileCursor)
+}
+
+func (s *Server) handleLocalFileExport(w http.ResponseWriter, r *http.Request) {
+	writeExport(w, r, "local-files", "csv", localFileExportColumns, func(opts models.QueryOptions) ([]models.LocalFile, error) {
+		files, _, err := s.storage.GetLocalFiles(r.Context(), opts)
+		return files, err
+	}, storage.LocalFileCursor)
+}
+
+// handleOrphanExport keeps the bare list of paths as its default format, the
+// one of the first versions of the endpoint.
+func (s *Server) handleOrphanExport(w http.ResponseWriter, r *http.Request) {
+	writeExport(w, r, "orphans", "txt", orphanFileExportColumns, func(opts models.QueryOptions) ([]models.OrphanFile, error) {
+		files, _, err := s.storage.GetOrphanFiles(r.Context(), opts)
+		return files, err
+	}, storage.OrphanFileCursor)
+}
+
+// writeExport streams the rows matching the filters of the request in the
+// format and with the columns it asks for, reading them page after page from
+// the cursor of the last one. The txt format writes one row per line with
+// its columns separated by tabs, only the file path unless columns is given.
+func writeExport[T any](w http.ResponseWriter, r *http.Request, name, defaultFormat string, all []exportColumn[T], query func(models.QueryOptions) ([]T, error), cursor func(string, T) *models.Cursor) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = defaultFormat
+	}
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		writeError(w, 400, "format must be csv, json, ndjson, xlsx or txt")
+		return
+	}
+
+	columns := all
+	if format == "txt" {
+		columns = all[:1]
+	}
+	if list := r.URL.Query().Get("columns"); list != "" {
+		columns = nil
+		for _, name := range strings.Split(list, ",") {
+			i := slices.IndexFunc(all, func(c exportColumn[T]) bool { return c.name == name })
+			if i < 0 {
+				writeError(w, 400, "Invalid column: "+name)
+				return
+			}
+			columns = append(columns, all[i])
+		}
+	}
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.name
+	}
+
+	opts := parseQueryOptions(r)
+	opts.Page, opts.PerPage, opts.After = 1, exportPageSize, nil
+	if opts.Sort == "" {
+		opts.Sort = "file_path"
+	}
+	rows, err := query(opts)
+	if err != nil {
+		writeError(w, 500, "Failed to export "+name)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.%s", name, format))
+	w.WriteHeader(200)
+
+	// Headers are sent: an error can only truncate the file
+	out := newExportWriter(w, format, names)
+	values := make([]any, len(columns))
+	for len(rows) > 0 {
+		for _, row := range rows {
+			for i, c := range columns {
+				values[i] = c.value(row)
+			}
+			if out.row(values) != nil {
+				return
+			}
+		}
+		if len(rows) < opts.PerPage {
+			break
+		}
+		opts.After = cursor(opts.Sort, rows[len(rows)-1])
+		if rows, err = query(opts); err != nil {
+			return
+		}
+	}
+	out.close()
+}
+
+// exportContentTypes maps the export formats to their content type.
+var exportContentTypes = map[string]string{
+	"csv":    "text/csv",
+	"json":   "application/json",
+	"ndjson": "application/x-ndjson",
+	"xlsx":   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"txt":    "text/plain",
+}
+
+// exportWriter writes the rows of an export in one format.
+type exportWriter interface {
+	row(values []any) error
+	close() error
+}
+
+func newExportWriter(w io.Writer, format string, columns []string) exportWriter {
+	switch format {
+	case "json", "ndjson":
+		return &jsonExport{w: w, columns: columns, array: format == "json"}
+	case "xlsx":
+		return newXLSXExport(w, columns)
+	case "txt":
+		return &textExport{w: w}
+	}
+	c := &csvExport{w: csv.NewWriter(w)}
+	c.w.Write(columns)
+	return c
+}
+
+// exportText formats a value for the text formats. Unknown times are empty.
+func exportText(v any) string {
+	switch v := v.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.UTC().Format(time.RFC3339)
+	}
+	return fmt.Sprint(v)
+}
+
+type csvExport struct {
+	w *csv.Writer
+}
+
+func (c *csvExport) row(values []any) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		record[i] = exportText(v)
+	}
+	return c.w.Write(record)
+}
+
+func (c *csvExport) close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type textExport struct {
+	w io.Writer
+}
+
+func (t *textExport) row(values []any) error {
+	fields := make([]string, len(values))
+	for i, v := range values {
+		fields[i] = exportText(v)
+	}
+	_, err := io.WriteString(t.w, strings.Join(fields, "\t")+"\n")
+	return err
+}
+
+func (t *textExport) close() error { return nil }
+
+// jsonExport writes the rows as JSON objects with their fields in the order
+// of the columns, in an array or one per line.
+type jsonExport struct {
+	w       io.Writer
+	columns []string
+	array   bool
+	rows    int
+}
+
+func (j *jsonExport) row(values []any) error {
+	var b strings.Builder
+	switch {
+	case j.array && j.rows == 0:
+		b.WriteString("[\n")
+	case j.array:
+		b.WriteString(",\n")
+	}
+	b.WriteString("{")
+	for i, v := range values {
+		if t, ok := v.(time.Time); ok && t.IsZero() {
+			v = nil
+		}
+		key, _ := json.Marshal(j.columns[i])
+		value, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.Write(key)
+		b.WriteString(":")
+		b.Write(value)
+	}
+	b.WriteString("}")
+	if !j.array {
+		b.WriteString("\n")
+	}
+	j.rows++
+	_, err := io.WriteString(j.w, b.String())
+	return err
+}
+
+func (j *jsonExport) close() error {
+	if !j.array {
+		return nil
+	}
+	end := "\n]\n"
+	if j.rows == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(j.w, end)
+	return err
+}
+
+// xlsxExport writes a workbook of a single sheet, streaming its rows into
+// the sheet part of the archive. Texts are inline strings so that no shared
+// strings table has to be kept in memory.
+type xlsxExport struct {
+	zip   *zip.Writer
+	sheet io.Writer
+	err   error
+}
+
+func newXLSXExport(w io.Writer, columns []string) *xlsxExport {
+	x := &xlsxExport{zip: zip.NewWriter(w)}
+	for _, part := range xlsxParts {
+		if x.err == nil {
+			x.write(part.name, part.content)
+		}
+	}
+	if x.err == nil {
+		x.sheet, x.err = x.zip.Create("xl/worksheets/sheet1.xml")
+	}
+	if x.err == nil {
+		_, x.err = io.WriteString(x.sheet, xml.Header+`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	}
+	header := make([]any, len(columns))
+	for i, c := range columns {
+		header[i] = c
+	}
+	x.row(header)
+	return x
+}
+
+func (x *xlsxExport) write(name, content string) {
+	f, err := x.zip.Create(name)
+	if err == nil {
+		_, err = io.WriteString(f, content)
+	}
+	x.err = err
+}
+
+func (x *xlsxExport) row(values []any) error {
+	if x.err != nil {
+		return x.err
+	}
+	var b strings.Builder
+	b.WriteString("<row>")
+	for _, v := range values {
+		if n, ok := v.(int64); ok {
+			fmt.Fprintf(&b, "<c><v>%d</v></c>", n)
+			continue
+		}
+		b.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+		xml.EscapeText(&b, []byte(exportText(v)))
+		b.WriteString("</t></is></c>")
+	}
+	b.WriteString("</row>")
+	_, x.err = io.WriteString(x.sheet, b.String())
+	return x.err
+}
+
+func (x *xlsxExport) close() error {
+	if x.err != nil {
+		return x.err
+	}
+	if _, err := io.WriteString(x.sheet, "</sheetData></worksheet>"); err != nil {
+		return err
+	}
+	return x.zip.Close()
+}
+
+// xlsxParts are the fixed parts of the workbooks of xlsxExport.
+var xlsxParts = []struct{ name, content string }{
+	{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Export" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
@@ -4,12 +4,20 @@ package web
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"godatacleaner/internal/models"
+	"godatacleaner/internal/storage"
+	"godatacleaner/internal/syncer"
 )
 
+// maxPerPage is the largest page the paginated endpoints return, enough for
+// the WebUI to show 10k rows in one virtualized table.
+const maxPerPage = 10000
+
 // parseQueryOptions extracts pagination parameters from the request.
 func parseQueryOptions(r *http.Request) models.QueryOptions {
 	opts := models.QueryOptions{
@@ -24,7 +32,7 @@ func parseQueryOptions(r *http.Request) models.QueryOptions {
 		}
 	}
 	if p := r.URL.Query().Get("per_page"); p != "" {
-		if v, err := strconv.Atoi(p); err == nil && v > 0 && v <= 1000 {
+		if v, err := strconv.Atoi(p); err == nil && v > 0 && v <= maxPerPage {
 			opts.PerPage = v
 		}
 	}
@@ -40,12 +48,52 @@ func parseQueryOptions(r *http.Request) models.QueryOptions {
 	if c := r.URL.Query().Get("category"); c != "" {
 		opts.Category = c
 	}
+	if st := r.URL.Query().Get("state"); st != "" {
+		opts.State = st
+	}
 	if u := r.URL.Query().Get("unique"); u == "true" {
 		opts.Unique = true
 	}
+	// Ages in days
+	if a := r.URL.Query().Get("min_age"); a != "" {
+		if v, err := strconv.Atoi(a); err == nil && v > 0 {
+			opts.MinAge = days(v)
+		}
+	}
+	if a := r.URL.Query().Get("max_age"); a != "" {
+		if v, err := strconv.Atoi(a); err == nil && v > 0 {
+			opts.MaxAge = days(v)
+		}
+	}
+	if a := r.URL.Query().Get("orphaned_for"); a != "" {
+		if v, err := strconv.Atoi(a); err == nil && v > 0 {
+			opts.MinOrphanAge = days(v)
+		}
+	}
+	// Sizes in bytes
+	if v, err := strconv.ParseInt(r.URL.Query().Get("min_size"), 10, 64); err == nil && v > 0 {
+		opts.MinSize = v
+	}
+	if v, err := strconv.ParseInt(r.URL.Query().Get("max_size"), 10, 64); err == nil && v > 0 {
+		opts.MaxSize = v
+	}
+	if r.URL.Query().Get("exclude_hardlinked") == "true" {
+		opts.ExcludeHardlinked = true
+	}
+	// Keyset pagination, from the next cursor of the previous page
+	if a := r.URL.Query().Get("after_id"); a != "" {
+		if v, err := strconv.ParseInt(a, 10, 64); err == nil && v >= 0 {
+			opts.After = &models.Cursor{ID: v, Value: r.URL.Query().Get("after_value")}
+		}
+	}
 	return opts
 }
 
+// days returns the duration of n days.
+func days(n int) time.Duration {
+	return time.Duration(n) * 24 * time.Hour
+}
+
 func writeJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -74,9 +122,13 @@ func (s *Server) handleTorrentFiles(w http.ResponseWriter, r *http.Request) {
 	if files == nil {
 		files = []models.TorrentFile{}
 	}
-	writeJSON(w, 200, models.PaginatedResponse{
+	resp := models.PaginatedResponse{
 		Data: files, Total: total, Page: opts.Page, PerPage: opts.PerPage, TotalPages: totalPages(total, opts.PerPage),
-	})
+	}
+	if len(files) == opts.PerPage {
+		resp.Next = storage.TorrentFileCursor(opts.Sort, files[len(files)-1])
+	}
+	writeJSON(w, 200, resp)
 }
 
 func (s *Server) handleTorrentStats(w http.ResponseWriter, r *http.Request) {
@@ -91,6 +143,23 @@ func (s *Server) handleTorrentStats(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleTorrentTree returns the subfolders and files of the torrent folder
+// given by path, the root by default, with the size of each subfolder.
+func (s *Server) handleTorrentTree(w http.ResponseWriter, r *http.Request) {
+	s.writeFolderTree(w, r, "torrent_files")
+}
+
+// writeFolderTree writes one level of the folder tree of table, so that the
+// WebUI can drill down to the folders taking the space.
+func (s *Server) writeFolderTree(w http.ResponseWriter, r *http.Request, table string) {
+	tree, err := s.storage.GetFolderTree(r.Context(), table, r.URL.Query().Get("path"))
+	if err != nil {
+		writeError(w, 500, "Failed to get folder tree")
+		return
+	}
+	writeJSON(w, 200, tree)
+}
+
 func (s *Server) handleTorrentFolders(w http.ResponseWriter, r *http.Request) {
 	folders, err := s.storage.GetFolderStats(context.Background(), "torrent_files")
 	if err != nil {
@@ -103,6 +172,125 @@ func (s *Server) handleTorrentFolders(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, 200, models.FolderStatsResponse{Folders: folders})
 }
 
+func (s *Server) handleTorrentList(w http.ResponseWriter, r *http.Request) {
+	opts := parseQueryOptions(r)
+	torrents, total, err := s.storage.GetTorrents(r.Context(), opts)
+	if err != nil {
+		writeError(w, 500, "Failed to get torrents")
+		return
+	}
+	if torrents == nil {
+		torrents = []models.Torrent{}
+	}
+	writeJSON(w, 200, models.PaginatedResponse{
+		Data: torrents, Total: total, Page: opts.Page, PerPage: opts.PerPage, TotalPages: totalPages(total, opts.PerPage),
+	})
+}
+
+func (s *Server) handleTorrentStates(w http.ResponseWriter, r *http.Request) {
+	states, err := s.storage.GetTorrentStateStats(r.Context())
+	if err != nil {
+		writeError(w, 500, "Failed to get torrent states")
+		return
+	}
+	if states == nil {
+		states = []models.TorrentStateStats{}
+	}
+	writeJSON(w, 200, models.TorrentStatesResponse{States: states})
+}
+
+func (s *Server) handleTrackerStats(w http.ResponseWriter, r *http.Request) {
+	trackers, err := s.storage.GetTrackerStats(r.Context())
+	if err != nil {
+		writeError(w, 500, "Failed to get tracker stats")
+		return
+	}
+	if trackers == nil {
+		trackers = []models.TrackerStats{}
+	}
+	writeJSON(w, 200, models.TrackerStatsResponse{Trackers: trackers})
+}
+
+func (s *Server) handleDeadTorrents(w http.ResponseWriter, r *http.Request) {
+	if s.cleaner == nil {
+		writeError(w, 503, "Cleaner not configured")
+		return
+	}
+	report, err := s.cleaner.DeadTorrents(r.Context())
+	if err != nil {
+		writeError(w, 500, "Failed to get dead torrents")
+		return
+	}
+	if report.Torrents == nil {
+		report.Torrents = []models.Torrent{}
+	}
+	writeJSON(w, 200, report)
+}
+
+func (s *Server) handleMissingTorrents(w http.ResponseWriter, r *http.Request) {
+	if s.cleaner == nil {
+		writeError(w, 503, "Cleaner not configured")
+		return
+	}
+	report, err := s.cleaner.Missing(r.Context())
+	if err != nil {
+		writeError(w, 500, "Failed to get missing files")
+		return
+	}
+	if report.Torrents == nil {
+		report.Torrents = []models.MissingTorrent{}
+	}
+	writeJSON(w, 200, report)
+}
+
+// handleTorrentCompleteness returns the share of the files of every torrent
+// found locally, the least complete first. max_completeness keeps only the
+// torrents at most that percent on disk.
+func (s *Server) handleTorrentCompleteness(w http.ResponseWriter, r *http.Request) {
+	if s.cleaner == nil {
+		writeError(w, 503, "Cleaner not configured")
+		return
+	}
+	opts := parseQueryOptions(r)
+	maxCompleteness := 100.0
+	if v := r.URL.Query().Get("max_completeness"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 || f > 100 {
+			writeError(w, 400, "Invalid max_completeness")
+			return
+		}
+		maxCompleteness = f
+	}
+
+	torrents, total, err := s.cleaner.Completeness(r.Context(), opts, maxCompleteness)
+	if err != nil {
+		writeError(w, 500, "Failed to get torrent completeness")
+		return
+	}
+	if torrents == nil {
+		torrents = []models.TorrentCompleteness{}
+	}
+	writeJSON(w, 200, models.PaginatedResponse{
+		Data: torrents, Total: total, Page: opts.Page, PerPage: opts.PerPage, TotalPages: totalPages(total, opts.PerPage),
+	})
+}
+
+func (s *Server) handleMissingFiles(w http.ResponseWriter, r *http.Request) {
+	if s.cleaner == nil {
+		writeError(w, 503, "Cleaner not configured")
+		return
+	}
+	files, err := s.cleaner.MissingFiles(r.Context(), r.PathValue("instance"), r.PathValue("hash"))
+	if err != nil {
+		writeError(w, 500, "Failed to get missing files")
+		return
+	}
+	if files == nil {
+		files = []models.MissingFile{}
+	}
+	writeJSON(w, 200, models.MissingFilesResponse{Files: files})
+}
+
 func (s *Server) handleLocalFiles(w http.ResponseWriter, r *http.Request) {
 	opts := parseQueryOptions(r)
 	files, total, err := s.storage.GetLocalFiles(context.Background(), opts)
@@ -113,9 +301,13 @@ func (s *Server) handleLocalFiles(w http.ResponseWriter, r *http.Request) {
 	if files == nil {
 		files = []models.LocalFile{}
 	}
-	writeJSON(w, 200, models.PaginatedResponse{
+	resp := models.PaginatedResponse{
 		Data: files, Total: total, Page: opts.Page, PerPage: opts.PerPage, TotalPages: totalPages(total, opts.PerPage),
-	})
+	}
+	if len(files) == opts.PerPage {
+		resp.Next = storage.LocalFileCursor(opts.Sort, files[len(files)-1])
+	}
+	writeJSON(w, 200, resp)
 }
 
 func (s *Server) handleLocalStats(w http.ResponseWriter, r *http.Request) {
@@ -130,6 +322,122 @@ func (s *Server) handleLocalStats(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, 200, models.CategoryStatsResponse{Categories: stats})
 }
 
+func (s *Server) handleInProgressStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.storage.GetInProgressStats(context.Background())
+	if err != nil {
+		writeError(w, 500, "Failed to get in-progress stats")
+		return
+	}
+	if stats == nil {
+		stats = []models.CategoryStats{}
+	}
+	writeJSON(w, 200, models.CategoryStatsResponse{Categories: stats})
+}
+
+func (s *Server) handleExtras(w http.ResponseWriter, r *http.Request) {
+	if s.cleaner == nil {
+		writeError(w, 503, "Cleaner not configured")
+		return
+	}
+	report, err := s.cleaner.Extras(r.Context())
+	if err != nil {
+		writeError(w, 500, "Failed to get extra files")
+		return
+	}
+	if report.Files == nil {
+		report.Files = []models.ExtraFile{}
+	}
+	writeJSON(w, 200, report)
+}
+
+func (s *Server) handleDiskUsage(w http.ResponseWriter, r *http.Request) {
+	if s.syncer == nil {
+		writeError(w, 503, "Sync not configured")
+		return
+	}
+	writeJSON(w, 200, models.DiskUsageResponse{Disks: s.syncer.DiskUsage()})
+}
+
+// handleDuplicates returns the groups of local files with the same content,
+// by checksum, or by name and size with by=name, which needs no checksums.
+func (s *Server) handleDuplicates(w http.ResponseWriter, r *http.Request) {
+	by := r.URL.Query().Get("by")
+	var groups []models.DuplicateGroup
+	var err error
+	switch by {
+	case "", "checksum":
+		by = "checksum"
+		if s.syncer == nil {
+			writeError(w, 503, "Sync not configured")
+			return
+		}
+		groups, err = s.syncer.Duplicates(r.Context())
+		if errors.Is(err, syncer.ErrChecksumDisabled) {
+			writeError(w, 503, "Checksums not configured")
+			return
+		}
+	case "name":
+		groups, err = s.storage.GetDuplicateNames(r.Context())
+	default:
+		writeError(w, 400, "Invalid by")
+		return
+	}
+	if err != nil {
+		writeError(w, 500, "Failed to get duplicate files")
+		return
+	}
+	resp := models.DuplicatesResponse{By: by, Groups: groups}
+	if resp.Groups == nil {
+		resp.Groups = []models.DuplicateGroup{}
+	}
+	for _, g := range groups {
+		resp.WastedBytes += g.WastedBytes
+	}
+	writeJSON(w, 200, resp)
+}
+
+// handleLocalTree returns the subfolders and files of the local folder
+// given by path, the root by default, with the size of each subfolder.
+func (s *Server) handleLocalTree(w http.ResponseWriter, r *http.Request) {
+	s.writeFolderTree(w, r, "local_files")
+}
+
+func (s *Server) handleLocalFileTorrents(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		writeError(w, 400, "Missing path")
+		return
+	}
+	resp, err := s.storage.GetFileTorrents(r.Context(), path)
+	if errors.Is(err, storage.ErrNotFound) {
+		writeError(w, 404, "Local file not found")
+		return
+	}
+	if err != nil {
+		writeError(w, 500, "Failed to get file torrents")
+		return
+	}
+	writeJSON(w, 200, resp)
+}
+
+func (s *Server) handleLocalFileDetail(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		writeError(w, 400, "Missing path")
+		return
+	}
+	detail, err := s.storage.GetFileDetail(r.Context(), path)
+	if errors.Is(err, storage.ErrNotFound) {
+		writeError(w, 404, "Local file not found")
+		return
+	}
+	if err != nil {
+		writeError(w, 500, "Failed to get file detail")
+		return
+	}
+	writeJSON(w, 200, detail)
+}
+
 func (s *Server) handleLocalFolders(w http.ResponseWriter, r *http.Request) {
 	folders, err := s.storage.GetFolderStats(context.Background(), "local_files")
 	if err != nil {
@@ -152,9 +460,13 @@ func (s *Server) handleOrphanFiles(w http.ResponseWriter, r *http.Request) {
 	if files == nil {
 		files = []models.OrphanFile{}
 	}
-	writeJSON(w, 200, models.PaginatedResponse{
+	resp := models.PaginatedResponse{
 		Data: files, Total: total, Page: opts.Page, PerPage: opts.PerPage, TotalPages: totalPages(total, opts.PerPage),
-	})
+	}
+	if len(files) == opts.PerPage {
+		resp.Next = storage.OrphanFileCursor(opts.Sort, files[len(files)-1])
+	}
+	writeJSON(w, 200, resp)
 }
 
 func (s *Server) handleOrphanStats(w http.ResponseWriter, r *http.Request) {
@@ -170,33 +482,61 @@ func (s *Server) handleOrphanStats(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleUnknownExtensions(w http.ResponseWriter, r *http.Request) {
-	stats, err := s.storage.GetUnknownExtensionStats(context.Background())
+	stats, err := s.storage.GetExtensionStats(r.Context(), "unknown")
 	if err != nil {
 		writeError(w, 500, "Failed to get extension stats")
 		return
 	}
+	if len(stats) > 20 {
+		stats = stats[:20]
+	}
 	if stats == nil {
 		stats = []models.ExtensionStats{}
 	}
 	writeJSON(w, 200, models.ExtensionStatsResponse{Extensions: stats})
 }
 
-func (s *Server) handleOrphanExport(w http.ResponseWriter, r *http.Request) {
-	// Get all orphan files (no pagination for export)
-	opts := models.QueryOptions{Page: 1, PerPage: 1000000}
-	files, _, err := s.storage.GetOrphanFiles(context.Background(), opts)
+// handleExtensionStats returns the statistics by extension of the whole
+// library, or of a category, with their category and orphan breakdowns.
+// limit keeps only the largest extensions.
+func (s *Server) handleExtensionStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.storage.GetExtensionStats(r.Context(), r.URL.Query().Get("category"))
 	if err != nil {
-		writeError(w, 500, "Failed to get orphan files")
+		writeError(w, 500, "Failed to get extension stats")
 		return
 	}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if l, err := strconv.Atoi(v); err == nil && l > 0 && l < len(stats) {
+			stats = stats[:l]
+		}
+	}
+	if stats == nil {
+		stats = []models.ExtensionStats{}
+	}
+	writeJSON(w, 200, models.ExtensionStatsResponse{Extensions: stats})
+}
 
-	// Set headers for CSV download
-	w.Header().Set("Content-Type", "text/csv")
-	w.Header().Set("Content-Disposition", "attachment; filename=orphans.csv")
-	w.WriteHeader(200)
+// handleLibraryHistory returns the daily size of every category and of its
+// orphans over the last days, 90 by default.
+func (s *Server) handleLibraryHistory(w http.ResponseWriter, r *http.Request) {
+	days := 90
+	if v := r.URL.Query().Get("days"); v != "" {
+		d, err := strconv.Atoi(v)
+		if err != nil || d < 1 || d > 3650 {
+			writeError(w, 400, "Invalid days")
+			return
+		}
+		days = d
+	}
 
-	// Write CSV content (just file paths)
-	for _, f := range files {
-		w.Write([]byte(f.FilePath + "\n"))
+	since := time.Now().AddDate(0, 0, 1-days)
+	points, err := s.storage.GetLibraryHistory(r.Context(), since)
+	if err != nil {
+		writeError(w, 500, "Failed to get library history")
+		return
+	}
+	if points == nil {
+		points = []models.LibraryHistoryPoint{}
 	}
+	writeJSON(w, 200, models.LibraryHistoryResponse{Days: days, Points: points})
 }
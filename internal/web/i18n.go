@@ -0,0 +1,96 @@
+package web
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"log"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"godatacleaner/internal/models"
+)
+
+// languagePacks holds the translations of the WebUI: i18n/<code>.json maps
+// the message keys of the interface to their text in the language code, and
+// language.name to the name of the language.
+//
+//go:embed i18n/*.json
+var languagePacks embed.FS
+
+// defaultLanguage is the language of the WebUI when the browser prefers none
+// of the others. Its pack holds every message: a message missing from
+// another pack is shown in it.
+const defaultLanguage = "fr"
+
+// languages maps the language codes to their messages.
+var languages = loadLanguagePacks()
+
+func loadLanguagePacks() map[string]map[string]string {
+	packs := make(map[string]map[string]string)
+	names, _ := fs.Glob(languagePacks, "i18n/*.json")
+	for _, name := range names {
+		data, err := languagePacks.ReadFile(name)
+		if err != nil {
+			continue
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			log.Printf("⚠️  Invalid language pack %s: %v", name, err)
+			continue
+		}
+		packs[strings.TrimSuffix(path.Base(name), ".json")] = messages
+	}
+	return packs
+}
+
+// preferredLanguage returns the language of the WebUI the Accept-Language
+// header prefers, comparing the primary language of its tags (en for en-US),
+// or the default language.
+func preferredLanguage(header string) string {
+	best, bestQ := defaultLanguage, 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		code, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if _, ok := languages[code]; ok && q > bestQ {
+			best, bestQ = code, q
+		}
+	}
+	return best
+}
+
+// handleLanguagePack returns the messages of the WebUI in the language of
+// the lang parameter, which the WebUI sets when the user picks a language,
+// or else in the language preferred by the browser.
+func (s *Server) handleLanguagePack(w http.ResponseWriter, r *http.Request) {
+	lang := r.URL.Query().Get("lang")
+	if _, ok := languages[lang]; !ok {
+		lang = preferredLanguage(r.Header.Get("Accept-Language"))
+	}
+
+	messages := make(map[string]string, len(languages[defaultLanguage]))
+	for k, v := range languages[defaultLanguage] {
+		messages[k] = v
+	}
+	for k, v := range languages[lang] {
+		messages[k] = v
+	}
+
+	resp := models.LanguagePackResponse{Language: lang, Messages: messages}
+	for code, pack := range languages {
+		resp.Languages = append(resp.Languages, models.Language{Code: code, Name: pack["language.name"]})
+	}
+	sort.Slice(resp.Languages, func(i, j int) bool { return resp.Languages[i].Code < resp.Languages[j].Code })
+
+	w.Header().Set("Vary", "Accept-Language")
+	writeJSON(w, 200, resp)
+}
@@ -0,0 +1,78 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"godatacleaner/internal/models"
+	"godatacleaner/internal/storage"
+)
+
+func (s *Server) handleIgnoredPaths(w http.ResponseWriter, r *http.Request) {
+	paths, err := s.storage.ListIgnoredPaths(r.Context())
+	if err != nil {
+		writeError(w, 500, "Failed to get ignored paths")
+		return
+	}
+	if paths == nil {
+		paths = []models.IgnoredPath{}
+	}
+	writeJSON(w, 200, models.IgnoredPathsResponse{Paths: paths})
+}
+
+func (s *Server) handleAddIgnoredPath(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Path    string `json:"path"`
+		Pattern string `json:"pattern"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, 400, "Invalid JSON body")
+		return
+	}
+	if req.Pattern != "" {
+		if req.Path != "" {
+			writeError(w, 400, "Give either a path or a pattern")
+			return
+		}
+		if !storage.ValidIgnoredPattern(req.Pattern) {
+			writeError(w, 400, "pattern must be a valid glob, absolute or starting with *")
+			return
+		}
+		id, pattern, err := s.storage.AddIgnoredPattern(r.Context(), req.Pattern)
+		if err != nil {
+			writeError(w, 500, "Failed to add ignored pattern")
+			return
+		}
+		writeJSON(w, 201, models.IgnoredPath{ID: id, Path: pattern, Pattern: true})
+		return
+	}
+	if len(req.Path) == 0 || req.Path[0] != '/' {
+		writeError(w, 400, "path must be absolute")
+		return
+	}
+	id, path, err := s.storage.AddIgnoredPath(r.Context(), req.Path)
+	if err != nil {
+		writeError(w, 500, "Failed to add ignored path")
+		return
+	}
+	writeJSON(w, 201, models.IgnoredPath{ID: id, Path: path})
+}
+
+func (s *Server) handleRemoveIgnoredPath(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(r)
+	if !ok {
+		writeError(w, 400, "Invalid ignored path id")
+		return
+	}
+	err := s.storage.RemoveIgnoredPath(r.Context(), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		writeError(w, 404, "Ignored path not found")
+		return
+	}
+	if err != nil {
+		writeError(w, 500, "Failed to remove ignored path")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
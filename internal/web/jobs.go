@@ -0,0 +1,140 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"godatacleaner/internal/models"
+	"godatacleaner/internal/scheduler"
+	"godatacleaner/internal/storage"
+)
+
+// pathID parses the {id} path parameter of the request.
+func pathID(r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	return id, err == nil && id > 0
+}
+
+func (s *Server) handleCleanupJobs(w http.ResponseWriter, r *http.Request) {
+	jobs, err := s.storage.ListCleanupJobs(context.Background())
+	if err != nil {
+		writeError(w, 500, "Failed to get cleanup jobs")
+		return
+	}
+	if jobs == nil {
+		jobs = []models.CleanupJob{}
+	}
+	writeJSON(w, 200, models.CleanupJobsResponse{Jobs: jobs})
+}
+
+func (s *Server) handleCleanupJobEnable(w http.ResponseWriter, r *http.Request) {
+	s.setCleanupJobEnabled(w, r, true)
+}
+
+func (s *Server) handleCleanupJobDisable(w http.ResponseWriter, r *http.Request) {
+	s.setCleanupJobEnabled(w, r, false)
+}
+
+func (s *Server) setCleanupJobEnabled(w http.ResponseWriter, r *http.Request, enabled bool) {
+	id, ok := pathID(r)
+	if !ok {
+		writeError(w, 400, "Invalid job id")
+		return
+	}
+	err := s.storage.SetCleanupJobEnabled(context.Background(), id, enabled)
+	if errors.Is(err, storage.ErrNotFound) {
+		writeError(w, 404, "Cleanup job not found")
+		return
+	}
+	if err != nil {
+		writeError(w, 500, "Failed to update cleanup job")
+		return
+	}
+	job, err := s.storage.GetCleanupJob(context.Background(), id)
+	if err != nil {
+		writeError(w, 500, "Failed to get cleanup job")
+		return
+	}
+	writeJSON(w, 200, job)
+}
+
+// handleCleanupJobSchedule changes the schedule of a cleanup job; the
+// scheduler reloads the jobs on every tick.
+func (s *Server) handleCleanupJobSchedule(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(r)
+	if !ok {
+		writeError(w, 400, "Invalid job id")
+		return
+	}
+	var req struct {
+		Schedule string `json:"schedule"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, 400, "Invalid JSON body")
+		return
+	}
+	if err := scheduler.ValidateSchedule(req.Schedule); err != nil {
+		writeError(w, 400, err.Error())
+		return
+	}
+	err := s.storage.SetCleanupJobSchedule(r.Context(), id, req.Schedule)
+	if errors.Is(err, storage.ErrNotFound) {
+		writeError(w, 404, "Cleanup job not found")
+		return
+	}
+	if err != nil {
+		writeError(w, 500, "Failed to update cleanup job")
+		return
+	}
+	job, err := s.storage.GetCleanupJob(r.Context(), id)
+	if err != nil {
+		writeError(w, 500, "Failed to get cleanup job")
+		return
+	}
+	writeJSON(w, 200, job)
+}
+
+func (s *Server) handleCleanupRuns(w http.ResponseWriter, r *http.Request) {
+	var jobID int64
+	if v := r.URL.Query().Get("job_id"); v != "" {
+		if id, err := strconv.ParseInt(v, 10, 64); err == nil {
+			jobID = id
+		}
+	}
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if l, err := strconv.Atoi(v); err == nil && l > 0 && l <= 1000 {
+			limit = l
+		}
+	}
+
+	runs, err := s.storage.ListCleanupRuns(context.Background(), jobID, limit)
+	if err != nil {
+		writeError(w, 500, "Failed to get cleanup runs")
+		return
+	}
+	if runs == nil {
+		runs = []models.CleanupRun{}
+	}
+	writeJSON(w, 200, models.CleanupRunsResponse{Runs: runs})
+}
+
+func (s *Server) handleCleanupRunFiles(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(r)
+	if !ok {
+		writeError(w, 400, "Invalid run id")
+		return
+	}
+	files, err := s.storage.GetCleanupRunFiles(context.Background(), id)
+	if err != nil {
+		writeError(w, 500, "Failed to get cleanup run files")
+		return
+	}
+	if files == nil {
+		files = []models.CleanupRunFile{}
+	}
+	writeJSON(w, 200, models.CleanupRunFilesResponse{Files: files})
+}
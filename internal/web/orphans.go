@@ -0,0 +1,241 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"godatacleaner/internal/cleaner"
+	"godatacleaner/internal/models"
+)
+
+// deleteOrphansRequest selects the orphans to delete, either by path or with
+// the same filters as GET /api/v1/orphans/files. A request without token is a
+// preview: nothing is deleted and a confirmation token is returned, to send
+// back alone to execute the deletion.
+type deleteOrphansRequest struct {
+	Token  string   `json:"token"`
+	Paths  []string `json:"paths"`
+	Filter *struct {
+		Category          string `json:"category"`
+		Search            string `json:"search"`
+		DeadTorrents      bool   `json:"dead_torrents"` // Files of dead torrents instead of orphans
+		MinAge            int    `json:"min_age"`       // Days
+		MaxAge            int    `json:"max_age"`       // Days
+		OrphanedFor       int    `json:"orphaned_for"`  // Days
+		MinSize           int64  `json:"min_size"`      // Bytes
+		MaxSize           int64  `json:"max_size"`      // Bytes
+		ExcludeHardlinked bool   `json:"exclude_hardlinked"`
+	} `json:"filter"`
+	DryRun     bool  `json:"dry_run"`
+	Quarantine *bool `json:"quarantine"` // Defaults to true when a quarantine is configured
+	Archive    bool  `json:"archive"`
+	Companions bool  `json:"companions"`
+}
+
+// deletionPreview is returned by a preview, with the token confirming it.
+type deletionPreview struct {
+	Token     string          `json:"token"`
+	ExpiresAt time.Time       `json:"expires_at"`
+	Files     int64           `json:"files"`
+	Bytes     int64           `json:"bytes"`
+	Protected int64           `json:"protected"`
+	Report    *cleaner.Report `json:"report"`
+}
+
+// handleOrphanTotals returns the number and the size of the orphans matching
+// the filters of GET /api/v1/orphans/files, for the WebUI to select them all.
+func (s *Server) handleOrphanTotals(w http.ResponseWriter, r *http.Request) {
+	totals, err := s.storage.GetOrphanTotals(r.Context(), parseQueryOptions(r))
+	if err != nil {
+		writeError(w, 500, "Failed to get orphan totals")
+		return
+	}
+	writeJSON(w, 200, totals)
+}
+
+// handleOrphanReleases returns the orphans matching the filters of GET
+// /api/v1/orphans/files grouped by release folder, sorted on size,
+// file_count, name or mod_time.
+func (s *Server) handleOrphanReleases(w http.ResponseWriter, r *http.Request) {
+	opts := parseQueryOptions(r)
+	releases, total, err := s.storage.GetOrphanReleases(r.Context(), opts)
+	if err != nil {
+		writeError(w, 500, "Failed to get orphan releases")
+		return
+	}
+	writeJSON(w, 200, models.PaginatedResponse{
+		Data: releases, Total: total, Page: opts.Page, PerPage: opts.PerPage, TotalPages: totalPages(total, opts.PerPage),
+	})
+}
+
+// handleOrphanReleaseFiles returns the orphans of a release folder matching
+// the same filters, to show them or to delete the whole folder.
+func (s *Server) handleOrphanReleaseFiles(w http.ResponseWriter, r *http.Request) {
+	folder := r.URL.Query().Get("folder")
+	if folder == "" {
+		writeError(w, 400, "folder is required")
+		return
+	}
+	files, err := s.storage.GetOrphanReleaseFiles(r.Context(), folder, parseQueryOptions(r))
+	if err != nil {
+		writeError(w, 500, "Failed to get orphan release files")
+		return
+	}
+	if files == nil {
+		files = []models.OrphanFile{}
+	}
+	writeJSON(w, 200, models.OrphanReleaseFilesResponse{Folder: folder, Files: files})
+}
+
+func (s *Server) handleDeleteOrphanFiles(w http.ResponseWriter, r *http.Request) {
+	if s.cleaner == nil {
+		writeError(w, 503, "Cleaner not configured")
+		return
+	}
+
+	var req deleteOrphansRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, 400, "Invalid JSON body")
+		return
+	}
+
+	// Second step: execute exactly what was previewed
+	if req.Token != "" {
+		pending, ok := s.confirm.take(req.Token)
+		if !ok || pending.hash != "" {
+			writeError(w, 400, "Invalid or expired confirmation token")
+			return
+		}
+		started := time.Now()
+		report, err := s.cleaner.RunFiles(r.Context(), pending.paths, pending.opts)
+		s.recordCleanup(r, started, pending.opts, report, err)
+		writeCleanReport(w, report, err)
+		return
+	}
+
+	if len(req.Paths) == 0 && req.Filter == nil {
+		writeError(w, 400, "paths or filter is required")
+		return
+	}
+	if len(req.Paths) > 0 && req.Filter != nil {
+		writeError(w, 400, "paths and filter are mutually exclusive")
+		return
+	}
+
+	// A preview that could not be confirmed is refused at once
+	if !req.DryRun {
+		if err := s.cleaner.CheckLastSync(r.Context()); err != nil {
+			writeCleanReport(w, nil, err)
+			return
+		}
+	}
+
+	opts := cleaner.Options{
+		DryRun:     true,
+		Quarantine: s.cleaner.HasQuarantine() && !req.Archive,
+		Archive:    req.Archive,
+		Companions: req.Companions,
+	}
+	if req.Quarantine != nil {
+		opts.Quarantine = *req.Quarantine
+	}
+
+	var report *cleaner.Report
+	var err error
+	if req.Filter != nil {
+		opts.Category = req.Filter.Category
+		opts.Search = req.Filter.Search
+		opts.DeadTorrents = req.Filter.DeadTorrents
+		opts.MinAge = days(req.Filter.MinAge)
+		opts.MaxAge = days(req.Filter.MaxAge)
+		opts.MinOrphanAge = days(req.Filter.OrphanedFor)
+		opts.MinSize = req.Filter.MinSize
+		opts.MaxSize = req.Filter.MaxSize
+		opts.ExcludeHardlinked = req.Filter.ExcludeHardlinked
+		report, err = s.cleaner.Run(r.Context(), opts)
+	} else {
+		report, err = s.cleaner.RunFiles(r.Context(), req.Paths, opts)
+	}
+	if err != nil || req.DryRun {
+		writeCleanReport(w, report, err)
+		return
+	}
+
+	// First step: remember the files that would be handled. Companions are
+	// found again from their video when the deletion is confirmed.
+	pending := pendingDeletion{opts: opts, expires: time.Now().Add(confirmationTTL)}
+	pending.opts.DryRun = false
+	for _, a := range report.Actions {
+		if a.CompanionOf == "" && !a.Protected && a.Error == "" {
+			pending.paths = append(pending.paths, a.File.FilePath)
+		}
+	}
+	token, err := s.confirm.add(pending)
+	if err != nil {
+		writeError(w, 500, "Failed to create confirmation token")
+		return
+	}
+
+	if report.Actions == nil {
+		report.Actions = []cleaner.Action{}
+	}
+	writeJSON(w, 200, deletionPreview{
+		Token:     token,
+		ExpiresAt: pending.expires,
+		Files:     report.FilesRemoved,
+		Bytes:     report.BytesReclaimed,
+		Protected: report.Protected,
+		Report:    report,
+	})
+}
+
+// recordCleanup adds a confirmed deletion to the cleanup run history, with
+// the user who confirmed it.
+func (s *Server) recordCleanup(r *http.Request, started time.Time, opts cleaner.Options, report *cleaner.Report, cleanErr error) {
+	run := models.CleanupRun{
+		Source:     models.CleanupSourceWeb,
+		StartedAt:  started,
+		FinishedAt: time.Now(),
+		Quarantine: opts.Quarantine,
+	}
+	if u := currentUser(r); u != nil {
+		run.User = u.Username
+	}
+	if cleanErr != nil {
+		run.Error = cleanErr.Error()
+	}
+	var files []models.CleanupRunFile
+	if report != nil {
+		run.FilesRemoved = report.FilesRemoved
+		run.BytesReclaimed = report.BytesReclaimed
+		run.Failed = report.Failed
+		files = report.Files()
+	}
+	if _, err := s.storage.RecordCleanupRun(context.WithoutCancel(r.Context()), run, files); err != nil {
+		log.Printf("⚠️  Failed to record cleanup run: %v", err)
+	}
+}
+
+// writeCleanReport writes the report of a cleanup, or the error that stopped it.
+func writeCleanReport(w http.ResponseWriter, report *cleaner.Report, err error) {
+	if errors.Is(err, cleaner.ErrNoQuarantine) || errors.Is(err, cleaner.ErrNoArchive) || errors.Is(err, cleaner.ErrBothMoves) || errors.Is(err, cleaner.ErrReadOnly) {
+		writeError(w, 400, err.Error())
+		return
+	}
+	if errors.Is(err, cleaner.ErrPartialSync) {
+		writeError(w, 409, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, 500, "Failed to delete orphan files")
+		return
+	}
+	if report.Actions == nil {
+		report.Actions = []cleaner.Action{}
+	}
+	writeJSON(w, 200, report)
+}
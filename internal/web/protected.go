@@ -0,0 +1,62 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"godatacleaner/internal/cleaner"
+	"godatacleaner/internal/models"
+	"godatacleaner/internal/storage"
+)
+
+func (s *Server) handleProtectedPaths(w http.ResponseWriter, r *http.Request) {
+	paths, err := s.storage.ListProtectedPaths(context.Background())
+	if err != nil {
+		writeError(w, 500, "Failed to get protected paths")
+		return
+	}
+	if paths == nil {
+		paths = []models.ProtectedPath{}
+	}
+	writeJSON(w, 200, models.ProtectedPathsResponse{Paths: paths})
+}
+
+func (s *Server) handleAddProtectedPath(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Pattern string `json:"pattern"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, 400, "Invalid JSON body")
+		return
+	}
+	if err := cleaner.ValidatePattern(req.Pattern); err != nil {
+		writeError(w, 400, err.Error())
+		return
+	}
+	id, err := s.storage.AddProtectedPath(context.Background(), req.Pattern)
+	if err != nil {
+		writeError(w, 500, "Failed to add protected path")
+		return
+	}
+	writeJSON(w, 201, models.ProtectedPath{ID: id, Pattern: req.Pattern})
+}
+
+func (s *Server) handleRemoveProtectedPath(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(r)
+	if !ok {
+		writeError(w, 400, "Invalid protected path id")
+		return
+	}
+	err := s.storage.RemoveProtectedPath(context.Background(), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		writeError(w, 404, "Protected path not found")
+		return
+	}
+	if err != nil {
+		writeError(w, 500, "Failed to remove protected path")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,61 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"godatacleaner/internal/cleaner"
+	"godatacleaner/internal/models"
+)
+
+func (s *Server) handleQuarantine(w http.ResponseWriter, r *http.Request) {
+	opts := parseQueryOptions(r)
+	entries, total, err := s.storage.ListQuarantine(context.Background(), opts)
+	if err != nil {
+		writeError(w, 500, "Failed to get quarantine entries")
+		return
+	}
+	if entries == nil {
+		entries = []models.QuarantineEntry{}
+	}
+	writeJSON(w, 200, models.PaginatedResponse{
+		Data: entries, Total: total, Page: opts.Page, PerPage: opts.PerPage, TotalPages: totalPages(total, opts.PerPage),
+	})
+}
+
+func (s *Server) handleQuarantineRestore(w http.ResponseWriter, r *http.Request) {
+	if s.cleaner == nil {
+		writeError(w, 503, "Cleaner not configured")
+		return
+	}
+
+	var req struct {
+		IDs   []int64 `json:"ids"`
+		Batch string  `json:"batch"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, 400, "Invalid JSON body")
+		return
+	}
+	if len(req.IDs) == 0 && req.Batch == "" {
+		writeError(w, 400, "ids or batch is required")
+		return
+	}
+
+	var report *cleaner.RestoreReport
+	var err error
+	if req.Batch != "" {
+		report, err = s.cleaner.RestoreBatch(r.Context(), req.Batch)
+	} else {
+		report, err = s.cleaner.Restore(r.Context(), req.IDs)
+	}
+	if err != nil {
+		writeError(w, 500, "Failed to restore quarantined files")
+		return
+	}
+	if report.Actions == nil {
+		report.Actions = []cleaner.RestoreAction{}
+	}
+	writeJSON(w, 200, report)
+}
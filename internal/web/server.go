@@ -5,59 +5,216 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 
+	"godatacleaner/internal/cleaner"
 	"godatacleaner/internal/storage"
+	"godatacleaner/internal/syncer"
 )
 
 // Server handles HTTP requests for the WebUI and REST API.
 type Server struct {
-	storage *storage.Storage
-	host    string
-	port    int
+	storage      storage.Store
+	cleaner      *cleaner.Cleaner
+	syncer       *syncer.Syncer
+	syncs        *syncManager
+	settings     *settingsEditor
+	confirm      *confirmations
+	passwords    *passwordCache
+	authUsername string
+	authPassword string
+	authToken    string
+	corsOrigins  []string
+	host         string
+	port         int
 }
 
 // NewServer creates a new web server.
-func NewServer(storage *storage.Storage, host string, port int) *Server {
+func NewServer(storage storage.Store, host string, port int) *Server {
 	return &Server{
-		storage: storage,
-		host:    host,
-		port:    port,
+		storage:   storage,
+		syncs:     newSyncManager(),
+		confirm:   newConfirmations(),
+		passwords: newPasswordCache(),
+		host:      host,
+		port:      port,
 	}
 }
 
+// WithCleaner sets the cleaner used by the endpoints that act on files.
+func (s *Server) WithCleaner(c *cleaner.Cleaner) *Server {
+	s.cleaner = c
+	return s
+}
+
+// WithSyncer sets the syncer used to refresh the database from the API.
+func (s *Server) WithSyncer(sy *syncer.Syncer) *Server {
+	s.syncer = sy
+	return s
+}
+
 // Start starts the HTTP server with configured routes.
 // It sets up the HTTP router with routes for the WebUI and REST API.
 func (s *Server) Start() error {
+	// Build the server address
+	addr := fmt.Sprintf("%s:%d", s.host, s.port)
+
+	// Log server startup
+	log.Printf("Starting web server on http://%s", addr)
+
+	return http.ListenAndServe(addr, s.handler())
+}
+
+// handler returns the routes of the WebUI and REST API, every route requiring
+// authentication when enabled.
+func (s *Server) handler() http.Handler {
 	// Create a new ServeMux for routing
 	mux := http.NewServeMux()
 
 	// Configure routes for WebUI
 	mux.HandleFunc("GET /", s.handleIndex)
+	mux.HandleFunc("GET /assets/{name}", s.handleAsset)
+	mux.HandleFunc("GET /api/v1/i18n", s.handleLanguagePack)
+
+	// Configure routes for account API
+	mux.HandleFunc("POST /api/v1/login", s.handleLogin)
+	mux.HandleFunc("POST /api/v1/logout", s.handleLogout)
+	mux.HandleFunc("GET /api/v1/me", s.handleCurrentUser)
+	mux.HandleFunc("GET /api/v1/me/columns", s.handleColumnLayouts)
+	mux.HandleFunc("POST /api/v1/me/columns/{list}", s.handleSetColumnLayout)
+	mux.HandleFunc("DELETE /api/v1/me/columns/{list}", s.handleResetColumnLayout)
+
+	// Configure routes for API keys API
+	mux.HandleFunc("GET /api/v1/keys", s.handleAPIKeys)
+	mux.HandleFunc("POST /api/v1/keys", s.handleCreateAPIKey)
+	mux.HandleFunc("DELETE /api/v1/keys/{id}", s.handleRevokeAPIKey)
+
+	// Configure routes for sync API
+	mux.HandleFunc("POST /api/v1/sync", s.handleStartSync)
+	mux.HandleFunc("GET /api/v1/sync/progress", s.handleSyncProgress)
+	mux.HandleFunc("POST /api/v1/sync/cancel", s.handleCancelSync)
+	mux.HandleFunc("GET /api/v1/sync/status", s.handleSyncState)
+	mux.HandleFunc("GET /api/v1/sync/runs", s.handleSyncRuns)
+	mux.HandleFunc("GET /api/v1/sync/runs/{id}/diff", s.handleSyncDiff)
+	mux.HandleFunc("GET /api/v1/sync/runs/{id}/errors", s.handleSyncErrors)
+	mux.HandleFunc("GET /api/v1/sync/{id}", s.handleSyncStatus)
+	mux.HandleFunc("GET /api/v1/scan/progress", s.handleScanProgress)
 
 	// Configure routes for Torrent API
-	mux.HandleFunc("GET /api/torrent/files", s.handleTorrentFiles)
-	mux.HandleFunc("GET /api/torrent/stats", s.handleTorrentStats)
-	mux.HandleFunc("GET /api/torrent/folders", s.handleTorrentFolders)
+	mux.HandleFunc("GET /api/v1/torrent/files", s.handleTorrentFiles)
+	mux.HandleFunc("GET /api/v1/torrent/export", s.handleTorrentFileExport)
+	mux.HandleFunc("GET /api/v1/torrent/stats", s.handleTorrentStats)
+	mux.HandleFunc("GET /api/v1/torrent/folders", s.handleTorrentFolders)
+	mux.HandleFunc("GET /api/v1/torrent/tree", s.handleTorrentTree)
+	mux.HandleFunc("GET /api/v1/torrent/torrents", s.handleTorrentList)
+	mux.HandleFunc("DELETE /api/v1/torrent/torrents/{instance}/{hash}", s.handleDeleteTorrent)
+	mux.HandleFunc("POST /api/v1/torrent/torrents/{instance}/{hash}/pause", s.handlePauseTorrent)
+	mux.HandleFunc("POST /api/v1/torrent/torrents/{instance}/{hash}/resume", s.handleResumeTorrent)
+	mux.HandleFunc("POST /api/v1/torrent/torrents/{instance}/{hash}/recheck", s.handleRecheckTorrent)
+	mux.HandleFunc("POST /api/v1/torrent/torrents/{instance}/{hash}/location", s.handleMoveTorrent)
+	mux.HandleFunc("POST /api/v1/torrent/recheck", s.handleRecheckPaths)
+	mux.HandleFunc("POST /api/v1/torrent/category", s.handleSetCategory)
+	mux.HandleFunc("POST /api/v1/torrent/tags", s.handleSetTags)
+	mux.HandleFunc("POST /api/v1/torrent/reannounce", s.handleReannounce)
+	mux.HandleFunc("GET /api/v1/torrent/states", s.handleTorrentStates)
+	mux.HandleFunc("GET /api/v1/torrent/trackers", s.handleTrackerStats)
+	mux.HandleFunc("GET /api/v1/torrent/dead", s.handleDeadTorrents)
+	mux.HandleFunc("GET /api/v1/torrent/missing", s.handleMissingTorrents)
+	mux.HandleFunc("GET /api/v1/torrent/missing/{instance}/{hash}", s.handleMissingFiles)
+	mux.HandleFunc("GET /api/v1/torrent/completeness", s.handleTorrentCompleteness)
 
 	// Configure routes for Local API
-	mux.HandleFunc("GET /api/local/files", s.handleLocalFiles)
-	mux.HandleFunc("GET /api/local/stats", s.handleLocalStats)
-	mux.HandleFunc("GET /api/local/folders", s.handleLocalFolders)
+	mux.HandleFunc("GET /api/v1/local/files", s.handleLocalFiles)
+	mux.HandleFunc("GET /api/v1/local/export", s.handleLocalFileExport)
+	mux.HandleFunc("GET /api/v1/local/stats", s.handleLocalStats)
+	mux.HandleFunc("GET /api/v1/local/folders", s.handleLocalFolders)
+	mux.HandleFunc("GET /api/v1/local/tree", s.handleLocalTree)
+	mux.HandleFunc("GET /api/v1/local/file", s.handleLocalFileDetail)
+	mux.HandleFunc("GET /api/v1/local/file/torrents", s.handleLocalFileTorrents)
+	mux.HandleFunc("GET /api/v1/local/top", s.handleTopFiles)
+	mux.HandleFunc("GET /api/v1/local/top/folders", s.handleTopFolders)
+	mux.HandleFunc("GET /api/v1/local/downloading", s.handleInProgressStats)
+	mux.HandleFunc("GET /api/v1/local/extras", s.handleExtras)
+	mux.HandleFunc("GET /api/v1/local/duplicates", s.handleDuplicates)
+	mux.HandleFunc("GET /api/v1/local/disks", s.handleDiskUsage)
 
 	// Configure routes for Orphans API
-	mux.HandleFunc("GET /api/orphans/files", s.handleOrphanFiles)
-	mux.HandleFunc("GET /api/orphans/stats", s.handleOrphanStats)
-	mux.HandleFunc("GET /api/orphans/export", s.handleOrphanExport)
+	mux.HandleFunc("GET /api/v1/orphans/files", s.handleOrphanFiles)
+	mux.HandleFunc("GET /api/v1/orphans/stats", s.handleOrphanStats)
+	mux.HandleFunc("GET /api/v1/orphans/totals", s.handleOrphanTotals)
+	mux.HandleFunc("GET /api/v1/orphans/releases", s.handleOrphanReleases)
+	mux.HandleFunc("GET /api/v1/orphans/releases/files", s.handleOrphanReleaseFiles)
+	mux.HandleFunc("GET /api/v1/orphans/export", s.handleOrphanExport)
+	mux.HandleFunc("DELETE /api/v1/orphans/files", s.handleDeleteOrphanFiles)
 
 	// Configure routes for Unknown extensions API
-	mux.HandleFunc("GET /api/unknown/extensions", s.handleUnknownExtensions)
+	mux.HandleFunc("GET /api/v1/unknown/extensions", s.handleUnknownExtensions)
+	mux.HandleFunc("GET /api/v1/stats/extensions", s.handleExtensionStats)
+	mux.HandleFunc("GET /api/v1/stats/history", s.handleLibraryHistory)
 
-	// Build the server address
-	addr := fmt.Sprintf("%s:%d", s.host, s.port)
+	// Configure routes for cleanup jobs API
+	mux.HandleFunc("GET /api/v1/jobs", s.handleCleanupJobs)
+	mux.HandleFunc("POST /api/v1/jobs/{id}/enable", s.handleCleanupJobEnable)
+	mux.HandleFunc("POST /api/v1/jobs/{id}/disable", s.handleCleanupJobDisable)
+	mux.HandleFunc("POST /api/v1/jobs/{id}/schedule", s.handleCleanupJobSchedule)
+	mux.HandleFunc("GET /api/v1/jobs/runs", s.handleCleanupRuns)
+	mux.HandleFunc("GET /api/v1/jobs/runs/{id}/files", s.handleCleanupRunFiles)
 
-	// Log server startup
-	log.Printf("Starting web server on http://%s", addr)
+	// Configure routes for protected paths API
+	mux.HandleFunc("GET /api/v1/protected", s.handleProtectedPaths)
+	mux.HandleFunc("POST /api/v1/protected", s.handleAddProtectedPath)
+	mux.HandleFunc("DELETE /api/v1/protected/{id}", s.handleRemoveProtectedPath)
+
+	// Configure routes for the orphan ignore list API
+	mux.HandleFunc("GET /api/v1/ignored", s.handleIgnoredPaths)
+	mux.HandleFunc("POST /api/v1/ignored", s.handleAddIgnoredPath)
+	mux.HandleFunc("DELETE /api/v1/ignored/{id}", s.handleRemoveIgnoredPath)
+	mux.HandleFunc("GET /api/v1/views", s.handleSavedViews)
+	mux.HandleFunc("POST /api/v1/views", s.handleCreateSavedView)
+	mux.HandleFunc("POST /api/v1/views/{id}", s.handleUpdateSavedView)
+	mux.HandleFunc("DELETE /api/v1/views/{id}", s.handleDeleteSavedView)
+
+	// Configure routes for quarantine API
+	mux.HandleFunc("GET /api/v1/quarantine", s.handleQuarantine)
+	mux.HandleFunc("POST /api/v1/quarantine/restore", s.handleQuarantineRestore)
+
+	// Configure routes for cleanup simulation API
+	mux.HandleFunc("GET /api/v1/simulate", s.handleSimulate)
+
+	// Configure routes for database backup API
+	mux.HandleFunc("GET /api/v1/admin/backup", s.handleBackup)
+
+	// Configure routes for runtime settings API
+	mux.HandleFunc("GET /api/v1/config", s.handleSettings)
+	mux.HandleFunc("PUT /api/v1/config", s.handleUpdateSettings)
+
+	// Keep the unversioned paths of the first API working
+	legacy := legacyAPI(mux)
+	for _, method := range []string{"GET", "POST", "PUT", "DELETE"} {
+		mux.Handle(method+" /api/", legacy)
+	}
+
+	return s.cors(s.requireAuth(mux))
+}
+
+// legacyAPI serves the unversioned /api/... paths by forwarding them to their
+// /api/v1/... equivalent. Responses are flagged as deprecated and point to the
+// new path so clients can migrate.
+func legacyAPI(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Unknown versioned paths must not be forwarded again
+		if r.URL.Path == "/api/v1" || strings.HasPrefix(r.URL.Path, "/api/v1/") {
+			writeError(w, 404, "Not found")
+			return
+		}
+
+		path := "/api/v1" + strings.TrimPrefix(r.URL.Path, "/api")
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", path))
 
-	// Start the HTTP server
-	return http.ListenAndServe(addr, mux)
+		r2 := r.Clone(r.Context())
+		r2.URL.Path = path
+		r2.URL.RawPath = ""
+		mux.ServeHTTP(w, r2)
+	})
 }
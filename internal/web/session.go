@@ -0,0 +1,151 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"godatacleaner/internal/auth"
+	"godatacleaner/internal/models"
+	"godatacleaner/internal/storage"
+)
+
+// sessionCookie is the name of the cookie holding the session token.
+const sessionCookie = "gdc_session"
+
+// Sessions expire after a period of inactivity: a few hours by default, or a
+// month with "remember me". The expiration is pushed back at most once a minute.
+const (
+	sessionTTL       = 12 * time.Hour
+	rememberTTL      = 30 * 24 * time.Hour
+	sessionExtension = time.Minute
+)
+
+// activityTimeout bounds the writes recording the activity of a session or an
+// API key. A sync writes its result in batches, each holding the only writer
+// connection (see storage.SyncTx), and requests should not wait for a long one
+// just to push back a date: the next request retries.
+const activityTimeout = time.Second
+
+// sessionTTLFor returns the inactivity timeout of a session.
+func sessionTTLFor(remember bool) time.Duration {
+	if remember {
+		return rememberTTL
+	}
+	return sessionTTL
+}
+
+// authenticateSession returns the user of a session token, or nil when the
+// session expired or its user no longer exists. The role is read from the
+// user on every request, so role changes apply to open sessions.
+func (s *Server) authenticateSession(ctx context.Context, token string) (*models.User, error) {
+	hash := auth.HashToken(token)
+	sess, err := s.storage.GetSession(ctx, hash)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{Username: sess.Username, Role: models.RoleAdmin}
+	if s.authUsername == "" || sess.Username != s.authUsername {
+		user, err = s.storage.GetUserByUsername(ctx, sess.Username)
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if ttl := sessionTTLFor(sess.Remember); time.Until(sess.ExpiresAt) < ttl-sessionExtension {
+		ctx, cancel := context.WithTimeout(ctx, activityTimeout)
+		defer cancel()
+		if err := s.storage.ExtendSession(ctx, hash, ttl, sessionExtension); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+	}
+	return user, nil
+}
+
+// setSessionCookie sends the session cookie. Without "remember me" it is a
+// browser session cookie, dropped when the browser closes.
+func setSessionCookie(w http.ResponseWriter, r *http.Request, token string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Remember bool   `json:"remember"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, 400, "Invalid JSON body")
+		return
+	}
+
+	enabled, err := s.authEnabled(r.Context())
+	if err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	if !enabled {
+		writeError(w, 400, "Authentication is disabled")
+		return
+	}
+
+	user, err := s.checkCredentials(r.Context(), req.Username, req.Password)
+	if err != nil {
+		writeError(w, 500, err.Error())
+		return
+	}
+	if user == nil {
+		writeError(w, 401, "Invalid username or password")
+		return
+	}
+
+	if err := s.storage.DeleteExpiredSessions(r.Context()); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+	token, hash, err := auth.GenerateSessionToken()
+	if err != nil {
+		writeError(w, 500, "Failed to create session")
+		return
+	}
+	ttl := sessionTTLFor(req.Remember)
+	if err := s.storage.CreateSession(r.Context(), hash, user.Username, req.Remember, ttl); err != nil {
+		writeError(w, 500, "Failed to create session")
+		return
+	}
+
+	maxAge := 0
+	if req.Remember {
+		maxAge = int(ttl.Seconds())
+	}
+	setSessionCookie(w, r, token, maxAge)
+	writeJSON(w, 200, models.CurrentUserResponse{Username: user.Username, Role: user.Role, AuthEnabled: true})
+}
+
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		if err := s.storage.DeleteSession(r.Context(), auth.HashToken(cookie.Value)); err != nil {
+			writeError(w, 500, "Failed to delete session")
+			return
+		}
+	}
+	setSessionCookie(w, r, "", -1)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,85 @@
+package web
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+
+	"godatacleaner/internal/config"
+)
+
+// settingsEditor holds the running configuration edited from the WebUI, see
+// WithSettings.
+type settingsEditor struct {
+	mu    sync.Mutex
+	cfg   *config.Config
+	path  string
+	apply func(*config.Config)
+}
+
+// settingsResponse is the response of the settings endpoints.
+type settingsResponse struct {
+	config.Settings
+	ConfigPath string   `json:"config_path"`
+	Overridden []string `json:"overridden"` // Keys set by environment variables, which win on the next start
+}
+
+// WithSettings enables the settings endpoints: cfg is the running
+// configuration, saved to the config file at path when changed, and apply
+// makes a changed configuration take effect without a restart.
+func (s *Server) WithSettings(cfg *config.Config, path string, apply func(*config.Config)) *Server {
+	s.settings = &settingsEditor{cfg: cfg, path: path, apply: apply}
+	return s
+}
+
+func (s *Server) handleSettings(w http.ResponseWriter, r *http.Request) {
+	if s.settings == nil {
+		writeError(w, 503, "Settings not configured")
+		return
+	}
+	s.settings.mu.Lock()
+	defer s.settings.mu.Unlock()
+	writeJSON(w, 200, s.settings.response())
+}
+
+// handleUpdateSettings validates the settings, writes them to the config file,
+// then applies them. The configuration is left unchanged when the file cannot
+// be written.
+func (s *Server) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
+	if s.settings == nil {
+		writeError(w, 503, "Settings not configured")
+		return
+	}
+	var req config.Settings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, 400, "Invalid JSON body")
+		return
+	}
+
+	s.settings.mu.Lock()
+	defer s.settings.mu.Unlock()
+	next, err := s.settings.cfg.WithSettings(req)
+	if err != nil {
+		writeError(w, 400, err.Error())
+		return
+	}
+	if err := config.SaveSettings(s.settings.path, next.Settings()); err != nil {
+		log.Printf("⚠️  Failed to save settings to %s: %v", s.settings.path, err)
+		writeError(w, 500, "Failed to save config file")
+		return
+	}
+	s.settings.cfg = next
+	s.settings.apply(next)
+	log.Printf("⚙️  Settings saved to %s", s.settings.path)
+	writeJSON(w, 200, s.settings.response())
+}
+
+// response returns the current settings. The caller holds mu.
+func (e *settingsEditor) response() settingsResponse {
+	return settingsResponse{
+		Settings:   e.cfg.Settings(),
+		ConfigPath: e.path,
+		Overridden: config.OverriddenSettings(),
+	}
+}
@@ -0,0 +1,34 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+)
+
+func (s *Server) handleSimulate(w http.ResponseWriter, r *http.Request) {
+	if s.cleaner == nil {
+		writeError(w, 503, "Cleaner not configured")
+		return
+	}
+
+	top := 0
+	if v := r.URL.Query().Get("top"); v != "" {
+		if t, err := strconv.Atoi(v); err == nil && t > 0 && t <= 1000 {
+			top = t
+		}
+	}
+
+	report, err := s.cleaner.Simulate(r.Context(), top)
+	if err != nil {
+		writeError(w, 500, "Failed to simulate cleanup")
+		return
+	}
+
+	if r.URL.Query().Get("format") == "html" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		report.WriteHTML(w)
+		return
+	}
+	writeJSON(w, 200, report)
+}
@@ -0,0 +1,421 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"godatacleaner/internal/models"
+	"godatacleaner/internal/scanner"
+	"godatacleaner/internal/storage"
+	"godatacleaner/internal/syncer"
+)
+
+// Sync statuses
+const (
+	syncRunning   = "running"
+	syncDone      = "done"
+	syncFailed    = "failed"
+	syncCancelled = "cancelled"
+)
+
+// maxSyncHistory is the number of finished syncs kept in memory.
+const maxSyncHistory = 20
+
+// syncJob is a sync started from the API.
+type syncJob struct {
+	ID         int64           `json:"id"`
+	Status     string          `json:"status"`
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+	Progress   syncer.Progress `json:"progress"`
+	Result     *syncer.Result  `json:"result,omitempty"`
+	Error      string          `json:"error,omitempty"`
+
+	cancel context.CancelFunc
+}
+
+// syncManager runs at most one sync at a time in the background.
+type syncManager struct {
+	mu      sync.Mutex
+	nextID  int64
+	current *syncJob
+	jobs    map[int64]*syncJob
+	order   []int64
+	subs    map[chan syncJob]struct{}
+}
+
+func newSyncManager() *syncManager {
+	return &syncManager{
+		jobs: make(map[int64]*syncJob),
+		subs: make(map[chan syncJob]struct{}),
+	}
+}
+
+// start launches a sync unless one is already running, in which case
+// the running job is returned with started set to false.
+func (m *syncManager) start(s *syncer.Syncer) (job syncJob, started bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.current != nil {
+		return *m.current, false
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.nextID++
+	j := &syncJob{ID: m.nextID, Status: syncRunning, StartedAt: time.Now(), cancel: cancel}
+	m.current = j
+	m.jobs[j.ID] = j
+	m.order = append(m.order, j.ID)
+	if len(m.order) > maxSyncHistory {
+		delete(m.jobs, m.order[0])
+		m.order = m.order[1:]
+	}
+
+	go m.run(ctx, s, j)
+	return *j, true
+}
+
+func (m *syncManager) run(ctx context.Context, s *syncer.Syncer, j *syncJob) {
+	defer j.cancel()
+
+	result, err := s.Run(ctx, func(p syncer.Progress) {
+		m.mu.Lock()
+		j.Progress = p
+		m.publish(*j)
+		m.mu.Unlock()
+	})
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	j.FinishedAt = &now
+	j.Result = result
+	switch {
+	case errors.Is(err, context.Canceled):
+		j.Status = syncCancelled
+		log.Printf("⏹️  Synchronisation %d annulée", j.ID)
+	case err != nil:
+		j.Status = syncFailed
+		j.Error = err.Error()
+		log.Printf("⚠️  Synchronisation %d en échec: %v", j.ID, err)
+	default:
+		j.Status = syncDone
+	}
+	m.current = nil
+	m.publish(*j)
+}
+
+// publish sends a job update to every subscriber. Subscribers only need the
+// latest state, so a pending update that was not read yet is replaced.
+// Must be called with m.mu held.
+func (m *syncManager) publish(j syncJob) {
+	for ch := range m.subs {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- j
+	}
+}
+
+// subscribe returns the current sync, or the latest one when none is running,
+// and a channel receiving its updates. ok is false when no sync ever ran.
+func (m *syncManager) subscribe() (job syncJob, updates chan syncJob, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j := m.latestLocked()
+	if j == nil {
+		return syncJob{}, nil, false
+	}
+
+	updates = make(chan syncJob, 1)
+	m.subs[updates] = struct{}{}
+	return *j, updates, true
+}
+
+// latest returns a copy of the current sync, or of the latest one when none
+// is running. ok is false when no sync ever ran.
+func (m *syncManager) latest() (syncJob, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j := m.latestLocked()
+	if j == nil {
+		return syncJob{}, false
+	}
+	return *j, true
+}
+
+// running returns a copy of the running sync. ok is false when none is running.
+func (m *syncManager) running() (syncJob, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.current == nil {
+		return syncJob{}, false
+	}
+	return *m.current, true
+}
+
+// latestLocked returns the current or latest sync, or nil.
+// Must be called with m.mu held.
+func (m *syncManager) latestLocked() *syncJob {
+	if m.current == nil && len(m.order) > 0 {
+		return m.jobs[m.order[len(m.order)-1]]
+	}
+	return m.current
+}
+
+func (m *syncManager) unsubscribe(ch chan syncJob) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.subs, ch)
+}
+
+// cancelCurrent cancels the running sync. The sync stops at its next step and
+// its pending database writes are rolled back. ok is false when no sync is running.
+func (m *syncManager) cancelCurrent() (job syncJob, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.current == nil {
+		return syncJob{}, false
+	}
+	m.current.cancel()
+	return *m.current, true
+}
+
+// get returns a copy of a sync job.
+func (m *syncManager) get(id int64) (syncJob, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, ok := m.jobs[id]
+	if !ok {
+		return syncJob{}, false
+	}
+	return *j, true
+}
+
+func (s *Server) handleStartSync(w http.ResponseWriter, r *http.Request) {
+	if s.syncer == nil {
+		writeError(w, 503, "Sync not configured")
+		return
+	}
+
+	job, started := s.syncs.start(s.syncer)
+	if !started {
+		writeJSON(w, 409, job)
+		return
+	}
+	writeJSON(w, 202, job)
+}
+
+// StartSync starts a sync as POST /api/v1/sync does, so that the WebUI
+// follows its progress. It reports false when a sync is already running or
+// no syncer is configured.
+func (s *Server) StartSync() bool {
+	if s.syncer == nil {
+		return false
+	}
+	_, started := s.syncs.start(s.syncer)
+	return started
+}
+
+func (s *Server) handleCancelSync(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.syncs.cancelCurrent()
+	if !ok {
+		writeError(w, 409, "No sync is running")
+		return
+	}
+	writeJSON(w, 202, job)
+}
+
+func (s *Server) handleSyncStatus(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(r)
+	if !ok {
+		writeError(w, 400, "Invalid sync id")
+		return
+	}
+	job, ok := s.syncs.get(id)
+	if !ok {
+		writeError(w, 404, "Sync not found")
+		return
+	}
+	writeJSON(w, 200, job)
+}
+
+// syncState is the response of GET /api/v1/sync/status.
+type syncState struct {
+	Current *syncJob        `json:"current"`  // Sync running from the API, null when none
+	LastRun *models.SyncRun `json:"last_run"` // Latest sync of the history, from any process, null when none
+}
+
+// handleSyncState returns the running sync and the latest one recorded, for
+// the WebUI header to tell how fresh the data is.
+func (s *Server) handleSyncState(w http.ResponseWriter, r *http.Request) {
+	var state syncState
+	if job, ok := s.syncs.running(); ok {
+		state.Current = &job
+	}
+	runs, err := s.storage.ListSyncRuns(r.Context(), 1)
+	if err != nil {
+		writeError(w, 500, "Failed to get sync runs")
+		return
+	}
+	if len(runs) > 0 {
+		state.LastRun = &runs[0]
+	}
+	writeJSON(w, 200, state)
+}
+
+// handleSyncRuns returns the sync history, newest first, including the
+// syncs run by the CLI.
+func (s *Server) handleSyncRuns(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if l, err := strconv.Atoi(v); err == nil && l > 0 && l <= 1000 {
+			limit = l
+		}
+	}
+
+	runs, err := s.storage.ListSyncRuns(context.Background(), limit)
+	if err != nil {
+		writeError(w, 500, "Failed to get sync runs")
+		return
+	}
+	if runs == nil {
+		runs = []models.SyncRun{}
+	}
+	writeJSON(w, 200, models.SyncRunsResponse{Runs: runs})
+}
+
+// handleSyncDiff returns the local files added, removed or resized by a sync
+// since the previous one, "last" standing for the latest sync that recorded
+// them. At most limit files are listed, 1000 by default.
+func (s *Server) handleSyncDiff(w http.ResponseWriter, r *http.Request) {
+	var id int64
+	if r.PathValue("id") != "last" {
+		var ok bool
+		if id, ok = pathID(r); !ok {
+			writeError(w, 400, "Invalid run id")
+			return
+		}
+	}
+	limit := 1000
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if l, err := strconv.Atoi(v); err == nil && l > 0 && l <= 100000 {
+			limit = l
+		}
+	}
+
+	diff, err := s.storage.GetSyncDiff(r.Context(), id, limit)
+	if errors.Is(err, storage.ErrNotFound) {
+		writeError(w, 404, "Sync run not found")
+		return
+	}
+	if err != nil {
+		writeError(w, 500, "Failed to get sync changes")
+		return
+	}
+	writeJSON(w, 200, diff)
+}
+
+// handleSyncErrors returns the torrents and torrent clients a sync could not
+// read, "last" standing for the latest sync.
+func (s *Server) handleSyncErrors(w http.ResponseWriter, r *http.Request) {
+	var id int64
+	if r.PathValue("id") != "last" {
+		var ok bool
+		if id, ok = pathID(r); !ok {
+			writeError(w, 400, "Invalid run id")
+			return
+		}
+	}
+
+	report, err := s.storage.GetSyncErrors(r.Context(), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		writeError(w, 404, "Sync run not found")
+		return
+	}
+	if err != nil {
+		writeError(w, 500, "Failed to get sync errors")
+		return
+	}
+	writeJSON(w, 200, report)
+}
+
+// scanProgress is the progress of the local scan of a sync.
+type scanProgress struct {
+	SyncID  int64 `json:"sync_id"`
+	Running bool  `json:"running"`
+	*scanner.Progress
+}
+
+// handleScanProgress returns the progress of the local scan of the current
+// sync, or of the latest one when none is running.
+func (s *Server) handleScanProgress(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.syncs.latest()
+	if !ok || job.Progress.Scan == nil {
+		writeError(w, 404, "No scan has been started")
+		return
+	}
+	writeJSON(w, 200, scanProgress{
+		SyncID:   job.ID,
+		Running:  job.Status == syncRunning && !job.Progress.Scan.Done,
+		Progress: job.Progress.Scan,
+	})
+}
+
+// handleSyncProgress streams the progress of the current sync as Server-Sent
+// Events: a "progress" event on every update, then a final "done" event.
+func (s *Server) handleSyncProgress(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, 500, "Streaming not supported")
+		return
+	}
+
+	job, updates, ok := s.syncs.subscribe()
+	if !ok {
+		writeError(w, 404, "No sync has been started")
+		return
+	}
+	defer s.syncs.unsubscribe(updates)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	for {
+		event := "progress"
+		if job.Status != syncRunning {
+			event = "done"
+		}
+		data, err := json.Marshal(job)
+		if err != nil {
+			return
+		}
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+		flusher.Flush()
+		if event == "done" {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case job = <-updates:
+		}
+	}
+}
@@ -16,10 +16,10 @@ const indexTemplate = `<!DOCTYPE html>
     <meta charset="UTF-8">
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
     <title>GoDataCleaner</title>
-    <script src="https://unpkg.com/react@18/umd/react.production.min.js" crossorigin></script>
-    <script src="https://unpkg.com/react-dom@18/umd/react-dom.production.min.js" crossorigin></script>
-    <script src="https://unpkg.com/@babel/standalone/babel.min.js"></script>
-    <script src="https://cdn.jsdelivr.net/npm/chart.js"></script>
+    <script src="/assets/react-18.3.1.production.min.js"></script>
+    <script src="/assets/react-dom-18.3.1.production.min.js"></script>
+    <script src="/assets/babel-standalone-7.26.4.min.js"></script>
+    <script src="/assets/chart-4.4.7.umd.js"></script>
     <style>
         * { box-sizing: border-box; margin: 0; padding: 0; }
         body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; background: #1a1a2e; color: #eee; min-height: 100vh; }
@@ -35,20 +35,35 @@ const indexTemplate = `<!DOCTYPE html>
         .card .value { font-size: 28px; font-weight: 700; color: #00d9ff; }
         .card .sub { font-size: 12px; color: #666; margin-top: 4px; }
         .controls { display: flex; gap: 10px; margin-bottom: 15px; flex-wrap: wrap; }
+        .saved-views { display: flex; gap: 10px; align-items: center; }
+        .column-picker { position: relative; }
+        .column-picker .menu { position: absolute; right: 0; top: calc(100% + 5px); z-index: 10; min-width: 200px; padding: 10px 15px; background: #16213e; border: 1px solid #333; border-radius: 8px; }
+        .column-picker .menu label { display: flex; align-items: center; gap: 8px; padding: 4px 0; color: #ccc; font-size: 14px; cursor: pointer; }
+        .column-picker .menu a { display: block; margin-top: 8px; color: #00d9ff; font-size: 13px; }
         .search { flex: 1; min-width: 200px; padding: 10px 15px; background: #16213e; border: 1px solid #333; border-radius: 8px; color: #fff; font-size: 14px; }
         .search:focus { outline: none; border-color: #00d9ff; }
         select { padding: 10px 15px; background: #16213e; border: 1px solid #333; border-radius: 8px; color: #fff; font-size: 14px; cursor: pointer; }
         table { width: 100%; border-collapse: collapse; background: #16213e; border-radius: 12px; overflow: hidden; table-layout: fixed; }
+        .table-scroll { max-height: 75vh; overflow-y: auto; border-radius: 12px; }
+        .table-scroll table { overflow: visible; }
+        .table-scroll th { position: sticky; top: 0; z-index: 1; }
         th, td { padding: 12px 15px; text-align: left; border-bottom: 1px solid #222; overflow: hidden; text-overflow: ellipsis; white-space: nowrap; }
         th { background: #0f1729; color: #888; font-size: 12px; text-transform: uppercase; cursor: pointer; user-select: none; }
         th:hover { color: #00d9ff; }
         tr:hover { background: #1f3460; }
         .size { color: #00d9ff; font-weight: 500; white-space: nowrap; }
-        .category { padding: 4px 8px; border-radius: 4px; font-size: 11px; font-weight: 600; }
+        .category { padding: 4px 8px; border-radius: 4px; font-size: 11px; font-weight: 600; background: #9b59b633; color: #9b59b6; }
         .category.movies { background: #e74c3c33; color: #e74c3c; }
         .category.shows { background: #3498db33; color: #3498db; }
         .category.4k { background: #f39c1233; color: #f39c12; }
         .category.unknown { background: #95a5a633; color: #95a5a6; }
+        .views { display: flex; gap: 10px; margin-bottom: 15px; }
+        .views .tab { padding: 8px 16px; }
+        .state { padding: 4px 8px; border-radius: 4px; font-size: 11px; font-weight: 600; background: #95a5a633; color: #95a5a6; }
+        .state.seeding { background: #2ecc7133; color: #2ecc71; }
+        .state.downloading { background: #3498db33; color: #3498db; }
+        .state.paused { background: #f39c1233; color: #f39c12; }
+        .state.errored { background: #e74c3c33; color: #e74c3c; }
         .pagination { display: flex; justify-content: center; align-items: center; gap: 10px; margin-top: 20px; }
         .pagination button { padding: 8px 16px; background: #16213e; border: 1px solid #333; border-radius: 6px; color: #fff; cursor: pointer; }
         .pagination button:hover:not(:disabled) { background: #1f3460; border-color: #00d9ff; }
@@ -56,8 +71,70 @@ const indexTemplate = `<!DOCTYPE html>
         .pagination span { color: #888; }
         .export-btn { padding: 10px 20px; background: #00d9ff; border: none; border-radius: 8px; color: #1a1a2e; font-weight: 600; cursor: pointer; }
         .export-btn:hover { background: #00b8d9; }
+        .danger-btn { padding: 10px 20px; background: #e74c3c; border: none; border-radius: 8px; color: #fff; font-weight: 600; cursor: pointer; }
+        .danger-btn:hover:not(:disabled) { background: #c0392b; }
+        .danger-btn:disabled { opacity: 0.5; cursor: not-allowed; }
+        .row-btn { padding: 4px 10px; background: none; border: 1px solid #333; border-radius: 6px; color: #888; cursor: pointer; font-size: 12px; }
+        .row-btn:hover { border-color: #00d9ff; color: #00d9ff; }
+        .confirm { background: #16213e; border: 1px solid #e74c3c; padding: 15px 20px; border-radius: 12px; margin-bottom: 15px; display: flex; align-items: center; gap: 15px; flex-wrap: wrap; }
+        .confirm label { color: #888; font-size: 14px; }
+        .selection { background: #16213e; padding: 10px 20px; border-radius: 8px; margin-bottom: 15px; display: flex; align-items: center; gap: 15px; font-size: 14px; }
+        .selection a { color: #00d9ff; }
+        .drawer-overlay { position: fixed; inset: 0; background: #0008; z-index: 10; }
+        .drawer { position: fixed; top: 0; right: 0; bottom: 0; width: 520px; max-width: 100%; background: #16213e; border-left: 1px solid #00d9ff55; padding: 20px; overflow-y: auto; z-index: 11; font-size: 14px; }
+        .drawer .head { display: flex; justify-content: space-between; align-items: flex-start; gap: 15px; margin-bottom: 15px; }
+        .drawer .head h2 { font-size: 16px; color: #00d9ff; word-break: break-all; }
+        .drawer h3 { color: #888; font-size: 12px; text-transform: uppercase; margin: 20px 0 8px; }
+        .drawer dl { display: grid; grid-template-columns: 130px 1fr; gap: 6px 10px; }
+        .drawer dt { color: #888; }
+        .drawer dd { word-break: break-all; }
+        .drawer ul { list-style: none; }
+        .drawer li { padding: 6px 0; border-top: 1px solid #222; }
+        .drawer li .sub { color: #666; font-size: 12px; word-break: break-all; }
+        .drawer .status.orphan { color: #e74c3c; }
+        .drawer .status.matched { color: #2ecc71; }
+        .treemap { position: relative; height: 500px; background: #16213e; border-radius: 12px; overflow: hidden; }
+        .treemap .cell { position: absolute; border: 1px solid #1a1a2e; padding: 4px 6px; overflow: hidden; font-size: 12px; color: #eee; }
+        .treemap .cell.dir { cursor: pointer; }
+        .treemap .cell.dir:hover { filter: brightness(1.25); }
+        .treemap .cell .sub { color: #ddd; opacity: 0.7; }
+        .legend { display: flex; align-items: center; gap: 10px; font-size: 12px; color: #888; margin-top: 10px; }
+        .legend .scale { width: 160px; height: 10px; border-radius: 5px; background: linear-gradient(to right, hsl(120, 60%, 35%), hsl(60, 60%, 35%), hsl(0, 60%, 35%)); }
+        .missing .files td { background: #0f1729; font-size: 13px; }
+        .missing .absent { color: #e74c3c; }
+        .missing .mismatch { color: #f39c12; }
+        .duplicates .group td { background: #16213e; font-weight: 600; }
+        .duplicates .deletable { color: #e74c3c; }
+        .duplicates .kept { color: #2ecc71; }
         .chart-container { background: #16213e; padding: 20px; border-radius: 12px; height: 400px; }
         .loading { text-align: center; padding: 40px; color: #888; }
+        .header { display: flex; justify-content: space-between; align-items: center; margin-bottom: 20px; }
+        .header h1 { margin-bottom: 0; }
+        .header-right { display: flex; align-items: center; }
+        .user { color: #888; font-size: 14px; margin-right: 15px; }
+        .header .language { margin-left: 15px; }
+        .login .language { width: 100%; margin-top: 15px; }
+        .sync { display: flex; align-items: center; gap: 15px; color: #888; font-size: 14px; }
+        .sync button:disabled { opacity: 0.5; cursor: not-allowed; }
+        .sync .last-run.warn { color: #f39c12; }
+        .sync .last-run.link { cursor: pointer; text-decoration: underline dotted; }
+        .sync-bar { width: 200px; height: 8px; background: #16213e; border-radius: 4px; overflow: hidden; }
+        .login { max-width: 360px; margin: 80px auto; background: #16213e; border-radius: 12px; padding: 30px; }
+        .login input[type=text], .login input[type=password] { width: 100%; padding: 10px 15px; margin-bottom: 15px; background: #1a1a2e; border: 1px solid #2a2a4e; border-radius: 8px; color: #eee; font-size: 14px; }
+        .login label { display: block; color: #888; font-size: 14px; margin-bottom: 15px; }
+        .login button { width: 100%; }
+        .login .error { color: #e74c3c; margin-bottom: 15px; }
+        .new-key { background: #16213e; border: 1px solid #00d9ff; border-radius: 8px; padding: 15px; margin-bottom: 20px; }
+        .settings section { background: #16213e; border-radius: 12px; padding: 20px; margin-bottom: 20px; }
+        .settings h2 { color: #00d9ff; font-size: 16px; margin-bottom: 6px; }
+        .settings .help { color: #888; font-size: 13px; margin-bottom: 12px; }
+        .settings .row { display: flex; gap: 10px; margin-bottom: 8px; align-items: center; }
+        .settings .row input { flex: 1; padding: 8px 12px; background: #1a1a2e; border: 1px solid #333; border-radius: 6px; color: #fff; font-size: 14px; }
+        .settings .row input.narrow { flex: 0 0 90px; }
+        .settings .warning { color: #f39c12; font-size: 13px; margin-bottom: 12px; }
+        .settings .actions { display: flex; gap: 15px; align-items: center; margin-bottom: 20px; }
+        .new-key code { display: block; margin-top: 8px; color: #00d9ff; word-break: break-all; user-select: all; }
+        .sync-bar div { height: 100%; background: #00d9ff; transition: width 0.3s; }
     </style>
 </head>
 <body>
@@ -65,6 +142,38 @@ const indexTemplate = `<!DOCTYPE html>
     <script type="text/babel">
         const { useState, useEffect, useRef } = React;
 
+        // Traductions de l'interface, chargées depuis /api/v1/i18n
+        let messages = {};
+        let languages = [];
+        let language = '';
+
+        // t returns the message of key in the language of the interface, with
+        // its {placeholders} replaced by params. React elements may be given
+        // as params, the message is then returned as a list of nodes.
+        function t(key, params) {
+            const msg = messages[key] || key;
+            if (!params) return msg;
+            const parts = msg.split(/\{(\w+)\}/);
+            if (Object.values(params).some(v => typeof v === 'object')) {
+                return parts.map((part, i) => i % 2 ? <span key={i}>{params[part]}</span> : part);
+            }
+            return parts.map((part, i) => i % 2 ? (part in params ? params[part] : '{' + part + '}') : part).join('');
+        }
+
+        // LanguageSelect switches the language of the interface. The choice is
+        // kept in the browser, instead of the language it prefers.
+        function LanguageSelect() {
+            const change = (code) => {
+                localStorage.setItem('lang', code);
+                loadLanguage(code);
+            };
+            return (
+                <select className="language" value={language} onChange={e => change(e.target.value)}>
+                    {languages.map(l => <option key={l.code} value={l.code}>{l.name}</option>)}
+                </select>
+            );
+        }
+
         function formatSize(bytes) {
             if (bytes === 0) return '0 B';
             const k = 1024;
@@ -73,6 +182,160 @@ const indexTemplate = `<!DOCTYPE html>
             return parseFloat((bytes / Math.pow(k, i)).toFixed(2)) + ' ' + sizes[i];
         }
 
+        function formatDuration(seconds) {
+            if (!seconds) return '-';
+            const days = Math.floor(seconds / 86400);
+            const hours = Math.floor((seconds % 86400) / 3600);
+            if (days > 0) return t('duration.days', { days, hours });
+            return t('duration.hours', { hours, minutes: Math.floor((seconds % 3600) / 60) });
+        }
+
+        // Âge d'un fichier d'après sa date de modification (zéro si inconnue)
+        function formatAge(modTime) {
+            const t = Date.parse(modTime);
+            if (!(t > 0)) return '-';
+            return formatDuration(Math.max(60, Math.floor((Date.now() - t) / 1000)));
+        }
+
+        // CategorySelect lists the categories of the given stats, which
+        // depend on the category rules of the configuration.
+        function CategorySelect({ value, stats, onChange }) {
+            return (
+                <select value={value} onChange={e => onChange(e.target.value)}>
+                    <option value="">{t('filters.all_categories')}</option>
+                    {stats.map(s => <option key={s.category} value={s.category}>{s.category.toUpperCase()}</option>)}
+                </select>
+            );
+        }
+
+        // statsCategories returns the categories of the local stats, then
+        // those only found in the orphan stats.
+        const statsCategories = (localStats, orphanStats) => [...new Set([...localStats, ...orphanStats].map(s => s.category))];
+
+        const categoryColors = { '4k': '#f39c12', 'movies': '#e74c3c', 'shows': '#3498db', 'unknown': '#95a5a6' };
+        const extraColors = ['#9b59b6', '#1abc9c', '#e67e22', '#2ecc71', '#e84393', '#00cec9'];
+        const categoryColor = (category, i) => categoryColors[category] || extraColors[i % extraColors.length];
+
+        function AgeSelect({ value, onChange, all }) {
+            return (
+                <select value={value} onChange={e => onChange(e.target.value)}>
+                    <option value="">{all || t('filters.all_ages')}</option>
+                    <option value="30">{t('filters.older_than_days', { days: 30 })}</option>
+                    <option value="90">{t('filters.older_than_days', { days: 90 })}</option>
+                    <option value="180">{t('filters.older_than_days', { days: 180 })}</option>
+                    <option value="365">{t('filters.older_than_year')}</option>
+                </select>
+            );
+        }
+
+        const sizeSteps = [100 * 1024 * 1024, 1024 * 1024 * 1024, 10 * 1024 * 1024 * 1024, 50 * 1024 * 1024 * 1024];
+
+        function SizeSelect({ value, onChange, all, max }) {
+            // Une vue enregistrée peut porter une taille hors de la liste
+            const steps = value && !sizeSteps.includes(Number(value)) ? [...sizeSteps, Number(value)].sort((a, b) => a - b) : sizeSteps;
+            return (
+                <select value={value} onChange={e => onChange(e.target.value)}>
+                    <option value="">{all}</option>
+                    {steps.map(b => <option key={b} value={b}>{t(max ? 'filters.smaller_than' : 'filters.larger_than', { size: formatSize(b) })}</option>)}
+                </select>
+            );
+        }
+
+        function SavedViews({ list, admin, current, onApply }) {
+            const [views, setViews] = useState([]);
+            const [selected, setSelected] = useState('');
+            const [reload, setReload] = useState(0);
+
+            useEffect(() => {
+                fetch('/api/v1/views?list=' + list).then(r => r.json()).then(d => setViews(d.views || []));
+            }, [list, reload]);
+
+            const apply = (id) => {
+                setSelected(id);
+                const view = views.find(v => String(v.id) === id);
+                if (view) onApply(view);
+            };
+            const save = () => {
+                const name = prompt(t('views.name_prompt'));
+                if (!name) return;
+                fetch('/api/v1/views', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({ ...current, name, list })
+                }).then(r => r.json()).then(d => {
+                    if (d.error) { alert(t('common.error', { error: d.error })); return; }
+                    setSelected(String(d.id));
+                    setReload(reload + 1);
+                });
+            };
+            const remove = () => {
+                const view = views.find(v => String(v.id) === selected);
+                if (!view || !confirm(t('views.confirm_delete', { name: view.name }))) return;
+                fetch('/api/v1/views/' + view.id, { method: 'DELETE' }).then(r => {
+                    if (!r.ok) { r.json().then(d => alert(t('common.error', { error: d.error }))); return; }
+                    setSelected('');
+                    setReload(reload + 1);
+                });
+            };
+
+            return (
+                <div className="saved-views">
+                    <select value={selected} onChange={e => apply(e.target.value)}>
+                        <option value="">{t('views.saved')}</option>
+                        {views.map(v => <option key={v.id} value={v.id}>{v.name}</option>)}
+                    </select>
+                    {admin && <button className="row-btn" onClick={save}>{t('views.save')}</button>}
+                    {admin && selected && <button className="row-btn" onClick={remove}>{t('views.delete')}</button>}
+                </div>
+            );
+        }
+
+        // Colonnes affichées par l'utilisateur dans une liste, enregistrées côté
+        // serveur ; celles de defaults tant qu'il ne les a pas choisies
+        function useColumnLayout(list, defaults) {
+            const [layout, setLayout] = useState(null);
+
+            useEffect(() => {
+                fetch('/api/v1/me/columns').then(r => r.json()).then(d => setLayout((d.layouts || {})[list] || null));
+            }, [list]);
+
+            const save = (columns) => {
+                setLayout(columns);
+                const request = columns
+                    ? fetch('/api/v1/me/columns/' + list, { method: 'POST', headers: { 'Content-Type': 'application/json' }, body: JSON.stringify({ columns }) })
+                    : fetch('/api/v1/me/columns/' + list, { method: 'DELETE' });
+                request.then(r => { if (!r.ok) r.json().then(d => alert(t('common.error', { error: d.error }))); });
+            };
+            return [layout || defaults, save];
+        }
+
+        // Définitions des colonnes de shown, dans son ordre
+        const shownColumns = (columns, shown) => shown.map(key => columns.find(c => c.key === key)).filter(Boolean);
+
+        function ColumnPicker({ columns, shown, onChange }) {
+            const [open, setOpen] = useState(false);
+            // Les colonnes gardent l'ordre de la liste complète
+            const toggle = (key, checked) => onChange(columns.filter(c => c.key === key ? checked : shown.includes(c.key)).map(c => c.key));
+            return (
+                <div className="column-picker">
+                    <button className="row-btn" onClick={() => setOpen(!open)}>{t('columns.choose')} ▾</button>
+                    {open && (
+                        <div className="menu">
+                            {columns.map(c => (
+                                <label key={c.key}>
+                                    <input type="checkbox" checked={shown.includes(c.key)} disabled={shown.length === 1 && shown.includes(c.key)} onChange={e => toggle(c.key, e.target.checked)} /> {c.label}
+                                </label>
+                            ))}
+                            <a href="#" onClick={e => { e.preventDefault(); onChange(null); }}>{t('columns.reset')}</a>
+                        </div>
+                    )}
+                </div>
+            );
+        }
+
+        const stateLabels = { seeding: 'state.seeding', downloading: 'state.downloading', paused: 'state.paused', queued: 'state.queued', checking: 'state.checking', errored: 'state.errored', unknown: 'state.unknown' };
+        const stateLabel = (state) => stateLabels[state] ? t(stateLabels[state]) : state;
+
         function Card({ title, value, sub }) {
             return (
                 <div className="card">
@@ -83,22 +346,61 @@ const indexTemplate = `<!DOCTYPE html>
             );
         }
 
-        function DataTable({ data, columns, sort, order, onSort, loading }) {
-            if (loading) return <div className="loading">Chargement...</div>;
+        const exportFormats = ['csv', 'xlsx', 'json', 'ndjson', 'txt'];
+
+        // Lien de téléchargement des lignes correspondant aux filtres, avec les
+        // colonnes affichées
+        function ExportLink({ url, query, columns }) {
+            const [format, setFormat] = useState('csv');
             return (
+                <div className="saved-views">
+                    <select value={format} onChange={e => setFormat(e.target.value)} title={t('export.format')}>
+                        {exportFormats.map(f => <option key={f} value={f}>{f.toUpperCase()}</option>)}
+                    </select>
+                    <a href={url + '?format=' + format + '&columns=' + columns.join(',') + '&' + query} className="export-btn">{t('export.download')}</a>
+                </div>
+            );
+        }
+
+        // Au-delà de virtualRows lignes, le tableau défile dans un cadre et seules
+        // les lignes visibles sont rendues, entre deux lignes d'espacement
+        const virtualRows = 200;
+        const rowHeight = 45;
+        const overscanRows = 20;
+
+        function DataTable({ data, columns, sort, order, onSort, loading }) {
+            const scroller = useRef(null);
+            const [scrollTop, setScrollTop] = useState(0);
+            const [viewport, setViewport] = useState(800);
+            const virtual = data.length > virtualRows;
+
+            // Une nouvelle page repart du haut
+            useEffect(() => {
+                setScrollTop(0);
+                if (!scroller.current) return;
+                scroller.current.scrollTop = 0;
+                setViewport(scroller.current.clientHeight);
+            }, [data, loading]);
+
+            if (loading) return <div className="loading">{t('common.loading')}</div>;
+
+            const first = virtual ? Math.max(0, Math.floor(scrollTop / rowHeight) - overscanRows) : 0;
+            const last = virtual ? Math.min(data.length, Math.ceil((scrollTop + viewport) / rowHeight) + overscanRows) : data.length;
+            const table = (
                 <table>
                     <thead>
                         <tr>
                             {columns.map(col => (
-                                <th key={col.key} onClick={() => onSort(col.key)}>
+                                <th key={col.key} onClick={() => col.sortable !== false && onSort(col.key)} style={col.width ? {width: col.width} : undefined}>
                                     {col.label} {sort === col.key ? (order === 'asc' ? '↑' : '↓') : ''}
                                 </th>
                             ))}
                         </tr>
                     </thead>
                     <tbody>
-                        {data.map((row, i) => (
-                            <tr key={i}>
+                        {first > 0 && <tr style={{height: first * rowHeight}} />}
+                        {data.slice(first, last).map((row, i) => (
+                            <tr key={first + i} style={virtual ? {height: rowHeight} : undefined}>
                                 {columns.map(col => (
                                     <td key={col.key} className={col.className}>
                                         {col.render ? col.render(row[col.key], row) : row[col.key]}
@@ -106,39 +408,101 @@ const indexTemplate = `<!DOCTYPE html>
                                 ))}
                             </tr>
                         ))}
+                        {last < data.length && <tr style={{height: (data.length - last) * rowHeight}} />}
                     </tbody>
                 </table>
             );
+            if (!virtual) return table;
+            return <div className="table-scroll" ref={scroller} onScroll={e => setScrollTop(e.target.scrollTop)}>{table}</div>;
         }
 
-        function Pagination({ page, totalPages, onPageChange }) {
+        const pageSizes = [50, 200, 1000, 10000];
+
+        function Pagination({ page, totalPages, onPageChange, perPage, onPerPageChange }) {
             return (
                 <div className="pagination">
                     <button onClick={() => onPageChange(1)} disabled={page <= 1}>««</button>
                     <button onClick={() => onPageChange(page - 1)} disabled={page <= 1}>«</button>
-                    <span>Page {page} / {totalPages || 1}</span>
+                    <span>{t('pagination.page', { page, total: totalPages || 1 })}</span>
                     <button onClick={() => onPageChange(page + 1)} disabled={page >= totalPages}>»</button>
                     <button onClick={() => onPageChange(totalPages)} disabled={page >= totalPages}>»»</button>
+                    {onPerPageChange && (
+                        <select value={perPage} onChange={e => onPerPageChange(Number(e.target.value))}>
+                            {pageSizes.map(n => <option key={n} value={n}>{t('pagination.per_page', { count: n.toLocaleString() })}</option>)}
+                        </select>
+                    )}
+                </div>
+            );
+        }
+
+        function TorrentsTab({ admin }) {
+            const [view, setView] = useState('files');
+            return (
+                <div>
+                    <div className="views">
+                        <button className={'tab' + (view === 'files' ? ' active' : '')} onClick={() => setView('files')}>{t('torrents.view_files')}</button>
+                        <button className={'tab' + (view === 'torrents' ? ' active' : '')} onClick={() => setView('torrents')}>{t('torrents.view_torrents')}</button>
+                        <button className={'tab' + (view === 'completeness' ? ' active' : '')} onClick={() => setView('completeness')}>{t('torrents.view_completeness')}</button>
+                    </div>
+                    {view === 'files' && <TorrentFilesView />}
+                    {view === 'torrents' && <TorrentListView admin={admin} />}
+                    {view === 'completeness' && <CompletenessView admin={admin} />}
+                </div>
+            );
+        }
+
+        // Suppression d'un torrent de son client. Les fichiers ne sont supprimés
+        // qu'après l'aperçu du serveur, confirmé avec son jeton, et jamais
+        // quand l'un d'eux est protégé
+        function DeleteTorrentConfirm({ torrent, filesLabel, onDone }) {
+            const [deleteFiles, setDeleteFiles] = useState(false);
+            const [deleting, setDeleting] = useState(false);
+
+            const remove = () => {
+                const url = '/api/v1/torrent/torrents/' + encodeURIComponent(torrent.instance) + '/' + torrent.hash + '?delete_files=' + deleteFiles;
+                const request = (query) => fetch(url + query, { method: 'DELETE' }).then(r => r.status === 204 ? {} : r.json());
+                const done = (d) => {
+                    if (d.error) alert(t('common.error', { error: d.error }));
+                    setDeleting(false);
+                    onDone(true);
+                };
+                setDeleting(true);
+                if (!deleteFiles) return request('').then(done);
+                request('').then(preview => {
+                    if (preview.error) return done(preview);
+                    if (!confirm(t('torrents.confirm_delete_files', { files: preview.files, size: formatSize(preview.bytes) }))) return setDeleting(false);
+                    request('&token=' + preview.token).then(done);
+                });
+            };
+
+            return (
+                <div className="confirm">
+                    <span>{t('torrents.confirm_delete', { name: <strong>{torrent.name}</strong>, instance: torrent.instance })}</span>
+                    <label><input type="checkbox" checked={deleteFiles} onChange={e => setDeleteFiles(e.target.checked)} /> {filesLabel}</label>
+                    <button className="danger-btn" onClick={remove} disabled={deleting}>{t('common.confirm')}</button>
+                    <button className="row-btn" onClick={() => onDone(false)} disabled={deleting}>{t('common.cancel')}</button>
                 </div>
             );
         }
 
-        function TorrentsTab() {
+        function TorrentListView({ admin }) {
             const [data, setData] = useState([]);
-            const [stats, setStats] = useState({ total_files: 0, total_torrents: 0, total_size: 0 });
+            const [states, setStates] = useState([]);
             const [page, setPage] = useState(1);
             const [totalPages, setTotalPages] = useState(1);
             const [search, setSearch] = useState('');
+            const [state, setState] = useState('');
             const [sort, setSort] = useState('size');
             const [order, setOrder] = useState('desc');
             const [loading, setLoading] = useState(true);
-            const [unique, setUnique] = useState(true);
+            const [reload, setReload] = useState(0);
+            const [removing, setRemoving] = useState(null);
 
             useEffect(() => {
                 let ignore = false;
                 setLoading(true);
-                fetch('/api/torrent/stats?unique=' + unique).then(r => r.json()).then(d => { if (!ignore) setStats(d); });
-                fetch('/api/torrent/files?page=' + page + '&per_page=50&sort=' + sort + '&order=' + order + '&search=' + encodeURIComponent(search) + '&unique=' + unique)
+                fetch('/api/v1/torrent/states').then(r => r.json()).then(d => { if (!ignore) setStates(d.states || []); });
+                fetch('/api/v1/torrent/torrents?page=' + page + '&per_page=50&sort=' + sort + '&order=' + order + '&search=' + encodeURIComponent(search) + '&state=' + state)
                     .then(r => r.json())
                     .then(d => {
                         if (!ignore) {
@@ -148,7 +512,32 @@ const indexTemplate = `<!DOCTYPE html>
                         }
                     });
                 return () => { ignore = true; };
-            }, [page, sort, order, search, unique]);
+            }, [page, sort, order, search, state, reload]);
+
+            const torrentAction = (row, action) => {
+                fetch('/api/v1/torrent/torrents/' + encodeURIComponent(row.instance) + '/' + row.hash + '/' + action, { method: 'POST' })
+                    .then(r => r.ok ? null : r.json())
+                    .then(d => {
+                        if (d && d.error) alert(t('common.error', { error: d.error }));
+                        setReload(reload + 1);
+                    });
+            };
+            const togglePause = (row) => torrentAction(row, row.state === 'paused' ? 'resume' : 'pause');
+            const recheck = (row) => torrentAction(row, 'recheck');
+            const move = (row) => {
+                const location = prompt(t('torrents.move_prompt', { name: row.name }), row.save_path);
+                if (!location || location === row.save_path) return;
+                fetch('/api/v1/torrent/torrents/' + encodeURIComponent(row.instance) + '/' + row.hash + '/location', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({ location })
+                })
+                    .then(r => r.ok ? null : r.json())
+                    .then(d => {
+                        if (d && d.error) alert(t('common.error', { error: d.error }));
+                        setReload(reload + 1);
+                    });
+            };
 
             const handleSort = (col) => {
                 if (sort === col) setOrder(order === 'asc' ? 'desc' : 'asc');
@@ -157,58 +546,85 @@ const indexTemplate = `<!DOCTYPE html>
             };
 
             const columns = [
-                { key: 'file_name', label: 'Fichier', className: '', render: (v) => v },
-                { key: 'file_path', label: 'Chemin', className: 'path', render: (v) => v },
-                { key: 'torrent_name', label: 'Torrent', className: '', render: (v) => v },
-                { key: 'size', label: 'Taille', className: 'size', render: (v) => formatSize(v) },
+                { key: 'name', label: t('columns.torrent'), render: (v) => v },
+                { key: 'instance', label: t('columns.client'), render: (v) => v },
+                { key: 'trackers', label: t('columns.trackers'), sortable: false, render: (v) => (v || []).join(', ') },
+                { key: 'state', label: t('columns.state'), render: (v) => <span className={'state ' + v}>{stateLabel(v)}</span> },
+                { key: 'ratio', label: t('columns.ratio'), className: 'size', render: (v) => v.toFixed(2) },
+                { key: 'seeding_time', label: t('columns.seeding_time'), className: 'size', render: (v) => formatDuration(v) },
+                { key: 'size', label: t('columns.size'), className: 'size', render: (v) => formatSize(v) },
             ];
+            if (admin) columns.push({ key: 'actions', label: '', sortable: false, render: (v, row) => (
+                <div style={{display: 'flex', gap: '6px'}}>
+                    <button className="row-btn" onClick={() => togglePause(row)}>{row.state === 'paused' ? t('torrents.resume') : t('torrents.pause')}</button>
+                    <button className="row-btn" onClick={() => recheck(row)}>{t('torrents.recheck')}</button>
+                    <button className="row-btn" onClick={() => move(row)}>{t('torrents.move')}</button>
+                    <button className="row-btn" onClick={() => setRemoving(row)}>{t('common.delete')}</button>
+                </div>
+            ) });
 
             return (
                 <div>
                     <div className="cards">
-                        <Card title="Torrents" value={(stats.total_torrents || 0).toLocaleString()} />
-                        <Card title="Fichiers" value={(stats.total_files || 0).toLocaleString()} sub={unique ? 'uniques' : 'total'} />
-                        <Card title="Poids total" value={formatSize(stats.total_size || 0)} />
+                        {states.map(st => (
+                            <Card key={st.state} title={stateLabel(st.state)} value={st.torrent_count.toLocaleString()} sub={formatSize(st.total_size)} />
+                        ))}
                     </div>
                     <div className="controls">
-                        <input className="search" placeholder="Rechercher..." value={search} onChange={e => { setSearch(e.target.value); setPage(1); }} />
-                        <label style={{display: 'flex', alignItems: 'center', gap: '8px', cursor: 'pointer', padding: '10px 15px', background: '#16213e', borderRadius: '8px', border: '1px solid #333'}}>
-                            <input type="checkbox" checked={unique} onChange={e => { setUnique(e.target.checked); setPage(1); }} style={{cursor: 'pointer'}} />
-                            <span style={{color: unique ? '#00d9ff' : '#888', fontSize: '14px'}}>Fichiers uniques</span>
-                        </label>
+                        <input className="search" placeholder={t('common.search')} value={search} onChange={e => { setSearch(e.target.value); setPage(1); }} />
+                        <select value={state} onChange={e => { setState(e.target.value); setPage(1); }}>
+                            <option value="">{t('torrents.all_states')}</option>
+                            {Object.keys(stateLabels).map(k => <option key={k} value={k}>{stateLabel(k)}</option>)}
+                        </select>
                     </div>
+                    {removing && (
+                        <DeleteTorrentConfirm key={removing.instance + '/' + removing.hash} torrent={removing}
+                            filesLabel={t('torrents.delete_files', { size: formatSize(removing.size) })}
+                            onDone={changed => { setRemoving(null); if (changed) setReload(reload + 1); }} />
+                    )}
                     <DataTable data={data} columns={columns} sort={sort} order={order} onSort={handleSort} loading={loading} />
                     <Pagination page={page} totalPages={totalPages} onPageChange={setPage} />
                 </div>
             );
         }
 
-        function LocalTab() {
+        function CompletenessView({ admin }) {
             const [data, setData] = useState([]);
-            const [stats, setStats] = useState([]);
             const [page, setPage] = useState(1);
             const [totalPages, setTotalPages] = useState(1);
+            const [total, setTotal] = useState(0);
             const [search, setSearch] = useState('');
-            const [category, setCategory] = useState('');
-            const [sort, setSort] = useState('size');
-            const [order, setOrder] = useState('desc');
+            const [maxCompleteness, setMaxCompleteness] = useState('99.99');
+            const [sort, setSort] = useState('completeness');
+            const [order, setOrder] = useState('asc');
             const [loading, setLoading] = useState(true);
+            const [reload, setReload] = useState(0);
+            const [removing, setRemoving] = useState(null);
 
             useEffect(() => {
                 let ignore = false;
                 setLoading(true);
-                fetch('/api/local/stats').then(r => r.json()).then(d => { if (!ignore) setStats(d.categories || []); });
-                fetch('/api/local/files?page=' + page + '&per_page=50&sort=' + sort + '&order=' + order + '&search=' + encodeURIComponent(search) + '&category=' + category)
+                fetch('/api/v1/torrent/completeness?page=' + page + '&per_page=50&sort=' + sort + '&order=' + order + '&search=' + encodeURIComponent(search) + '&max_completeness=' + maxCompleteness)
                     .then(r => r.json())
                     .then(d => {
                         if (!ignore) {
                             setData(d.data || []);
+                            setTotal(d.total || 0);
                             setTotalPages(d.total_pages || 1);
                             setLoading(false);
                         }
                     });
                 return () => { ignore = true; };
-            }, [page, sort, order, search, category]);
+            }, [page, sort, order, search, maxCompleteness, reload]);
+
+            const recheck = (row) => {
+                fetch('/api/v1/torrent/torrents/' + encodeURIComponent(row.instance) + '/' + row.hash + '/recheck', { method: 'POST' })
+                    .then(r => r.ok ? null : r.json())
+                    .then(d => {
+                        if (d && d.error) alert(t('common.error', { error: d.error }));
+                        setReload(reload + 1);
+                    });
+            };
 
             const handleSort = (col) => {
                 if (sort === col) setOrder(order === 'asc' ? 'desc' : 'asc');
@@ -217,52 +633,61 @@ const indexTemplate = `<!DOCTYPE html>
             };
 
             const columns = [
-                { key: 'file_name', label: 'Fichier', render: (v) => v },
-                { key: 'file_path', label: 'Chemin', className: 'path', render: (v) => v },
-                { key: 'category', label: 'Catégorie', render: (v) => <span className={'category ' + v}>{v}</span> },
-                { key: 'size', label: 'Taille', className: 'size', render: (v) => formatSize(v) },
+                { key: 'name', label: t('columns.torrent'), render: (v) => v },
+                { key: 'instance', label: t('columns.client'), render: (v) => v },
+                { key: 'state', label: t('columns.state'), render: (v) => v ? <span className={'state ' + v}>{stateLabel(v)}</span> : '—' },
+                { key: 'present_files', label: t('columns.present_files'), className: 'size', render: (v, row) => v.toLocaleString() + ' / ' + row.file_count.toLocaleString() },
+                { key: 'missing_bytes', label: t('columns.missing'), className: 'size', render: (v) => formatSize(v) },
+                { key: 'total_size', label: t('columns.size'), className: 'size', render: (v) => formatSize(v) },
+                { key: 'completeness', label: t('columns.on_disk'), className: 'size', render: (v) => v.toFixed(1) + ' %' },
             ];
-
-            const totalFiles = stats.reduce((a, c) => a + c.file_count, 0);
-            const totalSize = stats.reduce((a, c) => a + c.total_size, 0);
+            if (admin) columns.push({ key: 'actions', label: '', sortable: false, render: (v, row) => (
+                <div style={{display: 'flex', gap: '6px'}}>
+                    <button className="row-btn" onClick={() => recheck(row)}>{t('torrents.recheck')}</button>
+                    <button className="row-btn" onClick={() => setRemoving(row)}>{t('common.delete')}</button>
+                </div>
+            ) });
 
             return (
                 <div>
-                    <div className="cards">
-                        <Card title="Fichiers" value={totalFiles.toLocaleString()} />
-                        <Card title="Poids total" value={formatSize(totalSize)} />
-                    </div>
                     <div className="controls">
-                        <input className="search" placeholder="Rechercher..." value={search} onChange={e => { setSearch(e.target.value); setPage(1); }} />
-                        <select value={category} onChange={e => { setCategory(e.target.value); setPage(1); }}>
-                            <option value="">Toutes catégories</option>
-                            <option value="4k">4K</option>
-                            <option value="movies">Movies</option>
-                            <option value="shows">Shows</option>
+                        <input className="search" placeholder={t('common.search')} value={search} onChange={e => { setSearch(e.target.value); setPage(1); }} />
+                        <select value={maxCompleteness} onChange={e => { setMaxCompleteness(e.target.value); setPage(1); }}>
+                            <option value="100">{t('completeness.all')}</option>
+                            <option value="99.99">{t('completeness.incomplete')}</option>
+                            <option value="50">{t('completeness.half')}</option>
+                            <option value="0">{t('completeness.absent')}</option>
                         </select>
+                        <span>{t('completeness.count', { count: total.toLocaleString() })}</span>
                     </div>
+                    {removing && (
+                        <DeleteTorrentConfirm key={removing.instance + '/' + removing.hash} torrent={removing}
+                            filesLabel={t('completeness.delete_remaining_files', { size: formatSize(removing.total_size - removing.missing_bytes) })}
+                            onDone={changed => { setRemoving(null); if (changed) setReload(reload + 1); }} />
+                    )}
                     <DataTable data={data} columns={columns} sort={sort} order={order} onSort={handleSort} loading={loading} />
                     <Pagination page={page} totalPages={totalPages} onPageChange={setPage} />
                 </div>
             );
         }
 
-        function OrphansTab() {
+        function TorrentFilesView() {
             const [data, setData] = useState([]);
-            const [stats, setStats] = useState([]);
+            const [stats, setStats] = useState({ total_files: 0, total_torrents: 0, total_size: 0 });
             const [page, setPage] = useState(1);
             const [totalPages, setTotalPages] = useState(1);
             const [search, setSearch] = useState('');
-            const [category, setCategory] = useState('');
             const [sort, setSort] = useState('size');
             const [order, setOrder] = useState('desc');
             const [loading, setLoading] = useState(true);
+            const [unique, setUnique] = useState(true);
+            const [perPage, setPerPage] = useState(50);
 
             useEffect(() => {
                 let ignore = false;
                 setLoading(true);
-                fetch('/api/orphans/stats').then(r => r.json()).then(d => { if (!ignore) setStats(d.categories || []); });
-                fetch('/api/orphans/files?page=' + page + '&per_page=50&sort=' + sort + '&order=' + order + '&search=' + encodeURIComponent(search) + '&category=' + category)
+                fetch('/api/v1/torrent/stats?unique=' + unique).then(r => r.json()).then(d => { if (!ignore) setStats(d); });
+                fetch('/api/v1/torrent/files?page=' + page + '&per_page=' + perPage + '&sort=' + sort + '&order=' + order + '&search=' + encodeURIComponent(search) + '&unique=' + unique)
                     .then(r => r.json())
                     .then(d => {
                         if (!ignore) {
@@ -272,7 +697,7 @@ const indexTemplate = `<!DOCTYPE html>
                         }
                     });
                 return () => { ignore = true; };
-            }, [page, sort, order, search, category]);
+            }, [page, perPage, sort, order, search, unique]);
 
             const handleSort = (col) => {
                 if (sort === col) setOrder(order === 'asc' ? 'desc' : 'asc');
@@ -280,213 +705,1350 @@ const indexTemplate = `<!DOCTYPE html>
                 setPage(1);
             };
 
-            const columns = [
-                { key: 'file_name', label: 'Fichier', render: (v) => v },
-                { key: 'file_path', label: 'Chemin', className: 'path', render: (v) => v },
-                { key: 'category', label: 'Catégorie', render: (v) => <span className={'category ' + v}>{v}</span> },
-                { key: 'size', label: 'Taille', className: 'size', render: (v) => formatSize(v) },
+            const allColumns = [
+                { key: 'file_name', label: t('columns.file'), className: '', render: (v) => v },
+                { key: 'file_path', label: t('columns.path'), className: 'path', render: (v) => v },
+                { key: 'torrent_name', label: t('columns.torrent'), className: '', render: (v) => v },
+                { key: 'torrent_hash', label: t('columns.hash'), className: 'path', render: (v) => v },
+                { key: 'instance', label: t('columns.client'), className: '', render: (v) => v },
+                { key: 'size', label: t('columns.size'), className: 'size', render: (v) => formatSize(v) },
             ];
-
-            const totalFiles = stats.reduce((a, c) => a + c.file_count, 0);
-            const totalSize = stats.reduce((a, c) => a + c.total_size, 0);
+            const [shown, setShown] = useColumnLayout('torrent_files', ['file_name', 'file_path', 'torrent_name', 'instance', 'size']);
+            const columns = shownColumns(allColumns, shown);
 
             return (
                 <div>
                     <div className="cards">
-                        <Card title="Fichiers" value={totalFiles.toLocaleString()} />
-                        <Card title="Poids total" value={formatSize(totalSize)} />
+                        <Card title={t('cards.torrents')} value={(stats.total_torrents || 0).toLocaleString()} />
+                        <Card title={t('cards.files')} value={(stats.total_files || 0).toLocaleString()} sub={t(unique ? 'torrents.unique' : 'torrents.total')} />
+                        <Card title={t('cards.total_size')} value={formatSize(stats.total_size || 0)} />
                     </div>
                     <div className="controls">
-                        <input className="search" placeholder="Rechercher..." value={search} onChange={e => { setSearch(e.target.value); setPage(1); }} />
-                        <select value={category} onChange={e => { setCategory(e.target.value); setPage(1); }}>
-                            <option value="">Toutes catégories</option>
-                            <option value="4k">4K</option>
-                            <option value="movies">Movies</option>
-                            <option value="shows">Shows</option>
-                        </select>
-                        <a href="/api/orphans/export" className="export-btn">Exporter CSV</a>
+                        <input className="search" placeholder={t('common.search')} value={search} onChange={e => { setSearch(e.target.value); setPage(1); }} />
+                        <label style={{display: 'flex', alignItems: 'center', gap: '8px', cursor: 'pointer', padding: '10px 15px', background: '#16213e', borderRadius: '8px', border: '1px solid #333'}}>
+                            <input type="checkbox" checked={unique} onChange={e => { setUnique(e.target.checked); setPage(1); }} style={{cursor: 'pointer'}} />
+                            <span style={{color: unique ? '#00d9ff' : '#888', fontSize: '14px'}}>{t('torrents.unique_files')}</span>
+                        </label>
+                        <ColumnPicker columns={allColumns} shown={shown} onChange={setShown} />
+                        <ExportLink url="/api/v1/torrent/export" query={'sort=' + sort + '&order=' + order + '&search=' + encodeURIComponent(search) + '&unique=' + unique} columns={shown} />
                     </div>
                     <DataTable data={data} columns={columns} sort={sort} order={order} onSort={handleSort} loading={loading} />
-                    <Pagination page={page} totalPages={totalPages} onPageChange={setPage} />
+                    <Pagination page={page} totalPages={totalPages} onPageChange={setPage} perPage={perPage} onPerPageChange={n => { setPerPage(n); setPage(1); }} />
                 </div>
             );
         }
 
-        function StatsTab() {
-            const pieChartRef = useRef(null);
-            const orphanChartRef = useRef(null);
-            const healthChartRef = useRef(null);
-            const pieChartInstance = useRef(null);
-            const orphanChartInstance = useRef(null);
-            const healthChartInstance = useRef(null);
-            
-            const [torrentStats, setTorrentStats] = useState({ total_files: 0, total_torrents: 0, total_size: 0 });
-            const [localStats, setLocalStats] = useState([]);
-            const [orphanStats, setOrphanStats] = useState([]);
-            const [extensionStats, setExtensionStats] = useState([]);
+        function LocalTab({ admin }) {
+            const [view, setView] = useState('files');
+            return (
+                <div>
+                    <div className="views">
+                        <button className={'tab' + (view === 'files' ? ' active' : '')} onClick={() => setView('files')}>{t('local.view_files')}</button>
+                        <button className={'tab' + (view === 'folders' ? ' active' : '')} onClick={() => setView('folders')}>{t('local.view_folders')}</button>
+                        <button className={'tab' + (view === 'treemap' ? ' active' : '')} onClick={() => setView('treemap')}>{t('local.view_treemap')}</button>
+                    </div>
+                    {view === 'files' && <LocalFilesView admin={admin} />}
+                    {view === 'folders' && <FolderExplorer api="/api/v1/local/tree" />}
+                    {view === 'treemap' && <Treemap api="/api/v1/local/tree" />}
+                </div>
+            );
+        }
+
+        function FolderExplorer({ api }) {
+            const [path, setPath] = useState('/');
+            const [tree, setTree] = useState({ path: '/', parent: '', file_count: 0, total_size: 0, entries: [] });
             const [loading, setLoading] = useState(true);
 
             useEffect(() => {
-                Promise.all([
-                    fetch('/api/torrent/stats').then(r => r.json()),
-                    fetch('/api/local/stats').then(r => r.json()),
-                    fetch('/api/orphans/stats').then(r => r.json()),
-                    fetch('/api/unknown/extensions').then(r => r.json())
-                ]).then(([ts, ls, os, es]) => {
-                    setTorrentStats(ts);
-                    setLocalStats(ls.categories || []);
-                    setOrphanStats(os.categories || []);
-                    setExtensionStats(es.extensions || []);
-                    setLoading(false);
-                });
-            }, []);
+                let ignore = false;
+                setLoading(true);
+                fetch(api + '?path=' + encodeURIComponent(path))
+                    .then(r => r.json())
+                    .then(d => {
+                        if (!ignore) {
+                            setTree(d);
+                            setLoading(false);
+                        }
+                    });
+                return () => { ignore = true; };
+            }, [api, path]);
 
-            useEffect(() => {
-                if (!healthChartRef.current || localStats.length === 0) return;
-                if (healthChartInstance.current) healthChartInstance.current.destroy();
-                const totalLocal = localStats.reduce((a, c) => a + c.file_count, 0);
-                const totalOrphan = orphanStats.reduce((a, c) => a + c.file_count, 0);
-                const healthy = totalLocal - totalOrphan;
-                const ctx = healthChartRef.current.getContext('2d');
-                healthChartInstance.current = new Chart(ctx, {
-                    type: 'doughnut',
-                    data: {
-                        labels: ['Sains', 'Orphelins'],
-                        datasets: [{ data: [healthy, totalOrphan], backgroundColor: ['#2ecc71', '#e74c3c'], borderWidth: 0 }]
-                    },
-                    options: { responsive: true, maintainAspectRatio: false, cutout: '75%', plugins: { legend: { display: false } } }
-                });
-                return () => { if (healthChartInstance.current) healthChartInstance.current.destroy(); };
-            }, [localStats, orphanStats]);
+            const parts = tree.path.split('/').filter(p => p);
+            const columns = [
+                { key: 'name', label: t('columns.name'), sortable: false, render: (v, row) => row.dir
+                    ? <a href="#" onClick={e => { e.preventDefault(); setPath(row.path); }}>📁 {v}</a>
+                    : v },
+                { key: 'file_count', label: t('columns.files'), className: 'size', sortable: false, render: (v) => v.toLocaleString() },
+                { key: 'total_size', label: t('columns.size'), className: 'size', sortable: false, render: (v) => formatSize(v) },
+                { key: 'share', label: '%', className: 'size', sortable: false, render: (v, row) => tree.total_size > 0 ? (100 * row.total_size / tree.total_size).toFixed(1) + ' %' : '' },
+            ];
 
-            useEffect(() => {
-                if (!pieChartRef.current || localStats.length === 0) return;
-                if (pieChartInstance.current) pieChartInstance.current.destroy();
-                const colors = { '4k': '#f39c12', 'movies': '#e74c3c', 'shows': '#3498db', 'unknown': '#95a5a6' };
-                const ctx = pieChartRef.current.getContext('2d');
-                pieChartInstance.current = new Chart(ctx, {
-                    type: 'doughnut',
-                    data: {
-                        labels: localStats.map(s => s.category.toUpperCase()),
-                        datasets: [{ data: localStats.map(s => s.total_size), backgroundColor: localStats.map(s => colors[s.category] || '#666'), borderWidth: 0 }]
-                    },
-                    options: {
-                        responsive: true, maintainAspectRatio: false,
-                        plugins: { legend: { position: 'right', labels: { color: '#ccc', padding: 15 } }, tooltip: { callbacks: { label: (ctx) => ctx.label + ': ' + formatSize(ctx.raw) } } }
-                    }
-                });
-                return () => { if (pieChartInstance.current) pieChartInstance.current.destroy(); };
-            }, [localStats]);
+            return (
+                <div>
+                    <div className="cards">
+                        <Card title={t('cards.files')} value={tree.file_count.toLocaleString()} />
+                        <Card title={t('cards.total_size')} value={formatSize(tree.total_size)} />
+                    </div>
+                    <div className="controls">
+                        <a href="#" onClick={e => { e.preventDefault(); setPath('/'); }}>/</a>
+                        {parts.map((p, i) => (
+                            <span key={i}>
+                                <a href="#" onClick={e => { e.preventDefault(); setPath('/' + parts.slice(0, i + 1).join('/')); }}>{p}</a>/
+                            </span>
+                        ))}
+                        {tree.parent && <button className="row-btn" onClick={() => setPath(tree.parent)}>{t('folders.up')}</button>}
+                    </div>
+                    <DataTable data={tree.entries} columns={columns} sort="" order="" onSort={() => {}} loading={loading} />
+                </div>
+            );
+        }
+
+        // Découpe le rectangle (x, y, w, h) en cellules proportionnelles aux tailles,
+        // en rangées aussi carrées que possible (algorithme squarified)
+        function squarify(items, x, y, w, h) {
+            const total = items.reduce((s, it) => s + it.value, 0);
+            const cells = [];
+            if (total <= 0 || w <= 0 || h <= 0) return cells;
+            const scale = (w * h) / total;
+            const rest = items.map(it => ({ item: it, area: it.value * scale }));
+            const worst = (row, side) => {
+                const sum = row.reduce((s, r) => s + r.area, 0);
+                let max = 0;
+                row.forEach(r => { max = Math.max(max, (side * side * r.area) / (sum * sum), (sum * sum) / (side * side * r.area)); });
+                return max;
+            };
+            let i = 0;
+            while (i < rest.length) {
+                const side = Math.min(w, h);
+                const row = [rest[i++]];
+                while (i < rest.length && worst(row.concat([rest[i]]), side) <= worst(row, side)) row.push(rest[i++]);
+                const sum = row.reduce((s, r) => s + r.area, 0);
+                // La rangée occupe toute la largeur du plus petit côté
+                if (w >= h) {
+                    const rw = sum / h;
+                    let ry = y;
+                    row.forEach(r => { const rh = r.area / rw; cells.push({ item: r.item, x: x, y: ry, w: rw, h: rh }); ry += rh; });
+                    x += rw; w -= rw;
+                } else {
+                    const rh = sum / w;
+                    let rx = x;
+                    row.forEach(r => { const cw = r.area / rh; cells.push({ item: r.item, x: rx, y: y, w: cw, h: rh }); rx += cw; });
+                    y += rh; h -= rh;
+                }
+            }
+            return cells;
+        }
+
+        // Vert sans orphelins, rouge quand tout est orphelin
+        function orphanColor(ratio) {
+            return 'hsl(' + Math.round(120 * (1 - ratio)) + ', 60%, 35%)';
+        }
+
+        function Treemap({ api }) {
+            const [path, setPath] = useState('/');
+            const [tree, setTree] = useState({ path: '/', parent: '', file_count: 0, total_size: 0, orphan_size: 0, entries: [] });
+            const [loading, setLoading] = useState(true);
+            const [size, setSize] = useState({ w: 0, h: 0 });
+            const box = useRef(null);
 
             useEffect(() => {
-                if (!orphanChartRef.current || localStats.length === 0) return;
-                if (orphanChartInstance.current) orphanChartInstance.current.destroy();
-                const categories = ['4k', 'movies', 'shows', 'unknown'];
-                const localData = categories.map(c => { const s = localStats.find(x => x.category === c); return s ? s.total_size / (1024*1024*1024) : 0; });
-                const orphanData = categories.map(c => { const s = orphanStats.find(x => x.category === c); return s ? s.total_size / (1024*1024*1024) : 0; });
-                const ctx = orphanChartRef.current.getContext('2d');
-                orphanChartInstance.current = new Chart(ctx, {
-                    type: 'bar',
-                    data: {
-                        labels: categories.map(c => c.toUpperCase()),
-                        datasets: [
-                            { label: 'Local (GB)', data: localData, backgroundColor: '#3498db', borderRadius: 4 },
-                            { label: 'Orphelins (GB)', data: orphanData, backgroundColor: '#e74c3c', borderRadius: 4 }
-                        ]
-                    },
-                    options: { responsive: true, maintainAspectRatio: false, plugins: { legend: { labels: { color: '#888' } } }, scales: { x: { ticks: { color: '#888' }, grid: { color: '#222' } }, y: { ticks: { color: '#888' }, grid: { color: '#222' } } } }
-                });
-                return () => { if (orphanChartInstance.current) orphanChartInstance.current.destroy(); };
-            }, [localStats, orphanStats]);
+                let ignore = false;
+                setLoading(true);
+                fetch(api + '?path=' + encodeURIComponent(path))
+                    .then(r => r.json())
+                    .then(d => {
+                        if (!ignore) {
+                            setTree(d);
+                            setLoading(false);
+                        }
+                    });
+                return () => { ignore = true; };
+            }, [api, path]);
 
-            if (loading) return <div className="loading">Chargement...</div>;
+            useEffect(() => {
+                const measure = () => { if (box.current) setSize({ w: box.current.clientWidth, h: box.current.clientHeight }); };
+                measure();
+                window.addEventListener('resize', measure);
+                return () => window.removeEventListener('resize', measure);
+            }, []);
 
-            const totalLocalFiles = localStats.reduce((a, c) => a + c.file_count, 0);
-            const totalLocalSize = localStats.reduce((a, c) => a + c.total_size, 0);
-            const totalOrphanFiles = orphanStats.reduce((a, c) => a + c.file_count, 0);
-            const totalOrphanSize = orphanStats.reduce((a, c) => a + c.total_size, 0);
-            const orphanPercent = totalLocalFiles > 0 ? ((totalOrphanFiles / totalLocalFiles) * 100).toFixed(1) : 0;
-            const orphanSizePercent = totalLocalSize > 0 ? ((totalOrphanSize / totalLocalSize) * 100).toFixed(1) : 0;
-            const healthyFiles = totalLocalFiles - totalOrphanFiles;
-            const healthPercent = totalLocalFiles > 0 ? ((healthyFiles / totalLocalFiles) * 100).toFixed(0) : 100;
+            // Au-delà de 200 entrées, le reste est regroupé dans une seule cellule
+            const entries = tree.entries.filter(e => e.total_size > 0);
+            const items = entries.slice(0, 200).map(e => ({ ...e, value: e.total_size }));
+            const others = entries.slice(200);
+            if (others.length > 0) {
+                items.push({
+                    name: t('treemap.others', { count: others.length }),
+                    path: '', dir: false,
+                    file_count: others.reduce((s, e) => s + e.file_count, 0),
+                    total_size: others.reduce((s, e) => s + e.total_size, 0),
+                    orphan_size: others.reduce((s, e) => s + e.orphan_size, 0),
+                    value: others.reduce((s, e) => s + e.total_size, 0),
+                });
+            }
+            const cells = squarify(items, 0, 0, size.w, size.h);
+            const ratio = (e) => e.total_size > 0 ? e.orphan_size / e.total_size : 0;
+            const percent = (r) => (100 * r).toFixed(1) + ' %';
+            const parts = tree.path.split('/').filter(p => p);
 
-            const ProgressBar = ({ percent, color }) => (
-                <div style={{background: '#0f1729', borderRadius: '4px', height: '8px', width: '100%', marginTop: '8px'}}>
-                    <div style={{background: color, borderRadius: '4px', height: '100%', width: percent + '%'}}></div>
-                </div>
-            );
             return (
                 <div>
-                    <h2 style={{color: '#00d9ff', marginBottom: '20px', fontSize: '18px'}}>📊 Vue d'ensemble</h2>
-                    <div style={{display: 'grid', gridTemplateColumns: '1fr 1fr', gap: '20px', marginBottom: '30px'}}>
-                        <div style={{display: 'grid', gridTemplateColumns: '1fr 1fr', gap: '15px'}}>
-                            <Card title="Torrents" value={(torrentStats.total_torrents || 0).toLocaleString()} sub={torrentStats.total_files?.toLocaleString() + ' fichiers'} />
-                            <Card title="Espace Torrents" value={formatSize(torrentStats.total_size || 0)} />
-                            <Card title="Fichiers Locaux" value={totalLocalFiles.toLocaleString()} />
-                            <Card title="Espace Local" value={formatSize(totalLocalSize)} />
-                        </div>
+                    <div className="cards">
+                        <Card title={t('cards.files')} value={tree.file_count.toLocaleString()} />
+                        <Card title={t('cards.total_size')} value={formatSize(tree.total_size)} />
+                        <Card title={t('treemap.orphans')} value={formatSize(tree.orphan_size)} sub={percent(ratio(tree))} />
+                    </div>
+                    <div className="controls">
+                        <a href="#" onClick={e => { e.preventDefault(); setPath('/'); }}>/</a>
+                        {parts.map((p, i) => (
+                            <span key={i}>
+                                <a href="#" onClick={e => { e.preventDefault(); setPath('/' + parts.slice(0, i + 1).join('/')); }}>{p}</a>/
+                            </span>
+                        ))}
+                        {tree.parent && <button className="row-btn" onClick={() => setPath(tree.parent)}>{t('folders.up')}</button>}
+                    </div>
+                    <div className="treemap" ref={box}>
+                        {loading && <div className="loading">{t('common.loading')}</div>}
+                        {!loading && cells.map(c => (
+                            <div key={c.item.path || c.item.name}
+                                className={'cell' + (c.item.dir ? ' dir' : '')}
+                                style={{ left: c.x, top: c.y, width: c.w, height: c.h, background: orphanColor(ratio(c.item)) }}
+                                title={t('treemap.tooltip', { name: c.item.name, size: formatSize(c.item.total_size), files: c.item.file_count.toLocaleString(), orphans: percent(ratio(c.item)) })}
+                                onClick={() => { if (c.item.dir) setPath(c.item.path); }}>
+                                {c.w > 60 && c.h > 30 && (
+                                    <div>
+                                        <div>{c.item.dir ? '📁 ' : ''}{c.item.name}</div>
+                                        <div className="sub">{formatSize(c.item.total_size)}</div>
+                                    </div>
+                                )}
+                            </div>
+                        ))}
+                    </div>
+                    <div className="legend">
+                        <span>{t('treemap.legend')}</span>
+                        <span>0 %</span><div className="scale"></div><span>100 %</span>
+                    </div>
+                </div>
+            );
+        }
+
+        // Tout ce qui est connu d'un fichier local : les torrents qui l'attendent,
+        // pour vérifier une correspondance avant de faire confiance à une
+        // suppression, et les quasi-correspondances qui expliquent les faux orphelins
+        function FileDetails({ path, onClose }) {
+            const [detail, setDetail] = useState(null);
+            const [error, setError] = useState('');
+
+            useEffect(() => {
+                let ignore = false;
+                setDetail(null);
+                setError('');
+                fetch('/api/v1/local/file?path=' + encodeURIComponent(path))
+                    .then(r => r.json().then(d => ({ ok: r.ok, d })))
+                    .then(({ ok, d }) => {
+                        if (ignore) return;
+                        if (ok) setDetail(d);
+                        else setError(t('common.error', { error: d.error }));
+                    });
+                return () => { ignore = true; };
+            }, [path]);
+
+            return (
+                <div>
+                    <div className="drawer-overlay" onClick={onClose}></div>
+                    <div className="drawer">
+                        <div className="head">
+                            <h2>{detail ? detail.file_name : path}</h2>
+                            <button className="row-btn" onClick={onClose}>{t('common.close')}</button>
+                        </div>
+                        {error && <div className="status orphan">{error}</div>}
+                        {!error && !detail && <div className="loading">{t('common.loading')}</div>}
+                        {detail && (
+                            <div>
+                                <dl>
+                                    <dt>{t('file_details.status')}</dt><dd className={'status ' + detail.status}>{t('file_details.status_' + detail.status)}</dd>
+                                    <dt>{t('file_details.path')}</dt><dd>{detail.file_path}</dd>
+                                    <dt>{t('file_details.relative_path')}</dt><dd>{detail.relative_path}</dd>
+                                    <dt>{t('columns.category')}</dt><dd><span className={'category ' + detail.category}>{detail.category}</span></dd>
+                                    <dt>{t('columns.size')}</dt><dd>{formatSize(detail.size)}{detail.allocated < detail.size ? ' (' + t('file_details.allocated', { size: formatSize(detail.allocated) }) + ')' : ''}</dd>
+                                    <dt>{t('columns.age')}</dt><dd>{formatAge(detail.mod_time)}</dd>
+                                    {detail.links > 1 && [<dt key="lt">{t('file_details.links')}</dt>, <dd key="ld">{detail.links}</dd>]}
+                                    {detail.checksum && [<dt key="ct">{t('file_details.checksum')}</dt>, <dd key="cd">{detail.checksum}</dd>]}
+                                </dl>
+                                <h3>{t('file_details.torrents')}</h3>
+                                {detail.torrents.length === 0 && <div className={'status ' + detail.status}>{t('file_torrents.' + detail.status)}</div>}
+                                <ul>
+                                    {detail.torrents.map((tf, i) => (
+                                        <li key={i}>
+                                            <div>{tf.name} {tf.state && <span className={'state ' + tf.state}>{stateLabel(tf.state)}</span>}</div>
+                                            <div className="sub">{tf.instance} · {tf.hash}</div>
+                                            <div className="sub">{tf.hardlink ? t('file_torrents.via_hardlink', { path: tf.hardlink }) : tf.file_path}</div>
+                                        </li>
+                                    ))}
+                                </ul>
+                                {detail.near_misses.length > 0 && (
+                                    <div>
+                                        <h3>{t('file_details.near_misses')}</h3>
+                                        <ul>
+                                            {detail.near_misses.map((m, i) => (
+                                                <li key={i}>
+                                                    <div>{m.name} {m.size !== detail.size && <span className="sub">{t('file_details.other_size', { size: formatSize(m.size) })}</span>}</div>
+                                                    <div className="sub">{m.relative_path}</div>
+                                                    <div className="sub">{m.instance} · {m.file_path}</div>
+                                                </li>
+                                            ))}
+                                        </ul>
+                                    </div>
+                                )}
+                            </div>
+                        )}
+                    </div>
+                </div>
+            );
+        }
+
+        function LocalFilesView({ admin }) {
+            const [data, setData] = useState([]);
+            const [stats, setStats] = useState([]);
+            const [page, setPage] = useState(1);
+            const [perPage, setPerPage] = useState(50);
+            const [totalPages, setTotalPages] = useState(1);
+            const [search, setSearch] = useState('');
+            const [category, setCategory] = useState('');
+            const [minAge, setMinAge] = useState('');
+            const [minSize, setMinSize] = useState('');
+            const [maxSize, setMaxSize] = useState('');
+            const [sort, setSort] = useState('size');
+            const [order, setOrder] = useState('desc');
+            const [loading, setLoading] = useState(true);
+            const [lookup, setLookup] = useState('');
+
+            useEffect(() => {
+                let ignore = false;
+                setLoading(true);
+                fetch('/api/v1/local/stats').then(r => r.json()).then(d => { if (!ignore) setStats(d.categories || []); });
+                fetch('/api/v1/local/files?page=' + page + '&per_page=' + perPage + '&sort=' + sort + '&order=' + order + '&search=' + encodeURIComponent(search) + '&category=' + category + '&min_age=' + minAge + '&min_size=' + minSize + '&max_size=' + maxSize)
+                    .then(r => r.json())
+                    .then(d => {
+                        if (!ignore) {
+                            setData(d.data || []);
+                            setTotalPages(d.total_pages || 1);
+                            setLoading(false);
+                        }
+                    });
+                return () => { ignore = true; };
+            }, [page, perPage, sort, order, search, category, minAge, minSize, maxSize]);
+
+            const handleSort = (col) => {
+                if (sort === col) setOrder(order === 'asc' ? 'desc' : 'asc');
+                else { setSort(col); setOrder('desc'); }
+                setPage(1);
+            };
+
+            const applyView = (v) => {
+                setSearch(v.search);
+                setCategory(v.category);
+                setMinSize(v.min_size ? String(v.min_size) : '');
+                setMaxSize(v.max_size ? String(v.max_size) : '');
+                setSort(v.sort || 'size');
+                setOrder(v.order || 'desc');
+                setPage(1);
+            };
+
+            const allColumns = [
+                { key: 'file_name', label: t('columns.file'), render: (v, row) => <a href="#" title={t('file_details.show')} onClick={e => { e.preventDefault(); setLookup(row.file_path); }}>{v}</a> },
+                { key: 'file_path', label: t('columns.path'), className: 'path', render: (v) => v },
+                { key: 'relative_path', label: t('columns.relative_path'), className: 'path', sortable: false, render: (v) => v },
+                { key: 'category', label: t('columns.category'), render: (v) => <span className={'category ' + v}>{v}</span> },
+                { key: 'size', label: t('columns.size'), className: 'size', render: (v) => formatSize(v) },
+                { key: 'mod_time', label: t('columns.age'), render: (v) => formatAge(v) },
+                { key: 'links', label: t('columns.links'), sortable: false, render: (v) => v || '—' },
+            ];
+            const [shown, setShown] = useColumnLayout('local', ['file_name', 'file_path', 'category', 'size', 'mod_time']);
+            const columns = shownColumns(allColumns, shown);
+
+            const totalFiles = stats.reduce((a, c) => a + c.file_count, 0);
+            const totalSize = stats.reduce((a, c) => a + c.total_size, 0);
+
+            return (
+                <div>
+                    <div className="cards">
+                        <Card title={t('cards.files')} value={totalFiles.toLocaleString()} />
+                        <Card title={t('cards.total_size')} value={formatSize(totalSize)} />
+                    </div>
+                    <div className="controls">
+                        <input className="search" placeholder={t('common.search')} value={search} onChange={e => { setSearch(e.target.value); setPage(1); }} />
+                        <CategorySelect value={category} stats={stats} onChange={v => { setCategory(v); setPage(1); }} />
+                        <AgeSelect value={minAge} onChange={v => { setMinAge(v); setPage(1); }} />
+                        <SizeSelect value={minSize} all={t('filters.min_size')} onChange={v => { setMinSize(v); setPage(1); }} />
+                        <SizeSelect value={maxSize} all={t('filters.max_size')} max onChange={v => { setMaxSize(v); setPage(1); }} />
+                        <SavedViews list="local" admin={admin} current={{ search, category, min_size: Number(minSize) || 0, max_size: Number(maxSize) || 0, sort, order }} onApply={applyView} />
+                        <ColumnPicker columns={allColumns} shown={shown} onChange={setShown} />
+                        <ExportLink url="/api/v1/local/export" query={'sort=' + sort + '&order=' + order + '&search=' + encodeURIComponent(search) + '&category=' + category + '&min_age=' + minAge + '&min_size=' + minSize + '&max_size=' + maxSize} columns={shown} />
+                    </div>
+                    {lookup && <FileDetails path={lookup} onClose={() => setLookup('')} />}
+                    <DataTable data={data} columns={columns} sort={sort} order={order} onSort={handleSort} loading={loading} />
+                    <Pagination page={page} totalPages={totalPages} onPageChange={setPage} perPage={perPage} onPerPageChange={n => { setPerPage(n); setPage(1); }} />
+                </div>
+            );
+        }
+
+        function OrphansTab({ admin }) {
+            const [view, setView] = useState('files');
+            return (
+                <div>
+                    <div className="views">
+                        <button className={'tab' + (view === 'files' ? ' active' : '')} onClick={() => setView('files')}>{t('orphans.view_files')}</button>
+                        <button className={'tab' + (view === 'releases' ? ' active' : '')} onClick={() => setView('releases')}>{t('orphans.view_releases')}</button>
+                        <button className={'tab' + (view === 'ignored' ? ' active' : '')} onClick={() => setView('ignored')}>{t('orphans.view_ignored')}</button>
+                    </div>
+                    {view === 'files' && <OrphanFilesView admin={admin} />}
+                    {view === 'releases' && <OrphanReleasesView admin={admin} />}
+                    {view === 'ignored' && <IgnoredView admin={admin} />}
+                </div>
+            );
+        }
+
+        function OrphanFilesView({ admin }) {
+            const [data, setData] = useState([]);
+            const [stats, setStats] = useState([]);
+            const [page, setPage] = useState(1);
+            const [perPage, setPerPage] = useState(50);
+            const [totalPages, setTotalPages] = useState(1);
+            const [search, setSearch] = useState('');
+            const [category, setCategory] = useState('');
+            const [minAge, setMinAge] = useState('');
+            const [orphanedFor, setOrphanedFor] = useState('');
+            const [minSize, setMinSize] = useState('');
+            const [maxSize, setMaxSize] = useState('');
+            const [excludeHardlinked, setExcludeHardlinked] = useState(false);
+            const [sort, setSort] = useState('size');
+            const [order, setOrder] = useState('desc');
+            const [loading, setLoading] = useState(true);
+            const [lookup, setLookup] = useState('');
+            const [total, setTotal] = useState(0);
+            const [selected, setSelected] = useState(new Map());
+            const [allMatching, setAllMatching] = useState(null);
+            const [reload, setReload] = useState(0);
+            const [deleting, setDeleting] = useState(false);
+            const [recheck, setRecheck] = useState(false);
+
+            const filters = 'search=' + encodeURIComponent(search) + '&category=' + category + '&min_age=' + minAge + '&orphaned_for=' + orphanedFor + '&min_size=' + minSize + '&max_size=' + maxSize + '&exclude_hardlinked=' + excludeHardlinked;
+
+            useEffect(() => {
+                let ignore = false;
+                setLoading(true);
+                fetch('/api/v1/orphans/stats').then(r => r.json()).then(d => { if (!ignore) setStats(d.categories || []); });
+                fetch('/api/v1/orphans/files?page=' + page + '&per_page=' + perPage + '&sort=' + sort + '&order=' + order + '&' + filters)
+                    .then(r => r.json())
+                    .then(d => {
+                        if (!ignore) {
+                            setData(d.data || []);
+                            setTotal(d.total || 0);
+                            setTotalPages(d.total_pages || 1);
+                            setLoading(false);
+                        }
+                    });
+                return () => { ignore = true; };
+            }, [page, perPage, sort, order, search, category, minAge, orphanedFor, minSize, maxSize, excludeHardlinked, reload]);
+
+            // La sélection survit au changement de page, pas à celui des filtres
+            useEffect(() => {
+                setSelected(new Map());
+                setAllMatching(null);
+            }, [search, category, minAge, orphanedFor, minSize, maxSize, excludeHardlinked, reload]);
+
+            // selected associe les chemins sélectionnés à leur taille, allMatching
+            // garde les totaux des filtres quand tous leurs orphelins sont sélectionnés
+            const toggle = (row) => {
+                const next = new Map(selected);
+                if (next.has(row.file_path)) next.delete(row.file_path); else next.set(row.file_path, row.size);
+                setSelected(next);
+            };
+            const allSelected = allMatching !== null || (data.length > 0 && data.every(f => selected.has(f.file_path)));
+            const clearSelection = () => {
+                setSelected(new Map());
+                setAllMatching(null);
+            };
+            const toggleAll = () => {
+                if (allMatching) return clearSelection();
+                const next = new Map(selected);
+                data.forEach(f => allSelected ? next.delete(f.file_path) : next.set(f.file_path, f.size));
+                setSelected(next);
+            };
+            const selectMatching = () => {
+                fetch('/api/v1/orphans/totals?' + filters).then(r => r.json()).then(d => {
+                    if (d.error) { alert(t('common.error', { error: d.error })); return; }
+                    setAllMatching(d);
+                });
+            };
+            const selectedCount = allMatching ? allMatching.file_count : selected.size;
+            const selectedBytes = allMatching ? allMatching.total_size : Array.from(selected.values()).reduce((a, b) => a + b, 0);
+
+            const deleteSelected = () => {
+                const request = (body) => fetch('/api/v1/orphans/files', { method: 'DELETE', headers: { 'Content-Type': 'application/json' }, body: JSON.stringify(body) }).then(r => r.json());
+                const done = () => { setDeleting(false); setReload(reload + 1); };
+                setDeleting(true);
+                // Aperçu côté serveur, puis exécution avec le jeton de confirmation
+                const selection = allMatching
+                    ? { filter: { search, category, min_age: Number(minAge) || 0, orphaned_for: Number(orphanedFor) || 0, min_size: Number(minSize) || 0, max_size: Number(maxSize) || 0, exclude_hardlinked: excludeHardlinked } }
+                    : { paths: Array.from(selected.keys()) };
+                request(selection).then(preview => {
+                    if (preview.error) { alert(t('common.error', { error: preview.error })); return done(); }
+                    const action = preview.report.quarantine ? 'orphans.confirm_quarantine' : preview.report.archive ? 'orphans.confirm_archive' : 'orphans.confirm_delete';
+                    const skipped = preview.protected ? t('orphans.protected_skipped', { count: preview.protected }) : '';
+                    const msg = t(action, { files: preview.files, size: formatSize(preview.bytes), skipped });
+                    if (!confirm(msg)) return done();
+                    request({ token: preview.token }).then(d => {
+                        if (d.error) { alert(t('common.error', { error: d.error })); return done(); }
+                        const msg = t('orphans.cleanup_done', { files: d.files_removed, size: formatSize(d.bytes_reclaimed) }) + (d.failed ? t('common.failed_count', { count: d.failed }) : '');
+                        const paths = d.actions.filter(a => !a.error && !a.protected).map(a => a.file.file_path);
+                        if (!recheck || paths.length === 0) { alert(msg); return done(); }
+                        // Revérification des torrents attendant les fichiers traités
+                        fetch('/api/v1/torrent/recheck', { method: 'POST', headers: { 'Content-Type': 'application/json' }, body: JSON.stringify({ paths }) })
+                            .then(r => r.json())
+                            .then(rc => {
+                                alert(msg + '\n' + (rc.error ? t('orphans.recheck_error', { error: rc.error }) : t('orphans.rechecked', { count: rc.rechecked }) + (rc.failed ? t('common.failed_count', { count: rc.failed }) : '')));
+                                done();
+                            });
+                    });
+                });
+            };
+
+            const ignoreSelected = () => {
+                if (!confirm(t('orphans.confirm_ignore', { count: selected.size }))) return;
+                setDeleting(true);
+                Promise.all(Array.from(selected.keys()).map(path => fetch('/api/v1/ignored', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({ path })
+                }).then(r => r.ok ? null : r.json()))).then(results => {
+                    const failed = results.filter(d => d && d.error);
+                    if (failed.length > 0) alert(t('common.error', { error: failed[0].error }));
+                    setDeleting(false);
+                    setReload(reload + 1);
+                });
+            };
+
+            const handleSort = (col) => {
+                if (sort === col) setOrder(order === 'asc' ? 'desc' : 'asc');
+                else { setSort(col); setOrder('desc'); }
+                setPage(1);
+            };
+
+            const applyView = (v) => {
+                setSearch(v.search);
+                setCategory(v.category);
+                setMinSize(v.min_size ? String(v.min_size) : '');
+                setMaxSize(v.max_size ? String(v.max_size) : '');
+                setSort(v.sort || 'size');
+                setOrder(v.order || 'desc');
+                setPage(1);
+            };
+
+            const allColumns = [
+                { key: 'file_name', label: t('columns.file'), render: (v, row) => <a href="#" title={t('file_details.show')} onClick={e => { e.preventDefault(); setLookup(row.file_path); }}>{v}</a> },
+                { key: 'file_path', label: t('columns.path'), className: 'path', render: (v) => v },
+                { key: 'relative_path', label: t('columns.relative_path'), className: 'path', sortable: false, render: (v) => v },
+                { key: 'category', label: t('columns.category'), render: (v) => <span className={'category ' + v}>{v}</span> },
+                { key: 'size', label: t('columns.size'), className: 'size', render: (v) => formatSize(v) },
+                { key: 'mod_time', label: t('columns.age'), render: (v) => formatAge(v) },
+                { key: 'orphaned_at', label: t('columns.orphaned_for'), render: (v) => formatAge(v) },
+                { key: 'links', label: t('columns.links'), render: (v) => v > 1 ? <span style={{color: '#f39c12'}} title={t('orphans.hardlinks', { links: v })}>🔗 {v}</span> : (v || '—') },
+            ];
+            const [shown, setShown] = useColumnLayout('orphans', ['file_name', 'file_path', 'category', 'size', 'mod_time', 'orphaned_at', 'links']);
+            const columns = [
+                { key: 'select', label: <input type="checkbox" checked={allSelected} onChange={toggleAll} />, sortable: false, width: '40px', render: (v, row) => <input type="checkbox" checked={allMatching !== null || selected.has(row.file_path)} disabled={allMatching !== null} onChange={() => toggle(row)} /> },
+                ...shownColumns(allColumns, shown),
+            ];
+
+            const totalFiles = stats.reduce((a, c) => a + c.file_count, 0);
+            const totalSize = stats.reduce((a, c) => a + c.total_size, 0);
+
+            return (
+                <div>
+                    <div className="cards">
+                        <Card title={t('cards.files')} value={totalFiles.toLocaleString()} />
+                        <Card title={t('cards.total_size')} value={formatSize(totalSize)} />
+                    </div>
+                    <div className="controls">
+                        <input className="search" placeholder={t('common.search')} value={search} onChange={e => { setSearch(e.target.value); setPage(1); }} />
+                        <CategorySelect value={category} stats={stats} onChange={v => { setCategory(v); setPage(1); }} />
+                        <AgeSelect value={minAge} onChange={v => { setMinAge(v); setPage(1); }} />
+                        <AgeSelect value={orphanedFor} all={t('orphans.all_orphan_dates')} onChange={v => { setOrphanedFor(v); setPage(1); }} />
+                        <SizeSelect value={minSize} all={t('filters.min_size')} onChange={v => { setMinSize(v); setPage(1); }} />
+                        <SizeSelect value={maxSize} all={t('filters.max_size')} max onChange={v => { setMaxSize(v); setPage(1); }} />
+                        <label style={{color: '#888', fontSize: '14px', display: 'flex', alignItems: 'center', gap: '6px'}} title={t('orphans.exclude_hardlinked_hint')}><input type="checkbox" checked={excludeHardlinked} onChange={e => { setExcludeHardlinked(e.target.checked); setPage(1); }} /> {t('orphans.exclude_hardlinked')}</label>
+                        <SavedViews list="orphans" admin={admin} current={{ search, category, min_size: Number(minSize) || 0, max_size: Number(maxSize) || 0, sort, order }} onApply={applyView} />
+                        <ColumnPicker columns={allColumns} shown={shown} onChange={setShown} />
+                        <ExportLink url="/api/v1/orphans/export" query={'sort=' + sort + '&order=' + order + '&' + filters} columns={shown} />
+                        {admin && <label style={{color: '#888', fontSize: '14px', display: 'flex', alignItems: 'center', gap: '6px'}}><input type="checkbox" checked={recheck} onChange={e => setRecheck(e.target.checked)} /> {t('orphans.recheck_torrents')}</label>}
+                        {admin && <button className="row-btn" onClick={ignoreSelected} disabled={selected.size === 0 || allMatching !== null || deleting}>{t('orphans.ignore_selected')}</button>}
+                        {admin && <button className="danger-btn" onClick={deleteSelected} disabled={selectedCount === 0 || deleting}>{t('orphans.delete_selected', { count: selectedCount.toLocaleString() })}</button>}
+                    </div>
+                    {selectedCount > 0 && (
+                        <div className="selection">
+                            <span>{t('orphans.selection', { count: selectedCount.toLocaleString(), size: formatSize(selectedBytes) })}</span>
+                            {!allMatching && allSelected && total > selected.size && <a href="#" onClick={e => { e.preventDefault(); selectMatching(); }}>{t('orphans.select_matching', { count: total.toLocaleString() })}</a>}
+                            <a href="#" onClick={e => { e.preventDefault(); clearSelection(); }}>{t('orphans.clear_selection')}</a>
+                        </div>
+                    )}
+                    {lookup && <FileDetails path={lookup} onClose={() => setLookup('')} />}
+                    <DataTable data={data} columns={columns} sort={sort} order={order} onSort={handleSort} loading={loading} />
+                    <Pagination page={page} totalPages={totalPages} onPageChange={setPage} perPage={perPage} onPerPageChange={n => { setPerPage(n); setPage(1); }} />
+                </div>
+            );
+        }
+
+        function OrphanReleasesView({ admin }) {
+            const [data, setData] = useState([]);
+            const [stats, setStats] = useState([]);
+            const [page, setPage] = useState(1);
+            const [totalPages, setTotalPages] = useState(1);
+            const [total, setTotal] = useState(0);
+            const [search, setSearch] = useState('');
+            const [category, setCategory] = useState('');
+            const [excludeHardlinked, setExcludeHardlinked] = useState(false);
+            const [sort, setSort] = useState('size');
+            const [loading, setLoading] = useState(true);
+            const [files, setFiles] = useState({});
+            const [deleting, setDeleting] = useState(false);
+            const [reload, setReload] = useState(0);
+
+            const filters = 'search=' + encodeURIComponent(search) + '&category=' + category + '&exclude_hardlinked=' + excludeHardlinked;
+
+            useEffect(() => {
+                let ignore = false;
+                setLoading(true);
+                fetch('/api/v1/orphans/stats').then(r => r.json()).then(d => { if (!ignore) setStats(d.categories || []); });
+                fetch('/api/v1/orphans/releases?page=' + page + '&per_page=50&sort=' + sort + '&order=' + (sort === 'name' ? 'asc' : 'desc') + '&' + filters)
+                    .then(r => r.json())
+                    .then(d => {
+                        if (!ignore) {
+                            setData(d.data || []);
+                            setTotal(d.total || 0);
+                            setTotalPages(d.total_pages || 1);
+                            setFiles({});
+                            setLoading(false);
+                        }
+                    });
+                return () => { ignore = true; };
+            }, [page, sort, search, category, excludeHardlinked, reload]);
+
+            const releaseFiles = (release) => fetch('/api/v1/orphans/releases/files?folder=' + encodeURIComponent(release.folder) + '&' + filters)
+                .then(r => r.json())
+                .then(d => d.files || []);
+
+            // Les orphelins d'un dossier sont chargés à son premier dépliage
+            const toggle = (release) => {
+                const key = release.folder;
+                if (key in files) {
+                    const next = { ...files };
+                    delete next[key];
+                    setFiles(next);
+                    return;
+                }
+                setFiles(f => ({ ...f, [key]: null }));
+                releaseFiles(release).then(list => setFiles(f => key in f ? { ...f, [key]: list } : f));
+            };
+
+            // Tous les orphelins du dossier, avec l'aperçu confirmé de l'onglet Orphelins
+            const deleteRelease = (release) => {
+                const request = (body) => fetch('/api/v1/orphans/files', { method: 'DELETE', headers: { 'Content-Type': 'application/json' }, body: JSON.stringify(body) }).then(r => r.json());
+                const done = () => { setDeleting(false); setReload(reload + 1); };
+                setDeleting(true);
+                releaseFiles(release).then(list => request({ paths: list.map(f => f.file_path) })).then(preview => {
+                    if (preview.error) { alert(t('common.error', { error: preview.error })); return done(); }
+                    const action = preview.report.quarantine ? 'orphans.confirm_quarantine' : preview.report.archive ? 'orphans.confirm_archive' : 'orphans.confirm_delete';
+                    const skipped = preview.protected ? t('orphans.protected_skipped', { count: preview.protected }) : '';
+                    if (!confirm(release.name + '\n' + t(action, { files: preview.files, size: formatSize(preview.bytes), skipped }))) return done();
+                    request({ token: preview.token }).then(d => {
+                        if (d.error) alert(t('common.error', { error: d.error }));
+                        else alert(t('orphans.cleanup_done', { files: d.files_removed, size: formatSize(d.bytes_reclaimed) }) + (d.failed ? t('common.failed_count', { count: d.failed }) : ''));
+                        done();
+                    });
+                });
+            };
+
+            const totalSize = data.reduce((a, r) => a + r.total_size, 0);
+            const columns = admin ? 7 : 6;
+
+            return (
+                <div className="missing">
+                    <div className="cards">
+                        <Card title={t('releases.folders')} value={total.toLocaleString()} />
+                        <Card title={t('releases.page_size')} value={formatSize(totalSize)} />
+                    </div>
+                    <div className="controls">
+                        <input className="search" placeholder={t('common.search')} value={search} onChange={e => { setSearch(e.target.value); setPage(1); }} />
+                        <CategorySelect value={category} stats={stats} onChange={v => { setCategory(v); setPage(1); }} />
+                        <select value={sort} onChange={e => { setSort(e.target.value); setPage(1); }}>
+                            <option value="size">{t('releases.sort_size')}</option>
+                            <option value="file_count">{t('releases.sort_files')}</option>
+                            <option value="mod_time">{t('releases.sort_recent')}</option>
+                            <option value="name">{t('releases.sort_name')}</option>
+                        </select>
+                        <label style={{color: '#888', fontSize: '14px', display: 'flex', alignItems: 'center', gap: '6px'}} title={t('orphans.exclude_hardlinked_hint')}><input type="checkbox" checked={excludeHardlinked} onChange={e => { setExcludeHardlinked(e.target.checked); setPage(1); }} /> {t('orphans.exclude_hardlinked')}</label>
+                    </div>
+                    <p style={{color: '#888', marginBottom: '15px', fontSize: '14px'}}>{t('releases.help')}</p>
+                    {loading ? <div className="loading">{t('common.loading')}</div> : (
+                        <table>
+                            <thead><tr><th style={{width: '50px'}}></th><th>{t('columns.release')}</th><th>{t('columns.category')}</th><th>{t('columns.orphans')}</th><th>{t('columns.size')}</th><th>{t('columns.age')}</th>{admin && <th></th>}</tr></thead>
+                            <tbody>
+                                {data.map(release => {
+                                    const key = release.folder;
+                                    const list = files[key];
+                                    return [
+                                        <tr key={key}>
+                                            <td><button className="row-btn" onClick={() => toggle(release)}>{key in files ? '▾' : '▸'}</button></td>
+                                            <td title={release.folder}>{release.name}</td>
+                                            <td><span className={'category ' + release.category}>{release.category}</span></td>
+                                            <td className={release.file_count === release.local_files ? 'absent' : undefined}>{release.file_count === release.local_files ? t('releases.whole', { count: release.file_count.toLocaleString() }) : release.file_count.toLocaleString() + ' / ' + release.local_files.toLocaleString()}</td>
+                                            <td className="size">{formatSize(release.total_size)}</td>
+                                            <td>{formatAge(release.mod_time)}</td>
+                                            {admin && <td><button className="danger-btn" onClick={() => deleteRelease(release)} disabled={deleting}>{t(release.single ? 'releases.delete_file' : 'releases.delete_folder')}</button></td>}
+                                        </tr>,
+                                        key in files && (
+                                            <tr key={key + '/files'} className="files">
+                                                <td colSpan={columns}>
+                                                    {list === null ? t('common.loading') : (
+                                                        <table>
+                                                            <thead><tr><th>{t('columns.path')}</th><th>{t('columns.size')}</th><th>{t('columns.orphaned_for')}</th></tr></thead>
+                                                            <tbody>
+                                                                {list.map(f => (
+                                                                    <tr key={f.file_path}>
+                                                                        <td>{f.file_path}</td>
+                                                                        <td className="size">{formatSize(f.size)}</td>
+                                                                        <td>{formatAge(f.orphaned_at)}</td>
+                                                                    </tr>
+                                                                ))}
+                                                            </tbody>
+                                                        </table>
+                                                    )}
+                                                </td>
+                                            </tr>
+                                        )
+                                    ];
+                                })}
+                            </tbody>
+                        </table>
+                    )}
+                    <Pagination page={page} totalPages={totalPages} onPageChange={setPage} />
+                </div>
+            );
+        }
+
+        function IgnoredView({ admin }) {
+            const [entries, setEntries] = useState([]);
+            const [value, setValue] = useState('');
+            const [kind, setKind] = useState('path');
+            const [reload, setReload] = useState(0);
+            const [loading, setLoading] = useState(true);
+
+            useEffect(() => {
+                fetch('/api/v1/ignored').then(r => r.json()).then(d => {
+                    setEntries(d.paths || []);
+                    setLoading(false);
+                });
+            }, [reload]);
+
+            const add = () => {
+                fetch('/api/v1/ignored', { method: 'POST', headers: { 'Content-Type': 'application/json' }, body: JSON.stringify({ [kind]: value.trim() }) })
+                    .then(r => r.json())
+                    .then(d => {
+                        if (d.error) { alert(t('common.error', { error: d.error })); return; }
+                        setValue('');
+                        setReload(reload + 1);
+                    });
+            };
+            const remove = (entry) => {
+                if (!confirm(t('ignored.confirm_remove', { path: entry.path, count: entry.orphan_files.toLocaleString() }))) return;
+                fetch('/api/v1/ignored/' + entry.id, { method: 'DELETE' }).then(() => setReload(reload + 1));
+            };
+
+            const columns = [
+                { key: 'path', label: t('columns.path'), sortable: false, render: (v, row) => row.pattern ? <code>{v}</code> : v },
+                { key: 'pattern', label: t('columns.type'), sortable: false, width: '90px', render: (v) => v ? t('ignored.pattern') : t('ignored.path') },
+                { key: 'orphan_files', label: t('ignored.orphan_files'), sortable: false, width: '130px', render: (v) => v.toLocaleString() },
+                { key: 'orphan_size', label: t('ignored.orphan_size'), sortable: false, width: '130px', render: (v) => <span className="size">{formatSize(v)}</span> },
+                { key: 'file_count', label: t('ignored.files'), sortable: false, width: '130px', render: (v, row) => v.toLocaleString() + ' (' + formatSize(row.total_size) + ')' },
+                { key: 'created_at', label: t('columns.created'), sortable: false, width: '180px', render: (v) => v ? new Date(v).toLocaleString() : '-' },
+            ];
+            if (admin) columns.push({ key: 'id', label: '', sortable: false, width: '120px', render: (v, row) => <button className="danger-btn" onClick={() => remove(row)}>{t('ignored.remove')}</button> });
+
+            const hidden = entries.reduce((a, e) => a + e.orphan_files, 0);
+            const hiddenSize = entries.reduce((a, e) => a + e.orphan_size, 0);
+
+            return (
+                <div>
+                    <div className="cards">
+                        <Card title={t('ignored.entries')} value={entries.length.toLocaleString()} />
+                        <Card title={t('ignored.orphan_files')} value={hidden.toLocaleString()} />
+                        <Card title={t('ignored.orphan_size')} value={formatSize(hiddenSize)} />
+                    </div>
+                    {admin && (
+                        <div className="controls">
+                            <select value={kind} onChange={e => setKind(e.target.value)}>
+                                <option value="path">{t('ignored.path')}</option>
+                                <option value="pattern">{t('ignored.pattern')}</option>
+                            </select>
+                            <input className="search" placeholder={t(kind === 'pattern' ? 'ignored.pattern_placeholder' : 'ignored.path_placeholder')} value={value} onChange={e => setValue(e.target.value)} />
+                            <button className="export-btn" onClick={add} disabled={!value.trim()}>{t('ignored.add')}</button>
+                        </div>
+                    )}
+                    <p style={{color: '#888', marginBottom: '15px', fontSize: '14px'}}>{t('ignored.help')}</p>
+                    <DataTable data={entries} columns={columns} loading={loading} onSort={() => {}} />
+                </div>
+            );
+        }
+
+        function StatsTab() {
+            const pieChartRef = useRef(null);
+            const orphanChartRef = useRef(null);
+            const healthChartRef = useRef(null);
+            const pieChartInstance = useRef(null);
+            const orphanChartInstance = useRef(null);
+            const healthChartInstance = useRef(null);
+            const historySizeChartRef = useRef(null);
+            const historyOrphanChartRef = useRef(null);
+            const historySizeChartInstance = useRef(null);
+            const historyOrphanChartInstance = useRef(null);
+            
+            const [torrentStats, setTorrentStats] = useState({ total_files: 0, total_torrents: 0, total_size: 0 });
+            const [localStats, setLocalStats] = useState([]);
+            const [orphanStats, setOrphanStats] = useState([]);
+            const [downloadingStats, setDownloadingStats] = useState([]);
+            const [extras, setExtras] = useState({ categories: [], files: [], total_files: 0, total_size: 0, orphan_files: 0, orphan_bytes: 0 });
+            const [extensionStats, setExtensionStats] = useState([]);
+            const [trackerStats, setTrackerStats] = useState([]);
+            const [dead, setDead] = useState({ torrents: [], total_size: 0, reclaimable_files: 0, reclaimable_bytes: 0 });
+            const [missing, setMissing] = useState({ torrents: [], missing_files: 0, missing_bytes: 0 });
+            const [disks, setDisks] = useState([]);
+            const [topOrphans, setTopOrphans] = useState(false);
+            const [topFiles, setTopFiles] = useState([]);
+            const [topFolders, setTopFolders] = useState([]);
+            const [historyDays, setHistoryDays] = useState(90);
+            const [history, setHistory] = useState([]);
+            const [loading, setLoading] = useState(true);
+
+            useEffect(() => {
+                Promise.all([
+                    fetch('/api/v1/torrent/stats').then(r => r.json()),
+                    fetch('/api/v1/local/stats').then(r => r.json()),
+                    fetch('/api/v1/orphans/stats').then(r => r.json()),
+                    fetch('/api/v1/stats/extensions?limit=20').then(r => r.json()),
+                    fetch('/api/v1/torrent/trackers').then(r => r.json()),
+                    fetch('/api/v1/torrent/dead').then(r => r.json()),
+                    fetch('/api/v1/local/downloading').then(r => r.json()),
+                    fetch('/api/v1/local/extras').then(r => r.json()),
+                    fetch('/api/v1/torrent/missing').then(r => r.json()),
+                    fetch('/api/v1/local/disks').then(r => r.json())
+                ]).then(([ts, ls, os, es, trs, dt, dl, ex, ms, dk]) => {
+                    setTorrentStats(ts);
+                    setLocalStats(ls.categories || []);
+                    setOrphanStats(os.categories || []);
+                    setExtensionStats(es.extensions || []);
+                    setTrackerStats(trs.trackers || []);
+                    if (dt.torrents) setDead(dt);
+                    setDownloadingStats(dl.categories || []);
+                    if (ex.files) setExtras(ex);
+                    if (ms.torrents) setMissing(ms);
+                    setDisks(dk.disks || []);
+                    setLoading(false);
+                });
+            }, []);
+
+            useEffect(() => {
+                let ignore = false;
+                Promise.all([
+                    fetch('/api/v1/local/top?n=10&orphans=' + topOrphans).then(r => r.json()),
+                    fetch('/api/v1/local/top/folders?n=10&orphans=' + topOrphans).then(r => r.json())
+                ]).then(([tf, td]) => {
+                    if (ignore) return;
+                    setTopFiles(tf.files || []);
+                    setTopFolders(td.folders || []);
+                });
+                return () => { ignore = true; };
+            }, [topOrphans]);
+
+            useEffect(() => {
+                let ignore = false;
+                fetch('/api/v1/stats/history?days=' + historyDays).then(r => r.json()).then(d => {
+                    if (!ignore) setHistory(d.points || []);
+                });
+                return () => { ignore = true; };
+            }, [historyDays]);
+
+            useEffect(() => {
+                if (!historySizeChartRef.current || !historyOrphanChartRef.current || history.length === 0) return;
+                if (historySizeChartInstance.current) historySizeChartInstance.current.destroy();
+                if (historyOrphanChartInstance.current) historyOrphanChartInstance.current.destroy();
+                // Une courbe par catégorie, un point par jour enregistré
+                const days = [...new Set(history.map(p => p.day))];
+                const categories = [...new Set(history.map(p => p.category))].sort();
+                const series = (field) => categories.map((c, i) => ({
+                    label: c.toUpperCase(),
+                    data: days.map(d => { const p = history.find(x => x.day === d && x.category === c); return p ? p[field] / (1024*1024*1024) : null; }),
+                    borderColor: categoryColor(c, i), backgroundColor: categoryColor(c, i), tension: 0.2, pointRadius: days.length > 60 ? 0 : 2, spanGaps: true
+                }));
+                const options = {
+                    responsive: true, maintainAspectRatio: false, interaction: { mode: 'index', intersect: false },
+                    plugins: { legend: { labels: { color: '#888' } }, tooltip: { callbacks: { label: (ctx) => ctx.dataset.label + ': ' + formatSize(ctx.raw * 1024*1024*1024) } } },
+                    scales: { x: { ticks: { color: '#888' }, grid: { color: '#222' } }, y: { beginAtZero: true, ticks: { color: '#888' }, grid: { color: '#222' } } }
+                };
+                historySizeChartInstance.current = new Chart(historySizeChartRef.current.getContext('2d'), { type: 'line', data: { labels: days, datasets: series('total_size') }, options });
+                historyOrphanChartInstance.current = new Chart(historyOrphanChartRef.current.getContext('2d'), { type: 'line', data: { labels: days, datasets: series('orphan_size') }, options });
+                return () => {
+                    if (historySizeChartInstance.current) historySizeChartInstance.current.destroy();
+                    if (historyOrphanChartInstance.current) historyOrphanChartInstance.current.destroy();
+                };
+            }, [history, loading]);
+
+            useEffect(() => {
+                if (!healthChartRef.current || localStats.length === 0) return;
+                if (healthChartInstance.current) healthChartInstance.current.destroy();
+                const totalLocal = localStats.reduce((a, c) => a + c.file_count, 0);
+                const totalOrphan = orphanStats.reduce((a, c) => a + c.file_count, 0);
+                const totalDownloading = downloadingStats.reduce((a, c) => a + c.file_count, 0);
+                const healthy = totalLocal - totalOrphan - totalDownloading;
+                const ctx = healthChartRef.current.getContext('2d');
+                healthChartInstance.current = new Chart(ctx, {
+                    type: 'doughnut',
+                    data: {
+                        labels: [t('stats.healthy'), t('stats.orphans'), t('stats.downloading')],
+                        datasets: [{ data: [healthy, totalOrphan, totalDownloading], backgroundColor: ['#2ecc71', '#e74c3c', '#3498db'], borderWidth: 0 }]
+                    },
+                    options: { responsive: true, maintainAspectRatio: false, cutout: '75%', plugins: { legend: { display: false } } }
+                });
+                return () => { if (healthChartInstance.current) healthChartInstance.current.destroy(); };
+            }, [localStats, orphanStats, downloadingStats]);
+
+            useEffect(() => {
+                if (!pieChartRef.current || localStats.length === 0) return;
+                if (pieChartInstance.current) pieChartInstance.current.destroy();
+                const ctx = pieChartRef.current.getContext('2d');
+                pieChartInstance.current = new Chart(ctx, {
+                    type: 'doughnut',
+                    data: {
+                        labels: localStats.map(s => s.category.toUpperCase()),
+                        datasets: [{ data: localStats.map(s => s.total_size), backgroundColor: localStats.map((s, i) => categoryColor(s.category, i)), borderWidth: 0 }]
+                    },
+                    options: {
+                        responsive: true, maintainAspectRatio: false,
+                        plugins: { legend: { position: 'right', labels: { color: '#ccc', padding: 15 } }, tooltip: { callbacks: { label: (ctx) => ctx.label + ': ' + formatSize(ctx.raw) } } }
+                    }
+                });
+                return () => { if (pieChartInstance.current) pieChartInstance.current.destroy(); };
+            }, [localStats]);
+
+            useEffect(() => {
+                if (!orphanChartRef.current || localStats.length === 0) return;
+                if (orphanChartInstance.current) orphanChartInstance.current.destroy();
+                const categories = statsCategories(localStats, orphanStats);
+                const localData = categories.map(c => { const s = localStats.find(x => x.category === c); return s ? s.total_size / (1024*1024*1024) : 0; });
+                const orphanData = categories.map(c => { const s = orphanStats.find(x => x.category === c); return s ? s.total_size / (1024*1024*1024) : 0; });
+                const ctx = orphanChartRef.current.getContext('2d');
+                orphanChartInstance.current = new Chart(ctx, {
+                    type: 'bar',
+                    data: {
+                        labels: categories.map(c => c.toUpperCase()),
+                        datasets: [
+                            { label: t('stats.local_gb'), data: localData, backgroundColor: '#3498db', borderRadius: 4 },
+                            { label: t('stats.orphans_gb'), data: orphanData, backgroundColor: '#e74c3c', borderRadius: 4 }
+                        ]
+                    },
+                    options: { responsive: true, maintainAspectRatio: false, plugins: { legend: { labels: { color: '#888' } } }, scales: { x: { ticks: { color: '#888' }, grid: { color: '#222' } }, y: { ticks: { color: '#888' }, grid: { color: '#222' } } } }
+                });
+                return () => { if (orphanChartInstance.current) orphanChartInstance.current.destroy(); };
+            }, [localStats, orphanStats]);
+
+            if (loading) return <div className="loading">{t('common.loading')}</div>;
+
+            const totalLocalFiles = localStats.reduce((a, c) => a + c.file_count, 0);
+            const totalLocalSize = localStats.reduce((a, c) => a + c.total_size, 0);
+            const totalOrphanFiles = orphanStats.reduce((a, c) => a + c.file_count, 0);
+            const totalOrphanSize = orphanStats.reduce((a, c) => a + c.total_size, 0);
+            const totalLocalAllocated = localStats.reduce((a, c) => a + c.allocated_size, 0);
+            const totalOrphanAllocated = orphanStats.reduce((a, c) => a + c.allocated_size, 0);
+            const orphanPercent = totalLocalFiles > 0 ? ((totalOrphanFiles / totalLocalFiles) * 100).toFixed(1) : 0;
+            const orphanSizePercent = totalLocalSize > 0 ? ((totalOrphanSize / totalLocalSize) * 100).toFixed(1) : 0;
+            const totalDownloadingFiles = downloadingStats.reduce((a, c) => a + c.file_count, 0);
+            const downloadingPercent = totalLocalFiles > 0 ? ((totalDownloadingFiles / totalLocalFiles) * 100).toFixed(1) : 0;
+            const healthyFiles = totalLocalFiles - totalOrphanFiles - totalDownloadingFiles;
+            const healthPercent = totalLocalFiles > 0 ? ((healthyFiles / totalLocalFiles) * 100).toFixed(0) : 100;
+
+            const ProgressBar = ({ percent, color }) => (
+                <div style={{background: '#0f1729', borderRadius: '4px', height: '8px', width: '100%', marginTop: '8px'}}>
+                    <div style={{background: color, borderRadius: '4px', height: '100%', width: percent + '%'}}></div>
+                </div>
+            );
+            return (
+                <div>
+                    <h2 style={{color: '#00d9ff', marginBottom: '20px', fontSize: '18px'}}>📊 {t('stats.overview')}</h2>
+                    <div style={{display: 'grid', gridTemplateColumns: '1fr 1fr', gap: '20px', marginBottom: '30px'}}>
+                        <div style={{display: 'grid', gridTemplateColumns: '1fr 1fr', gap: '15px'}}>
+                            <Card title={t('cards.torrents')} value={(torrentStats.total_torrents || 0).toLocaleString()} sub={t('stats.files_count', { count: torrentStats.total_files?.toLocaleString() })} />
+                            <Card title={t('stats.torrent_space')} value={formatSize(torrentStats.total_size || 0)} />
+                            <Card title={t('stats.local_files')} value={totalLocalFiles.toLocaleString()} />
+                            <Card title={t('stats.local_space')} value={formatSize(totalLocalSize)} sub={t('stats.on_disk', { size: formatSize(totalLocalAllocated) })} />
+                        </div>
                         <div className="card">
-                            <h3>💚 Santé du stockage</h3>
+                            <h3>💚 {t('stats.storage_health')}</h3>
                             <div style={{display: 'flex', alignItems: 'center', gap: '20px', marginTop: '15px', height: 'calc(100% - 40px)'}}>
                                 <div style={{width: '120px', height: '120px', position: 'relative', flexShrink: 0}}>
                                     <canvas ref={healthChartRef}></canvas>
                                     <div style={{position: 'absolute', top: '50%', left: '50%', transform: 'translate(-50%, -50%)', textAlign: 'center'}}>
                                         <div style={{fontSize: '22px', fontWeight: 'bold', color: healthPercent > 80 ? '#2ecc71' : healthPercent > 50 ? '#f39c12' : '#e74c3c'}}>{healthPercent}%</div>
-                                        <div style={{fontSize: '9px', color: '#888'}}>SAIN</div>
+                                        <div style={{fontSize: '9px', color: '#888'}}>{t('stats.healthy_badge')}</div>
                                     </div>
                                 </div>
                                 <div style={{flex: 1}}>
                                     <div style={{marginBottom: '15px'}}>
-                                        <div style={{display: 'flex', justifyContent: 'space-between', fontSize: '13px', marginBottom: '6px'}}><span style={{color: '#2ecc71'}}>● Fichiers sains</span><span>{healthyFiles.toLocaleString()}</span></div>
-                                        <ProgressBar percent={100 - orphanPercent} color="#2ecc71" />
+                                        <div style={{display: 'flex', justifyContent: 'space-between', fontSize: '13px', marginBottom: '6px'}}><span style={{color: '#2ecc71'}}>● {t('stats.healthy_files')}</span><span>{healthyFiles.toLocaleString()}</span></div>
+                                        <ProgressBar percent={100 - orphanPercent - downloadingPercent} color="#2ecc71" />
                                     </div>
-                                    <div>
-                                        <div style={{display: 'flex', justifyContent: 'space-between', fontSize: '13px', marginBottom: '6px'}}><span style={{color: '#e74c3c'}}>● Fichiers orphelins</span><span>{totalOrphanFiles.toLocaleString()}</span></div>
+                                    <div style={{marginBottom: '15px'}}>
+                                        <div style={{display: 'flex', justifyContent: 'space-between', fontSize: '13px', marginBottom: '6px'}}><span style={{color: '#e74c3c'}}>● {t('stats.orphan_files')}</span><span>{totalOrphanFiles.toLocaleString()}</span></div>
                                         <ProgressBar percent={orphanPercent} color="#e74c3c" />
                                     </div>
+                                    <div>
+                                        <div style={{display: 'flex', justifyContent: 'space-between', fontSize: '13px', marginBottom: '6px'}}><span style={{color: '#3498db'}}>● {t('stats.downloading')}</span><span>{totalDownloadingFiles.toLocaleString()}</span></div>
+                                        <ProgressBar percent={downloadingPercent} color="#3498db" />
+                                    </div>
                                 </div>
                             </div>
                         </div>
                     </div>
 
-                    <h2 style={{color: '#00d9ff', margin: '30px 0 20px', fontSize: '18px'}}>🗑️ Orphelins</h2>
+                    {disks.length > 0 && (
+                        <div>
+                            <h2 style={{color: '#00d9ff', margin: '30px 0 20px', fontSize: '18px'}}>💽 {t('stats.disks')}</h2>
+                            <div className="cards">
+                                {disks.map(d => {
+                                    // Couleur selon l'espace restant sur le disque
+                                    const usedPercent = d.total > 0 ? Math.round(100 * d.used / d.total) : 0;
+                                    const color = usedPercent >= 90 ? '#e74c3c' : usedPercent >= 75 ? '#f39c12' : '#2ecc71';
+                                    return (
+                                        <div className="card" key={d.mount_point} title={d.roots.join('\n')}>
+                                            <h3>{d.mount_point}</h3>
+                                            <div className="value" style={{color}}>{formatSize(d.free)}</div>
+                                            <div className="sub">{t('stats.disk_usage', { used: formatSize(d.used), total: formatSize(d.total), percent: usedPercent })}</div>
+                                            <ProgressBar percent={usedPercent} color={color} />
+                                        </div>
+                                    );
+                                })}
+                            </div>
+                        </div>
+                    )}
+
+                    <h2 style={{color: '#00d9ff', margin: '30px 0 20px', fontSize: '18px'}}>🗑️ {t('stats.orphans')}</h2>
+                    <div className="cards">
+                        <div className="card"><h3>{t('stats.orphan_files')}</h3><div className="value" style={{color: '#e74c3c'}}>{totalOrphanFiles.toLocaleString()}</div><div className="sub">{t('stats.percent_of_total', { percent: orphanPercent })}</div><ProgressBar percent={orphanPercent} color="#e74c3c" /></div>
+                        <div className="card"><h3>{t('stats.orphan_space')}</h3><div className="value" style={{color: '#e74c3c'}}>{formatSize(totalOrphanSize)}</div><div className="sub">{t('stats.percent_of_storage', { percent: orphanSizePercent })}</div><ProgressBar percent={orphanSizePercent} color="#e74c3c" /></div>
+                        <div className="card"><h3>{t('stats.reclaimable_space')}</h3><div className="value" style={{color: '#f39c12'}}>{formatSize(totalOrphanAllocated)}</div><div className="sub">{t('stats.reclaimable_hint')}</div></div>
+                    </div>
+
+                    <div style={{display: 'grid', gridTemplateColumns: 'repeat(auto-fit, minmax(300px, 1fr))', gap: '20px', margin: '30px 0'}}>
+                        <div className="chart-container" style={{height: '280px', padding: '15px'}}>
+                            <h3 style={{color: '#888', marginBottom: '15px', fontSize: '14px'}}>📁 {t('stats.category_chart')}</h3>
+                            <div style={{height: 'calc(100% - 30px)'}}><canvas ref={pieChartRef}></canvas></div>
+                        </div>
+                        <div className="chart-container" style={{height: '280px', padding: '15px'}}>
+                            <h3 style={{color: '#888', marginBottom: '15px', fontSize: '14px'}}>📊 {t('stats.local_vs_orphans')}</h3>
+                            <div style={{height: 'calc(100% - 30px)'}}><canvas ref={orphanChartRef}></canvas></div>
+                        </div>
+                    </div>
+
+                    <div style={{display: 'flex', alignItems: 'center', gap: '15px', marginBottom: '20px'}}>
+                        <h2 style={{color: '#00d9ff', fontSize: '18px'}}>📈 {t('stats.history')}</h2>
+                        <select value={historyDays} onChange={e => setHistoryDays(parseInt(e.target.value))}>
+                            {[30, 90, 365].map(d => <option key={d} value={d}>{t('stats.history_days', { days: d })}</option>)}
+                        </select>
+                    </div>
+                    {history.length === 0 ? (
+                        <p style={{color: '#888', marginBottom: '30px', fontSize: '14px'}}>{t('stats.history_empty')}</p>
+                    ) : (
+                        <div style={{display: 'grid', gridTemplateColumns: 'repeat(auto-fit, minmax(300px, 1fr))', gap: '20px', marginBottom: '30px'}}>
+                            <div className="chart-container" style={{height: '280px', padding: '15px'}}>
+                                <h3 style={{color: '#888', marginBottom: '15px', fontSize: '14px'}}>📦 {t('stats.history_size')}</h3>
+                                <div style={{height: 'calc(100% - 30px)'}}><canvas ref={historySizeChartRef}></canvas></div>
+                            </div>
+                            <div className="chart-container" style={{height: '280px', padding: '15px'}}>
+                                <h3 style={{color: '#888', marginBottom: '15px', fontSize: '14px'}}>🗑️ {t('stats.history_orphans')}</h3>
+                                <div style={{height: 'calc(100% - 30px)'}}><canvas ref={historyOrphanChartRef}></canvas></div>
+                            </div>
+                        </div>
+                    )}
+
+                    <h2 style={{color: '#00d9ff', marginBottom: '20px', fontSize: '18px'}}>📋 {t('stats.category_detail')}</h2>
+                    <table>
+                        <thead><tr><th>{t('columns.category')}</th><th>{t('columns.files')}</th><th>{t('columns.size')}</th><th>{t('columns.orphans')}</th><th>{t('columns.orphan_size')}</th><th>{t('columns.orphan_percent')}</th><th>{t('columns.health')}</th></tr></thead>
+                        <tbody>
+                            {statsCategories(localStats, orphanStats).map(cat => {
+                                const local = localStats.find(s => s.category === cat) || { file_count: 0, total_size: 0 };
+                                const orphan = orphanStats.find(s => s.category === cat) || { file_count: 0, total_size: 0 };
+                                const pct = local.file_count > 0 ? ((orphan.file_count / local.file_count) * 100).toFixed(1) : 0;
+                                const health = 100 - pct;
+                                return (
+                                    <tr key={cat}>
+                                        <td><span className={'category ' + cat}>{cat.toUpperCase()}</span></td>
+                                        <td>{local.file_count.toLocaleString()}</td>
+                                        <td className="size">{formatSize(local.total_size)}</td>
+                                        <td style={{color: '#e74c3c'}}>{orphan.file_count.toLocaleString()}</td>
+                                        <td style={{color: '#e74c3c'}}>{formatSize(orphan.total_size)}</td>
+                                        <td style={{color: pct > 50 ? '#e74c3c' : pct > 20 ? '#f39c12' : '#2ecc71', fontWeight: 'bold'}}>{pct}%</td>
+                                        <td><div style={{display: 'flex', alignItems: 'center', gap: '8px'}}><div style={{flex: 1, background: '#0f1729', borderRadius: '4px', height: '6px'}}><div style={{background: health > 80 ? '#2ecc71' : health > 50 ? '#f39c12' : '#e74c3c', borderRadius: '4px', height: '100%', width: health + '%'}}></div></div><span style={{fontSize: '11px', color: '#888'}}>{health.toFixed(0)}%</span></div></td>
+                                    </tr>
+                                );
+                            })}
+                        </tbody>
+                    </table>
+
+                    <h2 style={{color: '#00d9ff', margin: '30px 0 20px', fontSize: '18px'}}>🧩 {t('stats.by_extension')}</h2>
+                    <table>
+                        <thead><tr><th>{t('columns.extension')}</th><th>{t('columns.files')}</th><th>{t('columns.size')}</th><th>{t('columns.categories')}</th><th>{t('columns.orphans')}</th><th>{t('columns.orphan_size')}</th></tr></thead>
+                        <tbody>
+                            {extensionStats.map(e => (
+                                <tr key={e.extension}>
+                                    <td>{e.extension === 'no_extension' ? t('stats.no_extension') : '.' + e.extension}</td>
+                                    <td>{e.file_count.toLocaleString()}</td>
+                                    <td className="size">{formatSize(e.total_size)}</td>
+                                    <td>{e.categories.map(c => <span key={c.category} className={'category ' + c.category} title={formatSize(c.total_size)} style={{marginRight: '4px'}}>{c.category.toUpperCase()}</span>)}</td>
+                                    <td style={{color: '#e74c3c'}}>{e.orphan_files.toLocaleString()}</td>
+                                    <td style={{color: '#e74c3c'}}>{formatSize(e.orphan_size)}</td>
+                                </tr>
+                            ))}
+                        </tbody>
+                    </table>
+
+                    <h2 style={{color: '#00d9ff', margin: '30px 0 20px', fontSize: '18px'}}>📡 {t('stats.by_tracker')}</h2>
+                    <table>
+                        <thead><tr><th>{t('columns.tracker')}</th><th>{t('columns.torrents')}</th><th>{t('columns.size')}</th><th>{t('columns.torrent_space_percent')}</th></tr></thead>
+                        <tbody>
+                            {trackerStats.map(tr => {
+                                const pct = torrentStats.total_size > 0 ? ((tr.total_size / torrentStats.total_size) * 100).toFixed(1) : 0;
+                                return (
+                                    <tr key={tr.tracker}>
+                                        <td>{tr.tracker || <span style={{color: '#888'}}>{t('stats.no_tracker')}</span>}</td>
+                                        <td>{tr.torrent_count.toLocaleString()}</td>
+                                        <td className="size">{formatSize(tr.total_size)}</td>
+                                        <td><div style={{display: 'flex', alignItems: 'center', gap: '8px'}}><div style={{flex: 1, background: '#0f1729', borderRadius: '4px', height: '6px'}}><div style={{background: '#00d9ff', borderRadius: '4px', height: '100%', width: Math.min(pct, 100) + '%'}}></div></div><span style={{fontSize: '11px', color: '#888'}}>{pct}%</span></div></td>
+                                    </tr>
+                                );
+                            })}
+                        </tbody>
+                    </table>
+
+                    <h2 style={{color: '#00d9ff', margin: '30px 0 20px', fontSize: '18px'}}>🎞️ {t('stats.extras')}</h2>
+                    <p style={{color: '#888', marginBottom: '15px', fontSize: '14px'}}>
+                        {t('stats.extras_summary', { files: extras.total_files.toLocaleString(), size: formatSize(extras.total_size), orphans: extras.orphan_files.toLocaleString(), orphan_size: formatSize(extras.orphan_bytes) })}
+                    </p>
+                    {extras.total_files > 0 && (
+                        <table>
+                            <thead><tr><th>{t('columns.type')}</th><th>{t('columns.files')}</th><th>{t('columns.size')}</th></tr></thead>
+                            <tbody>
+                                {extras.categories.map(c => (
+                                    <tr key={c.category}>
+                                        <td><span className={'category ' + c.category}>{c.category}</span></td>
+                                        <td>{c.file_count.toLocaleString()}</td>
+                                        <td className="size">{formatSize(c.total_size)}</td>
+                                    </tr>
+                                ))}
+                            </tbody>
+                        </table>
+                    )}
+                    {extras.files.length > 0 && (
+                        <table style={{marginTop: '15px'}}>
+                            <thead><tr><th>{t('columns.file')}</th><th>{t('columns.type')}</th><th>{t('columns.orphan')}</th><th>{t('columns.size')}</th></tr></thead>
+                            <tbody>
+                                {extras.files.slice(0, 50).map(f => (
+                                    <tr key={f.file_path}>
+                                        <td title={f.file_path}>{f.file_name}</td>
+                                        <td><span className={'category ' + f.category}>{f.category}</span></td>
+                                        <td>{f.orphan ? <span style={{color: '#e74c3c'}}>{t('common.yes')}</span> : t('common.no')}</td>
+                                        <td className="size">{formatSize(f.size)}</td>
+                                    </tr>
+                                ))}
+                            </tbody>
+                        </table>
+                    )}
+
+                    <h2 style={{color: '#00d9ff', margin: '30px 0 20px', fontSize: '18px'}}>💀 {t('stats.dead')}</h2>
+                    <p style={{color: '#888', marginBottom: '15px', fontSize: '14px'}}>
+                        {t('stats.dead_summary', { torrents: dead.torrents.length.toLocaleString(), size: formatSize(dead.total_size), files: dead.reclaimable_files.toLocaleString(), reclaimable: formatSize(dead.reclaimable_bytes) })}
+                    </p>
+                    {dead.torrents.length > 0 && (
+                        <table>
+                            <thead><tr><th>{t('columns.torrent')}</th><th>{t('columns.client')}</th><th>{t('columns.tracker_message')}</th><th>{t('columns.size')}</th></tr></thead>
+                            <tbody>
+                                {dead.torrents.map(torrent => (
+                                    <tr key={torrent.instance + '/' + torrent.hash}>
+                                        <td>{torrent.name}</td>
+                                        <td>{torrent.instance}</td>
+                                        <td style={{color: '#e74c3c'}}>{torrent.tracker_message}</td>
+                                        <td className="size">{formatSize(torrent.size)}</td>
+                                    </tr>
+                                ))}
+                            </tbody>
+                        </table>
+                    )}
+
+                    <h2 style={{color: '#00d9ff', margin: '30px 0 20px', fontSize: '18px'}}>🐘 {t('stats.top')}</h2>
+                    <p style={{color: '#888', marginBottom: '15px', fontSize: '14px'}}>
+                        <label><input type="checkbox" checked={topOrphans} onChange={e => setTopOrphans(e.target.checked)} /> {t('stats.orphans_only')}</label>
+                    </p>
+                    <div style={{display: 'grid', gridTemplateColumns: 'repeat(auto-fit, minmax(300px, 1fr))', gap: '20px'}}>
+                        <table>
+                            <thead><tr><th>{t('columns.file')}</th><th>{t('columns.size')}</th></tr></thead>
+                            <tbody>
+                                {topFiles.map(f => (
+                                    <tr key={f.file_path}>
+                                        <td className="path">{f.file_path}{f.orphan && !topOrphans ? ' 🗑️' : ''}</td>
+                                        <td className="size">{formatSize(f.size)}</td>
+                                    </tr>
+                                ))}
+                            </tbody>
+                        </table>
+                        <table>
+                            <thead><tr><th>{t('columns.folder')}</th><th>{t('columns.files')}</th><th>{t('columns.size')}</th></tr></thead>
+                            <tbody>
+                                {topFolders.map(f => (
+                                    <tr key={f.path}>
+                                        <td className="path">{f.path}</td>
+                                        <td>{f.file_count.toLocaleString()}</td>
+                                        <td className="size">{formatSize(f.total_size)}</td>
+                                    </tr>
+                                ))}
+                            </tbody>
+                        </table>
+                    </div>
+
+                    <h2 style={{color: '#00d9ff', margin: '30px 0 20px', fontSize: '18px'}}>🔍 {t('stats.missing')}</h2>
+                    <p style={{color: '#888', marginBottom: '15px', fontSize: '14px'}}>
+                        {t('stats.missing_summary', { torrents: missing.torrents.length.toLocaleString(), files: missing.missing_files.toLocaleString(), size: formatSize(missing.missing_bytes) })}
+                    </p>
+                    {missing.torrents.length > 0 && (
+                        <table>
+                            <thead><tr><th>{t('columns.torrent')}</th><th>{t('columns.client')}</th><th>{t('columns.state')}</th><th>{t('columns.present')}</th><th>{t('columns.missing_files')}</th><th>{t('columns.missing_size')}</th></tr></thead>
+                            <tbody>
+                                {missing.torrents.slice(0, 50).map(torrent => (
+                                    <tr key={torrent.instance + '/' + torrent.hash}>
+                                        <td>{torrent.name}</td>
+                                        <td>{torrent.instance}</td>
+                                        <td>{stateLabel(torrent.state)}</td>
+                                        <td>{torrent.completeness.toFixed(1)}%</td>
+                                        <td>{torrent.missing_files.toLocaleString()} / {torrent.file_count.toLocaleString()}</td>
+                                        <td className="size">{formatSize(torrent.missing_bytes)}</td>
+                                    </tr>
+                                ))}
+                            </tbody>
+                        </table>
+                    )}
+                </div>
+            );
+        }
+
+        function MissingTab({ admin }) {
+            const [report, setReport] = useState(null);
+            const [search, setSearch] = useState('');
+            const [kind, setKind] = useState('all');
+            const [files, setFiles] = useState({});
+            const [rechecked, setRechecked] = useState({});
+
+            useEffect(() => {
+                fetch('/api/v1/torrent/missing').then(r => r.json()).then(setReport);
+            }, []);
+
+            const keyOf = (torrent) => torrent.instance + '/' + torrent.hash;
+
+            // Les fichiers d'un torrent sont chargés à son premier dépliage
+            const toggle = (torrent) => {
+                const key = keyOf(torrent);
+                if (key in files) {
+                    const next = { ...files };
+                    delete next[key];
+                    setFiles(next);
+                    return;
+                }
+                setFiles(f => ({ ...f, [key]: null }));
+                fetch('/api/v1/torrent/missing/' + encodeURIComponent(torrent.instance) + '/' + torrent.hash)
+                    .then(r => r.json())
+                    .then(d => setFiles(f => key in f ? { ...f, [key]: d.files || [] } : f));
+            };
+
+            const recheck = (torrent) => {
+                fetch('/api/v1/torrent/torrents/' + encodeURIComponent(torrent.instance) + '/' + torrent.hash + '/recheck', { method: 'POST' })
+                    .then(r => r.ok ? null : r.json())
+                    .then(d => {
+                        if (d && d.error) alert(t('common.error', { error: d.error }));
+                        else setRechecked(rc => ({ ...rc, [keyOf(torrent)]: true }));
+                    });
+            };
+
+            if (!report) return <div className="loading">{t('common.loading')}</div>;
+            if (report.error) return <div className="loading">{t('common.error', { error: report.error })}</div>;
+
+            const query = search.toLowerCase();
+            const torrents = report.torrents.filter(torrent =>
+                (kind === 'all' || (kind === 'missing' ? torrent.missing_files > 0 : torrent.mismatched_files > 0)) &&
+                torrent.name.toLowerCase().includes(query));
+            const columns = admin ? 8 : 7;
+
+            return (
+                <div className="missing">
                     <div className="cards">
-                        <div className="card"><h3>Fichiers orphelins</h3><div className="value" style={{color: '#e74c3c'}}>{totalOrphanFiles.toLocaleString()}</div><div className="sub">{orphanPercent}% du total</div><ProgressBar percent={orphanPercent} color="#e74c3c" /></div>
-                        <div className="card"><h3>Espace orphelin</h3><div className="value" style={{color: '#e74c3c'}}>{formatSize(totalOrphanSize)}</div><div className="sub">{orphanSizePercent}% du stockage</div><ProgressBar percent={orphanSizePercent} color="#e74c3c" /></div>
-                        <div className="card"><h3>Espace récupérable</h3><div className="value" style={{color: '#f39c12'}}>{formatSize(totalOrphanSize)}</div><div className="sub">Si nettoyage complet</div></div>
+                        <Card title={t('missing.torrents')} value={report.torrents.length.toLocaleString()} />
+                        <Card title={t('missing.missing_files')} value={report.missing_files.toLocaleString()} sub={formatSize(report.missing_bytes)} />
+                        <Card title={t('missing.mismatched_files')} value={report.mismatched_files.toLocaleString()} sub={formatSize(report.mismatched_bytes)} />
                     </div>
-
-                    <div style={{display: 'grid', gridTemplateColumns: 'repeat(auto-fit, minmax(300px, 1fr))', gap: '20px', margin: '30px 0'}}>
-                        <div className="chart-container" style={{height: '280px', padding: '15px'}}>
-                            <h3 style={{color: '#888', marginBottom: '15px', fontSize: '14px'}}>📁 Répartition par catégorie</h3>
-                            <div style={{height: 'calc(100% - 30px)'}}><canvas ref={pieChartRef}></canvas></div>
-                        </div>
-                        <div className="chart-container" style={{height: '280px', padding: '15px'}}>
-                            <h3 style={{color: '#888', marginBottom: '15px', fontSize: '14px'}}>📊 Local vs Orphelins (GB)</h3>
-                            <div style={{height: 'calc(100% - 30px)'}}><canvas ref={orphanChartRef}></canvas></div>
-                        </div>
+                    <div className="controls">
+                        <input className="search" placeholder={t('common.search')} value={search} onChange={e => setSearch(e.target.value)} />
+                        <select value={kind} onChange={e => setKind(e.target.value)}>
+                            <option value="all">{t('missing.all')}</option>
+                            <option value="missing">{t('missing.only_missing')}</option>
+                            <option value="mismatched">{t('missing.only_mismatched')}</option>
+                        </select>
+                        <span>{t('missing.count', { count: torrents.length.toLocaleString() })}</span>
                     </div>
-
-                    <h2 style={{color: '#00d9ff', marginBottom: '20px', fontSize: '18px'}}>📋 Détail par catégorie</h2>
+                    <p style={{color: '#888', marginBottom: '15px', fontSize: '14px'}}>{t('missing.help')}</p>
                     <table>
-                        <thead><tr><th>Catégorie</th><th>Fichiers</th><th>Taille</th><th>Orphelins</th><th>Taille orph.</th><th>% Orph.</th><th>Santé</th></tr></thead>
+                        <thead><tr><th></th><th>{t('columns.torrent')}</th><th>{t('columns.client')}</th><th>{t('columns.state')}</th><th>{t('columns.present')}</th><th>{t('columns.missing_files')}</th><th>{t('columns.mismatched_files')}</th>{admin && <th></th>}</tr></thead>
                         <tbody>
-                            {['4k', 'movies', 'shows', 'unknown'].map(cat => {
-                                const local = localStats.find(s => s.category === cat) || { file_count: 0, total_size: 0 };
-                                const orphan = orphanStats.find(s => s.category === cat) || { file_count: 0, total_size: 0 };
-                                const pct = local.file_count > 0 ? ((orphan.file_count / local.file_count) * 100).toFixed(1) : 0;
-                                const health = 100 - pct;
-                                return (
-                                    <tr key={cat}>
-                                        <td><span className={'category ' + cat}>{cat.toUpperCase()}</span></td>
-                                        <td>{local.file_count.toLocaleString()}</td>
-                                        <td className="size">{formatSize(local.total_size)}</td>
-                                        <td style={{color: '#e74c3c'}}>{orphan.file_count.toLocaleString()}</td>
-                                        <td style={{color: '#e74c3c'}}>{formatSize(orphan.total_size)}</td>
-                                        <td style={{color: pct > 50 ? '#e74c3c' : pct > 20 ? '#f39c12' : '#2ecc71', fontWeight: 'bold'}}>{pct}%</td>
-                                        <td><div style={{display: 'flex', alignItems: 'center', gap: '8px'}}><div style={{flex: 1, background: '#0f1729', borderRadius: '4px', height: '6px'}}><div style={{background: health > 80 ? '#2ecc71' : health > 50 ? '#f39c12' : '#e74c3c', borderRadius: '4px', height: '100%', width: health + '%'}}></div></div><span style={{fontSize: '11px', color: '#888'}}>{health.toFixed(0)}%</span></div></td>
-                                    </tr>
-                                );
+                            {torrents.map(torrent => {
+                                const key = keyOf(torrent);
+                                const list = files[key];
+                                return [
+                                    <tr key={key}>
+                                        <td><button className="row-btn" onClick={() => toggle(torrent)}>{key in files ? '▾' : '▸'}</button></td>
+                                        <td>{torrent.name}</td>
+                                        <td>{torrent.instance}</td>
+                                        <td>{torrent.state ? <span className={'state ' + torrent.state}>{stateLabel(torrent.state)}</span> : '—'}</td>
+                                        <td>{torrent.completeness.toFixed(1)}%</td>
+                                        <td className="absent">{torrent.missing_files.toLocaleString()} / {torrent.file_count.toLocaleString()} ({formatSize(torrent.missing_bytes)})</td>
+                                        <td className="mismatch">{torrent.mismatched_files.toLocaleString()} ({formatSize(torrent.mismatched_bytes)})</td>
+                                        {admin && <td>{rechecked[key] ? t('missing.rechecked') : <button className="row-btn" onClick={() => recheck(torrent)}>{t('torrents.recheck')}</button>}</td>}
+                                    </tr>,
+                                    key in files && (
+                                        <tr key={key + '/files'} className="files">
+                                            <td colSpan={columns}>
+                                                {list === null ? t('common.loading') : (
+                                                    <table>
+                                                        <thead><tr><th>{t('columns.path')}</th><th>{t('columns.size')}</th><th>{t('columns.local_size')}</th></tr></thead>
+                                                        <tbody>
+                                                            {list.map(f => (
+                                                                <tr key={f.file_path}>
+                                                                    <td>{f.file_path}</td>
+                                                                    <td className="size">{formatSize(f.size)}</td>
+                                                                    <td className={f.mismatched ? 'mismatch' : 'absent'}>{f.mismatched ? formatSize(f.local_size) : t('missing.absent')}</td>
+                                                                </tr>
+                                                            ))}
+                                                        </tbody>
+                                                    </table>
+                                                )}
+                                            </td>
+                                        </tr>
+                                    )
+                                ];
                             })}
                         </tbody>
                     </table>
@@ -494,27 +2056,719 @@ const indexTemplate = `<!DOCTYPE html>
             );
         }
 
+        function DuplicatesTab({ admin }) {
+            const [by, setBy] = useState('checksum');
+            const [data, setData] = useState(null);
+            const [noChecksums, setNoChecksums] = useState(false);
+            const [search, setSearch] = useState('');
+            const [page, setPage] = useState(1);
+            const [selected, setSelected] = useState(new Set());
+            const [deleting, setDeleting] = useState(false);
+            const [lookup, setLookup] = useState('');
+            const [reload, setReload] = useState(0);
+            const perPage = 50;
+
+            useEffect(() => {
+                let ignore = false;
+                fetch('/api/v1/local/duplicates?by=' + by).then(r => r.json()).then(d => {
+                    if (ignore) return;
+                    // Sans empreintes, les doublons sont cherchés par nom et taille
+                    if (d.error && by === 'checksum') {
+                        setNoChecksums(true);
+                        setBy('name');
+                        return;
+                    }
+                    setData(d);
+                });
+                return () => { ignore = true; };
+            }, [by, reload]);
+
+            const keyOf = (g) => (g.checksum || g.name) + '/' + g.size;
+            const deletable = (g) => g.files.filter(f => f.deletable);
+
+            if (!data) return <div className="loading">{t('common.loading')}</div>;
+            if (data.error) return <div className="loading">{t('common.error', { error: data.error })}</div>;
+
+            const query = search.toLowerCase();
+            const groups = data.groups.filter(g => g.files.some(f => f.file_path.toLowerCase().includes(query)));
+            const totalPages = Math.max(1, Math.ceil(groups.length / perPage));
+            const shown = groups.slice((page - 1) * perPage, page * perPage);
+            const reclaimable = data.groups.reduce((a, g) => a + deletable(g).length * g.size, 0);
+            const chosen = data.groups.filter(g => selected.has(keyOf(g)));
+            const chosenFiles = chosen.reduce((a, g) => a.concat(deletable(g)), []);
+            const chosenBytes = chosenFiles.reduce((a, f) => a + f.size, 0);
+
+            const toggle = (g) => {
+                const next = new Set(selected);
+                if (next.has(keyOf(g))) next.delete(keyOf(g)); else next.add(keyOf(g));
+                setSelected(next);
+            };
+            const selectAll = () => setSelected(new Set(groups.filter(g => deletable(g).length > 0).map(keyOf)));
+
+            // Seules les copies orphelines sont supprimées, comme dans l'onglet Orphelins
+            const deleteSelected = () => {
+                const request = (body) => fetch('/api/v1/orphans/files', { method: 'DELETE', headers: { 'Content-Type': 'application/json' }, body: JSON.stringify(body) }).then(r => r.json());
+                const done = () => { setDeleting(false); setSelected(new Set()); setReload(reload + 1); };
+                setDeleting(true);
+                request({ paths: chosenFiles.map(f => f.file_path) }).then(preview => {
+                    if (preview.error) { alert(t('common.error', { error: preview.error })); return done(); }
+                    const action = preview.report.quarantine ? 'orphans.confirm_quarantine' : preview.report.archive ? 'orphans.confirm_archive' : 'orphans.confirm_delete';
+                    const skipped = preview.protected ? t('orphans.protected_skipped', { count: preview.protected }) : '';
+                    if (!confirm(t(action, { files: preview.files, size: formatSize(preview.bytes), skipped }))) return done();
+                    request({ token: preview.token }).then(d => {
+                        if (d.error) alert(t('common.error', { error: d.error }));
+                        else alert(t('orphans.cleanup_done', { files: d.files_removed, size: formatSize(d.bytes_reclaimed) }) + (d.failed ? t('common.failed_count', { count: d.failed }) : ''));
+                        done();
+                    });
+                });
+            };
+
+            const copyLabel = (f) => {
+                if (f.deletable) return <span className="deletable">{t('duplicates.deletable')}</span>;
+                if (f.status === 'orphan') return <span className="kept">{t('duplicates.kept')}</span>;
+                return <span className="kept">{t('file_details.status_' + f.status)}</span>;
+            };
+
+            return (
+                <div className="duplicates">
+                    <div className="cards">
+                        <Card title={t('duplicates.groups')} value={data.groups.length.toLocaleString()} />
+                        <Card title={t('duplicates.wasted')} value={formatSize(data.wasted_bytes)} />
+                        <Card title={t('duplicates.reclaimable')} value={formatSize(reclaimable)} sub={t('duplicates.reclaimable_hint')} />
+                    </div>
+                    <div className="controls">
+                        <input className="search" placeholder={t('common.search')} value={search} onChange={e => { setSearch(e.target.value); setPage(1); }} />
+                        <select value={by} onChange={e => { setBy(e.target.value); setData(null); setSelected(new Set()); setPage(1); }}>
+                            <option value="checksum" disabled={noChecksums}>{t('duplicates.by_checksum')}</option>
+                            <option value="name">{t('duplicates.by_name')}</option>
+                        </select>
+                        {admin && <button className="row-btn" onClick={selectAll} disabled={deleting}>{t('duplicates.select_all')}</button>}
+                        {admin && <button className="danger-btn" onClick={deleteSelected} disabled={chosenFiles.length === 0 || deleting}>{t('duplicates.delete_selected', { count: chosenFiles.length.toLocaleString() })}</button>}
+                    </div>
+                    {noChecksums && <p style={{color: '#888', marginBottom: '15px', fontSize: '14px'}}>{t('duplicates.no_checksums')}</p>}
+                    {chosen.length > 0 && (
+                        <div className="selection">
+                            <span>{t('duplicates.selection', { groups: chosen.length.toLocaleString(), count: chosenFiles.length.toLocaleString(), size: formatSize(chosenBytes) })}</span>
+                            <a href="#" onClick={e => { e.preventDefault(); setSelected(new Set()); }}>{t('orphans.clear_selection')}</a>
+                        </div>
+                    )}
+                    {lookup && <FileDetails path={lookup} onClose={() => setLookup('')} />}
+                    <table>
+                        <thead><tr>{admin && <th></th>}<th>{t('columns.path')}</th><th>{t('columns.category')}</th><th>{t('columns.age')}</th><th>{t('columns.status')}</th></tr></thead>
+                        <tbody>
+                            {shown.map(g => [
+                                <tr key={keyOf(g)} className="group">
+                                    {admin && <td><input type="checkbox" checked={selected.has(keyOf(g))} onChange={() => toggle(g)} disabled={deletable(g).length === 0} /></td>}
+                                    <td colSpan={4}>{t('duplicates.group', { copies: g.copies, size: formatSize(g.size), wasted: formatSize(g.wasted_bytes) })}</td>
+                                </tr>
+                            ].concat(g.files.map(f => (
+                                <tr key={keyOf(g) + '/' + f.file_path}>
+                                    {admin && <td></td>}
+                                    <td><a href="#" onClick={e => { e.preventDefault(); setLookup(f.file_path); }}>{f.file_path}</a></td>
+                                    <td><span className={'category ' + f.category}>{f.category.toUpperCase()}</span></td>
+                                    <td>{formatAge(f.mod_time)}</td>
+                                    <td>{copyLabel(f)}</td>
+                                </tr>
+                            ))))}
+                        </tbody>
+                    </table>
+                    {groups.length === 0 && <div className="loading">{t('duplicates.none')}</div>}
+                    <Pagination page={page} totalPages={totalPages} onPageChange={setPage} />
+                </div>
+            );
+        }
+
+        const syncStages = {
+            torrents: 'sync.stage_torrents',
+            torrents_insert: 'sync.stage_torrents_insert',
+            scan: 'sync.stage_scan',
+            insert: 'sync.stage_insert',
+            cleanup: 'sync.stage_cleanup',
+            checksum: 'sync.stage_checksum',
+        };
+
+        function syncPercent(p) {
+            if (p.stage === 'torrents') return p.torrents_total ? p.torrents_done / p.torrents_total * 100 : 0;
+            if (p.stage === 'torrents_insert' || p.stage === 'insert') return p.insert_total ? p.inserted / p.insert_total * 100 : 0;
+            if (p.stage === 'cleanup' || p.stage === 'done') return 100;
+            if (p.stage === 'checksum') return p.checksum_total ? p.checksummed / p.checksum_total * 100 : 100;
+            if (p.stage === 'scan' && p.scan && p.scan.eta_seconds > 0) return p.scan.elapsed_seconds / (p.scan.elapsed_seconds + p.scan.eta_seconds) * 100;
+            return null;
+        }
+
+        function HistoryTab({ initialView }) {
+            const [view, setView] = useState(initialView || 'deletions');
+            return (
+                <div>
+                    <div className="views">
+                        <button className={'tab' + (view === 'deletions' ? ' active' : '')} onClick={() => setView('deletions')}>{t('history.view_deletions')}</button>
+                        <button className={'tab' + (view === 'syncs' ? ' active' : '')} onClick={() => setView('syncs')}>{t('history.view_syncs')}</button>
+                        <button className={'tab' + (view === 'errors' ? ' active' : '')} onClick={() => setView('errors')}>{t('history.view_errors')}</button>
+                    </div>
+                    {view === 'deletions' && <DeletionHistory />}
+                    {view === 'syncs' && <SyncHistory />}
+                    {view === 'errors' && <SyncErrors />}
+                </div>
+            );
+        }
+
+        function DeletionHistory() {
+            const [runs, setRuns] = useState([]);
+            const [files, setFiles] = useState({});
+            const [loading, setLoading] = useState(true);
+
+            useEffect(() => {
+                fetch('/api/v1/jobs/runs?limit=500').then(r => r.json()).then(d => {
+                    setRuns(d.runs || []);
+                    setLoading(false);
+                });
+            }, []);
+
+            // Les fichiers d'une exécution sont chargés à son premier dépliage
+            const toggle = (run) => {
+                if (run.id in files) {
+                    const next = { ...files };
+                    delete next[run.id];
+                    setFiles(next);
+                    return;
+                }
+                setFiles(f => ({ ...f, [run.id]: null }));
+                fetch('/api/v1/jobs/runs/' + run.id + '/files').then(r => r.json()).then(d => setFiles(f => run.id in f ? { ...f, [run.id]: d.files || [] } : f));
+            };
+
+            const origin = (run) => {
+                if (run.source === 'web') return t('history.source_web', { user: run.user || t('history.api') });
+                if (run.source === 'cli') return t('history.source_cli');
+                return t('history.source_job', { name: run.job_name || '#' + run.job_id });
+            };
+            const mode = (run) => run.dry_run ? t('history.mode_dry_run') : run.quarantine ? t('history.mode_quarantine') : t('history.mode_delete');
+
+            const done = runs.filter(r => !r.dry_run);
+            const reclaimed = done.reduce((a, r) => a + r.bytes_reclaimed, 0);
+            const removed = done.reduce((a, r) => a + r.files_removed, 0);
+
+            return (
+                <div className="missing">
+                    <div className="cards">
+                        <Card title={t('history.runs')} value={runs.length.toLocaleString()} />
+                        <Card title={t('history.files_removed')} value={removed.toLocaleString()} />
+                        <Card title={t('history.reclaimed')} value={formatSize(reclaimed)} />
+                    </div>
+                    <p style={{color: '#888', marginBottom: '15px', fontSize: '14px'}}>{t('history.deletions_help')}</p>
+                    {loading ? <div className="loading">{t('common.loading')}</div> : (
+                        <table>
+                            <thead><tr><th style={{width: '50px'}}></th><th>{t('history.date')}</th><th>{t('history.origin')}</th><th>{t('history.mode')}</th><th>{t('columns.files')}</th><th>{t('history.reclaimed')}</th><th>{t('columns.status')}</th></tr></thead>
+                            <tbody>
+                                {runs.map(run => {
+                                    const list = files[run.id];
+                                    return [
+                                        <tr key={run.id}>
+                                            <td><button className="row-btn" onClick={() => toggle(run)}>{run.id in files ? '▾' : '▸'}</button></td>
+                                            <td>{new Date(run.started_at).toLocaleString()}</td>
+                                            <td>{origin(run)}</td>
+                                            <td>{mode(run)}</td>
+                                            <td>{run.files_removed.toLocaleString()}</td>
+                                            <td className="size">{formatSize(run.bytes_reclaimed)}</td>
+                                            <td className={run.error || run.failed ? 'absent' : undefined}>{run.error ? t('common.error', { error: run.error }) : run.failed ? t('history.failed', { count: run.failed }) : t('history.ok')}</td>
+                                        </tr>,
+                                        run.id in files && (
+                                            <tr key={run.id + '/files'} className="files">
+                                                <td colSpan={7}>
+                                                    {list === null ? t('common.loading') : (
+                                                        <table>
+                                                            <thead><tr><th>{t('columns.path')}</th><th>{t('columns.size')}</th><th>{t('history.result')}</th></tr></thead>
+                                                            <tbody>
+                                                                {list.map((f, i) => (
+                                                                    <tr key={i}>
+                                                                        <td>{f.file_path}</td>
+                                                                        <td className="size">{formatSize(f.size)}</td>
+                                                                        <td className={f.error ? 'absent' : undefined}>{f.error ? f.error : f.moved_to ? t('history.moved_to', { path: f.moved_to }) : run.dry_run ? t('history.mode_dry_run') : t('history.deleted')}</td>
+                                                                    </tr>
+                                                                ))}
+                                                            </tbody>
+                                                        </table>
+                                                    )}
+                                                </td>
+                                            </tr>
+                                        )
+                                    ];
+                                })}
+                            </tbody>
+                        </table>
+                    )}
+                </div>
+            );
+        }
+
+        function SyncHistory() {
+            const [runs, setRuns] = useState([]);
+            const [loading, setLoading] = useState(true);
+
+            useEffect(() => {
+                fetch('/api/v1/sync/runs?limit=500').then(r => r.json()).then(d => {
+                    setRuns(d.runs || []);
+                    setLoading(false);
+                });
+            }, []);
+
+            const columns = [
+                { key: 'started_at', label: t('history.date'), sortable: false, render: (v) => new Date(v).toLocaleString() },
+                { key: 'duration_seconds', label: t('history.duration'), sortable: false, render: (v) => t('history.seconds', { seconds: v.toFixed(1) }) },
+                { key: 'status', label: t('columns.status'), sortable: false, render: (v, row) => <span className={v === 'done' ? undefined : 'absent'} title={row.error}>{t('history.status_' + v)}</span> },
+                { key: 'full', label: t('history.full'), sortable: false, render: (v) => v ? t('common.yes') : t('common.no') },
+                { key: 'torrents', label: t('history.torrents'), sortable: false, render: (v, row) => row.torrents_synced ? v.toLocaleString() + (row.torrents_failed ? ' (' + t('history.failed', { count: row.torrents_failed }) + ')' : '') : t('history.kept') },
+                { key: 'torrent_files', label: t('history.torrent_files'), sortable: false, render: (v) => v.toLocaleString() },
+                { key: 'local_files', label: t('history.local_files'), sortable: false, render: (v, row) => row.local_synced ? v.toLocaleString() : t('history.kept') },
+                { key: 'error', label: t('history.error'), sortable: false, render: (v) => v || '-' },
+            ];
+
+            return (
+                <div className="missing">
+                    <DataTable data={runs} columns={columns} loading={loading} onSort={() => {}} />
+                </div>
+            );
+        }
+
+        // Torrents et clients illisibles d'une synchronisation : les fichiers
+        // d'un nouveau torrent illisible manquent en base et passent pour orphelins
+        function SyncErrors() {
+            const [runs, setRuns] = useState([]);
+            const [run, setRun] = useState('last');
+            const [report, setReport] = useState(null);
+            const [loading, setLoading] = useState(true);
+
+            useEffect(() => {
+                fetch('/api/v1/sync/runs?limit=30').then(r => r.json()).then(d => setRuns(d.runs || []));
+            }, []);
+
+            useEffect(() => {
+                let ignore = false;
+                setLoading(true);
+                fetch('/api/v1/sync/runs/' + run + '/errors').then(r => r.json()).then(d => {
+                    if (!ignore) {
+                        setReport(d.error ? null : d);
+                        setLoading(false);
+                    }
+                });
+                return () => { ignore = true; };
+            }, [run]);
+
+            const errors = report ? report.errors : [];
+            const missing = errors.filter(e => e.hash && e.kept_files === 0).length;
+            const columns = [
+                { key: 'instance', label: t('columns.client'), sortable: false, width: '140px' },
+                { key: 'name', label: t('columns.torrent'), sortable: false, render: (v, row) => row.hash ? <span title={row.hash}>{v}</span> : <span className="absent">{t('sync_errors.client')}</span> },
+                { key: 'kept_files', label: t('sync_errors.kept_files'), sortable: false, width: '200px', render: (v, row) => !row.hash ? t('sync_errors.all_kept') : v > 0 ? v.toLocaleString() : <span className="absent">{t('sync_errors.not_in_db')}</span> },
+                { key: 'error', label: t('history.error'), sortable: false },
+            ];
+
+            return (
+                <div className="missing">
+                    <div className="controls">
+                        <select value={run} onChange={e => setRun(e.target.value)}>
+                            <option value="last">{t('sync_errors.last')}</option>
+                            {runs.map(r => <option key={r.id} value={r.id}>{new Date(r.started_at).toLocaleString() + (r.torrents_failed > 0 || !r.torrents_synced ? ' ⚠️' : '')}</option>)}
+                        </select>
+                    </div>
+                    {report && (
+                        <div className="cards">
+                            <Card title={t('sync_errors.errors')} value={errors.length.toLocaleString()} />
+                            <Card title={t('sync_errors.missing')} value={missing.toLocaleString()} />
+                        </div>
+                    )}
+                    <p style={{color: '#888', marginBottom: '15px', fontSize: '14px'}}>{t('sync_errors.help')}</p>
+                    <DataTable data={errors} columns={columns} loading={loading} onSort={() => {}} />
+                </div>
+            );
+        }
+
+        function KeysTab() {
+            const [keys, setKeys] = useState([]);
+            const [name, setName] = useState('');
+            const [scope, setScope] = useState('read');
+            const [created, setCreated] = useState(null);
+            const [reload, setReload] = useState(0);
+            const [loading, setLoading] = useState(true);
+
+            useEffect(() => {
+                fetch('/api/v1/keys').then(r => r.json()).then(d => {
+                    setKeys(d.keys || []);
+                    setLoading(false);
+                });
+            }, [reload]);
+
+            const create = () => {
+                fetch('/api/v1/keys', { method: 'POST', headers: { 'Content-Type': 'application/json' }, body: JSON.stringify({ name, scope }) })
+                    .then(r => r.json())
+                    .then(d => {
+                        if (d.error) { alert(t('common.error', { error: d.error })); return; }
+                        setCreated(d);
+                        setName('');
+                        setReload(reload + 1);
+                    });
+            };
+            const revoke = (key) => {
+                if (!confirm(t('keys.confirm_revoke', { name: key.name }))) return;
+                fetch('/api/v1/keys/' + key.id, { method: 'DELETE' }).then(() => setReload(reload + 1));
+            };
+
+            const formatDate = (v) => v ? new Date(v).toLocaleString() : '-';
+            const columns = [
+                { key: 'name', label: t('columns.name'), sortable: false },
+                { key: 'prefix', label: t('columns.key'), sortable: false, render: (v) => <code>{v}…</code> },
+                { key: 'scope', label: t('columns.scope'), sortable: false, render: (v) => v === 'write' ? t('keys.scope_write') : t('keys.scope_read') },
+                { key: 'created_at', label: t('columns.created'), sortable: false, render: formatDate },
+                { key: 'last_used_at', label: t('columns.last_used'), sortable: false, render: formatDate },
+                { key: 'revoked_at', label: '', sortable: false, width: '120px', render: (v, row) => v
+                    ? t('keys.revoked')
+                    : <button className="danger-btn" onClick={() => revoke(row)}>{t('keys.revoke')}</button> },
+            ];
+
+            return (
+                <div>
+                    {created && (
+                        <div className="new-key">
+                            {t('keys.created', { name: created.name })}
+                            <code>{created.key}</code>
+                        </div>
+                    )}
+                    <div className="controls">
+                        <input className="search" placeholder={t('keys.name_placeholder')} value={name} onChange={e => setName(e.target.value)} />
+                        <select value={scope} onChange={e => setScope(e.target.value)}>
+                            <option value="read">{t('keys.read')}</option>
+                            <option value="write">{t('keys.read_write')}</option>
+                        </select>
+                        <button className="export-btn" onClick={create} disabled={!name.trim()}>{t('keys.create')}</button>
+                    </div>
+                    <DataTable data={keys} columns={columns} loading={loading} onSort={() => {}} />
+                </div>
+            );
+        }
+
+        // Liste éditable : une ligne de champs par élément, ajout et suppression
+        function ListEditor({ items, fields, empty, onChange }) {
+            const update = (i, key, value) => onChange(items.map((it, j) => j === i ? { ...it, [key]: value } : it));
+            return (
+                <div>
+                    {items.map((it, i) => (
+                        <div className="row" key={i}>
+                            {fields.map(f => (
+                                <input key={f.key} className={f.narrow ? 'narrow' : ''} placeholder={f.label} title={f.label} value={it[f.key]} onChange={e => update(i, f.key, e.target.value)} />
+                            ))}
+                            <button className="row-btn" onClick={() => onChange(items.filter((_, j) => j !== i))}>{t('settings.remove')}</button>
+                        </div>
+                    ))}
+                    <button className="row-btn" onClick={() => onChange(items.concat([{ ...empty }]))}>{t('settings.add')}</button>
+                </div>
+            );
+        }
+
+        function SettingsTab() {
+            const [settings, setSettings] = useState(null);
+            const [form, setForm] = useState(null);
+            const [jobs, setJobs] = useState([]);
+            const [schedules, setSchedules] = useState({});
+            const [message, setMessage] = useState('');
+            const [saving, setSaving] = useState(false);
+
+            // Les listes sont éditées sous forme de texte, converties à l'enregistrement
+            const toForm = (d) => ({
+                scan_roots: d.scan_roots.map(r => ({ path: r.path, max_depth: r.max_depth ? String(r.max_depth) : '', include: (r.include || []).join(', ') })),
+                category_rules: d.category_rules.map(r => ({ name: r.name, pattern: r.pattern })),
+                relative_markers: d.relative_markers.join(', '),
+                path_rewrites: d.path_rewrites.map(r => ({ from: r.from, to: r.to })),
+            });
+            const split = (v) => v.split(',').map(x => x.trim()).filter(x => x);
+
+            useEffect(() => {
+                fetch('/api/v1/config').then(r => r.json()).then(d => {
+                    if (d.error) { setMessage(t('common.error', { error: d.error })); return; }
+                    setSettings(d);
+                    setForm(toForm(d));
+                });
+                fetch('/api/v1/jobs').then(r => r.json()).then(d => setJobs(d.jobs || []));
+            }, []);
+
+            const save = () => {
+                const body = {
+                    scan_roots: form.scan_roots.filter(r => r.path.trim()).map(r => ({ path: r.path.trim(), max_depth: Number(r.max_depth) || 0, include: split(r.include) })),
+                    category_rules: form.category_rules.filter(r => r.name.trim()).map(r => ({ name: r.name.trim(), pattern: r.pattern })),
+                    relative_markers: split(form.relative_markers),
+                    path_rewrites: form.path_rewrites.filter(r => r.from.trim()).map(r => ({ from: r.from.trim(), to: r.to.trim() })),
+                };
+                setSaving(true);
+                fetch('/api/v1/config', { method: 'PUT', headers: { 'Content-Type': 'application/json' }, body: JSON.stringify(body) })
+                    .then(r => r.json())
+                    .then(d => {
+                        setSaving(false);
+                        if (d.error) { setMessage(t('common.error', { error: d.error })); return; }
+                        setSettings(d);
+                        setForm(toForm(d));
+                        setMessage(t('settings.saved', { path: d.config_path }));
+                    });
+            };
+
+            const saveSchedule = (job) => {
+                fetch('/api/v1/jobs/' + job.id + '/schedule', { method: 'POST', headers: { 'Content-Type': 'application/json' }, body: JSON.stringify({ schedule: schedules[job.id] }) })
+                    .then(r => r.json())
+                    .then(d => {
+                        if (d.error) { alert(t('common.error', { error: d.error })); return; }
+                        setJobs(jobs.map(j => j.id === d.id ? d : j));
+                        const next = { ...schedules };
+                        delete next[job.id];
+                        setSchedules(next);
+                    });
+            };
+
+            if (!form) return <div className="loading">{message || t('common.loading')}</div>;
+            const overridden = (key) => settings.overridden.indexOf(key) >= 0 && <div className="warning">{t('settings.overridden')}</div>;
+
+            return (
+                <div className="settings">
+                    <section>
+                        <h2>{t('settings.scan_roots')}</h2>
+                        <div className="help">{t('settings.scan_roots_help')}</div>
+                        {overridden('scan_roots')}
+                        <ListEditor items={form.scan_roots} empty={{ path: '', max_depth: '', include: '' }} onChange={v => setForm({ ...form, scan_roots: v })}
+                            fields={[{ key: 'path', label: t('settings.root_path') }, { key: 'max_depth', label: t('settings.max_depth'), narrow: true }, { key: 'include', label: t('settings.include') }]} />
+                    </section>
+                    <section>
+                        <h2>{t('settings.category_rules')}</h2>
+                        <div className="help">{t('settings.category_rules_help')}</div>
+                        {overridden('category_rules')}
+                        <ListEditor items={form.category_rules} empty={{ name: '', pattern: '' }} onChange={v => setForm({ ...form, category_rules: v })}
+                            fields={[{ key: 'name', label: t('settings.category'), narrow: true }, { key: 'pattern', label: t('settings.pattern') }]} />
+                    </section>
+                    <section>
+                        <h2>{t('settings.path_rewrites')}</h2>
+                        <div className="help">{t('settings.path_rewrites_help')}</div>
+                        {overridden('path_rewrites')}
+                        <ListEditor items={form.path_rewrites} empty={{ from: '', to: '' }} onChange={v => setForm({ ...form, path_rewrites: v })}
+                            fields={[{ key: 'from', label: t('settings.from') }, { key: 'to', label: t('settings.to') }]} />
+                    </section>
+                    <section>
+                        <h2>{t('settings.relative_markers')}</h2>
+                        <div className="help">{t('settings.relative_markers_help')}</div>
+                        {overridden('relative_markers')}
+                        <div className="row">
+                            <input value={form.relative_markers} placeholder="movies, shows" onChange={e => setForm({ ...form, relative_markers: e.target.value })} />
+                        </div>
+                    </section>
+                    <div className="actions">
+                        <button className="export-btn" onClick={save} disabled={saving}>{t('settings.save')}</button>
+                        <span>{message}</span>
+                    </div>
+                    <section>
+                        <h2>{t('settings.jobs')}</h2>
+                        <div className="help">{t('settings.jobs_help')}</div>
+                        {jobs.length === 0 && <div className="help">{t('settings.no_jobs')}</div>}
+                        {jobs.map(job => (
+                            <div className="row" key={job.id}>
+                                <span style={{ flex: 1 }}>{job.name}{!job.enabled && ' (' + t('settings.disabled') + ')'}</span>
+                                <input value={schedules[job.id] !== undefined ? schedules[job.id] : job.schedule} onChange={e => setSchedules({ ...schedules, [job.id]: e.target.value })} />
+                                <button className="row-btn" disabled={schedules[job.id] === undefined} onClick={() => saveSchedule(job)}>{t('settings.save')}</button>
+                            </div>
+                        ))}
+                    </section>
+                </div>
+            );
+        }
+
+        // Fraîcheur des données : au-delà, la dernière synchronisation est signalée
+        const staleSyncHours = 24;
+
+        function SyncButton({ admin, onDone, onErrors }) {
+            const [job, setJob] = useState(null);
+            const [lastRun, setLastRun] = useState(null);
+            const running = job && job.status === 'running';
+
+            // Dernière synchronisation enregistrée, et celle en cours lancée
+            // depuis un autre onglet ou un autre navigateur
+            const loadState = () => fetch('/api/v1/sync/status').then(r => r.json()).then(d => {
+                setLastRun(d.last_run || null);
+                if (d.current) setJob(d.current);
+            });
+
+            useEffect(() => {
+                loadState();
+                const timer = setInterval(loadState, 60000);
+                return () => clearInterval(timer);
+            }, []);
+
+            useEffect(() => {
+                if (!running) return;
+                const source = new EventSource('/api/v1/sync/progress');
+                source.addEventListener('progress', e => setJob(JSON.parse(e.data)));
+                source.addEventListener('done', e => {
+                    setJob(JSON.parse(e.data));
+                    source.close();
+                    loadState();
+                    onDone();
+                });
+                return () => source.close();
+            }, [running]);
+
+            const start = () => {
+                fetch('/api/v1/sync', { method: 'POST' }).then(r => r.json()).then(d => {
+                    if (d.error) alert(t('common.error', { error: d.error }));
+                    else setJob(d);
+                });
+            };
+
+            const cancel = () => {
+                fetch('/api/v1/sync/cancel', { method: 'POST' });
+            };
+
+            let status = '';
+            let percent = null;
+            if (running) {
+                const p = job.progress;
+                percent = syncPercent(p);
+                status = t(syncStages[p.stage] || 'sync.starting') + '...';
+                if (p.stage === 'torrents') status += ' ' + t('sync.torrents_progress', { done: p.torrents_done, total: p.torrents_total, files: p.torrent_files.toLocaleString() });
+                if (p.stage === 'scan') {
+                    status += ' ' + t('sync.scan_files', { files: p.local_files.toLocaleString() });
+                    if (p.scan) status += ' (' + t('sync.scan_rate', { size: formatSize(p.scan.bytes), rate: Math.round(p.scan.rate).toLocaleString() })
+                        + (p.scan.eta_seconds > 0 ? t('sync.scan_eta', { seconds: Math.ceil(p.scan.eta_seconds) }) : '') + ')';
+                }
+                if (p.stage === 'torrents_insert' || p.stage === 'insert') status += ' ' + p.inserted.toLocaleString() + '/' + p.insert_total.toLocaleString();
+            }
+            else if (job && job.status === 'failed') status = t('sync.failed', { error: job.error });
+            else if (job && job.status === 'cancelled') status = t('sync.cancelled');
+            else if (job && job.result) status = t('sync.done_torrents', { files: job.result.torrent_files.toLocaleString() }) + ', ' + (job.result.local_synced
+                ? t('sync.done_local', { files: job.result.local_files.toLocaleString() })
+                : t('sync.local_kept'));
+            if (job && job.result && job.result.torrents_failed > 0) status += ' (' + t('sync.torrents_failed', { count: job.result.torrents_failed }) + ')';
+
+            // Sans synchronisation suivie, résumé de la dernière enregistrée
+            let summary = null;
+            if (!status && lastRun) {
+                const stale = Date.now() - Date.parse(lastRun.finished_at) > staleSyncHours * 3600 * 1000;
+                const errors = lastRun.torrents_failed + (lastRun.status === 'failed' ? 1 : 0);
+                const kept = lastRun.status === 'partial' && !lastRun.torrents_synced;
+                let text = t('sync.last_run', { ago: formatAge(lastRun.finished_at), torrents: lastRun.torrents.toLocaleString(), files: lastRun.local_files.toLocaleString() });
+                if (errors > 0) text += ', ' + t('sync.last_errors', { count: errors });
+                if (kept) text += ', ' + t('sync.last_torrents_kept');
+                if (lastRun.status === 'cancelled') text += ', ' + t('sync.last_cancelled');
+                const failed = errors > 0 || kept;
+                summary = <span className={'last-run' + (stale || failed ? ' warn' : '') + (failed ? ' link' : '')} title={lastRun.error || new Date(lastRun.finished_at).toLocaleString()} onClick={failed ? onErrors : undefined}>{text}</span>;
+            }
+            else if (!status && !running) summary = <span className="last-run warn">{t('sync.never')}</span>;
+
+            return (
+                <div className="sync">
+                    {status && <span>{status}</span>}
+                    {summary}
+                    {percent !== null && (
+                        <div className="sync-bar"><div style={{width: percent + '%'}}></div></div>
+                    )}
+                    {admin && running && <button className="danger-btn" onClick={cancel}>{t('common.cancel')}</button>}
+                    {admin && <button className="export-btn" onClick={start} disabled={running}>{t('sync.start')}</button>}
+                </div>
+            );
+        }
+
+        function LoginForm({ onLogin }) {
+            const [username, setUsername] = useState('');
+            const [password, setPassword] = useState('');
+            const [remember, setRemember] = useState(false);
+            const [error, setError] = useState('');
+
+            const submit = (e) => {
+                e.preventDefault();
+                fetch('/api/v1/login', { method: 'POST', headers: { 'Content-Type': 'application/json' }, body: JSON.stringify({ username, password, remember }) })
+                    .then(r => r.json())
+                    .then(d => {
+                        if (d.error) { setError(t('login.invalid')); return; }
+                        onLogin(d);
+                    });
+            };
+
+            return (
+                <form className="login" onSubmit={submit}>
+                    <h1>🧹 GoDataCleaner</h1>
+                    {error && <div className="error">{error}</div>}
+                    <input type="text" placeholder={t('login.username')} value={username} onChange={e => setUsername(e.target.value)} autoFocus />
+                    <input type="password" placeholder={t('login.password')} value={password} onChange={e => setPassword(e.target.value)} />
+                    <label><input type="checkbox" checked={remember} onChange={e => setRemember(e.target.checked)} /> {t('login.remember')}</label>
+                    <button className="export-btn" type="submit">{t('login.submit')}</button>
+                    <LanguageSelect />
+                </form>
+            );
+        }
+
         function App() {
             const [tab, setTab] = useState('torrents');
+            const [historyView, setHistoryView] = useState('deletions');
+            const [refresh, setRefresh] = useState(0);
+            const [user, setUser] = useState(null);
+            const [loggedOut, setLoggedOut] = useState(false);
+            const admin = user && user.role === 'admin';
+
+            useEffect(() => {
+                fetch('/api/v1/me').then(r => {
+                    if (r.status === 401) { setLoggedOut(true); return; }
+                    r.json().then(setUser);
+                });
+            }, []);
+
+            const logout = () => {
+                fetch('/api/v1/logout', { method: 'POST' }).then(() => { setUser(null); setLoggedOut(true); });
+            };
+
+            if (loggedOut) return <LoginForm onLogin={u => { setUser(u); setLoggedOut(false); }} />;
+            if (!user) return <div className="loading">{t('common.loading')}</div>;
 
             return (
                 <div className="container">
-                    <h1>🧹 GoDataCleaner</h1>
+                    <div className="header">
+                        <h1>🧹 GoDataCleaner</h1>
+                        <div className="header-right">
+                            {user.username && <span className="user">👤 {user.username} ({user.role})</span>}
+                            {user.auth_enabled && <button className="export-btn" style={{marginRight: '15px'}} onClick={logout}>{t('app.logout')}</button>}
+                            <SyncButton admin={admin} onDone={() => setRefresh(r => r + 1)} onErrors={() => { setHistoryView('errors'); setTab('history'); }} />
+                            <LanguageSelect />
+                        </div>
+                    </div>
                     <div className="tabs">
-                        <button className={'tab' + (tab === 'torrents' ? ' active' : '')} onClick={() => setTab('torrents')}>Torrents</button>
-                        <button className={'tab' + (tab === 'local' ? ' active' : '')} onClick={() => setTab('local')}>Local</button>
-                        <button className={'tab' + (tab === 'orphans' ? ' active' : '')} onClick={() => setTab('orphans')}>Orphelins</button>
-                        <button className={'tab' + (tab === 'stats' ? ' active' : '')} onClick={() => setTab('stats')}>Stats</button>
+                        <button className={'tab' + (tab === 'torrents' ? ' active' : '')} onClick={() => setTab('torrents')}>{t('tabs.torrents')}</button>
+                        <button className={'tab' + (tab === 'local' ? ' active' : '')} onClick={() => setTab('local')}>{t('tabs.local')}</button>
+                        <button className={'tab' + (tab === 'orphans' ? ' active' : '')} onClick={() => setTab('orphans')}>{t('tabs.orphans')}</button>
+                        <button className={'tab' + (tab === 'missing' ? ' active' : '')} onClick={() => setTab('missing')}>{t('tabs.missing')}</button>
+                        <button className={'tab' + (tab === 'duplicates' ? ' active' : '')} onClick={() => setTab('duplicates')}>{t('tabs.duplicates')}</button>
+                        <button className={'tab' + (tab === 'stats' ? ' active' : '')} onClick={() => setTab('stats')}>{t('tabs.stats')}</button>
+                        <button className={'tab' + (tab === 'history' ? ' active' : '')} onClick={() => { setHistoryView('deletions'); setTab('history'); }}>{t('tabs.history')}</button>
+                        {admin && <button className={'tab' + (tab === 'keys' ? ' active' : '')} onClick={() => setTab('keys')}>{t('tabs.keys')}</button>}
+                        {admin && <button className={'tab' + (tab === 'settings' ? ' active' : '')} onClick={() => setTab('settings')}>{t('tabs.settings')}</button>}
                     </div>
-                    {tab === 'torrents' && <TorrentsTab />}
-                    {tab === 'local' && <LocalTab />}
-                    {tab === 'orphans' && <OrphansTab />}
-                    {tab === 'stats' && <StatsTab />}
+                    {tab === 'torrents' && <TorrentsTab key={refresh} admin={admin} />}
+                    {tab === 'local' && <LocalTab key={refresh} admin={admin} />}
+                    {tab === 'orphans' && <OrphansTab key={refresh} admin={admin} />}
+                    {tab === 'missing' && <MissingTab key={refresh} admin={admin} />}
+                    {tab === 'duplicates' && <DuplicatesTab key={refresh} admin={admin} />}
+                    {tab === 'stats' && <StatsTab key={refresh} />}
+                    {tab === 'history' && <HistoryTab key={refresh + historyView} initialView={historyView} />}
+                    {tab === 'keys' && admin && <KeysTab />}
+                    {tab === 'settings' && admin && <SettingsTab />}
                 </div>
             );
         }
 
-        ReactDOM.createRoot(document.getElementById('root')).render(<App />);
+        const root = ReactDOM.createRoot(document.getElementById('root'));
+
+        // loadLanguage loads the messages of a language, or of the language
+        // preferred by the browser when empty, then renders the interface.
+        function loadLanguage(code) {
+            return fetch('/api/v1/i18n' + (code ? '?lang=' + code : '')).then(r => r.json()).then(d => {
+                messages = d.messages || {};
+                languages = d.languages || [];
+                language = d.language;
+                document.documentElement.lang = d.language;
+                root.render(<App key={d.language} />);
+            });
+        }
+
+        loadLanguage(localStorage.getItem('lang') || '');
     </script>
 </body>
 </html>`
@@ -0,0 +1,51 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+
+	"godatacleaner/internal/models"
+)
+
+// parseTop reads the order, the number of results (100 by default, at most
+// 1000) and the orphan filter of the top endpoints.
+func parseTop(r *http.Request) (by string, n int, orphans bool) {
+	by = r.URL.Query().Get("by")
+	n = 100
+	if v := r.URL.Query().Get("n"); v != "" {
+		if l, err := strconv.Atoi(v); err == nil && l > 0 && l <= 1000 {
+			n = l
+		}
+	}
+	return by, n, r.URL.Query().Get("orphans") == "true"
+}
+
+// handleTopFiles returns the largest local files, by size or allocated
+// size, without paging through all of them.
+func (s *Server) handleTopFiles(w http.ResponseWriter, r *http.Request) {
+	by, n, orphans := parseTop(r)
+	files, err := s.storage.GetTopFiles(r.Context(), by, n, orphans)
+	if err != nil {
+		writeError(w, 500, "Failed to get top files")
+		return
+	}
+	if files == nil {
+		files = []models.TopFile{}
+	}
+	writeJSON(w, 200, models.TopFilesResponse{Files: files})
+}
+
+// handleTopFolders returns the largest local folders, by size, allocated
+// size or number of files.
+func (s *Server) handleTopFolders(w http.ResponseWriter, r *http.Request) {
+	by, n, orphans := parseTop(r)
+	folders, err := s.storage.GetTopFolders(r.Context(), by, n, orphans)
+	if err != nil {
+		writeError(w, 500, "Failed to get top folders")
+		return
+	}
+	if folders == nil {
+		folders = []models.TopFolder{}
+	}
+	writeJSON(w, 200, models.TopFoldersResponse{Folders: folders})
+}
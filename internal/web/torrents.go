@@ -0,0 +1,358 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"godatacleaner/internal/models"
+	"godatacleaner/internal/storage"
+	"godatacleaner/internal/syncer"
+	"godatacleaner/internal/torrent"
+)
+
+// torrentDeletionPreview is returned by the preview of the deletion of a
+// torrent with its data, with the token confirming it.
+type torrentDeletionPreview struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Files     int64     `json:"files"`
+	Bytes     int64     `json:"bytes"`
+}
+
+// handleDeleteTorrent removes a torrent from its client. With delete_files,
+// its data is deleted too, in two steps like the orphans: a request without
+// token is a preview returning a confirmation token, to send back as the
+// token parameter. A torrent with a protected file is never deleted with its
+// data.
+func (s *Server) handleDeleteTorrent(w http.ResponseWriter, r *http.Request) {
+	if s.syncer == nil {
+		writeError(w, 503, "Sync not configured")
+		return
+	}
+
+	instance, hash := r.PathValue("instance"), r.PathValue("hash")
+	deleteFiles := r.URL.Query().Get("delete_files") == "true"
+	if deleteFiles {
+		if s.cleaner == nil {
+			writeError(w, 503, "Cleaner not configured")
+			return
+		}
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			s.previewTorrentDeletion(w, r, instance, hash)
+			return
+		}
+		pending, ok := s.confirm.take(token)
+		if !ok || pending.instance != instance || pending.hash != hash {
+			writeError(w, 400, "Invalid or expired confirmation token")
+			return
+		}
+		// Patterns may have been added since the preview
+		if !s.checkTorrentProtection(w, r, pending.paths) {
+			return
+		}
+	}
+
+	if err := s.syncer.DeleteTorrent(r.Context(), instance, hash, deleteFiles); err != nil {
+		writeTorrentError(w, err, "Failed to delete torrent")
+		return
+	}
+	log.Printf("🗑️  Torrent %s supprimé de %s (fichiers supprimés: %t)", hash, instance, deleteFiles)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// previewTorrentDeletion answers the first step of the deletion of a torrent
+// with its data: the local files that would be deleted and the token
+// confirming it, or 409 when one of them is protected.
+func (s *Server) previewTorrentDeletion(w http.ResponseWriter, r *http.Request, instance, hash string) {
+	files, err := s.syncer.TorrentLocalFiles(r.Context(), instance, hash)
+	if err != nil {
+		writeError(w, 500, "Failed to get torrent files")
+		return
+	}
+
+	pending := pendingDeletion{instance: instance, hash: hash, expires: time.Now().Add(confirmationTTL)}
+	preview := torrentDeletionPreview{ExpiresAt: pending.expires, Files: int64(len(files))}
+	for _, f := range files {
+		pending.paths = append(pending.paths, f.FilePath)
+		preview.Bytes += f.Allocated
+	}
+	if !s.checkTorrentProtection(w, r, pending.paths) {
+		return
+	}
+
+	if preview.Token, err = s.confirm.add(pending); err != nil {
+		writeError(w, 500, "Failed to create confirmation token")
+		return
+	}
+	writeJSON(w, 200, preview)
+}
+
+// checkTorrentProtection answers 409 and returns false when one of the local
+// files of a torrent is protected.
+func (s *Server) checkTorrentProtection(w http.ResponseWriter, r *http.Request, paths []string) bool {
+	protected, err := s.cleaner.ProtectedPaths(r.Context(), paths)
+	if err != nil {
+		writeError(w, 500, "Failed to check protected paths")
+		return false
+	}
+	if len(protected) > 0 {
+		writeError(w, 409, fmt.Sprintf("The torrent has %d protected file(s), such as %s", len(protected), protected[0]))
+		return false
+	}
+	return true
+}
+
+func (s *Server) handlePauseTorrent(w http.ResponseWriter, r *http.Request) {
+	if s.syncer == nil {
+		writeError(w, 503, "Sync not configured")
+		return
+	}
+	if err := s.syncer.PauseTorrent(r.Context(), r.PathValue("instance"), r.PathValue("hash")); err != nil {
+		writeTorrentError(w, err, "Failed to pause torrent")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleResumeTorrent(w http.ResponseWriter, r *http.Request) {
+	if s.syncer == nil {
+		writeError(w, 503, "Sync not configured")
+		return
+	}
+	if err := s.syncer.ResumeTorrent(r.Context(), r.PathValue("instance"), r.PathValue("hash")); err != nil {
+		writeTorrentError(w, err, "Failed to resume torrent")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleRecheckTorrent(w http.ResponseWriter, r *http.Request) {
+	if s.syncer == nil {
+		writeError(w, 503, "Sync not configured")
+		return
+	}
+	if err := s.syncer.RecheckTorrent(r.Context(), r.PathValue("instance"), r.PathValue("hash")); err != nil {
+		writeTorrentError(w, err, "Failed to recheck torrent")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleRecheckPaths rechecks the torrents expecting the given local files,
+// typically the files of a cleanup or a restore.
+func (s *Server) handleRecheckPaths(w http.ResponseWriter, r *http.Request) {
+	if s.syncer == nil {
+		writeError(w, 503, "Sync not configured")
+		return
+	}
+
+	var req struct {
+		Paths []string `json:"paths"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, 400, "Invalid JSON body")
+		return
+	}
+	if len(req.Paths) == 0 {
+		writeError(w, 400, "paths is required")
+		return
+	}
+
+	report, err := s.syncer.RecheckPaths(r.Context(), req.Paths)
+	if err != nil {
+		writeError(w, 500, "Failed to recheck torrents")
+		return
+	}
+	if report.Actions == nil {
+		report.Actions = []syncer.RecheckAction{}
+	}
+	writeJSON(w, 200, report)
+}
+
+func (s *Server) handleMoveTorrent(w http.ResponseWriter, r *http.Request) {
+	if s.syncer == nil {
+		writeError(w, 503, "Sync not configured")
+		return
+	}
+
+	var req struct {
+		Location string `json:"location"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, 400, "Invalid JSON body")
+		return
+	}
+	if !path.IsAbs(req.Location) {
+		writeError(w, 400, "location must be an absolute path")
+		return
+	}
+
+	instance, hash := r.PathValue("instance"), r.PathValue("hash")
+	if err := s.syncer.MoveTorrent(r.Context(), instance, hash, path.Clean(req.Location)); err != nil {
+		writeTorrentError(w, err, "Failed to move torrent")
+		return
+	}
+	log.Printf("🚚 Torrent %s de %s déplacé vers %s", hash, instance, req.Location)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// torrentSelection selects torrents by instance and hash, and by the local
+// files they expect as in handleRecheckPaths.
+type torrentSelection struct {
+	Torrents []models.Torrent `json:"torrents"`
+	Paths    []string         `json:"paths"`
+}
+
+// selectedTorrents returns the torrents of a selection.
+func (s *Server) selectedTorrents(r *http.Request, sel torrentSelection) ([]models.Torrent, error) {
+	torrents := sel.Torrents
+	if len(sel.Paths) > 0 {
+		found, err := s.storage.GetTorrentsForPaths(r.Context(), sel.Paths)
+		if err != nil {
+			return nil, err
+		}
+		torrents = append(torrents, found...)
+	}
+	return torrents, nil
+}
+
+// handleSetCategory sets the category of the selected torrents, or removes
+// it when the category is empty.
+func (s *Server) handleSetCategory(w http.ResponseWriter, r *http.Request) {
+	if s.syncer == nil {
+		writeError(w, 503, "Sync not configured")
+		return
+	}
+
+	var req struct {
+		torrentSelection
+		Category *string `json:"category"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, 400, "Invalid JSON body")
+		return
+	}
+	if req.Category == nil {
+		writeError(w, 400, "category is required")
+		return
+	}
+
+	torrents, err := s.selectedTorrents(r, req.torrentSelection)
+	if err != nil {
+		writeError(w, 500, "Failed to select torrents")
+		return
+	}
+	if len(torrents) == 0 {
+		writeError(w, 400, "No torrent selected")
+		return
+	}
+
+	report := s.syncer.SetCategory(r.Context(), torrents, *req.Category)
+	log.Printf("🏷️  Catégorie %q appliquée à %d torrents (%d échecs)", *req.Category, report.Labeled, report.Failed)
+	writeLabelReport(w, report)
+}
+
+// handleSetTags adds and removes tags of the selected torrents.
+func (s *Server) handleSetTags(w http.ResponseWriter, r *http.Request) {
+	if s.syncer == nil {
+		writeError(w, 503, "Sync not configured")
+		return
+	}
+
+	var req struct {
+		torrentSelection
+		Add    []string `json:"add"`
+		Remove []string `json:"remove"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, 400, "Invalid JSON body")
+		return
+	}
+	if len(req.Add) == 0 && len(req.Remove) == 0 {
+		writeError(w, 400, "add or remove is required")
+		return
+	}
+	for _, tag := range append(req.Add, req.Remove...) {
+		if strings.TrimSpace(tag) == "" || strings.Contains(tag, ",") {
+			writeError(w, 400, "Invalid tag: "+tag)
+			return
+		}
+	}
+
+	torrents, err := s.selectedTorrents(r, req.torrentSelection)
+	if err != nil {
+		writeError(w, 500, "Failed to select torrents")
+		return
+	}
+	if len(torrents) == 0 {
+		writeError(w, 400, "No torrent selected")
+		return
+	}
+
+	report := s.syncer.SetTags(r.Context(), torrents, req.Add, req.Remove)
+	log.Printf("🏷️  Tags mis à jour sur %d torrents (%d échecs)", report.Labeled, report.Failed)
+	writeLabelReport(w, report)
+}
+
+// handleReannounce reannounces the selected torrents to their trackers.
+func (s *Server) handleReannounce(w http.ResponseWriter, r *http.Request) {
+	if s.syncer == nil {
+		writeError(w, 503, "Sync not configured")
+		return
+	}
+
+	var sel torrentSelection
+	if err := json.NewDecoder(r.Body).Decode(&sel); err != nil {
+		writeError(w, 400, "Invalid JSON body")
+		return
+	}
+
+	torrents, err := s.selectedTorrents(r, sel)
+	if err != nil {
+		writeError(w, 500, "Failed to select torrents")
+		return
+	}
+	if len(torrents) == 0 {
+		writeError(w, 400, "No torrent selected")
+		return
+	}
+
+	report := s.syncer.Reannounce(r.Context(), torrents)
+	if report.Actions == nil {
+		report.Actions = []syncer.InstanceAction{}
+	}
+	log.Printf("📣 %d torrents réannoncés (%d échecs)", report.Reannounced, report.Failed)
+	writeJSON(w, 200, report)
+}
+
+// writeLabelReport writes the report of a change of category or tags.
+func writeLabelReport(w http.ResponseWriter, report *syncer.LabelReport) {
+	if report.Actions == nil {
+		report.Actions = []syncer.InstanceAction{}
+	}
+	writeJSON(w, 200, report)
+}
+
+// writeTorrentError writes the error of an action on a torrent client.
+func writeTorrentError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, syncer.ErrSyncRunning):
+		writeError(w, 409, "A sync is running")
+	case errors.Is(err, syncer.ErrUnknownInstance):
+		writeError(w, 404, "Unknown torrent client instance")
+	case errors.Is(err, storage.ErrNotFound):
+		writeError(w, 404, "Torrent not found")
+	case errors.Is(err, torrent.ErrUnsupported):
+		writeError(w, 501, "Action not supported by the torrent client")
+	default:
+		log.Printf("⚠️  %s: %v", msg, err)
+		writeError(w, 502, msg)
+	}
+}
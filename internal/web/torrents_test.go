@@ -0,0 +1,159 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"godatacleaner/internal/cleaner"
+	"godatacleaner/internal/config"
+	"godatacleaner/internal/models"
+	"godatacleaner/internal/storage"
+	"godatacleaner/internal/syncer"
+	"godatacleaner/internal/torrent"
+)
+
+// fakeClient is a torrent client recording the torrents it is asked to delete.
+type fakeClient struct {
+	mu      sync.Mutex
+	deleted map[string]bool // Hash → with its files
+}
+
+func (c *fakeClient) Name() string                                              { return "fake" }
+func (c *fakeClient) Login(ctx context.Context) error                           { return nil }
+func (c *fakeClient) GetTorrents(ctx context.Context) ([]models.Torrent, error) { return nil, nil }
+func (c *fakeClient) GetTorrentFiles(ctx context.Context, hash string) ([]models.TorrentFile, error) {
+	return nil, nil
+}
+
+func (c *fakeClient) DeleteTorrent(ctx context.Context, hash string, deleteFiles bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deleted[hash] = deleteFiles
+	return nil
+}
+
+var (
+	registerFake sync.Once
+	fake         = &fakeClient{deleted: make(map[string]bool)}
+)
+
+// torrentServer returns a server whose single torrent, t of the fake client,
+// has its file under root.
+func torrentServer(t *testing.T) (*Server, *storage.Storage) {
+	t.Helper()
+	registerFake.Do(func() {
+		torrent.Register("fake", func(cfg *config.Config) ([]torrent.TorrentSource, error) {
+			return []torrent.TorrentSource{fake}, nil
+		})
+	})
+
+	ctx := context.Background()
+	store, err := storage.NewStorage(filepath.Join(t.TempDir(), "test.db"), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if err := store.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	diskPath := filepath.Join(root, "movies/A/a.mkv")
+	if err := os.MkdirAll(filepath.Dir(diskPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(diskPath, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := store.BeginSync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	torrents := []models.Torrent{{Hash: "t", Name: "t", SavePath: "/downloads/movies", State: models.TorrentStateSeeding, Instance: "fake"}}
+	files := []models.TorrentFile{{TorrentHash: "t", TorrentName: "t", FileName: "a.mkv", FilePath: "/downloads/movies/A/a.mkv", Size: 4, Instance: "fake"}}
+	local := []models.LocalFile{{FilePath: diskPath, FileName: "a.mkv", Size: 4, Allocated: 4, Category: "movies", Links: 1}}
+	if _, err := tx.ReplaceTorrentFiles(ctx, torrents, files, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.ReplaceLocalFiles(ctx, local, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(ctx, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewServer(store, "localhost", 0).
+		WithCleaner(cleaner.NewCleaner(store, root)).
+		WithSyncer(syncer.NewSyncer(store, &config.Config{TorrentClients: []string{"fake"}, LocalPath: root}))
+	return s, store
+}
+
+// deleteTorrent sends a deletion of torrent t with its files and returns the
+// status and the body of the answer.
+func deleteTorrent(t *testing.T, s *Server, token string) (int, torrentDeletionPreview) {
+	t.Helper()
+	path := "/api/v1/torrent/torrents/fake/t?delete_files=true"
+	if token != "" {
+		path += "&token=" + token
+	}
+	w := httptest.NewRecorder()
+	s.handler().ServeHTTP(w, httptest.NewRequest(http.MethodDelete, path, nil))
+	var preview torrentDeletionPreview
+	if w.Code == 200 {
+		if err := json.NewDecoder(w.Body).Decode(&preview); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w.Code, preview
+}
+
+func TestDeleteTorrentFiles(t *testing.T) {
+	ctx := context.Background()
+	s, store := torrentServer(t)
+
+	code, preview := deleteTorrent(t, s, "")
+	if code != 200 || preview.Token == "" || preview.Files != 1 || preview.Bytes != 4 {
+		t.Fatalf("preview = %d %+v, want 1 file of 4 bytes and a token", code, preview)
+	}
+	if code, _ := deleteTorrent(t, s, "other"); code != 400 {
+		t.Errorf("unknown token = %d, want 400", code)
+	}
+
+	// A pattern added since the preview is honoured by the confirmation
+	id, err := store.AddProtectedPath(ctx, "movies/A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code, _ := deleteTorrent(t, s, preview.Token); code != 409 {
+		t.Errorf("confirmation of a protected torrent = %d, want 409", code)
+	}
+	if code, _ := deleteTorrent(t, s, ""); code != 409 {
+		t.Errorf("preview of a protected torrent = %d, want 409", code)
+	}
+	if _, ok := fake.deleted["t"]; ok {
+		t.Fatal("protected torrent deleted")
+	}
+
+	if err := store.RemoveProtectedPath(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	_, preview = deleteTorrent(t, s, "")
+	if code, _ := deleteTorrent(t, s, preview.Token); code != 204 {
+		t.Fatalf("confirmation = %d, want 204", code)
+	}
+	if withFiles, ok := fake.deleted["t"]; !ok || !withFiles {
+		t.Error("torrent not deleted with its files")
+	}
+	if code, _ := deleteTorrent(t, s, preview.Token); code != 400 {
+		t.Errorf("reused token = %d, want 400", code)
+	}
+}
@@ -0,0 +1,108 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"godatacleaner/internal/models"
+	"godatacleaner/internal/storage"
+)
+
+func (s *Server) handleSavedViews(w http.ResponseWriter, r *http.Request) {
+	list := r.URL.Query().Get("list")
+	if list != "" && !models.ValidViewList(list) {
+		writeError(w, 400, "Invalid list")
+		return
+	}
+	views, err := s.storage.ListSavedViews(r.Context(), list)
+	if err != nil {
+		writeError(w, 500, "Failed to get saved views")
+		return
+	}
+	if views == nil {
+		views = []models.SavedView{}
+	}
+	writeJSON(w, 200, models.SavedViewsResponse{Views: views})
+}
+
+// decodeSavedView reads and validates the saved view of a request body.
+func decodeSavedView(w http.ResponseWriter, r *http.Request) (models.SavedView, bool) {
+	var v models.SavedView
+	if err := json.NewDecoder(r.Body).Decode(&v); err != nil {
+		writeError(w, 400, "Invalid JSON body")
+		return v, false
+	}
+	v.Name = strings.TrimSpace(v.Name)
+	switch {
+	case v.Name == "":
+		writeError(w, 400, "name is required")
+	case !models.ValidViewList(v.List):
+		writeError(w, 400, "list must be orphans or local")
+	case !storage.ValidViewSort(v.List, v.Sort):
+		writeError(w, 400, "Invalid sort column")
+	case v.Order != "" && v.Order != "asc" && v.Order != "desc":
+		writeError(w, 400, "order must be asc or desc")
+	case v.MinSize < 0 || v.MaxSize < 0 || (v.MaxSize > 0 && v.MaxSize < v.MinSize):
+		writeError(w, 400, "Invalid size range")
+	default:
+		return v, true
+	}
+	return v, false
+}
+
+func (s *Server) handleCreateSavedView(w http.ResponseWriter, r *http.Request) {
+	v, ok := decodeSavedView(w, r)
+	if !ok {
+		return
+	}
+	id, err := s.storage.CreateSavedView(r.Context(), v)
+	if err != nil {
+		writeError(w, 500, "Failed to create saved view")
+		return
+	}
+	v.ID = id
+	writeJSON(w, 201, v)
+}
+
+func (s *Server) handleUpdateSavedView(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(r)
+	if !ok {
+		writeError(w, 400, "Invalid saved view id")
+		return
+	}
+	v, ok := decodeSavedView(w, r)
+	if !ok {
+		return
+	}
+	v.ID = id
+	err := s.storage.UpdateSavedView(r.Context(), v)
+	if errors.Is(err, storage.ErrNotFound) {
+		writeError(w, 404, "Saved view not found")
+		return
+	}
+	if err != nil {
+		writeError(w, 500, "Failed to update saved view")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleDeleteSavedView(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(r)
+	if !ok {
+		writeError(w, 400, "Invalid saved view id")
+		return
+	}
+	err := s.storage.DeleteSavedView(r.Context(), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		writeError(w, 404, "Saved view not found")
+		return
+	}
+	if err != nil {
+		writeError(w, 500, "Failed to delete saved view")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}